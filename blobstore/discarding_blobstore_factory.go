@@ -0,0 +1,43 @@
+package blobstore
+
+import (
+	"io"
+	"net/http"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+)
+
+type discardingBlobstoreFactory struct {
+	uuidGenerator boshuuid.Generator
+	fs            boshsys.FileSystem
+	logger        boshlog.Logger
+}
+
+// NewDiscardingBlobstoreFactory creates blobstores that accept blobs without
+// keeping them, e.g. when agent calls are replayed and no agent fetches them.
+func NewDiscardingBlobstoreFactory(uuidGenerator boshuuid.Generator, fs boshsys.FileSystem, logger boshlog.Logger) Factory {
+	return discardingBlobstoreFactory{
+		uuidGenerator: uuidGenerator,
+		fs:            fs,
+		logger:        logger,
+	}
+}
+
+func (f discardingBlobstoreFactory) Create(_ string, _ *http.Client) (Blobstore, error) {
+	return NewBlobstore(discardingDavClient{}, f.uuidGenerator, f.fs, f.logger), nil
+}
+
+type discardingDavClient struct{}
+
+func (c discardingDavClient) Get(path string) (io.ReadCloser, error) {
+	return nil, bosherr.Errorf("Blob '%s' was discarded", path)
+}
+
+func (c discardingDavClient) Put(_ string, content io.ReadCloser, _ int64) error {
+	_, err := io.Copy(io.Discard, content)
+
+	return err
+}
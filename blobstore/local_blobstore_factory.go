@@ -0,0 +1,61 @@
+package blobstore
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+)
+
+type localBlobstoreFactory struct {
+	uuidGenerator boshuuid.Generator
+	fs            boshsys.FileSystem
+	dir           string
+	logger        boshlog.Logger
+}
+
+// NewLocalBlobstoreFactory creates blobstores that keep blobs in dir instead
+// of uploading them to the agent's mbus endpoint, e.g. for the local fake CPI.
+func NewLocalBlobstoreFactory(uuidGenerator boshuuid.Generator, fs boshsys.FileSystem, dir string, logger boshlog.Logger) Factory {
+	return localBlobstoreFactory{
+		uuidGenerator: uuidGenerator,
+		fs:            fs,
+		dir:           dir,
+		logger:        logger,
+	}
+}
+
+func (f localBlobstoreFactory) Create(_ string, _ *http.Client) (Blobstore, error) {
+	err := f.fs.MkdirAll(f.dir, 0700)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Creating local blobstore directory '%s'", f.dir)
+	}
+
+	return NewBlobstore(localDavClient{fs: f.fs, dir: f.dir}, f.uuidGenerator, f.fs, f.logger), nil
+}
+
+type localDavClient struct {
+	fs  boshsys.FileSystem
+	dir string
+}
+
+func (c localDavClient) Get(path string) (io.ReadCloser, error) {
+	return c.fs.OpenFile(filepath.Join(c.dir, path), os.O_RDONLY, 0)
+}
+
+func (c localDavClient) Put(path string, content io.ReadCloser, _ int64) error {
+	file, err := c.fs.OpenFile(filepath.Join(c.dir, path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close() //nolint:errcheck
+
+	_, err = io.Copy(file, content)
+
+	return err
+}
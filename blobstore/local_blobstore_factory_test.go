@@ -0,0 +1,32 @@
+package blobstore_test
+
+import (
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/blobstore"
+)
+
+var _ = Describe("LocalBlobstoreFactory", func() {
+	It("keeps blobs in the directory", func() {
+		fs := fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		blobstore, err := NewLocalBlobstoreFactory(fakeuuid.NewFakeGenerator(), fs, "/fake-blobs", logger).Create("https://fake-host:6868", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = fs.WriteFileString("/fake-source", "fake-contents")
+		Expect(err).ToNot(HaveOccurred())
+
+		blobID, err := blobstore.Add("/fake-source")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fs.ReadFileString("/fake-blobs/" + blobID)).To(Equal("fake-contents"))
+
+		localBlob, err := blobstore.Get(blobID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fs.ReadFileString(localBlob.Path())).To(Equal("fake-contents"))
+	})
+})
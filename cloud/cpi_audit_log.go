@@ -0,0 +1,293 @@
+package cloud
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// CPIAuditEntry describes one CPI call. Arguments, logs and error messages are redacted.
+type CPIAuditEntry struct {
+	Time       time.Time     `json:"time"`
+	Method     string        `json:"method"`
+	Arguments  []interface{} `json:"arguments"`
+	CID        string        `json:"cid,omitempty"`
+	DurationMS int64         `json:"duration_ms"`
+	Log        string        `json:"log,omitempty"`
+	ErrorType  string        `json:"error_type,omitempty"`
+	Error      string        `json:"error,omitempty"`
+
+	// Replayed is set when the call was answered from a CPI cassette
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+func (e CPIAuditEntry) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+type CPIAuditLog interface {
+	Path() string
+	Append(CPIAuditEntry) error
+	Entries() ([]CPIAuditEntry, error)
+}
+
+type fileCPIAuditLog struct {
+	fs   boshsys.FileSystem
+	path string
+}
+
+// NewFileCPIAuditLog keeps entries as JSON lines; existing lines are never rewritten.
+func NewFileCPIAuditLog(fs boshsys.FileSystem, path string) CPIAuditLog {
+	return fileCPIAuditLog{fs: fs, path: path}
+}
+
+func (l fileCPIAuditLog) Path() string { return l.path }
+
+func (l fileCPIAuditLog) Append(entry CPIAuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling CPI audit entry")
+	}
+
+	file, err := l.fs.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening CPI audit log '%s'", l.path)
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing CPI audit log '%s'", l.path)
+	}
+
+	return nil
+}
+
+func (l fileCPIAuditLog) Entries() ([]CPIAuditEntry, error) {
+	if !l.fs.FileExists(l.path) {
+		return nil, nil
+	}
+
+	contents, err := l.fs.ReadFile(l.path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading CPI audit log '%s'", l.path)
+	}
+
+	var entries []CPIAuditEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(nil, len(contents)+1)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry CPIAuditEntry
+
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Unmarshalling CPI audit log '%s' line %d", l.path, lineNum)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// CPILogCollector keeps the Log output of CPI calls until it is taken by the
+// auditing cloud, since Cloud methods do not return it.
+type CPILogCollector struct {
+	cpiCmdRunner CPICmdRunner
+	logs         []string
+}
+
+func NewCPILogCollector(cpiCmdRunner CPICmdRunner) *CPILogCollector {
+	return &CPILogCollector{cpiCmdRunner: cpiCmdRunner}
+}
+
+func (c *CPILogCollector) Run(context CmdContext, method string, apiVersion int, args ...interface{}) (CmdOutput, error) {
+	cmdOutput, err := c.cpiCmdRunner.Run(context, method, apiVersion, args...)
+	if cmdOutput.Log != "" {
+		c.logs = append(c.logs, cmdOutput.Log)
+	}
+
+	return cmdOutput, err
+}
+
+// TakeLog returns the Log output collected since it was last taken.
+func (c *CPILogCollector) TakeLog() string {
+	log := strings.Join(c.logs, "")
+	c.logs = nil
+
+	return log
+}
+
+type auditingCloud struct {
+	cloud        Cloud
+	auditLog     CPIAuditLog
+	logCollector *CPILogCollector
+	replayed     bool
+	timeService  clock.Clock
+	logger       boshlog.Logger
+	logTag       string
+}
+
+// NewAuditingCloud appends an entry to auditLog for every call of cloud, which
+// may be a CPI release, a replay or the local fake CPI. logCollector provides
+// the CPI Log output when there is a CPI release to produce it. Failing to
+// write the audit log is logged but does not fail the call.
+func NewAuditingCloud(
+	cloud Cloud,
+	auditLog CPIAuditLog,
+	logCollector *CPILogCollector,
+	replayed bool,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Cloud {
+	return auditingCloud{
+		cloud:        cloud,
+		auditLog:     auditLog,
+		logCollector: logCollector,
+		replayed:     replayed,
+		timeService:  timeService,
+		logger:       logger,
+		logTag:       "auditingCloud",
+	}
+}
+
+func (c auditingCloud) CreateStemcell(imagePath string, cloudProperties biproperty.Map) (string, error) {
+	startTime := c.timeService.Now()
+	stemcellCID, err := c.cloud.CreateStemcell(imagePath, cloudProperties)
+	c.audit(startTime, "create_stemcell", []interface{}{imagePath, cloudProperties}, stemcellCID, err)
+	return stemcellCID, err
+}
+
+func (c auditingCloud) DeleteStemcell(stemcellCID string) error {
+	startTime := c.timeService.Now()
+	err := c.cloud.DeleteStemcell(stemcellCID)
+	c.audit(startTime, "delete_stemcell", []interface{}{stemcellCID}, "", err)
+	return err
+}
+
+func (c auditingCloud) HasVM(vmCID string) (bool, error) {
+	startTime := c.timeService.Now()
+	found, err := c.cloud.HasVM(vmCID)
+	c.audit(startTime, "has_vm", []interface{}{vmCID}, "", err)
+	return found, err
+}
+
+func (c auditingCloud) CreateVM(
+	agentID string,
+	stemcellCID string,
+	cloudProperties biproperty.Map,
+	diskCIDs []string,
+	networksInterfaces map[string]biproperty.Map,
+	env biproperty.Map,
+) (string, error) {
+	startTime := c.timeService.Now()
+	vmCID, err := c.cloud.CreateVM(agentID, stemcellCID, cloudProperties, diskCIDs, networksInterfaces, env)
+	c.audit(startTime, "create_vm", []interface{}{agentID, stemcellCID, cloudProperties, networksInterfaces, diskCIDs, env}, vmCID, err)
+	return vmCID, err
+}
+
+func (c auditingCloud) SetVMMetadata(vmCID string, metadata VMMetadata) error {
+	startTime := c.timeService.Now()
+	err := c.cloud.SetVMMetadata(vmCID, metadata)
+	c.audit(startTime, "set_vm_metadata", []interface{}{vmCID, metadata}, "", err)
+	return err
+}
+
+func (c auditingCloud) SetDiskMetadata(diskCID string, metadata DiskMetadata) error {
+	startTime := c.timeService.Now()
+	err := c.cloud.SetDiskMetadata(diskCID, metadata)
+	c.audit(startTime, "set_disk_metadata", []interface{}{diskCID, metadata}, "", err)
+	return err
+}
+
+func (c auditingCloud) DeleteVM(vmCID string) error {
+	startTime := c.timeService.Now()
+	err := c.cloud.DeleteVM(vmCID)
+	c.audit(startTime, "delete_vm", []interface{}{vmCID}, "", err)
+	return err
+}
+
+func (c auditingCloud) CreateDisk(size int, cloudProperties biproperty.Map, vmCID string) (string, error) {
+	startTime := c.timeService.Now()
+	diskCID, err := c.cloud.CreateDisk(size, cloudProperties, vmCID)
+	c.audit(startTime, "create_disk", []interface{}{size, cloudProperties, vmCID}, diskCID, err)
+	return diskCID, err
+}
+
+func (c auditingCloud) AttachDisk(vmCID, diskCID string) (interface{}, error) {
+	startTime := c.timeService.Now()
+	diskHints, err := c.cloud.AttachDisk(vmCID, diskCID)
+	c.audit(startTime, "attach_disk", []interface{}{vmCID, diskCID}, "", err)
+	return diskHints, err
+}
+
+func (c auditingCloud) DetachDisk(vmCID, diskCID string) error {
+	startTime := c.timeService.Now()
+	err := c.cloud.DetachDisk(vmCID, diskCID)
+	c.audit(startTime, "detach_disk", []interface{}{vmCID, diskCID}, "", err)
+	return err
+}
+
+func (c auditingCloud) DeleteDisk(diskCID string) error {
+	startTime := c.timeService.Now()
+	err := c.cloud.DeleteDisk(diskCID)
+	c.audit(startTime, "delete_disk", []interface{}{diskCID}, "", err)
+	return err
+}
+
+func (c auditingCloud) Info() (CpiInfo, error) {
+	startTime := c.timeService.Now()
+	cpiInfo, err := c.cloud.Info()
+	c.audit(startTime, "info", []interface{}{}, "", err)
+	return cpiInfo, err
+}
+
+func (c auditingCloud) String() string {
+	return c.cloud.String()
+}
+
+func (c auditingCloud) audit(startTime time.Time, method string, args []interface{}, cid string, callErr error) {
+	redactor := NewCPIRedactor()
+
+	entry := CPIAuditEntry{
+		Time:       startTime.UTC(),
+		Method:     method,
+		Arguments:  redactor.RedactArguments(normalizeCPIValue(args).([]interface{})),
+		CID:        cid,
+		DurationMS: c.timeService.Since(startTime).Milliseconds(),
+		Replayed:   c.replayed,
+	}
+
+	if c.logCollector != nil {
+		entry.Log = redactor.RedactText(c.logCollector.TakeLog())
+	}
+
+	if cpiErr, ok := callErr.(Error); ok {
+		entry.ErrorType = cpiErr.Type()
+		entry.Error = redactor.RedactText(cpiErr.Message())
+	} else if callErr != nil {
+		entry.Error = redactor.RedactText(callErr.Error())
+	}
+
+	err := c.auditLog.Append(entry)
+	if err != nil {
+		c.logger.Warn(c.logTag, "Failed to audit CPI '%s' method: %s", method, err.Error())
+	}
+}
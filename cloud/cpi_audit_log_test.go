@@ -0,0 +1,194 @@
+package cloud_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	fakebicloud "github.com/cloudfoundry/bosh-cli/v7/cloud/fakes"
+)
+
+var _ = Describe("CPIAuditLog", func() {
+	var (
+		logger   boshlog.Logger
+		auditLog CPIAuditLog
+		path     string
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+
+		tmpDir, err := os.MkdirTemp("", "cpi-audit-log")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(func() { os.RemoveAll(tmpDir) }) //nolint:errcheck
+
+		path = filepath.Join(tmpDir, "state.json.cpi-log")
+		auditLog = NewFileCPIAuditLog(boshsys.NewOsFileSystem(logger), path)
+	})
+
+	It("appends entries as JSON lines", func() {
+		Expect(auditLog.Append(CPIAuditEntry{Method: "create_vm", CID: "fake-vm-cid"})).To(Succeed())
+		Expect(auditLog.Append(CPIAuditEntry{Method: "attach_disk", ErrorType: "Bosh::Clouds::DiskNotFound"})).To(Succeed())
+
+		contents, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(MatchRegexp(`(?m)\A\{.*"method":"create_vm".*\}\n\{.*"method":"attach_disk".*\}\n\z`))
+
+		entries, err := auditLog.Entries()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].CID).To(Equal("fake-vm-cid"))
+		Expect(entries[1].ErrorType).To(Equal("Bosh::Clouds::DiskNotFound"))
+	})
+
+	It("returns no entries if nothing was audited yet", func() {
+		entries, err := auditLog.Entries()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("returns an error for malformed lines", func() {
+		Expect(os.WriteFile(path, []byte("{}\nnot-json\n"), 0600)).To(Succeed())
+
+		_, err := auditLog.Entries()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("line 2"))
+	})
+
+	Describe("auditing cloud", func() {
+		var (
+			fakeCloud   *fakebicloud.FakeCloud
+			timeService *fakeclock.FakeClock
+			cloud       Cloud
+			startTime   time.Time
+		)
+
+		BeforeEach(func() {
+			fakeCloud = fakebicloud.NewFakeCloud()
+			startTime = time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+			timeService = fakeclock.NewFakeClock(startTime)
+
+			cloud = NewAuditingCloud(fakeCloud, auditLog, nil, false, timeService, logger)
+		})
+
+		It("audits calls with redacted arguments and the resulting CID", func() {
+			fakeCloud.CreateVMCID = "fake-vm-cid"
+
+			vmCID, err := cloud.CreateVM("fake-agent-id", "fake-stemcell-cid", biproperty.Map{"secret_key": "fake-secret-key"}, nil, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vmCID).To(Equal("fake-vm-cid"))
+			Expect(fakeCloud.CreateVMInput.CloudProperties).To(Equal(biproperty.Map{"secret_key": "fake-secret-key"}))
+
+			entries, err := auditLog.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]CPIAuditEntry{
+				{
+					Time:   startTime,
+					Method: "create_vm",
+					Arguments: []interface{}{
+						"fake-agent-id", "fake-stemcell-cid", map[string]interface{}{"secret_key": "<redacted>"}, nil, nil, nil,
+					},
+					CID: "fake-vm-cid",
+				},
+			}))
+		})
+
+		It("audits CPI errors", func() {
+			fakeCloud.DeleteVMErr = NewCPIError("delete_vm", CmdError{Type: "Bosh::Clouds::VMNotFound", Message: "fake-message"})
+
+			err := cloud.DeleteVM("fake-vm-cid")
+			Expect(err).To(HaveOccurred())
+
+			entries, err := auditLog.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries[0].Method).To(Equal("delete_vm"))
+			Expect(entries[0].Arguments).To(Equal([]interface{}{"fake-vm-cid"}))
+			Expect(entries[0].ErrorType).To(Equal("Bosh::Clouds::VMNotFound"))
+			Expect(entries[0].Error).To(Equal("fake-message"))
+			Expect(entries[0].CID).To(BeEmpty())
+		})
+
+		It("audits calls that could not be run", func() {
+			fakeCloud.InfoError = errors.New("fake-run-err")
+
+			_, err := cloud.Info()
+			Expect(err).To(HaveOccurred())
+
+			entries, err := auditLog.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries[0].Method).To(Equal("info"))
+			Expect(entries[0].Arguments).To(Equal([]interface{}{}))
+			Expect(entries[0].ErrorType).To(BeEmpty())
+			Expect(entries[0].Error).To(Equal("fake-run-err"))
+		})
+
+		It("marks replayed calls", func() {
+			cloud = NewAuditingCloud(fakeCloud, auditLog, nil, true, timeService, logger)
+
+			_, err := cloud.HasVM("fake-vm-cid")
+			Expect(err).ToNot(HaveOccurred())
+
+			entries, err := auditLog.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries[0].Method).To(Equal("has_vm"))
+			Expect(entries[0].Replayed).To(BeTrue())
+		})
+
+		It("audits redacted CPI logs of the call", func() {
+			cpiCmdRunner := fakebicloud.NewFakeCPICmdRunner()
+			cpiCmdRunner.RunCmdOutputs = []CmdOutput{
+				{Result: map[string]interface{}{"stemcell_formats": []interface{}{"fake-format"}, "api_version": float64(2)}, Log: "info log\n"},
+				{Result: "fake-disk-cid", Log: "using fake-secret-key\n"},
+			}
+
+			logCollector := NewCPILogCollector(cpiCmdRunner)
+			cloud = NewAuditingCloud(
+				NewCloud(logCollector, "fake-director-id", 2, logger), auditLog, logCollector, false, timeService, logger)
+
+			diskCID, err := cloud.CreateDisk(1024, biproperty.Map{"secret_key": "fake-secret-key"}, "fake-vm-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskCID).To(Equal("fake-disk-cid"))
+
+			entries, err := auditLog.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Method).To(Equal("create_disk"))
+			Expect(entries[0].CID).To(Equal("fake-disk-cid"))
+			Expect(entries[0].Log).To(Equal("info log\nusing <redacted>\n"))
+		})
+
+		It("audits calls of the local fake CPI", func() {
+			fs := fakesys.NewFakeFileSystem()
+			cloud, err := NewLocalFakeFactory(fs, fakeuuid.NewFakeGenerator(), "/fake-cpi", auditLog, timeService, logger).NewCloud(nil, "fake-director-id", 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = cloud.HasVM("fake-vm-cid")
+			Expect(err).ToNot(HaveOccurred())
+
+			entries, err := auditLog.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Method).To(Equal("has_vm"))
+		})
+
+		It("does not fail calls if the audit log cannot be written", func() {
+			fs := fakesys.NewFakeFileSystem()
+			fs.OpenFileErr = errors.New("fake-open-err")
+			cloud = NewAuditingCloud(fakeCloud, NewFileCPIAuditLog(fs, "/audit"), nil, false, timeService, logger)
+
+			_, err := cloud.Info()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
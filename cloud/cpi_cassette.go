@@ -0,0 +1,332 @@
+package cloud
+
+import (
+	"encoding/json"
+	"reflect"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// CPICassette is a recording of CPI and agent calls in the order they were made.
+type CPICassette struct {
+	Interactions      []CPIInteraction   `json:"interactions"`
+	AgentInteractions []AgentInteraction `json:"agent_interactions,omitempty"`
+}
+
+type CPIInteraction struct {
+	Input  CmdInput  `json:"input"`
+	Output CmdOutput `json:"output"`
+
+	// RunError is set when the CPI could not be run or did not answer in time
+	RunError string `json:"run_error,omitempty"`
+}
+
+type AgentInteraction struct {
+	Method    string        `json:"method"`
+	Arguments []interface{} `json:"arguments"`
+	Result    interface{}   `json:"result,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func LoadCPICassette(fs boshsys.FileSystem, path string) (CPICassette, error) {
+	contents, err := fs.ReadFile(path)
+	if err != nil {
+		return CPICassette{}, bosherr.WrapErrorf(err, "Reading CPI cassette '%s'", path)
+	}
+
+	var cassette CPICassette
+
+	err = json.Unmarshal(contents, &cassette)
+	if err != nil {
+		return CPICassette{}, bosherr.WrapErrorf(err, "Unmarshalling CPI cassette '%s'", path)
+	}
+
+	return cassette, nil
+}
+
+func (c CPICassette) Save(fs boshsys.FileSystem, path string) error {
+	contents, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling CPI cassette")
+	}
+
+	err = fs.WriteFile(path, contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing CPI cassette '%s'", path)
+	}
+
+	return nil
+}
+
+// CPICassetteRecorder collects the CPI and agent calls of a command into the
+// cassette at path, with credentials redacted. The cassette is written after
+// every call so that failed runs are recorded too.
+type CPICassetteRecorder struct {
+	fs       boshsys.FileSystem
+	path     string
+	redactor *CPIRedactor
+	cassette CPICassette
+	logger   boshlog.Logger
+	logTag   string
+}
+
+func NewCPICassetteRecorder(fs boshsys.FileSystem, path string, logger boshlog.Logger) *CPICassetteRecorder {
+	return &CPICassetteRecorder{
+		fs:       fs,
+		path:     path,
+		redactor: NewCPIRedactor(),
+		logger:   logger,
+		logTag:   "cpiCassetteRecorder",
+	}
+}
+
+func (r *CPICassetteRecorder) recordCPICall(context CmdContext, method string, apiVersion int, args []interface{}, cmdOutput CmdOutput, runErr error) error {
+	interaction := CPIInteraction{
+		Input: CmdInput{
+			Method:     method,
+			Arguments:  r.redactArguments(args),
+			Context:    context,
+			ApiVersion: apiVersion,
+		},
+		Output: CmdOutput{
+			Result: r.redactor.Redact(normalizeCPIValue(cmdOutput.Result)),
+			Log:    r.redactor.RedactText(cmdOutput.Log),
+		},
+	}
+
+	if cmdOutput.Error != nil {
+		cmdError := *cmdOutput.Error
+		cmdError.Message = r.redactor.RedactText(cmdError.Message)
+		interaction.Output.Error = &cmdError
+	}
+
+	if runErr != nil {
+		interaction.RunError = r.redactor.RedactText(runErr.Error())
+	}
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+
+	r.logger.Debug(r.logTag, "Recording CPI call #%d '%s' to '%s'", len(r.cassette.Interactions), method, r.path)
+
+	err := r.cassette.Save(r.fs, r.path)
+	if err != nil {
+		return bosherr.WrapError(err, "Recording CPI call")
+	}
+
+	return nil
+}
+
+func (r *CPICassetteRecorder) recordAgentCall(method string, args []interface{}, result interface{}, callErr error) error {
+	interaction := AgentInteraction{
+		Method:    method,
+		Arguments: r.redactArguments(args),
+	}
+
+	if callErr != nil {
+		interaction.Error = r.redactor.RedactText(callErr.Error())
+	} else {
+		interaction.Result = r.redactor.Redact(normalizeCPIValue(result))
+	}
+
+	r.cassette.AgentInteractions = append(r.cassette.AgentInteractions, interaction)
+
+	r.logger.Debug(r.logTag, "Recording agent call #%d '%s' to '%s'", len(r.cassette.AgentInteractions), method, r.path)
+
+	err := r.cassette.Save(r.fs, r.path)
+	if err != nil {
+		return bosherr.WrapError(err, "Recording agent call")
+	}
+
+	return nil
+}
+
+func (r *CPICassetteRecorder) redactArguments(args []interface{}) []interface{} {
+	if len(args) == 0 {
+		args = make([]interface{}, 0)
+	}
+
+	return r.redactor.RedactArguments(normalizeCPIValue(args).([]interface{}))
+}
+
+// CPICassettePlayer answers the CPI and agent calls of a command from the
+// cassette at path. Calls have to be made in the recorded order; arguments are
+// not compared since they contain generated IDs that change between runs.
+type CPICassettePlayer struct {
+	fs        boshsys.FileSystem
+	path      string
+	cassette  *CPICassette
+	nextCPI   int
+	nextAgent int
+	logger    boshlog.Logger
+	logTag    string
+}
+
+func NewCPICassettePlayer(fs boshsys.FileSystem, path string, logger boshlog.Logger) *CPICassettePlayer {
+	return &CPICassettePlayer{
+		fs:     fs,
+		path:   path,
+		logger: logger,
+		logTag: "cpiCassettePlayer",
+	}
+}
+
+func (p *CPICassettePlayer) load() error {
+	if p.cassette != nil {
+		return nil
+	}
+
+	cassette, err := LoadCPICassette(p.fs, p.path)
+	if err != nil {
+		return err
+	}
+
+	p.cassette = &cassette
+
+	return nil
+}
+
+func (p *CPICassettePlayer) replayCPICall(method string, args []interface{}) (CPIInteraction, error) {
+	err := p.load()
+	if err != nil {
+		return CPIInteraction{}, err
+	}
+
+	if p.nextCPI >= len(p.cassette.Interactions) {
+		return CPIInteraction{}, bosherr.Errorf(
+			"Replaying CPI call #%d '%s': CPI cassette '%s' only has %d calls", p.nextCPI+1, method, p.path, len(p.cassette.Interactions))
+	}
+
+	interaction := p.cassette.Interactions[p.nextCPI]
+
+	if interaction.Input.Method != method {
+		return CPIInteraction{}, bosherr.Errorf(
+			"Replaying CPI call #%d: expected method '%s' but got '%s'", p.nextCPI+1, interaction.Input.Method, method)
+	}
+
+	p.nextCPI++
+
+	if len(args) == 0 {
+		args = make([]interface{}, 0)
+	}
+
+	actualArgs := NewCPIRedactor().RedactArguments(normalizeCPIValue(args).([]interface{}))
+	if !reflect.DeepEqual(actualArgs, interaction.Input.Arguments) {
+		p.logger.Debug(p.logTag, "Arguments of CPI call #%d '%s' differ from recording:\nrecorded: %#v\nactual: %#v",
+			p.nextCPI, method, interaction.Input.Arguments, actualArgs)
+	}
+
+	p.logger.Debug(p.logTag, "Replaying CPI call #%d '%s' from '%s'", p.nextCPI, method, p.path)
+
+	return interaction, nil
+}
+
+// replayAgentCall unmarshals the recorded result into result unless the call failed.
+func (p *CPICassettePlayer) replayAgentCall(method string, result interface{}) error {
+	err := p.load()
+	if err != nil {
+		return err
+	}
+
+	if p.nextAgent >= len(p.cassette.AgentInteractions) {
+		return bosherr.Errorf(
+			"Replaying agent call #%d '%s': CPI cassette '%s' only has %d agent calls", p.nextAgent+1, method, p.path, len(p.cassette.AgentInteractions))
+	}
+
+	interaction := p.cassette.AgentInteractions[p.nextAgent]
+
+	if interaction.Method != method {
+		return bosherr.Errorf(
+			"Replaying agent call #%d: expected method '%s' but got '%s'", p.nextAgent+1, interaction.Method, method)
+	}
+
+	p.nextAgent++
+
+	p.logger.Debug(p.logTag, "Replaying agent call #%d '%s' from '%s'", p.nextAgent, method, p.path)
+
+	if interaction.Error != "" {
+		return bosherr.Error(interaction.Error)
+	}
+
+	if result == nil || interaction.Result == nil {
+		return nil
+	}
+
+	bytes, err := json.Marshal(interaction.Result)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Replaying agent call #%d '%s'", p.nextAgent, method)
+	}
+
+	err = json.Unmarshal(bytes, result)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Replaying agent call #%d '%s'", p.nextAgent, method)
+	}
+
+	return nil
+}
+
+type recordingCPICmdRunner struct {
+	cpiCmdRunner CPICmdRunner
+	recorder     *CPICassetteRecorder
+}
+
+// NewRecordingCPICmdRunner runs CPI calls with cpiCmdRunner and records each
+// request and response with recorder, including calls that could not be run.
+func NewRecordingCPICmdRunner(cpiCmdRunner CPICmdRunner, recorder *CPICassetteRecorder) CPICmdRunner {
+	return &recordingCPICmdRunner{
+		cpiCmdRunner: cpiCmdRunner,
+		recorder:     recorder,
+	}
+}
+
+func (r *recordingCPICmdRunner) Run(context CmdContext, method string, apiVersion int, args ...interface{}) (CmdOutput, error) {
+	cmdOutput, runErr := r.cpiCmdRunner.Run(context, method, apiVersion, args...)
+
+	err := r.recorder.recordCPICall(context, method, apiVersion, args, cmdOutput, runErr)
+	if err != nil && runErr == nil {
+		return cmdOutput, err
+	}
+
+	return cmdOutput, runErr
+}
+
+type replayingCPICmdRunner struct {
+	player *CPICassettePlayer
+}
+
+// NewReplayingCPICmdRunner answers CPI calls with player without running a CPI.
+func NewReplayingCPICmdRunner(player *CPICassettePlayer) CPICmdRunner {
+	return replayingCPICmdRunner{player: player}
+}
+
+func (r replayingCPICmdRunner) Run(context CmdContext, method string, apiVersion int, args ...interface{}) (CmdOutput, error) {
+	interaction, err := r.player.replayCPICall(method, args)
+	if err != nil {
+		return CmdOutput{}, err
+	}
+
+	if interaction.RunError != "" {
+		return interaction.Output, bosherr.Error(interaction.RunError)
+	}
+
+	return interaction.Output, nil
+}
+
+// normalizeCPIValue converts value to what it looks like after a JSON round trip
+// (e.g. structs into maps) so that recorded and live values can be compared and redacted.
+func normalizeCPIValue(value interface{}) interface{} {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+
+	err = json.Unmarshal(bytes, &normalized)
+	if err != nil {
+		return value
+	}
+
+	return normalized
+}
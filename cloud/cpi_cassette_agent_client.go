@@ -0,0 +1,248 @@
+package cloud
+
+import (
+	biagentclient "github.com/cloudfoundry/bosh-agent/v2/agentclient"
+	bias "github.com/cloudfoundry/bosh-agent/v2/agentclient/applyspec"
+	bihttpagent "github.com/cloudfoundry/bosh-agent/v2/agentclient/http"
+)
+
+type recordingAgentClientFactory struct {
+	agentClientFactory bihttpagent.AgentClientFactory
+	recorder           *CPICassetteRecorder
+}
+
+// NewRecordingAgentClientFactory creates agent clients that record each call
+// with recorder next to the CPI calls, since replaying the CPI alone leaves
+// nothing to answer for the agent on the created VM.
+func NewRecordingAgentClientFactory(agentClientFactory bihttpagent.AgentClientFactory, recorder *CPICassetteRecorder) bihttpagent.AgentClientFactory {
+	return recordingAgentClientFactory{agentClientFactory: agentClientFactory, recorder: recorder}
+}
+
+func (f recordingAgentClientFactory) NewAgentClient(directorID, mbusURL, caCert string) (biagentclient.AgentClient, error) {
+	agentClient, err := f.agentClientFactory.NewAgentClient(directorID, mbusURL, caCert)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordingAgentClient{agentClient: agentClient, recorder: f.recorder}, nil
+}
+
+type recordingAgentClient struct {
+	agentClient biagentclient.AgentClient
+	recorder    *CPICassetteRecorder
+}
+
+// record returns callErr, or the error of recording the call when there is none.
+func (c recordingAgentClient) record(method string, args []interface{}, result interface{}, callErr error) error {
+	err := c.recorder.recordAgentCall(method, args, result, callErr)
+	if callErr != nil {
+		return callErr
+	}
+
+	return err
+}
+
+func (c recordingAgentClient) Ping() (string, error) {
+	result, err := c.agentClient.Ping()
+	return result, c.record("ping", nil, result, err)
+}
+
+func (c recordingAgentClient) Stop() error {
+	return c.record("stop", nil, nil, c.agentClient.Stop())
+}
+
+func (c recordingAgentClient) Drain(drainType string) (int64, error) {
+	result, err := c.agentClient.Drain(drainType)
+	return result, c.record("drain", []interface{}{drainType}, result, err)
+}
+
+func (c recordingAgentClient) Apply(spec bias.ApplySpec) error {
+	return c.record("apply", []interface{}{spec}, nil, c.agentClient.Apply(spec))
+}
+
+func (c recordingAgentClient) Start() error {
+	return c.record("start", nil, nil, c.agentClient.Start())
+}
+
+func (c recordingAgentClient) GetState() (biagentclient.AgentState, error) {
+	result, err := c.agentClient.GetState()
+	return result, c.record("get_state", nil, result, err)
+}
+
+func (c recordingAgentClient) AddPersistentDisk(diskCID string, hints interface{}) error {
+	return c.record("add_persistent_disk", []interface{}{diskCID, hints}, nil, c.agentClient.AddPersistentDisk(diskCID, hints))
+}
+
+func (c recordingAgentClient) RemovePersistentDisk(diskCID string) error {
+	return c.record("remove_persistent_disk", []interface{}{diskCID}, nil, c.agentClient.RemovePersistentDisk(diskCID))
+}
+
+func (c recordingAgentClient) MountDisk(diskCID string) error {
+	return c.record("mount_disk", []interface{}{diskCID}, nil, c.agentClient.MountDisk(diskCID))
+}
+
+func (c recordingAgentClient) UnmountDisk(diskCID string) error {
+	return c.record("unmount_disk", []interface{}{diskCID}, nil, c.agentClient.UnmountDisk(diskCID))
+}
+
+func (c recordingAgentClient) ListDisk() ([]string, error) {
+	result, err := c.agentClient.ListDisk()
+	return result, c.record("list_disk", nil, result, err)
+}
+
+func (c recordingAgentClient) MigrateDisk() error {
+	return c.record("migrate_disk", nil, nil, c.agentClient.MigrateDisk())
+}
+
+func (c recordingAgentClient) CompilePackage(packageSource biagentclient.BlobRef, compiledPackageDependencies []biagentclient.BlobRef) (biagentclient.BlobRef, error) {
+	result, err := c.agentClient.CompilePackage(packageSource, compiledPackageDependencies)
+	return result, c.record("compile_package", []interface{}{packageSource, compiledPackageDependencies}, result, err)
+}
+
+func (c recordingAgentClient) DeleteARPEntries(ips []string) error {
+	return c.record("delete_arp_entries", []interface{}{ips}, nil, c.agentClient.DeleteARPEntries(ips))
+}
+
+func (c recordingAgentClient) SyncDNS(blobID, sha1 string, version uint64) (string, error) {
+	result, err := c.agentClient.SyncDNS(blobID, sha1, version)
+	return result, c.record("sync_dns", []interface{}{blobID, sha1, version}, result, err)
+}
+
+func (c recordingAgentClient) RunScript(scriptName string, options map[string]interface{}) error {
+	return c.record("run_script", []interface{}{scriptName, options}, nil, c.agentClient.RunScript(scriptName, options))
+}
+
+func (c recordingAgentClient) SetUpSSH(username string, publicKey string) (biagentclient.SSHResult, error) {
+	result, err := c.agentClient.SetUpSSH(username, publicKey)
+	return result, c.record("set_up_ssh", []interface{}{username, publicKey}, result, err)
+}
+
+func (c recordingAgentClient) CleanUpSSH(username string) (biagentclient.SSHResult, error) {
+	result, err := c.agentClient.CleanUpSSH(username)
+	return result, c.record("clean_up_ssh", []interface{}{username}, result, err)
+}
+
+func (c recordingAgentClient) BundleLogs(owningUser string, logType string, filters []string) (biagentclient.BundleLogsResult, error) {
+	result, err := c.agentClient.BundleLogs(owningUser, logType, filters)
+	return result, c.record("bundle_logs", []interface{}{owningUser, logType, filters}, result, err)
+}
+
+func (c recordingAgentClient) RemoveFile(path string) error {
+	return c.record("remove_file", []interface{}{path}, nil, c.agentClient.RemoveFile(path))
+}
+
+type replayingAgentClientFactory struct {
+	player *CPICassettePlayer
+}
+
+// NewReplayingAgentClientFactory creates agent clients that answer calls with
+// player instead of talking to an agent.
+func NewReplayingAgentClientFactory(player *CPICassettePlayer) bihttpagent.AgentClientFactory {
+	return replayingAgentClientFactory{player: player}
+}
+
+func (f replayingAgentClientFactory) NewAgentClient(_, _, _ string) (biagentclient.AgentClient, error) {
+	return replayingAgentClient{player: f.player}, nil
+}
+
+type replayingAgentClient struct {
+	player *CPICassettePlayer
+}
+
+func (c replayingAgentClient) Ping() (string, error) {
+	var result string
+	err := c.player.replayAgentCall("ping", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) Stop() error {
+	return c.player.replayAgentCall("stop", nil)
+}
+
+func (c replayingAgentClient) Drain(string) (int64, error) {
+	var result int64
+	err := c.player.replayAgentCall("drain", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) Apply(bias.ApplySpec) error {
+	return c.player.replayAgentCall("apply", nil)
+}
+
+func (c replayingAgentClient) Start() error {
+	return c.player.replayAgentCall("start", nil)
+}
+
+func (c replayingAgentClient) GetState() (biagentclient.AgentState, error) {
+	var result biagentclient.AgentState
+	err := c.player.replayAgentCall("get_state", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) AddPersistentDisk(string, interface{}) error {
+	return c.player.replayAgentCall("add_persistent_disk", nil)
+}
+
+func (c replayingAgentClient) RemovePersistentDisk(string) error {
+	return c.player.replayAgentCall("remove_persistent_disk", nil)
+}
+
+func (c replayingAgentClient) MountDisk(string) error {
+	return c.player.replayAgentCall("mount_disk", nil)
+}
+
+func (c replayingAgentClient) UnmountDisk(string) error {
+	return c.player.replayAgentCall("unmount_disk", nil)
+}
+
+func (c replayingAgentClient) ListDisk() ([]string, error) {
+	var result []string
+	err := c.player.replayAgentCall("list_disk", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) MigrateDisk() error {
+	return c.player.replayAgentCall("migrate_disk", nil)
+}
+
+func (c replayingAgentClient) CompilePackage(biagentclient.BlobRef, []biagentclient.BlobRef) (biagentclient.BlobRef, error) {
+	var result biagentclient.BlobRef
+	err := c.player.replayAgentCall("compile_package", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) DeleteARPEntries([]string) error {
+	return c.player.replayAgentCall("delete_arp_entries", nil)
+}
+
+func (c replayingAgentClient) SyncDNS(string, string, uint64) (string, error) {
+	var result string
+	err := c.player.replayAgentCall("sync_dns", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) RunScript(string, map[string]interface{}) error {
+	return c.player.replayAgentCall("run_script", nil)
+}
+
+func (c replayingAgentClient) SetUpSSH(string, string) (biagentclient.SSHResult, error) {
+	var result biagentclient.SSHResult
+	err := c.player.replayAgentCall("set_up_ssh", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) CleanUpSSH(string) (biagentclient.SSHResult, error) {
+	var result biagentclient.SSHResult
+	err := c.player.replayAgentCall("clean_up_ssh", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) BundleLogs(string, string, []string) (biagentclient.BundleLogsResult, error) {
+	var result biagentclient.BundleLogsResult
+	err := c.player.replayAgentCall("bundle_logs", &result)
+	return result, err
+}
+
+func (c replayingAgentClient) RemoveFile(string) error {
+	return c.player.replayAgentCall("remove_file", nil)
+}
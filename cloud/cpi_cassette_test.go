@@ -0,0 +1,190 @@
+package cloud_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	fakebicloud "github.com/cloudfoundry/bosh-cli/v7/cloud/fakes"
+)
+
+var _ = Describe("CPICassette", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		logger  boshlog.Logger
+		context CmdContext
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		context = CmdContext{DirectorID: "fake-director-id"}
+	})
+
+	Describe("recording CPI cmd runner", func() {
+		var (
+			cpiCmdRunner *fakebicloud.FakeCPICmdRunner
+			recorder     CPICmdRunner
+		)
+
+		BeforeEach(func() {
+			cpiCmdRunner = fakebicloud.NewFakeCPICmdRunner()
+			recorder = NewRecordingCPICmdRunner(cpiCmdRunner, NewCPICassetteRecorder(fs, "/cassette.json", logger))
+		})
+
+		It("runs the call and records it with credentials redacted", func() {
+			cpiCmdRunner.RunCmdOutputs = []CmdOutput{
+				{Result: "fake-vm-cid", Log: "created vm with fake-secret-key"},
+			}
+
+			cmdOutput, err := recorder.Run(context, "create_vm", 1, "fake-agent-id", map[string]interface{}{"secret_key": "fake-secret-key"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdOutput.Result).To(Equal("fake-vm-cid"))
+			Expect(cmdOutput.Log).To(Equal("created vm with fake-secret-key"))
+
+			cassette, err := LoadCPICassette(fs, "/cassette.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cassette.Interactions).To(Equal([]CPIInteraction{
+				{
+					Input: CmdInput{
+						Method:     "create_vm",
+						Arguments:  []interface{}{"fake-agent-id", map[string]interface{}{"secret_key": "<redacted>"}},
+						Context:    context,
+						ApiVersion: 1,
+					},
+					Output: CmdOutput{Result: "fake-vm-cid", Log: "created vm with <redacted>"},
+				},
+			}))
+
+			contents, err := fs.ReadFileString("/cassette.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).ToNot(ContainSubstring("fake-secret-key"))
+		})
+
+		It("records calls in order including CPI errors", func() {
+			cpiCmdRunner.RunCmdOutputs = []CmdOutput{
+				{Result: "fake-stemcell-cid"},
+				{Error: &CmdError{Type: "Bosh::Clouds::VMCreationFailed", Message: "fake-message", OkToRetry: true}},
+			}
+
+			_, err := recorder.Run(context, "create_stemcell", 1, "/stemcell/image", map[string]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = recorder.Run(context, "create_vm", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			cassette, err := LoadCPICassette(fs, "/cassette.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cassette.Interactions).To(HaveLen(2))
+			Expect(cassette.Interactions[0].Input.Method).To(Equal("create_stemcell"))
+			Expect(cassette.Interactions[1].Input.Method).To(Equal("create_vm"))
+			Expect(cassette.Interactions[1].Input.Arguments).To(Equal([]interface{}{}))
+			Expect(cassette.Interactions[1].Output.Error).To(Equal(
+				&CmdError{Type: "Bosh::Clouds::VMCreationFailed", Message: "fake-message", OkToRetry: true}))
+		})
+
+		It("records calls that could not be run", func() {
+			cpiCmdRunner.RunErrs = []error{errors.New("fake-run-err")}
+
+			_, err := recorder.Run(context, "create_vm", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-run-err"))
+
+			cassette, err := LoadCPICassette(fs, "/cassette.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cassette.Interactions).To(HaveLen(1))
+			Expect(cassette.Interactions[0].Input.Method).To(Equal("create_vm"))
+			Expect(cassette.Interactions[0].RunError).To(Equal("fake-run-err"))
+		})
+
+		It("returns an error if the cassette cannot be written", func() {
+			fs.WriteFileError = errors.New("fake-write-err")
+
+			_, err := recorder.Run(context, "info", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Writing CPI cassette '/cassette.json'"))
+		})
+	})
+
+	Describe("replaying CPI cmd runner", func() {
+		var (
+			replayer CPICmdRunner
+		)
+
+		BeforeEach(func() {
+			cassette := CPICassette{
+				Interactions: []CPIInteraction{
+					{
+						Input:  CmdInput{Method: "create_stemcell", Arguments: []interface{}{"/stemcell/image"}},
+						Output: CmdOutput{Result: "fake-stemcell-cid"},
+					},
+					{
+						Input:  CmdInput{Method: "create_vm", Arguments: []interface{}{"fake-agent-id"}},
+						Output: CmdOutput{Error: &CmdError{Type: "Bosh::Clouds::CloudError", Message: "fake-message"}},
+					},
+					{
+						Input:    CmdInput{Method: "delete_vm", Arguments: []interface{}{"fake-vm-cid"}},
+						RunError: "fake-run-err",
+					},
+				},
+			}
+			Expect(cassette.Save(fs, "/cassette.json")).To(Succeed())
+
+			replayer = NewReplayingCPICmdRunner(NewCPICassettePlayer(fs, "/cassette.json", logger))
+		})
+
+		It("answers calls in the recorded order", func() {
+			cmdOutput, err := replayer.Run(context, "create_stemcell", 1, "/stemcell/image", map[string]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdOutput).To(Equal(CmdOutput{Result: "fake-stemcell-cid"}))
+
+			cmdOutput, err = replayer.Run(context, "create_vm", 1, "other-agent-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdOutput.Error).To(Equal(&CmdError{Type: "Bosh::Clouds::CloudError", Message: "fake-message"}))
+		})
+
+		It("returns an error when a different method is called than recorded", func() {
+			_, err := replayer.Run(context, "create_vm", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Replaying CPI call #1: expected method 'create_stemcell' but got 'create_vm'"))
+		})
+
+		It("returns the recorded error of calls that could not be run", func() {
+			_, err := replayer.Run(context, "create_stemcell", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = replayer.Run(context, "create_vm", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = replayer.Run(context, "delete_vm", 1, "fake-vm-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-run-err"))
+		})
+
+		It("returns an error when more calls are made than recorded", func() {
+			_, err := replayer.Run(context, "create_stemcell", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = replayer.Run(context, "create_vm", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _ = replayer.Run(context, "delete_vm", 1)
+
+			_, err = replayer.Run(context, "delete_disk", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Replaying CPI call #4 'delete_disk': CPI cassette '/cassette.json' only has 3 calls"))
+		})
+
+		It("returns an error when the cassette cannot be read", func() {
+			replayer = NewReplayingCPICmdRunner(NewCPICassettePlayer(fs, "/missing.json", logger))
+
+			_, err := replayer.Run(context, "info", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading CPI cassette '/missing.json'"))
+		})
+	})
+})
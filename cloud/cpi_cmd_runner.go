@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -64,9 +65,13 @@ type CPICmdRunner interface {
 	Run(context CmdContext, method string, apiVersion int, args ...interface{}) (CmdOutput, error)
 }
 
+// cpiKillGracePeriod is how long a timed out CPI gets to exit after SIGTERM
+const cpiKillGracePeriod = 10 * time.Second
+
 type cpiCmdRunner struct {
 	cmdRunner boshsys.CmdRunner
 	cpi       CPI
+	policy    CPIRetryPolicy
 	logger    boshlog.Logger
 	logTag    string
 }
@@ -74,11 +79,13 @@ type cpiCmdRunner struct {
 func NewCPICmdRunner(
 	cmdRunner boshsys.CmdRunner,
 	cpi CPI,
+	policy CPIRetryPolicy,
 	logger boshlog.Logger,
 ) CPICmdRunner {
 	return &cpiCmdRunner{
 		cmdRunner: cmdRunner,
 		cpi:       cpi,
+		policy:    policy,
 		logger:    logger,
 		logTag:    "cpiCmdRunner",
 	}
@@ -109,7 +116,7 @@ func (r *cpiCmdRunner) Run(context CmdContext, method string, apiVersion int, ar
 		Stdin: bytes.NewReader(inputBytes),
 	}
 
-	stdout, stderr, exitCode, err := r.cmdRunner.RunComplexCommand(cmd)
+	stdout, stderr, exitCode, err := r.runCommand(cmd, method)
 	r.logger.Debug(r.logTag, "Exit Code %d when executing external CPI command '%s'\nSTDIN: '%s'\nSTDOUT: '%s'\nSTDERR: '%s'", exitCode, cmdPath, string(inputBytes), stdout, stderr)
 	if err != nil {
 		return CmdOutput{}, bosherr.WrapErrorf(err, "Executing external CPI command: '%s'", cmdPath)
@@ -125,3 +132,33 @@ func (r *cpiCmdRunner) Run(context CmdContext, method string, apiVersion int, ar
 
 	return cmdOutput, err
 }
+
+func (r *cpiCmdRunner) runCommand(cmd boshsys.Command, method string) (string, string, int, error) {
+	timeout := r.policy.TimeoutFor(method)
+	if timeout <= 0 {
+		return r.cmdRunner.RunComplexCommand(cmd)
+	}
+
+	process, err := r.cmdRunner.RunComplexCommandAsync(cmd)
+	if err != nil {
+		return "", "", -1, err
+	}
+
+	resultCh := process.Wait()
+
+	select {
+	case result := <-resultCh:
+		return result.Stdout, result.Stderr, result.ExitStatus, result.Error
+
+	case <-time.After(timeout):
+		err = process.TerminateNicely(cpiKillGracePeriod)
+		if err != nil {
+			r.logger.Warn(r.logTag, "Terminating timed out CPI '%s' method: %s", method, err.Error())
+		} else {
+			// Collect the terminated process so that nothing is left waiting on it
+			<-resultCh
+		}
+
+		return "", "", -1, bosherr.Errorf("CPI '%s' method timed out after %s", method, timeout)
+	}
+}
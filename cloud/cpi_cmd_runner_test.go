@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"time"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -35,7 +37,7 @@ var _ = Describe("CpiCmdRunner", func() {
 
 		cmdRunner = fakesys.NewFakeCmdRunner()
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		cpiCmdRunner = NewCPICmdRunner(cmdRunner, cpi, logger)
+		cpiCmdRunner = NewCPICmdRunner(cmdRunner, cpi, DefaultCPIRetryPolicy(), logger)
 
 		apiVersion = 1
 	})
@@ -188,6 +190,52 @@ var _ = Describe("CpiCmdRunner", func() {
 			})
 		})
 
+		Context("when the method has a timeout", func() {
+			BeforeEach(func() {
+				policy := DefaultCPIRetryPolicy()
+				policy.Timeouts = map[string]time.Duration{"fake-method": 10 * time.Millisecond}
+
+				cpiCmdRunner = NewCPICmdRunner(cmdRunner, cpi, policy, boshlog.NewLogger(boshlog.LevelNone))
+			})
+
+			It("returns the result if the command finishes in time", func() {
+				cmdRunner.AddProcess("/jobs/cpi/bin/cpi", &fakesys.FakeProcess{
+					WaitResult: boshsys.Result{Stdout: `{"result":"fake-cid"}`},
+				})
+
+				cmdOutput, err := cpiCmdRunner.Run(context, "fake-method", apiVersion)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdOutput.Result).To(Equal("fake-cid"))
+			})
+
+			It("terminates the command and returns an error if it runs too long", func() {
+				process := &fakesys.FakeProcess{
+					TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {
+						p.WaitCh <- boshsys.Result{ExitStatus: -1}
+					},
+				}
+				cmdRunner.AddProcess("/jobs/cpi/bin/cpi", process)
+
+				_, err := cpiCmdRunner.Run(context, "fake-method", apiVersion)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("CPI 'fake-method' method timed out after 10ms"))
+				Expect(process.TerminatedNicely).To(BeTrue())
+				Expect(process.WaitCh).To(BeEmpty())
+			})
+
+			It("does not wait for the command if it cannot be terminated", func() {
+				process := &fakesys.FakeProcess{
+					TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {},
+					TerminateNicelyErr:       errors.New("fake-terminate-err"),
+				}
+				cmdRunner.AddProcess("/jobs/cpi/bin/cpi", process)
+
+				_, err := cpiCmdRunner.Run(context, "fake-method", apiVersion)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("CPI 'fake-method' method timed out after 10ms"))
+			})
+		})
+
 		Context("when arguments passed to cmd runner is empty", func() {
 			BeforeEach(func() {
 				cmdOutput := CmdOutput{
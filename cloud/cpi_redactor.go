@@ -0,0 +1,181 @@
+package cloud
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const RedactedCPIValue = "<redacted>"
+
+var sensitiveCPIKeys = []string{
+	"password",
+	"secret",
+	"private_key",
+	"token",
+	"credentials",
+	"json_key",
+	"api_key",
+}
+
+// CPIRedactor replaces credentials found in CPI arguments and results,
+// e.g. cloud properties such as secret_access_key and agent mbus URLs.
+type CPIRedactor struct {
+	secrets map[string]struct{}
+}
+
+func NewCPIRedactor() *CPIRedactor {
+	return &CPIRedactor{secrets: map[string]struct{}{}}
+}
+
+// Redact returns a copy of value with sensitive map values and URL passwords replaced.
+// Replaced values are remembered so that they can also be scrubbed from free text.
+func (r *CPIRedactor) Redact(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		redacted := map[string]interface{}{}
+		for k, v := range typedValue {
+			redacted[k] = r.redactEntry(k, v)
+		}
+		return redacted
+
+	case map[interface{}]interface{}:
+		redacted := map[interface{}]interface{}{}
+		for k, v := range typedValue {
+			if key, ok := k.(string); ok {
+				redacted[k] = r.redactEntry(key, v)
+			} else {
+				redacted[k] = r.Redact(v)
+			}
+		}
+		return redacted
+
+	case []interface{}:
+		redacted := make([]interface{}, len(typedValue))
+		for i, v := range typedValue {
+			redacted[i] = r.Redact(v)
+		}
+		return redacted
+
+	case string:
+		return r.redactURL(typedValue)
+
+	default:
+		return value
+	}
+}
+
+// RedactArguments redacts each CPI method argument.
+func (r *CPIRedactor) RedactArguments(args []interface{}) []interface{} {
+	return r.Redact(append([]interface{}{}, args...)).([]interface{})
+}
+
+// RedactText replaces previously redacted values found in text, e.g. in CPI logs.
+func (r *CPIRedactor) RedactText(text string) string {
+	secrets := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		secrets = append(secrets, secret)
+	}
+
+	// Longer secrets first so that a secret containing another is fully replaced
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, RedactedCPIValue)
+	}
+
+	return text
+}
+
+func (r *CPIRedactor) redactEntry(key string, value interface{}) interface{} {
+	if !r.isSensitiveKey(key) {
+		return r.Redact(value)
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		r.remember(typedValue)
+		return RedactedCPIValue
+	case nil, bool:
+		return value
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		// e.g. credentials: {...}; keep the structure so that replays see the same shape
+		return r.redactAll(value)
+	default:
+		return RedactedCPIValue
+	}
+}
+
+func (r *CPIRedactor) redactAll(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		redacted := map[string]interface{}{}
+		for k, v := range typedValue {
+			redacted[k] = r.redactAll(v)
+		}
+		return redacted
+
+	case map[interface{}]interface{}:
+		redacted := map[interface{}]interface{}{}
+		for k, v := range typedValue {
+			redacted[k] = r.redactAll(v)
+		}
+		return redacted
+
+	case []interface{}:
+		redacted := make([]interface{}, len(typedValue))
+		for i, v := range typedValue {
+			redacted[i] = r.redactAll(v)
+		}
+		return redacted
+
+	case string:
+		r.remember(typedValue)
+		return RedactedCPIValue
+
+	default:
+		return value
+	}
+}
+
+func (r *CPIRedactor) redactURL(value string) string {
+	if !strings.Contains(value, "://") || !strings.Contains(value, "@") {
+		return value
+	}
+
+	parsedURL, err := url.Parse(value)
+	if err != nil || parsedURL.User == nil {
+		return value
+	}
+
+	password, found := parsedURL.User.Password()
+	if !found || password == "" {
+		return value
+	}
+
+	r.remember(password)
+
+	parsedURL.User = url.UserPassword(parsedURL.User.Username(), RedactedCPIValue)
+
+	// Avoid escaping of the placeholder so that redacted URLs stay readable
+	return strings.Replace(parsedURL.String(), url.QueryEscape(RedactedCPIValue), RedactedCPIValue, 1)
+}
+
+func (r *CPIRedactor) isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, sensitiveKey := range sensitiveCPIKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *CPIRedactor) remember(secret string) {
+	// Very short values would scrub unrelated text from logs
+	if len(secret) >= 4 {
+		r.secrets[secret] = struct{}{}
+	}
+}
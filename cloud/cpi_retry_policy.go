@@ -0,0 +1,110 @@
+package cloud
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	biuifmt "github.com/cloudfoundry/bosh-cli/v7/ui/fmt"
+)
+
+const (
+	// CPI calls are not retried unless asked for
+	DefaultCPIMaxAttempts  = 1
+	DefaultCPIRetryBackoff = 5 * time.Second
+	DefaultCPIMaxBackoff   = 1 * time.Minute
+)
+
+// CPIRetryPolicy decides how often CPI calls that fail with ok_to_retry are
+// attempted and how long a CPI call may run before it is terminated.
+type CPIRetryPolicy struct {
+	MaxAttempts int
+
+	// Backoff is doubled after every failed attempt up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Timeout applies to methods without an entry in Timeouts; zero means no timeout
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+}
+
+func DefaultCPIRetryPolicy() CPIRetryPolicy {
+	return CPIRetryPolicy{
+		MaxAttempts: DefaultCPIMaxAttempts,
+		Backoff:     DefaultCPIRetryBackoff,
+		MaxBackoff:  DefaultCPIMaxBackoff,
+	}
+}
+
+func (p CPIRetryPolicy) TimeoutFor(method string) time.Duration {
+	if timeout, found := p.Timeouts[method]; found {
+		return timeout
+	}
+
+	return p.Timeout
+}
+
+// BackoffFor returns how long to wait after the given failed attempt (starting at 1).
+func (p CPIRetryPolicy) BackoffFor(attempt int) time.Duration {
+	backoff := p.Backoff
+
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return backoff
+}
+
+type retryingCPICmdRunner struct {
+	cpiCmdRunner CPICmdRunner
+	policy       CPIRetryPolicy
+	ui           biui.UI
+	timeService  clock.Clock
+	logger       boshlog.Logger
+	logTag       string
+}
+
+// NewRetryingCPICmdRunner repeats CPI calls that fail with an error the CPI
+// marked as ok_to_retry. Each retry is reported on the current stage line.
+func NewRetryingCPICmdRunner(
+	cpiCmdRunner CPICmdRunner,
+	policy CPIRetryPolicy,
+	ui biui.UI,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) CPICmdRunner {
+	return &retryingCPICmdRunner{
+		cpiCmdRunner: cpiCmdRunner,
+		policy:       policy,
+		ui:           ui,
+		timeService:  timeService,
+		logger:       logger,
+		logTag:       "retryingCPICmdRunner",
+	}
+}
+
+func (r *retryingCPICmdRunner) Run(context CmdContext, method string, apiVersion int, args ...interface{}) (CmdOutput, error) {
+	for attempt := 1; ; attempt++ {
+		cmdOutput, err := r.cpiCmdRunner.Run(context, method, apiVersion, args...)
+		if err != nil || cmdOutput.Error == nil || !cmdOutput.Error.OkToRetry || attempt >= r.policy.MaxAttempts {
+			return cmdOutput, err
+		}
+
+		backoff := r.policy.BackoffFor(attempt)
+
+		r.logger.Warn(r.logTag, "CPI '%s' method failed with retryable error (attempt %d of %d), retrying in %s: %s",
+			method, attempt, r.policy.MaxAttempts, backoff, cmdOutput.Error)
+
+		r.ui.BeginLinef(" attempt %d of %d failed with '%s', retrying in %s...",
+			attempt, r.policy.MaxAttempts, cmdOutput.Error.Type, biuifmt.Duration(backoff))
+
+		r.timeService.Sleep(backoff)
+	}
+}
@@ -0,0 +1,120 @@
+package cloud_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	fakebicloud "github.com/cloudfoundry/bosh-cli/v7/cloud/fakes"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+type sleepRecordingClock struct {
+	clock.Clock
+	SleepCalls []time.Duration
+}
+
+func (c *sleepRecordingClock) Sleep(d time.Duration) {
+	c.SleepCalls = append(c.SleepCalls, d)
+}
+
+var _ = Describe("CPIRetryPolicy", func() {
+	Describe("TimeoutFor", func() {
+		It("prefers method timeouts over the default timeout", func() {
+			policy := CPIRetryPolicy{
+				Timeout:  30 * time.Minute,
+				Timeouts: map[string]time.Duration{"attach_disk": 5 * time.Minute},
+			}
+
+			Expect(policy.TimeoutFor("attach_disk")).To(Equal(5 * time.Minute))
+			Expect(policy.TimeoutFor("create_vm")).To(Equal(30 * time.Minute))
+		})
+	})
+
+	Describe("BackoffFor", func() {
+		It("doubles the backoff up to the max backoff", func() {
+			policy := CPIRetryPolicy{Backoff: 5 * time.Second, MaxBackoff: time.Minute}
+
+			Expect(policy.BackoffFor(1)).To(Equal(5 * time.Second))
+			Expect(policy.BackoffFor(2)).To(Equal(10 * time.Second))
+			Expect(policy.BackoffFor(4)).To(Equal(40 * time.Second))
+			Expect(policy.BackoffFor(5)).To(Equal(time.Minute))
+			Expect(policy.BackoffFor(100)).To(Equal(time.Minute))
+		})
+	})
+
+	Describe("retrying CPI cmd runner", func() {
+		var (
+			cpiCmdRunner *fakebicloud.FakeCPICmdRunner
+			ui           *fakeui.FakeUI
+			timeService  *sleepRecordingClock
+			runner       CPICmdRunner
+			context      CmdContext
+
+			retryableErr = &CmdError{Type: "Bosh::Clouds::CloudError", Message: "fake-rate-limited", OkToRetry: true}
+		)
+
+		BeforeEach(func() {
+			cpiCmdRunner = fakebicloud.NewFakeCPICmdRunner()
+			ui = &fakeui.FakeUI{}
+			timeService = &sleepRecordingClock{}
+			context = CmdContext{DirectorID: "fake-director-id"}
+
+			policy := CPIRetryPolicy{MaxAttempts: 3, Backoff: 5 * time.Second, MaxBackoff: time.Minute}
+			runner = NewRetryingCPICmdRunner(cpiCmdRunner, policy, ui, timeService, boshlog.NewLogger(boshlog.LevelNone))
+		})
+
+		It("retries errors marked ok_to_retry and reports every retry", func() {
+			cpiCmdRunner.RunCmdOutputs = []CmdOutput{
+				{Error: retryableErr},
+				{Error: retryableErr},
+				{Result: "fake-vm-cid"},
+			}
+
+			cmdOutput, err := runner.Run(context, "create_vm", 2, "fake-agent-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdOutput.Result).To(Equal("fake-vm-cid"))
+
+			Expect(cpiCmdRunner.CurrentRunInput).To(HaveLen(3))
+			Expect(timeService.SleepCalls).To(Equal([]time.Duration{5 * time.Second, 10 * time.Second}))
+			Expect(ui.Said).To(Equal([]string{
+				" attempt 1 of 3 failed with 'Bosh::Clouds::CloudError', retrying in 00:00:05...",
+				" attempt 2 of 3 failed with 'Bosh::Clouds::CloudError', retrying in 00:00:10...",
+			}))
+		})
+
+		It("returns the last error once attempts are used up", func() {
+			cpiCmdRunner.RunCmdOutputs = []CmdOutput{{Error: retryableErr}}
+
+			cmdOutput, err := runner.Run(context, "attach_disk", 2, "fake-vm-cid", "fake-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdOutput.Error).To(Equal(retryableErr))
+			Expect(cpiCmdRunner.CurrentRunInput).To(HaveLen(3))
+			Expect(timeService.SleepCalls).To(HaveLen(2))
+		})
+
+		It("does not retry errors not marked ok_to_retry", func() {
+			cmdError := &CmdError{Type: "Bosh::Clouds::VMCreationFailed", Message: "fake-message"}
+			cpiCmdRunner.RunCmdOutputs = []CmdOutput{{Error: cmdError}}
+
+			cmdOutput, err := runner.Run(context, "create_vm", 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdOutput.Error).To(Equal(cmdError))
+			Expect(cpiCmdRunner.CurrentRunInput).To(HaveLen(1))
+			Expect(ui.Said).To(BeEmpty())
+		})
+
+		It("does not retry calls that could not be run", func() {
+			cpiCmdRunner.RunErrs = []error{errors.New("fake-run-err")}
+
+			_, err := runner.Run(context, "create_vm", 2)
+			Expect(err).To(HaveOccurred())
+			Expect(cpiCmdRunner.CurrentRunInput).To(HaveLen(1))
+		})
+	})
+})
@@ -1,36 +1,100 @@
 package cloud
 
 import (
+	"code.cloudfoundry.org/clock"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 
 	biinstall "github.com/cloudfoundry/bosh-cli/v7/installation"
+	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
 )
 
 type Factory interface {
 	NewCloud(installation biinstall.Installation, directorID string, stemcellApiVersion int) (Cloud, error)
 }
 
+// CPICassetteConfig optionally records CPI and agent calls to, or replays them from, a cassette file.
+type CPICassetteConfig struct {
+	RecordPath string
+	ReplayPath string
+}
+
 type factory struct {
-	fs        boshsys.FileSystem
-	cmdRunner boshsys.CmdRunner
-	logger    boshlog.Logger
+	fs          boshsys.FileSystem
+	cmdRunner   boshsys.CmdRunner
+	recorder    *CPICassetteRecorder
+	player      *CPICassettePlayer
+	retryPolicy CPIRetryPolicy
+	auditLog    CPIAuditLog
+	ui          biui.UI
+	timeService clock.Clock
+	logger      boshlog.Logger
 }
 
 func NewFactory(
 	fs boshsys.FileSystem,
 	cmdRunner boshsys.CmdRunner,
+	recorder *CPICassetteRecorder,
+	player *CPICassettePlayer,
+	retryPolicy CPIRetryPolicy,
+	auditLog CPIAuditLog,
+	ui biui.UI,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) Factory {
 	return &factory{
-		fs:        fs,
-		cmdRunner: cmdRunner,
-		logger:    logger,
+		fs:          fs,
+		cmdRunner:   cmdRunner,
+		recorder:    recorder,
+		player:      player,
+		retryPolicy: retryPolicy,
+		auditLog:    auditLog,
+		ui:          ui,
+		timeService: timeService,
+		logger:      logger,
 	}
 }
 
+// NewCloud answers CPI calls from the cassette player when there is one,
+// in which case the installation does not need to contain a CPI.
 func (f *factory) NewCloud(installation biinstall.Installation, directorID string, stemcellApiVersion int) (Cloud, error) {
+	var cpiCmdRunner CPICmdRunner
+
+	if f.player != nil {
+		cpiCmdRunner = NewReplayingCPICmdRunner(f.player)
+	} else {
+		cpi, err := f.findCPI(installation)
+		if err != nil {
+			return nil, err
+		}
+
+		cpiCmdRunner = NewCPICmdRunner(f.cmdRunner, cpi, f.retryPolicy, f.logger)
+	}
+
+	var logCollector *CPILogCollector
+
+	if f.auditLog != nil {
+		logCollector = NewCPILogCollector(cpiCmdRunner)
+		cpiCmdRunner = logCollector
+	}
+
+	if f.recorder != nil {
+		cpiCmdRunner = NewRecordingCPICmdRunner(cpiCmdRunner, f.recorder)
+	}
+
+	cpiCmdRunner = NewRetryingCPICmdRunner(cpiCmdRunner, f.retryPolicy, f.ui, f.timeService, f.logger)
+
+	cloud := NewCloud(cpiCmdRunner, directorID, stemcellApiVersion, f.logger)
+
+	if f.auditLog != nil {
+		cloud = NewAuditingCloud(cloud, f.auditLog, logCollector, f.player != nil, f.timeService, f.logger)
+	}
+
+	return cloud, nil
+}
+
+func (f *factory) findCPI(installation biinstall.Installation) (CPI, error) {
 	numberCpiBinariesFound := 0
 	foundCPI := CPI{}
 
@@ -50,9 +114,8 @@ func (f *factory) NewCloud(installation biinstall.Installation, directorID strin
 	}
 
 	if numberCpiBinariesFound != 1 {
-		return nil, bosherr.Errorf("Found %d Jobs with a 'bin/cpi' binary. Expected 1.", numberCpiBinariesFound)
+		return CPI{}, bosherr.Errorf("Found %d Jobs with a 'bin/cpi' binary. Expected 1.", numberCpiBinariesFound)
 	}
 
-	cpiCmdRunner := NewCPICmdRunner(f.cmdRunner, foundCPI, f.logger)
-	return NewCloud(cpiCmdRunner, directorID, stemcellApiVersion, f.logger), nil
+	return foundCPI, nil
 }
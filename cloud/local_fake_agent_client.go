@@ -0,0 +1,298 @@
+package cloud
+
+import (
+	"net/url"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/v2/agentclient"
+	bias "github.com/cloudfoundry/bosh-agent/v2/agentclient/applyspec"
+	bihttpagent "github.com/cloudfoundry/bosh-agent/v2/agentclient/http"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// LocalFakeAgent is what the agent on a local fake VM knows about itself.
+type LocalFakeAgent struct {
+	Stopped         bool                   `json:"stopped"`
+	Spec            *bias.ApplySpec        `json:"spec,omitempty"`
+	PersistentDisks map[string]interface{} `json:"persistent_disks,omitempty"`
+	MountedDiskCIDs []string               `json:"mounted_disk_cids,omitempty"`
+}
+
+type localFakeAgentClient struct {
+	cloud   localFakeCloud
+	mbusURL string
+}
+
+// NewLocalFakeAgentClient answers for the agent of the local fake VM that is
+// reachable at mbusURL, i.e. the one with the mbus host as a network IP, or
+// the only VM when none matches. It keeps its state next to the VM record so
+// that the agent is gone together with its VM.
+func NewLocalFakeAgentClient(
+	fs boshsys.FileSystem,
+	dir string,
+	mbusURL string,
+	logger boshlog.Logger,
+) biagentclient.AgentClient {
+	return localFakeAgentClient{
+		cloud: localFakeCloud{
+			fs:     fs,
+			dir:    dir,
+			logger: logger,
+			logTag: "localFakeAgentClient",
+		},
+		mbusURL: mbusURL,
+	}
+}
+
+func (c localFakeAgentClient) Ping() (string, error) {
+	_, _, err := c.find()
+	if err != nil {
+		return "", err
+	}
+
+	return "pong", nil
+}
+
+func (c localFakeAgentClient) Stop() error {
+	return c.update("stop", func(agent *LocalFakeAgent, _ LocalFakeVM) error {
+		agent.Stopped = true
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) Drain(string) (int64, error) {
+	_, _, err := c.find()
+	return 0, err
+}
+
+func (c localFakeAgentClient) Apply(spec bias.ApplySpec) error {
+	return c.update("apply", func(agent *LocalFakeAgent, _ LocalFakeVM) error {
+		agent.Spec = &spec
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) Start() error {
+	return c.update("start", func(agent *LocalFakeAgent, _ LocalFakeVM) error {
+		agent.Stopped = false
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) GetState() (biagentclient.AgentState, error) {
+	_, vm, err := c.find()
+	if err != nil {
+		return biagentclient.AgentState{}, err
+	}
+
+	agentState := biagentclient.AgentState{
+		JobState:     "running",
+		NetworkSpecs: map[string]biagentclient.NetworkSpec{},
+	}
+
+	if vm.Agent.Stopped {
+		agentState.JobState = "stopped"
+	}
+
+	for name, network := range vm.Networks {
+		ip, _ := network["ip"].(string)
+		agentState.NetworkSpecs[name] = biagentclient.NetworkSpec{IP: ip}
+	}
+
+	return agentState, nil
+}
+
+func (c localFakeAgentClient) AddPersistentDisk(diskCID string, diskHint interface{}) error {
+	return c.update("add_persistent_disk", func(agent *LocalFakeAgent, vm LocalFakeVM) error {
+		if !c.attached(vm, diskCID) {
+			return bosherr.Errorf("Disk '%s' is not attached to the VM", diskCID)
+		}
+
+		if agent.PersistentDisks == nil {
+			agent.PersistentDisks = map[string]interface{}{}
+		}
+		agent.PersistentDisks[diskCID] = diskHint
+
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) RemovePersistentDisk(diskCID string) error {
+	return c.update("remove_persistent_disk", func(agent *LocalFakeAgent, _ LocalFakeVM) error {
+		delete(agent.PersistentDisks, diskCID)
+		agent.MountedDiskCIDs = c.without(agent.MountedDiskCIDs, diskCID)
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) MountDisk(diskCID string) error {
+	return c.update("mount_disk", func(agent *LocalFakeAgent, vm LocalFakeVM) error {
+		if _, found := agent.PersistentDisks[diskCID]; !found || !c.attached(vm, diskCID) {
+			return bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCID)
+		}
+
+		agent.MountedDiskCIDs = append(c.without(agent.MountedDiskCIDs, diskCID), diskCID)
+
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) UnmountDisk(diskCID string) error {
+	return c.update("unmount_disk", func(agent *LocalFakeAgent, _ LocalFakeVM) error {
+		agent.MountedDiskCIDs = c.without(agent.MountedDiskCIDs, diskCID)
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) ListDisk() ([]string, error) {
+	_, vm, err := c.find()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{}, vm.Agent.MountedDiskCIDs...), nil
+}
+
+func (c localFakeAgentClient) MigrateDisk() error {
+	_, _, err := c.find()
+	return err
+}
+
+// CompilePackage hands back the package source as its compiled package
+// since nothing can run on a local fake VM.
+func (c localFakeAgentClient) CompilePackage(packageSource biagentclient.BlobRef, _ []biagentclient.BlobRef) (biagentclient.BlobRef, error) {
+	_, _, err := c.find()
+	if err != nil {
+		return biagentclient.BlobRef{}, err
+	}
+
+	return packageSource, nil
+}
+
+func (c localFakeAgentClient) DeleteARPEntries([]string) error {
+	return nil
+}
+
+func (c localFakeAgentClient) SyncDNS(string, string, uint64) (string, error) {
+	return "synced", nil
+}
+
+func (c localFakeAgentClient) RunScript(string, map[string]interface{}) error {
+	_, _, err := c.find()
+	return err
+}
+
+func (c localFakeAgentClient) SetUpSSH(string, string) (biagentclient.SSHResult, error) {
+	return biagentclient.SSHResult{}, c.unsupportedErr("ssh")
+}
+
+func (c localFakeAgentClient) CleanUpSSH(string) (biagentclient.SSHResult, error) {
+	return biagentclient.SSHResult{}, c.unsupportedErr("ssh")
+}
+
+func (c localFakeAgentClient) BundleLogs(string, string, []string) (biagentclient.BundleLogsResult, error) {
+	return biagentclient.BundleLogsResult{}, c.unsupportedErr("fetch_logs")
+}
+
+func (c localFakeAgentClient) RemoveFile(string) error {
+	return nil
+}
+
+func (c localFakeAgentClient) update(method string, fn func(*LocalFakeAgent, LocalFakeVM) error) error {
+	return c.cloud.update(method, func(state *localFakeCloudState) error {
+		vmCID, err := c.findIn(*state)
+		if err != nil {
+			return err
+		}
+
+		vm := state.VMs[vmCID]
+
+		err = fn(&vm.Agent, vm)
+		if err != nil {
+			return err
+		}
+
+		state.VMs[vmCID] = vm
+
+		return nil
+	})
+}
+
+func (c localFakeAgentClient) find() (string, LocalFakeVM, error) {
+	state, err := c.cloud.load()
+	if err != nil {
+		return "", LocalFakeVM{}, err
+	}
+
+	vmCID, err := c.findIn(state)
+	if err != nil {
+		return "", LocalFakeVM{}, err
+	}
+
+	return vmCID, state.VMs[vmCID], nil
+}
+
+func (c localFakeAgentClient) findIn(state localFakeCloudState) (string, error) {
+	parsedURL, err := url.Parse(c.mbusURL)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Parsing Mbus URL")
+	}
+
+	host := parsedURL.Hostname()
+
+	for vmCID, vm := range state.VMs {
+		for _, network := range vm.Networks {
+			if ip, _ := network["ip"].(string); ip != "" && ip == host {
+				return vmCID, nil
+			}
+		}
+	}
+
+	if len(state.VMs) == 1 {
+		for vmCID := range state.VMs {
+			return vmCID, nil
+		}
+	}
+
+	return "", bosherr.Errorf("No local fake VM is reachable at '%s'", host)
+}
+
+func (c localFakeAgentClient) attached(vm LocalFakeVM, diskCID string) bool {
+	for _, attachedDiskCID := range vm.DiskCIDs {
+		if attachedDiskCID == diskCID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c localFakeAgentClient) without(diskCIDs []string, diskCID string) []string {
+	remaining := []string{}
+	for _, otherDiskCID := range diskCIDs {
+		if otherDiskCID != diskCID {
+			remaining = append(remaining, otherDiskCID)
+		}
+	}
+	return remaining
+}
+
+func (c localFakeAgentClient) unsupportedErr(action string) error {
+	return bosherr.Errorf("The local fake agent does not support '%s'", action)
+}
+
+type localFakeAgentClientFactory struct {
+	fs     boshsys.FileSystem
+	dir    string
+	logger boshlog.Logger
+}
+
+// NewLocalFakeAgentClientFactory creates agent clients for the VMs of the
+// local fake clouds that share the records in dir.
+func NewLocalFakeAgentClientFactory(fs boshsys.FileSystem, dir string, logger boshlog.Logger) bihttpagent.AgentClientFactory {
+	return localFakeAgentClientFactory{fs: fs, dir: dir, logger: logger}
+}
+
+func (f localFakeAgentClientFactory) NewAgentClient(_, mbusURL, _ string) (biagentclient.AgentClient, error) {
+	return NewLocalFakeAgentClient(f.fs, f.dir, mbusURL, f.logger), nil
+}
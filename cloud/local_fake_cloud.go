@@ -0,0 +1,452 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+
+	biinstall "github.com/cloudfoundry/bosh-cli/v7/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-cli/v7/installation/manifest"
+)
+
+// LocalFakeCPIName selects the local fake CPI, e.g. via create-env --cpi=fake
+// or cloud_provider.cpi in the manifest.
+const LocalFakeCPIName = biinstallmanifest.LocalFakeCPI
+
+const localFakeCloudStateFile = "cloud.json"
+
+type localFakeCloudState struct {
+	Stemcells map[string]LocalFakeStemcell `json:"stemcells"`
+	VMs       map[string]LocalFakeVM       `json:"vms"`
+	Disks     map[string]LocalFakeDisk     `json:"disks"`
+}
+
+type LocalFakeStemcell struct {
+	ImagePath       string         `json:"image_path"`
+	CloudProperties biproperty.Map `json:"cloud_properties"`
+}
+
+type LocalFakeVM struct {
+	AgentID         string                    `json:"agent_id"`
+	StemcellCID     string                    `json:"stemcell_cid"`
+	CloudProperties biproperty.Map            `json:"cloud_properties"`
+	Networks        map[string]biproperty.Map `json:"networks"`
+	Env             biproperty.Map            `json:"env"`
+	DiskCIDs        []string                  `json:"disk_cids"`
+	Metadata        VMMetadata                `json:"metadata"`
+	Agent           LocalFakeAgent            `json:"agent"`
+}
+
+type LocalFakeDisk struct {
+	Size            int            `json:"size"`
+	CloudProperties biproperty.Map `json:"cloud_properties"`
+	VMCID           string         `json:"vm_cid"`
+	Metadata        DiskMetadata   `json:"metadata"`
+}
+
+type localFakeCloud struct {
+	fs            boshsys.FileSystem
+	uuidGenerator boshuuid.Generator
+	dir           string
+	logger        boshlog.Logger
+	logTag        string
+}
+
+// NewLocalFakeCloud is an in-process Cloud that keeps stemcells, VMs and disks
+// as records in dir instead of creating them on an IaaS. It answers like a CPI
+// would, e.g. with VMNotFound and DiskNotFound errors for unknown CIDs, so that
+// deployment and disk migration logic can be exercised without one.
+func NewLocalFakeCloud(
+	fs boshsys.FileSystem,
+	uuidGenerator boshuuid.Generator,
+	dir string,
+	logger boshlog.Logger,
+) Cloud {
+	return localFakeCloud{
+		fs:            fs,
+		uuidGenerator: uuidGenerator,
+		dir:           dir,
+		logger:        logger,
+		logTag:        "localFakeCloud",
+	}
+}
+
+func (c localFakeCloud) CreateStemcell(imagePath string, cloudProperties biproperty.Map) (string, error) {
+	var stemcellCID string
+
+	err := c.update("create_stemcell", func(state *localFakeCloudState) error {
+		cid, err := c.newCID("stemcell")
+		if err != nil {
+			return err
+		}
+
+		stemcellCID = cid
+		state.Stemcells[stemcellCID] = LocalFakeStemcell{ImagePath: imagePath, CloudProperties: cloudProperties}
+
+		return nil
+	})
+
+	return stemcellCID, err
+}
+
+func (c localFakeCloud) DeleteStemcell(stemcellCID string) error {
+	return c.update("delete_stemcell", func(state *localFakeCloudState) error {
+		if _, found := state.Stemcells[stemcellCID]; !found {
+			return c.notFoundErr("delete_stemcell", StemcellNotFoundError, "Stemcell", stemcellCID)
+		}
+
+		delete(state.Stemcells, stemcellCID)
+
+		return nil
+	})
+}
+
+func (c localFakeCloud) HasVM(vmCID string) (bool, error) {
+	state, err := c.load()
+	if err != nil {
+		return false, err
+	}
+
+	_, found := state.VMs[vmCID]
+
+	return found, nil
+}
+
+func (c localFakeCloud) CreateVM(
+	agentID string,
+	stemcellCID string,
+	cloudProperties biproperty.Map,
+	diskCIDs []string,
+	networksInterfaces map[string]biproperty.Map,
+	env biproperty.Map,
+) (string, error) {
+	var vmCID string
+
+	err := c.update("create_vm", func(state *localFakeCloudState) error {
+		if _, found := state.Stemcells[stemcellCID]; !found {
+			return c.notFoundErr("create_vm", StemcellNotFoundError, "Stemcell", stemcellCID)
+		}
+
+		for _, diskCID := range diskCIDs {
+			if _, found := state.Disks[diskCID]; !found {
+				return c.notFoundErr("create_vm", DiskNotFoundError, "Disk", diskCID)
+			}
+		}
+
+		cid, err := c.newCID("vm")
+		if err != nil {
+			return err
+		}
+
+		vmCID = cid
+		state.VMs[vmCID] = LocalFakeVM{
+			AgentID:         agentID,
+			StemcellCID:     stemcellCID,
+			CloudProperties: cloudProperties,
+			Networks:        networksInterfaces,
+			Env:             env,
+			DiskCIDs:        []string{},
+			Metadata:        VMMetadata{},
+		}
+
+		return nil
+	})
+
+	return vmCID, err
+}
+
+func (c localFakeCloud) SetVMMetadata(vmCID string, metadata VMMetadata) error {
+	return c.update("set_vm_metadata", func(state *localFakeCloudState) error {
+		vm, found := state.VMs[vmCID]
+		if !found {
+			return c.notFoundErr("set_vm_metadata", VMNotFoundError, "VM", vmCID)
+		}
+
+		vm.Metadata = metadata
+		state.VMs[vmCID] = vm
+
+		return nil
+	})
+}
+
+func (c localFakeCloud) SetDiskMetadata(diskCID string, metadata DiskMetadata) error {
+	return c.update("set_disk_metadata", func(state *localFakeCloudState) error {
+		disk, found := state.Disks[diskCID]
+		if !found {
+			return c.notFoundErr("set_disk_metadata", DiskNotFoundError, "Disk", diskCID)
+		}
+
+		disk.Metadata = metadata
+		state.Disks[diskCID] = disk
+
+		return nil
+	})
+}
+
+func (c localFakeCloud) DeleteVM(vmCID string) error {
+	return c.update("delete_vm", func(state *localFakeCloudState) error {
+		vm, found := state.VMs[vmCID]
+		if !found {
+			return c.notFoundErr("delete_vm", VMNotFoundError, "VM", vmCID)
+		}
+
+		// Like most IaaSes persistent disks survive their VM and become detached
+		for _, diskCID := range vm.DiskCIDs {
+			if disk, found := state.Disks[diskCID]; found {
+				disk.VMCID = ""
+				state.Disks[diskCID] = disk
+			}
+		}
+
+		delete(state.VMs, vmCID)
+
+		return nil
+	})
+}
+
+func (c localFakeCloud) CreateDisk(size int, cloudProperties biproperty.Map, vmCID string) (string, error) {
+	var diskCID string
+
+	err := c.update("create_disk", func(state *localFakeCloudState) error {
+		if _, found := state.VMs[vmCID]; vmCID != "" && !found {
+			return c.notFoundErr("create_disk", VMNotFoundError, "VM", vmCID)
+		}
+
+		cid, err := c.newCID("disk")
+		if err != nil {
+			return err
+		}
+
+		diskCID = cid
+		state.Disks[diskCID] = LocalFakeDisk{Size: size, CloudProperties: cloudProperties, Metadata: DiskMetadata{}}
+
+		return nil
+	})
+
+	return diskCID, err
+}
+
+func (c localFakeCloud) AttachDisk(vmCID, diskCID string) (interface{}, error) {
+	var diskHint interface{}
+
+	err := c.update("attach_disk", func(state *localFakeCloudState) error {
+		vm, found := state.VMs[vmCID]
+		if !found {
+			return c.notFoundErr("attach_disk", VMNotFoundError, "VM", vmCID)
+		}
+
+		disk, found := state.Disks[diskCID]
+		if !found {
+			return c.notFoundErr("attach_disk", DiskNotFoundError, "Disk", diskCID)
+		}
+
+		if disk.VMCID != "" && disk.VMCID != vmCID {
+			return NewCPIError("attach_disk", CmdError{
+				Type:    "Bosh::Clouds::CloudError",
+				Message: fmt.Sprintf("Disk '%s' is already attached to VM '%s'", diskCID, disk.VMCID),
+			})
+		}
+
+		// Disks take the next device after the ones already attached
+		device := len(vm.DiskCIDs)
+
+		if disk.VMCID == vmCID {
+			for i, attachedDiskCID := range vm.DiskCIDs {
+				if attachedDiskCID == diskCID {
+					device = i
+				}
+			}
+		} else {
+			vm.DiskCIDs = append(vm.DiskCIDs, diskCID)
+			state.VMs[vmCID] = vm
+
+			disk.VMCID = vmCID
+			state.Disks[diskCID] = disk
+		}
+
+		diskHint = fmt.Sprintf("/dev/sd%c", 'b'+device)
+
+		return nil
+	})
+
+	return diskHint, err
+}
+
+func (c localFakeCloud) DetachDisk(vmCID, diskCID string) error {
+	return c.update("detach_disk", func(state *localFakeCloudState) error {
+		vm, found := state.VMs[vmCID]
+		if !found {
+			return c.notFoundErr("detach_disk", VMNotFoundError, "VM", vmCID)
+		}
+
+		disk, found := state.Disks[diskCID]
+		if !found || disk.VMCID != vmCID {
+			return c.notFoundErr("detach_disk", DiskNotFoundError, "Disk", diskCID)
+		}
+
+		diskCIDs := []string{}
+		for _, attachedDiskCID := range vm.DiskCIDs {
+			if attachedDiskCID != diskCID {
+				diskCIDs = append(diskCIDs, attachedDiskCID)
+			}
+		}
+
+		vm.DiskCIDs = diskCIDs
+		state.VMs[vmCID] = vm
+
+		disk.VMCID = ""
+		state.Disks[diskCID] = disk
+
+		return nil
+	})
+}
+
+func (c localFakeCloud) DeleteDisk(diskCID string) error {
+	return c.update("delete_disk", func(state *localFakeCloudState) error {
+		disk, found := state.Disks[diskCID]
+		if !found {
+			return c.notFoundErr("delete_disk", DiskNotFoundError, "Disk", diskCID)
+		}
+
+		if disk.VMCID != "" {
+			return NewCPIError("delete_disk", CmdError{
+				Type:    "Bosh::Clouds::CloudError",
+				Message: fmt.Sprintf("Disk '%s' is attached to VM '%s'", diskCID, disk.VMCID),
+			})
+		}
+
+		delete(state.Disks, diskCID)
+
+		return nil
+	})
+}
+
+func (c localFakeCloud) Info() (CpiInfo, error) {
+	return CpiInfo{StemcellFormats: []string{"warden-tar", "general-tar"}, ApiVersion: MaxCpiApiVersionSupported}, nil
+}
+
+func (c localFakeCloud) String() string {
+	return fmt.Sprintf("LocalFakeCloud{Dir=%s}", c.dir)
+}
+
+func (c localFakeCloud) update(method string, fn func(*localFakeCloudState) error) error {
+	c.logger.Debug(c.logTag, "Calling '%s'", method)
+
+	state, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&state)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling local fake cloud state")
+	}
+
+	err = c.fs.WriteFile(c.statePath(), contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing local fake cloud state '%s'", c.statePath())
+	}
+
+	return nil
+}
+
+func (c localFakeCloud) load() (localFakeCloudState, error) {
+	state := localFakeCloudState{}
+
+	if c.fs.FileExists(c.statePath()) {
+		contents, err := c.fs.ReadFile(c.statePath())
+		if err != nil {
+			return state, bosherr.WrapErrorf(err, "Reading local fake cloud state '%s'", c.statePath())
+		}
+
+		err = json.Unmarshal(contents, &state)
+		if err != nil {
+			return state, bosherr.WrapErrorf(err, "Unmarshalling local fake cloud state '%s'", c.statePath())
+		}
+	}
+
+	if state.Stemcells == nil {
+		state.Stemcells = map[string]LocalFakeStemcell{}
+	}
+	if state.VMs == nil {
+		state.VMs = map[string]LocalFakeVM{}
+	}
+	if state.Disks == nil {
+		state.Disks = map[string]LocalFakeDisk{}
+	}
+
+	return state, nil
+}
+
+func (c localFakeCloud) statePath() string {
+	return filepath.Join(c.dir, localFakeCloudStateFile)
+}
+
+func (c localFakeCloud) newCID(prefix string) (string, error) {
+	uuid, err := c.uuidGenerator.Generate()
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Generating %s CID", prefix)
+	}
+
+	return fmt.Sprintf("%s-%s", prefix, uuid), nil
+}
+
+func (c localFakeCloud) notFoundErr(method, errType, kind, cid string) error {
+	return NewCPIError(method, CmdError{Type: errType, Message: fmt.Sprintf("%s '%s' not found", kind, cid)})
+}
+
+type localFakeFactory struct {
+	fs            boshsys.FileSystem
+	uuidGenerator boshuuid.Generator
+	dir           string
+	auditLog      CPIAuditLog
+	timeService   clock.Clock
+	logger        boshlog.Logger
+}
+
+// NewLocalFakeFactory creates local fake clouds that share the records in dir
+// regardless of the installed CPI release. Calls are audited when auditLog is not nil.
+func NewLocalFakeFactory(
+	fs boshsys.FileSystem,
+	uuidGenerator boshuuid.Generator,
+	dir string,
+	auditLog CPIAuditLog,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Factory {
+	return localFakeFactory{
+		fs:            fs,
+		uuidGenerator: uuidGenerator,
+		dir:           dir,
+		auditLog:      auditLog,
+		timeService:   timeService,
+		logger:        logger,
+	}
+}
+
+func (f localFakeFactory) NewCloud(_ biinstall.Installation, _ string, _ int) (Cloud, error) {
+	err := f.fs.MkdirAll(f.dir, 0700)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Creating local fake cloud directory '%s'", f.dir)
+	}
+
+	cloud := NewLocalFakeCloud(f.fs, f.uuidGenerator, f.dir, f.logger)
+
+	if f.auditLog != nil {
+		cloud = NewAuditingCloud(cloud, f.auditLog, nil, false, f.timeService, f.logger)
+	}
+
+	return cloud, nil
+}
@@ -0,0 +1,171 @@
+package cloud_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cloud"
+)
+
+var _ = Describe("LocalFakeCloud", func() {
+	var (
+		fs            *fakesys.FakeFileSystem
+		uuidGenerator *fakeuuid.FakeGenerator
+		logger        boshlog.Logger
+		cloud         Cloud
+		stemcellCID   string
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		uuidGenerator = fakeuuid.NewFakeGenerator()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+
+		var err error
+		cloud, err = NewLocalFakeFactory(fs, uuidGenerator, "/fake-cpi", nil, nil, logger).NewCloud(nil, "fake-director-id", 2)
+		Expect(err).ToNot(HaveOccurred())
+
+		stemcellCID, err = cloud.CreateStemcell("/stemcell/image", biproperty.Map{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	expectCPIError := func(err error, errType string) {
+		Expect(err).To(HaveOccurred())
+		cloudErr, ok := err.(Error)
+		Expect(ok).To(BeTrue())
+		Expect(cloudErr.Type()).To(Equal(errType))
+	}
+
+	It("reports CPI API v2", func() {
+		cpiInfo, err := cloud.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cpiInfo.ApiVersion).To(Equal(MaxCpiApiVersionSupported))
+	})
+
+	It("keeps records across clouds using the same directory", func() {
+		vmCID, err := cloud.CreateVM("fake-agent-id", stemcellCID, biproperty.Map{}, nil, nil, biproperty.Map{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(vmCID).To(Equal("vm-fake-uuid-1"))
+		Expect(fs.FileExists("/fake-cpi/cloud.json")).To(BeTrue())
+
+		otherCloud := NewLocalFakeCloud(fs, uuidGenerator, "/fake-cpi", logger)
+
+		found, err := otherCloud.HasVM(vmCID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+
+		found, err = otherCloud.HasVM("unknown-vm-cid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	Describe("VMs", func() {
+		It("requires an existing stemcell", func() {
+			_, err := cloud.CreateVM("fake-agent-id", "unknown-stemcell-cid", biproperty.Map{}, nil, nil, biproperty.Map{})
+			expectCPIError(err, StemcellNotFoundError)
+		})
+
+		It("sets metadata and deletes VMs", func() {
+			vmCID, err := cloud.CreateVM("fake-agent-id", stemcellCID, biproperty.Map{}, nil, nil, biproperty.Map{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cloud.SetVMMetadata(vmCID, VMMetadata{"director": "fake-director"})).To(Succeed())
+			Expect(cloud.DeleteVM(vmCID)).To(Succeed())
+
+			found, err := cloud.HasVM(vmCID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns VMNotFound for unknown VMs", func() {
+			expectCPIError(cloud.SetVMMetadata("unknown-vm-cid", VMMetadata{}), VMNotFoundError)
+			expectCPIError(cloud.DeleteVM("unknown-vm-cid"), VMNotFoundError)
+		})
+	})
+
+	Describe("disks", func() {
+		var (
+			vmCID   string
+			diskCID string
+		)
+
+		BeforeEach(func() {
+			var err error
+			vmCID, err = cloud.CreateVM("fake-agent-id", stemcellCID, biproperty.Map{}, nil, nil, biproperty.Map{})
+			Expect(err).ToNot(HaveOccurred())
+
+			diskCID, err = cloud.CreateDisk(1024, biproperty.Map{}, vmCID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("attaches and detaches disks", func() {
+			diskHint, err := cloud.AttachDisk(vmCID, diskCID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskHint).To(Equal("/dev/sdb"))
+
+			diskHint, err = cloud.AttachDisk(vmCID, diskCID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskHint).To(Equal("/dev/sdb"))
+
+			otherDiskCID, err := cloud.CreateDisk(1024, biproperty.Map{}, vmCID)
+			Expect(err).ToNot(HaveOccurred())
+
+			diskHint, err = cloud.AttachDisk(vmCID, otherDiskCID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskHint).To(Equal("/dev/sdc"))
+
+			expectCPIError(cloud.DeleteDisk(diskCID), "Bosh::Clouds::CloudError")
+
+			Expect(cloud.DetachDisk(vmCID, diskCID)).To(Succeed())
+			Expect(cloud.DeleteDisk(diskCID)).To(Succeed())
+
+			expectCPIError(cloud.DeleteDisk(diskCID), DiskNotFoundError)
+		})
+
+		It("does not attach a disk to two VMs", func() {
+			otherVMCID, err := cloud.CreateVM("other-agent-id", stemcellCID, biproperty.Map{}, []string{diskCID}, nil, biproperty.Map{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = cloud.AttachDisk(vmCID, diskCID)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = cloud.AttachDisk(otherVMCID, diskCID)
+			expectCPIError(err, "Bosh::Clouds::CloudError")
+		})
+
+		It("detaches disks of deleted VMs so that they can be migrated", func() {
+			_, err := cloud.AttachDisk(vmCID, diskCID)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cloud.DeleteVM(vmCID)).To(Succeed())
+
+			newVMCID, err := cloud.CreateVM("fake-agent-id", stemcellCID, biproperty.Map{}, []string{diskCID}, nil, biproperty.Map{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = cloud.AttachDisk(newVMCID, diskCID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns DiskNotFound for unknown disks", func() {
+			_, err := cloud.AttachDisk(vmCID, "unknown-disk-cid")
+			expectCPIError(err, DiskNotFoundError)
+
+			expectCPIError(cloud.DetachDisk(vmCID, "unknown-disk-cid"), DiskNotFoundError)
+			expectCPIError(cloud.SetDiskMetadata("unknown-disk-cid", DiskMetadata{}), DiskNotFoundError)
+		})
+	})
+
+	It("returns an error if records cannot be saved", func() {
+		fs.WriteFileError = errors.New("fake-write-err")
+
+		_, err := cloud.CreateStemcell("/stemcell/image", biproperty.Map{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Writing local fake cloud state '/fake-cpi/cloud.json'"))
+	})
+})
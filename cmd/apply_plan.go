@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+// ApplyPlanDirectorFactory creates a Director that reports tasks to ui
+// so that output of deployments updated at the same time can be told apart.
+type ApplyPlanDirectorFactory func(ui boshui.UI) (boshdir.Director, error)
+
+type ApplyPlanCmd struct {
+	ui              boshui.UI
+	directorFactory ApplyPlanDirectorFactory
+	releaseUploader ReleaseUploader
+	fs              boshsys.FileSystem
+	timeService     clock.Clock
+	logger          boshlog.Logger
+}
+
+type applyPlanOutcome struct {
+	State    string
+	Duration time.Duration
+	Details  string
+}
+
+func NewApplyPlanCmd(
+	ui boshui.UI,
+	directorFactory ApplyPlanDirectorFactory,
+	releaseUploader ReleaseUploader,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) ApplyPlanCmd {
+	return ApplyPlanCmd{
+		ui:              ui,
+		directorFactory: directorFactory,
+		releaseUploader: releaseUploader,
+		fs:              fs,
+		timeService:     timeService,
+		logger:          logger,
+	}
+}
+
+func (c ApplyPlanCmd) Run(opts ApplyPlanOpts) error {
+	planPath := opts.Args.Plan.ExpandedPath
+
+	plan, err := NewDeploymentPlanFromPath(planPath, c.fs)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := plan.Order()
+	if err != nil {
+		return err
+	}
+
+	statePath := opts.StatePath
+	if len(statePath) == 0 {
+		statePath = planPath + ".state.json"
+	}
+
+	prevState := NewDeploymentPlanState(statePath, c.fs)
+
+	if opts.Resume {
+		err = prevState.Load()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.printPlan(ordered, prevState)
+
+	err = c.ui.AskForConfirmation()
+	if err != nil {
+		return err
+	}
+
+	state := NewDeploymentPlanState(statePath, c.fs)
+
+	outcomes, err := c.apply(plan, ordered, prevState, &state, opts)
+
+	c.printSummary(ordered, outcomes)
+
+	if err != nil {
+		return err
+	}
+
+	var failed, skipped int
+
+	for _, outcome := range outcomes {
+		switch outcome.State {
+		case DeploymentPlanStateFailed:
+			failed++
+		case DeploymentPlanStateSkipped:
+			skipped++
+		}
+	}
+
+	if failed > 0 || skipped > 0 {
+		return bosherr.Errorf(
+			"Expected all deployments to succeed but %d failed and %d were skipped; use --resume to continue", failed, skipped)
+	}
+
+	return nil
+}
+
+// apply deploys each deployment once its dependencies succeeded,
+// running at most opts.MaxParallel deployments at the same time.
+// State is saved after each deployment so that a failed or interrupted
+// plan can be resumed.
+func (c ApplyPlanCmd) apply(
+	plan DeploymentPlan,
+	ordered []DeploymentPlanItem,
+	prevState DeploymentPlanState,
+	state *DeploymentPlanState,
+	opts ApplyPlanOpts,
+) (map[string]applyPlanOutcome, error) {
+	parallel := opts.MaxParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	outcomes := map[string]applyPlanOutcome{}
+	outcomesLock := &sync.Mutex{}
+
+	var saveErr error
+
+	// Releases are uploaded one deployment at a time since deployments often share releases
+	releaseUploader := lockingReleaseUploader{uploader: c.releaseUploader, lock: &sync.Mutex{}}
+
+	finished := map[string]chan struct{}{}
+
+	for _, item := range ordered {
+		finished[item.Name] = make(chan struct{})
+	}
+
+	slots := make(chan struct{}, parallel)
+	wg := &sync.WaitGroup{}
+
+	record := func(name string, outcome applyPlanOutcome) {
+		outcomesLock.Lock()
+		defer outcomesLock.Unlock()
+
+		outcomes[name] = outcome
+
+		result := DeploymentPlanResult{State: outcome.State}
+		if outcome.State != DeploymentPlanStateDone {
+			result.Error = outcome.Details
+		}
+
+		state.Deployments[name] = result
+
+		err := state.Save()
+		if err != nil && saveErr == nil {
+			saveErr = err
+		}
+	}
+
+	for _, item := range ordered {
+		wg.Add(1)
+
+		go func(item DeploymentPlanItem) {
+			defer wg.Done()
+			defer close(finished[item.Name])
+
+			for _, dep := range item.DependsOn {
+				<-finished[dep]
+			}
+
+			if prevState.Succeeded(item.Name) {
+				record(item.Name, applyPlanOutcome{State: DeploymentPlanStateDone, Details: "Succeeded in a previous run"})
+				return
+			}
+
+			outcomesLock.Lock()
+			var failedDeps []string
+			for _, dep := range item.DependsOn {
+				if outcomes[dep].State != DeploymentPlanStateDone {
+					failedDeps = append(failedDeps, dep)
+				}
+			}
+			outcomesLock.Unlock()
+
+			if len(failedDeps) > 0 {
+				record(item.Name, applyPlanOutcome{
+					State:   DeploymentPlanStateSkipped,
+					Details: "Dependencies did not succeed: " + strings.Join(failedDeps, ", "),
+				})
+				return
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			startedAt := c.timeService.Now()
+
+			err := c.deploy(plan, item, releaseUploader, opts)
+
+			outcome := applyPlanOutcome{
+				State:    DeploymentPlanStateDone,
+				Duration: c.timeService.Since(startedAt),
+			}
+
+			if err != nil {
+				outcome.State = DeploymentPlanStateFailed
+				outcome.Details = err.Error()
+			}
+
+			record(item.Name, outcome)
+		}(item)
+	}
+
+	wg.Wait()
+
+	return outcomes, saveErr
+}
+
+// deploy goes through the same steps as the deploy command (configs of
+// type 'deploy', policies, release uploads, diff) without asking
+// for confirmation again. Its output is prefixed with the deployment name.
+func (c ApplyPlanCmd) deploy(plan DeploymentPlan, item DeploymentPlanItem, releaseUploader ReleaseUploader, opts ApplyPlanOpts) error {
+	bytes, err := plan.Evaluate(item)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := boshdir.NewManifestFromBytes(bytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing manifest")
+	}
+
+	if manifest.Name != item.Name {
+		return bosherr.Errorf("Expected manifest to specify deployment name '%s' but was '%s'", item.Name, manifest.Name)
+	}
+
+	ui := boshui.NewNonInteractiveUI(boshui.NewPrefixingUI(c.ui, item.Name+" | "))
+	defer ui.Flush()
+
+	director, err := c.directorFactory(ui)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := director.FindDeployment(item.Name)
+	if err != nil {
+		return err
+	}
+
+	policyChecker := NewDeployPolicyChecker(director, c.timeService, ui, c.logger)
+
+	deployOpts := DeployOpts{
+		Args:               DeployArgs{Manifest: FileBytesArg{Bytes: bytes}},
+		SkipUploadReleases: opts.SkipUploadReleases,
+	}
+
+	return NewDeployCmd(ui, deployment, releaseUploader, director, policyChecker).Run(deployOpts)
+}
+
+type lockingReleaseUploader struct {
+	uploader ReleaseUploader
+	lock     *sync.Mutex
+}
+
+func (u lockingReleaseUploader) UploadReleases(bytes []byte) ([]byte, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	return u.uploader.UploadReleases(bytes)
+}
+
+func (u lockingReleaseUploader) UploadReleasesWithFix(bytes []byte) ([]byte, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	return u.uploader.UploadReleasesWithFix(bytes)
+}
+
+func (c ApplyPlanCmd) printPlan(ordered []DeploymentPlanItem, prevState DeploymentPlanState) {
+	table := boshtbl.Table{
+		Content: "deployments",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Deployment"),
+			boshtbl.NewHeader("Depends On"),
+			boshtbl.NewHeader("Action"),
+		},
+	}
+
+	for _, item := range ordered {
+		action := "deploy"
+		if prevState.Succeeded(item.Name) {
+			action = "skip (succeeded in a previous run)"
+		}
+
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(item.Name),
+			boshtbl.NewValueStrings(item.DependsOn),
+			boshtbl.NewValueString(action),
+		})
+	}
+
+	c.ui.PrintTable(table)
+}
+
+func (c ApplyPlanCmd) printSummary(ordered []DeploymentPlanItem, outcomes map[string]applyPlanOutcome) {
+	table := boshtbl.Table{
+		Content: "deployments",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Deployment"),
+			boshtbl.NewHeader("State"),
+			boshtbl.NewHeader("Duration"),
+			boshtbl.NewHeader("Details"),
+		},
+	}
+
+	for _, item := range ordered {
+		outcome := outcomes[item.Name]
+
+		duration := ""
+		if outcome.Duration > 0 {
+			duration = outcome.Duration.Round(time.Second).String()
+		}
+
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(item.Name),
+			boshtbl.NewValueString(outcome.State),
+			boshtbl.NewValueString(duration),
+			boshtbl.NewValueString(outcome.Details),
+		})
+	}
+
+	c.ui.PrintTable(table)
+}
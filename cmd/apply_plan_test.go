@@ -0,0 +1,301 @@
+package cmd_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	fakecmd "github.com/cloudfoundry/bosh-cli/v7/cmd/cmdfakes"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	fakedir "github.com/cloudfoundry/bosh-cli/v7/director/directorfakes"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("ApplyPlanCmd", func() {
+	var (
+		ui              *fakeui.FakeUI
+		director        *fakedir.FakeDirector
+		releaseUploader *fakecmd.FakeReleaseUploader
+		fs              *fakesys.FakeFileSystem
+		taskUIs         []boshui.UI
+
+		deployments   map[string]*fakedir.FakeDeployment
+		deployedOrder []string
+		deployedLock  sync.Mutex
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		director = &fakedir.FakeDirector{}
+		releaseUploader = &fakecmd.FakeReleaseUploader{
+			UploadReleasesStub: func(bytes []byte) ([]byte, error) { return bytes, nil },
+		}
+		fs = fakesys.NewFakeFileSystem()
+		taskUIs = nil
+
+		deployments = map[string]*fakedir.FakeDeployment{}
+		deployedOrder = nil
+
+		for _, name := range []string{"dns", "db", "cf"} {
+			deployment := &fakedir.FakeDeployment{}
+			deployment.NameReturns(name)
+			deployment.UpdateStub = func(name string) func([]byte, boshdir.UpdateOpts) error {
+				return func([]byte, boshdir.UpdateOpts) error {
+					deployedLock.Lock()
+					defer deployedLock.Unlock()
+					deployedOrder = append(deployedOrder, name)
+					return nil
+				}
+			}(name)
+			deployments[name] = deployment
+		}
+
+		director.FindDeploymentStub = func(name string) (boshdir.Deployment, error) {
+			return deployments[name], nil
+		}
+
+		Expect(fs.WriteFileString("/plan.yml", `
+deployments:
+- {name: cf, manifest: cf.yml, depends_on: [dns, db]}
+- {name: dns, manifest: dns.yml}
+- {name: db, manifest: db.yml, depends_on: [dns], vars: {name: db}}
+`)).To(Succeed())
+		Expect(fs.WriteFileString("/cf.yml", "name: cf")).To(Succeed())
+		Expect(fs.WriteFileString("/dns.yml", "name: dns")).To(Succeed())
+		Expect(fs.WriteFileString("/db.yml", "name: ((name))")).To(Succeed())
+	})
+
+	Describe("Run", func() {
+		var (
+			applyPlanOpts opts.ApplyPlanOpts
+		)
+
+		BeforeEach(func() {
+			applyPlanOpts = opts.ApplyPlanOpts{
+				Args: opts.ApplyPlanArgs{Plan: opts.FileArg{ExpandedPath: "/plan.yml"}},
+			}
+		})
+
+		act := func() error {
+			timeService := fakeclock.NewFakeClock(time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC))
+
+			directorFactory := func(taskUI boshui.UI) (boshdir.Director, error) {
+				deployedLock.Lock()
+				defer deployedLock.Unlock()
+
+				taskUIs = append(taskUIs, taskUI)
+
+				return director, nil
+			}
+
+			command := cmd.NewApplyPlanCmd(ui, directorFactory, releaseUploader, fs, timeService, boshlog.NewLogger(boshlog.LevelNone))
+			return command.Run(applyPlanOpts)
+		}
+
+		summaryRows := func() [][]boshtbl.Value {
+			Expect(ui.Tables).To(HaveLen(2))
+			return ui.Tables[1].Rows
+		}
+
+		It("deploys deployments in dependency order with diff context", func() {
+			diff := boshdir.NewDeploymentDiff(nil, map[string]interface{}{"cloud_config_id": "1"})
+			deployments["cf"].DiffReturns(diff, nil)
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployedOrder).To(Equal([]string{"dns", "db", "cf"}))
+
+			bytes, updateOpts := deployments["cf"].UpdateArgsForCall(0)
+			Expect(bytes).To(Equal([]byte("name: cf\n")))
+			Expect(updateOpts).To(Equal(boshdir.UpdateOpts{Diff: diff}))
+
+			bytes, _ = deployments["db"].UpdateArgsForCall(0)
+			Expect(bytes).To(Equal([]byte("name: db\n")))
+
+			Expect(releaseUploader.UploadReleasesCallCount()).To(Equal(3))
+		})
+
+		It("prints plan before asking for confirmation", func() {
+			ui.AskedConfirmationErr = errors.New("stop")
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(deployedOrder).To(BeEmpty())
+
+			Expect(ui.Table).To(Equal(boshtbl.Table{
+				Content: "deployments",
+				Header: []boshtbl.Header{
+					boshtbl.NewHeader("Deployment"),
+					boshtbl.NewHeader("Depends On"),
+					boshtbl.NewHeader("Action"),
+				},
+				Rows: [][]boshtbl.Value{
+					{boshtbl.NewValueString("dns"), boshtbl.NewValueStrings(nil), boshtbl.NewValueString("deploy")},
+					{boshtbl.NewValueString("db"), boshtbl.NewValueStrings([]string{"dns"}), boshtbl.NewValueString("deploy")},
+					{boshtbl.NewValueString("cf"), boshtbl.NewValueStrings([]string{"dns", "db"}), boshtbl.NewValueString("deploy")},
+				},
+			}))
+		})
+
+		It("deploys all deployments when running in parallel", func() {
+			applyPlanOpts.MaxParallel = 3
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployedOrder).To(ConsistOf("dns", "db", "cf"))
+			Expect(deployedOrder[2]).To(Equal("cf"))
+		})
+
+		It("applies configs of type deploy and deploy policies like the deploy command", func() {
+			director.ListConfigsStub = func(limit int, filter boshdir.ConfigsFilter) ([]boshdir.Config, error) {
+				switch filter.Type {
+				case "deploy":
+					return []boshdir.Config{{Name: "fix", Type: "deploy", Content: "flags: [fix]"}}, nil
+				case "deploy-policy":
+					return []boshdir.Config{{Name: "safety", Type: "deploy-policy", Content: "rules:\n- type: max_in_flight\n  max: 1"}}, nil
+				}
+				return nil, nil
+			}
+
+			Expect(fs.WriteFileString("/db.yml", "name: ((name))\nupdate: {max_in_flight: 2}")).To(Succeed())
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(deployedOrder).To(Equal([]string{"dns"}))
+
+			_, updateOpts := deployments["dns"].UpdateArgsForCall(0)
+			Expect(updateOpts.Fix).To(BeTrue())
+
+			// Policy violations of db are printed with its prefix between plan and summary
+			Expect(ui.Tables).To(HaveLen(2))
+			Expect(ui.Said).To(ContainElement(And(HavePrefix("db | "), ContainSubstring("policy violations"))))
+			Expect(ui.Tables[1].Rows[1][3]).To(Equal(boshtbl.NewValueString(
+				"Expected deployment to satisfy deploy policies but found 1 violation(s); use --override-policy to deploy anyway")))
+		})
+
+		It("prefixes output of each deployment with its name", func() {
+			deployments["dns"].UpdateStub = func([]byte, boshdir.UpdateOpts) error {
+				taskUIs[0].PrintLinef("Task 1 done")
+				return nil
+			}
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Said).To(ContainElement("dns | Task 1 done"))
+		})
+
+		It("saves state after each deployment", func() {
+			deployments["db"].UpdateStub = func([]byte, boshdir.UpdateOpts) error {
+				state := cmd.NewDeploymentPlanState("/plan.yml.state.json", fs)
+				Expect(state.Load()).To(Succeed())
+				Expect(state.Deployments).To(Equal(map[string]cmd.DeploymentPlanResult{"dns": {State: "done"}}))
+				return nil
+			}
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployments["db"].UpdateCallCount()).To(Equal(1))
+		})
+
+		It("returns error if state cannot be saved", func() {
+			fs.WriteFileError = errors.New("fake-write-err")
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+			Expect(summaryRows()).To(HaveLen(3))
+		})
+
+		It("skips releases upload if requested", func() {
+			applyPlanOpts.SkipUploadReleases = true
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(releaseUploader.UploadReleasesCallCount()).To(Equal(0))
+		})
+
+		It("skips dependents of failed deployments and records state", func() {
+			deployments["db"].UpdateReturns(errors.New("fake-update-err"))
+			deployments["db"].UpdateStub = nil
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected all deployments to succeed but 1 failed and 1 were skipped; use --resume to continue"))
+			Expect(deployedOrder).To(Equal([]string{"dns"}))
+
+			Expect(summaryRows()).To(Equal([][]boshtbl.Value{
+				{boshtbl.NewValueString("dns"), boshtbl.NewValueString("done"), boshtbl.NewValueString(""), boshtbl.NewValueString("")},
+				{boshtbl.NewValueString("db"), boshtbl.NewValueString("failed"), boshtbl.NewValueString(""), boshtbl.NewValueString("fake-update-err")},
+				{boshtbl.NewValueString("cf"), boshtbl.NewValueString("skipped"), boshtbl.NewValueString(""), boshtbl.NewValueString("Dependencies did not succeed: db")},
+			}))
+
+			state := cmd.NewDeploymentPlanState("/plan.yml.state.json", fs)
+			Expect(state.Load()).To(Succeed())
+			Expect(state.Deployments).To(Equal(map[string]cmd.DeploymentPlanResult{
+				"dns": {State: "done"},
+				"db":  {State: "failed", Error: "fake-update-err"},
+				"cf":  {State: "skipped", Error: "Dependencies did not succeed: db"},
+			}))
+		})
+
+		It("fails deployments whose manifest name does not match", func() {
+			Expect(fs.WriteFileString("/dns.yml", "name: other")).To(Succeed())
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(summaryRows()[0][3]).To(Equal(boshtbl.NewValueString(
+				"Expected manifest to specify deployment name 'dns' but was 'other'")))
+			Expect(deployedOrder).To(BeEmpty())
+		})
+
+		It("resumes by skipping deployments that succeeded previously", func() {
+			Expect(fs.WriteFileString("/state.json", `{"deployments": {"dns": {"state": "done"}, "db": {"state": "failed"}}}`)).To(Succeed())
+
+			applyPlanOpts.StatePath = "/state.json"
+			applyPlanOpts.Resume = true
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployedOrder).To(Equal([]string{"db", "cf"}))
+
+			Expect(ui.Tables[0].Rows[0][2]).To(Equal(boshtbl.NewValueString("skip (succeeded in a previous run)")))
+			Expect(summaryRows()[0][3]).To(Equal(boshtbl.NewValueString("Succeeded in a previous run")))
+
+			state := cmd.NewDeploymentPlanState("/state.json", fs)
+			Expect(state.Load()).To(Succeed())
+			Expect(state.Succeeded("dns")).To(BeTrue())
+			Expect(state.Succeeded("db")).To(BeTrue())
+		})
+
+		It("ignores previous state unless resuming", func() {
+			Expect(fs.WriteFileString("/plan.yml.state.json", `{"deployments": {"dns": {"state": "done"}}}`)).To(Succeed())
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployedOrder).To(Equal([]string{"dns", "db", "cf"}))
+		})
+
+		It("returns error if plan has dependency cycles", func() {
+			Expect(fs.WriteFileString("/plan.yml", `
+deployments:
+- {name: a, manifest: a.yml, depends_on: [b]}
+- {name: b, manifest: b.yml, depends_on: [a]}
+`)).To(Succeed())
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("dependency cycles"))
+			Expect(ui.Tables).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"gopkg.in/yaml.v2"
+
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+const (
+	BundleIndexFile    = "bundle.yml"
+	BundleManifestFile = "manifest.yml"
+)
+
+// BundleIndex describes contents of a bundle archive.
+// Paths are relative to the root of the archive.
+type BundleIndex struct {
+	Manifest  string                `yaml:"manifest"`
+	Releases  []BundleIndexRelease  `yaml:"releases"`
+	Stemcells []BundleIndexStemcell `yaml:"stemcells"`
+}
+
+type BundleIndexRelease struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Path    string `yaml:"path"`
+	SHA1    string `yaml:"sha1"`
+
+	// Stemcell is set for releases compiled against a stemcell
+	Stemcell BundleIndexReleaseStemcell `yaml:"stemcell,omitempty"`
+}
+
+type BundleIndexReleaseStemcell struct {
+	OS      string `yaml:"os"`
+	Version string `yaml:"version"`
+}
+
+type BundleIndexStemcell struct {
+	Name    string `yaml:"name"`
+	OS      string `yaml:"os"`
+	Version string `yaml:"version"`
+	Path    string `yaml:"path"`
+	SHA1    string `yaml:"sha1"`
+}
+
+func NewBundleIndexFromDir(dir string, fs boshsys.FileSystem) (BundleIndex, error) {
+	var index BundleIndex
+
+	path := filepath.Join(dir, BundleIndexFile)
+
+	bytes, err := fs.ReadFile(path)
+	if err != nil {
+		return index, bosherr.WrapErrorf(err, "Reading bundle index")
+	}
+
+	err = yaml.Unmarshal(bytes, &index)
+	if err != nil {
+		return index, bosherr.WrapErrorf(err, "Unmarshalling bundle index")
+	}
+
+	return index, nil
+}
+
+func (i BundleIndex) Write(dir string, fs boshsys.FileSystem) error {
+	bytes, err := yaml.Marshal(i)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Marshalling bundle index")
+	}
+
+	err = fs.WriteFile(filepath.Join(dir, BundleIndexFile), bytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing bundle index")
+	}
+
+	return nil
+}
+
+func (i BundleIndex) Print(ui boshui.UI) {
+	table := boshtbl.Table{
+		Content: "bundle contents",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Type"),
+			boshtbl.NewHeader("Name"),
+			boshtbl.NewHeader("Version"),
+			boshtbl.NewHeader("Path"),
+		},
+	}
+
+	for _, rel := range i.Releases {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString("release"),
+			boshtbl.NewValueString(rel.Name),
+			boshtbl.NewValueString(rel.Version),
+			boshtbl.NewValueString(rel.Path),
+		})
+	}
+
+	for _, stemcell := range i.Stemcells {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString("stemcell"),
+			boshtbl.NewValueString(stemcell.Name),
+			boshtbl.NewValueString(stemcell.Version),
+			boshtbl.NewValueString(stemcell.Path),
+		})
+	}
+
+	ui.PrintTable(table)
+}
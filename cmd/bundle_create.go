@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshfu "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	bitarball "github.com/cloudfoundry/bosh-cli/v7/installation/tarball"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+//counterfeiter:generate . BundleReleaseExporter
+
+// BundleReleaseExporter exports compiled releases from a connected director
+// for releases that do not specify a URL.
+type BundleReleaseExporter interface {
+	ExportRelease(rel boshdir.ReleaseSlug, osSlug boshdir.OSVersionSlug, dstPath string) error
+}
+
+type BundleCreateCmd struct {
+	ui    boshui.UI
+	stage boshui.Stage
+
+	tarballProvider        bitarball.Provider
+	releaseExporter        BundleReleaseExporter
+	releaseArchiveFactory  func(string) boshdir.ReleaseArchive
+	stemcellArchiveFactory func(string) boshdir.StemcellArchive
+
+	digestCalculator bicrypto.DigestCalculator
+	compressor       boshfu.Compressor
+	fs               boshsys.FileSystem
+}
+
+type bundleManifest struct {
+	Releases  []bundleManifestRelease  `yaml:"releases"`
+	Stemcells []bundleManifestStemcell `yaml:"stemcells"`
+}
+
+type bundleManifestRelease struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	URL     string `yaml:"url"`
+	SHA1    string `yaml:"sha1"`
+
+	Stemcell boshdir.ManifestReleaseStemcell `yaml:"stemcell"`
+}
+
+type bundleManifestStemcell struct {
+	Alias   string `yaml:"alias"`
+	Name    string `yaml:"name"`
+	OS      string `yaml:"os"`
+	Version string `yaml:"version"`
+	URL     string `yaml:"url"`
+	SHA1    string `yaml:"sha1"`
+}
+
+type bundleTarballSource struct {
+	url         string
+	sha1        string
+	description string
+}
+
+func (s bundleTarballSource) GetURL() string      { return s.url }
+func (s bundleTarballSource) GetSHA1() string     { return s.sha1 }
+func (s bundleTarballSource) Description() string { return s.description }
+
+func NewBundleCreateCmd(
+	ui boshui.UI,
+	stage boshui.Stage,
+	tarballProvider bitarball.Provider,
+	releaseExporter BundleReleaseExporter,
+	releaseArchiveFactory func(string) boshdir.ReleaseArchive,
+	stemcellArchiveFactory func(string) boshdir.StemcellArchive,
+	digestCalculator bicrypto.DigestCalculator,
+	compressor boshfu.Compressor,
+	fs boshsys.FileSystem,
+) BundleCreateCmd {
+	return BundleCreateCmd{
+		ui:    ui,
+		stage: stage,
+
+		tarballProvider:        tarballProvider,
+		releaseExporter:        releaseExporter,
+		releaseArchiveFactory:  releaseArchiveFactory,
+		stemcellArchiveFactory: stemcellArchiveFactory,
+
+		digestCalculator: digestCalculator,
+		compressor:       compressor,
+		fs:               fs,
+	}
+}
+
+func (c BundleCreateCmd) Run(opts BundleCreateOpts) error {
+	tpl := boshtpl.NewTemplate(opts.Args.Manifest.Bytes)
+
+	bytes, err := tpl.Evaluate(opts.VarFlags.AsVariables(), opts.OpsFlags.AsOp(), boshtpl.EvaluateOpts{})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Evaluating manifest")
+	}
+
+	var manifest bundleManifest
+
+	err = yaml.Unmarshal(bytes, &manifest)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing manifest")
+	}
+
+	stagingDir, err := c.fs.TempDir("bosh-bundle")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating staging directory")
+	}
+
+	defer c.fs.RemoveAll(stagingDir) //nolint:errcheck
+
+	for _, dir := range []string{"releases", "stemcells"} {
+		err = c.fs.MkdirAll(filepath.Join(stagingDir, dir), 0755)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating staging directory")
+		}
+	}
+
+	index := BundleIndex{Manifest: BundleManifestFile}
+
+	var ops patch.Ops
+
+	for _, rel := range manifest.Releases {
+		indexRel, err := c.addRelease(rel, manifest, stagingDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Adding release '%s'", rel.Name)
+		}
+
+		index.Releases = append(index.Releases, indexRel)
+		ops = append(ops, pinOps("releases", patch.MatchingIndexToken{Key: "name", Value: rel.Name}, indexRel.Version)...)
+	}
+
+	for _, stemcell := range manifest.Stemcells {
+		indexStemcell, err := c.addStemcell(stemcell, opts.Stemcells, stagingDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Adding stemcell '%s'", stemcell.Alias)
+		}
+
+		index.Stemcells = append(index.Stemcells, indexStemcell)
+		ops = append(ops, pinOps("stemcells", patch.MatchingIndexToken{Key: "alias", Value: stemcell.Alias}, indexStemcell.Version)...)
+	}
+
+	// Bundled manifest refers to bundled versions instead of URLs that are not reachable offline
+	pinnedBytes, err := boshtpl.NewTemplate(bytes).Evaluate(boshtpl.StaticVariables{}, ops, boshtpl.EvaluateOpts{})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Pinning manifest versions")
+	}
+
+	err = c.fs.WriteFile(filepath.Join(stagingDir, BundleManifestFile), pinnedBytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing manifest")
+	}
+
+	err = index.Write(stagingDir, c.fs)
+	if err != nil {
+		return err
+	}
+
+	tarballPath, err := c.compressor.CompressFilesInDir(stagingDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Compressing bundle")
+	}
+
+	err = boshfu.NewFileMover(c.fs).Move(tarballPath, opts.Output.ExpandedPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Moving bundle to final destination")
+	}
+
+	index.Print(c.ui)
+
+	c.ui.PrintLinef("Created bundle '%s'", opts.Output.ExpandedPath)
+
+	return nil
+}
+
+func (c BundleCreateCmd) addRelease(rel bundleManifestRelease, manifest bundleManifest, stagingDir string) (BundleIndexRelease, error) {
+	var path string
+	var stemcell BundleIndexReleaseStemcell
+
+	if len(rel.URL) > 0 {
+		source := bundleTarballSource{
+			url:         rel.URL,
+			sha1:        rel.SHA1,
+			description: fmt.Sprintf("release '%s'", rel.Name),
+		}
+
+		if len(rel.Stemcell.OS) > 0 && len(rel.Stemcell.Version) > 0 {
+			stemcell = BundleIndexReleaseStemcell{OS: rel.Stemcell.OS, Version: rel.Stemcell.Version}
+		}
+
+		var err error
+
+		path, err = c.sourcePath(source)
+		if err != nil {
+			return BundleIndexRelease{}, err
+		}
+	} else {
+		if len(rel.Version) == 0 || rel.Version == "latest" {
+			return BundleIndexRelease{}, bosherr.Errorf("Expected release without URL to specify exact version to export it from the director")
+		}
+
+		osSlug, err := exportStemcell(rel, manifest)
+		if err != nil {
+			return BundleIndexRelease{}, err
+		}
+
+		stemcell = BundleIndexReleaseStemcell{OS: osSlug.OS(), Version: osSlug.Version()}
+
+		exportFile, err := c.fs.TempFile("bosh-bundle-export")
+		if err != nil {
+			return BundleIndexRelease{}, bosherr.WrapErrorf(err, "Creating export file")
+		}
+
+		exportFile.Close()                      //nolint:errcheck
+		defer c.fs.RemoveAll(exportFile.Name()) //nolint:errcheck
+
+		path = exportFile.Name()
+
+		err = c.releaseExporter.ExportRelease(boshdir.NewReleaseSlug(rel.Name, rel.Version), osSlug, path)
+		if err != nil {
+			return BundleIndexRelease{}, bosherr.WrapErrorf(err, "Exporting release")
+		}
+	}
+
+	info, err := c.releaseArchiveFactory(path).Info()
+	if err != nil {
+		return BundleIndexRelease{}, bosherr.WrapErrorf(err, "Retrieving release info")
+	}
+
+	if info.Name != rel.Name {
+		return BundleIndexRelease{}, bosherr.Errorf("Expected release tarball to contain release '%s' but was '%s'", rel.Name, info.Name)
+	}
+
+	if len(rel.Version) > 0 && rel.Version != "latest" && rel.Version != info.Version {
+		return BundleIndexRelease{}, bosherr.Errorf("Expected release tarball to contain version '%s' but was '%s'", rel.Version, info.Version)
+	}
+
+	relPath := filepath.Join("releases", fmt.Sprintf("%s-%s.tgz", info.Name, info.Version))
+
+	digest, err := c.copyIntoBundle(path, stagingDir, relPath)
+	if err != nil {
+		return BundleIndexRelease{}, err
+	}
+
+	return BundleIndexRelease{Name: info.Name, Version: info.Version, Path: relPath, SHA1: digest, Stemcell: stemcell}, nil
+}
+
+// exportStemcell returns stemcell that release is compiled against when exported.
+func exportStemcell(rel bundleManifestRelease, manifest bundleManifest) (boshdir.OSVersionSlug, error) {
+	stemcellOS, version := rel.Stemcell.OS, rel.Stemcell.Version
+
+	if len(stemcellOS) == 0 && len(manifest.Stemcells) > 0 {
+		stemcellOS, version = manifest.Stemcells[0].OS, manifest.Stemcells[0].Version
+	}
+
+	if len(stemcellOS) == 0 || len(version) == 0 || version == "latest" {
+		return boshdir.OSVersionSlug{}, bosherr.Errorf(
+			"Expected release or first manifest stemcell to specify exact stemcell OS and version to export release")
+	}
+
+	return boshdir.NewOSVersionSlug(stemcellOS, version), nil
+}
+
+func (c BundleCreateCmd) addStemcell(stemcell bundleManifestStemcell, localPaths []string, stagingDir string) (BundleIndexStemcell, error) {
+	var path string
+
+	if len(stemcell.URL) > 0 {
+		source := bundleTarballSource{
+			url:         stemcell.URL,
+			sha1:        stemcell.SHA1,
+			description: fmt.Sprintf("stemcell '%s'", stemcell.Alias),
+		}
+
+		var err error
+
+		path, err = c.sourcePath(source)
+		if err != nil {
+			return BundleIndexStemcell{}, err
+		}
+	}
+
+	for _, localPath := range localPaths {
+		if len(path) > 0 {
+			break
+		}
+
+		info, err := c.stemcellArchiveFactory(localPath).Info()
+		if err != nil {
+			return BundleIndexStemcell{}, bosherr.WrapErrorf(err, "Retrieving info of stemcell '%s'", localPath)
+		}
+
+		if stemcellMatches(stemcell, info) {
+			path = localPath
+		}
+	}
+
+	if len(path) == 0 {
+		return BundleIndexStemcell{}, bosherr.Errorf("Expected stemcell to specify URL or to be provided with --stemcell")
+	}
+
+	info, err := c.stemcellArchiveFactory(path).Info()
+	if err != nil {
+		return BundleIndexStemcell{}, bosherr.WrapErrorf(err, "Retrieving stemcell info")
+	}
+
+	if !stemcellMatches(stemcell, info) {
+		return BundleIndexStemcell{}, bosherr.Errorf(
+			"Expected stemcell tarball to match manifest stemcell but was '%s/%s'", info.Name, info.Version)
+	}
+
+	stemcellPath := filepath.Join("stemcells", fmt.Sprintf("%s-%s.tgz", info.Name, info.Version))
+
+	digest, err := c.copyIntoBundle(path, stagingDir, stemcellPath)
+	if err != nil {
+		return BundleIndexStemcell{}, err
+	}
+
+	return BundleIndexStemcell{Name: info.Name, OS: info.OS, Version: info.Version, Path: stemcellPath, SHA1: digest}, nil
+}
+
+func stemcellMatches(stemcell bundleManifestStemcell, info boshdir.StemcellMetadata) bool {
+	if len(stemcell.OS) > 0 && stemcell.OS != info.OS {
+		return false
+	}
+
+	if len(stemcell.Name) > 0 && stemcell.Name != info.Name {
+		return false
+	}
+
+	return stemcell.Version == "latest" || stemcell.Version == info.Version
+}
+
+// sourcePath downloads remote tarballs and verifies digests of local ones
+// since the tarball provider only verifies downloaded files.
+func (c BundleCreateCmd) sourcePath(source bundleTarballSource) (string, error) {
+	path, err := c.tarballProvider.Get(source, c.stage)
+	if err != nil {
+		return "", err
+	}
+
+	if !isRemoteURL(source.url) && len(source.sha1) > 0 {
+		digest, err := boshcrypto.ParseMultipleDigest(source.sha1)
+		if err != nil {
+			return "", err
+		}
+
+		err = digest.VerifyFilePath(path, c.fs)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Verifying digest of '%s'", path)
+		}
+	}
+
+	return path, nil
+}
+
+func isRemoteURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func (c BundleCreateCmd) copyIntoBundle(srcPath, stagingDir, relPath string) (string, error) {
+	dstPath := filepath.Join(stagingDir, relPath)
+
+	if srcPath != dstPath {
+		err := c.fs.CopyFile(srcPath, dstPath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Copying '%s' into bundle", srcPath)
+		}
+	}
+
+	digest, err := c.digestCalculator.Calculate(dstPath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Calculating digest of '%s'", relPath)
+	}
+
+	return digest, nil
+}
+
+func pinOps(key string, itemToken patch.Token, version string) patch.Ops {
+	itemPath := []patch.Token{patch.RootToken{}, patch.KeyToken{Key: key}, itemToken}
+
+	withKey := func(k string, optional bool) patch.Pointer {
+		return patch.NewPointer(append(append([]patch.Token{}, itemPath...), patch.KeyToken{Key: k, Optional: optional}))
+	}
+
+	return patch.Ops{
+		patch.ReplaceOp{Path: withKey("version", false), Value: version},
+		patch.RemoveOp{Path: withKey("url", true)},
+		patch.RemoveOp{Path: withKey("sha1", true)},
+	}
+}
+
+// DirectorReleaseExporter exports releases from a deployment of a connected director.
+type DirectorReleaseExporter struct {
+	directorAndDeployment func() (boshdir.Director, boshdir.Deployment)
+	fs                    boshsys.FileSystem
+}
+
+func NewDirectorReleaseExporter(directorAndDeployment func() (boshdir.Director, boshdir.Deployment), fs boshsys.FileSystem) DirectorReleaseExporter {
+	return DirectorReleaseExporter{directorAndDeployment: directorAndDeployment, fs: fs}
+}
+
+func (e DirectorReleaseExporter) ExportRelease(rel boshdir.ReleaseSlug, osSlug boshdir.OSVersionSlug, dstPath string) error {
+	director, deployment := e.directorAndDeployment()
+
+	result, err := deployment.ExportRelease(rel, osSlug, nil)
+	if err != nil {
+		return err
+	}
+
+	file, err := e.fs.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening '%s'", dstPath)
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	err = director.DownloadResourceUnchecked(result.BlobstoreID, file)
+	if err != nil {
+		return err
+	}
+
+	// Old directors may not send the digest
+	if len(result.SHA1) > 0 {
+		digest, err := boshcrypto.ParseMultipleDigest(result.SHA1)
+		if err != nil {
+			return err
+		}
+
+		return digest.VerifyFilePath(dstPath, e.fs)
+	}
+
+	return nil
+}
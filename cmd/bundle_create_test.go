@@ -0,0 +1,280 @@
+package cmd_test
+
+import (
+	"errors"
+
+	fakefu "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	fakecmd "github.com/cloudfoundry/bosh-cli/v7/cmd/cmdfakes"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	fakecrypto "github.com/cloudfoundry/bosh-cli/v7/crypto/fakes"
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	fakedir "github.com/cloudfoundry/bosh-cli/v7/director/directorfakes"
+	bitarball "github.com/cloudfoundry/bosh-cli/v7/installation/tarball"
+	mocktarball "github.com/cloudfoundry/bosh-cli/v7/installation/tarball/mocks"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("BundleCreateCmd", func() {
+	var (
+		mockCtrl         *gomock.Controller
+		ui               *fakeui.FakeUI
+		stage            *fakeui.FakeStage
+		tarballProvider  *mocktarball.MockProvider
+		releaseExporter  *fakecmd.FakeBundleReleaseExporter
+		releaseArchives  map[string]*fakedir.FakeReleaseArchive
+		stemcellArchives map[string]*fakedir.FakeStemcellArchive
+		digestCalculator *fakecrypto.FakeDigestCalculator
+		compressor       *fakefu.FakeCompressor
+		fs               *fakesys.FakeFileSystem
+		command          cmd.BundleCreateCmd
+
+		bundleOpts opts.BundleCreateOpts
+		staged     map[string]string
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+
+		ui = &fakeui.FakeUI{}
+		stage = fakeui.NewFakeStage()
+		tarballProvider = mocktarball.NewMockProvider(mockCtrl)
+		releaseExporter = &fakecmd.FakeBundleReleaseExporter{}
+		digestCalculator = fakecrypto.NewFakeDigestCalculator()
+		compressor = fakefu.NewFakeCompressor()
+		fs = fakesys.NewFakeFileSystem()
+		fs.TempDirDir = "/staging"
+
+		releaseArchives = map[string]*fakedir.FakeReleaseArchive{}
+		stemcellArchives = map[string]*fakedir.FakeStemcellArchive{}
+
+		releaseArchiveFactory := func(path string) boshdir.ReleaseArchive {
+			if archive, found := releaseArchives[path]; found {
+				return archive
+			}
+			// Exported releases are written to temporary files
+			return releaseArchives["exported"]
+		}
+
+		stemcellArchiveFactory := func(path string) boshdir.StemcellArchive {
+			return stemcellArchives[path]
+		}
+
+		command = cmd.NewBundleCreateCmd(
+			ui, stage, tarballProvider, releaseExporter,
+			releaseArchiveFactory, stemcellArchiveFactory, digestCalculator, compressor, fs)
+
+		bundleOpts = opts.BundleCreateOpts{
+			Args: opts.BundleCreateArgs{
+				Manifest: opts.FileBytesArg{Bytes: []byte(`
+name: dep
+releases:
+- {name: rel1, version: latest, url: "https://example.com/rel1.tgz", sha1: abc}
+- {name: rel2, version: "2", stemcell: {os: ubuntu-jammy, version: "1.5"}}
+stemcells:
+- {alias: default, os: ubuntu-jammy, version: latest}
+`)},
+			},
+			Output:    opts.FileArg{ExpandedPath: "/out/bundle.tgz"},
+			Stemcells: []string{"/stemcells/other.tgz", "/stemcells/jammy.tgz"},
+		}
+
+		Expect(fs.WriteFileString("/cache/rel1", "rel1-content")).To(Succeed())
+		Expect(fs.WriteFileString("/stemcells/jammy.tgz", "stemcell-content")).To(Succeed())
+		Expect(fs.WriteFileString("/compressed.tgz", "bundle-content")).To(Succeed())
+		Expect(fs.MkdirAll("/out", 0755)).To(Succeed())
+
+		releaseArchives["/cache/rel1"] = &fakedir.FakeReleaseArchive{}
+		releaseArchives["/cache/rel1"].InfoReturns(boshdir.ReleaseMetadata{Name: "rel1", Version: "1"}, nil)
+
+		releaseArchives["exported"] = &fakedir.FakeReleaseArchive{}
+		releaseArchives["exported"].InfoReturns(boshdir.ReleaseMetadata{Name: "rel2", Version: "2"}, nil)
+
+		stemcellArchives["/stemcells/other.tgz"] = &fakedir.FakeStemcellArchive{}
+		stemcellArchives["/stemcells/other.tgz"].InfoReturns(boshdir.StemcellMetadata{Name: "bosh-stemcell-xenial", OS: "ubuntu-xenial", Version: "1"}, nil)
+
+		stemcellArchives["/stemcells/jammy.tgz"] = &fakedir.FakeStemcellArchive{}
+		stemcellArchives["/stemcells/jammy.tgz"].InfoReturns(boshdir.StemcellMetadata{Name: "bosh-stemcell-jammy", OS: "ubuntu-jammy", Version: "1.5"}, nil)
+
+		tarballProvider.EXPECT().Get(gomock.Any(), stage).DoAndReturn(
+			func(source bitarball.Source, _ boshui.Stage) (string, error) {
+				Expect(source.GetURL()).To(Equal("https://example.com/rel1.tgz"))
+				Expect(source.GetSHA1()).To(Equal("abc"))
+				Expect(source.Description()).To(Equal("release 'rel1'"))
+				return "/cache/rel1", nil
+			}).AnyTimes()
+
+		releaseExporter.ExportReleaseStub = func(_ boshdir.ReleaseSlug, _ boshdir.OSVersionSlug, path string) error {
+			return fs.WriteFileString(path, "rel2-content")
+		}
+
+		digestCalculator.SetCalculateBehavior(map[string]fakecrypto.CalculateInput{
+			"/staging/releases/rel1-1.tgz":                   {DigestStr: "sha256:rel1"},
+			"/staging/releases/rel2-2.tgz":                   {DigestStr: "sha256:rel2"},
+			"/staging/stemcells/bosh-stemcell-jammy-1.5.tgz": {DigestStr: "sha256:stemcell"},
+		})
+
+		staged = map[string]string{}
+		compressor.CompressFilesInDirTarballPath = "/compressed.tgz"
+		compressor.CompressFilesInDirCallBack = func() {
+			for _, path := range []string{
+				"/staging/bundle.yml",
+				"/staging/manifest.yml",
+				"/staging/releases/rel1-1.tgz",
+				"/staging/releases/rel2-2.tgz",
+				"/staging/stemcells/bosh-stemcell-jammy-1.5.tgz",
+			} {
+				if !fs.FileExists(path) {
+					continue
+				}
+				content, err := fs.ReadFileString(path)
+				Expect(err).ToNot(HaveOccurred())
+				staged[path] = content
+			}
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	act := func() error { return command.Run(bundleOpts) }
+
+	It("creates bundle with releases, stemcells, an index and a manifest pinned to bundled versions", func() {
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(compressor.CompressFilesInDirDir).To(Equal("/staging"))
+
+		Expect(staged["/staging/releases/rel1-1.tgz"]).To(Equal("rel1-content"))
+		Expect(staged["/staging/releases/rel2-2.tgz"]).To(Equal("rel2-content"))
+		Expect(staged["/staging/stemcells/bosh-stemcell-jammy-1.5.tgz"]).To(Equal("stemcell-content"))
+
+		Expect(staged["/staging/bundle.yml"]).To(MatchYAML(`
+manifest: manifest.yml
+releases:
+- {name: rel1, version: "1", path: releases/rel1-1.tgz, sha1: "sha256:rel1"}
+- {name: rel2, version: "2", path: releases/rel2-2.tgz, sha1: "sha256:rel2", stemcell: {os: ubuntu-jammy, version: "1.5"}}
+stemcells:
+- {name: bosh-stemcell-jammy, os: ubuntu-jammy, version: "1.5", path: stemcells/bosh-stemcell-jammy-1.5.tgz, sha1: "sha256:stemcell"}
+`))
+
+		Expect(staged["/staging/manifest.yml"]).To(MatchYAML(`
+name: dep
+releases:
+- {name: rel1, version: "1"}
+- {name: rel2, version: "2", stemcell: {os: ubuntu-jammy, version: "1.5"}}
+stemcells:
+- {alias: default, os: ubuntu-jammy, version: "1.5"}
+`))
+
+		content, err := fs.ReadFileString("/out/bundle.tgz")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(content).To(Equal("bundle-content"))
+
+		Expect(fs.FileExists("/staging")).To(BeFalse())
+		Expect(ui.Said).To(ContainElement("Created bundle '/out/bundle.tgz'"))
+	})
+
+	It("exports releases without URL against the stemcell of the manifest", func() {
+		bundleOpts.Args.Manifest.Bytes = []byte(`
+name: dep
+releases:
+- {name: rel2, version: "2"}
+stemcells:
+- {alias: default, os: ubuntu-jammy, version: "1.5"}
+`)
+
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(releaseExporter.ExportReleaseCallCount()).To(Equal(1))
+		rel, osSlug, _ := releaseExporter.ExportReleaseArgsForCall(0)
+		Expect(rel).To(Equal(boshdir.NewReleaseSlug("rel2", "2")))
+		Expect(osSlug).To(Equal(boshdir.NewOSVersionSlug("ubuntu-jammy", "1.5")))
+	})
+
+	It("returns error if release without URL does not specify exact version", func() {
+		bundleOpts.Args.Manifest.Bytes = []byte("releases:\n- {name: rel2, version: latest}")
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Adding release 'rel2'"))
+		Expect(err.Error()).To(ContainSubstring("Expected release without URL to specify exact version"))
+	})
+
+	It("returns error if exported release cannot be compiled against a known stemcell", func() {
+		bundleOpts.Args.Manifest.Bytes = []byte("releases:\n- {name: rel2, version: 2}\nstemcells:\n- {alias: default, os: ubuntu-jammy, version: latest}")
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("exact stemcell OS and version to export release"))
+	})
+
+	It("returns error if exporting release fails", func() {
+		releaseExporter.ExportReleaseStub = nil
+		releaseExporter.ExportReleaseReturns(errors.New("fake-err"))
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-err"))
+	})
+
+	It("returns error if release tarball contains different version", func() {
+		releaseArchives["exported"].InfoReturns(boshdir.ReleaseMetadata{Name: "rel2", Version: "3"}, nil)
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Expected release tarball to contain version '2' but was '3'"))
+	})
+
+	It("returns error if local release does not match its digest", func() {
+		bundleOpts.Args.Manifest.Bytes = []byte(`
+releases:
+- {name: rel1, url: "file:///cache/rel1", sha1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"}
+`)
+		tarballProvider = mocktarball.NewMockProvider(mockCtrl)
+		tarballProvider.EXPECT().Get(gomock.Any(), stage).Return("/cache/rel1", nil)
+
+		command = cmd.NewBundleCreateCmd(
+			ui, stage, tarballProvider, releaseExporter,
+			func(path string) boshdir.ReleaseArchive { return releaseArchives[path] },
+			func(path string) boshdir.StemcellArchive { return stemcellArchives[path] },
+			digestCalculator, compressor, fs)
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Verifying digest of '/cache/rel1'"))
+	})
+
+	It("returns error if no stemcell tarball matches manifest stemcell", func() {
+		bundleOpts.Stemcells = []string{"/stemcells/other.tgz"}
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Adding stemcell 'default'"))
+		Expect(err.Error()).To(ContainSubstring("Expected stemcell to specify URL or to be provided with --stemcell"))
+	})
+
+	It("returns error if manifest cannot be evaluated", func() {
+		bundleOpts.Args.Manifest.Bytes = []byte("releases: [")
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Evaluating manifest"))
+	})
+
+	It("returns error if compressing fails", func() {
+		compressor.CompressFilesInDirErr = errors.New("fake-err")
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Compressing bundle"))
+	})
+})
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshfu "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type BundleUploadCmd struct {
+	ui       boshui.UI
+	director boshdir.Director
+
+	releaseArchiveFactory  func(string) boshdir.ReleaseArchive
+	stemcellArchiveFactory func(string) boshdir.StemcellArchive
+	policyChecker          DeployPolicyChecker
+
+	compressor boshfu.Compressor
+	fs         boshsys.FileSystem
+}
+
+func NewBundleUploadCmd(
+	ui boshui.UI,
+	director boshdir.Director,
+	releaseArchiveFactory func(string) boshdir.ReleaseArchive,
+	stemcellArchiveFactory func(string) boshdir.StemcellArchive,
+	policyChecker DeployPolicyChecker,
+	compressor boshfu.Compressor,
+	fs boshsys.FileSystem,
+) BundleUploadCmd {
+	return BundleUploadCmd{
+		ui:       ui,
+		director: director,
+
+		releaseArchiveFactory:  releaseArchiveFactory,
+		stemcellArchiveFactory: stemcellArchiveFactory,
+		policyChecker:          policyChecker,
+
+		compressor: compressor,
+		fs:         fs,
+	}
+}
+
+func (c BundleUploadCmd) Run(opts BundleUploadOpts) error {
+	dir, err := c.fs.TempDir("bosh-bundle")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating extraction directory")
+	}
+
+	defer c.fs.RemoveAll(dir) //nolint:errcheck
+
+	err = c.compressor.DecompressFileToDir(opts.Args.Bundle.ExpandedPath, dir, boshfu.CompressorOptions{})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Extracting bundle")
+	}
+
+	index, err := NewBundleIndexFromDir(dir, c.fs)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range index.Releases {
+		err = c.uploadRelease(dir, rel, opts.Fix)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Uploading release '%s/%s'", rel.Name, rel.Version)
+		}
+	}
+
+	for _, stemcell := range index.Stemcells {
+		err = c.uploadStemcell(dir, stemcell, opts.Fix)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Uploading stemcell '%s/%s'", stemcell.Name, stemcell.Version)
+		}
+	}
+
+	if !opts.Deploy {
+		return nil
+	}
+
+	manifestBytes, err := c.fs.ReadFile(filepath.Join(dir, index.Manifest))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading bundled manifest")
+	}
+
+	manifest, err := boshdir.NewManifestFromBytes(manifestBytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing bundled manifest")
+	}
+
+	deployment, err := c.director.FindDeployment(manifest.Name)
+	if err != nil {
+		return err
+	}
+
+	deployOpts := DeployOpts{
+		Args:               DeployArgs{Manifest: FileBytesArg{Bytes: manifestBytes}},
+		SkipUploadReleases: true,
+	}
+
+	// Releases were uploaded from the bundle above hence no release uploader is needed
+	return NewDeployCmd(c.ui, deployment, nil, c.director, c.policyChecker).Run(deployOpts)
+}
+
+func (c BundleUploadCmd) uploadRelease(dir string, rel BundleIndexRelease, fix bool) error {
+	if !fix {
+		var stemcell boshdir.OSVersionSlug
+		if len(rel.Stemcell.OS) > 0 && len(rel.Stemcell.Version) > 0 {
+			stemcell = boshdir.NewOSVersionSlug(rel.Stemcell.OS, rel.Stemcell.Version)
+		}
+
+		found, err := c.director.HasRelease(rel.Name, rel.Version, stemcell)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			if stemcell.IsProvided() {
+				c.ui.PrintLinef("Release '%s/%s' for stemcell '%s' already exists.", rel.Name, rel.Version, stemcell)
+			} else {
+				c.ui.PrintLinef("Release '%s/%s' already exists.", rel.Name, rel.Version)
+			}
+			return nil
+		}
+	}
+
+	path, err := c.verifiedPath(dir, rel.Path, rel.SHA1)
+	if err != nil {
+		return err
+	}
+
+	file, err := c.releaseArchiveFactory(path).File()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening release")
+	}
+
+	return c.director.UploadReleaseFile(file, false, fix)
+}
+
+func (c BundleUploadCmd) uploadStemcell(dir string, stemcell BundleIndexStemcell, fix bool) error {
+	if !fix {
+		needed, err := c.director.StemcellNeedsUpload(boshdir.StemcellInfo{Name: stemcell.Name, Version: stemcell.Version})
+		if err != nil {
+			return err
+		}
+
+		if !needed {
+			c.ui.PrintLinef("Stemcell '%s/%s' already exists.", stemcell.Name, stemcell.Version)
+			return nil
+		}
+	}
+
+	path, err := c.verifiedPath(dir, stemcell.Path, stemcell.SHA1)
+	if err != nil {
+		return err
+	}
+
+	file, err := c.stemcellArchiveFactory(path).File()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening stemcell")
+	}
+
+	return c.director.UploadStemcellFile(file, fix)
+}
+
+func (c BundleUploadCmd) verifiedPath(dir, relPath, sha1 string) (string, error) {
+	path := filepath.Join(dir, relPath)
+
+	digest, err := boshcrypto.ParseMultipleDigest(sha1)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Parsing digest of '%s'", relPath)
+	}
+
+	err = digest.VerifyFilePath(path, c.fs)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Verifying digest of '%s'", relPath)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,224 @@
+package cmd_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	fakefu "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	fakedir "github.com/cloudfoundry/bosh-cli/v7/director/directorfakes"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("BundleUploadCmd", func() {
+	var (
+		ui               *fakeui.FakeUI
+		director         *fakedir.FakeDirector
+		deployment       *fakedir.FakeDeployment
+		releaseArchive   *fakedir.FakeReleaseArchive
+		stemcellArchive  *fakedir.FakeStemcellArchive
+		compressor       *fakefu.FakeCompressor
+		fs               *fakesys.FakeFileSystem
+		releaseFile      *fakesys.FakeFile
+		stemcellFile     *fakesys.FakeFile
+		archivePaths     []string
+		bundleUploadOpts opts.BundleUploadOpts
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		director = &fakedir.FakeDirector{}
+		deployment = &fakedir.FakeDeployment{}
+		deployment.NameReturns("dep")
+		compressor = fakefu.NewFakeCompressor()
+		fs = fakesys.NewFakeFileSystem()
+		fs.TempDirDir = "/extracted"
+
+		releaseFile = fakesys.NewFakeFile("/extracted/releases/rel1-1.tgz", fs)
+		stemcellFile = fakesys.NewFakeFile("/extracted/stemcells/stemcell-1.tgz", fs)
+
+		releaseArchive = &fakedir.FakeReleaseArchive{}
+		releaseArchive.FileReturns(releaseFile, nil)
+
+		stemcellArchive = &fakedir.FakeStemcellArchive{}
+		stemcellArchive.FileReturns(stemcellFile, nil)
+
+		archivePaths = nil
+
+		director.FindDeploymentReturns(deployment, nil)
+		director.StemcellNeedsUploadReturns(true, nil)
+
+		Expect(fs.WriteFileString("/extracted/bundle.yml", `
+manifest: manifest.yml
+releases:
+- {name: rel1, version: "1", path: releases/rel1-1.tgz, sha1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"}
+stemcells:
+- {name: stemcell, os: ubuntu-jammy, version: "1", path: stemcells/stemcell-1.tgz, sha1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"}
+`)).To(Succeed())
+		Expect(fs.WriteFileString("/extracted/manifest.yml", "name: dep")).To(Succeed())
+		Expect(fs.WriteFileString("/extracted/releases/rel1-1.tgz", "")).To(Succeed())
+		Expect(fs.WriteFileString("/extracted/stemcells/stemcell-1.tgz", "")).To(Succeed())
+
+		bundleUploadOpts = opts.BundleUploadOpts{
+			Args: opts.BundleUploadArgs{Bundle: opts.FileArg{ExpandedPath: "/bundle.tgz"}},
+		}
+	})
+
+	act := func() error {
+		releaseArchiveFactory := func(path string) boshdir.ReleaseArchive {
+			archivePaths = append(archivePaths, path)
+			return releaseArchive
+		}
+
+		stemcellArchiveFactory := func(path string) boshdir.StemcellArchive {
+			archivePaths = append(archivePaths, path)
+			return stemcellArchive
+		}
+
+		policyChecker := cmd.NewDeployPolicyChecker(director, fakeclock.NewFakeClock(time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)), ui, boshlog.NewLogger(boshlog.LevelNone))
+
+		command := cmd.NewBundleUploadCmd(
+			ui, director, releaseArchiveFactory, stemcellArchiveFactory, policyChecker, compressor, fs)
+
+		return command.Run(bundleUploadOpts)
+	}
+
+	It("uploads releases and stemcells that are missing on the director", func() {
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(compressor.DecompressFileToDirTarballPaths).To(Equal([]string{"/bundle.tgz"}))
+		Expect(compressor.DecompressFileToDirDirs).To(Equal([]string{"/extracted"}))
+
+		name, version, osSlug := director.HasReleaseArgsForCall(0)
+		Expect(name).To(Equal("rel1"))
+		Expect(version).To(Equal("1"))
+		Expect(osSlug).To(Equal(boshdir.OSVersionSlug{}))
+
+		Expect(director.StemcellNeedsUploadArgsForCall(0)).To(Equal(
+			boshdir.StemcellInfo{Name: "stemcell", Version: "1"}))
+
+		Expect(archivePaths).To(Equal([]string{
+			"/extracted/releases/rel1-1.tgz",
+			"/extracted/stemcells/stemcell-1.tgz",
+		}))
+
+		Expect(director.UploadReleaseFileCallCount()).To(Equal(1))
+		file, rebase, fix := director.UploadReleaseFileArgsForCall(0)
+		Expect(file).To(Equal(releaseFile))
+		Expect(rebase).To(BeFalse())
+		Expect(fix).To(BeFalse())
+
+		Expect(director.UploadStemcellFileCallCount()).To(Equal(1))
+		file, fix = director.UploadStemcellFileArgsForCall(0)
+		Expect(file).To(Equal(stemcellFile))
+		Expect(fix).To(BeFalse())
+
+		Expect(deployment.UpdateCallCount()).To(Equal(0))
+		Expect(fs.FileExists("/extracted")).To(BeFalse())
+	})
+
+	It("skips releases and stemcells that already exist on the director", func() {
+		director.HasReleaseReturns(true, nil)
+		director.StemcellNeedsUploadReturns(false, nil)
+
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(director.UploadReleaseFileCallCount()).To(Equal(0))
+		Expect(director.UploadStemcellFileCallCount()).To(Equal(0))
+
+		Expect(ui.Said).To(ContainElement("Release 'rel1/1' already exists."))
+		Expect(ui.Said).To(ContainElement("Stemcell 'stemcell/1' already exists."))
+	})
+
+	It("checks compiled releases against the stemcell they were compiled for", func() {
+		Expect(fs.WriteFileString("/extracted/bundle.yml", `
+manifest: manifest.yml
+releases:
+- name: rel1
+  version: "1"
+  path: releases/rel1-1.tgz
+  sha1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+  stemcell: {os: ubuntu-jammy, version: "1.5"}
+`)).To(Succeed())
+
+		director.HasReleaseReturns(true, nil)
+
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		name, version, osSlug := director.HasReleaseArgsForCall(0)
+		Expect(name).To(Equal("rel1"))
+		Expect(version).To(Equal("1"))
+		Expect(osSlug).To(Equal(boshdir.NewOSVersionSlug("ubuntu-jammy", "1.5")))
+
+		Expect(director.UploadReleaseFileCallCount()).To(Equal(0))
+		Expect(ui.Said).To(ContainElement("Release 'rel1/1' for stemcell 'ubuntu-jammy/1.5' already exists."))
+	})
+
+	It("replaces existing releases and stemcells when fixing", func() {
+		bundleUploadOpts.Fix = true
+		director.HasReleaseReturns(true, nil)
+
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(director.HasReleaseCallCount()).To(Equal(0))
+		Expect(director.StemcellNeedsUploadCallCount()).To(Equal(0))
+
+		_, _, fix := director.UploadReleaseFileArgsForCall(0)
+		Expect(fix).To(BeTrue())
+
+		_, fix = director.UploadStemcellFileArgsForCall(0)
+		Expect(fix).To(BeTrue())
+	})
+
+	It("deploys bundled manifest without uploading releases when requested", func() {
+		bundleUploadOpts.Deploy = true
+
+		err := act()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(director.FindDeploymentArgsForCall(0)).To(Equal("dep"))
+
+		Expect(deployment.UpdateCallCount()).To(Equal(1))
+		bytes, _ := deployment.UpdateArgsForCall(0)
+		Expect(bytes).To(Equal([]byte("name: dep\n")))
+	})
+
+	It("returns error if bundled file does not match its digest", func() {
+		Expect(fs.WriteFileString("/extracted/releases/rel1-1.tgz", "corrupted")).To(Succeed())
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Uploading release 'rel1/1'"))
+		Expect(err.Error()).To(ContainSubstring("Verifying digest of 'releases/rel1-1.tgz'"))
+
+		Expect(director.UploadReleaseFileCallCount()).To(Equal(0))
+	})
+
+	It("returns error if bundle cannot be extracted", func() {
+		compressor.DecompressFileToDirErr = errors.New("fake-err")
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Extracting bundle"))
+	})
+
+	It("returns error if uploading stemcell fails", func() {
+		director.UploadStemcellFileReturns(errors.New("fake-err"))
+
+		err := act()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Uploading stemcell 'stemcell/1'"))
+	})
+})
@@ -2,22 +2,30 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"time"
 
 	bihttpagent "github.com/cloudfoundry/bosh-agent/v2/agentclient/http"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
 	"github.com/cppforlife/go-patch/patch"
 
+	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
 	cmdconf "github.com/cloudfoundry/bosh-cli/v7/cmd/config"
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
 	"github.com/cloudfoundry/bosh-cli/v7/crypto"
 	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	bitarball "github.com/cloudfoundry/bosh-cli/v7/installation/tarball"
 	"github.com/cloudfoundry/bosh-cli/v7/pcap"
 	boshrel "github.com/cloudfoundry/bosh-cli/v7/release"
+	boshjob "github.com/cloudfoundry/bosh-cli/v7/release/job"
 	boshreldir "github.com/cloudfoundry/bosh-cli/v7/releasedir"
 	boshssh "github.com/cloudfoundry/bosh-cli/v7/ssh"
 	bistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
+	bitemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler"
+	bitemplateerb "github.com/cloudfoundry/bosh-cli/v7/templatescompiler/erbrenderer"
 	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
 	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
 	boshuit "github.com/cloudfoundry/bosh-cli/v7/ui/task"
@@ -70,37 +78,89 @@ func (c Cmd) Execute() (cmdErr error) {
 		return NewEnvironmentsCmd(c.config(), deps.UI).Run()
 
 	case *CreateEnvOpts:
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		cpiConfig := c.cpiConfig(opts.CPIFlags, opts.Args.Manifest.Path, opts.OpsFlags.AsOp())
+		opts.VarsFSStore.Encryptor = stateEncryptor
+
 		envProvider := func(manifestPath string, statePath string, vars boshtpl.Variables, op patch.Op) DeploymentPreparer {
-			return NewEnvFactory(deps, manifestPath, statePath, vars, op, opts.RecreatePersistentDisks, opts.PackageDir).Preparer()
+			return NewEnvFactory(deps, manifestPath, statePath, vars, op, opts.RecreatePersistentDisks, opts.PackageDir, opts.ForceUnlock, stateEncryptor, cpiConfig, c.BoshOpts.RubyERBRendererOpt).Preparer()
 		}
 
 		stage := boshui.NewStage(deps.UI, deps.Time, deps.Logger)
 		return NewCreateEnvCmd(deps.UI, envProvider).Run(stage, *opts)
 
+	case *EnvCPILogOpts:
+		auditLogPath, err := DeploymentCPIAuditLogPath(opts.Args.Manifest.Path, opts.StatePath)
+		if err != nil {
+			return err
+		}
+
+		return NewEnvCPILogCmd(deps.UI, bicloud.NewFileCPIAuditLog(deps.FS, auditLogPath)).Run(*opts)
+
+	case *EnvStateHistoryOpts:
+		return NewEnvStateHistoryCmd(deps.UI, NewDeploymentStateService(deps, opts.Args.Manifest.Path, opts.StatePath, nil)).Run()
+
+	case *EnvStateDiffOpts:
+		deploymentStateService := NewDeploymentStateService(deps, opts.Args.Manifest.Path, opts.StatePath, c.stateEncryptor(opts.StateEncryptionFlags))
+		return NewEnvStateDiffCmd(deps.UI, deploymentStateService).Run(*opts)
+
+	case *EnvStateRollbackOpts:
+		deploymentStateService := NewDeploymentStateService(deps, opts.Args.Manifest.Path, opts.StatePath, c.stateEncryptor(opts.StateEncryptionFlags))
+		return NewEnvStateRollbackCmd(deps.UI, deploymentStateService, deps.Logger).Run(*opts)
+
+	case *EncryptEnvStateOpts:
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		opts.VarsFSStore.Encryptor = stateEncryptor
+
+		deploymentStateService := NewDeploymentStateService(deps, opts.Args.Manifest.Path, opts.StatePath, stateEncryptor)
+		return NewEncryptEnvStateCmd(deps.UI, deploymentStateService, stateEncryptor, deps.Logger).Run(*opts)
+
 	case *DeleteEnvOpts:
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		cpiConfig := c.cpiConfig(opts.CPIFlags, opts.Args.Manifest.Path, opts.OpsFlags.AsOp())
+		opts.VarsFSStore.Encryptor = stateEncryptor
+
 		envProvider := func(manifestPath string, statePath string, vars boshtpl.Variables, op patch.Op) DeploymentDeleter {
-			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, opts.PackageDir).Deleter()
+			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, opts.PackageDir, opts.ForceUnlock, stateEncryptor, cpiConfig, c.BoshOpts.RubyERBRendererOpt).Deleter()
 		}
 
 		stage := boshui.NewStage(deps.UI, deps.Time, deps.Logger)
 		return NewDeleteEnvCmd(deps.UI, envProvider).Run(stage, *opts)
 
 	case *StopEnvOpts:
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		opts.VarsFSStore.Encryptor = stateEncryptor
+
 		envProvider := func(manifestPath string, statePath string, vars boshtpl.Variables, op patch.Op) DeploymentStateManager {
-			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, "").StateManager()
+			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, "", opts.ForceUnlock, stateEncryptor, EnvCPIConfig{}, c.BoshOpts.RubyERBRendererOpt).StateManager()
 		}
 
 		stage := boshui.NewStage(deps.UI, deps.Time, deps.Logger)
 		return NewStopEnvCmd(deps.UI, envProvider).Run(stage, *opts)
 
 	case *StartEnvOpts:
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		opts.VarsFSStore.Encryptor = stateEncryptor
+
 		envProvider := func(manifestPath string, statePath string, vars boshtpl.Variables, op patch.Op) DeploymentStateManager {
-			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, "").StateManager()
+			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, "", opts.ForceUnlock, stateEncryptor, EnvCPIConfig{}, c.BoshOpts.RubyERBRendererOpt).StateManager()
 		}
 
 		stage := boshui.NewStage(deps.UI, deps.Time, deps.Logger)
 		return NewStartEnvCmd(deps.UI, envProvider).Run(stage, *opts)
 
+	case *RepairEnvStateOpts:
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		cpiConfig := c.cpiConfig(opts.CPIFlags, opts.Args.Manifest.Path, opts.OpsFlags.AsOp())
+		opts.VarsFSStore.Encryptor = stateEncryptor
+
+		envProvider := func(manifestPath string, statePath string, vars boshtpl.Variables, op patch.Op) DeploymentStateRepairer {
+			return NewEnvFactory(deps, manifestPath, statePath, vars, op, false, opts.PackageDir, opts.ForceUnlock, stateEncryptor, cpiConfig, c.BoshOpts.RubyERBRendererOpt).StateRepairer()
+		}
+
+		stage := boshui.NewStage(deps.UI, deps.Time, deps.Logger)
+		return NewRepairEnvStateCmd(deps.UI, envProvider).Run(stage, *opts)
+
 	case *AliasEnvOpts:
 		sessionFactory := func(config cmdconf.Config) Session {
 			return NewSessionFromOpts(c.BoshOpts, config, deps.UI, true, false, deps.FS, deps.Logger)
@@ -262,7 +322,69 @@ func (c Cmd) Execute() (cmdErr error) {
 		return NewDeleteVMCmd(deps.UI, c.deployment()).Run(*opts)
 
 	case *InterpolateOpts:
-		return NewInterpolateCmd(deps.UI).Run(*opts)
+		stateEncryptor := c.stateEncryptor(opts.StateEncryptionFlags)
+		opts.VarsFSStore.Encryptor = stateEncryptor
+		return NewInterpolateCmd(deps.UI, stateEncryptor).Run(*opts)
+
+	case *DiffManifestsOpts:
+		return NewDiffManifestsCmd(deps.UI).Run(*opts)
+
+	case *BundleCreateOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
+
+		releaseArchiveFactory := func(path string) boshdir.ReleaseArchive {
+			return boshdir.NewFSReleaseArchive(path, deps.FS)
+		}
+
+		stemcellArchiveFactory := func(path string) boshdir.StemcellArchive {
+			return boshdir.NewFSStemcellArchive(path, deps.FS)
+		}
+
+		return NewBundleCreateCmd(
+			deps.UI,
+			boshui.NewStage(deps.UI, deps.Time, deps.Logger),
+			c.tarballProvider(),
+			NewDirectorReleaseExporter(c.directorAndDeployment, deps.FS),
+			releaseArchiveFactory,
+			stemcellArchiveFactory,
+			deps.DigestCalculator,
+			deps.Compressor,
+			deps.FS,
+		).Run(*opts)
+
+	case *BundleUploadOpts:
+		releaseArchiveFactory := func(path string) boshdir.ReleaseArchive {
+			return boshdir.NewFSReleaseArchive(path, deps.FS)
+		}
+
+		stemcellArchiveFactory := func(path string) boshdir.StemcellArchive {
+			return boshdir.NewFSStemcellArchive(path, deps.FS)
+		}
+
+		director := c.director()
+		policyChecker := NewDeployPolicyChecker(director, deps.Time, deps.UI, deps.Logger)
+
+		return NewBundleUploadCmd(
+			deps.UI,
+			director,
+			releaseArchiveFactory,
+			stemcellArchiveFactory,
+			policyChecker,
+			deps.Compressor,
+			deps.FS,
+		).Run(*opts)
+
+	case *ValidateManifestOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
+		return NewValidateManifestCmd(c.manifestValidator()).Run(*opts)
+
+	case *VarsStoreRotateOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
+		return NewVarsStoreRotateCmd(deps.UI, deps.Time).Run(*opts)
+
+	case *VarsStoreExpiryOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
+		return NewVarsStoreExpiryCmd(deps.UI, deps.Time).Run(*opts)
 
 	case *ConfigOpts:
 		return NewConfigCmd(deps.UI, c.director()).Run(*opts)
@@ -274,6 +396,7 @@ func (c Cmd) Execute() (cmdErr error) {
 		return NewDiffConfigCmd(deps.UI, c.director()).Run(*opts)
 
 	case *UpdateConfigOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
 		return NewUpdateConfigCmd(deps.UI, c.director()).Run(*opts)
 
 	case *DeleteConfigOpts:
@@ -283,18 +406,22 @@ func (c Cmd) Execute() (cmdErr error) {
 		return NewCloudConfigCmd(deps.UI, c.director()).Run(*opts)
 
 	case *UpdateCloudConfigOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
 		return NewUpdateCloudConfigCmd(deps.UI, c.director()).Run(*opts)
 
 	case *CPIConfigOpts:
 		return NewCPIConfigCmd(deps.UI, c.director()).Run()
 
 	case *UpdateCPIConfigOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
 		return NewUpdateCPIConfigCmd(deps.UI, c.director()).Run(*opts)
 
 	case *RuntimeConfigOpts:
 		return NewRuntimeConfigCmd(deps.UI, c.director()).Run(*opts)
 
 	case *UpdateRuntimeConfigOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
+
 		director := c.director()
 		releaseManager := c.releaseManager(director)
 		return NewUpdateRuntimeConfigCmd(deps.UI, director, releaseManager).Run(*opts)
@@ -338,9 +465,31 @@ func (c Cmd) Execute() (cmdErr error) {
 		return NewUnignoreCmd(c.deployment()).Run(*opts)
 
 	case *DeployOpts:
+		opts.VarsFSStore.Encryptor = c.stateEncryptor(opts.StateEncryptionFlags)
+
+		if opts.ValidateOnly {
+			validateOpts := ValidateManifestOpts{
+				Args:     ValidateManifestArgs{Manifest: opts.Args.Manifest},
+				VarFlags: opts.VarFlags,
+				OpsFlags: opts.OpsFlags,
+				Releases: opts.Releases,
+			}
+			return NewValidateManifestCmd(c.manifestValidator()).Run(validateOpts)
+		}
+
 		director, deployment := c.directorAndDeployment()
 		releaseManager := c.releaseManager(director)
-		return NewDeployCmd(deps.UI, deployment, releaseManager, director).Run(*opts)
+		policyChecker := NewDeployPolicyChecker(director, deps.Time, deps.UI, deps.Logger)
+		return NewDeployCmd(deps.UI, deployment, releaseManager, director, policyChecker).Run(*opts)
+
+	case *ApplyPlanOpts:
+		releaseManager := c.releaseManager(c.director())
+
+		directorFactory := func(ui boshui.UI) (boshdir.Director, error) {
+			return NewSessionFromOpts(c.BoshOpts, c.config(), ui, false, false, deps.FS, deps.Logger).Director()
+		}
+
+		return NewApplyPlanCmd(deps.UI, directorFactory, releaseManager, deps.FS, deps.Time, deps.Logger).Run(*opts)
 
 	case *StartOpts:
 		return NewStartCmd(deps.UI, c.deployment()).Run(*opts)
@@ -423,6 +572,15 @@ func (c Cmd) Execute() (cmdErr error) {
 	case *GenerateJobOpts:
 		return NewGenerateJobCmd(c.releaseDir(opts.Directory)).Run(*opts)
 
+	case *RenderJobOpts:
+		erbRenderer := bitemplateerb.NewERBRenderer(deps.FS, deps.CmdRunner, deps.Logger)
+		if !c.BoshOpts.RubyERBRendererOpt {
+			erbRenderer = bitemplateerb.NewNativeERBRenderer(deps.FS, erbRenderer, deps.UI, deps.Logger)
+		}
+
+		jobRenderer := bitemplate.NewJobRenderer(erbRenderer, deps.FS, deps.UUIDGen, deps.Logger)
+		return NewRenderJobCmd(boshjob.NewDirReaderImpl(nil, deps.FS), jobRenderer, deps.FS, deps.UI).Run(*opts)
+
 	case *GeneratePackageOpts:
 		return NewGeneratePackageCmd(c.releaseDir(opts.Directory)).Run(*opts)
 
@@ -545,6 +703,59 @@ func (c Cmd) config() cmdconf.Config {
 	return config
 }
 
+func (c Cmd) stateEncryptor(flags StateEncryptionFlags) crypto.StateEncryptor {
+	encryptor, err := NewStateEncryptor(flags, c.deps.UI)
+	c.panicIfErr(err)
+
+	return encryptor
+}
+
+// cpiConfig rejects flags that only apply to CPI releases when the local fake
+// CPI is selected with --cpi or by the manifest.
+func (c Cmd) cpiConfig(flags CPIFlags, manifestPath string, manifestOp patch.Op) EnvCPIConfig {
+	if flags.CPI != "" && flags.CPI != bicloud.LocalFakeCPIName {
+		c.panicIfErr(bosherr.Errorf("Expected --cpi to be '%s', got '%s'", bicloud.LocalFakeCPIName, flags.CPI))
+	}
+
+	if flags.CPI != "" || usesLocalFakeCPI(c.deps.FS, manifestPath, manifestOp) {
+		if flags.RecordCPI != "" || flags.ReplayCPI != "" {
+			c.panicIfErr(bosherr.Error("Expected --record-cpi and --replay-cpi not to be used with the local fake CPI"))
+		}
+
+		if flags.CPIMaxAttempts != bicloud.DefaultCPIMaxAttempts || flags.CPIRetryBackoff != bicloud.DefaultCPIRetryBackoff || len(flags.CPITimeouts) > 0 {
+			c.panicIfErr(bosherr.Error("Expected --cpi-max-attempts, --cpi-retry-backoff and --cpi-timeout not to be used with the local fake CPI"))
+		}
+	}
+
+	if flags.RecordCPI != "" && flags.ReplayCPI != "" {
+		c.panicIfErr(bosherr.Error("Expected only one of --record-cpi and --replay-cpi"))
+	}
+
+	if flags.CPIMaxAttempts < 1 {
+		c.panicIfErr(bosherr.Errorf("Expected --cpi-max-attempts to be at least 1, got %d", flags.CPIMaxAttempts))
+	}
+
+	retryPolicy := bicloud.DefaultCPIRetryPolicy()
+	retryPolicy.MaxAttempts = flags.CPIMaxAttempts
+	retryPolicy.Backoff = flags.CPIRetryBackoff
+	retryPolicy.Timeouts = map[string]time.Duration{}
+
+	for _, timeout := range flags.CPITimeouts {
+		if timeout.Method == "" {
+			retryPolicy.Timeout = timeout.Timeout
+		} else {
+			retryPolicy.Timeouts[timeout.Method] = timeout.Timeout
+		}
+	}
+
+	return EnvCPIConfig{
+		Name:        flags.CPI,
+		Cassette:    bicloud.CPICassetteConfig{RecordPath: flags.RecordCPI, ReplayPath: flags.ReplayCPI},
+		RetryPolicy: retryPolicy,
+		AuditLog:    true,
+	}
+}
+
 func (c Cmd) session() Session {
 	return NewSessionFromOpts(c.BoshOpts, c.config(), c.deps.UI, true, true, c.deps.FS, c.deps.Logger)
 }
@@ -575,6 +786,14 @@ func (c Cmd) directorAndDeployment() (boshdir.Director, boshdir.Deployment) {
 	return director, deployment
 }
 
+// tarballProvider downloads tarballs into the same cache as create-env.
+func (c Cmd) tarballProvider() bitarball.Provider {
+	tarballCache := bitarball.NewCache(filepath.Join(os.Getenv("HOME"), ".bosh", "downloads"), c.deps.FS, c.deps.Logger)
+	httpClient := httpclient.NewHTTPClient(httpclient.CreateExternalDefaultClient(nil), c.deps.Logger)
+
+	return bitarball.NewProvider(tarballCache, c.deps.FS, httpClient, 3, 500*time.Millisecond, c.deps.Logger)
+}
+
 func (c Cmd) releaseProviders() (boshrel.Provider, boshreldir.Provider) {
 	indexReporter := boshui.NewIndexReporter(c.deps.UI)
 	blobsReporter := boshui.NewBlobsReporter(c.deps.UI)
@@ -625,6 +844,18 @@ func (c Cmd) releaseManager(director boshdir.Director) ReleaseManager {
 	return NewReleaseManager(createReleaseCmd, uploadReleaseCmd, c.BoshOpts.Parallel)
 }
 
+func (c Cmd) manifestValidator() ManifestValidator {
+	relProv, relDirProv := c.releaseProviders()
+
+	releaseDirFactory := func(path string) boshreldir.ReleaseDir {
+		return relDirProv.NewFSReleaseDir(path, c.BoshOpts.Parallel)
+	}
+
+	specsReader := NewReleaseJobSpecsReader(relProv.NewExtractingArchiveReader(), releaseDirFactory, c.deps.FS)
+
+	return NewManifestValidator(specsReader, c.deps.UI)
+}
+
 func (c Cmd) blobsDir(dir DirOrCWDArg) boshreldir.BlobsDir {
 	_, relDirProv := c.releaseProviders()
 	return relDirProv.NewFSBlobsDir(dir.Path)
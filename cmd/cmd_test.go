@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
 	"github.com/cloudfoundry/bosh-cli/v7/cmd"
 	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
 	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
@@ -105,6 +106,40 @@ var _ = Describe("Cmd", func() {
 			Expect(err.Error()).To(Equal("fake-err"))
 		})
 
+		Describe("create-env with the local fake CPI", func() {
+			var createEnvOpts *opts.CreateEnvOpts
+
+			BeforeEach(func() {
+				err := fs.WriteFileString("/manifest.yml", "cloud_provider: {cpi: fake}\n")
+				Expect(err).ToNot(HaveOccurred())
+
+				createEnvOpts = &opts.CreateEnvOpts{
+					Args: opts.CreateEnvArgs{Manifest: opts.FileBytesWithPathArg{Path: "/manifest.yml"}},
+					CPIFlags: opts.CPIFlags{
+						CPIMaxAttempts:  bicloud.DefaultCPIMaxAttempts,
+						CPIRetryBackoff: bicloud.DefaultCPIRetryBackoff,
+					},
+				}
+				boshCmd.Opts = createEnvOpts
+			})
+
+			It("rejects recording CPI calls when the manifest selects the fake CPI", func() {
+				createEnvOpts.RecordCPI = "/cassette.json"
+
+				err := boshCmd.Execute()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Expected --record-cpi and --replay-cpi not to be used with the local fake CPI"))
+			})
+
+			It("rejects retry settings when the manifest selects the fake CPI", func() {
+				createEnvOpts.CPIMaxAttempts = 5
+
+				err := boshCmd.Execute()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Expected --cpi-max-attempts, --cpi-retry-backoff and --cpi-timeout not to be used with the local fake CPI"))
+			})
+		})
+
 		It("returns error for unknown commands", func() {
 			err := boshCmd.Execute()
 			Expect(err).To(HaveOccurred())
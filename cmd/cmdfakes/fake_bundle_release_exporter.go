@@ -0,0 +1,116 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package cmdfakes
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/director"
+)
+
+type FakeBundleReleaseExporter struct {
+	ExportReleaseStub        func(director.ReleaseSlug, director.OSVersionSlug, string) error
+	exportReleaseMutex       sync.RWMutex
+	exportReleaseArgsForCall []struct {
+		arg1 director.ReleaseSlug
+		arg2 director.OSVersionSlug
+		arg3 string
+	}
+	exportReleaseReturns struct {
+		result1 error
+	}
+	exportReleaseReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeBundleReleaseExporter) ExportRelease(arg1 director.ReleaseSlug, arg2 director.OSVersionSlug, arg3 string) error {
+	fake.exportReleaseMutex.Lock()
+	ret, specificReturn := fake.exportReleaseReturnsOnCall[len(fake.exportReleaseArgsForCall)]
+	fake.exportReleaseArgsForCall = append(fake.exportReleaseArgsForCall, struct {
+		arg1 director.ReleaseSlug
+		arg2 director.OSVersionSlug
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ExportReleaseStub
+	fakeReturns := fake.exportReleaseReturns
+	fake.recordInvocation("ExportRelease", []interface{}{arg1, arg2, arg3})
+	fake.exportReleaseMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeBundleReleaseExporter) ExportReleaseCallCount() int {
+	fake.exportReleaseMutex.RLock()
+	defer fake.exportReleaseMutex.RUnlock()
+	return len(fake.exportReleaseArgsForCall)
+}
+
+func (fake *FakeBundleReleaseExporter) ExportReleaseCalls(stub func(director.ReleaseSlug, director.OSVersionSlug, string) error) {
+	fake.exportReleaseMutex.Lock()
+	defer fake.exportReleaseMutex.Unlock()
+	fake.ExportReleaseStub = stub
+}
+
+func (fake *FakeBundleReleaseExporter) ExportReleaseArgsForCall(i int) (director.ReleaseSlug, director.OSVersionSlug, string) {
+	fake.exportReleaseMutex.RLock()
+	defer fake.exportReleaseMutex.RUnlock()
+	argsForCall := fake.exportReleaseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeBundleReleaseExporter) ExportReleaseReturns(result1 error) {
+	fake.exportReleaseMutex.Lock()
+	defer fake.exportReleaseMutex.Unlock()
+	fake.ExportReleaseStub = nil
+	fake.exportReleaseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeBundleReleaseExporter) ExportReleaseReturnsOnCall(i int, result1 error) {
+	fake.exportReleaseMutex.Lock()
+	defer fake.exportReleaseMutex.Unlock()
+	fake.ExportReleaseStub = nil
+	if fake.exportReleaseReturnsOnCall == nil {
+		fake.exportReleaseReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.exportReleaseReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeBundleReleaseExporter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.exportReleaseMutex.RLock()
+	defer fake.exportReleaseMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeBundleReleaseExporter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ cmd.BundleReleaseExporter = new(FakeBundleReleaseExporter)
@@ -0,0 +1,116 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package cmdfakes
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+)
+
+type FakeReleaseJobSpecsLoader struct {
+	ReadStub        func(string) (cmd.ReleaseJobSpecs, error)
+	readMutex       sync.RWMutex
+	readArgsForCall []struct {
+		arg1 string
+	}
+	readReturns struct {
+		result1 cmd.ReleaseJobSpecs
+		result2 error
+	}
+	readReturnsOnCall map[int]struct {
+		result1 cmd.ReleaseJobSpecs
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeReleaseJobSpecsLoader) Read(arg1 string) (cmd.ReleaseJobSpecs, error) {
+	fake.readMutex.Lock()
+	ret, specificReturn := fake.readReturnsOnCall[len(fake.readArgsForCall)]
+	fake.readArgsForCall = append(fake.readArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ReadStub
+	fakeReturns := fake.readReturns
+	fake.recordInvocation("Read", []interface{}{arg1})
+	fake.readMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeReleaseJobSpecsLoader) ReadCallCount() int {
+	fake.readMutex.RLock()
+	defer fake.readMutex.RUnlock()
+	return len(fake.readArgsForCall)
+}
+
+func (fake *FakeReleaseJobSpecsLoader) ReadCalls(stub func(string) (cmd.ReleaseJobSpecs, error)) {
+	fake.readMutex.Lock()
+	defer fake.readMutex.Unlock()
+	fake.ReadStub = stub
+}
+
+func (fake *FakeReleaseJobSpecsLoader) ReadArgsForCall(i int) string {
+	fake.readMutex.RLock()
+	defer fake.readMutex.RUnlock()
+	argsForCall := fake.readArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeReleaseJobSpecsLoader) ReadReturns(result1 cmd.ReleaseJobSpecs, result2 error) {
+	fake.readMutex.Lock()
+	defer fake.readMutex.Unlock()
+	fake.ReadStub = nil
+	fake.readReturns = struct {
+		result1 cmd.ReleaseJobSpecs
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeReleaseJobSpecsLoader) ReadReturnsOnCall(i int, result1 cmd.ReleaseJobSpecs, result2 error) {
+	fake.readMutex.Lock()
+	defer fake.readMutex.Unlock()
+	fake.ReadStub = nil
+	if fake.readReturnsOnCall == nil {
+		fake.readReturnsOnCall = make(map[int]struct {
+			result1 cmd.ReleaseJobSpecs
+			result2 error
+		})
+	}
+	fake.readReturnsOnCall[i] = struct {
+		result1 cmd.ReleaseJobSpecs
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeReleaseJobSpecsLoader) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.readMutex.RLock()
+	defer fake.readMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeReleaseJobSpecsLoader) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ cmd.ReleaseJobSpecsLoader = new(FakeReleaseJobSpecsLoader)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cloudfoundry/bosh-cli/v7/cmd"
 	"github.com/cloudfoundry/bosh-cli/v7/cmd/config"
+	"github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 )
 
 type FakeSessionContext struct {
@@ -59,6 +60,16 @@ type FakeSessionContext struct {
 	environmentReturnsOnCall map[int]struct {
 		result1 string
 	}
+	HTTPStub        func() httpclient.Opts
+	hTTPMutex       sync.RWMutex
+	hTTPArgsForCall []struct {
+	}
+	hTTPReturns struct {
+		result1 httpclient.Opts
+	}
+	hTTPReturnsOnCall map[int]struct {
+		result1 httpclient.Opts
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -328,6 +339,59 @@ func (fake *FakeSessionContext) EnvironmentReturnsOnCall(i int, result1 string)
 	}{result1}
 }
 
+func (fake *FakeSessionContext) HTTP() httpclient.Opts {
+	fake.hTTPMutex.Lock()
+	ret, specificReturn := fake.hTTPReturnsOnCall[len(fake.hTTPArgsForCall)]
+	fake.hTTPArgsForCall = append(fake.hTTPArgsForCall, struct {
+	}{})
+	stub := fake.HTTPStub
+	fakeReturns := fake.hTTPReturns
+	fake.recordInvocation("HTTP", []interface{}{})
+	fake.hTTPMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSessionContext) HTTPCallCount() int {
+	fake.hTTPMutex.RLock()
+	defer fake.hTTPMutex.RUnlock()
+	return len(fake.hTTPArgsForCall)
+}
+
+func (fake *FakeSessionContext) HTTPCalls(stub func() httpclient.Opts) {
+	fake.hTTPMutex.Lock()
+	defer fake.hTTPMutex.Unlock()
+	fake.HTTPStub = stub
+}
+
+func (fake *FakeSessionContext) HTTPReturns(result1 httpclient.Opts) {
+	fake.hTTPMutex.Lock()
+	defer fake.hTTPMutex.Unlock()
+	fake.HTTPStub = nil
+	fake.hTTPReturns = struct {
+		result1 httpclient.Opts
+	}{result1}
+}
+
+func (fake *FakeSessionContext) HTTPReturnsOnCall(i int, result1 httpclient.Opts) {
+	fake.hTTPMutex.Lock()
+	defer fake.hTTPMutex.Unlock()
+	fake.HTTPStub = nil
+	if fake.hTTPReturnsOnCall == nil {
+		fake.hTTPReturnsOnCall = make(map[int]struct {
+			result1 httpclient.Opts
+		})
+	}
+	fake.hTTPReturnsOnCall[i] = struct {
+		result1 httpclient.Opts
+	}{result1}
+}
+
 func (fake *FakeSessionContext) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -341,6 +405,8 @@ func (fake *FakeSessionContext) Invocations() map[string][][]interface{} {
 	defer fake.deploymentMutex.RUnlock()
 	fake.environmentMutex.RLock()
 	defer fake.environmentMutex.RUnlock()
+	fake.hTTPMutex.RLock()
+	defer fake.hTTPMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -157,6 +157,7 @@ var globalFlags = []string{
 	"--non-interactive\tDon't ask for user input, env: BOSH_NON_INTERACTIVE",
 	"-n\tDon't ask for user input, env: BOSH_NON_INTERACTIVE",
 	"--parallel\tThe max number of parallel operations",
+	"--ruby-erb-renderer\tRender job templates with ruby instead of the built-in renderer, env: BOSH_RUBY_ERB_RENDERER",
 	"--sha2\tUse SHA256 checksums, env: BOSH_SHA2",
 	"--tty\tForce TTY-like output, env: BOSH_TTY",
 	"--version\tShow CLI version",
@@ -194,6 +195,7 @@ var globalCommands = []string{
 	"deployment\tShow deployment information",
 	"deployments\tList deployments",
 	"diff-config\tDiff two configs by ID or content",
+	"diff-manifests\tShow structural differences between two manifests or configs",
 	"disks\tList disks",
 	"environment\tShow environment",
 	"environments\tList environments",
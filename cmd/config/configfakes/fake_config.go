@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/cloudfoundry/bosh-cli/v7/cmd/config"
+	"github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	"github.com/cloudfoundry/bosh-cli/v7/uaa"
 )
 
@@ -56,6 +57,17 @@ type FakeConfig struct {
 	environmentsReturnsOnCall map[int]struct {
 		result1 []config.Environment
 	}
+	HTTPStub        func(string) httpclient.Opts
+	hTTPMutex       sync.RWMutex
+	hTTPArgsForCall []struct {
+		arg1 string
+	}
+	hTTPReturns struct {
+		result1 httpclient.Opts
+	}
+	hTTPReturnsOnCall map[int]struct {
+		result1 httpclient.Opts
+	}
 	ResolveEnvironmentStub        func(string) string
 	resolveEnvironmentMutex       sync.RWMutex
 	resolveEnvironmentArgsForCall []struct {
@@ -370,6 +382,67 @@ func (fake *FakeConfig) EnvironmentsReturnsOnCall(i int, result1 []config.Enviro
 	}{result1}
 }
 
+func (fake *FakeConfig) HTTP(arg1 string) httpclient.Opts {
+	fake.hTTPMutex.Lock()
+	ret, specificReturn := fake.hTTPReturnsOnCall[len(fake.hTTPArgsForCall)]
+	fake.hTTPArgsForCall = append(fake.hTTPArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.HTTPStub
+	fakeReturns := fake.hTTPReturns
+	fake.recordInvocation("HTTP", []interface{}{arg1})
+	fake.hTTPMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeConfig) HTTPCallCount() int {
+	fake.hTTPMutex.RLock()
+	defer fake.hTTPMutex.RUnlock()
+	return len(fake.hTTPArgsForCall)
+}
+
+func (fake *FakeConfig) HTTPCalls(stub func(string) httpclient.Opts) {
+	fake.hTTPMutex.Lock()
+	defer fake.hTTPMutex.Unlock()
+	fake.HTTPStub = stub
+}
+
+func (fake *FakeConfig) HTTPArgsForCall(i int) string {
+	fake.hTTPMutex.RLock()
+	defer fake.hTTPMutex.RUnlock()
+	argsForCall := fake.hTTPArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeConfig) HTTPReturns(result1 httpclient.Opts) {
+	fake.hTTPMutex.Lock()
+	defer fake.hTTPMutex.Unlock()
+	fake.HTTPStub = nil
+	fake.hTTPReturns = struct {
+		result1 httpclient.Opts
+	}{result1}
+}
+
+func (fake *FakeConfig) HTTPReturnsOnCall(i int, result1 httpclient.Opts) {
+	fake.hTTPMutex.Lock()
+	defer fake.hTTPMutex.Unlock()
+	fake.HTTPStub = nil
+	if fake.hTTPReturnsOnCall == nil {
+		fake.hTTPReturnsOnCall = make(map[int]struct {
+			result1 httpclient.Opts
+		})
+	}
+	fake.hTTPReturnsOnCall[i] = struct {
+		result1 httpclient.Opts
+	}{result1}
+}
+
 func (fake *FakeConfig) ResolveEnvironment(arg1 string) string {
 	fake.resolveEnvironmentMutex.Lock()
 	ret, specificReturn := fake.resolveEnvironmentReturnsOnCall[len(fake.resolveEnvironmentArgsForCall)]
@@ -744,6 +817,8 @@ func (fake *FakeConfig) Invocations() map[string][][]interface{} {
 	defer fake.credentialsMutex.RUnlock()
 	fake.environmentsMutex.RLock()
 	defer fake.environmentsMutex.RUnlock()
+	fake.hTTPMutex.RLock()
+	defer fake.hTTPMutex.RUnlock()
 	fake.resolveEnvironmentMutex.RLock()
 	defer fake.resolveEnvironmentMutex.RUnlock()
 	fake.saveMutex.RLock()
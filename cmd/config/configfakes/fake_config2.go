@@ -2,6 +2,7 @@ package configfakes
 
 import (
 	"github.com/cloudfoundry/bosh-cli/v7/cmd/config"
+	"github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	"github.com/cloudfoundry/bosh-cli/v7/uaa"
 )
 
@@ -60,6 +61,10 @@ func (f *FakeConfig2) CACert(environment string) string {
 	return f.Existing.EnvironmentCACert
 }
 
+func (f *FakeConfig2) HTTP(environment string) httpclient.Opts {
+	return httpclient.Opts{}
+}
+
 func (f *FakeConfig2) Credentials(environment string) config.Creds {
 	panic("Not implemented")
 }
@@ -2,11 +2,13 @@ package config
 
 import (
 	"os"
+	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"gopkg.in/yaml.v2"
 
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	"github.com/cloudfoundry/bosh-cli/v7/uaa"
 )
 
@@ -16,6 +18,16 @@ environments:
   ca_cert: |...
   username: admin
   password: admin
+  http:
+    attempts: 10
+    retry_delay: 2s
+    request_timeout: 1m
+    upload_idle_timeout: 5m
+    download_idle_timeout: 5m
+    upload_chunk_size: 67108864
+    proxy: socks5://localhost:1080
+    client_cert: |...
+    client_key: |...
 */
 
 type FSConfig struct {
@@ -41,6 +53,23 @@ type fsConfigSchema_Environment struct {
 	AccessTokenType string `yaml:"access_token_type,omitempty"`
 	AccessToken     string `yaml:"access_token,omitempty"`
 	RefreshToken    string `yaml:"refresh_token,omitempty"`
+
+	HTTP *fsConfigSchema_HTTP `yaml:"http,omitempty"`
+}
+
+type fsConfigSchema_HTTP struct {
+	Attempts   uint          `yaml:"attempts,omitempty"`
+	RetryDelay time.Duration `yaml:"retry_delay,omitempty"`
+
+	RequestTimeout      time.Duration `yaml:"request_timeout,omitempty"`
+	UploadIdleTimeout   time.Duration `yaml:"upload_idle_timeout,omitempty"`
+	DownloadIdleTimeout time.Duration `yaml:"download_idle_timeout,omitempty"`
+	UploadChunkSize     int64         `yaml:"upload_chunk_size,omitempty"`
+
+	Proxy string `yaml:"proxy,omitempty"`
+
+	ClientCert string `yaml:"client_cert,omitempty"`
+	ClientKey  string `yaml:"client_key,omitempty"`
 }
 
 func NewFSConfigFromPath(path string, fs boshsys.FileSystem) (FSConfig, error) {
@@ -128,6 +157,29 @@ func (c FSConfig) CACert(urlOrAlias string) string {
 	return tg.CACert
 }
 
+func (c FSConfig) HTTP(urlOrAlias string) bihttpclient.Opts {
+	_, tg := c.findOrCreateEnvironment(urlOrAlias)
+
+	if tg.HTTP == nil {
+		return bihttpclient.Opts{}
+	}
+
+	return bihttpclient.Opts{
+		MaxAttempts: tg.HTTP.Attempts,
+		RetryDelay:  tg.HTTP.RetryDelay,
+
+		RequestTimeout:      tg.HTTP.RequestTimeout,
+		UploadIdleTimeout:   tg.HTTP.UploadIdleTimeout,
+		DownloadIdleTimeout: tg.HTTP.DownloadIdleTimeout,
+		UploadChunkSize:     tg.HTTP.UploadChunkSize,
+
+		Proxy: tg.HTTP.Proxy,
+
+		ClientCert: tg.HTTP.ClientCert,
+		ClientKey:  tg.HTTP.ClientKey,
+	}
+}
+
 func (c FSConfig) Credentials(urlOrAlias string) Creds {
 	_, tg := c.findOrCreateEnvironment(urlOrAlias)
 
@@ -3,12 +3,14 @@ package config_test
 import (
 	"errors"
 	"os"
+	"time"
 
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/config"
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	"github.com/cloudfoundry/bosh-cli/v7/uaa"
 )
 
@@ -293,6 +295,64 @@ var _ = Describe("FSConfig", func() {
 		})
 	})
 
+	Describe("HTTP", func() {
+		It("returns empty options if environment is not found", func() {
+			Expect(config.HTTP("url")).To(Equal(bihttpclient.Opts{}))
+		})
+
+		It("returns HTTP options for environment url or alias", func() {
+			err := fs.WriteFileString("/dir/sub-dir/config", `
+environments:
+- url: url
+  alias: alias
+  http:
+    attempts: 10
+    retry_delay: 2s
+    request_timeout: 1m
+    upload_idle_timeout: 5m
+    download_idle_timeout: 90s
+    upload_chunk_size: 1048576
+    proxy: socks5://localhost:1080
+    client_cert: cert
+    client_key: key
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedOpts := bihttpclient.Opts{
+				MaxAttempts:         10,
+				RetryDelay:          2 * time.Second,
+				RequestTimeout:      time.Minute,
+				UploadIdleTimeout:   5 * time.Minute,
+				DownloadIdleTimeout: 90 * time.Second,
+				UploadChunkSize:     1048576,
+				Proxy:               "socks5://localhost:1080",
+				ClientCert:          "cert",
+				ClientKey:           "key",
+			}
+
+			config = readConfig()
+			Expect(config.HTTP("url")).To(Equal(expectedOpts))
+			Expect(config.HTTP("alias")).To(Equal(expectedOpts))
+		})
+
+		It("keeps HTTP options when config is saved", func() {
+			err := fs.WriteFileString("/dir/sub-dir/config", `
+environments:
+- url: url
+  http: {attempts: 3, request_timeout: 30s}
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			updatedConfig := readConfig().SetCredentials("url", Creds{Client: "admin"})
+			Expect(updatedConfig.Save()).To(Succeed())
+
+			Expect(readConfig().HTTP("url")).To(Equal(bihttpclient.Opts{
+				MaxAttempts:    3,
+				RequestTimeout: 30 * time.Second,
+			}))
+		})
+	})
+
 	Describe("ResolveEnvironment", func() {
 		It("returns url if it's a known url", func() {
 			updatedConfig, err := config.AliasEnvironment("url", "alias", "")
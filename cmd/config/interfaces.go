@@ -1,6 +1,9 @@
 package config
 
-import "github.com/cloudfoundry/bosh-cli/v7/uaa"
+import (
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
+	"github.com/cloudfoundry/bosh-cli/v7/uaa"
+)
 
 // You only need **one** of these per package!
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -14,6 +17,7 @@ type Config interface {
 	UnaliasEnvironment(alias string) (Config, error)
 
 	CACert(url string) string
+	HTTP(url string) bihttpclient.Opts
 
 	Credentials(url string) Creds
 	SetCredentials(url string, creds Creds) Config
@@ -24,5 +24,9 @@ func (c *CreateEnvCmd) Run(stage boshui.Stage, opts CreateEnvOpts) error {
 
 	depPreparer := c.envProvider(opts.Args.Manifest.Path, opts.StatePath, opts.VarFlags.AsVariables(), opts.OpsFlags.AsOp()) //nolint:staticcheck
 
+	if opts.DryRun {
+		return depPreparer.PlanDeployment(stage, opts.Recreate, opts.RecreatePersistentDisks)
+	}
+
 	return depPreparer.PrepareDeployment(stage, opts.Recreate, opts.RecreatePersistentDisks, opts.SkipDrain)
 }
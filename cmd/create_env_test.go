@@ -85,6 +85,7 @@ var _ = Describe("CreateEnvCmd", func() {
 			manifestSHA   string
 
 			mockDeployer         *mockdeployment.MockDeployer
+			mockPlanner          *mockdeployment.MockPlanner
 			mockInstaller        *mockinstall.MockInstaller
 			mockInstallerFactory *mockinstall.MockInstallerFactory
 			releaseReader        *fakebirel.FakeReader
@@ -169,6 +170,7 @@ var _ = Describe("CreateEnvCmd", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			mockDeployer = mockdeployment.NewMockDeployer(mockCtrl)
+			mockPlanner = mockdeployment.NewMockPlanner(mockCtrl)
 			mockInstaller = mockinstall.NewMockInstaller(mockCtrl)
 			mockInstallerFactory = mockinstall.NewMockInstallerFactory(mockCtrl)
 
@@ -202,7 +204,7 @@ var _ = Describe("CreateEnvCmd", func() {
 
 			configUUIDGenerator = &fakeuuid.FakeGenerator{}
 			configUUIDGenerator.GeneratedUUID = directorID
-			setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, configUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""))
+			setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, configUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""), nil)
 
 			fakeDeploymentValidator = fakebideplmanifest.NewFakeValidator()
 
@@ -308,7 +310,7 @@ var _ = Describe("CreateEnvCmd", func() {
 
 		JustBeforeEach(func() {
 			doGet := func(deploymentManifestPath string, statePath string, deploymentVars boshtpl.Variables, deploymentOp patch.Op) cmd.DeploymentPreparer {
-				deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, configUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, statePath))
+				deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, configUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, statePath), nil)
 				deploymentRepo := biconfig.NewDeploymentRepo(deploymentStateService)
 				releaseRepo := biconfig.NewReleaseRepo(deploymentStateService, fakeUUIDGenerator)
 				stemcellRepo := biconfig.NewStemcellRepo(deploymentStateService, fakeUUIDGenerator)
@@ -353,6 +355,7 @@ var _ = Describe("CreateEnvCmd", func() {
 					logger,
 					"deployCmd",
 					deploymentStateService,
+					false,
 					mockLegacyDeploymentStateMigrator,
 					releaseManager,
 					deploymentRecord,
@@ -372,6 +375,7 @@ var _ = Describe("CreateEnvCmd", func() {
 					deploymentManifestParser,
 					tempRootConfigurator,
 					targetProvider,
+					mockPlanner,
 				)
 			}
 
@@ -718,6 +722,83 @@ var _ = Describe("CreateEnvCmd", func() {
 			})
 		})
 
+		Context("when --dry-run is specified", func() {
+			BeforeEach(func() {
+				defaultCreateEnvOpts.DryRun = true
+			})
+
+			It("prints the plan without installing the CPI or deploying", func() {
+				expectInstall.Times(0)
+				expectNewCloud.Times(0)
+				expectDeploy.Times(0)
+				expectStemcellUpload.Times(0)
+
+				mockPlanner.EXPECT().Plan(
+					boshDeploymentManifest,
+					extractedStemcell,
+					manifestSHA,
+					gomock.Any(),
+					biconfig.DeploymentState{},
+					false,
+					false,
+				).Return(deployment.Plan{Changes: []deployment.PlannedChange{{
+					Resource: "stemcell",
+					Name:     "fake-stemcell-name/fake-stemcell-version",
+					Action:   deployment.PlanActionUpload,
+					Reason:   "stemcell has not been uploaded",
+				}}}, nil)
+
+				err := command.Run(fakeStage, defaultCreateEnvOpts)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stdOut).To(gbytes.Say("stemcell has not been uploaded"))
+				Expect(stdOut).To(gbytes.Say("Dry run: the CPI was not called and the deployment state was not changed."))
+			})
+
+			It("does not create the deployment state", func() {
+				mockPlanner.EXPECT().Plan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+
+				err := command.Run(fakeStage, defaultCreateEnvOpts)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fs.FileExists(deploymentStatePath)).To(BeFalse())
+			})
+
+			It("plans against the saved deployment state", func() {
+				previousDeploymentState := biconfig.DeploymentState{
+					DirectorID:         directorID,
+					CurrentManifestSHA: "fake-previous-manifest-sha",
+				}
+
+				err := setupDeploymentStateService.Save(previousDeploymentState)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockPlanner.EXPECT().Plan(
+					boshDeploymentManifest,
+					extractedStemcell,
+					manifestSHA,
+					gomock.Any(),
+					previousDeploymentState,
+					true,
+					false,
+				)
+
+				defaultCreateEnvOpts.Recreate = true
+
+				err = command.Run(fakeStage, defaultCreateEnvOpts)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns planning errors", func() {
+				mockPlanner.EXPECT().Plan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(deployment.Plan{}, errors.New("fake-plan-error"))
+
+				err := command.Run(fakeStage, defaultCreateEnvOpts)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Planning deployment: fake-plan-error"))
+			})
+		})
+
 		Context("when parsing the cpi deployment manifest fails", func() {
 			JustBeforeEach(func() {
 				manifest := bideplmanifest.Manifest{}
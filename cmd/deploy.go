@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"fmt"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	"gopkg.in/yaml.v3"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdiff "github.com/cloudfoundry/bosh-cli/v7/diff"
 	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
@@ -15,6 +18,7 @@ type DeployCmd struct {
 	deployment      boshdir.Deployment
 	releaseUploader ReleaseUploader
 	director        boshdir.Director
+	policyChecker   DeployPolicyChecker
 }
 
 type ReleaseUploader interface {
@@ -33,11 +37,16 @@ func NewDeployCmd(
 	deployment boshdir.Deployment,
 	releaseUploader ReleaseUploader,
 	director boshdir.Director,
+	policyChecker DeployPolicyChecker,
 ) DeployCmd {
-	return DeployCmd{ui, deployment, releaseUploader, director}
+	return DeployCmd{ui, deployment, releaseUploader, director, policyChecker}
 }
 
 func (c DeployCmd) Run(opts DeployOpts) error {
+	if opts.DiffFormat.IsPatch() && !opts.NoRedact {
+		return bosherr.Errorf("Expected --no-redact with --diff-format %s since redacted values would be applied", opts.DiffFormat)
+	}
+
 	tpl := boshtpl.NewTemplate(opts.Args.Manifest.Bytes)
 
 	configs, _ := c.director.ListConfigs(1, boshdir.ConfigsFilter{Type: "deploy"}) //nolint:errcheck
@@ -77,6 +86,11 @@ func (c DeployCmd) Run(opts DeployOpts) error {
 		return err
 	}
 
+	policyContext, err := c.checkPolicies(bytes, opts)
+	if err != nil {
+		return err
+	}
+
 	if opts.FixReleases {
 		bytes, err = c.releaseUploader.UploadReleasesWithFix(bytes)
 	} else if opts.SkipUploadReleases {
@@ -93,8 +107,15 @@ func (c DeployCmd) Run(opts DeployOpts) error {
 		return err
 	}
 
-	diff := NewDiff(deploymentDiff.Diff)
-	diff.Print(c.ui)
+	if opts.DiffFormat.IsSet() {
+		err = c.printSemanticDiff(bytes, opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		diff := NewDiff(deploymentDiff.Diff)
+		diff.Print(c.ui)
+	}
 
 	err = c.ui.AskForConfirmation()
 	if err != nil {
@@ -111,11 +132,66 @@ func (c DeployCmd) Run(opts DeployOpts) error {
 		MaxInFlight:             opts.MaxInFlight,
 		Diff:                    deploymentDiff,
 		ForceLatestVariables:    opts.ForceLatestVariables,
+		Context:                 policyContext,
 	}
 
 	return c.deployment.Update(bytes, updateOpts)
 }
 
+// checkPolicies fails when policy rules are violated unless violations are
+// explicitly overridden, in which case the override is recorded in the task context.
+func (c DeployCmd) checkPolicies(bytes []byte, opts DeployOpts) (map[string]interface{}, error) {
+	violations, err := c.policyChecker.Check(c.deployment, bytes, opts)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Checking deploy policies")
+	}
+
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	PrintDeployPolicyViolations(c.ui, violations)
+
+	if len(opts.OverridePolicy) == 0 {
+		return nil, bosherr.Errorf(
+			"Expected deployment to satisfy deploy policies but found %d violation(s); use --override-policy to deploy anyway", len(violations))
+	}
+
+	c.ui.ErrorLinef("Overriding %d policy violation(s): %s", len(violations), opts.OverridePolicy)
+
+	var messages []string
+
+	for _, violation := range violations {
+		messages = append(messages, fmt.Sprintf("%s/%s: %s", violation.Policy, violation.Rule, violation.Message))
+	}
+
+	return map[string]interface{}{
+		"policy_override": map[string]interface{}{
+			"reason":     opts.OverridePolicy,
+			"violations": messages,
+		},
+	}, nil
+}
+
+// printSemanticDiff compares the new manifest with the current one on the client.
+func (c DeployCmd) printSemanticDiff(bytes []byte, opts DeployOpts) error {
+	currentManifest, err := c.deployment.Manifest()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Fetching current manifest")
+	}
+
+	changes, err := boshdiff.CompareBytes([]byte(currentManifest), bytes)
+	if err != nil {
+		return err
+	}
+
+	if !opts.NoRedact {
+		changes = boshdiff.Redact(changes)
+	}
+
+	return NewSemanticDiff([]byte(currentManifest), changes).Print(c.ui, opts.DiffFormat)
+}
+
 func setFlags(flags []string, opts DeployOpts) DeployOpts {
 	for j := range flags {
 		switch flags[j] {
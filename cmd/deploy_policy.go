@@ -0,0 +1,493 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	semver "github.com/cppforlife/go-semi-semantic/version"
+	"gopkg.in/yaml.v3"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+const DeployPolicyConfigType = "deploy-policy"
+
+const (
+	DeployPolicyRuleForbidRecreate          = "forbid_recreate"
+	DeployPolicyRuleMaxInFlight             = "max_in_flight"
+	DeployPolicyRuleForbidStemcellDowngrade = "forbid_stemcell_downgrade"
+	DeployPolicyRuleRequireDryRun           = "require_dry_run"
+)
+
+// DeployPolicy is the content of a config of type 'deploy-policy'.
+// Similarly to configs of type 'deploy' it applies to all deployments
+// unless it includes or excludes specific deployments.
+type DeployPolicy struct {
+	IncludeDeployments []string           `yaml:"include"`
+	ExcludeDeployments []string           `yaml:"exclude"`
+	Rules              []DeployPolicyRule `yaml:"rules"`
+}
+
+type DeployPolicyRule struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// During limits forbid_recreate to a time window
+	During *DeployPolicyWindow `yaml:"during"`
+
+	// Max is used by max_in_flight
+	Max int `yaml:"max"`
+
+	// Within is used by require_dry_run (e.g. 24h)
+	Within string `yaml:"within"`
+}
+
+type DeployPolicyWindow struct {
+	Days     []string `yaml:"days"`
+	Hours    string   `yaml:"hours"`
+	Timezone string   `yaml:"timezone"`
+}
+
+type DeployPolicyViolation struct {
+	Policy  string
+	Rule    string
+	Message string
+}
+
+type DeployPolicyChecker struct {
+	director    boshdir.Director
+	timeService clock.Clock
+	ui          boshui.UI
+
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewDeployPolicyChecker(director boshdir.Director, timeService clock.Clock, ui boshui.UI, logger boshlog.Logger) DeployPolicyChecker {
+	return DeployPolicyChecker{
+		director:    director,
+		timeService: timeService,
+		ui:          ui,
+
+		logTag: "DeployPolicyChecker",
+		logger: logger,
+	}
+}
+
+type policyManifest struct {
+	Stemcells      []policyManifestStemcell      `yaml:"stemcells"`
+	Update         policyManifestUpdate          `yaml:"update"`
+	InstanceGroups []policyManifestInstanceGroup `yaml:"instance_groups"`
+}
+
+type policyManifestStemcell struct {
+	Alias   string `yaml:"alias"`
+	OS      string `yaml:"os"`
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+type policyManifestUpdate struct {
+	MaxInFlight string `yaml:"max_in_flight"`
+}
+
+type policyManifestInstanceGroup struct {
+	Name      string               `yaml:"name"`
+	Instances int                  `yaml:"instances"`
+	Update    policyManifestUpdate `yaml:"update"`
+}
+
+// Check evaluates rules of all policies that apply to the deployment.
+// Policies are skipped only for Directors without configs API. Other failures
+// to fetch policies are errors unless policies are overridden, in which case
+// they are reported as a violation so that the override is recorded.
+func (c DeployPolicyChecker) Check(deployment boshdir.Deployment, bytes []byte, opts DeployOpts) ([]DeployPolicyViolation, error) {
+	configs, err := c.director.ListConfigs(1, boshdir.ConfigsFilter{Type: DeployPolicyConfigType})
+	if err != nil {
+		c.logger.Warn(c.logTag, "Fetching configs of type '%s': %s", DeployPolicyConfigType, err.Error())
+
+		if _, ok := err.(boshdir.ConfigsNotSupportedError); ok {
+			c.ui.ErrorLinef("Warning: Skipping deploy policies since Director does not support configs")
+			return nil, nil
+		}
+
+		if len(opts.OverridePolicy) == 0 {
+			return nil, bosherr.WrapErrorf(err,
+				"Fetching configs of type '%s' (use --override-policy to deploy without checking them)", DeployPolicyConfigType)
+		}
+
+		return []DeployPolicyViolation{{
+			Policy:  DeployPolicyConfigType,
+			Rule:    "fetch",
+			Message: fmt.Sprintf("Deploy policies could not be fetched: %s", err.Error()),
+		}}, nil
+	}
+
+	var manifest policyManifest
+
+	err = yaml.Unmarshal(bytes, &manifest)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing manifest")
+	}
+
+	var violations []DeployPolicyViolation
+
+	for _, config := range configs {
+		var policy DeployPolicy
+
+		err := yaml.Unmarshal([]byte(config.Content), &policy)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing config of type '%s' (name: '%s')", config.Type, config.Name)
+		}
+
+		if !policy.appliesTo(deployment.Name()) {
+			continue
+		}
+
+		for i, rule := range policy.Rules {
+			messages, err := c.checkRule(rule, deployment, manifest, opts)
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Checking rule '%s' of config '%s'", rule.displayName(i), config.Name)
+			}
+
+			for _, msg := range messages {
+				violations = append(violations, DeployPolicyViolation{
+					Policy:  config.Name,
+					Rule:    rule.displayName(i),
+					Message: msg,
+				})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+func (p DeployPolicy) appliesTo(name string) bool {
+	if p.IncludeDeployments != nil {
+		return applies(p.IncludeDeployments, name)
+	}
+
+	return !applies(p.ExcludeDeployments, name)
+}
+
+func (r DeployPolicyRule) displayName(i int) string {
+	if len(r.Name) > 0 {
+		return r.Name
+	}
+
+	return fmt.Sprintf("%s (rule %d)", r.Type, i)
+}
+
+func (c DeployPolicyChecker) checkRule(rule DeployPolicyRule, deployment boshdir.Deployment, manifest policyManifest, opts DeployOpts) ([]string, error) {
+	switch rule.Type {
+	case DeployPolicyRuleForbidRecreate:
+		return c.checkRecreate(rule, opts)
+	case DeployPolicyRuleMaxInFlight:
+		return c.checkMaxInFlight(rule, manifest, opts)
+	case DeployPolicyRuleForbidStemcellDowngrade:
+		return c.checkStemcellDowngrade(deployment, manifest)
+	case DeployPolicyRuleRequireDryRun:
+		return c.checkDryRun(rule, deployment, opts)
+	default:
+		return nil, bosherr.Errorf("Unknown rule type '%s'", rule.Type)
+	}
+}
+
+func (c DeployPolicyChecker) checkRecreate(rule DeployPolicyRule, opts DeployOpts) ([]string, error) {
+	if !opts.Recreate && !opts.RecreatePersistentDisks {
+		return nil, nil
+	}
+
+	if rule.During == nil {
+		return []string{"Recreating VMs or persistent disks is not allowed"}, nil
+	}
+
+	inWindow, err := rule.During.contains(c.timeService.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !inWindow {
+		return nil, nil
+	}
+
+	return []string{fmt.Sprintf("Recreating VMs or persistent disks is not allowed during %s", rule.During.String())}, nil
+}
+
+func (w DeployPolicyWindow) contains(now time.Time) (bool, error) {
+	if len(w.Timezone) > 0 {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Loading timezone '%s'", w.Timezone)
+		}
+		now = now.In(loc)
+	}
+
+	if len(w.Days) > 0 {
+		today := strings.ToLower(now.Weekday().String()[:3])
+		found := false
+
+		for _, day := range w.Days {
+			if strings.ToLower(day) == today {
+				found = true
+			}
+		}
+
+		if !found {
+			return false, nil
+		}
+	}
+
+	if len(w.Hours) == 0 {
+		return true, nil
+	}
+
+	pieces := strings.Split(w.Hours, "-")
+	if len(pieces) != 2 {
+		return false, bosherr.Errorf("Expected hours '%s' to be in format 'HH:MM-HH:MM'", w.Hours)
+	}
+
+	from, err := minuteOfDay(pieces[0])
+	if err != nil {
+		return false, err
+	}
+
+	to, err := minuteOfDay(pieces[1])
+	if err != nil {
+		return false, err
+	}
+
+	current := now.Hour()*60 + now.Minute()
+
+	// Windows such as 22:00-06:00 wrap around midnight
+	if from <= to {
+		return current >= from && current < to, nil
+	}
+
+	return current >= from || current < to, nil
+}
+
+func (w DeployPolicyWindow) String() string {
+	var pieces []string
+
+	if len(w.Days) > 0 {
+		pieces = append(pieces, strings.Join(w.Days, ", "))
+	}
+
+	if len(w.Hours) > 0 {
+		pieces = append(pieces, w.Hours)
+	}
+
+	if len(w.Timezone) > 0 {
+		pieces = append(pieces, fmt.Sprintf("(%s)", w.Timezone))
+	}
+
+	return strings.Join(pieces, " ")
+}
+
+func minuteOfDay(str string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(str))
+	if err != nil {
+		return 0, bosherr.Errorf("Expected time '%s' to be in format 'HH:MM'", str)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (c DeployPolicyChecker) checkMaxInFlight(rule DeployPolicyRule, manifest policyManifest, opts DeployOpts) ([]string, error) {
+	var messages []string
+
+	if len(manifest.InstanceGroups) == 0 {
+		maxInFlight := manifest.Update.MaxInFlight
+		if len(opts.MaxInFlight) > 0 {
+			maxInFlight = opts.MaxInFlight
+		}
+
+		value, err := resolveMaxInFlight(maxInFlight, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if value > rule.Max {
+			messages = append(messages, fmt.Sprintf("Expected max_in_flight to be at most %d but was %d", rule.Max, value))
+		}
+
+		return messages, nil
+	}
+
+	for _, group := range manifest.InstanceGroups {
+		// Flag overrides instance group values which override global values
+		maxInFlight := manifest.Update.MaxInFlight
+		if len(group.Update.MaxInFlight) > 0 {
+			maxInFlight = group.Update.MaxInFlight
+		}
+		if len(opts.MaxInFlight) > 0 {
+			maxInFlight = opts.MaxInFlight
+		}
+
+		value, err := resolveMaxInFlight(maxInFlight, group.Instances)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Instance group '%s'", group.Name)
+		}
+
+		if value > rule.Max {
+			messages = append(messages, fmt.Sprintf(
+				"Expected max_in_flight for instance group '%s' to be at most %d but was %d", group.Name, rule.Max, value))
+		}
+	}
+
+	return messages, nil
+}
+
+// resolveMaxInFlight converts percentages into number of instances (at least 1).
+func resolveMaxInFlight(str string, instances int) (int, error) {
+	if len(str) == 0 {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(str, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+		if err != nil {
+			return 0, bosherr.Errorf("Expected max_in_flight '%s' to be a number or a percentage", str)
+		}
+
+		return int(math.Max(1, math.Ceil(percent*float64(instances)/100))), nil
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, bosherr.Errorf("Expected max_in_flight '%s' to be a number or a percentage", str)
+	}
+
+	return value, nil
+}
+
+func (c DeployPolicyChecker) checkStemcellDowngrade(deployment boshdir.Deployment, manifest policyManifest) ([]string, error) {
+	deployments, err := c.director.ListDeployments()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Listing deployments")
+	}
+
+	exists := false
+
+	for _, dep := range deployments {
+		if dep.Name == deployment.Name() {
+			exists = true
+		}
+	}
+
+	// New deployments cannot downgrade stemcells
+	if !exists {
+		return nil, nil
+	}
+
+	currentBytes, err := deployment.Manifest()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Fetching current manifest")
+	}
+
+	var current policyManifest
+
+	err = yaml.Unmarshal([]byte(currentBytes), &current)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing current manifest")
+	}
+
+	currentStemcells := map[string]policyManifestStemcell{}
+
+	for _, stemcell := range current.Stemcells {
+		currentStemcells[stemcell.Alias] = stemcell
+	}
+
+	var messages []string
+
+	for _, stemcell := range manifest.Stemcells {
+		currentStemcell, found := currentStemcells[stemcell.Alias]
+		if !found || stemcell.Version == "latest" || currentStemcell.Version == "latest" {
+			continue
+		}
+
+		if stemcell.OS != currentStemcell.OS || stemcell.Name != currentStemcell.Name {
+			continue
+		}
+
+		newVersion, err := semver.NewVersionFromString(stemcell.Version)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing version of stemcell '%s'", stemcell.Alias)
+		}
+
+		currentVersion, err := semver.NewVersionFromString(currentStemcell.Version)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing current version of stemcell '%s'", stemcell.Alias)
+		}
+
+		if newVersion.IsLt(currentVersion) {
+			messages = append(messages, fmt.Sprintf(
+				"Expected stemcell '%s' not to be downgraded from version '%s' to '%s'",
+				stemcell.Alias, currentStemcell.Version, stemcell.Version))
+		}
+	}
+
+	return messages, nil
+}
+
+func (c DeployPolicyChecker) checkDryRun(rule DeployPolicyRule, deployment boshdir.Deployment, opts DeployOpts) ([]string, error) {
+	if opts.DryRun {
+		return nil, nil
+	}
+
+	within, err := time.ParseDuration(rule.Within)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing duration '%s'", rule.Within)
+	}
+
+	tasks, err := c.director.RecentTasks(100, boshdir.TasksFilter{Deployment: deployment.Name()})
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Fetching recent tasks")
+	}
+
+	since := c.timeService.Now().Add(-within)
+
+	for _, task := range tasks {
+		isDryRun := strings.Contains(task.Description(), "dry run")
+
+		if isDryRun && task.State() == "done" && !task.StartedAt().Before(since) {
+			return nil, nil
+		}
+	}
+
+	return []string{fmt.Sprintf("Expected a successful dry run of deployment '%s' within the last %s", deployment.Name(), rule.Within)}, nil
+}
+
+// PrintDeployPolicyViolations prints violations as a table.
+func PrintDeployPolicyViolations(ui boshui.UI, violations []DeployPolicyViolation) {
+	table := boshtbl.Table{
+		Content: "policy violations",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Policy"),
+			boshtbl.NewHeader("Rule"),
+			boshtbl.NewHeader("Violation"),
+		},
+	}
+
+	for _, violation := range violations {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(violation.Policy),
+			boshtbl.NewValueString(violation.Rule),
+			boshtbl.NewValueString(violation.Message),
+		})
+	}
+
+	ui.PrintTable(table)
+}
@@ -0,0 +1,325 @@
+package cmd_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	fakedir "github.com/cloudfoundry/bosh-cli/v7/director/directorfakes"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("DeployPolicyChecker", func() {
+	var (
+		ui         *fakeui.FakeUI
+		director   *fakedir.FakeDirector
+		deployment *fakedir.FakeDeployment
+		now        time.Time
+		deployOpts opts.DeployOpts
+		manifest   string
+		policy     string
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		director = &fakedir.FakeDirector{}
+		deployment = &fakedir.FakeDeployment{
+			NameStub: func() string { return "dep" },
+		}
+
+		// Monday
+		now = time.Date(2024, time.March, 4, 10, 30, 0, 0, time.UTC)
+		deployOpts = opts.DeployOpts{}
+		manifest = "name: dep"
+		policy = ""
+
+		director.ListConfigsStub = func(limit int, filter boshdir.ConfigsFilter) ([]boshdir.Config, error) {
+			return []boshdir.Config{{Name: "safety", Type: filter.Type, Content: policy}}, nil
+		}
+	})
+
+	check := func() ([]cmd.DeployPolicyViolation, error) {
+		checker := cmd.NewDeployPolicyChecker(director, fakeclock.NewFakeClock(now), ui, boshlog.NewLogger(boshlog.LevelNone))
+		return checker.Check(deployment, []byte(manifest), deployOpts)
+	}
+
+	messages := func(violations []cmd.DeployPolicyViolation) []string {
+		var result []string
+		for _, violation := range violations {
+			result = append(result, violation.Message)
+		}
+		return result
+	}
+
+	It("fetches configs of type deploy-policy", func() {
+		_, err := check()
+		Expect(err).ToNot(HaveOccurred())
+
+		limit, filter := director.ListConfigsArgsForCall(0)
+		Expect(limit).To(Equal(1))
+		Expect(filter).To(Equal(boshdir.ConfigsFilter{Type: "deploy-policy"}))
+	})
+
+	It("warns and skips policies if director does not support configs", func() {
+		director.ListConfigsStub = nil
+		director.ListConfigsReturns(nil, boshdir.ConfigsNotSupportedError{Err: errors.New("fake-err")})
+
+		violations, err := check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(violations).To(BeEmpty())
+
+		Expect(ui.Errors).To(Equal([]string{
+			"Warning: Skipping deploy policies since Director does not support configs",
+		}))
+	})
+
+	It("returns error if configs cannot be fetched", func() {
+		director.ListConfigsStub = nil
+		director.ListConfigsReturns(nil, errors.New("fake-err"))
+
+		_, err := check()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Fetching configs of type 'deploy-policy'"))
+		Expect(err.Error()).To(ContainSubstring("fake-err"))
+	})
+
+	It("reports a violation if configs cannot be fetched and policies are overridden", func() {
+		director.ListConfigsStub = nil
+		director.ListConfigsReturns(nil, errors.New("fake-err"))
+		deployOpts.OverridePolicy = "director is degraded"
+
+		violations, err := check()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(violations).To(Equal([]cmd.DeployPolicyViolation{{
+			Policy:  "deploy-policy",
+			Rule:    "fetch",
+			Message: "Deploy policies could not be fetched: fake-err",
+		}}))
+	})
+
+	It("returns error for unknown rule types", func() {
+		policy = "rules:\n- type: unknown"
+
+		_, err := check()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown rule type 'unknown'"))
+	})
+
+	Describe("include and exclude", func() {
+		BeforeEach(func() {
+			deployOpts.Recreate = true
+		})
+
+		It("skips policies that do not include the deployment", func() {
+			policy = "include: [other]\nrules:\n- type: forbid_recreate"
+			Expect(check()).To(BeEmpty())
+		})
+
+		It("skips policies that exclude the deployment", func() {
+			policy = "exclude: [dep]\nrules:\n- type: forbid_recreate"
+			Expect(check()).To(BeEmpty())
+		})
+
+		It("checks policies that include the deployment", func() {
+			policy = "include: [dep]\nrules:\n- type: forbid_recreate"
+
+			violations, err := check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(Equal([]cmd.DeployPolicyViolation{{
+				Policy:  "safety",
+				Rule:    "forbid_recreate (rule 0)",
+				Message: "Recreating VMs or persistent disks is not allowed",
+			}}))
+		})
+	})
+
+	Describe("forbid_recreate", func() {
+		BeforeEach(func() {
+			policy = `
+rules:
+- type: forbid_recreate
+  during: {days: [mon, tue], hours: "09:00-17:00", timezone: UTC}
+`
+		})
+
+		It("allows deploys without recreating", func() {
+			Expect(check()).To(BeEmpty())
+		})
+
+		It("reports recreating during the window", func() {
+			deployOpts.RecreatePersistentDisks = true
+
+			violations, err := check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages(violations)).To(Equal([]string{
+				"Recreating VMs or persistent disks is not allowed during mon, tue 09:00-17:00 (UTC)",
+			}))
+		})
+
+		It("allows recreating outside of hours", func() {
+			deployOpts.Recreate = true
+			now = time.Date(2024, time.March, 4, 17, 0, 0, 0, time.UTC)
+			Expect(check()).To(BeEmpty())
+		})
+
+		It("allows recreating outside of days", func() {
+			deployOpts.Recreate = true
+			now = time.Date(2024, time.March, 6, 10, 0, 0, 0, time.UTC)
+			Expect(check()).To(BeEmpty())
+		})
+
+		It("uses timezone of the window", func() {
+			policy = `
+rules:
+- type: forbid_recreate
+  during: {hours: "22:00-06:00", timezone: America/New_York}
+`
+			deployOpts.Recreate = true
+			now = time.Date(2024, time.March, 4, 4, 0, 0, 0, time.UTC)
+
+			Expect(check()).To(HaveLen(1))
+		})
+
+		It("returns error if hours are invalid", func() {
+			policy = "rules:\n- type: forbid_recreate\n  during: {hours: '9-5'}"
+			deployOpts.Recreate = true
+
+			_, err := check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected time '9' to be in format 'HH:MM'"))
+		})
+	})
+
+	Describe("max_in_flight", func() {
+		BeforeEach(func() {
+			policy = "rules:\n- type: max_in_flight\n  max: 2"
+			manifest = `
+name: dep
+update: {max_in_flight: 1}
+instance_groups:
+- name: web
+  instances: 10
+  update: {max_in_flight: 30%}
+- name: db
+  instances: 3
+`
+		})
+
+		It("reports instance groups exceeding the maximum", func() {
+			violations, err := check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages(violations)).To(Equal([]string{
+				"Expected max_in_flight for instance group 'web' to be at most 2 but was 3",
+			}))
+		})
+
+		It("uses value from the flag", func() {
+			deployOpts.MaxInFlight = "5"
+
+			violations, err := check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(HaveLen(2))
+		})
+
+		It("returns error for invalid values", func() {
+			deployOpts.MaxInFlight = "many"
+
+			_, err := check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected max_in_flight 'many' to be a number or a percentage"))
+		})
+	})
+
+	Describe("forbid_stemcell_downgrade", func() {
+		BeforeEach(func() {
+			policy = "rules:\n- type: forbid_stemcell_downgrade"
+			manifest = `
+name: dep
+stemcells:
+- {alias: default, os: ubuntu-jammy, version: "1.200"}
+- {alias: other, os: ubuntu-jammy, version: latest}
+`
+			director.ListDeploymentsReturns([]boshdir.DeploymentResp{{Name: "dep"}}, nil)
+			deployment.ManifestReturns(`
+stemcells:
+- {alias: default, os: ubuntu-jammy, version: "1.300"}
+- {alias: other, os: ubuntu-jammy, version: "1.1"}
+`, nil)
+		})
+
+		It("reports downgraded stemcells", func() {
+			violations, err := check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages(violations)).To(Equal([]string{
+				"Expected stemcell 'default' not to be downgraded from version '1.300' to '1.200'",
+			}))
+		})
+
+		It("skips new deployments", func() {
+			director.ListDeploymentsReturns(nil, nil)
+			Expect(check()).To(BeEmpty())
+			Expect(deployment.ManifestCallCount()).To(Equal(0))
+		})
+
+		It("returns error if current manifest cannot be fetched", func() {
+			deployment.ManifestReturns("", errors.New("fake-err"))
+
+			_, err := check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Fetching current manifest"))
+		})
+	})
+
+	Describe("require_dry_run", func() {
+		var (
+			task *fakedir.FakeTask
+		)
+
+		BeforeEach(func() {
+			policy = "rules:\n- type: require_dry_run\n  within: 24h"
+
+			task = &fakedir.FakeTask{}
+			task.DescriptionReturns("create deployment (dry run)")
+			task.StateReturns("done")
+			task.StartedAtReturns(now.Add(-time.Hour))
+
+			director.RecentTasksReturns([]boshdir.Task{task}, nil)
+		})
+
+		It("allows deploys after a recent dry run", func() {
+			Expect(check()).To(BeEmpty())
+
+			_, filter := director.RecentTasksArgsForCall(0)
+			Expect(filter).To(Equal(boshdir.TasksFilter{Deployment: "dep"}))
+		})
+
+		It("reports missing dry runs", func() {
+			task.StartedAtReturns(now.Add(-25 * time.Hour))
+
+			violations, err := check()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages(violations)).To(Equal([]string{
+				"Expected a successful dry run of deployment 'dep' within the last 24h",
+			}))
+		})
+
+		It("reports failed dry runs", func() {
+			task.StateReturns("error")
+			Expect(check()).To(HaveLen(1))
+		})
+
+		It("allows dry runs", func() {
+			director.RecentTasksReturns(nil, nil)
+			deployOpts.DryRun = true
+
+			Expect(check()).To(BeEmpty())
+		})
+	})
+})
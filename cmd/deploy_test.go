@@ -2,7 +2,10 @@ package cmd_test
 
 import (
 	"errors"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/cppforlife/go-patch/patch"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -14,6 +17,7 @@ import (
 	fakedir "github.com/cloudfoundry/bosh-cli/v7/director/directorfakes"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
 )
 
 var _ = Describe("DeployCmd", func() {
@@ -37,7 +41,9 @@ var _ = Describe("DeployCmd", func() {
 
 		director = &fakedir.FakeDirector{}
 
-		command = cmd.NewDeployCmd(ui, deployment, releaseUploader, director)
+		policyChecker := cmd.NewDeployPolicyChecker(director, fakeclock.NewFakeClock(time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)), ui, boshlog.NewLogger(boshlog.LevelNone))
+
+		command = cmd.NewDeployCmd(ui, deployment, releaseUploader, director, policyChecker)
 	})
 
 	Describe("Run", func() {
@@ -286,6 +292,74 @@ releases:
 			Expect(ui.Said).To(ContainElement("- some line that was removed\n"))
 		})
 
+		It("compares with the current manifest on the client if diff format is set", func() {
+			deployOpts.Args.Manifest.Bytes = []byte("name: dep\ninstance_groups: [{name: web, instances: 2, properties: {password: new}}]")
+			deployOpts.DiffFormat = opts.DiffFormatUnified
+
+			deployment.ManifestReturns("name: dep\ninstance_groups: [{name: web, instances: 1, properties: {password: old}}]", nil)
+			deployment.DiffReturns(boshdir.NewDeploymentDiff([][]interface{}{{"director line", "added"}}, nil), nil)
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Said).To(BeEmpty())
+			Expect(ui.Blocks).To(Equal([]string{
+				"@@ /instance_groups/name=web/instances @@\n- 1\n+ 2\n" +
+					"@@ /instance_groups/name=web/properties/password @@\n- <redacted>\n+ <redacted>\n",
+			}))
+
+			Expect(deployment.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("does not redact client side diff if no-redact is set", func() {
+			deployOpts.Args.Manifest.Bytes = []byte("name: dep\nproperties: {password: new}")
+			deployOpts.DiffFormat = opts.DiffFormatSideBySide
+			deployOpts.NoRedact = true
+
+			deployment.ManifestReturns("name: dep\nproperties: {password: old}", nil)
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Table.Rows).To(Equal([][]boshtbl.Value{{
+				boshtbl.NewValueString("/properties/password"),
+				boshtbl.NewValueString("changed"),
+				boshtbl.NewValueString("old"),
+				boshtbl.NewValueString("new"),
+			}}))
+		})
+
+		It("returns error if diff format can be applied but values are redacted", func() {
+			deployOpts.DiffFormat = opts.DiffFormatJSONPatch
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected --no-redact with --diff-format json-patch since redacted values would be applied"))
+			Expect(deployment.DiffCallCount()).To(Equal(0))
+			Expect(deployment.UpdateCallCount()).To(Equal(0))
+		})
+
+		It("shows client side diff as operations if no-redact is set", func() {
+			deployOpts.Args.Manifest.Bytes = []byte("name: dep\nproperties: {password: new}")
+			deployOpts.DiffFormat = opts.DiffFormatOps
+			deployOpts.NoRedact = true
+
+			deployment.ManifestReturns("name: dep\nproperties: {password: old}", nil)
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks).To(HaveLen(1))
+			Expect(ui.Blocks[0]).To(MatchJSON(`[{"type": "replace", "path": "/properties/password", "value": "new"}]`))
+		})
+
+		It("returns error if current manifest cannot be fetched for client side diff", func() {
+			deployOpts.DiffFormat = opts.DiffFormatUnified
+			deployment.ManifestReturns("", errors.New("fake-err"))
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Fetching current manifest"))
+			Expect(deployment.UpdateCallCount()).To(Equal(0))
+		})
+
 		It("deploys manifest with diff context", func() {
 			context := map[string]interface{}{
 				"cloud_config_id":   2,
@@ -442,5 +516,129 @@ releases:
 				Fix: true,
 			}))
 		})
+
+		Context("when configs of type deploy-policy exist", func() {
+			BeforeEach(func() {
+				director.ListConfigsStub = func(limit int, filter boshdir.ConfigsFilter) ([]boshdir.Config, error) {
+					if filter.Type != "deploy-policy" {
+						return nil, nil
+					}
+					return []boshdir.Config{{
+						Name:    "safety",
+						Type:    "deploy-policy",
+						Content: "rules:\n- name: no-recreate\n  type: forbid_recreate",
+					}}, nil
+				}
+
+				deployOpts.Recreate = true
+			})
+
+			It("does not upload releases or deploy if policies are violated", func() {
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("found 1 violation(s); use --override-policy"))
+
+				Expect(releaseUploader.UploadReleasesCallCount()).To(Equal(0))
+				Expect(deployment.UpdateCallCount()).To(Equal(0))
+
+				Expect(ui.Table).To(Equal(boshtbl.Table{
+					Content: "policy violations",
+					Header: []boshtbl.Header{
+						boshtbl.NewHeader("Policy"),
+						boshtbl.NewHeader("Rule"),
+						boshtbl.NewHeader("Violation"),
+					},
+					Rows: [][]boshtbl.Value{
+						{
+							boshtbl.NewValueString("safety"),
+							boshtbl.NewValueString("no-recreate"),
+							boshtbl.NewValueString("Recreating VMs or persistent disks is not allowed"),
+						},
+					},
+				}))
+			})
+
+			It("deploys and records override in the task context if override reason is given", func() {
+				deployOpts.OverridePolicy = "security fix"
+
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ui.Errors).To(ContainElement("Overriding 1 policy violation(s): security fix"))
+
+				Expect(deployment.UpdateCallCount()).To(Equal(1))
+
+				_, updateOpts := deployment.UpdateArgsForCall(0)
+				Expect(updateOpts).To(Equal(boshdir.UpdateOpts{
+					Recreate: true,
+					Context: map[string]interface{}{
+						"policy_override": map[string]interface{}{
+							"reason":     "security fix",
+							"violations": []string{"safety/no-recreate: Recreating VMs or persistent disks is not allowed"},
+						},
+					},
+				}))
+			})
+
+			It("deploys without recording override if policies are satisfied", func() {
+				deployOpts.Recreate = false
+				deployOpts.OverridePolicy = "security fix"
+
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				_, updateOpts := deployment.UpdateArgsForCall(0)
+				Expect(updateOpts).To(Equal(boshdir.UpdateOpts{}))
+			})
+
+			It("returns error if policies cannot be checked", func() {
+				director.ListConfigsStub = func(limit int, filter boshdir.ConfigsFilter) ([]boshdir.Config, error) {
+					return []boshdir.Config{{Name: "safety", Type: filter.Type, Content: "rules:\n- type: unknown"}}, nil
+				}
+
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unknown rule type 'unknown'"))
+				Expect(deployment.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("does not deploy if configs cannot be fetched", func() {
+				director.ListConfigsStub = nil
+				director.ListConfigsReturns(nil, errors.New("fake-err"))
+
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("use --override-policy to deploy without checking them"))
+				Expect(deployment.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("records override in task context if configs cannot be fetched and policies are overridden", func() {
+				director.ListConfigsStub = nil
+				director.ListConfigsReturns(nil, errors.New("fake-err"))
+				deployOpts.OverridePolicy = "director is degraded"
+
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				_, updateOpts := deployment.UpdateArgsForCall(0)
+				Expect(updateOpts.Context).To(Equal(map[string]interface{}{
+					"policy_override": map[string]interface{}{
+						"reason":     "director is degraded",
+						"violations": []string{"deploy-policy/fetch: Deploy policies could not be fetched: fake-err"},
+					},
+				}))
+			})
+
+			It("warns and deploys if director does not support configs", func() {
+				director.ListConfigsStub = nil
+				director.ListConfigsReturns(nil, boshdir.ConfigsNotSupportedError{Err: errors.New("fake-err")})
+
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deployment.UpdateCallCount()).To(Equal(1))
+
+				Expect(ui.Errors).To(ContainElement(
+					"Warning: Skipping deploy policies since Director does not support configs"))
+			})
+		})
 	})
 })
@@ -28,6 +28,7 @@ func NewDeploymentDeleter(
 	logTag string,
 	logger boshlog.Logger,
 	deploymentStateService biconfig.DeploymentStateService,
+	forceUnlock bool,
 	releaseManager biinstall.ReleaseManager,
 	cloudFactory bicloud.Factory,
 	agentClientFactory bihttpagent.AgentClientFactory,
@@ -48,6 +49,7 @@ func NewDeploymentDeleter(
 		logTag:                                  logTag,
 		logger:                                  logger,
 		deploymentStateService:                  deploymentStateService,
+		forceUnlock:                             forceUnlock,
 		releaseManager:                          releaseManager,
 		cloudFactory:                            cloudFactory,
 		agentClientFactory:                      agentClientFactory,
@@ -70,6 +72,7 @@ type deploymentDeleter struct {
 	logTag                                  string
 	logger                                  boshlog.Logger
 	deploymentStateService                  biconfig.DeploymentStateService
+	forceUnlock                             bool
 	releaseManager                          biinstall.ReleaseManager
 	cloudFactory                            bicloud.Factory
 	agentClientFactory                      bihttpagent.AgentClientFactory
@@ -89,6 +92,12 @@ type deploymentDeleter struct {
 func (c *deploymentDeleter) DeleteDeployment(skipDrain bool, stage biui.Stage) (err error) {
 	c.ui.BeginLinef("Deployment state: '%s'\n", c.deploymentStateService.Path())
 
+	unlock, err := lockDeploymentState(c.deploymentStateService, c.forceUnlock, c.ui, c.logger, c.logTag)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if !c.deploymentStateService.Exists() {
 		c.ui.BeginLinef("No deployment state file found.\n")
 		return nil
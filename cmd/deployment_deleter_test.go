@@ -210,7 +210,7 @@ cloud_provider:
 			installationParser := biinstallmanifest.NewParser(fs, fakeUUIDGenerator, logger, installationValidator)
 			tarballCache := bitarball.NewCache("fake-base-path", fs, logger)
 			tarballProvider := bitarball.NewProvider(tarballCache, fs, nil, 1, 0, logger)
-			deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""))
+			deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""), nil)
 
 			cpiInstaller := bicpirel.CpiInstaller{
 				ReleaseManager:   releaseManager,
@@ -237,6 +237,7 @@ cloud_provider:
 				"deleteCmd",
 				logger,
 				deploymentStateService,
+				false,
 				releaseManager,
 				mockCloudFactory,
 				mockAgentClientFactory,
@@ -319,7 +320,7 @@ cloud_provider:
 			logger = boshlog.NewLogger(boshlog.LevelNone)
 			fakeUUIDGenerator = fakeuuid.NewFakeGenerator()
 			deploymentStatePath = biconfig.DeploymentStatePath(deploymentManifestPath, "")
-			setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, deploymentStatePath)
+			setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, deploymentStatePath, nil)
 			_, err := setupDeploymentStateService.Load()
 			Expect(err).ToNot(HaveOccurred())
 
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+// DeploymentPlan lists deployments that should be deployed together.
+// Relative paths are resolved against the directory of the plan file.
+type DeploymentPlan struct {
+	Deployments []DeploymentPlanItem `yaml:"deployments"`
+
+	dir string
+	fs  boshsys.FileSystem
+}
+
+type DeploymentPlanItem struct {
+	Name      string                 `yaml:"name"`
+	Manifest  string                 `yaml:"manifest"`
+	OpsFiles  []string               `yaml:"ops_files"`
+	VarsFiles []string               `yaml:"vars_files"`
+	Vars      map[string]interface{} `yaml:"vars"`
+	DependsOn []string               `yaml:"depends_on"`
+}
+
+func NewDeploymentPlanFromPath(path string, fs boshsys.FileSystem) (DeploymentPlan, error) {
+	plan := DeploymentPlan{dir: filepath.Dir(path), fs: fs}
+
+	bytes, err := fs.ReadFile(path)
+	if err != nil {
+		return plan, bosherr.WrapErrorf(err, "Reading plan '%s'", path)
+	}
+
+	err = yaml.UnmarshalStrict(bytes, &plan)
+	if err != nil {
+		return plan, bosherr.WrapErrorf(err, "Unmarshalling plan '%s'", path)
+	}
+
+	err = plan.validate()
+	if err != nil {
+		return plan, bosherr.WrapErrorf(err, "Validating plan '%s'", path)
+	}
+
+	return plan, nil
+}
+
+func (p DeploymentPlan) validate() error {
+	if len(p.Deployments) == 0 {
+		return bosherr.Error("Expected plan to include at least one deployment")
+	}
+
+	names := map[string]struct{}{}
+
+	for i, item := range p.Deployments {
+		if len(item.Name) == 0 {
+			return bosherr.Errorf("Expected deployment [%d] to specify non-empty name", i)
+		}
+
+		if len(item.Manifest) == 0 {
+			return bosherr.Errorf("Expected deployment '%s' to specify non-empty manifest", item.Name)
+		}
+
+		if _, found := names[item.Name]; found {
+			return bosherr.Errorf("Expected deployment '%s' to be specified only once", item.Name)
+		}
+
+		names[item.Name] = struct{}{}
+	}
+
+	for _, item := range p.Deployments {
+		for _, dep := range item.DependsOn {
+			if _, found := names[dep]; !found {
+				return bosherr.Errorf("Expected dependency '%s' of deployment '%s' to be specified in the plan", dep, item.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Order returns deployments sorted so that each deployment comes after its
+// dependencies; deployments without dependencies between them keep plan order.
+func (p DeploymentPlan) Order() ([]DeploymentPlanItem, error) {
+	items := map[string]DeploymentPlanItem{}
+
+	for _, item := range p.Deployments {
+		items[item.Name] = item
+	}
+
+	var ordered []DeploymentPlanItem
+
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(name string, path []string) error
+
+	visit = func(name string, path []string) error {
+		if visited[name] {
+			return nil
+		}
+
+		path = append(path, name)
+
+		if visiting[name] {
+			return bosherr.Errorf("Expected plan to not have dependency cycles but found '%s'", strings.Join(path, " -> "))
+		}
+
+		visiting[name] = true
+
+		for _, dep := range items[name].DependsOn {
+			err := visit(dep, path)
+			if err != nil {
+				return err
+			}
+		}
+
+		visiting[name] = false
+		visited[name] = true
+
+		ordered = append(ordered, items[name])
+
+		return nil
+	}
+
+	for _, item := range p.Deployments {
+		err := visit(item.Name, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// Evaluate interpolates deployment manifest with its ops files and variables.
+func (p DeploymentPlan) Evaluate(item DeploymentPlanItem) ([]byte, error) {
+	manifestBytes, err := p.fs.ReadFile(p.path(item.Manifest))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading manifest")
+	}
+
+	var varFlags VarFlags
+	var opsFlags OpsFlags
+
+	for _, path := range item.VarsFiles {
+		varsFileArg := boshtpl.VarsFileArg{FS: p.fs}
+
+		err := varsFileArg.UnmarshalFlag(p.path(path))
+		if err != nil {
+			return nil, err
+		}
+
+		varFlags.VarsFiles = append(varFlags.VarsFiles, varsFileArg)
+	}
+
+	// Sort inline variables to keep evaluation stable
+	var names []string
+
+	for name := range item.Vars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		varFlags.VarKVs = append(varFlags.VarKVs, boshtpl.VarKV{Name: name, Value: item.Vars[name]})
+	}
+
+	for _, path := range item.OpsFiles {
+		opsFileArg := OpsFileArg{FS: p.fs}
+
+		err := opsFileArg.UnmarshalFlag(p.path(path))
+		if err != nil {
+			return nil, err
+		}
+
+		opsFlags.OpsFiles = append(opsFlags.OpsFiles, opsFileArg)
+	}
+
+	tpl := boshtpl.NewTemplate(manifestBytes)
+
+	bytes, err := tpl.Evaluate(varFlags.AsVariables(), opsFlags.AsOp(), boshtpl.EvaluateOpts{})
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Evaluating manifest")
+	}
+
+	return bytes, nil
+}
+
+func (p DeploymentPlan) path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(p.dir, path)
+}
+
+const (
+	DeploymentPlanStateDone    = "done"
+	DeploymentPlanStateFailed  = "failed"
+	DeploymentPlanStateSkipped = "skipped"
+)
+
+// DeploymentPlanState records outcome of each deployment so that
+// a failed plan can be resumed without redeploying succeeded deployments.
+type DeploymentPlanState struct {
+	Deployments map[string]DeploymentPlanResult `json:"deployments"`
+
+	path string
+	fs   boshsys.FileSystem
+}
+
+type DeploymentPlanResult struct {
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+func NewDeploymentPlanState(path string, fs boshsys.FileSystem) DeploymentPlanState {
+	return DeploymentPlanState{
+		Deployments: map[string]DeploymentPlanResult{},
+
+		path: path,
+		fs:   fs,
+	}
+}
+
+func (s *DeploymentPlanState) Load() error {
+	if !s.fs.FileExists(s.path) {
+		return nil
+	}
+
+	bytes, err := s.fs.ReadFile(s.path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading plan state '%s'", s.path)
+	}
+
+	err = json.Unmarshal(bytes, s)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Unmarshalling plan state '%s'", s.path)
+	}
+
+	if s.Deployments == nil {
+		s.Deployments = map[string]DeploymentPlanResult{}
+	}
+
+	return nil
+}
+
+func (s DeploymentPlanState) Save() error {
+	bytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Marshalling plan state")
+	}
+
+	err = s.fs.WriteFile(s.path, bytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing plan state '%s'", s.path)
+	}
+
+	return nil
+}
+
+func (s DeploymentPlanState) Succeeded(name string) bool {
+	return s.Deployments[name].State == DeploymentPlanStateDone
+}
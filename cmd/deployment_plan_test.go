@@ -0,0 +1,189 @@
+package cmd_test
+
+import (
+	"errors"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+)
+
+var _ = Describe("DeploymentPlan", func() {
+	var (
+		fs *fakesys.FakeFileSystem
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+	})
+
+	names := func(items []cmd.DeploymentPlanItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	Describe("NewDeploymentPlanFromPath", func() {
+		It("returns error if plan cannot be read", func() {
+			fs.ReadFileError = errors.New("fake-err")
+
+			_, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading plan '/plan.yml'"))
+		})
+
+		It("returns error if plan has unknown keys", func() {
+			Expect(fs.WriteFileString("/plan.yml", "deployments:\n- name: a\n  manifets: a.yml")).To(Succeed())
+
+			_, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling plan '/plan.yml'"))
+		})
+
+		It("returns error if plan is empty", func() {
+			Expect(fs.WriteFileString("/plan.yml", "deployments: []")).To(Succeed())
+
+			_, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected plan to include at least one deployment"))
+		})
+
+		It("returns error if deployment is specified more than once", func() {
+			Expect(fs.WriteFileString("/plan.yml", "deployments:\n- {name: a, manifest: a.yml}\n- {name: a, manifest: b.yml}")).To(Succeed())
+
+			_, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected deployment 'a' to be specified only once"))
+		})
+
+		It("returns error if manifest is missing", func() {
+			Expect(fs.WriteFileString("/plan.yml", "deployments:\n- {name: a}")).To(Succeed())
+
+			_, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected deployment 'a' to specify non-empty manifest"))
+		})
+
+		It("returns error if dependency is not in the plan", func() {
+			Expect(fs.WriteFileString("/plan.yml", "deployments:\n- {name: a, manifest: a.yml, depends_on: [b]}")).To(Succeed())
+
+			_, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected dependency 'b' of deployment 'a' to be specified in the plan"))
+		})
+	})
+
+	Describe("Order", func() {
+		It("orders deployments after their dependencies keeping plan order otherwise", func() {
+			Expect(fs.WriteFileString("/plan.yml", `
+deployments:
+- {name: cf, manifest: cf.yml, depends_on: [dns, db]}
+- {name: dns, manifest: dns.yml}
+- {name: db, manifest: db.yml, depends_on: [dns]}
+- {name: logs, manifest: logs.yml}
+`)).To(Succeed())
+
+			plan, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).ToNot(HaveOccurred())
+
+			ordered, err := plan.Order()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(ordered)).To(Equal([]string{"dns", "db", "cf", "logs"}))
+		})
+
+		It("returns error if dependencies have cycles", func() {
+			Expect(fs.WriteFileString("/plan.yml", `
+deployments:
+- {name: a, manifest: a.yml, depends_on: [b]}
+- {name: b, manifest: b.yml, depends_on: [c]}
+- {name: c, manifest: c.yml, depends_on: [a]}
+`)).To(Succeed())
+
+			plan, err := cmd.NewDeploymentPlanFromPath("/plan.yml", fs)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = plan.Order()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("found 'a -> b -> c -> a'"))
+		})
+	})
+
+	Describe("Evaluate", func() {
+		BeforeEach(func() {
+			Expect(fs.WriteFileString("/plans/plan.yml", `
+deployments:
+- name: dep
+  manifest: dep.yml
+  ops_files: [ops.yml]
+  vars_files: [/vars/vars.yml]
+  vars: {instances: 2}
+`)).To(Succeed())
+			Expect(fs.WriteFileString("/plans/dep.yml", "name: ((name))\ninstances: ((instances))")).To(Succeed())
+			Expect(fs.WriteFileString("/plans/ops.yml", "- type: replace\n  path: /azs?\n  value: [z1]")).To(Succeed())
+			Expect(fs.WriteFileString("/vars/vars.yml", "name: dep\ninstances: 1")).To(Succeed())
+		})
+
+		It("interpolates manifest relative to the plan with ops files and variables", func() {
+			plan, err := cmd.NewDeploymentPlanFromPath("/plans/plan.yml", fs)
+			Expect(err).ToNot(HaveOccurred())
+
+			bytes, err := plan.Evaluate(plan.Deployments[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(bytes)).To(Equal("azs:\n- z1\ninstances: 2\nname: dep\n"))
+		})
+
+		It("returns error if ops file cannot be read", func() {
+			err := fs.RemoveAll("/plans/ops.yml")
+			Expect(err).ToNot(HaveOccurred())
+
+			plan, err := cmd.NewDeploymentPlanFromPath("/plans/plan.yml", fs)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = plan.Evaluate(plan.Deployments[0])
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading ops file '/plans/ops.yml'"))
+		})
+	})
+})
+
+var _ = Describe("DeploymentPlanState", func() {
+	var (
+		fs *fakesys.FakeFileSystem
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+	})
+
+	It("saves and loads outcomes", func() {
+		state := cmd.NewDeploymentPlanState("/plan.yml.state.json", fs)
+		state.Deployments["a"] = cmd.DeploymentPlanResult{State: "done"}
+		state.Deployments["b"] = cmd.DeploymentPlanResult{State: "failed", Error: "fake-err"}
+		Expect(state.Save()).To(Succeed())
+
+		loaded := cmd.NewDeploymentPlanState("/plan.yml.state.json", fs)
+		Expect(loaded.Load()).To(Succeed())
+		Expect(loaded.Succeeded("a")).To(BeTrue())
+		Expect(loaded.Succeeded("b")).To(BeFalse())
+		Expect(loaded.Deployments["b"].Error).To(Equal("fake-err"))
+	})
+
+	It("loads nothing if state file does not exist", func() {
+		state := cmd.NewDeploymentPlanState("/plan.yml.state.json", fs)
+		Expect(state.Load()).To(Succeed())
+		Expect(state.Deployments).To(BeEmpty())
+	})
+
+	It("returns error if state file cannot be parsed", func() {
+		Expect(fs.WriteFileString("/plan.yml.state.json", "{")).To(Succeed())
+
+		state := cmd.NewDeploymentPlanState("/plan.yml.state.json", fs)
+		err := state.Load()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unmarshalling plan state"))
+	})
+})
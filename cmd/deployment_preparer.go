@@ -20,6 +20,7 @@ import (
 	birelsetmanifest "github.com/cloudfoundry/bosh-cli/v7/release/set/manifest"
 	bistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
 	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
 )
 
 func NewDeploymentPreparer(
@@ -27,6 +28,7 @@ func NewDeploymentPreparer(
 	logger boshlog.Logger,
 	logTag string,
 	deploymentStateService biconfig.DeploymentStateService,
+	forceUnlock bool,
 	legacyDeploymentStateMigrator biconfig.LegacyDeploymentStateMigrator,
 	releaseManager biinstall.ReleaseManager,
 	deploymentRecord bidepl.Record,
@@ -46,12 +48,14 @@ func NewDeploymentPreparer(
 	deploymentManifestParser DeploymentManifestParser,
 	tempRootConfigurator TempRootConfigurator,
 	targetProvider biinstall.TargetProvider,
+	planner bidepl.Planner,
 ) DeploymentPreparer {
 	return DeploymentPreparer{
 		ui:                                      ui,
 		logger:                                  logger,
 		logTag:                                  logTag,
 		deploymentStateService:                  deploymentStateService,
+		forceUnlock:                             forceUnlock,
 		legacyDeploymentStateMigrator:           legacyDeploymentStateMigrator,
 		releaseManager:                          releaseManager,
 		deploymentRecord:                        deploymentRecord,
@@ -71,6 +75,7 @@ func NewDeploymentPreparer(
 		deploymentManifestParser:                deploymentManifestParser,
 		tempRootConfigurator:                    tempRootConfigurator,
 		targetProvider:                          targetProvider,
+		planner:                                 planner,
 	}
 }
 
@@ -79,6 +84,7 @@ type DeploymentPreparer struct {
 	logger                                  boshlog.Logger
 	logTag                                  string
 	deploymentStateService                  biconfig.DeploymentStateService
+	forceUnlock                             bool
 	legacyDeploymentStateMigrator           biconfig.LegacyDeploymentStateMigrator
 	releaseManager                          biinstall.ReleaseManager
 	deploymentRecord                        bidepl.Record
@@ -98,11 +104,18 @@ type DeploymentPreparer struct {
 	deploymentManifestParser                DeploymentManifestParser
 	tempRootConfigurator                    TempRootConfigurator
 	targetProvider                          biinstall.TargetProvider
+	planner                                 bidepl.Planner
 }
 
 func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage, recreate bool, recreatePersistentDisks bool, skipDrain bool) (err error) {
 	c.ui.BeginLinef("Deployment state: '%s'\n", c.deploymentStateService.Path())
 
+	unlock, err := lockDeploymentState(c.deploymentStateService, c.forceUnlock, c.ui, c.logger, c.logTag)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if !c.deploymentStateService.Exists() {
 		migrated, err := c.legacyDeploymentStateMigrator.MigrateIfExists(biconfig.LegacyDeploymentStatePath(c.deploymentManifestPath))
 		if err != nil {
@@ -135,39 +148,7 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage, recreate bool,
 		}
 	}()
 
-	var (
-		extractedStemcell    bistemcell.ExtractedStemcell
-		deploymentManifest   bideplmanifest.Manifest
-		installationManifest biinstallmanifest.Manifest
-		manifestSHA          string
-	)
-	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
-		var releaseSetManifest birelsetmanifest.Manifest
-		releaseSetManifest, installationManifest, err = c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.deploymentManifestPath, c.deploymentVars, c.deploymentOp)
-		if err != nil {
-			return err
-		}
-
-		for _, releaseRef := range releaseSetManifest.Releases {
-			err = c.releaseFetcher.DownloadAndExtract(releaseRef, stage)
-			if err != nil {
-				return err
-			}
-		}
-
-		err := c.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
-		if err != nil {
-			return err
-		}
-
-		deploymentManifest, manifestSHA, err = c.deploymentManifestParser.GetDeploymentManifest(c.deploymentManifestPath, c.deploymentVars, c.deploymentOp, releaseSetManifest, stage)
-		if err != nil {
-			return err
-		}
-
-		extractedStemcell, err = c.stemcellFetcher.GetStemcell(deploymentManifest, stage)
-		return err
-	})
+	extractedStemcell, deploymentManifest, installationManifest, manifestSHA, err := c.validate(stage)
 	if err != nil {
 		return err
 	}
@@ -227,6 +208,162 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage, recreate bool,
 	return err
 }
 
+// PlanDeployment prints what PrepareDeployment would change without
+// installing the CPI, calling it, or saving the deployment state. The
+// installation temp root is left alone since resolving it saves the state.
+func (c *DeploymentPreparer) PlanDeployment(stage biui.Stage, recreate bool, recreatePersistentDisks bool) (err error) {
+	c.ui.BeginLinef("Deployment state: '%s'\n", c.deploymentStateService.Path())
+
+	// Loading a missing state would create it, so plan against an empty one
+	deploymentStateExists := c.deploymentStateService.Exists()
+	deploymentState := biconfig.DeploymentState{}
+
+	if deploymentStateExists {
+		deploymentState, err = c.deploymentStateService.Load()
+		if err != nil {
+			return bosherr.WrapError(err, "Loading deployment state")
+		}
+	}
+
+	defer func() {
+		err := c.releaseManager.DeleteAll()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Deleting all extracted releases: %s", err.Error())
+		}
+	}()
+
+	extractedStemcell, deploymentManifest, _, manifestSHA, err := c.validate(stage)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		deleteErr := extractedStemcell.Cleanup()
+		if deleteErr != nil {
+			c.logger.Warn(c.logTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
+		}
+	}()
+
+	if deploymentStateExists {
+		isDeployed, err := c.deploymentRecord.IsDeployed(manifestSHA, c.releaseManager.List(), extractedStemcell)
+		if err != nil {
+			return bosherr.WrapError(err, "Checking if deployment has changed")
+		}
+
+		if isDeployed && !recreate && !recreatePersistentDisks {
+			c.ui.BeginLinef("No deployment, stemcell or release changes. Nothing to deploy.\n")
+			return nil
+		}
+	}
+
+	plan, err := c.planner.Plan(
+		deploymentManifest,
+		extractedStemcell,
+		manifestSHA,
+		c.releaseManager.List(),
+		deploymentState,
+		recreate,
+		recreatePersistentDisks,
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Planning deployment")
+	}
+
+	table := boshtbl.Table{
+		Content: "planned changes",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Resource"),
+			boshtbl.NewHeader("Name"),
+			boshtbl.NewHeader("Action"),
+			boshtbl.NewHeader("Reason"),
+		},
+	}
+
+	for _, change := range plan.Changes {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(change.Resource),
+			boshtbl.NewValueString(change.Name),
+			boshtbl.NewValueString(change.Action),
+			boshtbl.NewValueString(change.Reason),
+		})
+	}
+
+	c.ui.PrintTable(table)
+
+	c.ui.BeginLinef("Dry run: the CPI was not called and the deployment state was not changed.\n")
+
+	return nil
+}
+
+func (c *DeploymentPreparer) validate(stage biui.Stage) (
+	extractedStemcell bistemcell.ExtractedStemcell,
+	deploymentManifest bideplmanifest.Manifest,
+	installationManifest biinstallmanifest.Manifest,
+	manifestSHA string,
+	err error,
+) {
+	return deploymentValidator{
+		deploymentManifestPath:                  c.deploymentManifestPath,
+		deploymentVars:                          c.deploymentVars,
+		deploymentOp:                            c.deploymentOp,
+		cpiInstaller:                            c.cpiInstaller,
+		releaseFetcher:                          c.releaseFetcher,
+		stemcellFetcher:                         c.stemcellFetcher,
+		releaseSetAndInstallationManifestParser: c.releaseSetAndInstallationManifestParser,
+		deploymentManifestParser:                c.deploymentManifestParser,
+	}.validate(stage)
+}
+
+// deploymentValidator parses the manifests, fetches releases and the
+// stemcell and validates the CPI release before anything is changed.
+type deploymentValidator struct {
+	deploymentManifestPath                  string
+	deploymentVars                          boshtpl.Variables
+	deploymentOp                            patch.Op
+	cpiInstaller                            bicpirel.CpiInstaller
+	releaseFetcher                          biinstall.ReleaseFetcher
+	stemcellFetcher                         bistemcell.Fetcher
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentManifestParser                DeploymentManifestParser
+}
+
+func (v deploymentValidator) validate(stage biui.Stage) (
+	extractedStemcell bistemcell.ExtractedStemcell,
+	deploymentManifest bideplmanifest.Manifest,
+	installationManifest biinstallmanifest.Manifest,
+	manifestSHA string,
+	err error,
+) {
+	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
+		var releaseSetManifest birelsetmanifest.Manifest
+		releaseSetManifest, installationManifest, err = v.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(v.deploymentManifestPath, v.deploymentVars, v.deploymentOp)
+		if err != nil {
+			return err
+		}
+
+		for _, releaseRef := range releaseSetManifest.Releases {
+			err = v.releaseFetcher.DownloadAndExtract(releaseRef, stage)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := v.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
+		if err != nil {
+			return err
+		}
+
+		deploymentManifest, manifestSHA, err = v.deploymentManifestParser.GetDeploymentManifest(v.deploymentManifestPath, v.deploymentVars, v.deploymentOp, releaseSetManifest, stage)
+		if err != nil {
+			return err
+		}
+
+		extractedStemcell, err = v.stemcellFetcher.GetStemcell(deploymentManifest, stage)
+		return err
+	})
+
+	return extractedStemcell, deploymentManifest, installationManifest, manifestSHA, err
+}
+
 func (c *DeploymentPreparer) deploy(
 	deploymentState biconfig.DeploymentState,
 	extractedStemcell bistemcell.ExtractedStemcell,
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+// lockDeploymentState acquires the deployment state lock and returns a function that releases it.
+func lockDeploymentState(
+	deploymentStateService biconfig.DeploymentStateService,
+	forceUnlock bool,
+	ui biui.UI,
+	logger boshlog.Logger,
+	logTag string,
+) (func(), error) {
+	if forceUnlock {
+		err := deploymentStateService.ForceReleaseLock()
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Force releasing deployment state lock")
+		}
+
+		ui.BeginLinef("Released deployment state lock (--force-unlock)\n")
+	}
+
+	err := deploymentStateService.AcquireLock()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		err := deploymentStateService.ReleaseLock()
+		if err != nil {
+			logger.Warn(logTag, "Releasing deployment state lock: %s", err.Error())
+		}
+	}, nil
+}
@@ -25,6 +25,7 @@ func NewDeploymentStateManager(
 	logTag string,
 	logger boshlog.Logger,
 	deploymentStateService biconfig.DeploymentStateService,
+	forceUnlock bool,
 	agentClientFactory bihttpagent.AgentClientFactory,
 	deploymentManagerFactory bidepl.ManagerFactory,
 	deploymentManifestPath string,
@@ -38,6 +39,7 @@ func NewDeploymentStateManager(
 		logTag:                                  logTag,
 		logger:                                  logger,
 		deploymentStateService:                  deploymentStateService,
+		forceUnlock:                             forceUnlock,
 		agentClientFactory:                      agentClientFactory,
 		deploymentManagerFactory:                deploymentManagerFactory,
 		deploymentManifestPath:                  deploymentManifestPath,
@@ -53,6 +55,7 @@ type deploymentStateManager struct {
 	logTag                                  string
 	logger                                  boshlog.Logger
 	deploymentStateService                  biconfig.DeploymentStateService
+	forceUnlock                             bool
 	agentClientFactory                      bihttpagent.AgentClientFactory
 	deploymentManagerFactory                bidepl.ManagerFactory
 	deploymentManifestPath                  string
@@ -95,6 +98,12 @@ func (c *deploymentStateManager) StartDeployment(stage biui.Stage) (err error) {
 func (c *deploymentStateManager) executeStateChange(stage biui.Stage, stateChanger func(biui.Stage, string, biinstallmanifest.Manifest, bideplmanifest.Update) error) (err error) {
 	c.ui.BeginLinef("Deployment state: '%s'\n", c.deploymentStateService.Path())
 
+	unlock, err := lockDeploymentState(c.deploymentStateService, c.forceUnlock, c.ui, c.logger, c.logTag)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if !c.deploymentStateService.Exists() {
 		c.ui.BeginLinef("No deployment state file found.\n")
 		return nil
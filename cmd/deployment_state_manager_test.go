@@ -59,7 +59,8 @@ var _ = Describe("DeploymentStateManager", func() {
 		deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
 		deploymentStatePath    string
 
-		skipDrain bool
+		skipDrain   bool
+		forceUnlock bool
 	)
 
 	var certificate = `-----BEGIN CERTIFICATE-----
@@ -124,7 +125,7 @@ cloud_provider:
 		releaseSetParser := birelsetmanifest.NewParser(fs, logger, releaseSetValidator)
 		installationValidator := biinstallmanifest.NewValidator(logger)
 		installationParser := biinstallmanifest.NewParser(fs, fakeUUIDGenerator, logger, installationValidator)
-		deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""))
+		deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""), nil)
 
 		releaseSetAndInstallationManifestParser := cmd.ReleaseSetAndInstallationManifestParser{
 			ReleaseSetParser:   releaseSetParser,
@@ -145,6 +146,7 @@ cloud_provider:
 			"deleteCmd",
 			logger,
 			deploymentStateService,
+			forceUnlock,
 			mockAgentClientFactory,
 			mockDeploymentManagerFactory,
 			deploymentManifestPath,
@@ -161,7 +163,7 @@ cloud_provider:
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		fakeUUIDGenerator = fakeuuid.NewFakeGenerator()
 		deploymentStatePath = biconfig.DeploymentStatePath(deploymentManifestPath, "")
-		setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, deploymentStatePath)
+		setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, deploymentStatePath, nil)
 		_, err := setupDeploymentStateService.Load()
 		Expect(err).ToNot(HaveOccurred())
 
@@ -178,6 +180,7 @@ cloud_provider:
 
 		directorID = "fake-uuid-0"
 		skipDrain = false
+		forceUnlock = false
 
 		mockAgentClientFactory.EXPECT().NewAgentClient(
 			directorID,
@@ -255,6 +258,37 @@ cloud_provider:
 				Expect(fakeUI.Errors).To(BeEmpty())
 			})
 
+			It("releases the deployment state lock", func() {
+				expectStop(skipDrain)
+
+				err := newDeploymentStateManager().StopDeployment(skipDrain, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fs.FileExists(deploymentStatePath + ".lock")).To(BeFalse())
+			})
+
+			Context("when another run holds the deployment state lock", func() {
+				BeforeEach(func() {
+					err := fs.WriteFileString(deploymentStatePath+".lock", `{"id":"other","owner":"alice","pid":42,"hostname":"jumpbox"}`)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("returns an error without stopping the deployment", func() {
+					err := newDeploymentStateManager().StopDeployment(skipDrain, fakeStage)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("is locked by alice@jumpbox (PID 42)"))
+					Expect(fakeStage.PerformCalls).To(BeEmpty())
+				})
+
+				It("stops the deployment when forced to unlock", func() {
+					forceUnlock = true
+					expectStop(skipDrain)
+
+					err := newDeploymentStateManager().StopDeployment(skipDrain, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fs.FileExists(deploymentStatePath + ".lock")).To(BeFalse())
+				})
+			})
+
 			It("logs validating & stop stages", func() {
 				expectStop(skipDrain)
 
@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/cppforlife/go-patch/patch"
+
+	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	bicpirel "github.com/cloudfoundry/bosh-cli/v7/cpi/release"
+	bideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	biinstall "github.com/cloudfoundry/bosh-cli/v7/installation"
+	bistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
+	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+type DeploymentStateRepairer interface {
+	RepairDeploymentState(cids RepairCIDs, stage biui.Stage) error
+}
+
+// RepairCIDs are the IaaS resources known to belong to the deployment.
+type RepairCIDs struct {
+	VM       string
+	Disk     string
+	Stemcell string
+}
+
+func NewDeploymentStateRepairer(
+	ui biui.UI,
+	logTag string,
+	logger boshlog.Logger,
+	deploymentStateService biconfig.DeploymentStateService,
+	forceUnlock bool,
+	uuidGenerator boshuuid.Generator,
+	releaseManager biinstall.ReleaseManager,
+	cloudFactory bicloud.Factory,
+	deploymentManifestPath string,
+	deploymentVars boshtpl.Variables,
+	deploymentOp patch.Op,
+	cpiInstaller bicpirel.CpiInstaller,
+	releaseFetcher biinstall.ReleaseFetcher,
+	stemcellFetcher bistemcell.Fetcher,
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	deploymentManifestParser DeploymentManifestParser,
+	tempRootConfigurator TempRootConfigurator,
+	targetProvider biinstall.TargetProvider,
+) DeploymentStateRepairer {
+	return &deploymentStateRepairer{
+		ui:                                      ui,
+		logTag:                                  logTag,
+		logger:                                  logger,
+		deploymentStateService:                  deploymentStateService,
+		forceUnlock:                             forceUnlock,
+		uuidGenerator:                           uuidGenerator,
+		releaseManager:                          releaseManager,
+		cloudFactory:                            cloudFactory,
+		deploymentManifestPath:                  deploymentManifestPath,
+		deploymentVars:                          deploymentVars,
+		deploymentOp:                            deploymentOp,
+		cpiInstaller:                            cpiInstaller,
+		releaseFetcher:                          releaseFetcher,
+		stemcellFetcher:                         stemcellFetcher,
+		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		deploymentManifestParser:                deploymentManifestParser,
+		tempRootConfigurator:                    tempRootConfigurator,
+		targetProvider:                          targetProvider,
+	}
+}
+
+type deploymentStateRepairer struct {
+	ui                                      biui.UI
+	logTag                                  string
+	logger                                  boshlog.Logger
+	deploymentStateService                  biconfig.DeploymentStateService
+	forceUnlock                             bool
+	uuidGenerator                           boshuuid.Generator
+	releaseManager                          biinstall.ReleaseManager
+	cloudFactory                            bicloud.Factory
+	deploymentManifestPath                  string
+	deploymentVars                          boshtpl.Variables
+	deploymentOp                            patch.Op
+	cpiInstaller                            bicpirel.CpiInstaller
+	releaseFetcher                          biinstall.ReleaseFetcher
+	stemcellFetcher                         bistemcell.Fetcher
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentManifestParser                DeploymentManifestParser
+	tempRootConfigurator                    TempRootConfigurator
+	targetProvider                          biinstall.TargetProvider
+}
+
+type repairedRecord struct {
+	kind   string
+	cid    string
+	status string
+}
+
+func (c *deploymentStateRepairer) RepairDeploymentState(cids RepairCIDs, stage biui.Stage) (err error) {
+	c.ui.BeginLinef("Deployment state: '%s'\n", c.deploymentStateService.Path())
+
+	if cids.VM == "" {
+		return bosherr.Error("Expected a VM CID to repair the deployment state")
+	}
+
+	unlock, err := lockDeploymentState(c.deploymentStateService, c.forceUnlock, c.ui, c.logger, c.logTag)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	previousState, err := c.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	target, err := c.targetProvider.NewTarget()
+	if err != nil {
+		return bosherr.WrapError(err, "Determining installation target")
+	}
+
+	err = c.tempRootConfigurator.PrepareAndSetTempRoot(target.TmpPath(), c.logger)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting temp root")
+	}
+
+	defer func() {
+		err := c.releaseManager.DeleteAll()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Deleting all extracted releases: %s", err.Error())
+		}
+	}()
+
+	extractedStemcell, deploymentManifest, installationManifest, _, err := deploymentValidator{
+		deploymentManifestPath:                  c.deploymentManifestPath,
+		deploymentVars:                          c.deploymentVars,
+		deploymentOp:                            c.deploymentOp,
+		cpiInstaller:                            c.cpiInstaller,
+		releaseFetcher:                          c.releaseFetcher,
+		stemcellFetcher:                         c.stemcellFetcher,
+		releaseSetAndInstallationManifestParser: c.releaseSetAndInstallationManifestParser,
+		deploymentManifestParser:                c.deploymentManifestParser,
+	}.validate(stage)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		deleteErr := extractedStemcell.Cleanup()
+		if deleteErr != nil {
+			c.logger.Warn(c.logTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
+		}
+	}()
+
+	stemcellManifest := extractedStemcell.Manifest()
+
+	stemcellApiVersion := stemcellManifest.ApiVersion
+	if stemcellApiVersion == 0 {
+		stemcellApiVersion = 1
+	}
+
+	var records []repairedRecord
+
+	err = c.cpiInstaller.WithInstalledCpiRelease(installationManifest, target, stage, func(installation biinstall.Installation) error {
+		cloud, err := c.cloudFactory.NewCloud(installation, previousState.DirectorID, stemcellApiVersion)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating CPI client from CPI installation")
+		}
+
+		return stage.Perform("Validating CIDs with the CPI", func() error {
+			records, err = c.validateCIDs(cloud, cids, stemcellManifest, previousState)
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	deploymentState, err := c.buildDeploymentState(previousState, cids, deploymentManifest, stemcellManifest)
+	if err != nil {
+		return err
+	}
+
+	if cids.Disk != "" && len(deploymentState.Disks) == 0 {
+		records = append(records, repairedRecord{kind: "disk", cid: cids.Disk, status: "dangling: the manifest has no persistent disk, not recorded"})
+	}
+
+	records = append(records, c.danglingRecords(previousState, deploymentState)...)
+
+	c.printRecords(records)
+
+	err = c.ui.AskForConfirmation()
+	if err != nil {
+		return err
+	}
+
+	err = c.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving repaired deployment state")
+	}
+
+	c.ui.BeginLinef("Repaired deployment state: '%s'\n", c.deploymentStateService.Path())
+
+	return nil
+}
+
+func (c *deploymentStateRepairer) validateCIDs(
+	cloud bicloud.Cloud,
+	cids RepairCIDs,
+	stemcellManifest bistemcell.Manifest,
+	previousState biconfig.DeploymentState,
+) ([]repairedRecord, error) {
+	cpiInfo, err := cloud.Info()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting CPI info")
+	}
+
+	if len(stemcellManifest.StemcellFormats) > 0 && len(cpiInfo.StemcellFormats) > 0 {
+		supported := false
+		for _, format := range stemcellManifest.StemcellFormats {
+			for _, cpiFormat := range cpiInfo.StemcellFormats {
+				if format == cpiFormat {
+					supported = true
+				}
+			}
+		}
+
+		if !supported {
+			return nil, bosherr.Errorf("Stemcell formats '%s' are not supported by the CPI, which supports '%s'",
+				strings.Join(stemcellManifest.StemcellFormats, ", "), strings.Join(cpiInfo.StemcellFormats, ", "))
+		}
+	}
+
+	found, err := cloud.HasVM(cids.VM)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Checking VM '%s'", cids.VM)
+	}
+
+	if !found {
+		return nil, bosherr.Errorf("VM '%s' does not exist", cids.VM)
+	}
+
+	records := []repairedRecord{{kind: "vm", cid: cids.VM, status: "verified with the CPI"}}
+
+	if cids.Stemcell != "" {
+		records = append(records, repairedRecord{kind: "stemcell", cid: cids.Stemcell, status: "recorded, the CPI cannot verify stemcells"})
+	}
+
+	if cids.Disk != "" {
+		records = append(records, repairedRecord{kind: "disk", cid: cids.Disk, status: "recorded, the CPI cannot verify disks"})
+	}
+
+	if previousState.CurrentVMCID != "" && previousState.CurrentVMCID != cids.VM {
+		found, err := cloud.HasVM(previousState.CurrentVMCID)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Checking VM '%s'", previousState.CurrentVMCID)
+		}
+
+		status := "dangling: previous VM no longer exists"
+		if found {
+			status = "dangling: previous VM still exists, delete it from the IaaS"
+		}
+
+		records = append(records, repairedRecord{kind: "vm", cid: previousState.CurrentVMCID, status: status})
+	}
+
+	return records, nil
+}
+
+func (c *deploymentStateRepairer) buildDeploymentState(
+	previousState biconfig.DeploymentState,
+	cids RepairCIDs,
+	deploymentManifest bideplmanifest.Manifest,
+	stemcellManifest bistemcell.Manifest,
+) (biconfig.DeploymentState, error) {
+	// The manifest SHA is left empty so that the next create-env updates the
+	// repaired VM instead of assuming it already runs the manifest
+	deploymentState := biconfig.DeploymentState{
+		DirectorID:        previousState.DirectorID,
+		InstallationID:    previousState.InstallationID,
+		CurrentVMCID:      cids.VM,
+		CurrentReleaseIDs: []string{},
+		Disks:             []biconfig.DiskRecord{},
+		Stemcells:         []biconfig.StemcellRecord{},
+		Releases:          []biconfig.ReleaseRecord{},
+	}
+
+	if cids.Stemcell != "" {
+		id, err := c.uuidGenerator.Generate()
+		if err != nil {
+			return deploymentState, bosherr.WrapError(err, "Generating stemcell id")
+		}
+
+		deploymentState.CurrentStemcellID = id
+		deploymentState.Stemcells = append(deploymentState.Stemcells, biconfig.StemcellRecord{
+			ID:         id,
+			Name:       stemcellManifest.Name,
+			Version:    stemcellManifest.Version,
+			ApiVersion: stemcellManifest.ApiVersion,
+			CID:        cids.Stemcell,
+		})
+	}
+
+	if cids.Disk != "" {
+		diskPool, err := deploymentManifest.DiskPool(deploymentManifest.JobName())
+		if err != nil {
+			return deploymentState, err
+		}
+
+		if diskPool.DiskSize > 0 {
+			id, err := c.uuidGenerator.Generate()
+			if err != nil {
+				return deploymentState, bosherr.WrapError(err, "Generating disk id")
+			}
+
+			deploymentState.CurrentDiskID = id
+			deploymentState.Disks = append(deploymentState.Disks, biconfig.DiskRecord{
+				ID:              id,
+				CID:             cids.Disk,
+				Size:            diskPool.DiskSize,
+				CloudProperties: diskPool.CloudProperties,
+			})
+		}
+	}
+
+	for _, release := range c.releaseManager.List() {
+		id, err := c.uuidGenerator.Generate()
+		if err != nil {
+			return deploymentState, bosherr.WrapError(err, "Generating release id")
+		}
+
+		deploymentState.CurrentReleaseIDs = append(deploymentState.CurrentReleaseIDs, id)
+		deploymentState.Releases = append(deploymentState.Releases, biconfig.ReleaseRecord{
+			ID:      id,
+			Name:    release.Name(),
+			Version: release.Version(),
+		})
+	}
+
+	return deploymentState, nil
+}
+
+// danglingRecords reports disks and stemcells from the previous state that
+// are left out of the repaired state and may still exist in the IaaS.
+func (c *deploymentStateRepairer) danglingRecords(previousState, deploymentState biconfig.DeploymentState) []repairedRecord {
+	var records []repairedRecord
+
+	for _, disk := range previousState.Disks {
+		if !c.hasDisk(deploymentState, disk.CID) {
+			records = append(records, repairedRecord{kind: "disk", cid: disk.CID, status: "dangling: dropped from state, delete it from the IaaS if it exists"})
+		}
+	}
+
+	for _, stemcell := range previousState.Stemcells {
+		if !c.hasStemcell(deploymentState, stemcell.CID) {
+			records = append(records, repairedRecord{
+				kind:   "stemcell",
+				cid:    stemcell.CID,
+				status: fmt.Sprintf("dangling: '%s/%s' dropped from state, delete it from the IaaS if it exists", stemcell.Name, stemcell.Version),
+			})
+		}
+	}
+
+	return records
+}
+
+func (c *deploymentStateRepairer) hasDisk(deploymentState biconfig.DeploymentState, cid string) bool {
+	for _, disk := range deploymentState.Disks {
+		if disk.CID == cid {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *deploymentStateRepairer) hasStemcell(deploymentState biconfig.DeploymentState, cid string) bool {
+	for _, stemcell := range deploymentState.Stemcells {
+		if stemcell.CID == cid {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *deploymentStateRepairer) printRecords(records []repairedRecord) {
+	table := boshtbl.Table{
+		Content: "records",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Type"),
+			boshtbl.NewHeader("CID"),
+			boshtbl.NewHeader("Status"),
+		},
+	}
+
+	for _, record := range records {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(record.kind),
+			boshtbl.NewValueString(record.cid),
+			boshtbl.NewValueString(record.status),
+		})
+	}
+
+	c.ui.PrintTable(table)
+}
@@ -0,0 +1,299 @@
+package cmd_test
+
+import (
+	"errors"
+	"path/filepath"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	mockcloud "github.com/cloudfoundry/bosh-cli/v7/cloud/mocks"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	bicpirel "github.com/cloudfoundry/bosh-cli/v7/cpi/release"
+	bideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
+	fakebideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest/manifestfakes"
+	bidepltpl "github.com/cloudfoundry/bosh-cli/v7/deployment/template"
+	fakebidepltpl "github.com/cloudfoundry/bosh-cli/v7/deployment/template/templatefakes"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	biinstall "github.com/cloudfoundry/bosh-cli/v7/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-cli/v7/installation/manifest"
+	fakebiinstallmanifest "github.com/cloudfoundry/bosh-cli/v7/installation/manifest/fakes"
+	mockinstall "github.com/cloudfoundry/bosh-cli/v7/installation/mocks"
+	bitarball "github.com/cloudfoundry/bosh-cli/v7/installation/tarball"
+	boshjob "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	birelmanifest "github.com/cloudfoundry/bosh-cli/v7/release/manifest"
+	fakebirel "github.com/cloudfoundry/bosh-cli/v7/release/releasefakes"
+	. "github.com/cloudfoundry/bosh-cli/v7/release/resource"
+	birelsetmanifest "github.com/cloudfoundry/bosh-cli/v7/release/set/manifest"
+	fakebirelsetmanifest "github.com/cloudfoundry/bosh-cli/v7/release/set/manifest/fakes"
+	bistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
+	fakebistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell/stemcellfakes"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("DeploymentStateRepairer", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("RepairDeploymentState", func() {
+		var (
+			fs                     *fakesys.FakeFileSystem
+			logger                 boshlog.Logger
+			fakeUI                 *fakeui.FakeUI
+			fakeStage              *fakeui.FakeStage
+			deploymentStateService biconfig.DeploymentStateService
+
+			mockInstaller        *mockinstall.MockInstaller
+			mockInstallerFactory *mockinstall.MockInstallerFactory
+			mockCloudFactory     *mockcloud.MockFactory
+			mockCloud            *mockcloud.MockCloud
+
+			deploymentManifest bideplmanifest.Manifest
+
+			repairer cmd.DeploymentStateRepairer
+
+			deploymentManifestPath = filepath.Join("/", "path", "to", "manifest.yml")
+			deploymentStatePath    = filepath.Join("/", "path", "to", "manifest-state.json")
+			cpiReleaseTarballPath  = filepath.Join("/", "release", "tarball", "path")
+			stemcellTarballPath    = filepath.Join("/", "stemcell", "tarball", "path")
+		)
+
+		BeforeEach(func() {
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+			fs = fakesys.NewFakeFileSystem()
+			fakeUI = &fakeui.FakeUI{}
+			fakeStage = fakeui.NewFakeStage()
+
+			Expect(fs.WriteFileString(deploymentManifestPath, "")).To(Succeed())
+			Expect(fs.WriteFileString(cpiReleaseTarballPath, "")).To(Succeed())
+			Expect(fs.WriteFileString(stemcellTarballPath, "")).To(Succeed())
+
+			deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, &fakeuuid.FakeGenerator{}, logger, deploymentStatePath, nil)
+			err := deploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:        "fake-director-id",
+				InstallationID:    "fake-installation-id",
+				CurrentVMCID:      "old-vm-cid",
+				CurrentStemcellID: "old-stemcell-id",
+				CurrentDiskID:     "old-disk-id",
+				Stemcells: []biconfig.StemcellRecord{
+					{ID: "old-stemcell-id", Name: "old-stemcell-name", Version: "1", CID: "old-stemcell-cid"},
+				},
+				Disks: []biconfig.DiskRecord{
+					{ID: "old-disk-id", CID: "old-disk-cid", Size: 1024, CloudProperties: biproperty.Map{}},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			releaseManager := biinstall.NewReleaseManager(logger)
+
+			cpiRelease := &fakebirel.FakeRelease{}
+			cpiRelease.NameReturns("fake-cpi-release-name")
+			cpiRelease.VersionReturns("1.0")
+			job := boshjob.NewJob(NewResource("fake-cpi-release-job-name", "job-fp", nil))
+			job.Templates = map[string]string{"templates/cpi.erb": "bin/cpi"}
+			cpiRelease.JobsReturns([]*boshjob.Job{job})
+			cpiRelease.FindJobByNameReturns(*job, true)
+
+			releaseReader := &fakebirel.FakeReader{}
+			releaseReader.ReadReturns(cpiRelease, nil)
+
+			installationManifest := biinstallmanifest.Manifest{
+				Templates: []biinstallmanifest.ReleaseJobRef{
+					{Name: "fake-cpi-release-job-name", Release: "fake-cpi-release-name"},
+				},
+			}
+
+			fakeReleaseSetParser := fakebirelsetmanifest.NewFakeParser()
+			fakeReleaseSetParser.ParseManifest = birelsetmanifest.Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{Name: "fake-cpi-release-name", URL: "file://" + cpiReleaseTarballPath},
+				},
+			}
+			fakeInstallationParser := fakebiinstallmanifest.NewFakeParser()
+			fakeInstallationParser.ParseManifest = installationManifest
+
+			deploymentManifest = bideplmanifest.Manifest{
+				Name: "fake-deployment-name",
+				Jobs: []bideplmanifest.Job{
+					{Name: "fake-job-name", PersistentDisk: 2048},
+				},
+				ResourcePools: []bideplmanifest.ResourcePool{
+					{Stemcell: bideplmanifest.StemcellRef{URL: "file://" + stemcellTarballPath}},
+				},
+			}
+
+			fakeDeploymentParser := &fakebideplmanifest.FakeParser{}
+			fakeDeploymentParser.ParseReturns(deploymentManifest, nil)
+			fakeDeploymentValidator := fakebideplmanifest.NewFakeValidator()
+			fakeDeploymentValidator.SetValidateBehavior([]fakebideplmanifest.ValidateOutput{{Err: nil}})
+			fakeDeploymentValidator.SetValidateReleaseJobsBehavior([]fakebideplmanifest.ValidateReleaseJobsOutput{{Err: nil}})
+			fakeDeploymentTemplateFactory := &fakebidepltpl.FakeDeploymentTemplateFactory{}
+			fakeDeploymentTemplateFactory.NewDeploymentTemplateFromPathReturns(bidepltpl.NewDeploymentTemplate([]byte("--- {}")), nil)
+
+			extractedStemcell := bistemcell.NewExtractedStemcell(
+				bistemcell.Manifest{Name: "fake-stemcell-name", Version: "2", ApiVersion: 2, CloudProperties: biproperty.Map{}},
+				"fake-extracted-path",
+				nil,
+				fs,
+			)
+			fakeStemcellExtractor := fakebistemcell.NewFakeExtractor()
+			fakeStemcellExtractor.SetExtractBehavior(stemcellTarballPath, extractedStemcell, nil)
+
+			tarballCache := bitarball.NewCache("fake-base-path", fs, logger)
+			tarballProvider := bitarball.NewProvider(tarballCache, fs, nil, 1, 0, logger)
+
+			target := biinstall.NewTarget(filepath.Join("fake-install-dir", "fake-installation-id"), "")
+			installation := biinstall.NewInstallation(target, []biinstall.InstalledJob{}, installationManifest)
+
+			mockInstaller = mockinstall.NewMockInstaller(mockCtrl)
+			mockInstallerFactory = mockinstall.NewMockInstallerFactory(mockCtrl)
+			mockInstallerFactory.EXPECT().NewInstaller(target).Return(mockInstaller).AnyTimes()
+			mockInstaller.EXPECT().Install(installationManifest, gomock.Any()).Return(installation, nil).AnyTimes()
+			mockInstaller.EXPECT().Cleanup(installation).AnyTimes()
+
+			mockCloud = mockcloud.NewMockCloud(mockCtrl)
+			mockCloud.EXPECT().Info().Return(bicloud.CpiInfo{ApiVersion: 2}, nil).AnyTimes()
+
+			mockCloudFactory = mockcloud.NewMockFactory(mockCtrl)
+			mockCloudFactory.EXPECT().NewCloud(installation, "fake-director-id", 2).Return(mockCloud, nil).AnyTimes()
+
+			repairer = cmd.NewDeploymentStateRepairer(
+				fakeUI,
+				"repairEnvStateCmd",
+				logger,
+				deploymentStateService,
+				false,
+				&fakeuuid.FakeGenerator{},
+				releaseManager,
+				mockCloudFactory,
+				deploymentManifestPath,
+				boshtpl.StaticVariables{},
+				nil,
+				bicpirel.CpiInstaller{ReleaseManager: releaseManager, InstallerFactory: mockInstallerFactory},
+				biinstall.NewReleaseFetcher(tarballProvider, releaseReader, releaseManager),
+				bistemcell.Fetcher{TarballProvider: tarballProvider, StemcellExtractor: fakeStemcellExtractor},
+				cmd.ReleaseSetAndInstallationManifestParser{
+					ReleaseSetParser:   fakeReleaseSetParser,
+					InstallationParser: fakeInstallationParser,
+				},
+				cmd.NewDeploymentManifestParser(fakeDeploymentParser, fakeDeploymentValidator, releaseManager, fakeDeploymentTemplateFactory),
+				cmd.NewTempRootConfigurator(fs),
+				biinstall.NewTargetProvider(deploymentStateService, &fakeuuid.FakeGenerator{}, "fake-install-dir", ""),
+			)
+		})
+
+		It("rebuilds the deployment state from the given CIDs", func() {
+			mockCloud.EXPECT().HasVM("new-vm-cid").Return(true, nil)
+			mockCloud.EXPECT().HasVM("old-vm-cid").Return(false, nil)
+
+			err := repairer.RepairDeploymentState(cmd.RepairCIDs{
+				VM:       "new-vm-cid",
+				Disk:     "new-disk-cid",
+				Stemcell: "new-stemcell-cid",
+			}, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeUI.AskedConfirmationCalled).To(BeTrue())
+
+			state, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(state.DirectorID).To(Equal("fake-director-id"))
+			Expect(state.InstallationID).To(Equal("fake-installation-id"))
+			Expect(state.CurrentVMCID).To(Equal("new-vm-cid"))
+			Expect(state.CurrentManifestSHA).To(BeEmpty())
+
+			Expect(state.Stemcells).To(Equal([]biconfig.StemcellRecord{
+				{ID: state.CurrentStemcellID, Name: "fake-stemcell-name", Version: "2", ApiVersion: 2, CID: "new-stemcell-cid"},
+			}))
+			Expect(state.Disks).To(HaveLen(1))
+			Expect(state.Disks[0].ID).To(Equal(state.CurrentDiskID))
+			Expect(state.Disks[0].CID).To(Equal("new-disk-cid"))
+			Expect(state.Disks[0].Size).To(Equal(2048))
+			Expect(state.Releases).To(HaveLen(1))
+			Expect(state.Releases[0].Name).To(Equal("fake-cpi-release-name"))
+			Expect(state.CurrentReleaseIDs).To(Equal([]string{state.Releases[0].ID}))
+
+			Expect(fakeStage.PerformCalls).To(ContainElement(&fakeui.PerformCall{Name: "Validating CIDs with the CPI"}))
+		})
+
+		It("reports records from the previous state as dangling", func() {
+			mockCloud.EXPECT().HasVM("new-vm-cid").Return(true, nil)
+			mockCloud.EXPECT().HasVM("old-vm-cid").Return(true, nil)
+
+			err := repairer.RepairDeploymentState(cmd.RepairCIDs{VM: "new-vm-cid"}, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeUI.Table.Content).To(Equal("records"))
+			Expect(fakeUI.Table.Rows).To(Equal([][]boshtbl.Value{
+				{
+					boshtbl.NewValueString("vm"),
+					boshtbl.NewValueString("new-vm-cid"),
+					boshtbl.NewValueString("verified with the CPI"),
+				},
+				{
+					boshtbl.NewValueString("vm"),
+					boshtbl.NewValueString("old-vm-cid"),
+					boshtbl.NewValueString("dangling: previous VM still exists, delete it from the IaaS"),
+				},
+				{
+					boshtbl.NewValueString("disk"),
+					boshtbl.NewValueString("old-disk-cid"),
+					boshtbl.NewValueString("dangling: dropped from state, delete it from the IaaS if it exists"),
+				},
+				{
+					boshtbl.NewValueString("stemcell"),
+					boshtbl.NewValueString("old-stemcell-cid"),
+					boshtbl.NewValueString("dangling: 'old-stemcell-name/1' dropped from state, delete it from the IaaS if it exists"),
+				},
+			}))
+		})
+
+		It("returns an error when the VM does not exist", func() {
+			mockCloud.EXPECT().HasVM("new-vm-cid").Return(false, nil)
+
+			err := repairer.RepairDeploymentState(cmd.RepairCIDs{VM: "new-vm-cid"}, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VM 'new-vm-cid' does not exist"))
+
+			state, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.CurrentVMCID).To(Equal("old-vm-cid"))
+		})
+
+		It("returns an error when no VM CID is given", func() {
+			err := repairer.RepairDeploymentState(cmd.RepairCIDs{}, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected a VM CID to repair the deployment state"))
+		})
+
+		It("does not save the state when the user does not confirm", func() {
+			mockCloud.EXPECT().HasVM("new-vm-cid").Return(true, nil)
+			mockCloud.EXPECT().HasVM("old-vm-cid").Return(false, nil)
+
+			fakeUI.AskedConfirmationErr = errors.New("stop")
+
+			err := repairer.RepairDeploymentState(cmd.RepairCIDs{VM: "new-vm-cid"}, fakeStage)
+			Expect(err).To(HaveOccurred())
+
+			state, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.CurrentVMCID).To(Equal("old-vm-cid"))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+	boshreldir "github.com/cloudfoundry/bosh-cli/v7/releasedir"
+)
+
+// NewDeploymentStateService picks a deployment state backend based on --state.
+// Plain paths and file:// URIs are kept on the local file system;
+// s3://, gcs:// (gs://), http:// and https:// URIs are kept remotely.
+// The state is encrypted at rest when encryptor is not nil.
+func NewDeploymentStateService(deps BasicDeps, manifestPath string, statePath string, encryptor bicrypto.StateEncryptor) biconfig.DeploymentStateService {
+	if !strings.Contains(statePath, "://") {
+		return biconfig.NewFileSystemDeploymentStateService(
+			deps.FS, deps.UUIDGen, deps.Logger, biconfig.DeploymentStatePath(manifestPath, statePath), encryptor)
+	}
+
+	stateURL, err := url.Parse(statePath)
+	if err != nil {
+		return biconfig.NewErrDeploymentStateService(statePath, bosherr.WrapErrorf(err, "Parsing state URI '%s'", statePath))
+	}
+
+	blobID := strings.TrimPrefix(stateURL.Path, "/")
+
+	if blobID == "" {
+		return biconfig.NewErrDeploymentStateService(statePath, bosherr.Errorf("Expected state URI '%s' to include an object path", statePath))
+	}
+
+	var blobstore biconfig.StateBlobstore
+
+	switch stateURL.Scheme {
+	case "file":
+		return biconfig.NewFileSystemDeploymentStateService(deps.FS, deps.UUIDGen, deps.Logger, stateURL.Path, encryptor)
+
+	case "s3":
+		options := stateURLOptions(stateURL)
+		options["bucket_name"] = stateURL.Host
+		if _, found := options["credentials_source"]; !found {
+			options["credentials_source"] = "env_or_profile"
+		}
+		blobstore = boshreldir.NewS3Blobstore(deps.FS, deps.UUIDGen, options)
+
+	case "gcs", "gs":
+		options := stateURLOptions(stateURL)
+		options["bucket_name"] = stateURL.Host
+		blobstore = boshreldir.NewGCSBlobstore(deps.FS, deps.UUIDGen, options)
+
+	case "http", "https":
+		password, _ := stateURL.User.Password()
+		endpoint := url.URL{Scheme: stateURL.Scheme, Host: stateURL.Host, Path: path.Dir(stateURL.Path)}
+		blobID = path.Base(stateURL.Path)
+		blobstore = biconfig.NewHTTPStateBlobstore(
+			endpoint.String(), stateURL.User.Username(), password, httpclient.CreateExternalDefaultClient(nil), deps.FS)
+
+	default:
+		return biconfig.NewErrDeploymentStateService(statePath, bosherr.Errorf("Unsupported state URI scheme '%s'", stateURL.Scheme))
+	}
+
+	return biconfig.NewRemoteDeploymentStateService(blobstore, blobID, deps.FS, deps.UUIDGen, deps.Logger, stateURL.Redacted(), encryptor)
+}
+
+// DeploymentCPIAuditLogPath returns the path of the CPI audit log kept next to
+// the state file. Audit logs are only kept for local state files.
+func DeploymentCPIAuditLogPath(manifestPath string, statePath string) (string, error) {
+	if !strings.Contains(statePath, "://") {
+		return biconfig.DeploymentStatePath(manifestPath, statePath) + ".cpi-log", nil
+	}
+
+	stateURL, err := url.Parse(statePath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Parsing state URI '%s'", statePath)
+	}
+
+	if stateURL.Scheme != "file" {
+		return "", bosherr.Errorf("Expected state '%s' to be a local file to keep a CPI audit log", stateURL.Redacted())
+	}
+
+	return stateURL.Path + ".cpi-log", nil
+}
+
+// stateURLOptions converts query parameters into blobstore options,
+// e.g. s3://bucket/state.json?host=127.0.0.1&port=9000&use_ssl=false
+func stateURLOptions(stateURL *url.URL) map[string]interface{} {
+	options := map[string]interface{}{}
+
+	for key, values := range stateURL.Query() {
+		value := values[len(values)-1]
+
+		if i, err := strconv.Atoi(value); err == nil && key == "port" {
+			options[key] = i
+		} else if b, err := strconv.ParseBool(value); err == nil {
+			options[key] = b
+		} else {
+			options[key] = value
+		}
+	}
+
+	return options
+}
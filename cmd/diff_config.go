@@ -2,6 +2,7 @@ package cmd
 
 import (
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdiff "github.com/cloudfoundry/bosh-cli/v7/diff"
 	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
 	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
 )
@@ -16,6 +17,10 @@ func NewDiffConfigCmd(ui boshui.UI, director boshdir.Director) DiffConfigCmd {
 }
 
 func (c DiffConfigCmd) Run(opts DiffConfigOpts) error {
+	if opts.Format.IsSet() {
+		return c.printSemanticDiff(opts)
+	}
+
 	configDiff, err := c.director.DiffConfigByIDOrContent(opts.FromID, opts.FromContent.Bytes, opts.ToID, opts.ToContent.Bytes)
 	if err != nil {
 		return err
@@ -27,3 +32,36 @@ func (c DiffConfigCmd) Run(opts DiffConfigOpts) error {
 
 	return nil
 }
+
+// printSemanticDiff compares configs on the client instead of asking the director.
+func (c DiffConfigCmd) printSemanticDiff(opts DiffConfigOpts) error {
+	from, err := c.content(opts.FromID, opts.FromContent.Bytes)
+	if err != nil {
+		return err
+	}
+
+	to, err := c.content(opts.ToID, opts.ToContent.Bytes)
+	if err != nil {
+		return err
+	}
+
+	changes, err := boshdiff.CompareBytes(from, to)
+	if err != nil {
+		return err
+	}
+
+	return NewSemanticDiff(from, changes).Print(c.ui, opts.Format)
+}
+
+func (c DiffConfigCmd) content(id string, content []byte) ([]byte, error) {
+	if len(id) == 0 {
+		return content, nil
+	}
+
+	config, err := c.director.LatestConfigByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(config.Content), nil
+}
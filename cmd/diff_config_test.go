@@ -96,5 +96,50 @@ var _ = Describe("DiffConfigCmd", func() {
 				}))
 			Expect(director.DiffConfigByIDOrContentCallCount()).To(Equal(1))
 		})
+		Context("when format is set", func() {
+			BeforeEach(func() {
+				diffConfigOpts.Format = opts.DiffFormatUnified
+
+				director.LatestConfigByIDStub = func(id string) (boshdir.Config, error) {
+					return map[string]boshdir.Config{
+						"1": {ID: "1", Content: "vm_types: [{name: small, cloud_properties: {cpu: 1}}]"},
+						"2": {ID: "2", Content: "vm_types: [{name: small, cloud_properties: {cpu: 2}}]"},
+					}[id], nil
+				}
+			})
+
+			It("compares configs fetched by ID on the client", func() {
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(director.DiffConfigByIDOrContentCallCount()).To(Equal(0))
+				Expect(ui.Blocks).To(Equal([]string{
+					"@@ /vm_types/name=small/cloud_properties/cpu @@\n- 1\n+ 2\n",
+				}))
+			})
+
+			It("compares config contents on the client", func() {
+				diffConfigOpts = opts.DiffConfigOpts{
+					FromContent: opts.FileBytesArg{Bytes: []byte("a: 1")},
+					ToContent:   opts.FileBytesArg{Bytes: []byte("a: 1\nb: 2")},
+					Format:      opts.DiffFormatOps,
+				}
+
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(director.LatestConfigByIDCallCount()).To(Equal(0))
+				Expect(ui.Blocks[0]).To(MatchJSON(`[{"type": "replace", "path": "/b?", "value": 2}]`))
+			})
+
+			It("returns an error if config cannot be fetched", func() {
+				director.LatestConfigByIDStub = nil
+				director.LatestConfigByIDReturns(boshdir.Config{}, errors.New("fake-err"))
+
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-err"))
+			})
+		})
 	})
 })
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdiff "github.com/cloudfoundry/bosh-cli/v7/diff"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type DiffManifestsCmd struct {
+	ui boshui.UI
+}
+
+func NewDiffManifestsCmd(ui boshui.UI) DiffManifestsCmd {
+	return DiffManifestsCmd{ui: ui}
+}
+
+func (c DiffManifestsCmd) Run(opts DiffManifestsOpts) error {
+	if opts.Redact && opts.Format.IsPatch() {
+		return bosherr.Errorf("Expected --redact not to be used with --format %s since redacted values would be applied", opts.Format)
+	}
+
+	changes, err := boshdiff.CompareBytes(opts.Args.From.Bytes, opts.Args.To.Bytes)
+	if err != nil {
+		return err
+	}
+
+	if opts.Redact {
+		changes = boshdiff.Redact(changes)
+	}
+
+	return NewSemanticDiff(opts.Args.From.Bytes, changes).Print(c.ui, opts.Format)
+}
@@ -0,0 +1,167 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("DiffManifestsCmd", func() {
+	var (
+		ui      *fakeui.FakeUI
+		command cmd.DiffManifestsCmd
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		command = cmd.NewDiffManifestsCmd(ui)
+	})
+
+	Describe("Run", func() {
+		var (
+			diffOpts opts.DiffManifestsOpts
+		)
+
+		BeforeEach(func() {
+			diffOpts = opts.DiffManifestsOpts{
+				Args: opts.DiffManifestsArgs{
+					From: opts.FileBytesArg{Bytes: []byte(`
+instance_groups:
+- name: web
+  instances: 1
+  jobs:
+  - name: nginx
+    properties: {port: 80}
+- name: db
+`)},
+					To: opts.FileBytesArg{Bytes: []byte(`
+instance_groups:
+- name: web
+  instances: 1
+  jobs:
+  - name: nginx
+    properties: {port: 8080}
+- name: worker
+  instances: 2
+`)},
+				},
+			}
+		})
+
+		act := func() error { return command.Run(diffOpts) }
+
+		It("shows unified diff by default", func() {
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks).To(Equal([]string{
+				"@@ /instance_groups/name=web/jobs/name=nginx/properties/port @@\n- 80\n+ 8080\n" +
+					"@@ /instance_groups/name=db @@\n- name: db\n" +
+					"@@ /instance_groups/name=worker @@\n+ name: worker\n+ instances: 2\n",
+			}))
+		})
+
+		It("shows side by side diff", func() {
+			diffOpts.Format = opts.DiffFormatSideBySide
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Table).To(Equal(boshtbl.Table{
+				Content: "differences",
+
+				Header: []boshtbl.Header{
+					boshtbl.NewHeader("Path"),
+					boshtbl.NewHeader("Change"),
+					boshtbl.NewHeader("From"),
+					boshtbl.NewHeader("To"),
+				},
+
+				Rows: [][]boshtbl.Value{
+					{
+						boshtbl.NewValueString("/instance_groups/name=web/jobs/name=nginx/properties/port"),
+						boshtbl.NewValueString("changed"),
+						boshtbl.NewValueString("80"),
+						boshtbl.NewValueString("8080"),
+					},
+					{
+						boshtbl.NewValueString("/instance_groups/name=db"),
+						boshtbl.NewValueString("removed"),
+						boshtbl.NewValueString("name: db"),
+						boshtbl.NewValueString(""),
+					},
+					{
+						boshtbl.NewValueString("/instance_groups/name=worker"),
+						boshtbl.NewValueString("added"),
+						boshtbl.NewValueString(""),
+						boshtbl.NewValueString("name: worker\ninstances: 2"),
+					},
+				},
+			}))
+		})
+
+		It("shows diff as operations", func() {
+			diffOpts.Format = opts.DiffFormatOps
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks).To(HaveLen(1))
+			Expect(ui.Blocks[0]).To(MatchJSON(`[
+				{"type": "replace", "path": "/instance_groups/name=web/jobs/name=nginx/properties/port", "value": 8080},
+				{"type": "remove", "path": "/instance_groups/name=db"},
+				{"type": "replace", "path": "/instance_groups/-", "value": {"name": "worker", "instances": 2}}
+			]`))
+		})
+
+		It("shows diff as JSON patch", func() {
+			diffOpts.Format = opts.DiffFormatJSONPatch
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks).To(HaveLen(1))
+			Expect(ui.Blocks[0]).To(MatchJSON(`[
+				{"op": "replace", "path": "/instance_groups/0/jobs/0/properties/port", "value": 8080},
+				{"op": "remove", "path": "/instance_groups/1"},
+				{"op": "add", "path": "/instance_groups/-", "value": {"name": "worker", "instances": 2}}
+			]`))
+		})
+
+		It("returns error if redacted diff is requested as operations", func() {
+			diffOpts.Format = opts.DiffFormatOps
+			diffOpts.Redact = true
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected --redact not to be used with --format ops since redacted values would be applied"))
+			Expect(ui.Blocks).To(BeEmpty())
+		})
+
+		It("redacts values under properties if requested", func() {
+			diffOpts.Redact = true
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks[0]).To(HavePrefix(
+				"@@ /instance_groups/name=web/jobs/name=nginx/properties/port @@\n- <redacted>\n+ <redacted>\n",
+			))
+		})
+
+		It("shows nothing if manifests are equal", func() {
+			diffOpts.Args.To = diffOpts.Args.From
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks).To(BeEmpty())
+		})
+
+		It("returns error if manifest cannot be parsed", func() {
+			diffOpts.Args.To.Bytes = []byte("a: [1")
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing second document"))
+		})
+	})
+})
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type EncryptEnvStateCmd struct {
+	ui                     boshui.UI
+	deploymentStateService biconfig.DeploymentStateService
+	encryptor              bicrypto.StateEncryptor
+	logger                 boshlog.Logger
+}
+
+func NewEncryptEnvStateCmd(
+	ui boshui.UI,
+	deploymentStateService biconfig.DeploymentStateService,
+	encryptor bicrypto.StateEncryptor,
+	logger boshlog.Logger,
+) EncryptEnvStateCmd {
+	return EncryptEnvStateCmd{ui: ui, deploymentStateService: deploymentStateService, encryptor: encryptor, logger: logger}
+}
+
+// Run rewrites the state, its snapshots and the vars-store with the encryptor.
+// Files that are already encrypted with the same key are encrypted again.
+func (c EncryptEnvStateCmd) Run(opts EncryptEnvStateOpts) error {
+	if c.encryptor == nil {
+		return bosherr.Error("Expected a state encryption key, use --state-key-file, --state-passphrase or BOSH_STATE_KEY")
+	}
+
+	if opts.VarsFSStore.IsSet() && opts.VarsFSStore.Encryptor == nil {
+		return bosherr.Error("Expected the vars-store to be given the state encryption key")
+	}
+
+	unlock, err := lockDeploymentState(c.deploymentStateService, opts.ForceUnlock, c.ui, c.logger, "EncryptEnvStateCmd")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = c.deploymentStateService.Rewrite()
+	if err != nil {
+		return bosherr.WrapError(err, "Encrypting deployment state")
+	}
+
+	c.ui.PrintLinef("Encrypted deployment state: '%s'", c.deploymentStateService.Path())
+
+	if opts.VarsFSStore.IsSet() {
+		err = opts.VarsFSStore.Rewrite()
+		if err != nil {
+			return bosherr.WrapError(err, "Encrypting variables file store")
+		}
+
+		c.ui.PrintLinef("Encrypted variables file store: '%s'", opts.VarsFSStore.Path())
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd_test
+
+import (
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("EncryptEnvStateCmd", func() {
+	var (
+		ui                     *fakeui.FakeUI
+		fs                     *fakesys.FakeFileSystem
+		logger                 boshlog.Logger
+		encryptor              bicrypto.StateEncryptor
+		deploymentStateService biconfig.DeploymentStateService
+		command                cmd.EncryptEnvStateCmd
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		encryptor = bicrypto.NewAESGCMStateEncryptor([]byte("fake-key"))
+
+		plainService := biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, "/fake-state.json", nil)
+		err := plainService.Save(biconfig.DeploymentState{DirectorID: "fake-director-id"})
+		Expect(err).ToNot(HaveOccurred())
+
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, "/fake-state.json", encryptor)
+		command = cmd.NewEncryptEnvStateCmd(ui, deploymentStateService, encryptor, logger)
+	})
+
+	It("encrypts the state and the vars-store in place", func() {
+		err := fs.WriteFileString("/fake-creds.yml", "admin_password: fake-password")
+		Expect(err).ToNot(HaveOccurred())
+
+		varsStore := opts.VarsFSStore{FS: fs}
+		Expect((&varsStore).UnmarshalFlag("/fake-creds.yml")).To(Succeed())
+		varsStore.Encryptor = encryptor
+
+		err = command.Run(opts.EncryptEnvStateOpts{VarsFSStore: varsStore})
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := fs.ReadFile("/fake-state.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bicrypto.IsEncryptedState(contents)).To(BeTrue())
+
+		contents, err = fs.ReadFile("/fake-creds.yml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bicrypto.IsEncryptedState(contents)).To(BeTrue())
+
+		state, err := deploymentStateService.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.DirectorID).To(Equal("fake-director-id"))
+
+		Expect(ui.Said).To(Equal([]string{
+			"Encrypted deployment state: '/fake-state.json'",
+			"Encrypted variables file store: '/fake-creds.yml'",
+		}))
+		Expect(fs.FileExists("/fake-state.json.lock")).To(BeFalse())
+	})
+
+	It("returns an error without an encryption key", func() {
+		command = cmd.NewEncryptEnvStateCmd(ui, deploymentStateService, nil, logger)
+
+		err := command.Run(opts.EncryptEnvStateOpts{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected a state encryption key, use --state-key-file, --state-passphrase or BOSH_STATE_KEY"))
+
+		contents, err := fs.ReadFile("/fake-state.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bicrypto.IsEncryptedState(contents)).To(BeFalse())
+	})
+
+	It("returns an error when the state does not exist", func() {
+		Expect(fs.RemoveAll("/fake-state.json")).To(Succeed())
+
+		err := command.Run(opts.EncryptEnvStateOpts{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Deployment state file '/fake-state.json' does not exist"))
+	})
+})
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	biuifmt "github.com/cloudfoundry/bosh-cli/v7/ui/fmt"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+type EnvCPILogCmd struct {
+	ui       boshui.UI
+	auditLog bicloud.CPIAuditLog
+}
+
+func NewEnvCPILogCmd(ui boshui.UI, auditLog bicloud.CPIAuditLog) EnvCPILogCmd {
+	return EnvCPILogCmd{ui: ui, auditLog: auditLog}
+}
+
+func (c EnvCPILogCmd) Run(opts EnvCPILogOpts) error {
+	entries, err := c.auditLog.Entries()
+	if err != nil {
+		return err
+	}
+
+	table := boshtbl.Table{
+		Content: "CPI calls",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Time"),
+			boshtbl.NewHeader("Method"),
+			boshtbl.NewHeader("CID"),
+			boshtbl.NewHeader("Duration"),
+			boshtbl.NewHeader("Error Type"),
+			boshtbl.NewHeader("Error"),
+		},
+		Notes: []string{"Audit log: " + c.auditLog.Path()},
+	}
+
+	if opts.Logs {
+		table.Header = append(table.Header, boshtbl.NewHeader("Log"))
+	}
+
+	for _, entry := range entries {
+		method := entry.Method
+		if entry.Replayed {
+			method += " (replayed)"
+		}
+
+		row := []boshtbl.Value{
+			boshtbl.NewValueTime(entry.Time),
+			boshtbl.NewValueString(method),
+			boshtbl.NewValueString(entry.CID),
+			boshtbl.NewValueString(biuifmt.Duration(entry.Duration())),
+			boshtbl.NewValueFmt(boshtbl.NewValueString(entry.ErrorType), true),
+			boshtbl.NewValueFmt(boshtbl.NewValueString(entry.Error), true),
+		}
+
+		if opts.Logs {
+			row = append(row, boshtbl.NewValueString(entry.Log))
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	c.ui.PrintTable(table)
+
+	return nil
+}
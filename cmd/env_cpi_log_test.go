@@ -0,0 +1,101 @@
+package cmd_test
+
+import (
+	"errors"
+	"time"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("EnvCPILogCmd", func() {
+	var (
+		ui      *fakeui.FakeUI
+		fs      *fakesys.FakeFileSystem
+		command cmd.EnvCPILogCmd
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs = fakesys.NewFakeFileSystem()
+		command = cmd.NewEnvCPILogCmd(ui, bicloud.NewFileCPIAuditLog(fs, "/state.json.cpi-log"))
+
+		err := fs.WriteFileString("/state.json.cpi-log",
+			`{"time":"2026-10-01T12:00:00Z","method":"create_vm","arguments":[],"cid":"fake-vm-cid","duration_ms":65000,"log":"fake-log"}`+"\n"+
+				`{"time":"2026-10-01T12:01:05Z","method":"attach_disk","arguments":[],"duration_ms":2000,"error_type":"Bosh::Clouds::DiskNotFound","error":"fake-message"}`+"\n")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("lists audited CPI calls", func() {
+		err := command.Run(opts.EnvCPILogOpts{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table).To(Equal(boshtbl.Table{
+			Content: "CPI calls",
+
+			Header: []boshtbl.Header{
+				boshtbl.NewHeader("Time"),
+				boshtbl.NewHeader("Method"),
+				boshtbl.NewHeader("CID"),
+				boshtbl.NewHeader("Duration"),
+				boshtbl.NewHeader("Error Type"),
+				boshtbl.NewHeader("Error"),
+			},
+
+			Rows: [][]boshtbl.Value{
+				{
+					boshtbl.NewValueTime(time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)),
+					boshtbl.NewValueString("create_vm"),
+					boshtbl.NewValueString("fake-vm-cid"),
+					boshtbl.NewValueString("00:01:05"),
+					boshtbl.NewValueFmt(boshtbl.NewValueString(""), true),
+					boshtbl.NewValueFmt(boshtbl.NewValueString(""), true),
+				},
+				{
+					boshtbl.NewValueTime(time.Date(2026, time.October, 1, 12, 1, 5, 0, time.UTC)),
+					boshtbl.NewValueString("attach_disk"),
+					boshtbl.NewValueString(""),
+					boshtbl.NewValueString("00:00:02"),
+					boshtbl.NewValueFmt(boshtbl.NewValueString("Bosh::Clouds::DiskNotFound"), true),
+					boshtbl.NewValueFmt(boshtbl.NewValueString("fake-message"), true),
+				},
+			},
+
+			Notes: []string{"Audit log: /state.json.cpi-log"},
+		}))
+	})
+
+	It("shows CPI logs when requested", func() {
+		err := command.Run(opts.EnvCPILogOpts{Logs: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table.Header[6]).To(Equal(boshtbl.NewHeader("Log")))
+		Expect(ui.Table.Rows[0][6]).To(Equal(boshtbl.NewValueString("fake-log")))
+	})
+
+	It("marks replayed CPI calls", func() {
+		err := fs.WriteFileString("/state.json.cpi-log",
+			`{"time":"2026-10-01T12:00:00Z","method":"create_vm","arguments":[],"cid":"fake-vm-cid","duration_ms":0,"replayed":true}`+"\n")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = command.Run(opts.EnvCPILogOpts{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table.Rows[0][1]).To(Equal(boshtbl.NewValueString("create_vm (replayed)")))
+	})
+
+	It("returns an error if the audit log cannot be read", func() {
+		fs.RegisterReadFileError("/state.json.cpi-log", errors.New("fake-read-err"))
+
+		err := command.Run(opts.EnvCPILogOpts{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading CPI audit log '/state.json.cpi-log'"))
+	})
+})
@@ -7,12 +7,15 @@ import (
 
 	bihttpagent "github.com/cloudfoundry/bosh-agent/v2/agentclient/http"
 	"github.com/cloudfoundry/bosh-utils/httpclient"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
 
 	biblobstore "github.com/cloudfoundry/bosh-cli/v7/blobstore"
 	bicloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
 	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
 	bicpirel "github.com/cloudfoundry/bosh-cli/v7/cpi/release"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 	bidepl "github.com/cloudfoundry/bosh-cli/v7/deployment"
 	bidisk "github.com/cloudfoundry/bosh-cli/v7/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-cli/v7/deployment/instance"
@@ -35,6 +38,18 @@ import (
 	bitemplateerb "github.com/cloudfoundry/bosh-cli/v7/templatescompiler/erbrenderer"
 )
 
+// EnvCPIConfig selects the CPI used by env commands and how it is called.
+type EnvCPIConfig struct {
+	// Name selects a built-in CPI instead of the installed CPI release,
+	// overriding cloud_provider.cpi in the manifest
+	Name        string
+	Cassette    bicloud.CPICassetteConfig
+	RetryPolicy bicloud.CPIRetryPolicy
+
+	// AuditLog keeps CPI calls next to the state file; only set for commands that call the CPI
+	AuditLog bool
+}
+
 type envFactory struct {
 	deps         BasicDeps
 	manifestPath string
@@ -42,6 +57,7 @@ type envFactory struct {
 	manifestOp   patch.Op
 
 	deploymentStateService     biconfig.DeploymentStateService
+	forceUnlock                bool
 	installationManifestParser ReleaseSetAndInstallationManifestParser
 
 	releaseManager  boshinst.ReleaseManager
@@ -72,12 +88,29 @@ func NewEnvFactory(
 	manifestOp patch.Op,
 	recreatePersistentDisks bool,
 	packageDir string,
+	forceUnlock bool,
+	stateEncryptor bicrypto.StateEncryptor,
+	cpiConfig EnvCPIConfig,
+	rubyERBRenderer bool,
 ) *envFactory {
+	if cpiConfig.Name != "" {
+		ops := patch.Ops{}
+		if manifestOp != nil {
+			ops = append(ops, manifestOp)
+		}
+
+		manifestOp = append(ops, patch.ReplaceOp{
+			Path:  patch.MustNewPointerFromString("/cloud_provider/cpi?"),
+			Value: cpiConfig.Name,
+		})
+	}
+
 	f := envFactory{
 		deps:         deps,
 		manifestPath: manifestPath,
 		manifestVars: manifestVars,
 		manifestOp:   manifestOp,
+		forceUnlock:  forceUnlock,
 	}
 
 	f.releaseManager = boshinst.NewReleaseManager(deps.Logger)
@@ -111,17 +144,17 @@ func NewEnvFactory(
 		}
 	}
 
-	f.deploymentStateService = biconfig.NewFileSystemDeploymentStateService(
-		deps.FS, deps.UUIDGen, deps.Logger, biconfig.DeploymentStatePath(manifestPath, statePath))
+	f.deploymentStateService = NewDeploymentStateService(deps, manifestPath, statePath, stateEncryptor)
 
 	{
 		installerFactory := boshinst.NewInstallerFactory(
 			deps.UI, deps.CmdRunner, deps.Compressor, releaseJobResolver,
-			deps.UUIDGen, deps.Logger, deps.FS, deps.DigestCreationAlgorithms)
+			deps.UUIDGen, deps.Logger, deps.FS, deps.DigestCreationAlgorithms, rubyERBRenderer)
 
 		f.cpiInstaller = bicpirel.CpiInstaller{
 			ReleaseManager:   f.releaseManager,
 			InstallerFactory: installerFactory,
+			SkipInstall:      cpiConfig.Cassette.ReplayPath != "",
 		}
 	}
 
@@ -146,14 +179,60 @@ func NewEnvFactory(
 	}
 
 	{
-		f.blobstoreFactory = biblobstore.NewBlobstoreFactory(deps.UUIDGen, deps.FS, deps.Logger)
 		f.deploymentFactory = bidepl.NewFactory(10*time.Second, 500*time.Millisecond)
-		f.agentClientFactory = bihttpagent.NewAgentClientFactory(1*time.Second, deps.Logger)
-		f.cloudFactory = bicloud.NewFactory(deps.FS, deps.CmdRunner, deps.Logger)
+
+		var cpiAuditLog bicloud.CPIAuditLog
+
+		if cpiConfig.AuditLog {
+			cpiAuditLogPath, err := DeploymentCPIAuditLogPath(manifestPath, statePath)
+			if err == nil {
+				cpiAuditLog = bicloud.NewFileCPIAuditLog(deps.FS, cpiAuditLogPath)
+			} else {
+				// Remote states have no local file to keep the log next to
+				deps.Logger.Warn("envFactory", "Disabling CPI audit log: %s", err.Error())
+				deps.UI.ErrorLinef("Warning: CPI audit log is disabled: %s", err.Error())
+			}
+		}
+
+		if usesLocalFakeCPI(deps.FS, manifestPath, manifestOp) {
+			// Fake VMs are only known to the fake CPI, so their agents and blobstores are too
+			fakeCPIPath := filepath.Join(workspaceRootPath, "fake-cpi")
+
+			f.cloudFactory = bicloud.NewLocalFakeFactory(deps.FS, deps.UUIDGen, fakeCPIPath, cpiAuditLog, deps.Time, deps.Logger)
+			f.agentClientFactory = bicloud.NewLocalFakeAgentClientFactory(deps.FS, fakeCPIPath, deps.Logger)
+			f.blobstoreFactory = biblobstore.NewLocalBlobstoreFactory(deps.UUIDGen, deps.FS, filepath.Join(fakeCPIPath, "blobs"), deps.Logger)
+		} else {
+			f.agentClientFactory = bihttpagent.NewAgentClientFactory(1*time.Second, deps.Logger)
+			f.blobstoreFactory = biblobstore.NewBlobstoreFactory(deps.UUIDGen, deps.FS, deps.Logger)
+
+			var (
+				cassetteRecorder *bicloud.CPICassetteRecorder
+				cassettePlayer   *bicloud.CPICassettePlayer
+			)
+
+			if cpiConfig.Cassette.RecordPath != "" {
+				cassetteRecorder = bicloud.NewCPICassetteRecorder(deps.FS, cpiConfig.Cassette.RecordPath, deps.Logger)
+				f.agentClientFactory = bicloud.NewRecordingAgentClientFactory(f.agentClientFactory, cassetteRecorder)
+			}
+
+			if cpiConfig.Cassette.ReplayPath != "" {
+				// Replayed VMs do not exist, so there is no agent to answer or to fetch blobs
+				cassettePlayer = bicloud.NewCPICassettePlayer(deps.FS, cpiConfig.Cassette.ReplayPath, deps.Logger)
+				f.agentClientFactory = bicloud.NewReplayingAgentClientFactory(cassettePlayer)
+				f.blobstoreFactory = biblobstore.NewDiscardingBlobstoreFactory(deps.UUIDGen, deps.FS, deps.Logger)
+			}
+
+			f.cloudFactory = bicloud.NewFactory(
+				deps.FS, deps.CmdRunner, cassetteRecorder, cassettePlayer, cpiConfig.RetryPolicy, cpiAuditLog, deps.UI, deps.Time, deps.Logger)
+		}
 	}
 
 	{
 		erbRenderer := bitemplateerb.NewERBRenderer(deps.FS, deps.CmdRunner, deps.Logger)
+		if !rubyERBRenderer {
+			erbRenderer = bitemplateerb.NewNativeERBRenderer(deps.FS, erbRenderer, deps.UI, deps.Logger)
+		}
+
 		jobRenderer := bitemplate.NewJobRenderer(erbRenderer, deps.FS, deps.UUIDGen, deps.Logger)
 
 		builderFactory := biinstancestate.NewBuilderFactory(
@@ -193,6 +272,7 @@ func (f *envFactory) Preparer() DeploymentPreparer {
 		f.deps.Logger,
 		"DeploymentPreparer",
 		f.deploymentStateService,
+		f.forceUnlock,
 		biconfig.NewLegacyDeploymentStateMigrator(
 			f.deploymentStateService,
 			f.deps.FS,
@@ -227,6 +307,17 @@ func (f *envFactory) Preparer() DeploymentPreparer {
 		),
 		NewTempRootConfigurator(f.deps.FS),
 		f.targetProvider,
+		bidepl.NewPlanner(
+			bidepl.NewManagerFactory(
+				f.vmManagerFactory,
+				f.instanceManagerFactory,
+				f.diskManagerFactory,
+				f.stemcellManagerFactory,
+				f.deploymentFactory,
+			),
+			f.stemcellManagerFactory,
+			f.diskManagerFactory,
+		),
 	)
 }
 
@@ -236,6 +327,7 @@ func (f *envFactory) Deleter() DeploymentDeleter {
 		"DeploymentDeleter",
 		f.deps.Logger,
 		f.deploymentStateService,
+		f.forceUnlock,
 		f.releaseManager,
 		f.cloudFactory,
 		f.agentClientFactory,
@@ -259,12 +351,41 @@ func (f *envFactory) Deleter() DeploymentDeleter {
 	)
 }
 
+func (f *envFactory) StateRepairer() DeploymentStateRepairer {
+	return NewDeploymentStateRepairer(
+		f.deps.UI,
+		"DeploymentStateRepairer",
+		f.deps.Logger,
+		f.deploymentStateService,
+		f.forceUnlock,
+		f.deps.UUIDGen,
+		f.releaseManager,
+		f.cloudFactory,
+		f.manifestPath,
+		f.manifestVars,
+		f.manifestOp,
+		f.cpiInstaller,
+		f.releaseFetcher,
+		f.stemcellFetcher,
+		f.installationManifestParser,
+		NewDeploymentManifestParser(
+			bideplmanifest.NewParser(f.deps.FS, f.deps.Logger),
+			bideplmanifest.NewValidator(f.deps.Logger),
+			f.releaseManager,
+			bidepltpl.NewDeploymentTemplateFactory(f.deps.FS),
+		),
+		NewTempRootConfigurator(f.deps.FS),
+		f.targetProvider,
+	)
+}
+
 func (f *envFactory) StateManager() DeploymentStateManager {
 	return NewDeploymentStateManager(
 		f.deps.UI,
 		"DeploymentStateManager",
 		f.deps.Logger,
 		f.deploymentStateService,
+		f.forceUnlock,
 		f.agentClientFactory,
 		bidepl.NewManagerFactory(
 			f.vmManagerFactory,
@@ -285,3 +406,35 @@ func (f *envFactory) StateManager() DeploymentStateManager {
 		),
 	)
 }
+
+// usesLocalFakeCPI peeks at cloud_provider.cpi since the CPI, agent and
+// blobstore clients are chosen before the manifest is parsed. Variables are
+// left unresolved, parsing the manifest later reports any errors.
+func usesLocalFakeCPI(fs boshsys.FileSystem, manifestPath string, manifestOp patch.Op) bool {
+	contents, err := fs.ReadFile(manifestPath)
+	if err != nil {
+		return false
+	}
+
+	if manifestOp == nil {
+		manifestOp = patch.Ops{}
+	}
+
+	bytes, err := boshtpl.NewTemplate(contents).Evaluate(boshtpl.StaticVariables{}, manifestOp, boshtpl.EvaluateOpts{})
+	if err != nil {
+		return false
+	}
+
+	var manifest struct {
+		CloudProvider struct {
+			CPI string `yaml:"cpi"`
+		} `yaml:"cloud_provider"`
+	}
+
+	err = yaml.Unmarshal(bytes, &manifest)
+	if err != nil {
+		return false
+	}
+
+	return manifest.CloudProvider.CPI == bicloud.LocalFakeCPIName
+}
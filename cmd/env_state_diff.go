@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+type EnvStateDiffCmd struct {
+	ui                     boshui.UI
+	deploymentStateService biconfig.DeploymentStateService
+}
+
+func NewEnvStateDiffCmd(ui boshui.UI, deploymentStateService biconfig.DeploymentStateService) EnvStateDiffCmd {
+	return EnvStateDiffCmd{ui: ui, deploymentStateService: deploymentStateService}
+}
+
+func (c EnvStateDiffCmd) Run(opts EnvStateDiffOpts) error {
+	snapshots, err := c.deploymentStateService.History()
+	if err != nil {
+		return err
+	}
+
+	fromID, err := c.fromID(opts, snapshots)
+	if err != nil {
+		return err
+	}
+
+	from, err := c.deploymentStateService.LoadSnapshot(fromID)
+	if err != nil {
+		return err
+	}
+
+	toID := opts.To
+	var to biconfig.DeploymentState
+
+	if toID == "" {
+		if !c.deploymentStateService.Exists() {
+			return bosherr.Errorf("Deployment state '%s' does not exist", c.deploymentStateService.Path())
+		}
+
+		toID = "current"
+
+		to, err = c.deploymentStateService.Load()
+	} else {
+		to, err = c.deploymentStateService.LoadSnapshot(toID)
+	}
+	if err != nil {
+		return err
+	}
+
+	fromFields := deploymentStateFields(from)
+	toFields := deploymentStateFields(to)
+
+	table := boshtbl.Table{
+		Content: "deployment state differences",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Field"),
+			boshtbl.NewHeader(fromID),
+			boshtbl.NewHeader(toID),
+		},
+	}
+
+	for i, field := range fromFields {
+		if field[1] != toFields[i][1] {
+			table.Rows = append(table.Rows, []boshtbl.Value{
+				boshtbl.NewValueString(field[0]),
+				boshtbl.NewValueString(field[1]),
+				boshtbl.NewValueString(toFields[i][1]),
+			})
+		}
+	}
+
+	c.ui.PrintTable(table)
+
+	return nil
+}
+
+// fromID defaults to the snapshot preceding --to, or to the one preceding
+// the latest snapshot since the latest snapshot matches the current state.
+func (c EnvStateDiffCmd) fromID(opts EnvStateDiffOpts, snapshots []biconfig.DeploymentStateSnapshot) (string, error) {
+	if opts.From != "" {
+		return opts.From, nil
+	}
+
+	toIndex := len(snapshots) - 1
+
+	if opts.To != "" {
+		toIndex = -1
+		for i, snapshot := range snapshots {
+			if snapshot.ID == opts.To {
+				toIndex = i
+			}
+		}
+		if toIndex == -1 {
+			return "", bosherr.Errorf("Deployment state snapshot '%s' does not exist", opts.To)
+		}
+	}
+
+	if toIndex < 1 {
+		return "", bosherr.Error("Expected a previous deployment state snapshot to compare with, use --from")
+	}
+
+	return snapshots[toIndex-1].ID, nil
+}
+
+func deploymentStateFields(state biconfig.DeploymentState) [][2]string {
+	var disks, stemcells, releases []string
+
+	for _, disk := range state.Disks {
+		disks = append(disks, fmt.Sprintf("%s (cid: %s, size: %d)", disk.ID, disk.CID, disk.Size))
+	}
+
+	for _, stemcell := range state.Stemcells {
+		stemcells = append(stemcells, fmt.Sprintf("%s/%s (id: %s, cid: %s)", stemcell.Name, stemcell.Version, stemcell.ID, stemcell.CID))
+	}
+
+	for _, release := range state.Releases {
+		releases = append(releases, fmt.Sprintf("%s/%s (id: %s)", release.Name, release.Version, release.ID))
+	}
+
+	return [][2]string{
+		{"director_id", state.DirectorID},
+		{"installation_id", state.InstallationID},
+		{"current_vm_cid", state.CurrentVMCID},
+		{"current_stemcell_id", state.CurrentStemcellID},
+		{"current_disk_id", state.CurrentDiskID},
+		{"current_release_ids", strings.Join(state.CurrentReleaseIDs, "\n")},
+		{"current_manifest_sha", state.CurrentManifestSHA},
+		{"disks", strings.Join(disks, "\n")},
+		{"stemcells", strings.Join(stemcells, "\n")},
+		{"releases", strings.Join(releases, "\n")},
+	}
+}
@@ -0,0 +1,99 @@
+package cmd_test
+
+import (
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("EnvStateDiffCmd", func() {
+	var (
+		ui                     *fakeui.FakeUI
+		deploymentStateService biconfig.DeploymentStateService
+		command                cmd.EnvStateDiffCmd
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs := fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, "/fake-state.json", nil)
+		command = cmd.NewEnvStateDiffCmd(ui, deploymentStateService)
+
+		for _, vmCID := range []string{"fake-vm-cid-1", "fake-vm-cid-2"} {
+			Expect(deploymentStateService.AcquireLock()).To(Succeed())
+			err := deploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:   "fake-director-id",
+				CurrentVMCID: vmCID,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentStateService.ReleaseLock()).To(Succeed())
+		}
+	})
+
+	It("compares the previous snapshot with the current state by default", func() {
+		snapshots, err := deploymentStateService.History()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshots).To(HaveLen(2))
+
+		err = command.Run(opts.EnvStateDiffOpts{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table).To(Equal(boshtbl.Table{
+			Content: "deployment state differences",
+
+			Header: []boshtbl.Header{
+				boshtbl.NewHeader("Field"),
+				boshtbl.NewHeader(snapshots[0].ID),
+				boshtbl.NewHeader("current"),
+			},
+
+			Rows: [][]boshtbl.Value{
+				{
+					boshtbl.NewValueString("current_vm_cid"),
+					boshtbl.NewValueString("fake-vm-cid-1"),
+					boshtbl.NewValueString("fake-vm-cid-2"),
+				},
+			},
+		}))
+	})
+
+	It("compares the given snapshots", func() {
+		snapshots, err := deploymentStateService.History()
+		Expect(err).ToNot(HaveOccurred())
+
+		err = command.Run(opts.EnvStateDiffOpts{From: snapshots[1].ID, To: snapshots[0].ID})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table.Rows).To(Equal([][]boshtbl.Value{
+			{
+				boshtbl.NewValueString("current_vm_cid"),
+				boshtbl.NewValueString("fake-vm-cid-2"),
+				boshtbl.NewValueString("fake-vm-cid-1"),
+			},
+		}))
+	})
+
+	It("returns an error when there is no previous snapshot", func() {
+		snapshots, err := deploymentStateService.History()
+		Expect(err).ToNot(HaveOccurred())
+
+		err = command.Run(opts.EnvStateDiffOpts{To: snapshots[0].ID})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("use --from"))
+	})
+
+	It("returns an error when a snapshot does not exist", func() {
+		err := command.Run(opts.EnvStateDiffOpts{From: "unknown"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Deployment state snapshot 'unknown' does not exist"))
+	})
+})
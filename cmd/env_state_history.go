@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+type EnvStateHistoryCmd struct {
+	ui                     boshui.UI
+	deploymentStateService biconfig.DeploymentStateService
+}
+
+func NewEnvStateHistoryCmd(ui boshui.UI, deploymentStateService biconfig.DeploymentStateService) EnvStateHistoryCmd {
+	return EnvStateHistoryCmd{ui: ui, deploymentStateService: deploymentStateService}
+}
+
+func (c EnvStateHistoryCmd) Run() error {
+	snapshots, err := c.deploymentStateService.History()
+	if err != nil {
+		return err
+	}
+
+	table := boshtbl.Table{
+		Content: "deployment states",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("ID"),
+			boshtbl.NewHeader("Created At"),
+			boshtbl.NewHeader("Manifest SHA"),
+			boshtbl.NewHeader("VM CID"),
+			boshtbl.NewHeader("Disk CID"),
+		},
+		SortBy: []boshtbl.ColumnSort{{Column: 0, Asc: true}},
+	}
+
+	for _, snapshot := range snapshots {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(snapshot.ID),
+			boshtbl.NewValueTime(snapshot.CreatedAt),
+			boshtbl.NewValueString(snapshot.ManifestSHA),
+			boshtbl.NewValueString(snapshot.VMCID),
+			boshtbl.NewValueString(snapshot.DiskCID),
+		})
+	}
+
+	c.ui.PrintTable(table)
+
+	return nil
+}
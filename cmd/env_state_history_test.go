@@ -0,0 +1,74 @@
+package cmd_test
+
+import (
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("EnvStateHistoryCmd", func() {
+	var (
+		ui                     *fakeui.FakeUI
+		deploymentStateService biconfig.DeploymentStateService
+		command                cmd.EnvStateHistoryCmd
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs := fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, "/fake-state.json", nil)
+		command = cmd.NewEnvStateHistoryCmd(ui, deploymentStateService)
+	})
+
+	It("lists saved deployment states", func() {
+		Expect(deploymentStateService.AcquireLock()).To(Succeed())
+		err := deploymentStateService.Save(biconfig.DeploymentState{
+			DirectorID:         "fake-director-id",
+			CurrentVMCID:       "fake-vm-cid",
+			CurrentManifestSHA: "fake-manifest-sha",
+			CurrentDiskID:      "fake-disk-id",
+			Disks:              []biconfig.DiskRecord{{ID: "fake-disk-id", CID: "fake-disk-cid"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deploymentStateService.ReleaseLock()).To(Succeed())
+
+		snapshots, err := deploymentStateService.History()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshots).To(HaveLen(1))
+
+		err = command.Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table).To(Equal(boshtbl.Table{
+			Content: "deployment states",
+
+			Header: []boshtbl.Header{
+				boshtbl.NewHeader("ID"),
+				boshtbl.NewHeader("Created At"),
+				boshtbl.NewHeader("Manifest SHA"),
+				boshtbl.NewHeader("VM CID"),
+				boshtbl.NewHeader("Disk CID"),
+			},
+
+			SortBy: []boshtbl.ColumnSort{{Column: 0, Asc: true}},
+
+			Rows: [][]boshtbl.Value{
+				{
+					boshtbl.NewValueString(snapshots[0].ID),
+					boshtbl.NewValueTime(snapshots[0].CreatedAt),
+					boshtbl.NewValueString("fake-manifest-sha"),
+					boshtbl.NewValueString("fake-vm-cid"),
+					boshtbl.NewValueString("fake-disk-cid"),
+				},
+			},
+		}))
+	})
+})
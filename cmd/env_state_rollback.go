@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type EnvStateRollbackCmd struct {
+	ui                     boshui.UI
+	deploymentStateService biconfig.DeploymentStateService
+	logger                 boshlog.Logger
+}
+
+func NewEnvStateRollbackCmd(ui boshui.UI, deploymentStateService biconfig.DeploymentStateService, logger boshlog.Logger) EnvStateRollbackCmd {
+	return EnvStateRollbackCmd{ui: ui, deploymentStateService: deploymentStateService, logger: logger}
+}
+
+func (c EnvStateRollbackCmd) Run(opts EnvStateRollbackOpts) error {
+	// Lock before reading the snapshot so that a concurrent command
+	// cannot change the history between reading and restoring it
+	unlock, err := lockDeploymentState(c.deploymentStateService, opts.ForceUnlock, c.ui, c.logger, "EnvStateRollbackCmd")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	snapshot, err := c.deploymentStateService.LoadSnapshot(opts.To)
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("Restoring deployment state '%s' from snapshot '%s' (manifest SHA '%s', VM CID '%s')",
+		c.deploymentStateService.Path(), opts.To, snapshot.CurrentManifestSHA, snapshot.CurrentVMCID)
+	c.ui.PrintLinef("Only the state file is changed; VMs, disks and stemcells in the IaaS are left as they are.")
+
+	err = c.ui.AskForConfirmation()
+	if err != nil {
+		return err
+	}
+
+	return c.deploymentStateService.Save(snapshot)
+}
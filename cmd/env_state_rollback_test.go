@@ -0,0 +1,85 @@
+package cmd_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("EnvStateRollbackCmd", func() {
+	var (
+		ui                     *fakeui.FakeUI
+		fs                     *fakesys.FakeFileSystem
+		deploymentStateService biconfig.DeploymentStateService
+		command                cmd.EnvStateRollbackCmd
+		snapshotID             string
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs = fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeuuid.NewFakeGenerator(), logger, "/fake-state.json", nil)
+		command = cmd.NewEnvStateRollbackCmd(ui, deploymentStateService, logger)
+
+		for _, vmCID := range []string{"fake-vm-cid-1", "fake-vm-cid-2"} {
+			Expect(deploymentStateService.AcquireLock()).To(Succeed())
+			err := deploymentStateService.Save(biconfig.DeploymentState{
+				DirectorID:   "fake-director-id",
+				CurrentVMCID: vmCID,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentStateService.ReleaseLock()).To(Succeed())
+		}
+
+		snapshots, err := deploymentStateService.History()
+		Expect(err).ToNot(HaveOccurred())
+		snapshotID = snapshots[0].ID
+	})
+
+	It("restores the snapshot as the current state", func() {
+		err := command.Run(opts.EnvStateRollbackOpts{To: snapshotID})
+		Expect(err).ToNot(HaveOccurred())
+
+		state, err := deploymentStateService.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.CurrentVMCID).To(Equal("fake-vm-cid-1"))
+
+		Expect(fs.FileExists("/fake-state.json.lock")).To(BeFalse())
+	})
+
+	It("does not change the state when confirmation is declined", func() {
+		ui.AskedConfirmationErr = errors.New("stop")
+
+		err := command.Run(opts.EnvStateRollbackOpts{To: snapshotID})
+		Expect(err).To(HaveOccurred())
+
+		state, err := deploymentStateService.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.CurrentVMCID).To(Equal("fake-vm-cid-2"))
+	})
+
+	It("does not read the snapshot while another command holds the lock", func() {
+		err := fs.WriteFileString("/fake-state.json.lock", `{"id":"other","owner":"alice","pid":42,"hostname":"jumpbox"}`)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = command.Run(opts.EnvStateRollbackOpts{To: "unknown"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("is locked by alice@jumpbox (PID 42)"))
+	})
+
+	It("returns an error when the snapshot does not exist", func() {
+		err := command.Run(opts.EnvStateRollbackOpts{To: "unknown"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Deployment state snapshot 'unknown' does not exist"))
+	})
+})
@@ -370,10 +370,12 @@ var _ = Describe("Factory", func() {
 			boshOpts.RunErrand = opts.RunErrandOpts{}
 			boshOpts.Logs = opts.LogsOpts{}
 			boshOpts.Interpolate = opts.InterpolateOpts{}
+			boshOpts.DiffManifests = opts.DiffManifestsOpts{}
 			boshOpts.InitRelease = opts.InitReleaseOpts{}
 			boshOpts.ResetRelease = opts.ResetReleaseOpts{}
 			boshOpts.GenerateJob = opts.GenerateJobOpts{}
 			boshOpts.GeneratePackage = opts.GeneratePackageOpts{}
+			boshOpts.RenderJob = opts.RenderJobOpts{}
 			boshOpts.VendorPackage = opts.VendorPackageOpts{}
 			boshOpts.CreateRelease = opts.CreateReleaseOpts{}
 			boshOpts.FinalizeRelease = opts.FinalizeReleaseOpts{}
@@ -394,6 +396,10 @@ var _ = Describe("Factory", func() {
 			boshOpts.UpdateConfig = opts.UpdateConfigOpts{}
 			boshOpts.DeleteConfig = opts.DeleteConfigOpts{}
 			boshOpts.Curl = opts.CurlOpts{}
+			boshOpts.CreateEnv = opts.CreateEnvOpts{}
+			boshOpts.DeleteEnv = opts.DeleteEnvOpts{}
+			boshOpts.RepairEnvState = opts.RepairEnvStateOpts{}
+			boshOpts.ApplyPlan = opts.ApplyPlanOpts{}
 			return boshOpts
 		}
 
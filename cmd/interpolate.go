@@ -1,23 +1,45 @@
 package cmd
 
 import (
+	"sort"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	"github.com/cppforlife/go-patch/patch"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
 )
 
 type InterpolateCmd struct {
-	ui boshui.UI
+	ui             boshui.UI
+	stateEncryptor bicrypto.StateEncryptor
 }
 
-func NewInterpolateCmd(ui boshui.UI) InterpolateCmd {
-	return InterpolateCmd{ui: ui}
+func NewInterpolateCmd(ui boshui.UI, stateEncryptor bicrypto.StateEncryptor) InterpolateCmd {
+	return InterpolateCmd{ui: ui, stateEncryptor: stateEncryptor}
 }
 
 func (c InterpolateCmd) Run(opts InterpolateOpts) error {
-	tpl := boshtpl.NewTemplate(opts.Args.Manifest.Bytes)
+	manifestBytes := opts.Args.Manifest.Bytes
+
+	// Allows reading values out of encrypted vars stores
+	if bicrypto.IsEncryptedState(manifestBytes) {
+		if c.stateEncryptor == nil {
+			return bosherr.Error("Template is encrypted, expected an encryption key")
+		}
+
+		var err error
+
+		manifestBytes, err = c.stateEncryptor.Decrypt(manifestBytes)
+		if err != nil {
+			return bosherr.WrapError(err, "Decrypting template")
+		}
+	}
+
+	tpl := boshtpl.NewTemplate(manifestBytes)
 
 	vars := opts.VarFlags.AsVariables() //nolint:staticcheck
 	op := opts.OpsFlags.AsOp()          //nolint:staticcheck
@@ -26,6 +48,13 @@ func (c InterpolateCmd) Run(opts InterpolateOpts) error {
 		ExpectAllVarsUsed: opts.VarErrorsUnused,
 	}
 
+	if opts.Explain {
+		evalOpts.Trace = &boshtpl.EvaluateTrace{}
+
+		vars = opts.VarFlags.AsSourcedVariables()     //nolint:staticcheck
+		op = opts.OpsFlags.AsTracedOp(evalOpts.Trace) //nolint:staticcheck
+	}
+
 	if opts.Path.IsSet() {
 		evalOpts.PostVarSubstitutionOp = patch.FindOp{Path: opts.Path}
 
@@ -38,7 +67,70 @@ func (c InterpolateCmd) Run(opts InterpolateOpts) error {
 		return err
 	}
 
+	if opts.Explain {
+		c.printTrace(*evalOpts.Trace)
+		return nil
+	}
+
 	c.ui.PrintBlock(bytes)
 
 	return nil
 }
+
+func (c InterpolateCmd) printTrace(trace boshtpl.EvaluateTrace) {
+	opsTable := boshtbl.Table{
+		Content: "operations",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Source"),
+			boshtbl.NewHeader("Type"),
+			boshtbl.NewHeader("Path"),
+			boshtbl.NewHeader("Changed Paths"),
+		},
+	}
+
+	for _, op := range trace.Ops {
+		opsTable.Rows = append(opsTable.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(op.Source),
+			boshtbl.NewValueString(op.Type),
+			boshtbl.NewValueString(op.Path),
+			boshtbl.NewValueStrings(op.ChangedPaths),
+		})
+	}
+
+	varsTable := boshtbl.Table{
+		Content: "variables",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Name"),
+			boshtbl.NewHeader("Path"),
+			boshtbl.NewHeader("Source"),
+		},
+	}
+
+	// Variables are traced in map iteration order
+	variables := append([]boshtpl.VariableTrace{}, trace.Variables...)
+
+	sort.SliceStable(variables, func(i, j int) bool {
+		if variables[i].Path != variables[j].Path {
+			return variables[i].Path < variables[j].Path
+		}
+		return variables[i].Name < variables[j].Name
+	})
+
+	for _, variable := range variables {
+		source := boshtbl.NewValueFmt(boshtbl.NewValueString(variable.Source), false)
+		if !variable.Found {
+			source = boshtbl.NewValueFmt(boshtbl.NewValueString("not found"), true)
+		}
+
+		varsTable.Rows = append(varsTable.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(variable.Name),
+			boshtbl.NewValueString(variable.Path),
+			source,
+		})
+	}
+
+	c.ui.PrintTable(opsTable)
+	c.ui.PrintTable(varsTable)
+}
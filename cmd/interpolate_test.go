@@ -7,8 +7,10 @@ import (
 
 	"github.com/cloudfoundry/bosh-cli/v7/cmd"
 	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
 )
 
 var _ = Describe("InterpolateCmd", func() {
@@ -19,7 +21,7 @@ var _ = Describe("InterpolateCmd", func() {
 
 	BeforeEach(func() {
 		ui = &fakeui.FakeUI{}
-		command = cmd.NewInterpolateCmd(ui)
+		command = cmd.NewInterpolateCmd(ui, nil)
 	})
 
 	Describe("Run", func() {
@@ -62,6 +64,35 @@ var _ = Describe("InterpolateCmd", func() {
 			Expect(ui.Blocks).To(Equal([]string{bytes}))
 		})
 
+		Context("when template is encrypted", func() {
+			var encryptor bicrypto.StateEncryptor
+
+			BeforeEach(func() {
+				encryptor = bicrypto.NewAESGCMStateEncryptor([]byte("fake-key"))
+
+				encrypted, err := encryptor.Encrypt([]byte("password: fake-password\n"))
+				Expect(err).ToNot(HaveOccurred())
+
+				interpolateOpts.Args.Manifest = opts.FileBytesArg{Bytes: encrypted}
+				interpolateOpts.Path = patch.MustNewPointerFromString("/password")
+			})
+
+			It("decrypts template with the state key", func() {
+				command = cmd.NewInterpolateCmd(ui, encryptor)
+
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ui.Blocks).To(Equal([]string{"fake-password\n"}))
+			})
+
+			It("returns an error without the state key", func() {
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Template is encrypted, expected an encryption key"))
+				Expect(ui.Blocks).To(BeEmpty())
+			})
+		})
+
 		It("returns portion of the template after it's interpolated if path is given", func() {
 			interpolateOpts.Args.Manifest = opts.FileBytesArg{
 				Bytes: []byte("name1: ((name1))\nname2: ((name2))"),
@@ -149,5 +180,65 @@ var _ = Describe("InterpolateCmd", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Expected to use variables: name3"))
 		})
+
+		It("explains where variables and operations came from instead of showing the manifest", func() {
+			interpolateOpts.Args.Manifest = opts.FileBytesArg{
+				Bytes: []byte("name1: ((name1))\nname2: ((name2))\nname3: ((name3))"),
+			}
+
+			interpolateOpts.VarKVs = []boshtpl.VarKV{
+				{Name: "name1", Value: "val1-from-kv"},
+			}
+
+			interpolateOpts.VarsFiles = []boshtpl.VarsFileArg{
+				{Vars: boshtpl.StaticVariables{"name2": "val2-from-file"}, Path: "vars.yml"},
+			}
+
+			interpolateOpts.OpsFiles = []opts.OpsFileArg{
+				{
+					Path: "ops.yml",
+					Ops: patch.Ops([]patch.Op{
+						patch.ReplaceOp{Path: patch.MustNewPointerFromString("/xyz?"), Value: "val"},
+					}),
+				},
+			}
+
+			interpolateOpts.Explain = true
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ui.Blocks).To(BeEmpty())
+
+			Expect(ui.Tables).To(HaveLen(2))
+
+			Expect(ui.Tables[0].Content).To(Equal("operations"))
+			Expect(ui.Tables[0].Rows).To(Equal([][]boshtbl.Value{
+				{
+					boshtbl.NewValueString("ops.yml [0]"),
+					boshtbl.NewValueString("replace"),
+					boshtbl.NewValueString("/xyz?"),
+					boshtbl.NewValueStrings([]string{"/xyz?"}),
+				},
+			}))
+
+			Expect(ui.Tables[1].Content).To(Equal("variables"))
+			Expect(ui.Tables[1].Rows).To(Equal([][]boshtbl.Value{
+				{
+					boshtbl.NewValueString("name1"),
+					boshtbl.NewValueString("/name1"),
+					boshtbl.NewValueFmt(boshtbl.NewValueString("--var=name1"), false),
+				},
+				{
+					boshtbl.NewValueString("name2"),
+					boshtbl.NewValueString("/name2"),
+					boshtbl.NewValueFmt(boshtbl.NewValueString("--vars-file=vars.yml"), false),
+				},
+				{
+					boshtbl.NewValueString("name3"),
+					boshtbl.NewValueString("/name3"),
+					boshtbl.NewValueFmt(boshtbl.NewValueString("not found"), true),
+				},
+			}))
+		})
 	})
 })
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
+
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+//counterfeiter:generate . ReleaseJobSpecsLoader
+
+type ReleaseJobSpecsLoader interface {
+	Read(path string) (ReleaseJobSpecs, error)
+}
+
+type ManifestProblem struct {
+	// Path is a manifest path, e.g. /instance_groups/name=web/jobs/name=nginx/properties/port
+	Path    string
+	Message string
+
+	// Warning is set for problems that do not fail validation
+	Warning bool
+}
+
+// ManifestValidator checks job properties of a deployment manifest against
+// job specs of locally available releases.
+type ManifestValidator struct {
+	specsLoader ReleaseJobSpecsLoader
+	ui          boshui.UI
+}
+
+func NewManifestValidator(specsLoader ReleaseJobSpecsLoader, ui boshui.UI) ManifestValidator {
+	return ManifestValidator{specsLoader: specsLoader, ui: ui}
+}
+
+type validatedManifest struct {
+	Releases []struct {
+		Name string `yaml:"name"`
+		URL  string `yaml:"url"`
+	} `yaml:"releases"`
+
+	Properties map[interface{}]interface{} `yaml:"properties"`
+
+	InstanceGroups []struct {
+		Name       string                      `yaml:"name"`
+		Properties map[interface{}]interface{} `yaml:"properties"`
+
+		Jobs []struct {
+			Name       string                       `yaml:"name"`
+			Release    string                       `yaml:"release"`
+			Properties *map[interface{}]interface{} `yaml:"properties"`
+		} `yaml:"jobs"`
+	} `yaml:"instance_groups"`
+}
+
+// Validate returns problems found in the manifest. Releases are read from releasePaths
+// and from manifest releases that point to local files or directories.
+func (v ManifestValidator) Validate(manifestBytes []byte, releasePaths []string) ([]ManifestProblem, error) {
+	var manifest validatedManifest
+
+	err := yaml.Unmarshal(manifestBytes, &manifest)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing manifest")
+	}
+
+	for _, rel := range manifest.Releases {
+		if path, isLocal := v.localReleasePath(rel.URL); isLocal {
+			releasePaths = append(releasePaths, path)
+		}
+	}
+
+	releases := map[string]ReleaseJobSpecs{}
+
+	for _, path := range releasePaths {
+		specs, err := v.specsLoader.Read(path)
+		if err != nil {
+			return nil, err
+		}
+
+		releases[specs.Name] = specs
+	}
+
+	var problems []ManifestProblem
+
+	for _, group := range manifest.InstanceGroups {
+		for _, job := range group.Jobs {
+			jobPath := []patch.Token{
+				patch.RootToken{},
+				patch.KeyToken{Key: "instance_groups"},
+				patch.MatchingIndexToken{Key: "name", Value: group.Name},
+				patch.KeyToken{Key: "jobs"},
+				patch.MatchingIndexToken{Key: "name", Value: job.Name},
+			}
+
+			release, found := releases[job.Release]
+			if !found {
+				v.ui.ErrorLinef("Skipping job '%s' in instance group '%s' since release '%s' is not available locally", job.Name, group.Name, job.Release)
+				continue
+			}
+
+			spec, found := release.Jobs[job.Name]
+			if !found {
+				problems = append(problems, ManifestProblem{
+					Path:    patch.NewPointer(jobPath).String(),
+					Message: "Job not found in release '" + job.Release + "'",
+				})
+				continue
+			}
+
+			// Jobs without their own properties use instance group or deployment properties
+			// which may be shared with other jobs, hence unknown properties are not reported
+			propsPath, props, checkUnknown := jobPath, map[interface{}]interface{}{}, false
+
+			switch {
+			case job.Properties != nil:
+				props, checkUnknown = *job.Properties, true
+			case group.Properties != nil:
+				propsPath, props = jobPath[:3], group.Properties
+			case manifest.Properties != nil:
+				propsPath, props = jobPath[:1], manifest.Properties
+			}
+
+			for _, problem := range spec.CheckProperties(props, checkUnknown) {
+				problems = append(problems, ManifestProblem{
+					Path:    v.propertyPath(propsPath, problem),
+					Message: problem.Message,
+					Warning: problem.Warning,
+				})
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+func (v ManifestValidator) localReleasePath(url string) (string, bool) {
+	return strings.CutPrefix(url, "file://")
+}
+
+func (v ManifestValidator) propertyPath(propsPath []patch.Token, problem boshjobman.PropertyProblem) string {
+	tokens := append([]patch.Token{}, propsPath...)
+	tokens = append(tokens, patch.KeyToken{Key: "properties"})
+
+	for _, key := range strings.Split(problem.Name, ".") {
+		tokens = append(tokens, patch.KeyToken{Key: key})
+	}
+
+	return patch.NewPointer(tokens).String()
+}
+
+// PrintProblems shows problems and returns an error if there are any besides warnings.
+func (v ManifestValidator) PrintProblems(problems []ManifestProblem) error {
+	if len(problems) == 0 {
+		v.ui.PrintLinef("Manifest matches release job specs")
+		return nil
+	}
+
+	table := boshtbl.Table{
+		Content: "problems",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Path"),
+			boshtbl.NewHeader("Problem"),
+		},
+	}
+
+	var errCount int
+
+	for _, problem := range problems {
+		message := problem.Message
+
+		if problem.Warning {
+			message = "Warning: " + message
+		} else {
+			errCount++
+		}
+
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(problem.Path),
+			boshtbl.NewValueString(message),
+		})
+	}
+
+	v.ui.PrintTable(table)
+
+	if errCount == 0 {
+		return nil
+	}
+
+	return bosherr.Errorf("Expected manifest to match release job specs but found %d problem(s)", errCount)
+}
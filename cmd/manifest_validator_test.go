@@ -0,0 +1,226 @@
+package cmd_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/cmdfakes"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("ManifestValidator", func() {
+	var (
+		specsLoader *cmdfakes.FakeReleaseJobSpecsLoader
+		ui          *fakeui.FakeUI
+		validator   cmd.ManifestValidator
+	)
+
+	BeforeEach(func() {
+		specsLoader = &cmdfakes.FakeReleaseJobSpecsLoader{}
+		ui = &fakeui.FakeUI{}
+		validator = cmd.NewManifestValidator(specsLoader, ui)
+
+		specsLoader.ReadStub = func(path string) (cmd.ReleaseJobSpecs, error) {
+			switch path {
+			case "/rel1.tgz":
+				return cmd.ReleaseJobSpecs{
+					Name: "rel1",
+					Jobs: map[string]boshjobman.Manifest{
+						"job1": {Name: "job1", Properties: map[string]boshjobman.PropertyDefinition{
+							"port":      {Default: 80},
+							"nats.user": {},
+						}},
+					},
+				}, nil
+			case "/rel2":
+				return cmd.ReleaseJobSpecs{
+					Name: "rel2",
+					Jobs: map[string]boshjobman.Manifest{
+						"job2": {Name: "job2", Properties: map[string]boshjobman.PropertyDefinition{
+							"password": {},
+						}},
+					},
+				}, nil
+			default:
+				return cmd.ReleaseJobSpecs{}, errors.New("fake-err")
+			}
+		}
+	})
+
+	Describe("Validate", func() {
+		It("returns no problems if job properties match job specs", func() {
+			problems, err := validator.Validate([]byte(`
+instance_groups:
+- name: ig1
+  jobs:
+  - name: job1
+    release: rel1
+    properties: {port: 8080, nats: {user: user}}
+`), []string{"/rel1.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(BeEmpty())
+		})
+
+		It("reports unknown, missing and wrongly typed job properties with manifest paths", func() {
+			problems, err := validator.Validate([]byte(`
+instance_groups:
+- name: ig1
+  jobs:
+  - name: job1
+    release: rel1
+    properties: {port: [80], nats: {usr: user}}
+`), []string{"/rel1.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(Equal([]cmd.ManifestProblem{
+				{Path: "/instance_groups/name=ig1/jobs/name=job1/properties/nats/user", Message: "Missing property without default", Warning: true},
+				{Path: "/instance_groups/name=ig1/jobs/name=job1/properties/nats/usr", Message: "Unknown property"},
+				{Path: "/instance_groups/name=ig1/jobs/name=job1/properties/port", Message: "Expected number like the default but was array"},
+			}))
+		})
+
+		It("reads releases with local file URLs from the manifest", func() {
+			problems, err := validator.Validate([]byte(`
+releases:
+- name: rel2
+  url: file:///rel2
+- name: rel3
+  url: https://example.com/rel3.tgz
+instance_groups:
+- name: ig1
+  jobs:
+  - name: job2
+    release: rel2
+    properties: {}
+`), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(Equal([]cmd.ManifestProblem{
+				{Path: "/instance_groups/name=ig1/jobs/name=job2/properties/password", Message: "Missing property without default", Warning: true},
+			}))
+
+			Expect(specsLoader.ReadCallCount()).To(Equal(1))
+			Expect(specsLoader.ReadArgsForCall(0)).To(Equal("/rel2"))
+		})
+
+		It("checks instance group and deployment properties for jobs without their own properties", func() {
+			problems, err := validator.Validate([]byte(`
+properties: {other: val}
+instance_groups:
+- name: ig1
+  properties: {port: 80, other: val}
+  jobs:
+  - name: job1
+    release: rel1
+- name: ig2
+  jobs:
+  - name: job2
+    release: rel2
+`), []string{"/rel1.tgz", "/rel2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(Equal([]cmd.ManifestProblem{
+				{Path: "/instance_groups/name=ig1/properties/nats/user", Message: "Missing property without default", Warning: true},
+				{Path: "/properties/password", Message: "Missing property without default", Warning: true},
+			}))
+		})
+
+		It("reports jobs that are not found in releases", func() {
+			problems, err := validator.Validate([]byte(`
+instance_groups:
+- name: ig1
+  jobs:
+  - name: missing-job
+    release: rel1
+`), []string{"/rel1.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(Equal([]cmd.ManifestProblem{
+				{Path: "/instance_groups/name=ig1/jobs/name=missing-job", Message: "Job not found in release 'rel1'"},
+			}))
+		})
+
+		It("skips jobs from releases that are not available locally", func() {
+			problems, err := validator.Validate([]byte(`
+instance_groups:
+- name: ig1
+  jobs:
+  - name: job1
+    release: other-rel
+`), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(BeEmpty())
+
+			Expect(ui.Errors).To(Equal([]string{
+				"Skipping job 'job1' in instance group 'ig1' since release 'other-rel' is not available locally",
+			}))
+		})
+
+		It("returns error if release cannot be read", func() {
+			_, err := validator.Validate([]byte(`instance_groups: []`), []string{"/missing"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-err"))
+		})
+
+		It("returns error if manifest cannot be parsed", func() {
+			_, err := validator.Validate([]byte(`-`), nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing manifest"))
+		})
+	})
+
+	Describe("PrintProblems", func() {
+		It("prints problems and returns error", func() {
+			err := validator.PrintProblems([]cmd.ManifestProblem{
+				{Path: "/instance_groups/name=ig1/jobs/name=job1/properties/prot", Message: "Unknown property"},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected manifest to match release job specs but found 1 problem(s)"))
+
+			Expect(ui.Table).To(Equal(boshtbl.Table{
+				Content: "problems",
+
+				Header: []boshtbl.Header{
+					boshtbl.NewHeader("Path"),
+					boshtbl.NewHeader("Problem"),
+				},
+
+				Rows: [][]boshtbl.Value{
+					{
+						boshtbl.NewValueString("/instance_groups/name=ig1/jobs/name=job1/properties/prot"),
+						boshtbl.NewValueString("Unknown property"),
+					},
+				},
+			}))
+		})
+
+		It("prints warnings without returning error", func() {
+			err := validator.PrintProblems([]cmd.ManifestProblem{
+				{Path: "/properties/password", Message: "Missing property without default", Warning: true},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ui.Table.Rows).To(Equal([][]boshtbl.Value{
+				{
+					boshtbl.NewValueString("/properties/password"),
+					boshtbl.NewValueString("Warning: Missing property without default"),
+				},
+			}))
+		})
+
+		It("only counts problems that are not warnings", func() {
+			err := validator.PrintProblems([]cmd.ManifestProblem{
+				{Path: "/properties/password", Message: "Missing property without default", Warning: true},
+				{Path: "/properties/prot", Message: "Unknown property"},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected manifest to match release job specs but found 1 problem(s)"))
+		})
+
+		It("does not return error if there are no problems", func() {
+			Expect(validator.PrintProblems(nil)).To(Succeed())
+			Expect(ui.Said).To(Equal([]string{"Manifest matches release job specs"}))
+		})
+	})
+})
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/cloudfoundry/bosh-cli/v7/cmd (interfaces: DeploymentDeleter,DeploymentStateManager)
+// Source: github.com/cloudfoundry/bosh-cli/v7/cmd (interfaces: DeploymentDeleter,DeploymentStateManager,DeploymentStateRepairer)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -7,6 +7,7 @@ package mocks
 import (
 	reflect "reflect"
 
+	cmd "github.com/cloudfoundry/bosh-cli/v7/cmd"
 	ui "github.com/cloudfoundry/bosh-cli/v7/ui"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -98,3 +99,40 @@ func (mr *MockDeploymentStateManagerMockRecorder) StopDeployment(arg0, arg1 inte
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopDeployment", reflect.TypeOf((*MockDeploymentStateManager)(nil).StopDeployment), arg0, arg1)
 }
+
+// MockDeploymentStateRepairer is a mock of DeploymentStateRepairer interface.
+type MockDeploymentStateRepairer struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeploymentStateRepairerMockRecorder
+}
+
+// MockDeploymentStateRepairerMockRecorder is the mock recorder for MockDeploymentStateRepairer.
+type MockDeploymentStateRepairerMockRecorder struct {
+	mock *MockDeploymentStateRepairer
+}
+
+// NewMockDeploymentStateRepairer creates a new mock instance.
+func NewMockDeploymentStateRepairer(ctrl *gomock.Controller) *MockDeploymentStateRepairer {
+	mock := &MockDeploymentStateRepairer{ctrl: ctrl}
+	mock.recorder = &MockDeploymentStateRepairerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeploymentStateRepairer) EXPECT() *MockDeploymentStateRepairerMockRecorder {
+	return m.recorder
+}
+
+// RepairDeploymentState mocks base method.
+func (m *MockDeploymentStateRepairer) RepairDeploymentState(arg0 cmd.RepairCIDs, arg1 ui.Stage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RepairDeploymentState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RepairDeploymentState indicates an expected call of RepairDeploymentState.
+func (mr *MockDeploymentStateRepairerMockRecorder) RepairDeploymentState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairDeploymentState", reflect.TypeOf((*MockDeploymentStateRepairer)(nil).RepairDeploymentState), arg0, arg1)
+}
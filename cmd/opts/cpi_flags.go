@@ -0,0 +1,15 @@
+package opts
+
+import (
+	"time"
+)
+
+// Shared
+type CPIFlags struct {
+	CPI             string          `long:"cpi"               value-name:"NAME"              description:"Use a built-in CPI instead of the manifest's CPI release, same as cloud_provider.cpi ('fake' keeps stemcells, VMs and disks in ~/.bosh/fake-cpi)"`
+	RecordCPI       string          `long:"record-cpi"        value-name:"PATH"              description:"Record CPI and agent calls (with credentials redacted) to a cassette file"`
+	ReplayCPI       string          `long:"replay-cpi"        value-name:"PATH"              description:"Answer CPI and agent calls from a recorded cassette file instead of installing and running the CPI"`
+	CPIMaxAttempts  int             `long:"cpi-max-attempts"  value-name:"NUM"               description:"Attempts for CPI calls failing with errors marked ok_to_retry"                         default:"1"`
+	CPIRetryBackoff time.Duration   `long:"cpi-retry-backoff" value-name:"DURATION"          description:"Wait before retrying a CPI call, doubled after every attempt (up to 1m)"               default:"5s"`
+	CPITimeouts     []CPITimeoutArg `long:"cpi-timeout"       value-name:"[METHOD=]DURATION" description:"Terminate CPI calls running longer, e.g. 30m or attach_disk=5m (can be specified multiple times)"`
+}
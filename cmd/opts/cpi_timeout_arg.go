@@ -0,0 +1,36 @@
+package opts
+
+import (
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// CPITimeoutArg is either a DURATION for all CPI methods or METHOD=DURATION for one.
+type CPITimeoutArg struct {
+	Method  string
+	Timeout time.Duration
+}
+
+func (a *CPITimeoutArg) UnmarshalFlag(data string) error {
+	method, duration, found := strings.Cut(data, "=")
+	if !found {
+		method, duration = "", data
+	} else if method == "" {
+		return bosherr.Errorf("Expected CPI timeout '%s' to specify a method before '='", data)
+	}
+
+	timeout, err := time.ParseDuration(duration)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing CPI timeout '%s'", data)
+	}
+
+	if timeout <= 0 {
+		return bosherr.Errorf("Expected CPI timeout '%s' to be positive", data)
+	}
+
+	*a = CPITimeoutArg{Method: method, Timeout: timeout}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package opts_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+)
+
+var _ = Describe("CPITimeoutArg", func() {
+	Describe("UnmarshalFlag", func() {
+		var (
+			arg CPITimeoutArg
+		)
+
+		BeforeEach(func() {
+			arg = CPITimeoutArg{}
+		})
+
+		It("parses a timeout for all methods", func() {
+			err := arg.UnmarshalFlag("30m")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(arg).To(Equal(CPITimeoutArg{Timeout: 30 * time.Minute}))
+		})
+
+		It("parses a timeout for one method", func() {
+			err := arg.UnmarshalFlag("attach_disk=5m")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(arg).To(Equal(CPITimeoutArg{Method: "attach_disk", Timeout: 5 * time.Minute}))
+		})
+
+		It("returns an error if duration cannot be parsed", func() {
+			err := arg.UnmarshalFlag("attach_disk=soon")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing CPI timeout 'attach_disk=soon'"))
+		})
+
+		It("returns an error if method is empty", func() {
+			err := arg.UnmarshalFlag("=5m")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected CPI timeout '=5m' to specify a method before '='"))
+		})
+
+		It("returns an error if duration is not positive", func() {
+			err := arg.UnmarshalFlag("0s")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected CPI timeout '0s' to be positive"))
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package opts
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	DiffFormatUnified    DiffFormatArg = "unified"
+	DiffFormatSideBySide DiffFormatArg = "side-by-side"
+	DiffFormatOps        DiffFormatArg = "ops"
+	DiffFormatJSONPatch  DiffFormatArg = "json-patch"
+)
+
+// DiffFormatArg selects how client-side semantic diffs are rendered.
+type DiffFormatArg string
+
+func (a DiffFormatArg) IsSet() bool { return len(a) > 0 }
+
+// IsPatch returns true for formats meant to be applied to the first document
+// hence they must not contain redacted values.
+func (a DiffFormatArg) IsPatch() bool { return a == DiffFormatOps || a == DiffFormatJSONPatch }
+
+func (a *DiffFormatArg) UnmarshalFlag(data string) error {
+	switch format := DiffFormatArg(data); format {
+	case DiffFormatUnified, DiffFormatSideBySide, DiffFormatOps, DiffFormatJSONPatch:
+		*a = format
+		return nil
+	default:
+		return bosherr.Errorf("Expected diff format '%s' to be 'unified', 'side-by-side', 'ops' or 'json-patch'", data)
+	}
+}
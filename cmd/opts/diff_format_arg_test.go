@@ -0,0 +1,44 @@
+package opts_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+)
+
+var _ = Describe("DiffFormatArg", func() {
+	Describe("UnmarshalFlag", func() {
+		var (
+			arg DiffFormatArg
+		)
+
+		BeforeEach(func() {
+			arg = DiffFormatArg("")
+		})
+
+		It("parses known formats", func() {
+			for _, format := range []string{"unified", "side-by-side", "ops", "json-patch"} {
+				err := arg.UnmarshalFlag(format)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(arg).To(Equal(DiffFormatArg(format)))
+			}
+		})
+
+		It("returns an error for unknown formats", func() {
+			err := arg.UnmarshalFlag("context")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected diff format 'context' to be 'unified', 'side-by-side', 'ops' or 'json-patch'"))
+			Expect(arg.IsSet()).To(BeFalse())
+		})
+	})
+
+	Describe("IsPatch", func() {
+		It("returns true only for formats that can be applied", func() {
+			Expect(DiffFormatOps.IsPatch()).To(BeTrue())
+			Expect(DiffFormatJSONPatch.IsPatch()).To(BeTrue())
+			Expect(DiffFormatUnified.IsPatch()).To(BeFalse())
+			Expect(DiffFormatSideBySide.IsPatch()).To(BeFalse())
+		})
+	})
+})
@@ -1,7 +1,11 @@
 package opts
 
 import (
+	"fmt"
+
 	"github.com/cppforlife/go-patch/patch"
+
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 )
 
 // Shared
@@ -18,3 +22,17 @@ func (f OpsFlags) AsOp() patch.Op {
 
 	return ops
 }
+
+// AsTracedOp records each applied operation with the ops file it came from.
+func (f OpsFlags) AsTracedOp(trace *boshtpl.EvaluateTrace) patch.Op {
+	var ops patch.Ops
+
+	for _, opsFile := range f.OpsFiles {
+		for i, op := range opsFile.Ops {
+			source := fmt.Sprintf("%s [%d]", opsFile.Path, i)
+			ops = append(ops, boshtpl.NewTracedOp(op, source, trace))
+		}
+	}
+
+	return ops
+}
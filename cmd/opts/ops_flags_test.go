@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 )
 
 var _ = Describe("OpsFlags", func() {
@@ -34,4 +35,37 @@ var _ = Describe("OpsFlags", func() {
 			}))
 		})
 	})
+
+	Describe("AsTracedOp", func() {
+		It("records applied ops with ops file path and index", func() {
+			flags := OpsFlags{
+				OpsFiles: []OpsFileArg{
+					{
+						Path: "ops1.yml",
+						Ops: patch.Ops([]patch.Op{
+							patch.RemoveOp{Path: patch.MustNewPointerFromString("/a")},
+							patch.RemoveOp{Path: patch.MustNewPointerFromString("/b")},
+						}),
+					},
+					{
+						Path: "ops2.yml",
+						Ops: patch.Ops([]patch.Op{
+							patch.ReplaceOp{Path: patch.MustNewPointerFromString("/x?"), Value: "y"},
+						}),
+					},
+				},
+			}
+
+			trace := &boshtpl.EvaluateTrace{}
+
+			_, err := flags.AsTracedOp(trace).Apply(map[interface{}]interface{}{"a": 1, "b": 2})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(trace.Ops).To(Equal([]boshtpl.OpTrace{
+				{Source: "ops1.yml [0]", Type: "remove", Path: "/a", ChangedPaths: []string{"/a"}},
+				{Source: "ops1.yml [1]", Type: "remove", Path: "/b", ChangedPaths: []string{"/b"}},
+				{Source: "ops2.yml [0]", Type: "replace", Path: "/x?", ChangedPaths: []string{"/x?"}},
+			}))
+		})
+	})
 })
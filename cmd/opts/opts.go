@@ -22,6 +22,8 @@ type BoshOpts struct {
 	Sha2           bool      `long:"sha2"                  description:"Use SHA256 checksums" env:"BOSH_SHA2"`
 	Parallel       int       `long:"parallel" description:"The max number of parallel operations" default:"5"`
 
+	RubyERBRendererOpt bool `long:"ruby-erb-renderer" description:"Render job templates with ruby instead of the built-in renderer" env:"BOSH_RUBY_ERB_RENDERER"`
+
 	// Specify client credentials
 	ClientOpt       string `long:"client"        description:"Override username or UAA client"        env:"BOSH_CLIENT"`
 	ClientSecretOpt string `long:"client-secret" description:"Override password or UAA client secret" env:"BOSH_CLIENT_SECRET"`
@@ -41,14 +43,18 @@ type BoshOpts struct {
 	// -----> Director management
 
 	// Environments
-	Environment  EnvironmentOpts  `command:"environment"  alias:"env"  description:"Show environment"`
-	Environments EnvironmentsOpts `command:"environments" alias:"envs" description:"List environments"`
-	CreateEnv    CreateEnvOpts    `command:"create-env"                description:"Create or update BOSH environment"`
-	DeleteEnv    DeleteEnvOpts    `command:"delete-env"                description:"Delete BOSH environment"`
-	StopEnv      StopEnvOpts      `command:"stop-env"                  description:"Stop BOSH environment"`
-	StartEnv     StartEnvOpts     `command:"start-env"                 description:"Start BOSH environment"`
-	AliasEnv     AliasEnvOpts     `command:"alias-env"                 description:"Alias environment to save URL and CA certificate"`
-	UnaliasEnv   UnaliasEnvOpts   `command:"unalias-env"               description:"Remove an aliased environment"`
+	Environment     EnvironmentOpts     `command:"environment"  alias:"env"  description:"Show environment"`
+	Environments    EnvironmentsOpts    `command:"environments" alias:"envs" description:"List environments"`
+	CreateEnv       CreateEnvOpts       `command:"create-env"                description:"Create or update BOSH environment"`
+	DeleteEnv       DeleteEnvOpts       `command:"delete-env"                description:"Delete BOSH environment"`
+	StopEnv         StopEnvOpts         `command:"stop-env"                  description:"Stop BOSH environment"`
+	StartEnv        StartEnvOpts        `command:"start-env"                 description:"Start BOSH environment"`
+	EnvState        EnvStateOpts        `command:"env-state"                 description:"Inspect and restore saved BOSH environment states"`
+	RepairEnvState  RepairEnvStateOpts  `command:"repair-env-state"          description:"Rebuild BOSH environment state from known IaaS resources"`
+	EncryptEnvState EncryptEnvStateOpts `command:"encrypt-env-state"         description:"Encrypt BOSH environment state and vars-store files in place"`
+	EnvCPILog       EnvCPILogOpts       `command:"env-cpi-log"               description:"Show CPI calls made for BOSH environment"`
+	AliasEnv        AliasEnvOpts        `command:"alias-env"                 description:"Alias environment to save URL and CA certificate"`
+	UnaliasEnv      UnaliasEnvOpts      `command:"unalias-env"               description:"Remove an aliased environment"`
 
 	// Authentication
 	LogIn  LogInOpts  `command:"log-in"  alias:"l" alias:"login"  description:"Log in"` //nolint:staticcheck
@@ -92,8 +98,18 @@ type BoshOpts struct {
 	Deploy   DeployOpts   `command:"deploy"   alias:"d"   description:"Update deployment"`
 	Manifest ManifestOpts `command:"manifest" alias:"man" description:"Show deployment manifest"`
 
+	ApplyPlan ApplyPlanOpts `command:"apply-plan" description:"Update multiple deployments in dependency order from a plan file"`
+
 	Interpolate InterpolateOpts `command:"interpolate" alias:"int" description:"Interpolates variables into a manifest"`
 
+	ValidateManifest ValidateManifestOpts `command:"validate-manifest" description:"Validate manifest job properties against release job specs"`
+
+	DiffManifests DiffManifestsOpts `command:"diff-manifests" description:"Show structural differences between two manifests or configs"`
+
+	Bundle BundleOpts `command:"bundle" description:"Manage offline bundles of a manifest with its releases and stemcells"`
+
+	VarsStore VarsStoreOpts `command:"vars-store" description:"Manage variables file store"`
+
 	// Events
 	Events EventsOpts `command:"events" description:"List events"`
 	Event  EventOpts  `command:"event" description:"Show event details"`
@@ -164,6 +180,7 @@ type BoshOpts struct {
 	ResetRelease    ResetReleaseOpts    `command:"reset-release"               description:"Reset release"`
 	GenerateJob     GenerateJobOpts     `command:"generate-job"                description:"Generate job"`
 	GeneratePackage GeneratePackageOpts `command:"generate-package"            description:"Generate package"`
+	RenderJob       RenderJobOpts       `command:"render-job"                  description:"Render job templates locally"`
 	CreateRelease   CreateReleaseOpts   `command:"create-release"   alias:"cr" description:"Create release"`
 	VendorPackage   VendorPackageOpts   `command:"vendor-package"              description:"Vendor package"`
 
@@ -193,11 +210,15 @@ type CreateEnvOpts struct {
 	Args CreateEnvArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
+	CPIFlags
 	SkipDrain               bool   `long:"skip-drain" description:"Skip running drain and pre-stop scripts"`
-	StatePath               string `long:"state" value-name:"PATH" description:"State file path"`
+	StatePath               string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	ForceUnlock             bool   `long:"force-unlock" description:"Release a lock held on the state file by another run"`
 	Recreate                bool   `long:"recreate" description:"Recreate VM in deployment"`
 	RecreatePersistentDisks bool   `long:"recreate-persistent-disks" description:"Recreate persistent disks in the deployment"`
 	PackageDir              string `long:"package-dir" value-name:"DIR" description:"Package cache location override"`
+	DryRun                  bool   `long:"dry-run" description:"Show planned changes without calling the CPI or changing the state file"`
 	cmd
 }
 
@@ -209,9 +230,12 @@ type DeleteEnvOpts struct {
 	Args DeleteEnvArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
-	SkipDrain  bool   `long:"skip-drain" description:"Skip running drain and pre-stop scripts"`
-	StatePath  string `long:"state" value-name:"PATH" description:"State file path"`
-	PackageDir string `long:"package-dir" value-name:"DIR" description:"Package cache location override"`
+	StateEncryptionFlags
+	CPIFlags
+	SkipDrain   bool   `long:"skip-drain" description:"Skip running drain and pre-stop scripts"`
+	StatePath   string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	ForceUnlock bool   `long:"force-unlock" description:"Release a lock held on the state file by another run"`
+	PackageDir  string `long:"package-dir" value-name:"DIR" description:"Package cache location override"`
 	cmd
 }
 
@@ -219,8 +243,10 @@ type StopEnvOpts struct {
 	Args StartStopEnvArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
-	SkipDrain bool   `long:"skip-drain" description:"Skip running drain and pre-stop scripts"`
-	StatePath string `long:"state" value-name:"PATH" description:"State file path"`
+	StateEncryptionFlags
+	SkipDrain   bool   `long:"skip-drain" description:"Skip running drain and pre-stop scripts"`
+	StatePath   string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	ForceUnlock bool   `long:"force-unlock" description:"Release a lock held on the state file by another run"`
 	cmd
 }
 
@@ -228,7 +254,9 @@ type StartEnvOpts struct {
 	Args StartStopEnvArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
-	StatePath string `long:"state" value-name:"PATH" description:"State file path"`
+	StateEncryptionFlags
+	StatePath   string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	ForceUnlock bool   `long:"force-unlock" description:"Release a lock held on the state file by another run"`
 	cmd
 }
 
@@ -240,6 +268,75 @@ type StartStopEnvArgs struct {
 	Manifest FileBytesWithPathArg `positional-arg-name:"PATH" description:"Path to a manifest file"`
 }
 
+type RepairEnvStateOpts struct {
+	Args RepairEnvStateArgs `positional-args:"true" required:"true"`
+	VarFlags
+	OpsFlags
+	StateEncryptionFlags
+	CPIFlags
+	StatePath   string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	ForceUnlock bool   `long:"force-unlock" description:"Release a lock held on the state file by another run"`
+	PackageDir  string `long:"package-dir" value-name:"DIR" description:"Package cache location override"`
+	VMCID       string `long:"vm-cid" value-name:"CID" description:"CID of the deployed VM" required:"true"`
+	DiskCID     string `long:"disk-cid" value-name:"CID" description:"CID of the persistent disk attached to the VM"`
+	StemcellCID string `long:"stemcell-cid" value-name:"CID" description:"CID of the uploaded stemcell used by the VM"`
+	cmd
+}
+
+type RepairEnvStateArgs struct {
+	Manifest FileBytesWithPathArg `positional-arg-name:"PATH" description:"Path to a manifest file"`
+}
+
+type EnvStateOpts struct {
+	History  EnvStateHistoryOpts  `command:"history"  description:"List saved deployment states"`
+	Diff     EnvStateDiffOpts     `command:"diff"     description:"Show differences between saved deployment states"`
+	Rollback EnvStateRollbackOpts `command:"rollback" description:"Restore a saved deployment state"`
+}
+
+type EnvStateHistoryOpts struct {
+	Args      EnvStateArgs `positional-args:"true" required:"true"`
+	StatePath string       `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	cmd
+}
+
+type EnvStateDiffOpts struct {
+	Args EnvStateArgs `positional-args:"true" required:"true"`
+	StateEncryptionFlags
+	StatePath string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	From      string `long:"from"  value-name:"ID"   description:"Snapshot to compare from (default: previous snapshot)"`
+	To        string `long:"to"    value-name:"ID"   description:"Snapshot to compare to (default: current state)"`
+	cmd
+}
+
+type EnvStateRollbackOpts struct {
+	Args EnvStateArgs `positional-args:"true" required:"true"`
+	StateEncryptionFlags
+	StatePath   string `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	ForceUnlock bool   `long:"force-unlock" description:"Release a lock held on the state file by another run"`
+	To          string `long:"to" value-name:"ID" description:"Snapshot to restore" required:"true"`
+	cmd
+}
+
+type EncryptEnvStateOpts struct {
+	Args EnvStateArgs `positional-args:"true" required:"true"`
+	StateEncryptionFlags
+	StatePath   string      `long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`
+	VarsFSStore VarsFSStore `long:"vars-store" value-name:"PATH" description:"Variables file store to encrypt"`
+	ForceUnlock bool        `long:"force-unlock" description:"Release a lock held on the state file by another run"`
+	cmd
+}
+
+type EnvCPILogOpts struct {
+	Args      EnvStateArgs `positional-args:"true" required:"true"`
+	StatePath string       `long:"state" value-name:"PATH" description:"State file path or file:// URI"`
+	Logs      bool         `long:"logs" description:"Show CPI log output of each call"`
+	cmd
+}
+
+type EnvStateArgs struct {
+	Manifest FileBytesWithPathArg `positional-arg-name:"PATH" description:"Path to a manifest file"`
+}
+
 // Environment
 
 type EnvironmentOpts struct {
@@ -355,10 +452,12 @@ type InterpolateOpts struct {
 
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
 
 	Path            patch.Pointer `long:"path" value-name:"OP-PATH" description:"Extract value out of template (e.g.: /private_key)"`
 	VarErrors       bool          `long:"var-errs"                  description:"Expect all variables to be found, otherwise error"`
 	VarErrorsUnused bool          `long:"var-errs-unused"           description:"Expect all variables to be used, otherwise error"`
+	Explain         bool          `long:"explain"                   description:"Show where variables and operations came from instead of the result"`
 
 	cmd
 }
@@ -367,6 +466,97 @@ type InterpolateArgs struct {
 	Manifest FileBytesArg `positional-arg-name:"PATH" description:"Path to a template that will be interpolated"`
 }
 
+type DiffManifestsOpts struct {
+	Args DiffManifestsArgs `positional-args:"true" required:"true"`
+
+	Format DiffFormatArg `long:"format" value-name:"FORMAT" description:"Diff format: 'unified', 'side-by-side', 'ops' or 'json-patch'" default:"unified"`
+	Redact bool          `long:"redact"                     description:"Redact values under properties"`
+
+	cmd
+}
+
+type DiffManifestsArgs struct {
+	From FileBytesArg `positional-arg-name:"FROM" description:"Path to the original manifest ('-' for stdin)"`
+	To   FileBytesArg `positional-arg-name:"TO"   description:"Path to the changed manifest ('-' for stdin)"`
+}
+
+type ValidateManifestOpts struct {
+	Args ValidateManifestArgs `positional-args:"true" required:"true"`
+
+	VarFlags
+	OpsFlags
+	StateEncryptionFlags
+
+	Releases []string `long:"release" value-name:"PATH" description:"Path to a release tarball or directory (releases with file:// URLs in the manifest are read as well)"`
+
+	cmd
+}
+
+type ValidateManifestArgs struct {
+	Manifest FileBytesArg `positional-arg-name:"PATH" description:"Path to a manifest file"`
+}
+
+type BundleOpts struct {
+	Create BundleCreateOpts `command:"create" description:"Create a bundle with a manifest and releases and stemcells it references"`
+	Upload BundleUploadOpts `command:"upload" description:"Upload missing releases and stemcells from a bundle"`
+}
+
+type BundleCreateOpts struct {
+	Args BundleCreateArgs `positional-args:"true" required:"true"`
+
+	VarFlags
+	OpsFlags
+	StateEncryptionFlags
+
+	Output    FileArg  `long:"output"   value-name:"PATH" description:"Destination path for the bundle" required:"true"`
+	Stemcells []string `long:"stemcell" value-name:"PATH" description:"Path to a stemcell tarball for manifest stemcells without URL"`
+
+	cmd
+}
+
+type BundleCreateArgs struct {
+	Manifest FileBytesArg `positional-arg-name:"PATH" description:"Path to a manifest file"`
+}
+
+type BundleUploadOpts struct {
+	Args BundleUploadArgs `positional-args:"true" required:"true"`
+
+	Fix    bool `long:"fix"    description:"Replaces already uploaded releases and stemcells"`
+	Deploy bool `long:"deploy" description:"Deploy bundled manifest after uploading"`
+
+	cmd
+}
+
+type BundleUploadArgs struct {
+	Bundle FileArg `positional-arg-name:"PATH" description:"Path to a bundle"`
+}
+
+type VarsStoreOpts struct {
+	Rotate VarsStoreRotateOpts `command:"rotate" description:"Regenerate variables in a variables file store"`
+	Expiry VarsStoreExpiryOpts `command:"expiry" description:"Show expiry of certificates in a variables file store"`
+}
+
+type VarsStoreRotateOpts struct {
+	Args InterpolateArgs `positional-args:"true" required:"true"`
+
+	VarFlags
+	OpsFlags
+	StateEncryptionFlags
+
+	Names          []string `long:"name"            value-name:"NAME" description:"Variable to regenerate; certificates signed by it are regenerated too (can be specified multiple times)"`
+	ExpiringWithin int      `long:"expiring-within" value-name:"DAYS" description:"Regenerate certificates expiring within given number of days"`
+
+	cmd
+}
+
+type VarsStoreExpiryOpts struct {
+	VarsFSStore VarsFSStore `long:"vars-store" value-name:"PATH" description:"Variables file store" required:"true"`
+
+	StateEncryptionFlags
+
+	cmd
+}
+
 // Config
 
 type ConfigOpts struct {
@@ -394,6 +584,8 @@ type DiffConfigOpts struct {
 	ToID        string       `long:"to-id" description:"ID of second config to compare"`
 	FromContent FileBytesArg `long:"from-content" description:"Path to first config file to compare"`
 	ToContent   FileBytesArg `long:"to-content" description:"Path to second config file to compare"`
+
+	Format DiffFormatArg `long:"format" value-name:"FORMAT" description:"Compare configs on the client and show diff as 'unified', 'side-by-side', 'ops' or 'json-patch'"`
 	cmd
 }
 
@@ -404,6 +596,7 @@ type UpdateConfigOpts struct {
 	ExpectedLatestId string           `long:"expected-latest-id" description:"Expected ID of latest config"`
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
 	cmd
 }
 
@@ -434,6 +627,7 @@ type UpdateCloudConfigOpts struct {
 	Args UpdateCloudConfigArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
 
 	Name string `long:"name" description:"Cloud-Config name (default: '')" default:""`
 
@@ -452,6 +646,7 @@ type UpdateCPIConfigOpts struct {
 	Args UpdateCPIConfigArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
 
 	NoRedact bool `long:"no-redact" description:"Show non-redacted manifest diff"`
 
@@ -473,6 +668,7 @@ type UpdateRuntimeConfigOpts struct {
 	Args UpdateRuntimeConfigArgs `positional-args:"true" required:"true"`
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
 
 	NoRedact    bool   `long:"no-redact" description:"Show non-redacted manifest diff"`
 	Name        string `long:"name" description:"Runtime-Config name (default: '')" default:""`
@@ -500,6 +696,7 @@ type DeployOpts struct {
 
 	VarFlags
 	OpsFlags
+	StateEncryptionFlags
 
 	NoRedact bool `long:"no-redact" description:"Show non-redacted manifest diff"`
 
@@ -516,6 +713,13 @@ type DeployOpts struct {
 	DryRun               bool `long:"dry-run" description:"Renders job templates without altering deployment"`
 	ForceLatestVariables bool `long:"force-latest-variables" description:"Retrieve the latest variable values from the config server regardless of their update strategy"`
 
+	DiffFormat DiffFormatArg `long:"diff-format" value-name:"FORMAT" description:"Compare with the current manifest on the client and show diff as 'unified', 'side-by-side', 'ops' or 'json-patch'"`
+
+	ValidateOnly bool     `long:"validate-only" description:"Validate manifest job properties against release job specs without deploying"`
+	Releases     []string `long:"release" value-name:"PATH" description:"Path to a release tarball or directory used with --validate-only"`
+
+	OverridePolicy string `long:"override-policy" value-name:"REASON" description:"Deploy despite deploy policy violations and record the reason in the task context"`
+
 	cmd
 }
 
@@ -523,6 +727,21 @@ type DeployArgs struct {
 	Manifest FileBytesArg `positional-arg-name:"PATH" description:"Path to a manifest file"`
 }
 
+type ApplyPlanOpts struct {
+	Args ApplyPlanArgs `positional-args:"true" required:"true"`
+
+	StatePath          string `long:"state" value-name:"PATH" description:"Path to a file recording outcome of each deployment (default: PLAN.state.json)"`
+	Resume             bool   `long:"resume" description:"Skip deployments that succeeded according to the state file"`
+	SkipUploadReleases bool   `long:"skip-upload-releases" description:"Skips the upload procedure for releases"`
+	MaxParallel        int    `long:"max-parallel" value-name:"NUMBER" description:"Maximum number of deployments updated at the same time (unlike global --parallel, which limits client side operations)" default:"1"`
+
+	cmd
+}
+
+type ApplyPlanArgs struct {
+	Plan FileArg `positional-arg-name:"PATH" description:"Path to a plan file"`
+}
+
 type ManifestOpts struct {
 	cmd
 }
@@ -1062,6 +1281,17 @@ type GenerateJobArgs struct {
 	Name string `positional-arg-name:"NAME"`
 }
 
+type RenderJobOpts struct {
+	Directory DirOrCWDArg `long:"release-dir" description:"Release directory path if not current working directory" default:"."`
+
+	Job        string       `long:"job"        value-name:"NAME" description:"Name of the job to render" required:"true"`
+	Properties FileBytesArg `long:"properties" value-name:"PATH" description:"Path to a YAML file with job properties" required:"true"`
+	Spec       FileBytesArg `long:"spec"       value-name:"PATH" description:"Path to a YAML file overriding instance spec (deployment, id, index, az, bootstrap, address, networks, links)"`
+	OutputDir  string       `long:"output-dir" value-name:"DIR"  description:"Destination directory for rendered templates" required:"true"`
+
+	cmd
+}
+
 type GeneratePackageOpts struct {
 	Args GeneratePackageArgs `positional-args:"true" required:"true"`
 
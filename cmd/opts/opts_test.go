@@ -137,6 +137,14 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
+		Describe("RubyERBRendererOpt", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("RubyERBRendererOpt", opts)).To(Equal(
+					`long:"ruby-erb-renderer" description:"Render job templates with ruby instead of the built-in renderer" env:"BOSH_RUBY_ERB_RENDERER"`,
+				))
+			})
+		})
+
 		Describe("CACertOpt", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("CACertOpt", opts)).To(Equal(
@@ -233,6 +241,14 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
+		Describe("EnvState", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("EnvState", opts)).To(Equal(
+					`command:"env-state" description:"Inspect and restore saved BOSH environment states"`,
+				))
+			})
+		})
+
 		Describe("Environment", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("Environment", opts)).To(Equal(
@@ -249,6 +265,30 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
+		Describe("RepairEnvState", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("RepairEnvState", opts)).To(Equal(
+					`command:"repair-env-state" description:"Rebuild BOSH environment state from known IaaS resources"`,
+				))
+			})
+		})
+
+		Describe("EnvCPILog", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("EnvCPILog", opts)).To(Equal(
+					`command:"env-cpi-log" description:"Show CPI calls made for BOSH environment"`,
+				))
+			})
+		})
+
+		Describe("EncryptEnvState", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("EncryptEnvState", opts)).To(Equal(
+					`command:"encrypt-env-state" description:"Encrypt BOSH environment state and vars-store files in place"`,
+				))
+			})
+		})
+
 		Describe("LogIn", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("LogIn", opts)).To(Equal(
@@ -321,6 +361,46 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
+		Describe("ApplyPlan", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("ApplyPlan", opts)).To(Equal(
+					`command:"apply-plan" description:"Update multiple deployments in dependency order from a plan file"`,
+				))
+			})
+		})
+
+		Describe("Bundle", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Bundle", opts)).To(Equal(
+					`command:"bundle" description:"Manage offline bundles of a manifest with its releases and stemcells"`,
+				))
+			})
+		})
+
+		Describe("DiffManifests", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("DiffManifests", opts)).To(Equal(
+					`command:"diff-manifests" description:"Show structural differences between two manifests or configs"`,
+				))
+			})
+		})
+
+		Describe("ValidateManifest", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("ValidateManifest", opts)).To(Equal(
+					`command:"validate-manifest" description:"Validate manifest job properties against release job specs"`,
+				))
+			})
+		})
+
+		Describe("VarsStore", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("VarsStore", opts)).To(Equal(
+					`command:"vars-store" description:"Manage variables file store"`,
+				))
+			})
+		})
+
 		Describe("Config", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("Config", opts)).To(Equal(
@@ -737,6 +817,14 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
+		Describe("RenderJob", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("RenderJob", opts)).To(Equal(
+					`command:"render-job" description:"Render job templates locally"`,
+				))
+			})
+		})
+
 		Describe("GeneratePackage", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("GeneratePackage", opts)).To(Equal(
@@ -841,7 +929,13 @@ var _ = Describe("Opts", func() {
 
 		It("has --state", func() {
 			Expect(getStructTagForName("StatePath", opts)).To(Equal(
-				`long:"state" value-name:"PATH" description:"State file path"`,
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
 			))
 		})
 
@@ -851,6 +945,12 @@ var _ = Describe("Opts", func() {
 			))
 		})
 
+		It("has --dry-run", func() {
+			Expect(getStructTagForName("DryRun", opts)).To(Equal(
+				`long:"dry-run" description:"Show planned changes without calling the CPI or changing the state file"`,
+			))
+		})
+
 		It("has --recreate", func() {
 			Expect(getStructTagForName("Recreate", opts)).To(Equal(
 				`long:"recreate" description:"Recreate VM in deployment"`,
@@ -901,7 +1001,13 @@ var _ = Describe("Opts", func() {
 
 		It("has --state", func() {
 			Expect(getStructTagForName("StatePath", opts)).To(Equal(
-				`long:"state" value-name:"PATH" description:"State file path"`,
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
 			))
 		})
 
@@ -949,7 +1055,13 @@ var _ = Describe("Opts", func() {
 
 		It("has --state", func() {
 			Expect(getStructTagForName("StatePath", opts)).To(Equal(
-				`long:"state" value-name:"PATH" description:"State file path"`,
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
 			))
 		})
 
@@ -975,7 +1087,13 @@ var _ = Describe("Opts", func() {
 
 		It("has --state", func() {
 			Expect(getStructTagForName("StatePath", opts)).To(Equal(
-				`long:"state" value-name:"PATH" description:"State file path"`,
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
 			))
 		})
 
@@ -997,6 +1115,292 @@ var _ = Describe("Opts", func() {
 		})
 	})
 
+	Describe("EnvStateOpts", func() {
+		var opts *EnvStateOpts
+
+		BeforeEach(func() {
+			opts = &EnvStateOpts{}
+		})
+
+		It("has history", func() {
+			Expect(getStructTagForName("History", opts)).To(Equal(
+				`command:"history" description:"List saved deployment states"`,
+			))
+		})
+
+		It("has diff", func() {
+			Expect(getStructTagForName("Diff", opts)).To(Equal(
+				`command:"diff" description:"Show differences between saved deployment states"`,
+			))
+		})
+
+		It("has rollback", func() {
+			Expect(getStructTagForName("Rollback", opts)).To(Equal(
+				`command:"rollback" description:"Restore a saved deployment state"`,
+			))
+		})
+	})
+
+	Describe("EnvStateHistoryOpts", func() {
+		var opts *EnvStateHistoryOpts
+
+		BeforeEach(func() {
+			opts = &EnvStateHistoryOpts{}
+		})
+
+		Describe("Args", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+			})
+		})
+
+		It("has --state", func() {
+			Expect(getStructTagForName("StatePath", opts)).To(Equal(
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+	})
+
+	Describe("EnvStateDiffOpts", func() {
+		var opts *EnvStateDiffOpts
+
+		BeforeEach(func() {
+			opts = &EnvStateDiffOpts{}
+		})
+
+		It("has --state", func() {
+			Expect(getStructTagForName("StatePath", opts)).To(Equal(
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --from", func() {
+			Expect(getStructTagForName("From", opts)).To(Equal(
+				`long:"from" value-name:"ID" description:"Snapshot to compare from (default: previous snapshot)"`,
+			))
+		})
+
+		It("has --to", func() {
+			Expect(getStructTagForName("To", opts)).To(Equal(
+				`long:"to" value-name:"ID" description:"Snapshot to compare to (default: current state)"`,
+			))
+		})
+	})
+
+	Describe("EnvStateRollbackOpts", func() {
+		var opts *EnvStateRollbackOpts
+
+		BeforeEach(func() {
+			opts = &EnvStateRollbackOpts{}
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
+			))
+		})
+
+		It("has --to", func() {
+			Expect(getStructTagForName("To", opts)).To(Equal(
+				`long:"to" value-name:"ID" description:"Snapshot to restore" required:"true"`,
+			))
+		})
+	})
+
+	Describe("EnvStateArgs", func() {
+		var args *EnvStateArgs
+
+		BeforeEach(func() {
+			args = &EnvStateArgs{}
+		})
+
+		Describe("Manifest", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Manifest", args)).To(Equal(
+					`positional-arg-name:"PATH" description:"Path to a manifest file"`,
+				))
+			})
+		})
+	})
+
+	Describe("RepairEnvStateOpts", func() {
+		var opts *RepairEnvStateOpts
+
+		BeforeEach(func() {
+			opts = &RepairEnvStateOpts{}
+		})
+
+		It("has positional args", func() {
+			Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+		})
+
+		It("has --state", func() {
+			Expect(getStructTagForName("StatePath", opts)).To(Equal(
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
+			))
+		})
+
+		It("has --vm-cid", func() {
+			Expect(getStructTagForName("VMCID", opts)).To(Equal(
+				`long:"vm-cid" value-name:"CID" description:"CID of the deployed VM" required:"true"`,
+			))
+		})
+
+		It("has --disk-cid", func() {
+			Expect(getStructTagForName("DiskCID", opts)).To(Equal(
+				`long:"disk-cid" value-name:"CID" description:"CID of the persistent disk attached to the VM"`,
+			))
+		})
+
+		It("has --stemcell-cid", func() {
+			Expect(getStructTagForName("StemcellCID", opts)).To(Equal(
+				`long:"stemcell-cid" value-name:"CID" description:"CID of the uploaded stemcell used by the VM"`,
+			))
+		})
+	})
+
+	Describe("RepairEnvStateArgs", func() {
+		var args *RepairEnvStateArgs
+
+		BeforeEach(func() {
+			args = &RepairEnvStateArgs{}
+		})
+
+		Describe("Manifest", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Manifest", args)).To(Equal(
+					`positional-arg-name:"PATH" description:"Path to a manifest file"`,
+				))
+			})
+		})
+	})
+
+	Describe("EnvCPILogOpts", func() {
+		var opts *EnvCPILogOpts
+
+		BeforeEach(func() {
+			opts = &EnvCPILogOpts{}
+		})
+
+		It("has Args", func() {
+			Expect(getStructTagForName("Args", opts)).To(Equal(
+				`positional-args:"true" required:"true"`,
+			))
+		})
+
+		It("has --state", func() {
+			Expect(getStructTagForName("StatePath", opts)).To(Equal(
+				`long:"state" value-name:"PATH" description:"State file path or file:// URI"`,
+			))
+		})
+
+		It("has --logs", func() {
+			Expect(getStructTagForName("Logs", opts)).To(Equal(
+				`long:"logs" description:"Show CPI log output of each call"`,
+			))
+		})
+	})
+
+	Describe("EncryptEnvStateOpts", func() {
+		var opts *EncryptEnvStateOpts
+
+		BeforeEach(func() {
+			opts = &EncryptEnvStateOpts{}
+		})
+
+		It("has positional args", func() {
+			Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+		})
+
+		It("has --state", func() {
+			Expect(getStructTagForName("StatePath", opts)).To(Equal(
+				`long:"state" value-name:"PATH" description:"State file path or URI (file://, s3://, gcs://, http(s)://)"`,
+			))
+		})
+
+		It("has --vars-store", func() {
+			Expect(getStructTagForName("VarsFSStore", opts)).To(Equal(
+				`long:"vars-store" value-name:"PATH" description:"Variables file store to encrypt"`,
+			))
+		})
+
+		It("has --force-unlock", func() {
+			Expect(getStructTagForName("ForceUnlock", opts)).To(Equal(
+				`long:"force-unlock" description:"Release a lock held on the state file by another run"`,
+			))
+		})
+	})
+
+	Describe("CPIFlags", func() {
+		var opts *CPIFlags
+
+		BeforeEach(func() {
+			opts = &CPIFlags{}
+		})
+
+		It("has --cpi", func() {
+			Expect(getStructTagForName("CPI", opts)).To(Equal(
+				`long:"cpi" value-name:"NAME" description:"Use a built-in CPI instead of the manifest's CPI release, same as cloud_provider.cpi ('fake' keeps stemcells, VMs and disks in ~/.bosh/fake-cpi)"`,
+			))
+		})
+
+		It("has --cpi-max-attempts", func() {
+			Expect(getStructTagForName("CPIMaxAttempts", opts)).To(Equal(
+				`long:"cpi-max-attempts" value-name:"NUM" description:"Attempts for CPI calls failing with errors marked ok_to_retry" default:"1"`,
+			))
+		})
+
+		It("has --cpi-retry-backoff", func() {
+			Expect(getStructTagForName("CPIRetryBackoff", opts)).To(Equal(
+				`long:"cpi-retry-backoff" value-name:"DURATION" description:"Wait before retrying a CPI call, doubled after every attempt (up to 1m)" default:"5s"`,
+			))
+		})
+
+		It("has --cpi-timeout", func() {
+			Expect(getStructTagForName("CPITimeouts", opts)).To(Equal(
+				`long:"cpi-timeout" value-name:"[METHOD=]DURATION" description:"Terminate CPI calls running longer, e.g. 30m or attach_disk=5m (can be specified multiple times)"`,
+			))
+		})
+
+		It("has --record-cpi", func() {
+			Expect(getStructTagForName("RecordCPI", opts)).To(Equal(
+				`long:"record-cpi" value-name:"PATH" description:"Record CPI and agent calls (with credentials redacted) to a cassette file"`,
+			))
+		})
+
+		It("has --replay-cpi", func() {
+			Expect(getStructTagForName("ReplayCPI", opts)).To(Equal(
+				`long:"replay-cpi" value-name:"PATH" description:"Answer CPI and agent calls from a recorded cassette file instead of installing and running the CPI"`,
+			))
+		})
+	})
+
+	Describe("StateEncryptionFlags", func() {
+		var opts *StateEncryptionFlags
+
+		BeforeEach(func() {
+			opts = &StateEncryptionFlags{}
+		})
+
+		It("has --state-key-file", func() {
+			Expect(getStructTagForName("StateKeyFile", opts)).To(Equal(
+				`long:"state-key-file" value-name:"PATH" env:"BOSH_STATE_KEY_FILE" description:"Encrypt state and vars-store files with a key read from a file (key may also be set via BOSH_STATE_KEY)"`,
+			))
+		})
+
+		It("has --state-passphrase", func() {
+			Expect(getStructTagForName("StatePassphrase", opts)).To(Equal(
+				`long:"state-passphrase" description:"Encrypt state and vars-store files with a passphrase prompted for"`,
+			))
+		})
+	})
+
 	Describe("AliasEnvOpts", func() {
 		var opts *AliasEnvOpts
 
@@ -1120,6 +1524,14 @@ var _ = Describe("Opts", func() {
 				))
 			})
 		})
+
+		Describe("Format", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Format", opts)).To(Equal(
+					`long:"format" value-name:"FORMAT" description:"Compare configs on the client and show diff as 'unified', 'side-by-side', 'ops' or 'json-patch'"`,
+				))
+			})
+		})
 	})
 
 	Describe("UpdateConfigOpts", func() {
@@ -1171,224 +1583,549 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
-		Describe("Type", func() {
-			It("contains desired values", func() {
-				Expect(getStructTagForName("Type", opts)).To(Equal(`long:"type" description:"Config type, e.g. 'cloud', 'runtime', or 'cpi'"`))
-			})
+		Describe("Type", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Type", opts)).To(Equal(`long:"type" description:"Config type, e.g. 'cloud', 'runtime', or 'cpi'"`))
+			})
+		})
+
+		Describe("Name", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Name", opts)).To(Equal(`long:"name" description:"Config name"`))
+			})
+		})
+	})
+
+	Describe("DeleteConfigArgs", func() {
+		var opts *DeleteConfigArgs
+
+		BeforeEach(func() {
+			opts = &DeleteConfigArgs{}
+		})
+
+		Describe("ID", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("ID", opts)).To(Equal(
+					`positional-arg-name:"ID" description:"Config ID"`,
+				))
+			})
+		})
+	})
+
+	Describe("UnaliasEnvOpts", func() {
+		var opts *UnaliasEnvOpts
+
+		BeforeEach(func() {
+			opts = &UnaliasEnvOpts{}
+		})
+
+		Describe("Args", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+			})
+		})
+	})
+
+	Describe("UnaliasEnvArgs", func() {
+		var args *UnaliasEnvArgs
+
+		BeforeEach(func() {
+			args = &UnaliasEnvArgs{}
+		})
+
+		Describe("Alias", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Alias", args)).To(Equal(
+					`positional-arg-name:"ALIAS" description:"Environment alias"`,
+				))
+			})
+		})
+	})
+
+	Describe("TaskOpts", func() {
+		var opts *TaskOpts
+
+		BeforeEach(func() {
+			opts = &TaskOpts{}
+		})
+
+		Describe("Args", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true"`))
+			})
+		})
+
+		Describe("Event", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Event", opts)).To(Equal(
+					`long:"event" description:"Track event log"`,
+				))
+			})
+		})
+
+		Describe("CPI", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("CPI", opts)).To(Equal(
+					`long:"cpi" description:"Track CPI log"`,
+				))
+			})
+		})
+
+		Describe("Debug", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Debug", opts)).To(Equal(
+					`long:"debug" description:"Track debug log"`,
+				))
+			})
+		})
+
+		Describe("Result", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Result", opts)).To(Equal(
+					`long:"result" description:"Track result log"`,
+				))
+			})
+		})
+
+		Describe("All", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("All", opts)).To(Equal(
+					`long:"all" short:"a" description:"Include all task types (ssh, logs, vms, etc)"`,
+				))
+			})
+		})
+	})
+
+	Describe("TaskArgs", func() {
+		var opts *TaskArgs
+
+		BeforeEach(func() {
+			opts = &TaskArgs{}
+		})
+
+		Describe("ID", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("ID", opts)).To(Equal(
+					`positional-arg-name:"ID"`,
+				))
+			})
+		})
+	})
+
+	Describe("TasksOpts", func() {
+		var opts *TasksOpts
+
+		BeforeEach(func() {
+			opts = &TasksOpts{}
+		})
+
+		Describe("Recent", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Recent", opts)).To(Equal(
+					`long:"recent" short:"r" description:"Show 30 recent tasks. Use '=' to specify the number of tasks to show" optional:"true" optional-value:"30"`,
+				))
+			})
+		})
+
+		Describe("All", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("All", opts)).To(Equal(
+					`long:"all" short:"a" description:"Include all task types (ssh, logs, vms, etc)"`,
+				))
+			})
+		})
+	})
+
+	Describe("CancelTaskOpts", func() {
+		var opts *CancelTaskOpts
+
+		BeforeEach(func() {
+			opts = &CancelTaskOpts{}
+		})
+
+		Describe("Args", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+			})
+		})
+	})
+
+	Describe("CancelTasksOpts", func() {
+		var opts *CancelTasksOpts
+
+		BeforeEach(func() {
+			opts = &CancelTasksOpts{}
+		})
+
+		Describe("Types", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Types", opts)).To(Equal(
+					`long:"type" short:"t" description:"task types to cancel (cck_scan_and_fix, cck_apply, update_release, update_deployment, vms, etc) (default is all types)" optional:"true"`,
+				))
+			})
+		})
+
+		Describe("States", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("States", opts)).To(Equal(
+					`long:"state" short:"s" description:"task states to cancel (queued, processing) (default: queued)" optional:"true"`,
+				))
+			})
+		})
+	})
+
+	Describe("CleanUpOpts", func() {
+		var opts *CleanUpOpts
+
+		BeforeEach(func() {
+			opts = &CleanUpOpts{}
+		})
+
+		Describe("All", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("All", opts)).To(Equal(
+					`long:"all" description:"Clean up all unused resources including all orphaned disks"`,
+				))
+				Expect(getStructTagForName("DryRun", opts)).To(Equal(
+					`long:"dry-run" description:"Print out the resources that will be deleted but does not delete anything"`,
+				))
+				Expect(getStructTagForName("KeepOrphanedDisks", opts)).To(Equal(
+					`long:"keep-orphaned-disks" description:"Keep orphaned disks even with '--all'"`,
+				))
+			})
+		})
+	})
+
+	Describe("AttachDiskOpts", func() {
+		var opts *AttachDiskOpts
+
+		BeforeEach(func() {
+			opts = &AttachDiskOpts{}
+		})
+
+		Describe("Args", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+			})
+		})
+
+		Describe("DiskProperties", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("DiskProperties", opts)).To(Equal(`long:"disk-properties" description:"Disk properties to use for the new disk. Use 'copy' to copy the properties from the currently attached disk" optional:"true"`))
+			})
+		})
+	})
+
+	Describe("AttachDiskArgs", func() {
+		var opts *AttachDiskArgs
+
+		BeforeEach(func() {
+			opts = &AttachDiskArgs{}
+		})
+
+		Describe("DiskId", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Slug", opts)).To(Equal(`positional-arg-name:"INSTANCE-GROUP/INSTANCE-ID"`))
+				Expect(getStructTagForName("DiskCID", opts)).To(Equal(`positional-arg-name:"DISK-CID"`))
+			})
+		})
+	})
+
+	Describe("InterpolateOpts", func() {
+		var opts InterpolateOpts
+
+		It("has Args", func() {
+			Expect(getStructTagForName("Args", &opts)).To(Equal(`positional-args:"true" required:"true"`))
+		})
+
+		It("has Path", func() {
+			Expect(getStructTagForName("Path", &opts)).To(Equal(
+				`long:"path" value-name:"OP-PATH" description:"Extract value out of template (e.g.: /private_key)"`,
+			))
+		})
+
+		It("has VarErrors", func() {
+			Expect(getStructTagForName("VarErrors", &opts)).To(Equal(
+				`long:"var-errs" description:"Expect all variables to be found, otherwise error"`,
+			))
+		})
+
+		It("has VarErrorsUnused", func() {
+			Expect(getStructTagForName("VarErrorsUnused", &opts)).To(Equal(
+				`long:"var-errs-unused" description:"Expect all variables to be used, otherwise error"`,
+			))
+		})
+
+		It("has Explain", func() {
+			Expect(getStructTagForName("Explain", &opts)).To(Equal(
+				`long:"explain" description:"Show where variables and operations came from instead of the result"`,
+			))
+		})
+	})
+
+	Describe("VarsStoreOpts", func() {
+		var opts *VarsStoreOpts
+
+		BeforeEach(func() {
+			opts = &VarsStoreOpts{}
+		})
+
+		It("has rotate", func() {
+			Expect(getStructTagForName("Rotate", opts)).To(Equal(
+				`command:"rotate" description:"Regenerate variables in a variables file store"`,
+			))
 		})
 
-		Describe("Name", func() {
-			It("contains desired values", func() {
-				Expect(getStructTagForName("Name", opts)).To(Equal(`long:"name" description:"Config name"`))
-			})
+		It("has expiry", func() {
+			Expect(getStructTagForName("Expiry", opts)).To(Equal(
+				`command:"expiry" description:"Show expiry of certificates in a variables file store"`,
+			))
 		})
 	})
 
-	Describe("DeleteConfigArgs", func() {
-		var opts *DeleteConfigArgs
+	Describe("VarsStoreRotateOpts", func() {
+		var opts *VarsStoreRotateOpts
 
 		BeforeEach(func() {
-			opts = &DeleteConfigArgs{}
+			opts = &VarsStoreRotateOpts{}
 		})
 
-		Describe("ID", func() {
-			It("contains desired values", func() {
-				Expect(getStructTagForName("ID", opts)).To(Equal(
-					`positional-arg-name:"ID" description:"Config ID"`,
-				))
-			})
+		It("has Args", func() {
+			Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+		})
+
+		It("has --name", func() {
+			Expect(getStructTagForName("Names", opts)).To(Equal(
+				`long:"name" value-name:"NAME" description:"Variable to regenerate; certificates signed by it are regenerated too (can be specified multiple times)"`,
+			))
+		})
+
+		It("has --expiring-within", func() {
+			Expect(getStructTagForName("ExpiringWithin", opts)).To(Equal(
+				`long:"expiring-within" value-name:"DAYS" description:"Regenerate certificates expiring within given number of days"`,
+			))
 		})
 	})
 
-	Describe("UnaliasEnvOpts", func() {
-		var opts *UnaliasEnvOpts
+	Describe("VarsStoreExpiryOpts", func() {
+		var opts *VarsStoreExpiryOpts
 
 		BeforeEach(func() {
-			opts = &UnaliasEnvOpts{}
+			opts = &VarsStoreExpiryOpts{}
 		})
 
-		Describe("Args", func() {
-			It("contains desired values", func() {
-				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
-			})
+		It("has --vars-store", func() {
+			Expect(getStructTagForName("VarsFSStore", opts)).To(Equal(
+				`long:"vars-store" value-name:"PATH" description:"Variables file store" required:"true"`,
+			))
 		})
 	})
 
-	Describe("UnaliasEnvArgs", func() {
-		var args *UnaliasEnvArgs
+	Describe("InterpolateArgs", func() {
+		var opts *InterpolateArgs
 
 		BeforeEach(func() {
-			args = &UnaliasEnvArgs{}
+			opts = &InterpolateArgs{}
 		})
 
-		Describe("Alias", func() {
+		Describe("Manifest", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Alias", args)).To(Equal(
-					`positional-arg-name:"ALIAS" description:"Environment alias"`,
+				Expect(getStructTagForName("Manifest", opts)).To(Equal(
+					`positional-arg-name:"PATH" description:"Path to a template that will be interpolated"`,
 				))
 			})
 		})
 	})
 
-	Describe("TaskOpts", func() {
-		var opts *TaskOpts
+	Describe("ApplyPlanOpts", func() {
+		var opts *ApplyPlanOpts
 
 		BeforeEach(func() {
-			opts = &TaskOpts{}
+			opts = &ApplyPlanOpts{}
 		})
 
 		Describe("Args", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true"`))
+				Expect(getStructTagForName("Args", opts)).To(Equal(
+					`positional-args:"true" required:"true"`,
+				))
 			})
 		})
 
-		Describe("Event", func() {
+		Describe("StatePath", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Event", opts)).To(Equal(
-					`long:"event" description:"Track event log"`,
+				Expect(getStructTagForName("StatePath", opts)).To(Equal(
+					`long:"state" value-name:"PATH" description:"Path to a file recording outcome of each deployment (default: PLAN.state.json)"`,
 				))
 			})
 		})
 
-		Describe("CPI", func() {
+		Describe("Resume", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("CPI", opts)).To(Equal(
-					`long:"cpi" description:"Track CPI log"`,
+				Expect(getStructTagForName("Resume", opts)).To(Equal(
+					`long:"resume" description:"Skip deployments that succeeded according to the state file"`,
 				))
 			})
 		})
 
-		Describe("Debug", func() {
+		Describe("SkipUploadReleases", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Debug", opts)).To(Equal(
-					`long:"debug" description:"Track debug log"`,
+				Expect(getStructTagForName("SkipUploadReleases", opts)).To(Equal(
+					`long:"skip-upload-releases" description:"Skips the upload procedure for releases"`,
 				))
 			})
 		})
 
-		Describe("Result", func() {
+		Describe("MaxParallel", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Result", opts)).To(Equal(
-					`long:"result" description:"Track result log"`,
+				Expect(getStructTagForName("MaxParallel", opts)).To(Equal(
+					`long:"max-parallel" value-name:"NUMBER" description:"Maximum number of deployments updated at the same time (unlike global --parallel, which limits client side operations)" default:"1"`,
 				))
 			})
 		})
+	})
 
-		Describe("All", func() {
+	Describe("ApplyPlanArgs", func() {
+		var opts *ApplyPlanArgs
+
+		BeforeEach(func() {
+			opts = &ApplyPlanArgs{}
+		})
+
+		Describe("Plan", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("All", opts)).To(Equal(
-					`long:"all" short:"a" description:"Include all task types (ssh, logs, vms, etc)"`,
+				Expect(getStructTagForName("Plan", opts)).To(Equal(
+					`positional-arg-name:"PATH" description:"Path to a plan file"`,
 				))
 			})
 		})
 	})
 
-	Describe("TaskArgs", func() {
-		var opts *TaskArgs
+	Describe("BundleOpts", func() {
+		var opts *BundleOpts
 
 		BeforeEach(func() {
-			opts = &TaskArgs{}
+			opts = &BundleOpts{}
 		})
 
-		Describe("ID", func() {
+		Describe("Create", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("ID", opts)).To(Equal(
-					`positional-arg-name:"ID"`,
+				Expect(getStructTagForName("Create", opts)).To(Equal(
+					`command:"create" description:"Create a bundle with a manifest and releases and stemcells it references"`,
+				))
+			})
+		})
+
+		Describe("Upload", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Upload", opts)).To(Equal(
+					`command:"upload" description:"Upload missing releases and stemcells from a bundle"`,
 				))
 			})
 		})
 	})
 
-	Describe("TasksOpts", func() {
-		var opts *TasksOpts
+	Describe("BundleCreateOpts", func() {
+		var opts *BundleCreateOpts
 
 		BeforeEach(func() {
-			opts = &TasksOpts{}
+			opts = &BundleCreateOpts{}
 		})
 
-		Describe("Recent", func() {
+		Describe("Args", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Recent", opts)).To(Equal(
-					`long:"recent" short:"r" description:"Show 30 recent tasks. Use '=' to specify the number of tasks to show" optional:"true" optional-value:"30"`,
+				Expect(getStructTagForName("Args", opts)).To(Equal(
+					`positional-args:"true" required:"true"`,
 				))
 			})
 		})
 
-		Describe("All", func() {
+		Describe("Output", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("All", opts)).To(Equal(
-					`long:"all" short:"a" description:"Include all task types (ssh, logs, vms, etc)"`,
+				Expect(getStructTagForName("Output", opts)).To(Equal(
+					`long:"output" value-name:"PATH" description:"Destination path for the bundle" required:"true"`,
+				))
+			})
+		})
+
+		Describe("Stemcells", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Stemcells", opts)).To(Equal(
+					`long:"stemcell" value-name:"PATH" description:"Path to a stemcell tarball for manifest stemcells without URL"`,
 				))
 			})
 		})
 	})
 
-	Describe("CancelTaskOpts", func() {
-		var opts *CancelTaskOpts
+	Describe("BundleCreateArgs", func() {
+		var opts *BundleCreateArgs
 
 		BeforeEach(func() {
-			opts = &CancelTaskOpts{}
+			opts = &BundleCreateArgs{}
 		})
 
-		Describe("Args", func() {
+		Describe("Manifest", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+				Expect(getStructTagForName("Manifest", opts)).To(Equal(
+					`positional-arg-name:"PATH" description:"Path to a manifest file"`,
+				))
 			})
 		})
 	})
 
-	Describe("CancelTasksOpts", func() {
-		var opts *CancelTasksOpts
+	Describe("BundleUploadOpts", func() {
+		var opts *BundleUploadOpts
 
 		BeforeEach(func() {
-			opts = &CancelTasksOpts{}
+			opts = &BundleUploadOpts{}
 		})
 
-		Describe("Types", func() {
+		Describe("Args", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Types", opts)).To(Equal(
-					`long:"type" short:"t" description:"task types to cancel (cck_scan_and_fix, cck_apply, update_release, update_deployment, vms, etc) (default is all types)" optional:"true"`,
+				Expect(getStructTagForName("Args", opts)).To(Equal(
+					`positional-args:"true" required:"true"`,
 				))
 			})
 		})
 
-		Describe("States", func() {
+		Describe("Fix", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("States", opts)).To(Equal(
-					`long:"state" short:"s" description:"task states to cancel (queued, processing) (default: queued)" optional:"true"`,
+				Expect(getStructTagForName("Fix", opts)).To(Equal(
+					`long:"fix" description:"Replaces already uploaded releases and stemcells"`,
+				))
+			})
+		})
+
+		Describe("Deploy", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Deploy", opts)).To(Equal(
+					`long:"deploy" description:"Deploy bundled manifest after uploading"`,
 				))
 			})
 		})
 	})
 
-	Describe("CleanUpOpts", func() {
-		var opts *CleanUpOpts
+	Describe("BundleUploadArgs", func() {
+		var opts *BundleUploadArgs
 
 		BeforeEach(func() {
-			opts = &CleanUpOpts{}
+			opts = &BundleUploadArgs{}
 		})
 
-		Describe("All", func() {
+		Describe("Bundle", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("All", opts)).To(Equal(
-					`long:"all" description:"Clean up all unused resources including all orphaned disks"`,
-				))
-				Expect(getStructTagForName("DryRun", opts)).To(Equal(
-					`long:"dry-run" description:"Print out the resources that will be deleted but does not delete anything"`,
-				))
-				Expect(getStructTagForName("KeepOrphanedDisks", opts)).To(Equal(
-					`long:"keep-orphaned-disks" description:"Keep orphaned disks even with '--all'"`,
+				Expect(getStructTagForName("Bundle", opts)).To(Equal(
+					`positional-arg-name:"PATH" description:"Path to a bundle"`,
 				))
 			})
 		})
 	})
 
-	Describe("AttachDiskOpts", func() {
-		var opts *AttachDiskOpts
+	Describe("DiffManifestsOpts", func() {
+		var opts *DiffManifestsOpts
 
 		BeforeEach(func() {
-			opts = &AttachDiskOpts{}
+			opts = &DiffManifestsOpts{}
 		})
 
 		Describe("Args", func() {
@@ -1397,65 +2134,78 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
-		Describe("DiskProperties", func() {
+		Describe("Format", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("DiskProperties", opts)).To(Equal(`long:"disk-properties" description:"Disk properties to use for the new disk. Use 'copy' to copy the properties from the currently attached disk" optional:"true"`))
+				Expect(getStructTagForName("Format", opts)).To(Equal(
+					`long:"format" value-name:"FORMAT" description:"Diff format: 'unified', 'side-by-side', 'ops' or 'json-patch'" default:"unified"`,
+				))
+			})
+		})
+
+		Describe("Redact", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Redact", opts)).To(Equal(`long:"redact" description:"Redact values under properties"`))
 			})
 		})
 	})
 
-	Describe("AttachDiskArgs", func() {
-		var opts *AttachDiskArgs
+	Describe("DiffManifestsArgs", func() {
+		var opts *DiffManifestsArgs
 
 		BeforeEach(func() {
-			opts = &AttachDiskArgs{}
+			opts = &DiffManifestsArgs{}
 		})
 
-		Describe("DiskId", func() {
+		Describe("From", func() {
 			It("contains desired values", func() {
-				Expect(getStructTagForName("Slug", opts)).To(Equal(`positional-arg-name:"INSTANCE-GROUP/INSTANCE-ID"`))
-				Expect(getStructTagForName("DiskCID", opts)).To(Equal(`positional-arg-name:"DISK-CID"`))
+				Expect(getStructTagForName("From", opts)).To(Equal(
+					`positional-arg-name:"FROM" description:"Path to the original manifest ('-' for stdin)"`,
+				))
 			})
 		})
-	})
-
-	Describe("InterpolateOpts", func() {
-		var opts InterpolateOpts
 
-		It("has Args", func() {
-			Expect(getStructTagForName("Args", &opts)).To(Equal(`positional-args:"true" required:"true"`))
+		Describe("To", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("To", opts)).To(Equal(
+					`positional-arg-name:"TO" description:"Path to the changed manifest ('-' for stdin)"`,
+				))
+			})
 		})
+	})
 
-		It("has Path", func() {
-			Expect(getStructTagForName("Path", &opts)).To(Equal(
-				`long:"path" value-name:"OP-PATH" description:"Extract value out of template (e.g.: /private_key)"`,
-			))
+	Describe("ValidateManifestOpts", func() {
+		var opts *ValidateManifestOpts
+
+		BeforeEach(func() {
+			opts = &ValidateManifestOpts{}
 		})
 
-		It("has VarErrors", func() {
-			Expect(getStructTagForName("VarErrors", &opts)).To(Equal(
-				`long:"var-errs" description:"Expect all variables to be found, otherwise error"`,
-			))
+		Describe("Args", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Args", opts)).To(Equal(`positional-args:"true" required:"true"`))
+			})
 		})
 
-		It("has VarErrorsUnused", func() {
-			Expect(getStructTagForName("VarErrorsUnused", &opts)).To(Equal(
-				`long:"var-errs-unused" description:"Expect all variables to be used, otherwise error"`,
-			))
+		Describe("Releases", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Releases", opts)).To(Equal(
+					`long:"release" value-name:"PATH" description:"Path to a release tarball or directory (releases with file:// URLs in the manifest are read as well)"`,
+				))
+			})
 		})
 	})
 
-	Describe("InterpolateArgs", func() {
-		var opts *InterpolateArgs
+	Describe("ValidateManifestArgs", func() {
+		var opts *ValidateManifestArgs
 
 		BeforeEach(func() {
-			opts = &InterpolateArgs{}
+			opts = &ValidateManifestArgs{}
 		})
 
 		Describe("Manifest", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("Manifest", opts)).To(Equal(
-					`positional-arg-name:"PATH" description:"Path to a template that will be interpolated"`,
+					`positional-arg-name:"PATH" description:"Path to a manifest file"`,
 				))
 			})
 		})
@@ -1650,6 +2400,38 @@ var _ = Describe("Opts", func() {
 			})
 		})
 
+		Describe("DiffFormat", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("DiffFormat", opts)).To(Equal(
+					`long:"diff-format" value-name:"FORMAT" description:"Compare with the current manifest on the client and show diff as 'unified', 'side-by-side', 'ops' or 'json-patch'"`,
+				))
+			})
+		})
+
+		Describe("ValidateOnly", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("ValidateOnly", opts)).To(Equal(
+					`long:"validate-only" description:"Validate manifest job properties against release job specs without deploying"`,
+				))
+			})
+		})
+
+		Describe("Releases", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Releases", opts)).To(Equal(
+					`long:"release" value-name:"PATH" description:"Path to a release tarball or directory used with --validate-only"`,
+				))
+			})
+		})
+
+		Describe("OverridePolicy", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("OverridePolicy", opts)).To(Equal(
+					`long:"override-policy" value-name:"REASON" description:"Deploy despite deploy policy violations and record the reason in the task context"`,
+				))
+			})
+		})
+
 		Describe("FixReleases", func() {
 			It("contains desired values", func() {
 				Expect(getStructTagForName("FixReleases", opts)).To(Equal(
@@ -3059,6 +3841,54 @@ var _ = Describe("Opts", func() {
 		})
 	})
 
+	Describe("RenderJobOpts", func() {
+		var opts *RenderJobOpts
+
+		BeforeEach(func() {
+			opts = &RenderJobOpts{}
+		})
+
+		Describe("Directory", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Directory", opts)).To(Equal(
+					`long:"release-dir" description:"Release directory path if not current working directory" default:"."`,
+				))
+			})
+		})
+
+		Describe("Job", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Job", opts)).To(Equal(
+					`long:"job" value-name:"NAME" description:"Name of the job to render" required:"true"`,
+				))
+			})
+		})
+
+		Describe("Properties", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Properties", opts)).To(Equal(
+					`long:"properties" value-name:"PATH" description:"Path to a YAML file with job properties" required:"true"`,
+				))
+			})
+		})
+
+		Describe("Spec", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("Spec", opts)).To(Equal(
+					`long:"spec" value-name:"PATH" description:"Path to a YAML file overriding instance spec (deployment, id, index, az, bootstrap, address, networks, links)"`,
+				))
+			})
+		})
+
+		Describe("OutputDir", func() {
+			It("contains desired values", func() {
+				Expect(getStructTagForName("OutputDir", opts)).To(Equal(
+					`long:"output-dir" value-name:"DIR" description:"Destination directory for rendered templates" required:"true"`,
+				))
+			})
+		})
+	})
+
 	Describe("GeneratePackageOpts", func() {
 		var opts *GeneratePackageOpts
 
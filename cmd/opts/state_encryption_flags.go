@@ -0,0 +1,7 @@
+package opts
+
+// Shared
+type StateEncryptionFlags struct {
+	StateKeyFile    FileBytesArg `long:"state-key-file"   value-name:"PATH" env:"BOSH_STATE_KEY_FILE" description:"Encrypt state and vars-store files with a key read from a file (key may also be set via BOSH_STATE_KEY)"`
+	StatePassphrase bool         `long:"state-passphrase"                                             description:"Encrypt state and vars-store files with a passphrase prompted for"`
+}
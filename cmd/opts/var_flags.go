@@ -8,41 +8,65 @@ import (
 
 // Shared
 type VarFlags struct {
-	VarKVs      []boshtpl.VarKV       `long:"var"        short:"v" value-name:"VAR=VALUE" description:"Set variable"`
-	VarFiles    []boshtpl.VarFileArg  `long:"var-file"             value-name:"VAR=PATH"  description:"Set variable to file contents"`
-	VarsFiles   []boshtpl.VarsFileArg `long:"vars-file"  short:"l" value-name:"PATH"      description:"Load variables from a YAML file"`
-	VarsEnvs    []boshtpl.VarsEnvArg  `long:"vars-env"             value-name:"PREFIX"    description:"Load variables from environment variables (e.g.: 'MY' to load MY_var=value)"`
-	VarsFSStore VarsFSStore           `long:"vars-store"           value-name:"PATH"      description:"Load/save variables from/to a YAML file"`
+	VarKVs      []boshtpl.VarKV         `long:"var"        short:"v" value-name:"VAR=VALUE" description:"Set variable"`
+	VarFiles    []boshtpl.VarFileArg    `long:"var-file"             value-name:"VAR=PATH"  description:"Set variable to file contents"`
+	VarsFiles   []boshtpl.VarsFileArg   `long:"vars-file"  short:"l" value-name:"PATH"      description:"Load variables from a YAML file"`
+	VarsEnvs    []boshtpl.VarsEnvArg    `long:"vars-env"             value-name:"PREFIX"    description:"Load variables from environment variables (e.g.: 'MY' to load MY_var=value)"`
+	VarsFSStore VarsFSStore             `long:"vars-store"           value-name:"PATH"      description:"Load/save variables from/to a YAML file"`
+	VarsSources []boshtpl.VarsSourceArg `long:"vars-source"          value-name:"URI"       description:"Load variables from an external source (vault+https://, credhub+https:// or exec:)"`
 }
 
 func (f VarFlags) AsVariables() boshtpl.Variables {
+	return f.asVariables(false)
+}
+
+// AsSourcedVariables additionally describes which flag each variable came from.
+func (f VarFlags) AsSourcedVariables() boshtpl.Variables {
+	return f.asVariables(true)
+}
+
+func (f VarFlags) asVariables(withSources bool) boshtpl.Variables {
 	var firstToUse []boshtpl.Variables
 
 	staticVars := boshtpl.StaticVariables{}
+	staticSources := map[string]string{}
 
 	for i := range f.VarsEnvs {
 		for k, v := range f.VarsEnvs[i].Vars {
 			staticVars[k] = v
+			staticSources[k] = "--vars-env=" + f.VarsEnvs[i].Prefix
 		}
 	}
 
 	for i := range f.VarsFiles {
 		for k, v := range f.VarsFiles[i].Vars {
 			staticVars[k] = v
+			staticSources[k] = "--vars-file=" + f.VarsFiles[i].Path
 		}
 	}
 
 	for i := range f.VarFiles {
 		for k, v := range f.VarFiles[i].Vars {
 			staticVars[k] = v
+			staticSources[k] = "--var-file=" + k + "=" + f.VarFiles[i].Path
 		}
 	}
 
 	for _, kv := range f.VarKVs {
 		staticVars[kv.Name] = kv.Value
+		staticSources[kv.Name] = "--var=" + kv.Name
 	}
 
-	firstToUse = append(firstToUse, staticVars)
+	if withSources {
+		firstToUse = append(firstToUse, boshtpl.SourcedVariables{Variables: staticVars, Sources: staticSources})
+	} else {
+		firstToUse = append(firstToUse, staticVars)
+	}
+
+	// Consulted before the store so that existing secrets are not generated again
+	for i := range f.VarsSources {
+		firstToUse = append(firstToUse, f.VarsSources[i].Vars)
+	}
 
 	store := &f.VarsFSStore
 
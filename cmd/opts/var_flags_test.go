@@ -140,6 +140,81 @@ env: store
 			}
 		})
 
+		It("consults vars sources after static variables but before vars store", func() {
+			varsStore := &VarsFSStore{FS: fakesys.NewFakeFileSystem()}
+
+			err := varsStore.UnmarshalFlag("/file")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = varsStore.FS.WriteFileString("/file", `
+store: store
+source: store
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			flags := VarFlags{
+				VarKVs: []VarKV{
+					{Name: "kv", Value: "kv"},
+				},
+				VarsSources: []VarsSourceArg{
+					{Vars: StaticVariables{"kv": "source", "source": "source"}},
+					{Vars: StaticVariables{"source": "source2", "source2": "source2"}},
+				},
+				VarsFSStore: *varsStore,
+			}
+
+			vars := flags.AsVariables()
+
+			expectedVals := map[string]string{
+				"kv":      "kv",
+				"source":  "source",
+				"source2": "source2",
+				"store":   "store",
+			}
+
+			for key, expectedVal := range expectedVals {
+				val, found, err := vars.Get(VariableDefinition{Name: key})
+				Expect(val).To(Equal(expectedVal), fmt.Sprintf("Expecting key '%s' value to match", key))
+				Expect(found).To(BeTrue())
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("describes which flag each variable came from when sourced", func() {
+			varsStore := &VarsFSStore{FS: fakesys.NewFakeFileSystem()}
+
+			err := varsStore.UnmarshalFlag("/file")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = varsStore.FS.WriteFileString("/file", "store: store")
+			Expect(err).ToNot(HaveOccurred())
+
+			flags := VarFlags{
+				VarKVs:      []VarKV{{Name: "kv", Value: "kv"}},
+				VarFiles:    []VarFileArg{{Vars: StaticVariables{"var_file": "var_file"}, Path: "/var-file"}},
+				VarsFiles:   []VarsFileArg{{Vars: StaticVariables{"file": "file", "kv": "file"}, Path: "/vars-file"}},
+				VarsEnvs:    []VarsEnvArg{{Vars: StaticVariables{"env": "env"}, Prefix: "PREFIX"}},
+				VarsFSStore: *varsStore,
+			}
+
+			vars := flags.AsSourcedVariables().(MultiVars)
+
+			expectedSources := map[string]string{
+				"kv":       "--var=kv",
+				"var_file": "--var-file=var_file=/var-file",
+				"file":     "--vars-file=/vars-file",
+				"env":      "--vars-env=PREFIX",
+				"store":    "--vars-store=/file",
+			}
+
+			for key, expectedSource := range expectedSources {
+				_, found, source, err := vars.GetWithSource(VariableDefinition{Name: key})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(source).To(Equal(expectedSource), fmt.Sprintf("Expecting key '%s' source to match", key))
+			}
+		})
+
 		It("configures vars store to have ability to look up all variables for value generation", func() {
 			varsStore := &VarsFSStore{FS: fakesys.NewFakeFileSystem()}
 			err := varsStore.UnmarshalFlag("/file")
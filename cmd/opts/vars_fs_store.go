@@ -7,6 +7,7 @@ import (
 	cfgtypes "github.com/cloudfoundry/config-server/types"
 	"gopkg.in/yaml.v2"
 
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 )
 
@@ -15,6 +16,10 @@ type VarsFSStore struct {
 
 	ValueGeneratorFactory cfgtypes.ValueGeneratorFactory
 
+	// Encryptor encrypts the store on save when set.
+	// Encrypted stores are detected on load regardless.
+	Encryptor bicrypto.StateEncryptor
+
 	path string
 }
 
@@ -54,6 +59,34 @@ func (s VarsFSStore) List() ([]boshtpl.VariableDefinition, error) {
 	return vars.List()
 }
 
+// Rewrite writes an existing store back, encrypting it when Encryptor is set.
+func (s VarsFSStore) Rewrite() error {
+	if s.FS == nil {
+		s.FS = boshsys.NewOsFileSystemWithStrictTempRoot(boshlog.NewLogger(boshlog.LevelNone))
+	}
+
+	if !s.FS.FileExists(s.path) {
+		return bosherr.Errorf("Variables file store '%s' does not exist", s.path)
+	}
+
+	vars, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	return s.save(vars)
+}
+
+// Load returns all variables kept in the store.
+func (s VarsFSStore) Load() (boshtpl.StaticVariables, error) {
+	return s.load()
+}
+
+// Save replaces all variables kept in the store.
+func (s VarsFSStore) Save(vars boshtpl.StaticVariables) error {
+	return s.save(vars)
+}
+
 func (s VarsFSStore) generateAndSet(varDef boshtpl.VariableDefinition) (interface{}, error) {
 	generator, err := s.ValueGeneratorFactory.GetGenerator(varDef.Type)
 	if err != nil {
@@ -97,6 +130,17 @@ func (s VarsFSStore) load() (boshtpl.StaticVariables, error) {
 			return vars, err
 		}
 
+		if bicrypto.IsEncryptedState(bytes) {
+			if s.Encryptor == nil {
+				return vars, bosherr.Errorf("Variables file store '%s' is encrypted, expected an encryption key", s.path)
+			}
+
+			bytes, err = s.Encryptor.Decrypt(bytes)
+			if err != nil {
+				return vars, bosherr.WrapErrorf(err, "Decrypting variables file store '%s'", s.path)
+			}
+		}
+
 		err = yaml.Unmarshal(bytes, &vars)
 		if err != nil {
 			return vars, bosherr.WrapErrorf(err, "Deserializing variables file store '%s'", s.path)
@@ -119,7 +163,22 @@ func (s VarsFSStore) save(vars boshtpl.StaticVariables) error {
 		return bosherr.WrapErrorf(err, "Serializing variables")
 	}
 
-	err = s.FS.WriteFile(s.path, bytes)
+	if s.Encryptor != nil {
+		bytes, err = s.Encryptor.Encrypt(bytes)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Encrypting variables file store '%s'", s.path)
+		}
+	}
+
+	// Write next to the store and rename so that an interrupted write never truncates it
+	tmpPath := s.path + ".tmp"
+
+	err = s.FS.WriteFile(tmpPath, bytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing variables to file store '%s'", s.path)
+	}
+
+	err = s.FS.Rename(tmpPath, s.path)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Writing variables to file store '%s'", s.path)
 	}
@@ -127,6 +186,10 @@ func (s VarsFSStore) save(vars boshtpl.StaticVariables) error {
 	return nil
 }
 
+func (s VarsFSStore) Path() string { return s.path }
+
+func (s VarsFSStore) VariableSource(_ string) string { return "--vars-store=" + s.path }
+
 func (s *VarsFSStore) UnmarshalFlag(data string) error {
 	if s.FS == nil {
 		s.FS = boshsys.NewOsFileSystemWithStrictTempRoot(boshlog.NewLogger(boshlog.LevelNone))
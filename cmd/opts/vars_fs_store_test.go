@@ -10,6 +10,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
 )
 
@@ -180,6 +181,36 @@ var _ = Describe("VarsFSStore", func() {
 		})
 	})
 
+	Describe("Save", func() {
+		BeforeEach(func() {
+			err := (&store).UnmarshalFlag("/file")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("replaces store contents through a temporary file", func() {
+			err := fs.WriteFileString("/file", "key1: val")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = store.Save(boshtpl.StaticVariables{"key2": "val"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/file")).To(Equal("key2: val\n"))
+			Expect(fs.FileExists("/file.tmp")).To(BeFalse())
+		})
+
+		It("keeps existing store if renaming fails", func() {
+			err := fs.WriteFileString("/file", "key1: val")
+			Expect(err).ToNot(HaveOccurred())
+			fs.RenameError = errors.New("fake-err")
+
+			err = store.Save(boshtpl.StaticVariables{"key2": "val"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-err"))
+
+			Expect(fs.ReadFileString("/file")).To(Equal("key1: val"))
+		})
+	})
+
 	Describe("IsSet", func() {
 		It("returns true if store is configured with file path", func() {
 			err := (&store).UnmarshalFlag("/file")
@@ -207,4 +238,73 @@ var _ = Describe("VarsFSStore", func() {
 			Expect(err.Error()).To(ContainSubstring("fake-err"))
 		})
 	})
+
+	Describe("encryption", func() {
+		BeforeEach(func() {
+			err := (&store).UnmarshalFlag("/file")
+			Expect(err).ToNot(HaveOccurred())
+
+			store.Encryptor = bicrypto.NewAESGCMStateEncryptor([]byte("fake-key"))
+		})
+
+		It("encrypts generated variables when saving", func() {
+			val, found, err := store.Get(boshtpl.VariableDefinition{Name: "key", Type: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+
+			contents, err := fs.ReadFile("/file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bicrypto.IsEncryptedState(contents)).To(BeTrue())
+			Expect(string(contents)).ToNot(ContainSubstring(val.(string)))
+
+			loaded, found, err := store.Get(boshtpl.VariableDefinition{Name: "key"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(loaded).To(Equal(val))
+		})
+
+		It("loads plain stores", func() {
+			err := fs.WriteFileString("/file", "key: val")
+			Expect(err).ToNot(HaveOccurred())
+
+			val, found, err := store.Get(boshtpl.VariableDefinition{Name: "key"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("val"))
+		})
+
+		It("returns error if an encrypted store is loaded without a key", func() {
+			_, _, err := store.Get(boshtpl.VariableDefinition{Name: "key", Type: "password"})
+			Expect(err).ToNot(HaveOccurred())
+
+			store.Encryptor = nil
+
+			_, _, err = store.Get(boshtpl.VariableDefinition{Name: "key"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Variables file store '/file' is encrypted, expected an encryption key"))
+		})
+
+		Describe("Rewrite", func() {
+			It("encrypts an existing plain store in place", func() {
+				err := fs.WriteFileString("/file", "key: val")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(store.Rewrite()).To(Succeed())
+
+				contents, err := fs.ReadFile("/file")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bicrypto.IsEncryptedState(contents)).To(BeTrue())
+
+				val, _, err := store.Get(boshtpl.VariableDefinition{Name: "key"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(val).To(Equal("val"))
+			})
+
+			It("returns error if the store does not exist", func() {
+				err := store.Rewrite()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Variables file store '/file' does not exist"))
+			})
+		})
+	})
 })
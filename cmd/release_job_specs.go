@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+
+	boshrel "github.com/cloudfoundry/bosh-cli/v7/release"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	boshreldir "github.com/cloudfoundry/bosh-cli/v7/releasedir"
+)
+
+type ReleaseJobSpecs struct {
+	Name string
+	Jobs map[string]boshjobman.Manifest
+}
+
+// ReleaseJobSpecsReader reads job specs out of release tarballs or release directories
+// without building them.
+type ReleaseJobSpecsReader struct {
+	archiveReader     boshrel.Reader
+	releaseDirFactory func(string) boshreldir.ReleaseDir
+	fs                boshsys.FileSystem
+}
+
+func NewReleaseJobSpecsReader(
+	archiveReader boshrel.Reader,
+	releaseDirFactory func(string) boshreldir.ReleaseDir,
+	fs boshsys.FileSystem,
+) ReleaseJobSpecsReader {
+	return ReleaseJobSpecsReader{
+		archiveReader:     archiveReader,
+		releaseDirFactory: releaseDirFactory,
+		fs:                fs,
+	}
+}
+
+func (r ReleaseJobSpecsReader) Read(path string) (ReleaseJobSpecs, error) {
+	expandedPath, err := r.fs.ExpandPath(path)
+	if err != nil {
+		return ReleaseJobSpecs{}, bosherr.WrapErrorf(err, "Expanding release path '%s'", path)
+	}
+
+	fileInfo, err := r.fs.Stat(expandedPath)
+	if err != nil {
+		return ReleaseJobSpecs{}, bosherr.WrapErrorf(err, "Checking release path '%s'", path)
+	}
+
+	if fileInfo.IsDir() {
+		return r.readDir(expandedPath)
+	}
+
+	return r.readArchive(expandedPath)
+}
+
+func (r ReleaseJobSpecsReader) readDir(path string) (ReleaseJobSpecs, error) {
+	name, err := r.releaseDirFactory(path).DefaultName()
+	if err != nil {
+		return ReleaseJobSpecs{}, bosherr.WrapErrorf(err, "Determining release name of '%s'", path)
+	}
+
+	specPaths, err := r.fs.Glob(filepath.Join(path, "jobs", "*", "spec"))
+	if err != nil {
+		return ReleaseJobSpecs{}, bosherr.WrapErrorf(err, "Finding job specs in '%s'", path)
+	}
+
+	specs := ReleaseJobSpecs{Name: name, Jobs: map[string]boshjobman.Manifest{}}
+
+	for _, specPath := range specPaths {
+		spec, err := boshjobman.NewManifestFromPath(specPath, r.fs)
+		if err != nil {
+			return ReleaseJobSpecs{}, err
+		}
+
+		specs.Jobs[spec.Name] = spec
+	}
+
+	return specs, nil
+}
+
+func (r ReleaseJobSpecsReader) readArchive(path string) (specs ReleaseJobSpecs, err error) {
+	release, err := r.archiveReader.Read(path)
+	if err != nil {
+		return ReleaseJobSpecs{}, bosherr.WrapErrorf(err, "Reading release '%s'", path)
+	}
+
+	defer func() {
+		if cleanUpErr := release.CleanUp(); cleanUpErr != nil && err == nil {
+			err = bosherr.WrapErrorf(cleanUpErr, "Cleaning up release '%s'", path)
+		}
+	}()
+
+	specs = ReleaseJobSpecs{Name: release.Name(), Jobs: map[string]boshjobman.Manifest{}}
+
+	for _, job := range release.Jobs() {
+		spec, err := boshjobman.NewManifestFromPath(filepath.Join(job.ExtractedPath(), "job.MF"), r.fs)
+		if err != nil {
+			return ReleaseJobSpecs{}, err
+		}
+
+		specs.Jobs[job.Name()] = spec
+	}
+
+	return specs, nil
+}
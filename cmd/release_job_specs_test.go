@@ -0,0 +1,149 @@
+package cmd_test
+
+import (
+	"errors"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	boshjob "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	fakerel "github.com/cloudfoundry/bosh-cli/v7/release/releasefakes"
+	boshres "github.com/cloudfoundry/bosh-cli/v7/release/resource"
+	boshreldir "github.com/cloudfoundry/bosh-cli/v7/releasedir"
+	fakereldir "github.com/cloudfoundry/bosh-cli/v7/releasedir/releasedirfakes"
+)
+
+var _ = Describe("ReleaseJobSpecsReader", func() {
+	var (
+		archiveReader *fakerel.FakeReader
+		releaseDir    *fakereldir.FakeReleaseDir
+		fs            *fakesys.FakeFileSystem
+		reader        cmd.ReleaseJobSpecsReader
+	)
+
+	BeforeEach(func() {
+		archiveReader = &fakerel.FakeReader{}
+		releaseDir = &fakereldir.FakeReleaseDir{}
+		fs = fakesys.NewFakeFileSystem()
+
+		releaseDirFactory := func(path string) boshreldir.ReleaseDir {
+			Expect(path).To(Equal("/release-dir"))
+			return releaseDir
+		}
+
+		reader = cmd.NewReleaseJobSpecsReader(archiveReader, releaseDirFactory, fs)
+	})
+
+	Describe("Read", func() {
+		Context("when path is a release directory", func() {
+			BeforeEach(func() {
+				Expect(fs.MkdirAll("/release-dir", 0700)).To(Succeed())
+				releaseDir.DefaultNameReturns("rel", nil)
+
+				fs.SetGlob("/release-dir/jobs/*/spec", []string{"/release-dir/jobs/job1/spec"})
+				Expect(fs.WriteFileString("/release-dir/jobs/job1/spec", `
+name: job1
+properties:
+  port: {default: 80}
+`)).To(Succeed())
+			})
+
+			It("reads job specs from jobs directory", func() {
+				specs, err := reader.Read("/release-dir")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(specs).To(Equal(cmd.ReleaseJobSpecs{
+					Name: "rel",
+					Jobs: map[string]boshjobman.Manifest{
+						"job1": {
+							Name:       "job1",
+							Properties: map[string]boshjobman.PropertyDefinition{"port": {Default: 80}},
+						},
+					},
+				}))
+			})
+
+			It("returns error if release name cannot be determined", func() {
+				releaseDir.DefaultNameReturns("", errors.New("fake-err"))
+
+				_, err := reader.Read("/release-dir")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-err"))
+			})
+
+			It("returns error if job spec cannot be read", func() {
+				fs.ReadFileError = errors.New("fake-err")
+
+				_, err := reader.Read("/release-dir")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-err"))
+			})
+		})
+
+		Context("when path is a release tarball", func() {
+			var (
+				release *fakerel.FakeRelease
+			)
+
+			BeforeEach(func() {
+				Expect(fs.WriteFileString("/release.tgz", "")).To(Succeed())
+
+				release = &fakerel.FakeRelease{}
+				release.NameReturns("rel")
+				release.JobsReturns([]*boshjob.Job{
+					boshjob.NewExtractedJob(boshres.NewResourceWithBuiltArchive("job1", "fp", "path", "sha1"), "/extracted/job1", fs),
+				})
+				archiveReader.ReadReturns(release, nil)
+
+				Expect(fs.WriteFileString("/extracted/job1/job.MF", `
+name: job1
+properties:
+  port: {}
+`)).To(Succeed())
+			})
+
+			It("reads job specs from extracted jobs and cleans up", func() {
+				specs, err := reader.Read("/release.tgz")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(specs).To(Equal(cmd.ReleaseJobSpecs{
+					Name: "rel",
+					Jobs: map[string]boshjobman.Manifest{
+						"job1": {
+							Name:       "job1",
+							Properties: map[string]boshjobman.PropertyDefinition{"port": {}},
+						},
+					},
+				}))
+
+				Expect(archiveReader.ReadArgsForCall(0)).To(Equal("/release.tgz"))
+				Expect(release.CleanUpCallCount()).To(Equal(1))
+			})
+
+			It("returns error if release cannot be read", func() {
+				archiveReader.ReadReturns(nil, errors.New("fake-err"))
+
+				_, err := reader.Read("/release.tgz")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-err"))
+			})
+
+			It("returns error if release cannot be cleaned up", func() {
+				release.CleanUpReturns(errors.New("fake-err"))
+
+				_, err := reader.Read("/release.tgz")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Cleaning up release"))
+			})
+		})
+
+		It("returns error if path cannot be expanded", func() {
+			fs.ExpandPathErr = errors.New("fake-err")
+
+			_, err := reader.Read("~/release.tgz")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expanding release path '~/release.tgz'"))
+		})
+	})
+})
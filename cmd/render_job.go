@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"path/filepath"
+	"sort"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshjob "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	bitemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+type ReleaseJobSourceReader interface {
+	ReadSource(path string) (*boshjob.Job, error)
+}
+
+// RenderJobCmd renders templates of a job from a release directory
+// without building the release or talking to the Director.
+type RenderJobCmd struct {
+	jobReader   ReleaseJobSourceReader
+	jobRenderer bitemplate.JobRenderer
+	fs          boshsys.FileSystem
+	ui          boshui.UI
+}
+
+type renderJobSpec struct {
+	Deployment string `yaml:"deployment"`
+
+	ID        string `yaml:"id"`
+	Index     int    `yaml:"index"`
+	AZ        string `yaml:"az"`
+	Bootstrap *bool  `yaml:"bootstrap"`
+	Address   string `yaml:"address"`
+
+	Networks map[string]renderJobSpecNetwork `yaml:"networks"`
+	Links    map[string]renderJobSpecLink    `yaml:"links"`
+}
+
+type renderJobSpecNetwork struct {
+	IP      string `yaml:"ip"`
+	Netmask string `yaml:"netmask"`
+	Gateway string `yaml:"gateway"`
+}
+
+type renderJobSpecLink struct {
+	Address        string                      `yaml:"address"`
+	InstanceGroup  string                      `yaml:"instance_group"`
+	DefaultNetwork string                      `yaml:"default_network"`
+	DeploymentName string                      `yaml:"deployment_name"`
+	Domain         string                      `yaml:"domain"`
+	Instances      []renderJobSpecLinkInstance `yaml:"instances"`
+	Properties     map[interface{}]interface{} `yaml:"properties"`
+}
+
+type renderJobSpecLinkInstance struct {
+	Name      string `yaml:"name"`
+	ID        string `yaml:"id"`
+	Index     int    `yaml:"index"`
+	AZ        string `yaml:"az"`
+	Address   string `yaml:"address"`
+	Bootstrap bool   `yaml:"bootstrap"`
+}
+
+func NewRenderJobCmd(
+	jobReader ReleaseJobSourceReader,
+	jobRenderer bitemplate.JobRenderer,
+	fs boshsys.FileSystem,
+	ui boshui.UI,
+) RenderJobCmd {
+	return RenderJobCmd{jobReader: jobReader, jobRenderer: jobRenderer, fs: fs, ui: ui}
+}
+
+func (c RenderJobCmd) Run(opts RenderJobOpts) error {
+	job, err := c.jobReader.ReadSource(filepath.Join(opts.Directory.Path, "jobs", opts.Job))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading job '%s'", opts.Job)
+	}
+
+	properties, err := c.properties(opts.Properties.Bytes)
+	if err != nil {
+		return err
+	}
+
+	spec, instance, err := c.instanceSpec(opts.Spec.Bytes)
+	if err != nil {
+		return err
+	}
+
+	renderedJob, err := c.jobRenderer.RenderInstance(*job, &properties, biproperty.Map{}, biproperty.Map{}, spec.Deployment, instance)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Rendering job '%s'", opts.Job)
+	}
+
+	defer renderedJob.DeleteSilently()
+
+	err = c.fs.CopyDir(renderedJob.Path(), opts.OutputDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Copying rendered job to '%s'", opts.OutputDir)
+	}
+
+	c.printTable(*job, opts.OutputDir)
+
+	return nil
+}
+
+func (c RenderJobCmd) properties(bytes []byte) (biproperty.Map, error) {
+	var rawProperties map[interface{}]interface{}
+
+	err := yaml.Unmarshal(bytes, &rawProperties)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling job properties")
+	}
+
+	properties, err := biproperty.BuildMap(rawProperties)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing job properties")
+	}
+
+	return properties, nil
+}
+
+func (c RenderJobCmd) instanceSpec(bytes []byte) (renderJobSpec, bitemplate.InstanceSpec, error) {
+	var spec renderJobSpec
+
+	err := yaml.Unmarshal(bytes, &spec)
+	if err != nil {
+		return spec, bitemplate.InstanceSpec{}, bosherr.WrapErrorf(err, "Unmarshalling instance spec")
+	}
+
+	instance := bitemplate.InstanceSpec{
+		ID:        spec.ID,
+		Index:     spec.Index,
+		AZ:        spec.AZ,
+		Bootstrap: spec.Bootstrap,
+		Address:   spec.Address,
+	}
+
+	if len(spec.Networks) > 0 {
+		instance.Networks = map[string]bitemplate.NetworkSpec{}
+
+		for name, network := range spec.Networks {
+			instance.Networks[name] = bitemplate.NetworkSpec(network)
+		}
+	}
+
+	if len(spec.Links) > 0 {
+		instance.Links = map[string]bitemplate.LinkSpec{}
+
+		for name, link := range spec.Links {
+			properties, err := biproperty.BuildMap(link.Properties)
+			if err != nil {
+				return spec, bitemplate.InstanceSpec{}, bosherr.WrapErrorf(err, "Parsing link '%s' properties", name)
+			}
+
+			linkSpec := bitemplate.LinkSpec{
+				Address:        link.Address,
+				InstanceGroup:  link.InstanceGroup,
+				DefaultNetwork: link.DefaultNetwork,
+				DeploymentName: link.DeploymentName,
+				Domain:         link.Domain,
+				Instances:      []bitemplate.LinkInstanceSpec{},
+				Properties:     properties,
+			}
+
+			for _, linkInstance := range link.Instances {
+				linkSpec.Instances = append(linkSpec.Instances, bitemplate.LinkInstanceSpec(linkInstance))
+			}
+
+			instance.Links[name] = linkSpec
+		}
+	}
+
+	return spec, instance, nil
+}
+
+func (c RenderJobCmd) printTable(job boshjob.Job, outputDir string) {
+	table := boshtbl.Table{
+		Content: "templates",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Template"),
+			boshtbl.NewHeader("Path"),
+		},
+	}
+
+	var srcs []string
+
+	for src := range job.Templates {
+		srcs = append(srcs, src)
+	}
+
+	sort.Strings(srcs)
+
+	for _, src := range srcs {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(src),
+			boshtbl.NewValueString(filepath.Join(outputDir, job.Templates[src])),
+		})
+	}
+
+	table.Rows = append(table.Rows, []boshtbl.Value{
+		boshtbl.NewValueString("monit"),
+		boshtbl.NewValueString(filepath.Join(outputDir, "monit")),
+	})
+
+	c.ui.PrintTable(table)
+}
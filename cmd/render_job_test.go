@@ -0,0 +1,205 @@
+package cmd_test
+
+import (
+	"errors"
+
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshjob "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	bitemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler"
+	mocktemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler/mocks"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("RenderJobCmd", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		ui          *fakeui.FakeUI
+		jobRenderer *mocktemplate.MockJobRenderer
+		renderedJob *mocktemplate.MockRenderedJob
+		command     cmd.RenderJobCmd
+	)
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+
+		fs = fakesys.NewFakeFileSystem()
+		ui = &fakeui.FakeUI{}
+		jobRenderer = mocktemplate.NewMockJobRenderer(mockCtrl)
+		renderedJob = mocktemplate.NewMockRenderedJob(mockCtrl)
+
+		command = cmd.NewRenderJobCmd(boshjob.NewDirReaderImpl(nil, fs), jobRenderer, fs, ui)
+
+		err := fs.WriteFileString("/release/jobs/web/spec", `---
+name: web
+templates:
+  config.yml.erb: config/config.yml
+  ctl.erb: bin/ctl
+properties:
+  port: {default: 8080}
+`)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("Run", func() {
+		var (
+			renderJobOpts opts.RenderJobOpts
+		)
+
+		BeforeEach(func() {
+			renderJobOpts = opts.RenderJobOpts{
+				Directory:  opts.DirOrCWDArg{Path: "/release"},
+				Job:        "web",
+				Properties: opts.FileBytesArg{Bytes: []byte("port: 9090\ndb: {name: app}")},
+				OutputDir:  "/out",
+			}
+		})
+
+		act := func() error { return command.Run(renderJobOpts) }
+
+		It("renders job with given properties into output directory", func() {
+			Expect(fs.WriteFileString("/rendered/config/config.yml", "port: 9090")).To(Succeed())
+
+			jobRenderer.EXPECT().RenderInstance(gomock.Any(), gomock.Any(), biproperty.Map{}, biproperty.Map{}, "", bitemplate.InstanceSpec{}).DoAndReturn(
+				func(job boshjob.Job, properties *biproperty.Map, _, _ biproperty.Map, _ string, _ bitemplate.InstanceSpec) (bitemplate.RenderedJob, error) {
+					Expect(job.Name()).To(Equal("web"))
+					Expect(job.ExtractedPath()).To(Equal("/release/jobs/web"))
+					Expect(job.Properties["port"].Default).To(Equal(8080))
+
+					Expect(*properties).To(Equal(biproperty.Map{
+						"port": 9090,
+						"db":   biproperty.Map{"name": "app"},
+					}))
+
+					return renderedJob, nil
+				},
+			)
+
+			renderedJob.EXPECT().Path().Return("/rendered")
+			renderedJob.EXPECT().DeleteSilently()
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+
+			content, err := fs.ReadFileString("/out/config/config.yml")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(Equal("port: 9090"))
+
+			Expect(ui.Table).To(Equal(boshtbl.Table{
+				Content: "templates",
+				Header: []boshtbl.Header{
+					boshtbl.NewHeader("Template"),
+					boshtbl.NewHeader("Path"),
+				},
+				Rows: [][]boshtbl.Value{
+					{boshtbl.NewValueString("config.yml.erb"), boshtbl.NewValueString("/out/config/config.yml")},
+					{boshtbl.NewValueString("ctl.erb"), boshtbl.NewValueString("/out/bin/ctl")},
+					{boshtbl.NewValueString("monit"), boshtbl.NewValueString("/out/monit")},
+				},
+			}))
+		})
+
+		It("overrides instance spec values from spec file", func() {
+			renderJobOpts.Spec = opts.FileBytesArg{Bytes: []byte(`
+deployment: dep
+id: web-id
+index: 3
+az: z2
+bootstrap: false
+address: web.bosh
+networks:
+  private: {ip: 10.0.0.5, netmask: 255.255.255.0, gateway: 10.0.0.1}
+links:
+  db:
+    address: db.bosh
+    instance_group: db
+    instances:
+    - {name: db, id: db-id, index: 0, az: z1, address: 10.0.0.6, bootstrap: true}
+    properties:
+      port: 5432
+`)}
+
+			bootstrap := false
+
+			expectedInstance := bitemplate.InstanceSpec{
+				ID:        "web-id",
+				Index:     3,
+				AZ:        "z2",
+				Bootstrap: &bootstrap,
+				Address:   "web.bosh",
+				Networks: map[string]bitemplate.NetworkSpec{
+					"private": {IP: "10.0.0.5", Netmask: "255.255.255.0", Gateway: "10.0.0.1"},
+				},
+				Links: map[string]bitemplate.LinkSpec{
+					"db": {
+						Address:       "db.bosh",
+						InstanceGroup: "db",
+						Instances: []bitemplate.LinkInstanceSpec{
+							{Name: "db", ID: "db-id", Index: 0, AZ: "z1", Address: "10.0.0.6", Bootstrap: true},
+						},
+						Properties: biproperty.Map{"port": 5432},
+					},
+				},
+			}
+
+			jobRenderer.EXPECT().RenderInstance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "dep", expectedInstance).Return(renderedJob, nil)
+
+			renderedJob.EXPECT().Path().Return("/rendered")
+			renderedJob.EXPECT().DeleteSilently()
+
+			Expect(act()).To(Succeed())
+		})
+
+		It("returns error if job cannot be read", func() {
+			renderJobOpts.Job = "unknown"
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading job 'unknown'"))
+		})
+
+		It("returns error if properties cannot be parsed", func() {
+			renderJobOpts.Properties = opts.FileBytesArg{Bytes: []byte("-")}
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling job properties"))
+		})
+
+		It("returns error if spec cannot be parsed", func() {
+			renderJobOpts.Spec = opts.FileBytesArg{Bytes: []byte("index: not-int")}
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling instance spec"))
+		})
+
+		It("returns error if rendering fails", func() {
+			jobRenderer.EXPECT().RenderInstance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("fake-err"))
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Rendering job 'web': fake-err"))
+		})
+
+		It("returns error if copying rendered job fails", func() {
+			fs.CopyDirError = errors.New("fake-err")
+
+			jobRenderer.EXPECT().RenderInstance(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(renderedJob, nil)
+
+			renderedJob.EXPECT().Path().Return("/rendered")
+			renderedJob.EXPECT().DeleteSilently()
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Copying rendered job to '/out': fake-err"))
+		})
+	})
+})
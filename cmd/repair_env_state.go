@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/cppforlife/go-patch/patch"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type RepairEnvStateCmd struct {
+	ui          boshui.UI
+	envProvider func(string, string, boshtpl.Variables, patch.Op) DeploymentStateRepairer
+}
+
+func NewRepairEnvStateCmd(ui boshui.UI, envProvider func(string, string, boshtpl.Variables, patch.Op) DeploymentStateRepairer) *RepairEnvStateCmd {
+	return &RepairEnvStateCmd{ui: ui, envProvider: envProvider}
+}
+
+func (c *RepairEnvStateCmd) Run(stage boshui.Stage, opts RepairEnvStateOpts) error {
+	c.ui.BeginLinef("Deployment manifest: '%s'\n", opts.Args.Manifest.Path)
+
+	repairer := c.envProvider(
+		opts.Args.Manifest.Path,
+		opts.StatePath,
+		opts.VarFlags.AsVariables(), //nolint:staticcheck
+		opts.OpsFlags.AsOp(),        //nolint:staticcheck
+	)
+
+	return repairer.RepairDeploymentState(RepairCIDs{
+		VM:       opts.VMCID,
+		Disk:     opts.DiskCID,
+		Stemcell: opts.StemcellCID,
+	}, stage)
+}
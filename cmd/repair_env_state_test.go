@@ -0,0 +1,95 @@
+package cmd_test
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cppforlife/go-patch/patch"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	mockcmd "github.com/cloudfoundry/bosh-cli/v7/cmd/mocks"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("RepairEnvStateCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Run", func() {
+		var (
+			mockRepairer           *mockcmd.MockDeploymentStateRepairer
+			fakeUI                 *fakeui.FakeUI
+			fakeStage              *fakeui.FakeStage
+			deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+			statePath              string
+			repairOpts             opts.RepairEnvStateOpts
+		)
+
+		var newRepairEnvStateCmd = func() *cmd.RepairEnvStateCmd {
+			doGetFunc := func(manifestPath string, statePath_ string, vars boshtpl.Variables, op patch.Op) cmd.DeploymentStateRepairer {
+				Expect(manifestPath).To(Equal(deploymentManifestPath))
+				Expect(vars).To(Equal(boshtpl.NewMultiVars([]boshtpl.Variables{boshtpl.StaticVariables{"key": "value"}})))
+				Expect(op).To(Equal(patch.Ops{patch.ErrOp{}}))
+				statePath = statePath_
+				return mockRepairer
+			}
+
+			return cmd.NewRepairEnvStateCmd(fakeUI, doGetFunc)
+		}
+
+		BeforeEach(func() {
+			mockRepairer = mockcmd.NewMockDeploymentStateRepairer(mockCtrl)
+			fakeUI = &fakeui.FakeUI{}
+			fakeStage = fakeui.NewFakeStage()
+
+			repairOpts = opts.RepairEnvStateOpts{
+				Args: opts.RepairEnvStateArgs{
+					Manifest: opts.FileBytesWithPathArg{Path: deploymentManifestPath},
+				},
+				StatePath:   "/fake-state.json",
+				VMCID:       "fake-vm-cid",
+				DiskCID:     "fake-disk-cid",
+				StemcellCID: "fake-stemcell-cid",
+				VarFlags: opts.VarFlags{
+					VarKVs: []boshtpl.VarKV{{Name: "key", Value: "value"}},
+				},
+				OpsFlags: opts.OpsFlags{
+					OpsFiles: []opts.OpsFileArg{
+						{Ops: []patch.Op{patch.ErrOp{}}},
+					},
+				},
+			}
+		})
+
+		It("passes the CIDs on to the repairer", func() {
+			mockRepairer.EXPECT().RepairDeploymentState(cmd.RepairCIDs{
+				VM:       "fake-vm-cid",
+				Disk:     "fake-disk-cid",
+				Stemcell: "fake-stemcell-cid",
+			}, fakeStage).Return(nil)
+
+			err := newRepairEnvStateCmd().Run(fakeStage, repairOpts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statePath).To(Equal("/fake-state.json"))
+		})
+
+		It("returns an error when the repairer fails", func() {
+			mockRepairer.EXPECT().RepairDeploymentState(gomock.Any(), fakeStage).Return(bosherr.Error("boom"))
+
+			err := newRepairEnvStateCmd().Run(fakeStage, repairOpts)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("boom"))
+		})
+	})
+})
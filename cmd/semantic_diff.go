@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdiff "github.com/cloudfoundry/bosh-cli/v7/diff"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+// SemanticDiff renders structural differences computed on the client
+// as opposed to Diff which renders line differences from the director.
+type SemanticDiff struct {
+	from    []byte
+	changes []boshdiff.Change
+}
+
+// NewSemanticDiff takes the first compared document since JSON patch
+// addresses array items by their index in it.
+func NewSemanticDiff(from []byte, changes []boshdiff.Change) SemanticDiff {
+	return SemanticDiff{from: from, changes: changes}
+}
+
+func (d SemanticDiff) Print(ui boshui.UI, format DiffFormatArg) error {
+	switch format {
+	case DiffFormatSideBySide:
+		d.printSideBySide(ui)
+
+	case DiffFormatOps:
+		bytes, err := boshdiff.OpsJSON(d.changes)
+		if err != nil {
+			return err
+		}
+
+		ui.PrintBlock(append(bytes, '\n'))
+
+	case DiffFormatJSONPatch:
+		bytes, err := boshdiff.JSONPatch(d.from, d.changes)
+		if err != nil {
+			return err
+		}
+
+		ui.PrintBlock(append(bytes, '\n'))
+
+	default:
+		d.printUnified(ui)
+	}
+
+	return nil
+}
+
+// printUnified prints a block so that the diff is shown even when output is redirected.
+func (d SemanticDiff) printUnified(ui boshui.UI) {
+	lineFuncs := map[boshdiff.LineType]func(...interface{}) string{
+		boshdiff.LineHeader:  color.New(color.FgCyan).SprintFunc(),
+		boshdiff.LineAdded:   color.New(color.FgGreen).SprintFunc(),
+		boshdiff.LineRemoved: color.New(color.FgRed).SprintFunc(),
+	}
+
+	var block strings.Builder
+
+	for _, line := range boshdiff.UnifiedLines(d.changes) {
+		block.WriteString(lineFuncs[line.Type](line.Text) + "\n")
+	}
+
+	if block.Len() > 0 {
+		ui.PrintBlock([]byte(block.String()))
+	}
+}
+
+func (d SemanticDiff) printSideBySide(ui boshui.UI) {
+	table := boshtbl.Table{
+		Content: "differences",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Path"),
+			boshtbl.NewHeader("Change"),
+			boshtbl.NewHeader("From"),
+			boshtbl.NewHeader("To"),
+		},
+	}
+
+	for _, change := range d.changes {
+		from, to := boshdiff.FormatValue(change.From), boshdiff.FormatValue(change.To)
+
+		switch change.Type {
+		case boshdiff.ChangeAdded:
+			from = ""
+		case boshdiff.ChangeRemoved:
+			to = ""
+		}
+
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(change.Path.String()),
+			boshtbl.NewValueString(string(change.Type)),
+			boshtbl.NewValueString(from),
+			boshtbl.NewValueString(to),
+		})
+	}
+
+	ui.PrintTable(table)
+}
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
@@ -66,6 +69,7 @@ func (c SessionImpl) UAA() (boshuaa.UAA, error) {
 	}
 
 	uaaConfig.CACert = c.context.CACert()
+	uaaConfig.HTTP = c.context.HTTP()
 
 	creds := c.Credentials()
 	uaaConfig.Client = creds.Client
@@ -89,6 +93,8 @@ func (c *SessionImpl) Director() (boshdir.Director, error) {
 	}
 
 	dirConfig.CACert = c.context.CACert()
+	dirConfig.HTTP = c.context.HTTP()
+	dirConfig.ResumableUploadsDir = filepath.Join(os.Getenv("HOME"), ".bosh", "uploads")
 
 	creds := c.Credentials()
 
@@ -159,6 +165,7 @@ func (c *SessionImpl) AnonymousDirector() (boshdir.Director, error) {
 	}
 
 	dirConfig.CACert = c.context.CACert()
+	dirConfig.HTTP = c.context.HTTP()
 
 	return boshdir.NewFactory(c.logger).New(dirConfig, nil, nil)
 }
@@ -5,6 +5,7 @@ import (
 
 	cmdconf "github.com/cloudfoundry/bosh-cli/v7/cmd/config"
 	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 )
 
 // SessionContextImpl prefers options over config values
@@ -50,6 +51,10 @@ func (c SessionContextImpl) CACert() string {
 	return c.config.CACert(c.Environment())
 }
 
+func (c SessionContextImpl) HTTP() bihttpclient.Opts {
+	return c.config.HTTP(c.Environment())
+}
+
 func (c SessionContextImpl) Deployment() string {
 	return c.opts.DeploymentOpt
 }
@@ -9,6 +9,7 @@ import (
 	cmdconf "github.com/cloudfoundry/bosh-cli/v7/cmd/config"
 	fakeconf "github.com/cloudfoundry/bosh-cli/v7/cmd/config/configfakes"
 	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 )
 
 var _ = Describe("SessionContextImpl", func() {
@@ -109,6 +110,17 @@ var _ = Describe("SessionContextImpl", func() {
 		})
 	})
 
+	Describe("HTTP", func() {
+		It("returns config value for resolved environment", func() {
+			boshOpts.EnvironmentOpt = "opt-url"
+			config.ResolveEnvironmentStub = func(string) string { return "resolved-url" }
+			config.HTTPReturns(bihttpclient.Opts{MaxAttempts: 10})
+
+			Expect(build().HTTP()).To(Equal(bihttpclient.Opts{MaxAttempts: 10}))
+			Expect(config.HTTPArgsForCall(0)).To(Equal("resolved-url"))
+		})
+	})
+
 	Describe("Deployment", func() {
 		It("returns global option if provided", func() {
 			boshOpts.DeploymentOpt = "opt-dep"
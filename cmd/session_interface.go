@@ -2,6 +2,7 @@ package cmd
 
 import (
 	cmdconf "github.com/cloudfoundry/bosh-cli/v7/cmd/config"
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
 	boshuaa "github.com/cloudfoundry/bosh-cli/v7/uaa"
 )
@@ -11,6 +12,7 @@ import (
 type SessionContext interface {
 	Environment() string
 	CACert() string
+	HTTP() bihttpclient.Opts
 	Config() cmdconf.Config
 	Credentials() cmdconf.Creds
 
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+// NewStateEncryptor builds an encryptor from --state-key-file, BOSH_STATE_KEY
+// or --state-passphrase. It returns nil when none of them is given, so state and
+// vars-store files are written in plain text.
+func NewStateEncryptor(flags StateEncryptionFlags, ui biui.UI) (bicrypto.StateEncryptor, error) {
+	var sources []string
+	var secret []byte
+
+	if flags.StateKeyFile.Bytes != nil {
+		sources = append(sources, "--state-key-file")
+		secret = bytes.TrimSpace(flags.StateKeyFile.Bytes)
+	}
+
+	if key := os.Getenv("BOSH_STATE_KEY"); key != "" {
+		sources = append(sources, "BOSH_STATE_KEY")
+		secret = []byte(strings.TrimSpace(key))
+	}
+
+	if flags.StatePassphrase {
+		sources = append(sources, "--state-passphrase")
+
+		passphrase, err := ui.AskForPassword("State passphrase")
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Asking for state passphrase")
+		}
+
+		secret = []byte(passphrase)
+	}
+
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	if len(sources) > 1 {
+		return nil, bosherr.Errorf("Expected only one state encryption key, got %s", strings.Join(sources, ", "))
+	}
+
+	if len(secret) == 0 {
+		return nil, bosherr.Errorf("Expected state encryption key from %s to be non-empty", sources[0])
+	}
+
+	return bicrypto.NewAESGCMStateEncryptor(secret), nil
+}
@@ -0,0 +1,91 @@
+package cmd_test
+
+import (
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("NewStateEncryptor", func() {
+	var ui *fakeui.FakeUI
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		Expect(os.Unsetenv("BOSH_STATE_KEY")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv("BOSH_STATE_KEY")).To(Succeed())
+	})
+
+	var roundTrips = func(encryptor bicrypto.StateEncryptor, secret string) {
+		encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = bicrypto.NewAESGCMStateEncryptor([]byte(secret)).Decrypt(encrypted)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("returns nil when no key is given", func() {
+		encryptor, err := cmd.NewStateEncryptor(opts.StateEncryptionFlags{}, ui)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encryptor).To(BeNil())
+	})
+
+	It("uses the key file without surrounding whitespace", func() {
+		flags := opts.StateEncryptionFlags{StateKeyFile: opts.FileBytesArg{Bytes: []byte("fake-key\n")}}
+
+		encryptor, err := cmd.NewStateEncryptor(flags, ui)
+		Expect(err).ToNot(HaveOccurred())
+		roundTrips(encryptor, "fake-key")
+	})
+
+	It("uses BOSH_STATE_KEY", func() {
+		Expect(os.Setenv("BOSH_STATE_KEY", "fake-env-key")).To(Succeed())
+
+		encryptor, err := cmd.NewStateEncryptor(opts.StateEncryptionFlags{}, ui)
+		Expect(err).ToNot(HaveOccurred())
+		roundTrips(encryptor, "fake-env-key")
+	})
+
+	It("asks for a passphrase", func() {
+		ui.AskedPasswords = []fakeui.Answer{{Text: "fake-passphrase"}}
+
+		encryptor, err := cmd.NewStateEncryptor(opts.StateEncryptionFlags{StatePassphrase: true}, ui)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ui.AskedPasswordLabels).To(Equal([]string{"State passphrase"}))
+		roundTrips(encryptor, "fake-passphrase")
+	})
+
+	It("returns an error when asking for a passphrase fails", func() {
+		ui.AskedPasswords = []fakeui.Answer{{Error: errors.New("fake-err")}}
+
+		_, err := cmd.NewStateEncryptor(opts.StateEncryptionFlags{StatePassphrase: true}, ui)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-err"))
+	})
+
+	It("returns an error when more than one key is given", func() {
+		Expect(os.Setenv("BOSH_STATE_KEY", "fake-env-key")).To(Succeed())
+		flags := opts.StateEncryptionFlags{StateKeyFile: opts.FileBytesArg{Bytes: []byte("fake-key")}}
+
+		_, err := cmd.NewStateEncryptor(flags, ui)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected only one state encryption key, got --state-key-file, BOSH_STATE_KEY"))
+	})
+
+	It("returns an error when the key is empty", func() {
+		flags := opts.StateEncryptionFlags{StateKeyFile: opts.FileBytesArg{Bytes: []byte("\n")}}
+
+		_, err := cmd.NewStateEncryptor(flags, ui)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected state encryption key from --state-key-file to be non-empty"))
+	})
+})
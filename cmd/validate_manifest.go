@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+type ValidateManifestCmd struct {
+	validator ManifestValidator
+}
+
+func NewValidateManifestCmd(validator ManifestValidator) ValidateManifestCmd {
+	return ValidateManifestCmd{validator: validator}
+}
+
+func (c ValidateManifestCmd) Run(opts ValidateManifestOpts) error {
+	tpl := boshtpl.NewTemplate(opts.Args.Manifest.Bytes)
+
+	bytes, err := tpl.Evaluate(opts.VarFlags.AsVariables(), opts.OpsFlags.AsOp(), boshtpl.EvaluateOpts{}) //nolint:staticcheck
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Evaluating manifest")
+	}
+
+	problems, err := c.validator.Validate(bytes, opts.Releases)
+	if err != nil {
+		return err
+	}
+
+	return c.validator.PrintProblems(problems)
+}
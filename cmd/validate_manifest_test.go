@@ -0,0 +1,92 @@
+package cmd_test
+
+import (
+	"errors"
+
+	"github.com/cppforlife/go-patch/patch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/cmdfakes"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+var _ = Describe("ValidateManifestCmd", func() {
+	var (
+		specsLoader *cmdfakes.FakeReleaseJobSpecsLoader
+		ui          *fakeui.FakeUI
+		command     cmd.ValidateManifestCmd
+	)
+
+	BeforeEach(func() {
+		specsLoader = &cmdfakes.FakeReleaseJobSpecsLoader{}
+		ui = &fakeui.FakeUI{}
+		command = cmd.NewValidateManifestCmd(cmd.NewManifestValidator(specsLoader, ui))
+
+		specsLoader.ReadReturns(cmd.ReleaseJobSpecs{
+			Name: "rel",
+			Jobs: map[string]boshjobman.Manifest{
+				"job": {Name: "job", Properties: map[string]boshjobman.PropertyDefinition{"port": {Default: 80}}},
+			},
+		}, nil)
+	})
+
+	Describe("Run", func() {
+		var (
+			validateOpts opts.ValidateManifestOpts
+		)
+
+		BeforeEach(func() {
+			validateOpts = opts.ValidateManifestOpts{
+				Args: opts.ValidateManifestArgs{
+					Manifest: opts.FileBytesArg{Bytes: []byte(`
+instance_groups:
+- name: ig
+  jobs:
+  - name: job
+    release: rel
+    properties: {((prop_name)): 8080}
+`)},
+				},
+				Releases: []string{"/rel.tgz"},
+			}
+		})
+
+		act := func() error { return command.Run(validateOpts) }
+
+		It("validates interpolated manifest", func() {
+			validateOpts.VarFlags = opts.VarFlags{
+				VarKVs: []boshtpl.VarKV{{Name: "prop_name", Value: "port"}},
+			}
+
+			Expect(act()).To(Succeed())
+			Expect(specsLoader.ReadArgsForCall(0)).To(Equal("/rel.tgz"))
+			Expect(ui.Said).To(Equal([]string{"Manifest matches release job specs"}))
+		})
+
+		It("returns error if there are problems", func() {
+			validateOpts.VarFlags = opts.VarFlags{
+				VarKVs: []boshtpl.VarKV{{Name: "prop_name", Value: "prot"}},
+			}
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected manifest to match release job specs but found 1 problem(s)"))
+			Expect(ui.Table.Rows).To(HaveLen(1))
+		})
+
+		It("returns error if manifest cannot be interpolated", func() {
+			validateOpts.OpsFlags = opts.OpsFlags{
+				OpsFiles: []opts.OpsFileArg{{Ops: patch.Ops{patch.ErrOp{Err: errors.New("fake-err")}}}},
+			}
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Evaluating manifest"))
+		})
+	})
+})
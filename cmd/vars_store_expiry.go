@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"math"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshdir "github.com/cloudfoundry/bosh-cli/v7/director"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type VarsStoreExpiryCmd struct {
+	ui          boshui.UI
+	timeService clock.Clock
+}
+
+func NewVarsStoreExpiryCmd(ui boshui.UI, timeService clock.Clock) VarsStoreExpiryCmd {
+	return VarsStoreExpiryCmd{ui: ui, timeService: timeService}
+}
+
+func (c VarsStoreExpiryCmd) Run(opts VarsStoreExpiryOpts) error {
+	vars, err := opts.VarsFSStore.Load()
+	if err != nil {
+		return err
+	}
+
+	certs, err := varsStoreCertificates(vars)
+	if err != nil {
+		return err
+	}
+
+	var infos []boshdir.CertificateExpiryInfo
+
+	for _, cert := range certs {
+		infos = append(infos, boshdir.CertificateExpiryInfo{
+			Path:     cert.Name,
+			Expiry:   cert.NotAfter.UTC().Format(time.RFC3339),
+			DaysLeft: daysLeft(cert.NotAfter, c.timeService.Now()),
+		})
+	}
+
+	CertificateInfoTable{Certificates: infos, UI: c.ui}.Print()
+
+	return nil
+}
+
+type varsStoreCertificate struct {
+	Name        string
+	Certificate string
+	NotAfter    time.Time
+}
+
+// varsStoreCertificates returns certificates kept in the store sorted by variable name.
+func varsStoreCertificates(vars boshtpl.StaticVariables) ([]varsStoreCertificate, error) {
+	var certs []varsStoreCertificate
+
+	for name, val := range vars {
+		certPEM := storedCertificate(val)
+		if len(certPEM) == 0 {
+			continue
+		}
+
+		block, _ := pem.Decode([]byte(certPEM))
+		if block == nil {
+			return nil, bosherr.Errorf("Expected variable '%s' certificate to contain PEM formatted block", name)
+		}
+
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing variable '%s' certificate", name)
+		}
+
+		certs = append(certs, varsStoreCertificate{Name: name, Certificate: certPEM, NotAfter: crt.NotAfter})
+	}
+
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Name < certs[j].Name })
+
+	return certs, nil
+}
+
+// storedCertificate returns the 'certificate' key of a stored value if there is one.
+func storedCertificate(val interface{}) string {
+	valBytes, err := yaml.Marshal(val)
+	if err != nil {
+		return ""
+	}
+
+	var certVal struct {
+		Certificate string `yaml:"certificate"`
+	}
+
+	err = yaml.Unmarshal(valBytes, &certVal)
+	if err != nil {
+		return ""
+	}
+
+	return certVal.Certificate
+}
+
+func daysLeft(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
@@ -0,0 +1,96 @@
+package cmd_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("VarsStoreExpiryCmd", func() {
+	var (
+		ui          *fakeui.FakeUI
+		fs          *fakesys.FakeFileSystem
+		timeService *fakeclock.FakeClock
+		store       opts.VarsFSStore
+		command     cmd.VarsStoreExpiryCmd
+	)
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs = fakesys.NewFakeFileSystem()
+		// Half a day after generation keeps days left away from rounding boundaries
+		timeService = fakeclock.NewFakeClock(time.Now().Add(12 * time.Hour))
+		command = cmd.NewVarsStoreExpiryCmd(ui, timeService)
+
+		store = opts.VarsFSStore{FS: fs}
+		err := store.UnmarshalFlag("/vars.yml")
+		Expect(err).ToNot(HaveOccurred())
+
+		manifest := []byte(`
+variables:
+- name: ca
+  type: certificate
+  options: {is_ca: true, common_name: ca}
+- name: leaf
+  type: certificate
+  options: {ca: ca, common_name: leaf}
+- name: password
+  type: password
+`)
+
+		_, err = boshtpl.NewTemplate(manifest).Evaluate(opts.VarFlags{VarsFSStore: store}.AsVariables(), nil, boshtpl.EvaluateOpts{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("lists certificates with days left until they expire", func() {
+		timeService.Increment(30 * 24 * time.Hour)
+
+		err := command.Run(opts.VarsStoreExpiryOpts{VarsFSStore: store})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table.Header).To(Equal([]boshtbl.Header{
+			boshtbl.NewHeader("Certificate"),
+			boshtbl.NewHeader("Expiry Date (UTC)"),
+			boshtbl.NewHeader("Days Left"),
+		}))
+
+		Expect(ui.Table.Rows).To(HaveLen(2))
+		Expect(ui.Table.Rows[0][0]).To(Equal(boshtbl.NewValueString("ca")))
+		Expect(ui.Table.Rows[0][2]).To(Equal(boshtbl.NewValueFmt(boshtbl.NewValueInt(334), false)))
+		Expect(ui.Table.Rows[1][0]).To(Equal(boshtbl.NewValueString("leaf")))
+	})
+
+	It("highlights certificates expiring within 30 days", func() {
+		timeService.Increment(340 * 24 * time.Hour)
+
+		err := command.Run(opts.VarsStoreExpiryOpts{VarsFSStore: store})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table.Rows[0][2]).To(Equal(boshtbl.NewValueFmt(boshtbl.NewValueInt(24), true)))
+	})
+
+	It("returns an error if a stored certificate cannot be parsed", func() {
+		vars, err := store.Load()
+		Expect(err).ToNot(HaveOccurred())
+
+		vars["broken"] = map[interface{}]interface{}{"certificate": "not-a-cert"}
+
+		bytes, err := yaml.Marshal(vars)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fs.WriteFile("/vars.yml", bytes)).To(Succeed())
+
+		err = command.Run(opts.VarsStoreExpiryOpts{VarsFSStore: store})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Expected variable 'broken' certificate to contain PEM formatted block"))
+	})
+})
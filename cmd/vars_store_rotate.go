@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	cfgtypes "github.com/cloudfoundry/config-server/types"
+	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/cmd/opts" //nolint:staticcheck
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	boshui "github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+type VarsStoreRotateCmd struct {
+	ui          boshui.UI
+	timeService clock.Clock
+}
+
+func NewVarsStoreRotateCmd(ui boshui.UI, timeService clock.Clock) VarsStoreRotateCmd {
+	return VarsStoreRotateCmd{ui: ui, timeService: timeService}
+}
+
+type varsStoreRotation struct {
+	Def    boshtpl.VariableDefinition
+	Reason string
+}
+
+func (c VarsStoreRotateCmd) Run(opts VarsStoreRotateOpts) error {
+	if !opts.VarsFSStore.IsSet() {
+		return bosherr.Error("Expected --vars-store to be set")
+	}
+
+	if len(opts.Names) == 0 && opts.ExpiringWithin <= 0 {
+		return bosherr.Error("Expected --name or --expiring-within to select variables to rotate")
+	}
+
+	defs, err := c.definitions(opts.Args.Manifest.Bytes, opts.OpsFlags.AsOp())
+	if err != nil {
+		return err
+	}
+
+	storedVars, err := opts.VarsFSStore.Load()
+	if err != nil {
+		return err
+	}
+
+	rotations, err := c.selectRotations(defs, storedVars, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(rotations) == 0 {
+		c.ui.PrintLinef("No variables to rotate")
+		return nil
+	}
+
+	// Rotated values are consulted first so that leaf certificates are signed by rotated CAs
+	rotatedVars := boshtpl.StaticVariables{}
+	vars := boshtpl.NewMultiVars([]boshtpl.Variables{rotatedVars, opts.VarFlags.AsVariables()})
+	generatorFactory := cfgtypes.NewValueGeneratorConcrete(NewVarsCertLoader(vars))
+
+	for _, rotation := range rotations {
+		val, err := c.generate(rotation.Def, vars, generatorFactory)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Rotating variable '%s'", rotation.Def.Name)
+		}
+
+		rotatedVars[rotation.Def.Name] = c.withTransitionalCA(rotation.Def, val, storedVars)
+	}
+
+	for name, val := range rotatedVars {
+		storedVars[name] = val
+	}
+
+	err = opts.VarsFSStore.Save(storedVars)
+	if err != nil {
+		return err
+	}
+
+	c.printRotations(rotations)
+
+	return nil
+}
+
+// definitions returns manifest variable definitions in the order they are declared.
+func (c VarsStoreRotateCmd) definitions(manifestBytes []byte, op patch.Op) ([]boshtpl.VariableDefinition, error) {
+	var obj interface{}
+
+	err := yaml.Unmarshal(manifestBytes, &obj)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Deserializing manifest")
+	}
+
+	obj, err = op.Apply(obj)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Applying ops to manifest")
+	}
+
+	objBytes, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Serializing manifest")
+	}
+
+	var manifest struct {
+		Variables []struct {
+			Name    string      `yaml:"name"`
+			Type    string      `yaml:"type"`
+			Options interface{} `yaml:"options"`
+		} `yaml:"variables"`
+	}
+
+	err = yaml.Unmarshal(objBytes, &manifest)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Deserializing manifest variables")
+	}
+
+	var defs []boshtpl.VariableDefinition
+
+	for _, def := range manifest.Variables {
+		defs = append(defs, boshtpl.VariableDefinition{Name: def.Name, Type: def.Type, Options: def.Options})
+	}
+
+	return defs, nil
+}
+
+func (c VarsStoreRotateCmd) selectRotations(defs []boshtpl.VariableDefinition, storedVars boshtpl.StaticVariables, opts VarsStoreRotateOpts) ([]varsStoreRotation, error) {
+	reasons := map[string]string{}
+
+	for _, name := range opts.Names {
+		def, found := c.findDefinition(defs, name)
+		if !found || len(def.Type) == 0 {
+			return nil, bosherr.Errorf("Expected variable '%s' to be defined with a type in manifest", name)
+		}
+
+		reasons[name] = "selected"
+	}
+
+	if opts.ExpiringWithin > 0 {
+		certs, err := varsStoreCertificates(storedVars)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cert := range certs {
+			def, found := c.findDefinition(defs, cert.Name)
+			if _, selected := reasons[cert.Name]; selected || !found || def.Type != "certificate" {
+				continue
+			}
+
+			if days := daysLeft(cert.NotAfter, c.timeService.Now()); days <= opts.ExpiringWithin {
+				reasons[cert.Name] = fmt.Sprintf("expires in %d days", days)
+			}
+		}
+	}
+
+	var rotations []varsStoreRotation
+
+	// Certificates come after their CA in the manifest, hence a single pass
+	// also picks up certificates signed by intermediate CAs
+	for _, def := range defs {
+		reason, selected := reasons[def.Name]
+
+		if !selected && def.Type == "certificate" {
+			if ca := c.caName(def); len(ca) > 0 {
+				if _, caSelected := reasons[ca]; caSelected {
+					reason = fmt.Sprintf("signed by rotated CA '%s'", ca)
+					reasons[def.Name] = reason
+					selected = true
+				}
+			}
+		}
+
+		if selected {
+			rotations = append(rotations, varsStoreRotation{Def: def, Reason: reason})
+		}
+	}
+
+	return rotations, nil
+}
+
+func (c VarsStoreRotateCmd) generate(def boshtpl.VariableDefinition, vars boshtpl.Variables, generatorFactory cfgtypes.ValueGeneratorFactory) (interface{}, error) {
+	options := def.Options
+
+	if options != nil {
+		optionsBytes, err := yaml.Marshal(options)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Serializing options")
+		}
+
+		optionsBytes, err = boshtpl.NewTemplate(optionsBytes).Evaluate(vars, nil, boshtpl.EvaluateOpts{ExpectAllKeys: true})
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Interpolating options")
+		}
+
+		err = yaml.Unmarshal(optionsBytes, &options)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Deserializing options")
+		}
+	}
+
+	generator, err := generatorFactory.GetGenerator(def.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return generator.Generate(options)
+}
+
+// withTransitionalCA appends the certificate of the previous CA to the 'ca' key of
+// rotated certificates so that both the old and new CA are trusted while rolling out.
+func (c VarsStoreRotateCmd) withTransitionalCA(def boshtpl.VariableDefinition, val interface{}, storedVars boshtpl.StaticVariables) interface{} {
+	cert, ok := val.(cfgtypes.CertResponse)
+	if !ok {
+		return val
+	}
+
+	var oldCA string
+
+	if ca := c.caName(def); len(ca) > 0 {
+		oldCA = storedCertificate(storedVars[ca])
+	} else if c.isCA(def) {
+		oldCA = storedCertificate(storedVars[def.Name])
+	}
+
+	if len(oldCA) > 0 && !strings.Contains(cert.CA, oldCA) {
+		cert.CA = strings.TrimSuffix(cert.CA, "\n") + "\n" + oldCA
+	}
+
+	return cert
+}
+
+func (c VarsStoreRotateCmd) caName(def boshtpl.VariableDefinition) string {
+	options, ok := def.Options.(map[interface{}]interface{})
+	if !ok {
+		return ""
+	}
+
+	ca, _ := options["ca"].(string)
+
+	return ca
+}
+
+func (c VarsStoreRotateCmd) isCA(def boshtpl.VariableDefinition) bool {
+	options, ok := def.Options.(map[interface{}]interface{})
+	if !ok {
+		return false
+	}
+
+	isCA, _ := options["is_ca"].(bool)
+
+	return isCA
+}
+
+func (c VarsStoreRotateCmd) findDefinition(defs []boshtpl.VariableDefinition, name string) (boshtpl.VariableDefinition, bool) {
+	for _, def := range defs {
+		if def.Name == name {
+			return def, true
+		}
+	}
+
+	return boshtpl.VariableDefinition{}, false
+}
+
+func (c VarsStoreRotateCmd) printRotations(rotations []varsStoreRotation) {
+	table := boshtbl.Table{
+		Content: "variables",
+
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Name"),
+			boshtbl.NewHeader("Type"),
+			boshtbl.NewHeader("Reason"),
+		},
+	}
+
+	for _, rotation := range rotations {
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(rotation.Def.Name),
+			boshtbl.NewValueString(rotation.Def.Type),
+			boshtbl.NewValueString(rotation.Reason),
+		})
+	}
+
+	c.ui.PrintTable(table)
+}
@@ -0,0 +1,201 @@
+package cmd_test
+
+import (
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry/bosh-cli/v7/cmd"
+	"github.com/cloudfoundry/bosh-cli/v7/cmd/opts"
+	boshtpl "github.com/cloudfoundry/bosh-cli/v7/director/template"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("VarsStoreRotateCmd", func() {
+	var (
+		ui          *fakeui.FakeUI
+		fs          *fakesys.FakeFileSystem
+		timeService *fakeclock.FakeClock
+		store       opts.VarsFSStore
+		manifest    []byte
+		rotateOpts  opts.VarsStoreRotateOpts
+		command     cmd.VarsStoreRotateCmd
+	)
+
+	type certVal struct {
+		Certificate string `yaml:"certificate"`
+		PrivateKey  string `yaml:"private_key"`
+		CA          string `yaml:"ca"`
+	}
+
+	storedCert := func(name string) certVal {
+		vars, err := store.Load()
+		Expect(err).ToNot(HaveOccurred())
+
+		bytes, err := yaml.Marshal(vars[name])
+		Expect(err).ToNot(HaveOccurred())
+
+		var val certVal
+		Expect(yaml.Unmarshal(bytes, &val)).To(Succeed())
+
+		return val
+	}
+
+	storedVal := func(name string) interface{} {
+		vars, err := store.Load()
+		Expect(err).ToNot(HaveOccurred())
+
+		return vars[name]
+	}
+
+	BeforeEach(func() {
+		ui = &fakeui.FakeUI{}
+		fs = fakesys.NewFakeFileSystem()
+		// Half a day after generation keeps days left away from rounding boundaries
+		timeService = fakeclock.NewFakeClock(time.Now().Add(12 * time.Hour))
+		command = cmd.NewVarsStoreRotateCmd(ui, timeService)
+
+		store = opts.VarsFSStore{FS: fs}
+		err := store.UnmarshalFlag("/vars.yml")
+		Expect(err).ToNot(HaveOccurred())
+
+		manifest = []byte(`
+variables:
+- name: ca
+  type: certificate
+  options: {is_ca: true, common_name: ca}
+- name: leaf
+  type: certificate
+  options: {ca: ca, common_name: ((leaf_cn))}
+- name: other_ca
+  type: certificate
+  options: {is_ca: true, common_name: other-ca}
+- name: password
+  type: password
+`)
+
+		flags := opts.VarFlags{
+			VarKVs:      []boshtpl.VarKV{{Name: "leaf_cn", Value: "leaf"}},
+			VarsFSStore: store,
+		}
+
+		_, err = boshtpl.NewTemplate(manifest).Evaluate(flags.AsVariables(), nil, boshtpl.EvaluateOpts{})
+		Expect(err).ToNot(HaveOccurred())
+
+		rotateOpts = opts.VarsStoreRotateOpts{
+			Args:     opts.InterpolateArgs{Manifest: opts.FileBytesArg{Bytes: manifest}},
+			VarFlags: flags,
+		}
+	})
+
+	It("regenerates selected variables", func() {
+		oldPassword := storedVal("password")
+		oldCA := storedCert("ca")
+
+		rotateOpts.Names = []string{"password"}
+
+		err := command.Run(rotateOpts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(storedVal("password")).ToNot(Equal(oldPassword))
+		Expect(storedCert("ca")).To(Equal(oldCA))
+
+		Expect(ui.Table.Rows).To(Equal([][]boshtbl.Value{
+			{
+				boshtbl.NewValueString("password"),
+				boshtbl.NewValueString("password"),
+				boshtbl.NewValueString("selected"),
+			},
+		}))
+	})
+
+	It("re-signs certificates of a rotated CA and keeps trusting the old CA", func() {
+		oldCA := storedCert("ca")
+		oldLeaf := storedCert("leaf")
+		oldOtherCA := storedCert("other_ca")
+
+		rotateOpts.Names = []string{"ca"}
+
+		err := command.Run(rotateOpts)
+		Expect(err).ToNot(HaveOccurred())
+
+		newCA := storedCert("ca")
+		Expect(newCA.Certificate).ToNot(Equal(oldCA.Certificate))
+		Expect(newCA.CA).To(HavePrefix(newCA.Certificate))
+		Expect(newCA.CA).To(HaveSuffix(oldCA.Certificate))
+
+		newLeaf := storedCert("leaf")
+		Expect(newLeaf.Certificate).ToNot(Equal(oldLeaf.Certificate))
+		Expect(strings.Count(newLeaf.CA, "BEGIN CERTIFICATE")).To(Equal(2))
+		Expect(newLeaf.CA).To(HavePrefix(newCA.Certificate))
+		Expect(newLeaf.CA).To(HaveSuffix(oldCA.Certificate))
+
+		Expect(storedCert("other_ca")).To(Equal(oldOtherCA))
+
+		Expect(ui.Table.Rows).To(Equal([][]boshtbl.Value{
+			{
+				boshtbl.NewValueString("ca"),
+				boshtbl.NewValueString("certificate"),
+				boshtbl.NewValueString("selected"),
+			},
+			{
+				boshtbl.NewValueString("leaf"),
+				boshtbl.NewValueString("certificate"),
+				boshtbl.NewValueString("signed by rotated CA 'ca'"),
+			},
+		}))
+	})
+
+	It("regenerates certificates expiring within given number of days", func() {
+		timeService.Increment(340 * 24 * time.Hour)
+
+		rotateOpts.ExpiringWithin = 30
+
+		err := command.Run(rotateOpts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ui.Table.Rows).To(HaveLen(3))
+		Expect(ui.Table.Rows[0][2]).To(Equal(boshtbl.NewValueString("expires in 24 days")))
+	})
+
+	It("does not touch the store if nothing is expiring", func() {
+		rotateOpts.ExpiringWithin = 30
+
+		contents, err := fs.ReadFileString("/vars.yml")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = command.Run(rotateOpts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fs.ReadFileString("/vars.yml")).To(Equal(contents))
+		Expect(ui.Said).To(Equal([]string{"No variables to rotate"}))
+	})
+
+	It("returns an error if selected variable is not defined in manifest", func() {
+		rotateOpts.Names = []string{"unknown"}
+
+		err := command.Run(rotateOpts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected variable 'unknown' to be defined with a type in manifest"))
+	})
+
+	It("returns an error if no variables are selected", func() {
+		err := command.Run(rotateOpts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected --name or --expiring-within to select variables to rotate"))
+	})
+
+	It("returns an error if vars store is not set", func() {
+		rotateOpts.VarsFSStore = opts.VarsFSStore{}
+
+		err := command.Run(rotateOpts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected --vars-store to be set"))
+	})
+})
@@ -0,0 +1,128 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+// IdleTimeoutClient aborts requests whose request or response bodies
+// stop making progress. Unlike an overall request timeout it
+// does not limit how long large uploads and downloads may take.
+type IdleTimeoutClient struct {
+	delegate        boshhttp.Client
+	uploadTimeout   time.Duration
+	downloadTimeout time.Duration
+}
+
+func NewIdleTimeoutClient(delegate boshhttp.Client, uploadTimeout, downloadTimeout time.Duration) IdleTimeoutClient {
+	return IdleTimeoutClient{
+		delegate:        delegate,
+		uploadTimeout:   uploadTimeout,
+		downloadTimeout: downloadTimeout,
+	}
+}
+
+func (c IdleTimeoutClient) Do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+
+	// Shallow copy so that callers retrying the request keep the original body
+	req = req.WithContext(ctx)
+
+	var upload *idleReadCloser
+
+	if c.uploadTimeout > 0 && req.Body != nil && req.Body != http.NoBody {
+		upload = newIdleReadCloser(req.Body, c.uploadTimeout, cancel)
+		req.Body = upload
+	}
+
+	resp, err := c.delegate.Do(req)
+
+	// Waiting for a response is limited by the request timeout instead
+	if upload != nil {
+		upload.stop()
+	}
+
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+
+	if c.downloadTimeout > 0 {
+		resp.Body = newIdleReadCloser(resp.Body, c.downloadTimeout, cancel)
+	}
+
+	// Context is released once caller is done with the response
+	resp.Body = cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+type idleReadCloser struct {
+	io.ReadCloser
+
+	timeout time.Duration
+	cancel  context.CancelFunc
+
+	timer   *time.Timer
+	stopped bool
+	mutex   sync.Mutex
+}
+
+func newIdleReadCloser(rc io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleReadCloser {
+	return &idleReadCloser{
+		ReadCloser: rc,
+		timeout:    timeout,
+		cancel:     cancel,
+		timer:      time.AfterFunc(timeout, cancel),
+	}
+}
+
+func (r *idleReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	if err != nil {
+		r.stop()
+	} else if n > 0 {
+		r.reset()
+	}
+
+	return n, err
+}
+
+func (r *idleReadCloser) Close() error {
+	r.stop()
+	return r.ReadCloser.Close()
+}
+
+func (r *idleReadCloser) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.stopped {
+		r.timer.Reset(r.timeout)
+	}
+}
+
+func (r *idleReadCloser) stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.stopped = true
+	r.timer.Stop()
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
@@ -0,0 +1,113 @@
+package httpclient_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
+)
+
+var _ = Describe("IdleTimeoutClient", func() {
+	var (
+		server  *httptest.Server
+		unblock chan struct{}
+	)
+
+	BeforeEach(func() {
+		unblock = make(chan struct{})
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/stall-upload" {
+				select {
+				case <-unblock:
+				case <-req.Context().Done():
+				}
+			}
+
+			io.Copy(io.Discard, req.Body) //nolint:errcheck
+
+			w.Write([]byte("first")) //nolint:errcheck
+			w.(http.Flusher).Flush()
+
+			if req.URL.Path == "/stall" {
+				select {
+				case <-unblock:
+				case <-req.Context().Done():
+				}
+			}
+
+			w.Write([]byte("second")) //nolint:errcheck
+		}))
+	})
+
+	AfterEach(func() {
+		close(unblock)
+		server.Close()
+	})
+
+	It("returns responses that keep making progress", func() {
+		client := NewIdleTimeoutClient(http.DefaultClient, time.Second, time.Second)
+
+		req, err := http.NewRequest("POST", server.URL+"/ok", strings.NewReader("body"))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("firstsecond"))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("aborts downloads that stop making progress", func() {
+		client := NewIdleTimeoutClient(http.DefaultClient, 0, 50*time.Millisecond)
+
+		req, err := http.NewRequest("GET", server.URL+"/stall", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer resp.Body.Close() //nolint:errcheck
+
+		_, err = io.ReadAll(resp.Body)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("context canceled"))
+	})
+
+	It("aborts uploads that stop making progress", func() {
+		client := NewIdleTimeoutClient(http.DefaultClient, 50*time.Millisecond, 0)
+
+		// Large enough to fill network buffers while server is not reading
+		body := strings.NewReader(strings.Repeat("a", 64*1024*1024))
+
+		req, err := http.NewRequest("POST", server.URL+"/stall-upload", body)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.Do(req)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("context canceled"))
+	})
+
+	It("does not mutate original request so that it can be retried", func() {
+		client := NewIdleTimeoutClient(http.DefaultClient, time.Second, time.Second)
+
+		body := io.NopCloser(strings.NewReader("body"))
+
+		req, err := http.NewRequest("POST", server.URL+"/ok", body)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+
+		Expect(req.Body).To(BeIdenticalTo(body))
+	})
+})
@@ -0,0 +1,186 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	gourl "net/url"
+	"time"
+
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	DefaultAttempts   = 5
+	DefaultRetryDelay = 500 * time.Millisecond
+)
+
+// Opts customize HTTP clients used to talk to the Director and UAA.
+// Zero values keep default behaviour.
+type Opts struct {
+	// Number of attempts, including the first one, for requests
+	// failing because of network errors; 1 disables retries
+	MaxAttempts uint
+	RetryDelay  time.Duration
+
+	// Time to wait for response headers after request was written
+	RequestTimeout time.Duration
+
+	// Time without any transferred bytes after which
+	// request or response body transfer is aborted (and possibly retried)
+	UploadIdleTimeout   time.Duration
+	DownloadIdleTimeout time.Duration
+
+	// Size of chunks used by Directors supporting resumable uploads
+	UploadChunkSize int64
+
+	// Supports http://, https://, socks5:// and ssh+socks5://user@host:port?private-key=path URLs
+	Proxy string
+
+	// PEM encoded client certificate and key used for mutual TLS
+	ClientCert string
+	ClientKey  string
+}
+
+func (o Opts) Validate() error {
+	durations := map[string]time.Duration{
+		"retry_delay":           o.RetryDelay,
+		"request_timeout":       o.RequestTimeout,
+		"upload_idle_timeout":   o.UploadIdleTimeout,
+		"download_idle_timeout": o.DownloadIdleTimeout,
+	}
+
+	for name, duration := range durations {
+		if duration < 0 {
+			return bosherr.Errorf("Expected '%s' to be non-negative but was '%s'", name, duration)
+		}
+	}
+
+	if o.UploadChunkSize < 0 {
+		return bosherr.Errorf("Expected 'upload_chunk_size' to be non-negative but was '%d'", o.UploadChunkSize)
+	}
+
+	if len(o.ClientCert) > 0 || len(o.ClientKey) > 0 {
+		if _, err := o.clientCertificate(); err != nil {
+			return err
+		}
+	}
+
+	if len(o.Proxy) > 0 {
+		if _, err := parseProxyURL(o.Proxy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o Opts) Attempts() uint {
+	if o.MaxAttempts == 0 {
+		return DefaultAttempts
+	}
+
+	return o.MaxAttempts
+}
+
+func (o Opts) Delay() time.Duration {
+	if o.RetryDelay == 0 {
+		return DefaultRetryDelay
+	}
+
+	return o.RetryDelay
+}
+
+// Configure adjusts transport of a client created by bosh-utils httpclient package.
+func (o Opts) Configure(client *http.Client) error {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return bosherr.Error("Expected HTTP client to use configurable transport")
+	}
+
+	if o.RequestTimeout > 0 {
+		transport.ResponseHeaderTimeout = o.RequestTimeout
+	}
+
+	if len(o.ClientCert) > 0 || len(o.ClientKey) > 0 {
+		cert, err := o.clientCertificate()
+		if err != nil {
+			return err
+		}
+
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(o.Proxy) > 0 {
+		err := configureProxy(transport, o.Proxy)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExternalClient creates a client for services other than the Director and UAA,
+// e.g. variable sources. It trusts caCert (PEM) instead of system roots when given.
+func (o Opts) ExternalClient(caCert string) (*http.Client, error) {
+	var certPool *x509.CertPool
+
+	if len(caCert) > 0 {
+		var err error
+
+		certPool, err = boshcrypto.CertPoolFromPEM([]byte(caCert))
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Parsing CA certificate")
+		}
+	}
+
+	client := boshhttp.CreateExternalDefaultClient(certPool)
+
+	err := o.Configure(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// RetryClient wraps client so that requests are retried on network errors
+// and stalled body transfers are aborted.
+func (o Opts) RetryClient(client boshhttp.Client, logger boshlog.Logger) boshhttp.Client {
+	if o.UploadIdleTimeout > 0 || o.DownloadIdleTimeout > 0 {
+		client = NewIdleTimeoutClient(client, o.UploadIdleTimeout, o.DownloadIdleTimeout)
+	}
+
+	return boshhttp.NewNetworkSafeRetryClient(client, o.Attempts(), o.Delay(), logger)
+}
+
+func (o Opts) clientCertificate() (tls.Certificate, error) {
+	if len(o.ClientCert) == 0 || len(o.ClientKey) == 0 {
+		return tls.Certificate{}, bosherr.Error("Expected both client certificate and client key to be specified")
+	}
+
+	cert, err := tls.X509KeyPair([]byte(o.ClientCert), []byte(o.ClientKey))
+	if err != nil {
+		return tls.Certificate{}, bosherr.WrapErrorf(err, "Parsing client certificate and key")
+	}
+
+	return cert, nil
+}
+
+func parseProxyURL(proxy string) (*gourl.URL, error) {
+	proxyURL, err := gourl.Parse(proxy)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing proxy URL")
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "ssh+socks5":
+		return proxyURL, nil
+	default:
+		return nil, bosherr.Errorf("Expected proxy URL scheme to be one of http, https, socks5 or ssh+socks5 but was '%s'", proxyURL.Scheme)
+	}
+}
@@ -0,0 +1,172 @@
+package httpclient_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
+)
+
+var _ = Describe("Opts", func() {
+	generateClientCert := func() (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "client"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+
+		certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+		return string(certPEM), string(keyPEM)
+	}
+
+	Describe("Validate", func() {
+		It("returns without error for empty options", func() {
+			Expect(Opts{}.Validate()).To(Succeed())
+		})
+
+		It("returns error if duration is negative", func() {
+			err := Opts{RequestTimeout: -time.Second}.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected 'request_timeout' to be non-negative but was '-1s'"))
+		})
+
+		It("returns error if upload chunk size is negative", func() {
+			err := Opts{UploadChunkSize: -1}.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected 'upload_chunk_size' to be non-negative but was '-1'"))
+		})
+
+		It("returns error if only client certificate is specified", func() {
+			err := Opts{ClientCert: "cert"}.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected both client certificate and client key to be specified"))
+		})
+
+		It("returns error if client certificate cannot be parsed", func() {
+			err := Opts{ClientCert: "cert", ClientKey: "key"}.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing client certificate and key"))
+		})
+
+		It("returns error if proxy scheme is not supported", func() {
+			err := Opts{Proxy: "ftp://proxy"}.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected proxy URL scheme to be one of http, https, socks5 or ssh+socks5 but was 'ftp'"))
+		})
+
+		It("returns without error for supported proxies", func() {
+			Expect(Opts{Proxy: "http://proxy:3128"}.Validate()).To(Succeed())
+			Expect(Opts{Proxy: "socks5://proxy:1080"}.Validate()).To(Succeed())
+			Expect(Opts{Proxy: "ssh+socks5://user@jumpbox:22?private-key=/key"}.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("Attempts/Delay", func() {
+		It("returns defaults when not set", func() {
+			Expect(Opts{}.Attempts()).To(Equal(uint(5)))
+			Expect(Opts{}.Delay()).To(Equal(500 * time.Millisecond))
+		})
+
+		It("returns configured values", func() {
+			opts := Opts{MaxAttempts: 10, RetryDelay: 2 * time.Second}
+			Expect(opts.Attempts()).To(Equal(uint(10)))
+			Expect(opts.Delay()).To(Equal(2 * time.Second))
+		})
+	})
+
+	Describe("Configure", func() {
+		var (
+			client *http.Client
+		)
+
+		BeforeEach(func() {
+			client = httpclient.CreateDefaultClient(nil)
+		})
+
+		transport := func() *http.Transport { return client.Transport.(*http.Transport) }
+
+		It("keeps defaults for empty options", func() {
+			Expect(Opts{}.Configure(client)).To(Succeed())
+			Expect(transport().ResponseHeaderTimeout).To(BeZero())
+			Expect(transport().TLSClientConfig.Certificates).To(BeEmpty())
+		})
+
+		It("sets response header timeout from request timeout", func() {
+			Expect(Opts{RequestTimeout: time.Minute}.Configure(client)).To(Succeed())
+			Expect(transport().ResponseHeaderTimeout).To(Equal(time.Minute))
+		})
+
+		It("sets client certificate", func() {
+			cert, key := generateClientCert()
+
+			Expect(Opts{ClientCert: cert, ClientKey: key}.Configure(client)).To(Succeed())
+			Expect(transport().TLSClientConfig.Certificates).To(HaveLen(1))
+		})
+
+		It("uses HTTP proxy", func() {
+			Expect(Opts{Proxy: "http://proxy:3128"}.Configure(client)).To(Succeed())
+
+			req, err := http.NewRequest("GET", "https://director:25555/info", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			proxyURL, err := transport().Proxy(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proxyURL.String()).To(Equal("http://proxy:3128"))
+		})
+
+		It("dials through SOCKS5 proxy instead of HTTP proxy", func() {
+			Expect(Opts{Proxy: "socks5://proxy:1080"}.Configure(client)).To(Succeed())
+			Expect(transport().Proxy).To(BeNil())
+			Expect(transport().DialContext).ToNot(BeNil())
+		})
+
+		It("returns error if SSH proxy does not specify private key", func() {
+			err := Opts{Proxy: "ssh+socks5://user@jumpbox:22"}.Configure(client)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected proxy URL to specify 'private-key' query param"))
+		})
+
+		It("returns error if client does not use configurable transport", func() {
+			err := Opts{}.Configure(&http.Client{Transport: http.NewFileTransport(http.Dir("/"))})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ExternalClient", func() {
+		It("configures the client with the options", func() {
+			client, err := Opts{RequestTimeout: time.Minute}.ExternalClient("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.Transport.(*http.Transport).ResponseHeaderTimeout).To(Equal(time.Minute))
+		})
+
+		It("returns error if CA certificate cannot be parsed", func() {
+			_, err := Opts{}.ExternalClient("not-a-cert")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing CA certificate"))
+		})
+	})
+})
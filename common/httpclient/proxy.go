@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	gourl "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	proxy "github.com/cloudfoundry/socks5-proxy"
+	goproxy "golang.org/x/net/proxy"
+)
+
+// configureProxy replaces proxy settings picked up from the environment
+// (HTTP_PROXY, BOSH_ALL_PROXY) with an explicitly configured proxy.
+func configureProxy(transport *http.Transport, proxyStr string) error {
+	proxyURL, err := parseProxyURL(proxyStr)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	switch proxyURL.Scheme {
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(proxyURL)
+		transport.DialContext = dialer.DialContext
+
+	case "socks5":
+		socksDialer, err := goproxy.FromURL(proxyURL, dialer)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating SOCKS5 dialer")
+		}
+
+		contextDialer, ok := socksDialer.(goproxy.ContextDialer)
+		if !ok {
+			return bosherr.Error("Expected SOCKS5 dialer to support contexts")
+		}
+
+		transport.Proxy = nil
+		transport.DialContext = contextDialer.DialContext
+
+	case "ssh+socks5":
+		dialContext, err := sshProxyDialContext(proxyURL)
+		if err != nil {
+			return err
+		}
+
+		transport.Proxy = nil
+		transport.DialContext = dialContext
+	}
+
+	return nil
+}
+
+func sshProxyDialContext(proxyURL *gourl.URL) (func(context.Context, string, string) (net.Conn, error), error) {
+	keyPath := proxyURL.Query().Get("private-key")
+	if len(keyPath) == 0 {
+		return nil, bosherr.Error("Expected proxy URL to specify 'private-key' query param")
+	}
+
+	if strings.HasPrefix(keyPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Expanding private key path")
+		}
+
+		keyPath = home + keyPath[1:]
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading private key for SOCKS5 proxy")
+	}
+
+	username := ""
+	if proxyURL.User != nil {
+		username = proxyURL.User.Username()
+	}
+
+	socks5Proxy := proxy.NewSocks5Proxy(proxy.NewHostKey(), log.New(io.Discard, "", log.LstdFlags), 1*time.Minute)
+
+	var (
+		dialer proxy.DialFunc
+		mutex  sync.Mutex
+	)
+
+	// SSH connection is established lazily on the first request
+	return func(_ context.Context, network, address string) (net.Conn, error) {
+		mutex.Lock()
+
+		if dialer == nil {
+			proxyDialer, err := socks5Proxy.Dialer(username, string(key), proxyURL.Host)
+			if err != nil {
+				mutex.Unlock()
+				return nil, bosherr.WrapErrorf(err, "Creating SOCKS5 dialer")
+			}
+
+			dialer = proxyDialer
+		}
+
+		dial := dialer
+		mutex.Unlock()
+
+		return dial(network, address)
+	}, nil
+}
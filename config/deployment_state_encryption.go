@@ -0,0 +1,40 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+)
+
+// encryptDeploymentState leaves contents as they are when no encryptor is configured.
+func encryptDeploymentState(encryptor bicrypto.StateEncryptor, contents []byte) ([]byte, error) {
+	if encryptor == nil {
+		return contents, nil
+	}
+
+	encrypted, err := encryptor.Encrypt(contents)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Encrypting deployment state")
+	}
+
+	return encrypted, nil
+}
+
+// decryptDeploymentState detects encrypted contents, so plain files keep
+// loading after an encryptor is configured.
+func decryptDeploymentState(encryptor bicrypto.StateEncryptor, contents []byte, path string) ([]byte, error) {
+	if !bicrypto.IsEncryptedState(contents) {
+		return contents, nil
+	}
+
+	if encryptor == nil {
+		return nil, bosherr.Errorf("Deployment state '%s' is encrypted, expected an encryption key", path)
+	}
+
+	decrypted, err := encryptor.Decrypt(contents)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Decrypting deployment state '%s'", path)
+	}
+
+	return decrypted, nil
+}
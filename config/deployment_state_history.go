@@ -0,0 +1,248 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+)
+
+// MaxDeploymentStateSnapshots is the number of snapshots kept per deployment state.
+const MaxDeploymentStateSnapshots = 50
+
+const deploymentStateHistoryIndex = "index.json"
+
+type DeploymentStateSnapshot struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ManifestSHA string    `json:"manifest_sha"`
+	VMCID       string    `json:"vm_cid"`
+	DiskCID     string    `json:"disk_cid"`
+	Digest      string    `json:"digest"`
+}
+
+// pendingDeploymentStateSnapshot is the last state saved while holding the
+// state lock. It is recorded once when the lock is released so that a command
+// adds a single snapshot however often it saves. The state from before the
+// command is recorded on its first save so that it can be rolled back to.
+type pendingDeploymentStateSnapshot struct {
+	state    DeploymentState
+	contents []byte
+}
+
+// deploymentStateHistoryStore reads and writes files kept alongside the deployment state.
+type deploymentStateHistoryStore interface {
+	ReadHistoryFile(name string) ([]byte, bool, error)
+	WriteHistoryFile(name string, contents []byte) error
+	DeleteHistoryFile(name string) error
+}
+
+// deploymentStateHistory encrypts snapshots like the deployment state itself.
+// The index only lists IDs, CIDs and digests and is kept in plain text.
+type deploymentStateHistory struct {
+	store     deploymentStateHistoryStore
+	encryptor bicrypto.StateEncryptor
+	now       func() time.Time
+}
+
+func newDeploymentStateHistory(store deploymentStateHistoryStore, encryptor bicrypto.StateEncryptor) deploymentStateHistory {
+	return deploymentStateHistory{store: store, encryptor: encryptor, now: time.Now}
+}
+
+// Record keeps a snapshot of the saved state unless it is identical to the latest one.
+// contents are the unencrypted state so that digests stay comparable.
+func (h deploymentStateHistory) Record(deploymentState DeploymentState, contents []byte) error {
+	snapshots, err := h.List()
+	if err != nil {
+		return err
+	}
+
+	digestBytes := sha256.Sum256(contents)
+	digest := hex.EncodeToString(digestBytes[:])
+
+	if len(snapshots) > 0 && snapshots[len(snapshots)-1].Digest == digest {
+		return nil
+	}
+
+	createdAt := h.now().UTC()
+
+	snapshot := DeploymentStateSnapshot{
+		ID:          createdAt.Format("20060102T150405.000000000Z"),
+		CreatedAt:   createdAt,
+		ManifestSHA: deploymentState.CurrentManifestSHA,
+		VMCID:       deploymentState.CurrentVMCID,
+		Digest:      digest,
+	}
+
+	for _, disk := range deploymentState.Disks {
+		if disk.ID == deploymentState.CurrentDiskID {
+			snapshot.DiskCID = disk.CID
+		}
+	}
+
+	for _, existing := range snapshots {
+		if existing.ID == snapshot.ID {
+			snapshot.ID = fmt.Sprintf("%s-%d", snapshot.ID, len(snapshots))
+		}
+	}
+
+	err = h.writeSnapshot(snapshot.ID, contents)
+	if err != nil {
+		return err
+	}
+
+	snapshots = append(snapshots, snapshot)
+
+	for len(snapshots) > MaxDeploymentStateSnapshots {
+		err = h.store.DeleteHistoryFile(h.snapshotName(snapshots[0].ID))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Deleting deployment state snapshot '%s'", snapshots[0].ID)
+		}
+		snapshots = snapshots[1:]
+	}
+
+	indexContents, err := json.MarshalIndent(snapshots, "", "    ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling deployment state history")
+	}
+
+	err = h.store.WriteHistoryFile(deploymentStateHistoryIndex, indexContents)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing deployment state history")
+	}
+
+	return nil
+}
+
+// Delete removes all snapshots and the index.
+func (h deploymentStateHistory) Delete() error {
+	snapshots, err := h.List()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		err = h.store.DeleteHistoryFile(h.snapshotName(snapshot.ID))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Deleting deployment state snapshot '%s'", snapshot.ID)
+		}
+	}
+
+	err = h.store.DeleteHistoryFile(deploymentStateHistoryIndex)
+	if err != nil {
+		return bosherr.WrapError(err, "Deleting deployment state history")
+	}
+
+	return nil
+}
+
+// List returns snapshots ordered from oldest to newest.
+func (h deploymentStateHistory) List() ([]DeploymentStateSnapshot, error) {
+	contents, found, err := h.store.ReadHistoryFile(deploymentStateHistoryIndex)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading deployment state history")
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	var snapshots []DeploymentStateSnapshot
+
+	err = json.Unmarshal(contents, &snapshots)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling deployment state history")
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].ID < snapshots[j].ID })
+
+	return snapshots, nil
+}
+
+func (h deploymentStateHistory) Load(id string) (DeploymentState, error) {
+	contents, found, err := h.readSnapshot(id)
+	if err != nil {
+		return DeploymentState{}, err
+	}
+
+	if !found {
+		return DeploymentState{}, bosherr.Errorf("Deployment state snapshot '%s' does not exist", id)
+	}
+
+	var deploymentState DeploymentState
+
+	err = json.Unmarshal(contents, &deploymentState)
+	if err != nil {
+		return DeploymentState{}, bosherr.WrapErrorf(err, "Unmarshalling deployment state snapshot '%s'", id)
+	}
+
+	return deploymentState, nil
+}
+
+// Rewrite writes every snapshot back with the history's encryptor.
+func (h deploymentStateHistory) Rewrite() error {
+	snapshots, err := h.List()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		contents, found, err := h.readSnapshot(snapshot.ID)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			continue
+		}
+
+		err = h.writeSnapshot(snapshot.ID, contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h deploymentStateHistory) readSnapshot(id string) ([]byte, bool, error) {
+	contents, found, err := h.store.ReadHistoryFile(h.snapshotName(id))
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Reading deployment state snapshot '%s'", id)
+	}
+
+	if !found {
+		return nil, false, nil
+	}
+
+	contents, err = decryptDeploymentState(h.encryptor, contents, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return contents, true, nil
+}
+
+func (h deploymentStateHistory) writeSnapshot(id string, contents []byte) error {
+	contents, err := encryptDeploymentState(h.encryptor, contents)
+	if err != nil {
+		return err
+	}
+
+	err = h.store.WriteHistoryFile(h.snapshotName(id), contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing deployment state snapshot '%s'", id)
+	}
+
+	return nil
+}
+
+func (h deploymentStateHistory) snapshotName(id string) string {
+	return id + ".json"
+}
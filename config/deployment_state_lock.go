@@ -0,0 +1,80 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type DeploymentStateLock struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeploymentStateLockedError struct {
+	Path string
+	Lock DeploymentStateLock
+}
+
+func (e DeploymentStateLockedError) Error() string {
+	return fmt.Sprintf(
+		"Deployment state '%s' is locked by %s@%s (PID %d) since %s. "+
+			"If no other create-env, delete-env, stop-env or start-env is running, re-run with --force-unlock",
+		e.Path, e.Lock.Owner, e.Lock.Hostname, e.Lock.PID, e.Lock.CreatedAt.Format(time.RFC3339))
+}
+
+func newDeploymentStateLock() (DeploymentStateLock, error) {
+	idBytes := make([]byte, 16)
+
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return DeploymentStateLock{}, bosherr.WrapError(err, "Generating lock id")
+	}
+
+	owner := os.Getenv("USER")
+	if currentUser, err := user.Current(); err == nil {
+		owner = currentUser.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return DeploymentStateLock{
+		ID:        hex.EncodeToString(idBytes),
+		Owner:     owner,
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+func unmarshalDeploymentStateLock(contents []byte) (DeploymentStateLock, error) {
+	var lock DeploymentStateLock
+
+	err := json.Unmarshal(contents, &lock)
+	if err != nil {
+		return DeploymentStateLock{}, bosherr.WrapError(err, "Unmarshalling deployment state lock")
+	}
+
+	return lock, nil
+}
+
+func marshalDeploymentStateLock(lock DeploymentStateLock) ([]byte, error) {
+	jsonContent, err := json.MarshalIndent(lock, "", "    ")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshalling deployment state lock")
+	}
+
+	return jsonContent, nil
+}
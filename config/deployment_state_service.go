@@ -44,4 +44,21 @@ type DeploymentStateService interface {
 	Load() (DeploymentState, error)
 	Save(DeploymentState) error
 	Cleanup() error
+
+	// AcquireLock fails with DeploymentStateLockedError when another
+	// process holds the lock. ReleaseLock only releases a lock acquired
+	// by this service; ForceReleaseLock releases any lock.
+	AcquireLock() error
+	ReleaseLock() error
+	ForceReleaseLock() error
+
+	// History lists snapshots, oldest first. A snapshot of the last state
+	// saved while holding the lock is recorded when the lock is released,
+	// Cleanup removes them.
+	History() ([]DeploymentStateSnapshot, error)
+	LoadSnapshot(id string) (DeploymentState, error)
+
+	// Rewrite writes an existing state and its snapshots back, encrypting
+	// them when the service has an encryptor.
+	Rewrite() error
 }
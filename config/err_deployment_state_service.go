@@ -0,0 +1,25 @@
+package config
+
+// ErrDeploymentStateService postpones returning an error until the deployment state is accessed.
+type ErrDeploymentStateService struct {
+	path string
+	err  error
+}
+
+func NewErrDeploymentStateService(path string, err error) ErrDeploymentStateService {
+	return ErrDeploymentStateService{path: path, err: err}
+}
+
+func (s ErrDeploymentStateService) Path() string                                { return s.path }
+func (s ErrDeploymentStateService) Exists() bool                                { return false }
+func (s ErrDeploymentStateService) Load() (DeploymentState, error)              { return DeploymentState{}, s.err }
+func (s ErrDeploymentStateService) Save(DeploymentState) error                  { return s.err }
+func (s ErrDeploymentStateService) Cleanup() error                              { return s.err }
+func (s ErrDeploymentStateService) AcquireLock() error                          { return s.err }
+func (s ErrDeploymentStateService) ReleaseLock() error                          { return nil }
+func (s ErrDeploymentStateService) ForceReleaseLock() error                     { return s.err }
+func (s ErrDeploymentStateService) Rewrite() error                              { return s.err }
+func (s ErrDeploymentStateService) History() ([]DeploymentStateSnapshot, error) { return nil, s.err }
+func (s ErrDeploymentStateService) LoadSnapshot(string) (DeploymentState, error) {
+	return DeploymentState{}, s.err
+}
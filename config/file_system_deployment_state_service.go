@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -10,6 +11,8 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 )
 
 type fileSystemDeploymentStateService struct {
@@ -17,15 +20,23 @@ type fileSystemDeploymentStateService struct {
 	fs            boshsys.FileSystem
 	uuidGenerator boshuuid.Generator
 	logger        boshlog.Logger
+	encryptor     bicrypto.StateEncryptor
 	logTag        string
+	lockID        string
+
+	pendingSnapshot  *pendingDeploymentStateSnapshot
+	recordedPrevious bool
 }
 
-func NewFileSystemDeploymentStateService(fs boshsys.FileSystem, uuidGenerator boshuuid.Generator, logger boshlog.Logger, deploymentStatePath string) DeploymentStateService {
+// NewFileSystemDeploymentStateService encrypts the state when encryptor is
+// not nil. Encrypted files are always detected on load.
+func NewFileSystemDeploymentStateService(fs boshsys.FileSystem, uuidGenerator boshuuid.Generator, logger boshlog.Logger, deploymentStatePath string, encryptor bicrypto.StateEncryptor) DeploymentStateService {
 	return &fileSystemDeploymentStateService{
 		configPath:    deploymentStatePath,
 		fs:            fs,
 		uuidGenerator: uuidGenerator,
 		logger:        logger,
+		encryptor:     encryptor,
 		logTag:        "config",
 	}
 }
@@ -61,7 +72,11 @@ func (s *fileSystemDeploymentStateService) Load() (DeploymentState, error) {
 		if err != nil {
 			return DeploymentState{}, bosherr.WrapErrorf(err, "Reading deployment state file '%s'", s.configPath)
 		}
-		s.logger.Debug(s.logTag, "Deployment File Contents %#s", deploymentStateFileContents)
+
+		deploymentStateFileContents, err = decryptDeploymentState(s.encryptor, deploymentStateFileContents, s.configPath)
+		if err != nil {
+			return DeploymentState{}, err
+		}
 
 		err = json.Unmarshal(deploymentStateFileContents, deploymentState)
 		if err != nil {
@@ -89,11 +104,24 @@ func (s *fileSystemDeploymentStateService) Save(deploymentState DeploymentState)
 		return bosherr.WrapError(err, "Marshalling deployment state into JSON")
 	}
 
-	err = s.fs.WriteFile(s.configPath, jsonContent)
+	fileContent, err := encryptDeploymentState(s.encryptor, jsonContent)
+	if err != nil {
+		return err
+	}
+
+	if s.lockID != "" && !s.recordedPrevious {
+		s.recordPreviousSnapshot()
+	}
+
+	err = s.fs.WriteFile(s.configPath, fileContent)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Writing deployment state file '%s'", s.configPath)
 	}
 
+	if s.lockID != "" {
+		s.pendingSnapshot = &pendingDeploymentStateSnapshot{state: deploymentState, contents: jsonContent}
+	}
+
 	return nil
 }
 
@@ -119,5 +147,231 @@ func (s *fileSystemDeploymentStateService) Cleanup() error {
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Could not delete deployment state file %s", s.configPath)
 	}
+
+	s.pendingSnapshot = nil
+
+	err = s.fs.RemoveAll(s.historyDir())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Could not delete deployment state history %s", s.historyDir())
+	}
+
+	return nil
+}
+
+func (s *fileSystemDeploymentStateService) AcquireLock() error {
+	lockPath := s.lockPath()
+
+	if s.fs.FileExists(lockPath) {
+		return s.lockedErr(lockPath)
+	}
+
+	lock, err := newDeploymentStateLock()
+	if err != nil {
+		return err
+	}
+
+	jsonContent, err := marshalDeploymentStateLock(lock)
+	if err != nil {
+		return err
+	}
+
+	err = s.fs.MkdirAll(filepath.Dir(lockPath), os.ModePerm)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating deployment state lock directory '%s'", filepath.Dir(lockPath))
+	}
+
+	file, err := s.fs.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return s.lockedErr(lockPath)
+		}
+		return bosherr.WrapErrorf(err, "Creating deployment state lock file '%s'", lockPath)
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	_, err = file.Write(jsonContent)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing deployment state lock file '%s'", lockPath)
+	}
+
+	s.logger.Debug(s.logTag, "Acquired deployment state lock: %s", lockPath)
+	s.lockID = lock.ID
+	s.recordedPrevious = false
+
+	return nil
+}
+
+func (s *fileSystemDeploymentStateService) ReleaseLock() error {
+	if s.lockID == "" {
+		return nil
+	}
+
+	lockPath := s.lockPath()
+	lockExists := s.fs.FileExists(lockPath)
+
+	if lockExists {
+		contents, err := s.fs.ReadFile(lockPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading deployment state lock file '%s'", lockPath)
+		}
+
+		lock, err := unmarshalDeploymentStateLock(contents)
+		if err != nil {
+			return err
+		}
+
+		if lock.ID != s.lockID {
+			return bosherr.Errorf("Deployment state lock '%s' was taken over by %s@%s (PID %d)", lockPath, lock.Owner, lock.Hostname, lock.PID)
+		}
+	}
+
+	s.recordPendingSnapshot()
+
+	if lockExists {
+		err := s.fs.RemoveAll(lockPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing deployment state lock file '%s'", lockPath)
+		}
+	}
+
+	s.logger.Debug(s.logTag, "Released deployment state lock: %s", lockPath)
+	s.lockID = ""
+
+	return nil
+}
+
+func (s *fileSystemDeploymentStateService) ForceReleaseLock() error {
+	err := s.fs.RemoveAll(s.lockPath())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing deployment state lock file '%s'", s.lockPath())
+	}
+
 	return nil
 }
+
+func (s *fileSystemDeploymentStateService) lockPath() string {
+	return s.configPath + ".lock"
+}
+
+func (s *fileSystemDeploymentStateService) lockedErr(lockPath string) error {
+	contents, err := s.fs.ReadFile(lockPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading deployment state lock file '%s'", lockPath)
+	}
+
+	lock, err := unmarshalDeploymentStateLock(contents)
+	if err != nil {
+		return err
+	}
+
+	return DeploymentStateLockedError{Path: s.configPath, Lock: lock}
+}
+
+func (s *fileSystemDeploymentStateService) History() ([]DeploymentStateSnapshot, error) {
+	return s.history().List()
+}
+
+func (s *fileSystemDeploymentStateService) LoadSnapshot(id string) (DeploymentState, error) {
+	return s.history().Load(id)
+}
+
+func (s *fileSystemDeploymentStateService) Rewrite() error {
+	if !s.Exists() {
+		return bosherr.Errorf("Deployment state file '%s' does not exist", s.configPath)
+	}
+
+	deploymentState, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	err = s.Save(deploymentState)
+	if err != nil {
+		return err
+	}
+
+	err = s.history().Rewrite()
+	if err != nil {
+		return bosherr.WrapError(err, "Rewriting deployment state snapshots")
+	}
+
+	return nil
+}
+
+// recordPreviousSnapshot records the state as it was before the command changed it.
+func (s *fileSystemDeploymentStateService) recordPreviousSnapshot() {
+	s.recordedPrevious = true
+
+	if !s.fs.FileExists(s.configPath) {
+		return
+	}
+
+	contents, err := s.fs.ReadFile(s.configPath)
+	if err == nil {
+		contents, err = decryptDeploymentState(s.encryptor, contents, s.configPath)
+	}
+
+	var deploymentState DeploymentState
+
+	if err == nil {
+		err = json.Unmarshal(contents, &deploymentState)
+	}
+
+	if err == nil {
+		err = s.history().Record(deploymentState, contents)
+	}
+
+	if err != nil {
+		s.logger.Warn(s.logTag, "Recording previous deployment state snapshot: %s", err.Error())
+	}
+}
+
+func (s *fileSystemDeploymentStateService) recordPendingSnapshot() {
+	if s.pendingSnapshot == nil {
+		return
+	}
+
+	err := s.history().Record(s.pendingSnapshot.state, s.pendingSnapshot.contents)
+	if err != nil {
+		s.logger.Warn(s.logTag, "Recording deployment state snapshot: %s", err.Error())
+	}
+
+	s.pendingSnapshot = nil
+}
+
+func (s *fileSystemDeploymentStateService) history() deploymentStateHistory {
+	return newDeploymentStateHistory(fileSystemHistoryStore{fs: s.fs, dir: s.historyDir()}, s.encryptor)
+}
+
+func (s *fileSystemDeploymentStateService) historyDir() string {
+	return s.configPath + ".history"
+}
+
+type fileSystemHistoryStore struct {
+	fs  boshsys.FileSystem
+	dir string
+}
+
+func (s fileSystemHistoryStore) ReadHistoryFile(name string) ([]byte, bool, error) {
+	path := filepath.Join(s.dir, name)
+
+	if !s.fs.FileExists(path) {
+		return nil, false, nil
+	}
+
+	contents, err := s.fs.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return contents, true, nil
+}
+
+func (s fileSystemHistoryStore) WriteHistoryFile(name string, contents []byte) error {
+	return s.fs.WriteFile(filepath.Join(s.dir, name), contents)
+}
+
+func (s fileSystemHistoryStore) DeleteHistoryFile(name string) error {
+	return s.fs.RemoveAll(filepath.Join(s.dir, name))
+}
@@ -3,6 +3,8 @@ package config_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -13,6 +15,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/config"
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
 )
 
 var _ = Describe("fileSystemDeploymentStateService", func() {
@@ -28,7 +31,7 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 		deploymentStatePath = "/some/deployment.json"
 		logger := boshlog.NewLogger(boshlog.LevelNone)
 		fakeUUIDGenerator = fakeuuid.NewFakeGenerator()
-		service = NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, deploymentStatePath)
+		service = NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, deploymentStatePath, nil)
 	})
 
 	Describe("DeploymentStatePath", func() {
@@ -239,4 +242,283 @@ var _ = Describe("fileSystemDeploymentStateService", func() {
 			Expect(err.Error()).To(ContainSubstring("could not do that Dave"))
 		})
 	})
+
+	Describe("AcquireLock", func() {
+		It("writes a lock file next to the deployment state", func() {
+			err := service.AcquireLock()
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fakeFs.ReadFile("/some/deployment.json.lock")
+			Expect(err).ToNot(HaveOccurred())
+
+			var lock DeploymentStateLock
+			Expect(json.Unmarshal(contents, &lock)).To(Succeed())
+			Expect(lock.ID).ToNot(BeEmpty())
+			Expect(lock.PID).To(Equal(os.Getpid()))
+			Expect(lock.Hostname).ToNot(BeEmpty())
+			Expect(lock.CreatedAt).ToNot(BeZero())
+		})
+
+		It("returns a locked error when another process holds the lock", func() {
+			err := fakeFs.WriteFileString("/some/deployment.json.lock",
+				`{"id":"other","owner":"alice","pid":42,"hostname":"jumpbox","created_at":"2020-01-02T03:04:05Z"}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = service.AcquireLock()
+			Expect(err).To(BeAssignableToTypeOf(DeploymentStateLockedError{}))
+			Expect(err.Error()).To(ContainSubstring(
+				"Deployment state '/some/deployment.json' is locked by alice@jumpbox (PID 42) since 2020-01-02T03:04:05Z"))
+			Expect(err.Error()).To(ContainSubstring("--force-unlock"))
+		})
+	})
+
+	Describe("ReleaseLock", func() {
+		It("removes a lock acquired by the service", func() {
+			Expect(service.AcquireLock()).To(Succeed())
+			Expect(service.ReleaseLock()).To(Succeed())
+			Expect(fakeFs.FileExists("/some/deployment.json.lock")).To(BeFalse())
+		})
+
+		It("does not remove a lock that it did not acquire", func() {
+			err := fakeFs.WriteFileString("/some/deployment.json.lock", `{"id":"other"}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(service.ReleaseLock()).To(Succeed())
+			Expect(fakeFs.FileExists("/some/deployment.json.lock")).To(BeTrue())
+		})
+
+		It("returns an error when the lock was taken over", func() {
+			Expect(service.AcquireLock()).To(Succeed())
+
+			err := fakeFs.WriteFileString("/some/deployment.json.lock", `{"id":"other","owner":"bob","pid":7,"hostname":"laptop"}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = service.ReleaseLock()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("was taken over by bob@laptop (PID 7)"))
+			Expect(fakeFs.FileExists("/some/deployment.json.lock")).To(BeTrue())
+		})
+	})
+
+	Describe("ForceReleaseLock", func() {
+		It("removes any lock", func() {
+			err := fakeFs.WriteFileString("/some/deployment.json.lock", `{"id":"other"}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(service.ForceReleaseLock()).To(Succeed())
+			Expect(fakeFs.FileExists("/some/deployment.json.lock")).To(BeFalse())
+
+			Expect(service.AcquireLock()).To(Succeed())
+		})
+	})
+
+	Describe("History", func() {
+		saveLocked := func(service DeploymentStateService, states ...DeploymentState) {
+			Expect(service.AcquireLock()).To(Succeed())
+			for _, state := range states {
+				Expect(service.Save(state)).To(Succeed())
+			}
+			Expect(service.ReleaseLock()).To(Succeed())
+		}
+
+		It("records a snapshot of the last saved state for every command that changes it", func() {
+			saveLocked(service,
+				DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"},
+				DeploymentState{DirectorID: "fake-director-id", CurrentManifestSHA: "fake-sha-1"},
+			)
+			saveLocked(service, DeploymentState{DirectorID: "fake-director-id", CurrentManifestSHA: "fake-sha-1"})
+			saveLocked(service, DeploymentState{
+				DirectorID:         "fake-director-id",
+				CurrentManifestSHA: "fake-sha-2",
+				CurrentVMCID:       "fake-vm-cid",
+				CurrentDiskID:      "fake-disk-id",
+				Disks:              []DiskRecord{{ID: "fake-disk-id", CID: "fake-disk-cid"}},
+			})
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(2))
+
+			Expect(snapshots[0].ManifestSHA).To(Equal("fake-sha-1"))
+			Expect(snapshots[1].ManifestSHA).To(Equal("fake-sha-2"))
+			Expect(snapshots[1].VMCID).To(Equal("fake-vm-cid"))
+			Expect(snapshots[1].DiskCID).To(Equal("fake-disk-cid"))
+			Expect(snapshots[0].ID < snapshots[1].ID).To(BeTrue())
+
+			Expect(fakeFs.FileExists("/some/deployment.json.history/index.json")).To(BeTrue())
+			Expect(fakeFs.FileExists("/some/deployment.json.history/" + snapshots[0].ID + ".json")).To(BeTrue())
+
+			state, err := service.LoadSnapshot(snapshots[0].ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(DeploymentState{DirectorID: "fake-director-id", CurrentManifestSHA: "fake-sha-1"}))
+		})
+
+		It("keeps a limited number of snapshots", func() {
+			for i := 0; i < MaxDeploymentStateSnapshots+2; i++ {
+				saveLocked(service, DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: fmt.Sprintf("vm-%d", i)})
+			}
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(MaxDeploymentStateSnapshots))
+			Expect(snapshots[0].VMCID).To(Equal("vm-2"))
+
+			_, err = service.LoadSnapshot("does-not-exist")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment state snapshot 'does-not-exist' does not exist"))
+		})
+
+		It("records the state from before the command so that it can be rolled back to", func() {
+			Expect(service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentManifestSHA: "fake-sha-1"})).To(Succeed())
+
+			saveLocked(service, DeploymentState{DirectorID: "fake-director-id", CurrentManifestSHA: "fake-sha-2"})
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(2))
+			Expect(snapshots[0].ManifestSHA).To(Equal("fake-sha-1"))
+			Expect(snapshots[1].ManifestSHA).To(Equal("fake-sha-2"))
+		})
+
+		It("records the snapshot even when the lock file was removed", func() {
+			Expect(service.AcquireLock()).To(Succeed())
+			Expect(service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})).To(Succeed())
+			Expect(fakeFs.RemoveAll("/some/deployment.json.lock")).To(Succeed())
+			Expect(service.ReleaseLock()).To(Succeed())
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(1))
+			Expect(snapshots[0].VMCID).To(Equal("fake-vm-cid"))
+		})
+
+		It("returns no snapshots when nothing was saved", func() {
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+		})
+
+		It("records no snapshot for saves without the lock", func() {
+			Expect(service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})).To(Succeed())
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+		})
+
+		It("removes the snapshots on cleanup", func() {
+			saveLocked(service, DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid"})
+
+			Expect(service.Cleanup()).To(Succeed())
+			Expect(fakeFs.FileExists("/some/deployment.json.history")).To(BeFalse())
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+		})
+	})
+
+	Describe("encryption", func() {
+		var (
+			encryptor        bicrypto.StateEncryptor
+			encryptedService DeploymentStateService
+		)
+
+		BeforeEach(func() {
+			encryptor = bicrypto.NewAESGCMStateEncryptor([]byte("fake-key"))
+			encryptedService = NewFileSystemDeploymentStateService(
+				fakeFs, fakeUUIDGenerator, boshlog.NewLogger(boshlog.LevelNone), deploymentStatePath, encryptor)
+		})
+
+		It("encrypts the state and its snapshots when saving", func() {
+			Expect(encryptedService.AcquireLock()).To(Succeed())
+			Expect(encryptedService.Save(DeploymentState{DirectorID: "fake-director-id"})).To(Succeed())
+			Expect(encryptedService.ReleaseLock()).To(Succeed())
+
+			contents, err := fakeFs.ReadFile(deploymentStatePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bicrypto.IsEncryptedState(contents)).To(BeTrue())
+			Expect(string(contents)).ToNot(ContainSubstring("fake-director-id"))
+
+			snapshots, err := encryptedService.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(1))
+
+			snapshotContents, err := fakeFs.ReadFile("/some/deployment.json.history/" + snapshots[0].ID + ".json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bicrypto.IsEncryptedState(snapshotContents)).To(BeTrue())
+
+			state, err := encryptedService.LoadSnapshot(snapshots[0].ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.DirectorID).To(Equal("fake-director-id"))
+
+			state, err = encryptedService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.DirectorID).To(Equal("fake-director-id"))
+		})
+
+		It("loads plain state files", func() {
+			Expect(service.Save(DeploymentState{DirectorID: "fake-director-id"})).To(Succeed())
+
+			state, err := encryptedService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.DirectorID).To(Equal("fake-director-id"))
+		})
+
+		It("returns an error when an encrypted state is loaded without a key", func() {
+			Expect(encryptedService.Save(DeploymentState{DirectorID: "fake-director-id"})).To(Succeed())
+
+			_, err := service.Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment state '/some/deployment.json' is encrypted, expected an encryption key"))
+		})
+
+		It("returns an error when the key is wrong", func() {
+			Expect(encryptedService.Save(DeploymentState{DirectorID: "fake-director-id"})).To(Succeed())
+
+			otherService := NewFileSystemDeploymentStateService(
+				fakeFs, fakeUUIDGenerator, boshlog.NewLogger(boshlog.LevelNone), deploymentStatePath,
+				bicrypto.NewAESGCMStateEncryptor([]byte("other-key")))
+
+			_, err := otherService.Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Decrypting deployment state '/some/deployment.json'"))
+		})
+
+		Describe("Rewrite", func() {
+			It("encrypts an existing plain state and its snapshots in place", func() {
+				for _, vmCID := range []string{"vm-1", "vm-2"} {
+					Expect(service.AcquireLock()).To(Succeed())
+					Expect(service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: vmCID})).To(Succeed())
+					Expect(service.ReleaseLock()).To(Succeed())
+				}
+
+				Expect(encryptedService.Rewrite()).To(Succeed())
+
+				contents, err := fakeFs.ReadFile(deploymentStatePath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bicrypto.IsEncryptedState(contents)).To(BeTrue())
+
+				snapshots, err := encryptedService.History()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(snapshots).To(HaveLen(2))
+
+				for _, snapshot := range snapshots {
+					snapshotContents, err := fakeFs.ReadFile("/some/deployment.json.history/" + snapshot.ID + ".json")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(bicrypto.IsEncryptedState(snapshotContents)).To(BeTrue())
+				}
+
+				state, err := encryptedService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state.CurrentVMCID).To(Equal("vm-2"))
+			})
+
+			It("returns an error when the state does not exist", func() {
+				err := encryptedService.Rewrite()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Deployment state file '/some/deployment.json' does not exist"))
+			})
+		})
+	})
 })
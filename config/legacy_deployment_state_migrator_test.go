@@ -26,7 +26,7 @@ var _ = Describe("legacyDeploymentStateMigrator", func() {
 		legacyDeploymentStateFilePath = "/path/to/legacy/bosh-deployment.yml"
 		modernDeploymentStateFilePath = "/path/to/legacy/deployment.json"
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		deploymentStateService = NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, modernDeploymentStateFilePath)
+		deploymentStateService = NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, modernDeploymentStateFilePath, nil)
 		migrator = NewLegacyDeploymentStateMigrator(deploymentStateService, fakeFs, fakeUUIDGenerator, logger)
 	})
 
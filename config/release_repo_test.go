@@ -27,7 +27,7 @@ var _ = Describe("ReleaseRepo", func() {
 		fs = fakesys.NewFakeFileSystem()
 		fakeUUIDGenerator = &fakeuuid.FakeGenerator{}
 		fakeUUIDGenerator.GeneratedUUID = "fake-uuid"
-		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path", nil)
 		_, err := deploymentStateService.Load()
 		Expect(err).ToNot(HaveOccurred())
 		repo = NewReleaseRepo(deploymentStateService, fakeUUIDGenerator)
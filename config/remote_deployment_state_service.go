@@ -0,0 +1,422 @@
+package config
+
+import (
+	"encoding/json"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+
+	bicrypto "github.com/cloudfoundry/bosh-cli/v7/crypto"
+)
+
+type remoteDeploymentStateService struct {
+	uri           string
+	blobID        string
+	blobstore     StateBlobstore
+	fs            boshsys.FileSystem
+	uuidGenerator boshuuid.Generator
+	logger        boshlog.Logger
+	encryptor     bicrypto.StateEncryptor
+	logTag        string
+	lockID        string
+
+	pendingSnapshot  *pendingDeploymentStateSnapshot
+	recordedPrevious bool
+}
+
+// NewRemoteDeploymentStateService keeps the deployment state as a single
+// object in a blobstore. uri is only used for display purposes. The state is
+// encrypted before upload when encryptor is not nil.
+func NewRemoteDeploymentStateService(
+	blobstore StateBlobstore,
+	blobID string,
+	fs boshsys.FileSystem,
+	uuidGenerator boshuuid.Generator,
+	logger boshlog.Logger,
+	uri string,
+	encryptor bicrypto.StateEncryptor,
+) DeploymentStateService {
+	return &remoteDeploymentStateService{
+		uri:           uri,
+		blobID:        blobID,
+		blobstore:     blobstore,
+		fs:            fs,
+		uuidGenerator: uuidGenerator,
+		logger:        logger,
+		encryptor:     encryptor,
+		logTag:        "config",
+	}
+}
+
+func (s *remoteDeploymentStateService) Path() string {
+	return s.uri
+}
+
+func (s *remoteDeploymentStateService) Exists() bool {
+	exists, err := s.blobstore.Exists(s.blobID)
+	if err != nil {
+		s.logger.Warn(s.logTag, "Checking existence of deployment state '%s': %s", s.uri, err)
+		return false
+	}
+
+	return exists
+}
+
+func (s *remoteDeploymentStateService) Load() (DeploymentState, error) {
+	s.logger.Debug(s.logTag, "Loading deployment state: %s", s.uri)
+
+	deploymentState := &DeploymentState{}
+
+	exists, err := s.blobstore.Exists(s.blobID)
+	if err != nil {
+		return DeploymentState{}, bosherr.WrapErrorf(err, "Checking deployment state '%s'", s.uri)
+	}
+
+	if exists {
+		deploymentStateFileContents, err := s.download(s.blobID)
+		if err != nil {
+			return DeploymentState{}, bosherr.WrapErrorf(err, "Downloading deployment state '%s'", s.uri)
+		}
+
+		deploymentStateFileContents, err = decryptDeploymentState(s.encryptor, deploymentStateFileContents, s.uri)
+		if err != nil {
+			return DeploymentState{}, err
+		}
+
+		err = json.Unmarshal(deploymentStateFileContents, deploymentState)
+		if err != nil {
+			return DeploymentState{}, bosherr.WrapErrorf(err, "Unmarshalling deployment state '%s'", s.uri)
+		}
+	}
+
+	if deploymentState.DirectorID == "" {
+		uuid, err := s.uuidGenerator.Generate()
+		if err != nil {
+			return DeploymentState{}, bosherr.WrapError(err, "Generating DirectorID")
+		}
+		deploymentState.DirectorID = uuid
+
+		err = s.Save(*deploymentState)
+		if err != nil {
+			return DeploymentState{}, bosherr.WrapError(err, "Saving deployment state")
+		}
+	}
+
+	return *deploymentState, nil
+}
+
+func (s *remoteDeploymentStateService) Save(deploymentState DeploymentState) error {
+	s.logger.Debug(s.logTag, "Saving deployment state %#v", deploymentState)
+
+	jsonContent, err := json.MarshalIndent(deploymentState, "", "    ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling deployment state into JSON")
+	}
+
+	fileContent, err := encryptDeploymentState(s.encryptor, jsonContent)
+	if err != nil {
+		return err
+	}
+
+	if s.lockID != "" && !s.recordedPrevious {
+		s.recordPreviousSnapshot()
+	}
+
+	path, err := s.writeTempFile(fileContent)
+	if err != nil {
+		return err
+	}
+
+	defer s.fs.RemoveAll(path) //nolint:errcheck
+
+	err = s.blobstore.Put(path, s.blobID)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Uploading deployment state '%s'", s.uri)
+	}
+
+	if s.lockID != "" {
+		s.pendingSnapshot = &pendingDeploymentStateSnapshot{state: deploymentState, contents: jsonContent}
+	}
+
+	return nil
+}
+
+func (s *remoteDeploymentStateService) Cleanup() error {
+	err := s.blobstore.Delete(s.blobID)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Could not delete deployment state %s", s.uri)
+	}
+
+	s.pendingSnapshot = nil
+
+	err = s.history().Delete()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Could not delete deployment state history %s", s.uri)
+	}
+
+	return nil
+}
+
+func (s *remoteDeploymentStateService) AcquireLock() error {
+	lockBlobID := s.lockBlobID()
+
+	exists, err := s.blobstore.Exists(lockBlobID)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking deployment state lock '%s'", s.uri)
+	}
+
+	if exists {
+		return s.lockedErr()
+	}
+
+	lock, err := newDeploymentStateLock()
+	if err != nil {
+		return err
+	}
+
+	jsonContent, err := marshalDeploymentStateLock(lock)
+	if err != nil {
+		return err
+	}
+
+	path, err := s.writeTempFile(jsonContent)
+	if err != nil {
+		return err
+	}
+
+	defer s.fs.RemoveAll(path) //nolint:errcheck
+
+	created, err := s.blobstore.PutIfAbsent(path, lockBlobID)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Uploading deployment state lock '%s'", s.uri)
+	}
+
+	if !created {
+		return s.lockedErr()
+	}
+
+	s.logger.Debug(s.logTag, "Acquired deployment state lock: %s", s.uri)
+	s.lockID = lock.ID
+	s.recordedPrevious = false
+
+	return nil
+}
+
+func (s *remoteDeploymentStateService) ReleaseLock() error {
+	if s.lockID == "" {
+		return nil
+	}
+
+	exists, err := s.blobstore.Exists(s.lockBlobID())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking deployment state lock '%s'", s.uri)
+	}
+
+	if exists {
+		lock, err := s.currentLock()
+		if err != nil {
+			return err
+		}
+
+		if lock.ID != s.lockID {
+			return bosherr.Errorf("Deployment state lock '%s' was taken over by %s@%s (PID %d)", s.uri, lock.Owner, lock.Hostname, lock.PID)
+		}
+	}
+
+	s.recordPendingSnapshot()
+
+	if exists {
+		err = s.blobstore.Delete(s.lockBlobID())
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Deleting deployment state lock '%s'", s.uri)
+		}
+	}
+
+	s.logger.Debug(s.logTag, "Released deployment state lock: %s", s.uri)
+	s.lockID = ""
+
+	return nil
+}
+
+func (s *remoteDeploymentStateService) ForceReleaseLock() error {
+	err := s.blobstore.Delete(s.lockBlobID())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Deleting deployment state lock '%s'", s.uri)
+	}
+
+	return nil
+}
+
+func (s *remoteDeploymentStateService) lockBlobID() string {
+	return s.blobID + ".lock"
+}
+
+func (s *remoteDeploymentStateService) currentLock() (DeploymentStateLock, error) {
+	contents, err := s.download(s.lockBlobID())
+	if err != nil {
+		return DeploymentStateLock{}, bosherr.WrapErrorf(err, "Downloading deployment state lock '%s'", s.uri)
+	}
+
+	return unmarshalDeploymentStateLock(contents)
+}
+
+func (s *remoteDeploymentStateService) lockedErr() error {
+	lock, err := s.currentLock()
+	if err != nil {
+		return err
+	}
+
+	return DeploymentStateLockedError{Path: s.uri, Lock: lock}
+}
+
+func (s *remoteDeploymentStateService) download(blobID string) ([]byte, error) {
+	path, err := s.blobstore.Get(blobID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.fs.RemoveAll(path) //nolint:errcheck
+
+	contents, err := s.fs.ReadFile(path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading downloaded file '%s'", path)
+	}
+
+	return contents, nil
+}
+
+func (s *remoteDeploymentStateService) writeTempFile(contents []byte) (string, error) {
+	file, err := s.fs.TempFile("bosh-deployment-state")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Creating temporary file")
+	}
+
+	err = file.Close()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Closing temporary file")
+	}
+
+	err = s.fs.WriteFile(file.Name(), contents)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Writing temporary file")
+	}
+
+	return file.Name(), nil
+}
+
+func (s *remoteDeploymentStateService) History() ([]DeploymentStateSnapshot, error) {
+	return s.history().List()
+}
+
+func (s *remoteDeploymentStateService) LoadSnapshot(id string) (DeploymentState, error) {
+	return s.history().Load(id)
+}
+
+func (s *remoteDeploymentStateService) Rewrite() error {
+	if !s.Exists() {
+		return bosherr.Errorf("Deployment state '%s' does not exist", s.uri)
+	}
+
+	deploymentState, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	err = s.Save(deploymentState)
+	if err != nil {
+		return err
+	}
+
+	err = s.history().Rewrite()
+	if err != nil {
+		return bosherr.WrapError(err, "Rewriting deployment state snapshots")
+	}
+
+	return nil
+}
+
+// recordPreviousSnapshot records the state as it was before the command changed it.
+func (s *remoteDeploymentStateService) recordPreviousSnapshot() {
+	s.recordedPrevious = true
+
+	exists, err := s.blobstore.Exists(s.blobID)
+	if err != nil || !exists {
+		return
+	}
+
+	contents, err := s.download(s.blobID)
+	if err == nil {
+		contents, err = decryptDeploymentState(s.encryptor, contents, s.uri)
+	}
+
+	var deploymentState DeploymentState
+
+	if err == nil {
+		err = json.Unmarshal(contents, &deploymentState)
+	}
+
+	if err == nil {
+		err = s.history().Record(deploymentState, contents)
+	}
+
+	if err != nil {
+		s.logger.Warn(s.logTag, "Recording previous deployment state snapshot: %s", err.Error())
+	}
+}
+
+func (s *remoteDeploymentStateService) recordPendingSnapshot() {
+	if s.pendingSnapshot == nil {
+		return
+	}
+
+	err := s.history().Record(s.pendingSnapshot.state, s.pendingSnapshot.contents)
+	if err != nil {
+		s.logger.Warn(s.logTag, "Recording deployment state snapshot: %s", err.Error())
+	}
+
+	s.pendingSnapshot = nil
+}
+
+func (s *remoteDeploymentStateService) history() deploymentStateHistory {
+	return newDeploymentStateHistory(remoteHistoryStore{service: s, prefix: s.blobID + ".history/"}, s.encryptor)
+}
+
+type remoteHistoryStore struct {
+	service *remoteDeploymentStateService
+	prefix  string
+}
+
+func (s remoteHistoryStore) ReadHistoryFile(name string) ([]byte, bool, error) {
+	exists, err := s.service.blobstore.Exists(s.prefix + name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !exists {
+		return nil, false, nil
+	}
+
+	contents, err := s.service.download(s.prefix + name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return contents, true, nil
+}
+
+func (s remoteHistoryStore) WriteHistoryFile(name string, contents []byte) error {
+	path, err := s.service.writeTempFile(contents)
+	if err != nil {
+		return err
+	}
+
+	defer s.service.fs.RemoveAll(path) //nolint:errcheck
+
+	return s.service.blobstore.Put(path, s.prefix+name)
+}
+
+func (s remoteHistoryStore) DeleteHistoryFile(name string) error {
+	return s.service.blobstore.Delete(s.prefix + name)
+}
@@ -0,0 +1,313 @@
+package config_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/config"
+)
+
+var _ = Describe("remoteDeploymentStateService", func() {
+	var (
+		server         *ghttp.Server
+		service        DeploymentStateService
+		historyObjects map[string][]byte
+		historyHandler http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		historyObjects = map[string][]byte{}
+		historyHandler = func(w http.ResponseWriter, req *http.Request) {
+			contents, found := historyObjects[req.URL.Path]
+
+			switch req.Method {
+			case "PUT":
+				body, err := io.ReadAll(req.Body)
+				Expect(err).ToNot(HaveOccurred())
+				historyObjects[req.URL.Path] = body
+				w.WriteHeader(http.StatusCreated)
+			case "DELETE":
+				delete(historyObjects, req.URL.Path)
+			default:
+				if !found {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, err := w.Write(contents)
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+		for _, method := range []string{"HEAD", "GET", "PUT", "DELETE"} {
+			server.RouteToHandler(method, regexp.MustCompile(`^/states/director\.json\.history/`), historyHandler)
+		}
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := boshsys.NewOsFileSystem(logger)
+		blobstore := NewHTTPStateBlobstore(server.URL()+"/states/", "fake-user", "fake-pass", http.DefaultClient, fs)
+
+		service = NewRemoteDeploymentStateService(
+			blobstore, "director.json", fs, fakeuuid.NewFakeGenerator(), logger, "http://example.com/states/director.json", nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the uri as its path", func() {
+		Expect(service.Path()).To(Equal("http://example.com/states/director.json"))
+	})
+
+	Describe("Exists", func() {
+		It("returns true when the state object exists", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("HEAD", "/states/director.json"),
+				ghttp.VerifyBasicAuth("fake-user", "fake-pass"),
+				ghttp.RespondWith(http.StatusOK, nil),
+			))
+
+			Expect(service.Exists()).To(BeTrue())
+		})
+
+		It("returns false when the state object is missing", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, nil))
+
+			Expect(service.Exists()).To(BeFalse())
+		})
+	})
+
+	Describe("Load", func() {
+		It("downloads and parses the state", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("HEAD", "/states/director.json"),
+					ghttp.RespondWith(http.StatusOK, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/states/director.json"),
+					ghttp.RespondWith(http.StatusOK, `{"director_id":"fake-director-id","current_vm_cid":"fake-vm-cid"}`),
+				),
+			)
+
+			state, err := service.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state.DirectorID).To(Equal("fake-director-id"))
+			Expect(state.CurrentVMCID).To(Equal("fake-vm-cid"))
+		})
+
+		It("generates and uploads a director id when the state does not exist yet", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("HEAD", "/states/director.json"),
+					ghttp.RespondWith(http.StatusNotFound, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/states/director.json"),
+					func(w http.ResponseWriter, req *http.Request) {
+						var state DeploymentState
+						Expect(json.NewDecoder(req.Body).Decode(&state)).To(Succeed())
+						Expect(state.DirectorID).To(Equal("fake-uuid-0"))
+					},
+					ghttp.RespondWith(http.StatusCreated, nil),
+				),
+			)
+
+			state, err := service.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(DeploymentState{DirectorID: "fake-uuid-0"}))
+		})
+
+		It("returns an error when downloading fails", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, nil),
+				ghttp.RespondWith(http.StatusInternalServerError, nil),
+			)
+
+			_, err := service.Load()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Downloading deployment state 'http://example.com/states/director.json'"))
+			Expect(err.Error()).To(ContainSubstring("unexpected status 500"))
+		})
+	})
+
+	Describe("Save", func() {
+		It("uploads the state as json", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/states/director.json"),
+				func(w http.ResponseWriter, req *http.Request) {
+					var state DeploymentState
+					Expect(json.NewDecoder(req.Body).Decode(&state)).To(Succeed())
+					Expect(state).To(Equal(DeploymentState{DirectorID: "fake-director-id", CurrentDiskID: "fake-disk-id"}))
+				},
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			))
+
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentDiskID: "fake-disk-id"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("records a single snapshot of the last saved state when the lock is released", func() {
+			for _, method := range []string{"HEAD", "GET", "PUT", "DELETE"} {
+				server.RouteToHandler(method, "/states/director.json.lock", historyHandler)
+			}
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("HEAD", "/states/director.json"),
+					ghttp.RespondWith(http.StatusNotFound, nil),
+				),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			)
+
+			Expect(service.AcquireLock()).To(Succeed())
+
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-1"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(BeEmpty())
+
+			Expect(service.ReleaseLock()).To(Succeed())
+
+			snapshots, err = service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(1))
+			Expect(snapshots[0].VMCID).To(Equal("fake-vm-cid-2"))
+
+			state, err := service.LoadSnapshot(snapshots[0].ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"}))
+		})
+
+		It("records the state from before the command when the lock is released", func() {
+			for _, method := range []string{"HEAD", "GET", "PUT", "DELETE"} {
+				server.RouteToHandler(method, "/states/director.json.lock", historyHandler)
+			}
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("HEAD", "/states/director.json"),
+					ghttp.RespondWith(http.StatusOK, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/states/director.json"),
+					ghttp.RespondWith(http.StatusOK, `{"director_id":"fake-director-id","current_vm_cid":"fake-vm-cid-1"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/states/director.json"),
+					ghttp.RespondWith(http.StatusNoContent, nil),
+				),
+			)
+
+			Expect(service.AcquireLock()).To(Succeed())
+
+			err := service.Save(DeploymentState{DirectorID: "fake-director-id", CurrentVMCID: "fake-vm-cid-2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			delete(historyObjects, "/states/director.json.lock")
+
+			Expect(service.ReleaseLock()).To(Succeed())
+
+			snapshots, err := service.History()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshots).To(HaveLen(2))
+			Expect(snapshots[0].VMCID).To(Equal("fake-vm-cid-1"))
+			Expect(snapshots[1].VMCID).To(Equal("fake-vm-cid-2"))
+		})
+
+		It("returns an error when uploading fails", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusForbidden, nil))
+
+			err := service.Save(DeploymentState{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Uploading deployment state"))
+		})
+	})
+
+	Describe("Cleanup", func() {
+		It("deletes the state object", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/states/director.json"),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			))
+
+			Expect(service.Cleanup()).To(Succeed())
+		})
+
+		It("ignores missing state objects", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, nil))
+
+			Expect(service.Cleanup()).To(Succeed())
+		})
+
+		It("deletes the snapshots", func() {
+			historyObjects["/states/director.json.history/index.json"] = []byte(`[{"id":"fake-snapshot-id"}]`)
+			historyObjects["/states/director.json.history/fake-snapshot-id.json"] = []byte(`{}`)
+
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNoContent, nil))
+
+			Expect(service.Cleanup()).To(Succeed())
+			Expect(historyObjects).To(BeEmpty())
+		})
+	})
+
+	Describe("AcquireLock", func() {
+		It("creates the lock object with a conditional write", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("HEAD", "/states/director.json.lock"),
+					ghttp.RespondWith(http.StatusNotFound, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/states/director.json.lock"),
+					ghttp.VerifyHeaderKV("If-None-Match", "*"),
+					ghttp.RespondWith(http.StatusCreated, nil),
+				),
+			)
+
+			Expect(service.AcquireLock()).To(Succeed())
+		})
+
+		It("returns a locked error when the conditional write loses a race", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusNotFound, nil),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("PUT", "/states/director.json.lock"),
+					ghttp.RespondWith(http.StatusPreconditionFailed, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/states/director.json.lock"),
+					ghttp.RespondWith(http.StatusOK, `{"id":"other","owner":"alice","pid":42,"hostname":"jumpbox"}`),
+				),
+			)
+
+			err := service.AcquireLock()
+			Expect(err).To(BeAssignableToTypeOf(DeploymentStateLockedError{}))
+			Expect(err.Error()).To(ContainSubstring("is locked by alice@jumpbox (PID 42)"))
+		})
+	})
+
+	Describe("ForceReleaseLock", func() {
+		It("deletes the lock object", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/states/director.json.lock"),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			))
+
+			Expect(service.ForceReleaseLock()).To(Succeed())
+		})
+	})
+})
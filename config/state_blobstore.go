@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// StateBlobstore stores deployment state objects under fixed blob IDs.
+// releasedir.S3Blobstore and releasedir.GCSBlobstore satisfy it.
+type StateBlobstore interface {
+	Get(blobID string) (string, error)
+	Put(path string, blobID string) error
+	Exists(blobID string) (bool, error)
+	Delete(blobID string) error
+
+	// PutIfAbsent atomically creates the object only if it does not exist
+	// yet and reports whether it was created; it is used for state locks.
+	PutIfAbsent(path string, blobID string) (bool, error)
+}
+
+type httpStateBlobstore struct {
+	endpoint string
+	username string
+	password string
+	client   *http.Client
+	fs       boshsys.FileSystem
+}
+
+// NewHTTPStateBlobstore returns a StateBlobstore that uses plain
+// GET/PUT/DELETE/HEAD requests against <endpoint>/<blobID>.
+func NewHTTPStateBlobstore(endpoint, username, password string, client *http.Client, fs boshsys.FileSystem) StateBlobstore {
+	return httpStateBlobstore{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		username: username,
+		password: password,
+		client:   client,
+		fs:       fs,
+	}
+}
+
+func (b httpStateBlobstore) Get(blobID string) (string, error) {
+	resp, err := b.do("GET", blobID, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return "", b.statusErr("GET", blobID, resp)
+	}
+
+	file, err := b.fs.TempFile("bosh-http-state")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Creating destination file")
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	_, err = io.Copy(file, resp.Body)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading response body")
+	}
+
+	return file.Name(), nil
+}
+
+func (b httpStateBlobstore) Put(path string, blobID string) error {
+	contents, err := b.fs.ReadFile(path)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading source file")
+	}
+
+	resp, err := b.do("PUT", blobID, bytes.NewReader(contents), nil)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return b.statusErr("PUT", blobID, resp)
+	}
+
+	return nil
+}
+
+func (b httpStateBlobstore) PutIfAbsent(path string, blobID string) (bool, error) {
+	contents, err := b.fs.ReadFile(path)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Reading source file")
+	}
+
+	resp, err := b.do("PUT", blobID, bytes.NewReader(contents), map[string]string{"If-None-Match": "*"})
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return false, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, b.statusErr("PUT", blobID, resp)
+	}
+
+	return true, nil
+}
+
+func (b httpStateBlobstore) Exists(blobID string) (bool, error) {
+	resp, err := b.do("HEAD", blobID, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, b.statusErr("HEAD", blobID, resp)
+	}
+}
+
+func (b httpStateBlobstore) Delete(blobID string) error {
+	resp, err := b.do("DELETE", blobID, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return b.statusErr("DELETE", blobID, resp)
+	}
+
+	return nil
+}
+
+func (b httpStateBlobstore) do(method, blobID string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, b.url(blobID), body)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Building %s request for '%s'", method, b.url(blobID))
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if b.username != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Performing %s request for '%s'", method, b.url(blobID))
+	}
+
+	return resp, nil
+}
+
+func (b httpStateBlobstore) url(blobID string) string {
+	return fmt.Sprintf("%s/%s", b.endpoint, blobID)
+}
+
+func (b httpStateBlobstore) statusErr(method, blobID string, resp *http.Response) error {
+	return bosherr.Errorf("%s '%s' returned unexpected status %d", method, b.url(blobID), resp.StatusCode)
+}
@@ -16,10 +16,17 @@ const (
 type CpiInstaller struct {
 	ReleaseManager   birel.Manager
 	InstallerFactory biinstall.InstallerFactory
+
+	// SkipInstall is set when CPI calls are replayed and the CPI is never run
+	SkipInstall bool
 }
 
 func (i CpiInstaller) ValidateCpiRelease(installationManifest biinstallmanifest.Manifest, stage biui.Stage) error {
 	return stage.Perform("Validating cpi release", func() error {
+		if installationManifest.UsesLocalFakeCPI() {
+			return biui.NewSkipStageError(bosherr.Error("The local fake CPI is built in"), "Using the local fake CPI")
+		}
+
 		var (
 			errs                   []error
 			releasePackagingErrs   []error
@@ -75,7 +82,25 @@ func (i CpiInstaller) installCpiRelease(installer biinstall.Installer, installat
 	return installation, nil
 }
 
+// WithInstalledCpiRelease calls fn with an installation without jobs when
+// the local fake CPI is used or CPI calls are replayed, since there is
+// nothing to install.
 func (i CpiInstaller) WithInstalledCpiRelease(installationManifest biinstallmanifest.Manifest, target biinstall.Target, stage biui.Stage, fn func(biinstall.Installation) error) (errToReturn error) {
+	if installationManifest.UsesLocalFakeCPI() {
+		return fn(biinstall.NewInstallation(target, []biinstall.InstalledJob{}, installationManifest))
+	}
+
+	if i.SkipInstall {
+		err := stage.Perform("installing CPI", func() error {
+			return biui.NewSkipStageError(bosherr.Error("CPI calls are replayed"), "Replaying CPI calls")
+		})
+		if err != nil {
+			return err
+		}
+
+		return fn(biinstall.NewInstallation(target, []biinstall.InstalledJob{}, installationManifest))
+	}
+
 	installer := i.InstallerFactory.NewInstaller(target)
 
 	installation, err := i.installCpiRelease(installer, installationManifest, target, stage)
@@ -215,4 +215,60 @@ var _ = Describe("Installer", func() {
 			})
 		})
 	})
+
+	Describe("with the local fake CPI", func() {
+		var (
+			installationManifest biinstallationmanifest.Manifest
+			stage                *fakeui.FakeStage
+			cpiInstaller         release.CpiInstaller
+		)
+
+		BeforeEach(func() {
+			installationManifest = biinstallationmanifest.Manifest{CPI: biinstallationmanifest.LocalFakeCPI}
+			stage = fakeui.NewFakeStage()
+			cpiInstaller = release.CpiInstaller{}
+		})
+
+		It("skips validating the cpi release", func() {
+			err := cpiInstaller.ValidateCpiRelease(installationManifest, stage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stage.PerformCalls[0].Name).To(Equal("Validating cpi release"))
+			Expect(stage.PerformCalls[0].SkipError).To(BeAssignableToTypeOf(ui.SkipStageError{}))
+		})
+
+		It("calls the passed in function without installing anything", func() {
+			target := biinstallation.NewTarget("fake-installation-path", "")
+
+			var installationArgumentToFunction biinstallation.Installation
+			err := cpiInstaller.WithInstalledCpiRelease(installationManifest, target, stage, func(installation biinstallation.Installation) error {
+				installationArgumentToFunction = installation
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(installationArgumentToFunction.Target()).To(Equal(target))
+			Expect(installationArgumentToFunction.Jobs()).To(BeEmpty())
+			Expect(stage.PerformCalls).To(BeEmpty())
+		})
+	})
+
+	Describe("when CPI calls are replayed", func() {
+		It("calls the passed in function without installing the CPI release", func() {
+			stage := fakeui.NewFakeStage()
+			cpiInstaller := release.CpiInstaller{SkipInstall: true}
+			target := biinstallation.NewTarget("fake-installation-path", "")
+
+			var installationArgumentToFunction biinstallation.Installation
+			err := cpiInstaller.WithInstalledCpiRelease(biinstallationmanifest.Manifest{}, target, stage, func(installation biinstallation.Installation) error {
+				installationArgumentToFunction = installation
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(installationArgumentToFunction.Target()).To(Equal(target))
+			Expect(installationArgumentToFunction.Jobs()).To(BeEmpty())
+			Expect(stage.PerformCalls[0].Name).To(Equal("installing CPI"))
+			Expect(stage.PerformCalls[0].SkipError).To(BeAssignableToTypeOf(ui.SkipStageError{}))
+		})
+	})
 })
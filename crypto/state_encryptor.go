@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// EncryptedStateHeader starts every file written by a StateEncryptor so that
+// encrypted and plain files can be told apart on load.
+const EncryptedStateHeader = "BOSH-ENCRYPTED-STATE:v1:aes-256-gcm\n"
+
+const (
+	stateSaltSize       = 16
+	stateKeySize        = 32
+	stateKeyDerivations = 100000
+)
+
+type StateEncryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(contents []byte) ([]byte, error)
+}
+
+// IsEncryptedState reports whether contents were written by a StateEncryptor.
+func IsEncryptedState(contents []byte) bool {
+	return bytes.HasPrefix(contents, []byte(EncryptedStateHeader))
+}
+
+type aesGCMStateEncryptor struct {
+	secret      []byte
+	salt        []byte
+	derivedKeys map[string][]byte
+}
+
+// NewAESGCMStateEncryptor encrypts with AES-256-GCM. The cipher key is derived
+// from secret (a key or passphrase) with PBKDF2-SHA256 and a random salt
+// stored next to the ciphertext. One salt is used for everything encrypted by
+// the same encryptor so that repeated saves only derive the key once.
+func NewAESGCMStateEncryptor(secret []byte) StateEncryptor {
+	return &aesGCMStateEncryptor{secret: secret, derivedKeys: map[string][]byte{}}
+}
+
+func (e *aesGCMStateEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	if e.salt == nil {
+		salt := make([]byte, stateSaltSize)
+
+		_, err := io.ReadFull(rand.Reader, salt)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Generating salt")
+		}
+
+		e.salt = salt
+	}
+
+	aead, err := e.aead(e.salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Generating nonce")
+	}
+
+	sealed := append(append(append([]byte{}, e.salt...), nonce...), aead.Seal(nil, nonce, plaintext, []byte(EncryptedStateHeader))...)
+
+	contents := []byte(EncryptedStateHeader)
+	contents = append(contents, base64.StdEncoding.EncodeToString(sealed)...)
+	contents = append(contents, '\n')
+
+	return contents, nil
+}
+
+func (e *aesGCMStateEncryptor) Decrypt(contents []byte) ([]byte, error) {
+	if !IsEncryptedState(contents) {
+		return nil, bosherr.Error("Expected contents to be encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(contents[len(EncryptedStateHeader):])))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Decoding encrypted contents")
+	}
+
+	if len(sealed) < stateSaltSize {
+		return nil, bosherr.Error("Encrypted contents are truncated")
+	}
+
+	aead, err := e.aead(sealed[:stateSaltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	sealed = sealed[stateSaltSize:]
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, bosherr.Error("Encrypted contents are truncated")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(EncryptedStateHeader))
+	if err != nil {
+		return nil, bosherr.Error("Decrypting contents: the key is wrong or the contents were modified")
+	}
+
+	return plaintext, nil
+}
+
+func (e *aesGCMStateEncryptor) aead(salt []byte) (cipher.AEAD, error) {
+	if len(e.secret) == 0 {
+		return nil, bosherr.Error("Expected encryption key to be non-empty")
+	}
+
+	// Deriving a key is deliberately slow, so keys are reused per salt
+	key, found := e.derivedKeys[string(salt)]
+	if !found {
+		key = pbkdf2.Key(e.secret, salt, stateKeyDerivations, stateKeySize, sha256.New)
+		e.derivedKeys[string(salt)] = key
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating cipher")
+	}
+
+	return aead, nil
+}
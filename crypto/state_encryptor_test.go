@@ -0,0 +1,88 @@
+package crypto_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/crypto"
+)
+
+var _ = Describe("AESGCMStateEncryptor", func() {
+	var encryptor StateEncryptor
+
+	BeforeEach(func() {
+		encryptor = NewAESGCMStateEncryptor([]byte("fake-key"))
+	})
+
+	It("decrypts what it encrypted", func() {
+		encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(IsEncryptedState(encrypted)).To(BeTrue())
+		Expect(string(encrypted)).To(HavePrefix(EncryptedStateHeader))
+		Expect(string(encrypted)).ToNot(ContainSubstring("fake-plaintext"))
+
+		decrypted, err := encryptor.Decrypt(encrypted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(decrypted)).To(Equal("fake-plaintext"))
+	})
+
+	It("decrypts with a new encryptor given the same key", func() {
+		encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		decrypted, err := NewAESGCMStateEncryptor([]byte("fake-key")).Decrypt(encrypted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(decrypted)).To(Equal("fake-plaintext"))
+	})
+
+	It("uses a new nonce for every encryption", func() {
+		first, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		second, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(first).ToNot(Equal(second))
+	})
+
+	It("returns an error when the key is wrong", func() {
+		encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = NewAESGCMStateEncryptor([]byte("other-key")).Decrypt(encrypted)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Decrypting contents: the key is wrong or the contents were modified"))
+	})
+
+	It("returns an error when the contents were modified", func() {
+		encrypted, err := encryptor.Encrypt([]byte("fake-plaintext"))
+		Expect(err).ToNot(HaveOccurred())
+
+		body := strings.TrimPrefix(string(encrypted), EncryptedStateHeader)
+		tampered := EncryptedStateHeader + strings.Replace(body, body[30:31], string(body[30]^1), 1)
+
+		_, err = encryptor.Decrypt([]byte(tampered))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error when the contents are not encrypted", func() {
+		_, err := encryptor.Decrypt([]byte("{}"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected contents to be encrypted"))
+	})
+
+	It("returns an error when the key is empty", func() {
+		_, err := NewAESGCMStateEncryptor(nil).Encrypt([]byte("fake-plaintext"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Expected encryption key to be non-empty"))
+	})
+
+	Describe("IsEncryptedState", func() {
+		It("returns false for plain contents", func() {
+			Expect(IsEncryptedState([]byte(`{"director_id":"fake"}`))).To(BeFalse())
+		})
+	})
+})
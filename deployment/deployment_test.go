@@ -95,7 +95,7 @@ var _ = Describe("Deployment", func() {
 		fs = fakesys.NewFakeFileSystem()
 
 		fakeUUIDGenerator = fakeuuid.NewFakeGenerator()
-		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/deployment.json")
+		deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/deployment.json", nil)
 
 		fakeRepoUUIDGenerator = fakeuuid.NewFakeGenerator()
 		vmRepo = biconfig.NewVMRepo(deploymentStateService)
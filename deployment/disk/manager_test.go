@@ -30,7 +30,7 @@ var _ = Describe("Manager", func() {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
 		fakeFs = fakesys.NewFakeFileSystem()
 		fakeUUIDGenerator = &fakeuuid.FakeGenerator{}
-		deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, "/fake/path")
+		deploymentStateService := biconfig.NewFileSystemDeploymentStateService(fakeFs, fakeUUIDGenerator, logger, "/fake/path", nil)
 		diskRepo = biconfig.NewDiskRepo(deploymentStateService, fakeUUIDGenerator)
 		managerFactory := bidisk.NewManagerFactory(diskRepo, logger)
 		fakeCloud = fakebicloud.NewFakeCloud()
@@ -27,6 +27,7 @@ type builder struct {
 	jobDependencyCompiler     bistatejob.DependencyCompiler
 	jobListRenderer           bitemplate.JobListRenderer
 	renderedJobListCompressor bitemplate.RenderedJobListCompressor
+	linkResolver              LinkResolver
 	blobstore                 biblobstore.Blobstore
 	logger                    boshlog.Logger
 	logTag                    string
@@ -45,6 +46,7 @@ func NewBuilder(
 		jobDependencyCompiler:     jobDependencyCompiler,
 		jobListRenderer:           jobListRenderer,
 		renderedJobListCompressor: renderedJobListCompressor,
+		linkResolver:              NewLinkResolver(),
 		blobstore:                 blobstore,
 		logger:                    logger,
 		logTag:                    "instanceStateBuilder",
@@ -83,7 +85,12 @@ func (b *builder) Build(jobName string, instanceID int, deploymentManifest bidep
 		return nil, err
 	}
 
-	renderedJobTemplates, err := b.renderJobTemplates(releaseJobs, releaseJobProperties, deploymentJob.Properties, deploymentManifest.Properties, deploymentManifest.Name, defaultAddress, stage)
+	links, err := b.linkResolver.Resolve(deploymentManifest.Name, deploymentJob, releaseJobs, deploymentManifest.Properties, instanceID, defaultAddress)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Resolving links for instance '%s/%d'", jobName, instanceID)
+	}
+
+	renderedJobTemplates, err := b.renderJobTemplates(releaseJobs, releaseJobProperties, deploymentJob.Properties, deploymentManifest.Properties, deploymentManifest.Name, defaultAddress, links, stage)
 	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Rendering job templates for instance '%s/%d'", jobName, instanceID)
 	}
@@ -187,6 +194,7 @@ func (b *builder) renderJobTemplates(
 	globalProperties biproperty.Map,
 	deploymentName string,
 	address string,
+	links map[string]map[string]bitemplate.LinkSpec,
 	stage biui.Stage,
 ) (renderedJobs, error) {
 	var (
@@ -194,7 +202,7 @@ func (b *builder) renderJobTemplates(
 		blobID                 string
 	)
 	err := stage.Perform("Rendering job templates", func() error {
-		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, links)
 		if err != nil {
 			return err
 		}
@@ -18,6 +18,7 @@ import (
 	. "github.com/cloudfoundry/bosh-cli/v7/release/resource"
 	bistatejob "github.com/cloudfoundry/bosh-cli/v7/state/job"
 	mockstatejob "github.com/cloudfoundry/bosh-cli/v7/state/job/mocks"
+	bitemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler"
 	mocktemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler/mocks"
 	fakebiui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
 )
@@ -287,7 +288,7 @@ var _ = Describe("Builder", func() {
 				"fake-job-property": "fake-global-property-value",
 			}
 
-			mockJobListRenderer.EXPECT().Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, "fake-deployment-name", expectedIP).Return(mockRenderedJobList, nil)
+			mockJobListRenderer.EXPECT().Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, "fake-deployment-name", expectedIP, map[string]map[string]bitemplate.LinkSpec{}).Return(mockRenderedJobList, nil)
 
 			mockRenderedJobList.EXPECT().DeleteSilently()
 
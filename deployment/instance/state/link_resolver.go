@@ -0,0 +1,302 @@
+package state
+
+import (
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+
+	bideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
+	bireljob "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	bitemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler"
+)
+
+// LinkResolver resolves links between jobs colocated on an instance,
+// similarly to how the Director resolves links within an instance group.
+// Manually specified links are used as is.
+type LinkResolver struct{}
+
+type linkProvider struct {
+	name       string
+	definition boshjobman.LinkDefinition
+	job        bireljob.Job
+	properties biproperty.Map
+}
+
+func NewLinkResolver() LinkResolver {
+	return LinkResolver{}
+}
+
+// Resolve returns consumed links keyed by consuming job name and link name.
+// Release jobs are expected to be in the same order as deployment job templates.
+func (r LinkResolver) Resolve(
+	deploymentName string,
+	deploymentJob bideplmanifest.Job,
+	releaseJobs []bireljob.Job,
+	globalProperties biproperty.Map,
+	instanceID int,
+	address string,
+) (map[string]map[string]bitemplate.LinkSpec, error) {
+	providers, err := r.providers(deploymentJob, releaseJobs, globalProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	// All providers are colocated on the same instance
+	localLink := bitemplate.LinkSpec{
+		Address:        address,
+		InstanceGroup:  deploymentJob.Name,
+		DeploymentName: deploymentName,
+		Instances: []bitemplate.LinkInstanceSpec{{
+			Name:      deploymentJob.Name,
+			Index:     instanceID,
+			Address:   address,
+			Bootstrap: true,
+		}},
+	}
+
+	links := map[string]map[string]bitemplate.LinkSpec{}
+
+	for i, releaseJob := range releaseJobs {
+		jobRef := deploymentJob.Templates[i]
+
+		for name := range jobRef.Consumes {
+			if _, found := r.findDefinition(releaseJob.Consumes, name); !found {
+				return nil, bosherr.Errorf("Job '%s' does not consume link '%s'", releaseJob.Name(), name)
+			}
+		}
+
+		for _, consumed := range releaseJob.Consumes {
+			link, found, err := r.resolve(releaseJob, consumed, jobRef.Consumes[consumed.Name], providers, localLink)
+			if err != nil {
+				return nil, err
+			}
+
+			if !found {
+				continue
+			}
+
+			if links[releaseJob.Name()] == nil {
+				links[releaseJob.Name()] = map[string]bitemplate.LinkSpec{}
+			}
+
+			links[releaseJob.Name()][consumed.Name] = link
+		}
+	}
+
+	return links, nil
+}
+
+func (r LinkResolver) providers(deploymentJob bideplmanifest.Job, releaseJobs []bireljob.Job, globalProperties biproperty.Map) ([]linkProvider, error) {
+	var providers []linkProvider
+
+	for i, releaseJob := range releaseJobs {
+		jobRef := deploymentJob.Templates[i]
+
+		for name := range jobRef.Provides {
+			if _, found := r.findDefinition(releaseJob.Provides, name); !found {
+				return nil, bosherr.Errorf("Job '%s' does not provide link '%s'", releaseJob.Name(), name)
+			}
+		}
+
+		properties := r.jobProperties(jobRef, deploymentJob.Properties, globalProperties)
+
+		for _, provided := range releaseJob.Provides {
+			override := jobRef.Provides[provided.Name]
+			if override.Blocked {
+				continue
+			}
+
+			name := provided.Name
+			if len(override.As) > 0 {
+				name = override.As
+			}
+
+			providers = append(providers, linkProvider{
+				name:       name,
+				definition: provided,
+				job:        releaseJob,
+				properties: properties,
+			})
+		}
+	}
+
+	return providers, nil
+}
+
+func (r LinkResolver) resolve(
+	releaseJob bireljob.Job,
+	consumed boshjobman.LinkDefinition,
+	override bideplmanifest.ConsumedLink,
+	providers []linkProvider,
+	localLink bitemplate.LinkSpec,
+) (bitemplate.LinkSpec, bool, error) {
+	if override.Blocked {
+		return bitemplate.LinkSpec{}, false, nil
+	}
+
+	if override.Manual != nil {
+		return r.manualLink(*override.Manual), true, nil
+	}
+
+	errPrefix := "Cannot resolve link '" + consumed.Name + "' of job '" + releaseJob.Name() + "'"
+
+	var candidates []linkProvider
+
+	for _, provider := range providers {
+		if len(override.From) > 0 {
+			if provider.name == override.From {
+				candidates = append(candidates, provider)
+			}
+		} else if provider.definition.Type == consumed.Type {
+			candidates = append(candidates, provider)
+		}
+	}
+
+	switch {
+	case len(candidates) == 1:
+		provider := candidates[0]
+
+		if provider.definition.Type != consumed.Type {
+			return bitemplate.LinkSpec{}, false, bosherr.Errorf(
+				"%s: provider '%s' has type '%s' but expected '%s'", errPrefix, provider.name, provider.definition.Type, consumed.Type)
+		}
+
+		localLink.Properties = r.linkProperties(provider)
+
+		return localLink, true, nil
+
+	case len(candidates) > 1:
+		var names []string
+
+		for _, candidate := range candidates {
+			names = append(names, candidate.job.Name()+"."+candidate.name)
+		}
+
+		return bitemplate.LinkSpec{}, false, bosherr.Errorf(
+			"%s: multiple providers of type '%s' found (%s)", errPrefix, consumed.Type, strings.Join(names, ", "))
+
+	case len(override.From) > 0:
+		return bitemplate.LinkSpec{}, false, bosherr.Errorf(
+			"%s: no provider named '%s' found in instance group '%s'", errPrefix, override.From, localLink.InstanceGroup)
+
+	case consumed.Optional:
+		return bitemplate.LinkSpec{}, false, nil
+
+	default:
+		return bitemplate.LinkSpec{}, false, bosherr.Errorf(
+			"%s: no provider of type '%s' found in instance group '%s'", errPrefix, consumed.Type, localLink.InstanceGroup)
+	}
+}
+
+func (r LinkResolver) manualLink(manual bideplmanifest.ManualLink) bitemplate.LinkSpec {
+	link := bitemplate.LinkSpec{
+		Address:    manual.Address,
+		Instances:  []bitemplate.LinkInstanceSpec{},
+		Properties: manual.Properties,
+	}
+
+	for _, instance := range manual.Instances {
+		link.Instances = append(link.Instances, bitemplate.LinkInstanceSpec(instance))
+	}
+
+	return link
+}
+
+// linkProperties picks properties exposed by the link
+// out of provider's effective properties falling back to job spec defaults
+func (r LinkResolver) linkProperties(provider linkProvider) biproperty.Map {
+	properties := biproperty.Map{}
+
+	names := append([]string{}, provider.definition.Properties...)
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, found := lookupProperty(provider.properties, name)
+		if !found {
+			value = provider.job.Properties[name].Default
+		}
+
+		setProperty(properties, name, value)
+	}
+
+	return properties
+}
+
+// jobProperties matches how templates see properties: job level properties
+// take precedence over instance group properties merged on top of global properties
+func (r LinkResolver) jobProperties(jobRef bideplmanifest.ReleaseJobRef, instanceGroupProperties, globalProperties biproperty.Map) biproperty.Map {
+	if jobRef.Properties != nil {
+		return *jobRef.Properties
+	}
+
+	return mergeProperties(globalProperties, instanceGroupProperties)
+}
+
+func (r LinkResolver) findDefinition(definitions []boshjobman.LinkDefinition, name string) (boshjobman.LinkDefinition, bool) {
+	for _, definition := range definitions {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+
+	return boshjobman.LinkDefinition{}, false
+}
+
+func mergeProperties(base, overrides biproperty.Map) biproperty.Map {
+	result := biproperty.Map{}
+
+	for key, value := range base {
+		result[key] = value
+	}
+
+	for key, value := range overrides {
+		baseMap, baseIsMap := result[key].(biproperty.Map)
+		overrideMap, overrideIsMap := value.(biproperty.Map)
+
+		if baseIsMap && overrideIsMap {
+			result[key] = mergeProperties(baseMap, overrideMap)
+		} else {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+func lookupProperty(properties biproperty.Map, name string) (biproperty.Property, bool) {
+	var current biproperty.Property = properties
+
+	for _, key := range strings.Split(name, ".") {
+		currentMap, ok := current.(biproperty.Map)
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = currentMap[key]
+		if !ok || current == nil {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func setProperty(properties biproperty.Map, name string, value biproperty.Property) {
+	keys := strings.Split(name, ".")
+	current := properties
+
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(biproperty.Map)
+		if !ok {
+			next = biproperty.Map{}
+			current[key] = next
+		}
+
+		current = next
+	}
+
+	current[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,179 @@
+package state_test
+
+import (
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/deployment/instance/state"
+	bideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
+	boshjob "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+	. "github.com/cloudfoundry/bosh-cli/v7/release/resource"
+	bitemplate "github.com/cloudfoundry/bosh-cli/v7/templatescompiler"
+)
+
+var _ = Describe("LinkResolver", func() {
+	var (
+		resolver LinkResolver
+
+		deploymentJob    bideplmanifest.Job
+		dbJob            boshjob.Job
+		webJob           boshjob.Job
+		globalProperties biproperty.Map
+	)
+
+	BeforeEach(func() {
+		resolver = NewLinkResolver()
+
+		dbJob = *boshjob.NewJob(NewResource("db", "", nil))
+		dbJob.Provides = []boshjobman.LinkDefinition{
+			{Name: "conn", Type: "database", Properties: []string{"db.port", "db.user"}},
+		}
+		dbJob.Properties = map[string]boshjob.PropertyDefinition{
+			"db.port": {Default: 5432},
+			"db.user": {Default: "admin"},
+		}
+
+		webJob = *boshjob.NewJob(NewResource("web", "", nil))
+		webJob.Consumes = []boshjobman.LinkDefinition{
+			{Name: "database", Type: "database"},
+		}
+
+		deploymentJob = bideplmanifest.Job{
+			Name: "bosh",
+			Templates: []bideplmanifest.ReleaseJobRef{
+				{Name: "db", Release: "fake-release"},
+				{Name: "web", Release: "fake-release"},
+			},
+			Properties: biproperty.Map{
+				"db": biproperty.Map{"user": "instance-group-user"},
+			},
+		}
+
+		globalProperties = biproperty.Map{
+			"db": biproperty.Map{"user": "global-user", "port": 1234},
+		}
+	})
+
+	resolve := func() (map[string]map[string]bitemplate.LinkSpec, error) {
+		return resolver.Resolve("fake-deployment", deploymentJob, []boshjob.Job{dbJob, webJob}, globalProperties, 0, "10.0.0.1")
+	}
+
+	It("resolves links implicitly by type with provider's properties", func() {
+		links, err := resolve()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(links).To(Equal(map[string]map[string]bitemplate.LinkSpec{
+			"web": {
+				"database": {
+					Address:        "10.0.0.1",
+					InstanceGroup:  "bosh",
+					DeploymentName: "fake-deployment",
+					Instances: []bitemplate.LinkInstanceSpec{
+						{Name: "bosh", Index: 0, Address: "10.0.0.1", Bootstrap: true},
+					},
+					Properties: biproperty.Map{
+						"db": biproperty.Map{"port": 1234, "user": "instance-group-user"},
+					},
+				},
+			},
+		}))
+	})
+
+	It("uses job level properties of the provider and falls back to defaults", func() {
+		deploymentJob.Templates[0].Properties = &biproperty.Map{
+			"db": biproperty.Map{"user": "job-user"},
+		}
+
+		links, err := resolve()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links["web"]["database"].Properties).To(Equal(biproperty.Map{
+			"db": biproperty.Map{"port": 5432, "user": "job-user"},
+		}))
+	})
+
+	It("resolves links by name when consumer specifies 'from' and provider specifies 'as'", func() {
+		deploymentJob.Templates[0].Provides = map[string]bideplmanifest.ProvidedLink{"conn": {As: "primary-db"}}
+		deploymentJob.Templates[1].Consumes = map[string]bideplmanifest.ConsumedLink{"database": {From: "primary-db"}}
+
+		links, err := resolve()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links["web"]).To(HaveKey("database"))
+	})
+
+	It("uses manually specified links as is", func() {
+		deploymentJob.Templates[1].Consumes = map[string]bideplmanifest.ConsumedLink{
+			"database": {Manual: &bideplmanifest.ManualLink{
+				Address:    "db.example.com",
+				Instances:  []bideplmanifest.ManualLinkInstance{{Name: "external", Address: "10.0.0.9"}},
+				Properties: biproperty.Map{"db": biproperty.Map{"port": 3306}},
+			}},
+		}
+
+		links, err := resolve()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links["web"]["database"]).To(Equal(bitemplate.LinkSpec{
+			Address:    "db.example.com",
+			Instances:  []bitemplate.LinkInstanceSpec{{Name: "external", Address: "10.0.0.9"}},
+			Properties: biproperty.Map{"db": biproperty.Map{"port": 3306}},
+		}))
+	})
+
+	It("does not resolve blocked links", func() {
+		deploymentJob.Templates[1].Consumes = map[string]bideplmanifest.ConsumedLink{"database": {Blocked: true}}
+
+		links, err := resolve()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links).To(BeEmpty())
+	})
+
+	It("skips optional links without a provider", func() {
+		deploymentJob.Templates[0].Provides = map[string]bideplmanifest.ProvidedLink{"conn": {Blocked: true}}
+		webJob.Consumes[0].Optional = true
+
+		links, err := resolve()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links).To(BeEmpty())
+	})
+
+	It("returns error if required link has no provider", func() {
+		deploymentJob.Templates[0].Provides = map[string]bideplmanifest.ProvidedLink{"conn": {Blocked: true}}
+
+		_, err := resolve()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Cannot resolve link 'database' of job 'web': no provider of type 'database' found in instance group 'bosh'"))
+	})
+
+	It("returns error if multiple providers of the same type are found", func() {
+		dbJob.Provides = append(dbJob.Provides, boshjobman.LinkDefinition{Name: "replica", Type: "database"})
+
+		_, err := resolve()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("multiple providers of type 'database' found (db.conn, db.replica)"))
+	})
+
+	It("returns error if provider referenced by 'from' has a different type", func() {
+		dbJob.Provides = append(dbJob.Provides, boshjobman.LinkDefinition{Name: "metrics", Type: "prometheus"})
+		deploymentJob.Templates[1].Consumes = map[string]bideplmanifest.ConsumedLink{"database": {From: "metrics"}}
+
+		_, err := resolve()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("provider 'metrics' has type 'prometheus' but expected 'database'"))
+	})
+
+	It("returns error if manifest references links not defined in job spec", func() {
+		deploymentJob.Templates[1].Consumes = map[string]bideplmanifest.ConsumedLink{"unknown": {From: "conn"}}
+
+		_, err := resolve()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Job 'web' does not consume link 'unknown'"))
+
+		deploymentJob.Templates[1].Consumes = nil
+		deploymentJob.Templates[0].Provides = map[string]bideplmanifest.ProvidedLink{"unknown": {}}
+
+		_, err = resolve()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Job 'db' does not provide link 'unknown'"))
+	})
+})
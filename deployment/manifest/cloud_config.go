@@ -0,0 +1,34 @@
+package manifest
+
+import (
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+)
+
+// AZ, VMType, VMExtension and Stemcell are the director-style (cloud-config)
+// counterparts of resource pools. Parser translates instance groups using them
+// into a ResourcePool per instance group.
+
+type AZ struct {
+	Name            string
+	CloudProperties biproperty.Map
+}
+
+type VMType struct {
+	Name            string
+	CloudProperties biproperty.Map
+}
+
+type VMExtension struct {
+	Name            string
+	CloudProperties biproperty.Map
+}
+
+type Stemcell struct {
+	Alias string
+	URL   string
+	SHA1  string
+}
+
+func (s Stemcell) Ref() StemcellRef {
+	return StemcellRef{URL: s.URL, SHA1: s.SHA1}
+}
@@ -14,6 +14,22 @@ type Job struct {
 	PersistentDiskPool string
 	ResourcePool       string
 	Properties         biproperty.Map
+
+	// Director-style references, translated into ResourcePool
+	// and PersistentDiskPool by Parser
+	AZs          []string
+	VMType       string
+	VMExtensions []string
+	Stemcell     string
+}
+
+// AZ returns the availability zone of the job, create-env places
+// its single instance into the first one
+func (j Job) AZ() string {
+	if len(j.AZs) == 0 {
+		return ""
+	}
+	return j.AZs[0]
 }
 
 type JobLifecycle string
@@ -27,6 +43,37 @@ type ReleaseJobRef struct {
 	Name       string
 	Release    string
 	Properties *biproperty.Map
+
+	Consumes map[string]ConsumedLink
+	Provides map[string]ProvidedLink
+}
+
+// ConsumedLink configures how a consumed link is resolved.
+// Links are resolved implicitly by type unless From or Manual is specified.
+type ConsumedLink struct {
+	Blocked bool
+	From    string
+	Manual  *ManualLink
+}
+
+type ManualLink struct {
+	Address    string
+	Instances  []ManualLinkInstance
+	Properties biproperty.Map
+}
+
+type ManualLinkInstance struct {
+	Name      string
+	ID        string
+	Index     int
+	AZ        string
+	Address   string
+	Bootstrap bool
+}
+
+type ProvidedLink struct {
+	Blocked bool
+	As      string
 }
 
 type JobNetwork struct {
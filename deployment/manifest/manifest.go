@@ -12,6 +12,10 @@ type Manifest struct {
 	Networks      []Network
 	DiskPools     []DiskPool
 	ResourcePools []ResourcePool
+	AZs           []AZ
+	VMTypes       []VMType
+	VMExtensions  []VMExtension
+	Stemcells     []Stemcell
 	Update        Update
 	Tags          map[string]string
 }
@@ -35,6 +39,9 @@ func (d Manifest) NetworkInterfaces(jobName string) (map[string]biproperty.Map,
 	var err error
 	for _, jobNetwork := range job.Networks {
 		network := networkMap[jobNetwork.Name]
+		if d.UsesVMTypes() {
+			network = network.inAZ(job.AZ())
+		}
 		ifaceMap[jobNetwork.Name], err = network.Interface(jobNetwork.StaticIPs, jobNetwork.Defaults)
 		if err != nil {
 			return map[string]biproperty.Map{}, bosherr.WrapError(err, "Building network interface")
@@ -102,6 +109,22 @@ func (d Manifest) DiskPool(jobName string) (DiskPool, error) {
 	return DiskPool{}, nil
 }
 
+// UsesVMTypes reports whether instance groups are described with
+// director-style vm_types and stemcells instead of resource pools
+func (d Manifest) UsesVMTypes() bool {
+	if len(d.VMTypes) > 0 || len(d.Stemcells) > 0 {
+		return true
+	}
+
+	for _, job := range d.Jobs {
+		if job.VMType != "" || job.Stemcell != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (d Manifest) networkMap() map[string]Network {
 	result := map[string]Network{}
 	for _, network := range d.Networks {
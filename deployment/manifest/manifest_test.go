@@ -184,6 +184,52 @@ var _ = Describe("Manifest", func() {
 				})
 			})
 		})
+
+		Context("when the job uses a vm type and subnets are in azs", func() {
+			BeforeEach(func() {
+				deploymentManifest = Manifest{
+					Networks: []Network{
+						{
+							Name: "fake-network-name",
+							Type: "dynamic",
+							Subnets: []Subnet{
+								{
+									DNS:             []string{"1.1.1.1"},
+									CloudProperties: biproperty.Map{"subnet": "fake-subnet-z1"},
+									AZs:             []string{"z1"},
+								},
+								{
+									DNS:             []string{"2.2.2.2"},
+									CloudProperties: biproperty.Map{"subnet": "fake-subnet-z2"},
+									AZs:             []string{"z2"},
+								},
+							},
+						},
+					},
+					Jobs: []Job{
+						{
+							Name:     "fake-job-name",
+							AZs:      []string{"z2"},
+							VMType:   "default",
+							Networks: []JobNetwork{{Name: "fake-network-name"}},
+						},
+					},
+				}
+			})
+
+			It("uses dns and cloud properties of the subnet in the job az", func() {
+				networkInterfaces, err := deploymentManifest.NetworkInterfaces("fake-job-name")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(networkInterfaces).To(Equal(map[string]biproperty.Map{
+					"fake-network-name": {
+						"type":             "dynamic",
+						"dns":              []string{"2.2.2.2"},
+						"cloud_properties": biproperty.Map{"subnet": "fake-subnet-z2"},
+						"default":          []NetworkDefault{NetworkDefaultDNS, NetworkDefaultGateway},
+					},
+				}))
+			})
+		})
 	})
 
 	Describe("ResourcePool", func() {
@@ -33,6 +33,56 @@ type Subnet struct {
 	Gateway         string
 	DNS             []string
 	CloudProperties biproperty.Map
+	AZs             []string
+}
+
+// InAZ reports whether the subnet can be used in the availability zone,
+// subnets without azs can be used in any of them
+func (s Subnet) InAZ(az string) bool {
+	if az == "" || len(s.AZs) == 0 {
+		return true
+	}
+
+	for _, subnetAZ := range s.AZs {
+		if subnetAZ == az {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inAZ returns the network restricted to subnets of the availability zone.
+// Dynamic and vip networks take dns and cloud properties from the subnet
+// when they are not specified on the network itself.
+func (n Network) inAZ(az string) Network {
+	if len(n.Subnets) == 0 {
+		return n
+	}
+
+	var subnets []Subnet
+	for _, subnet := range n.Subnets {
+		if subnet.InAZ(az) {
+			subnets = append(subnets, subnet)
+		}
+	}
+
+	if len(subnets) == 0 {
+		return n
+	}
+
+	n.Subnets = subnets
+
+	if n.Type != Manual {
+		if len(n.DNS) == 0 {
+			n.DNS = subnets[0].DNS
+		}
+		if len(n.CloudProperties) == 0 {
+			n.CloudProperties = subnets[0].CloudProperties
+		}
+	}
+
+	return n
 }
 
 // Interface returns a property map representing a generic network interface.
@@ -27,6 +27,11 @@ type manifest struct {
 	Networks       []network
 	ResourcePools  []resourcePool `yaml:"resource_pools"`
 	DiskPools      []diskPool     `yaml:"disk_pools"`
+	AZs            []cloudConfigType
+	VMTypes        []cloudConfigType `yaml:"vm_types"`
+	VMExtensions   []cloudConfigType `yaml:"vm_extensions"`
+	DiskTypes      []diskPool        `yaml:"disk_types"`
+	Stemcells      []stemcell
 	Jobs           []job
 	InstanceGroups []job `yaml:"instance_groups"`
 	Properties     map[interface{}]interface{}
@@ -53,6 +58,8 @@ type subnet struct {
 	Gateway         string                      `yaml:"gateway"`
 	DNS             []string                    `yaml:"dns"`
 	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
+	AZ              string                      `yaml:"az"`
+	AZs             []string                    `yaml:"azs"`
 }
 
 type resourcePool struct {
@@ -69,6 +76,17 @@ type diskPool struct {
 	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
 }
 
+type cloudConfigType struct {
+	Name            string                      `yaml:"name"`
+	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
+}
+
+type stemcell struct {
+	Alias string
+	URL   string
+	SHA1  string
+}
+
 type job struct {
 	Name               string
 	Instances          int
@@ -80,6 +98,13 @@ type job struct {
 	PersistentDiskPool string `yaml:"persistent_disk_pool"`
 	ResourcePool       string `yaml:"resource_pool"`
 	Properties         map[interface{}]interface{}
+
+	AZs                []string
+	VMType             string   `yaml:"vm_type"`
+	VMExtensions       []string `yaml:"vm_extensions"`
+	Stemcell           string
+	PersistentDiskType string                      `yaml:"persistent_disk_type"`
+	Env                map[interface{}]interface{} `yaml:"env"`
 }
 
 type releaseJobRef struct {
@@ -89,6 +114,70 @@ type releaseJobRef struct {
 	// This is a pointer so we can differentiate between `properties: {}`
 	// and not specifying the key at all.
 	Properties *map[interface{}]interface{}
+
+	// Links are blocked with `nil` (or null) values
+	Consumes map[string]*consumedLink
+	Provides map[string]*providedLink
+}
+
+type consumedLink struct {
+	blocked bool
+
+	From string
+
+	Address    string
+	Instances  []manualLinkInstance
+	Properties *map[interface{}]interface{}
+}
+
+type manualLinkInstance struct {
+	Name      string
+	ID        string
+	Index     int
+	AZ        string
+	Address   string
+	Bootstrap bool
+}
+
+type providedLink struct {
+	blocked bool
+
+	As string
+}
+
+func (l *consumedLink) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if blocked, err := unmarshalBlockedLink(unmarshal); blocked || err != nil {
+		l.blocked = blocked
+		return err
+	}
+
+	type plain consumedLink
+	return unmarshal((*plain)(l))
+}
+
+func (l *providedLink) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if blocked, err := unmarshalBlockedLink(unmarshal); blocked || err != nil {
+		l.blocked = blocked
+		return err
+	}
+
+	type plain providedLink
+	return unmarshal((*plain)(l))
+}
+
+// unmarshalBlockedLink recognizes `nil` string used by the Director to block links
+func unmarshalBlockedLink(unmarshal func(interface{}) error) (bool, error) {
+	var str string
+
+	if unmarshal(&str) != nil {
+		return false, nil
+	}
+
+	if str != "nil" {
+		return false, bosherr.Errorf("Expected link to be a hash or 'nil' but was '%s'", str)
+	}
+
+	return true, nil
 }
 
 type stemcellRef struct {
@@ -177,6 +266,11 @@ func (p *parser) parseDeploymentManifest(depManifest manifest, path string) (Man
 	}
 	deployment.Jobs = jobs
 
+	err = p.parseCloudConfigManifests(&deployment, depManifest, rawJobs, path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
 	properties, err := biproperty.BuildMap(depManifest.Properties)
 	if err != nil {
 		return Manifest{}, bosherr.WrapErrorf(err, "Parsing global manifest properties: %#v", depManifest.Properties)
@@ -235,6 +329,14 @@ func (p *parser) parseJobManifests(rawJobs []job) ([]Job, error) {
 					ref.Properties = &properties
 				}
 
+				consumes, err := p.parseConsumedLinks(rawJobRef)
+				if err != nil {
+					return []Job{}, err
+				}
+
+				ref.Consumes = consumes
+				ref.Provides = p.parseProvidedLinks(rawJobRef)
+
 				releaseJobRefs[i] = ref
 			}
 			job.Templates = releaseJobRefs
@@ -275,6 +377,229 @@ func (p *parser) parseJobManifests(rawJobs []job) ([]Job, error) {
 	return jobs, nil
 }
 
+// parseCloudConfigManifests translates director-style vm_types, disk_types and
+// stemcells referenced by instance groups into resource pools and disk pools
+func (p *parser) parseCloudConfigManifests(deployment *Manifest, depManifest manifest, rawJobs []job, path string) error {
+	usesResourcePools := len(depManifest.ResourcePools) > 0 || len(depManifest.DiskPools) > 0
+	usesVMTypes := len(depManifest.VMTypes) > 0 || len(depManifest.DiskTypes) > 0 || len(depManifest.Stemcells) > 0
+
+	for _, rawJob := range rawJobs {
+		if rawJob.ResourcePool != "" || rawJob.PersistentDiskPool != "" {
+			usesResourcePools = true
+		}
+		if rawJob.VMType != "" || rawJob.Stemcell != "" || rawJob.PersistentDiskType != "" {
+			usesVMTypes = true
+		}
+	}
+
+	if usesResourcePools && usesVMTypes {
+		return bosherr.Error("Deployment specifies both resource_pools/disk_pools and vm_types/disk_types/stemcells keys, only one is allowed")
+	}
+
+	for _, rawAZ := range depManifest.AZs {
+		cloudProperties, err := p.parseCloudProperties("az", rawAZ)
+		if err != nil {
+			return err
+		}
+		deployment.AZs = append(deployment.AZs, AZ{Name: rawAZ.Name, CloudProperties: cloudProperties})
+	}
+
+	for _, rawVMType := range depManifest.VMTypes {
+		cloudProperties, err := p.parseCloudProperties("vm_type", rawVMType)
+		if err != nil {
+			return err
+		}
+		deployment.VMTypes = append(deployment.VMTypes, VMType{Name: rawVMType.Name, CloudProperties: cloudProperties})
+	}
+
+	for _, rawVMExtension := range depManifest.VMExtensions {
+		cloudProperties, err := p.parseCloudProperties("vm_extension", rawVMExtension)
+		if err != nil {
+			return err
+		}
+		deployment.VMExtensions = append(deployment.VMExtensions, VMExtension{Name: rawVMExtension.Name, CloudProperties: cloudProperties})
+	}
+
+	diskTypes, err := p.parseDiskPoolManifests(depManifest.DiskTypes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing disk_types: %#v", depManifest.DiskTypes)
+	}
+	deployment.DiskPools = append(deployment.DiskPools, diskTypes...)
+
+	for _, rawStemcell := range depManifest.Stemcells {
+		stemcell := Stemcell(rawStemcell)
+
+		stemcell.URL, err = biutil.AbsolutifyPath(path, stemcell.URL, p.fs)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Resolving stemcell path '%s", stemcell.URL)
+		}
+
+		deployment.Stemcells = append(deployment.Stemcells, stemcell)
+	}
+
+	for i, rawJob := range rawJobs {
+		job := &deployment.Jobs[i]
+		job.AZs = rawJob.AZs
+		job.VMType = rawJob.VMType
+		job.VMExtensions = rawJob.VMExtensions
+		job.Stemcell = rawJob.Stemcell
+
+		if rawJob.PersistentDiskType != "" {
+			job.PersistentDiskPool = rawJob.PersistentDiskType
+		}
+
+		if job.VMType == "" && job.Stemcell == "" {
+			continue
+		}
+
+		resourcePool, err := p.buildResourcePool(*deployment, *job, rawJob.Env)
+		if err != nil {
+			return err
+		}
+
+		job.ResourcePool = resourcePool.Name
+		deployment.ResourcePools = append(deployment.ResourcePools, resourcePool)
+	}
+
+	return nil
+}
+
+// buildResourcePool merges cloud properties of the az, vm_type and vm_extensions
+// in that order, unknown references are left to Validator
+func (p *parser) buildResourcePool(deployment Manifest, job Job, rawEnv map[interface{}]interface{}) (ResourcePool, error) {
+	resourcePool := ResourcePool{
+		Name:            job.Name,
+		CloudProperties: biproperty.Map{},
+	}
+
+	if len(job.Networks) > 0 {
+		resourcePool.Network = job.Networks[0].Name
+	}
+
+	for _, az := range deployment.AZs {
+		if az.Name == job.AZ() {
+			resourcePool.CloudProperties = mergeCloudProperties(resourcePool.CloudProperties, az.CloudProperties)
+		}
+	}
+
+	for _, vmType := range deployment.VMTypes {
+		if vmType.Name == job.VMType {
+			resourcePool.CloudProperties = mergeCloudProperties(resourcePool.CloudProperties, vmType.CloudProperties)
+		}
+	}
+
+	for _, name := range job.VMExtensions {
+		for _, vmExtension := range deployment.VMExtensions {
+			if vmExtension.Name == name {
+				resourcePool.CloudProperties = mergeCloudProperties(resourcePool.CloudProperties, vmExtension.CloudProperties)
+			}
+		}
+	}
+
+	for _, stemcell := range deployment.Stemcells {
+		if stemcell.Alias == job.Stemcell {
+			resourcePool.Stemcell = stemcell.Ref()
+		}
+	}
+
+	env, err := biproperty.BuildMap(rawEnv)
+	if err != nil {
+		return ResourcePool{}, bosherr.WrapErrorf(err, "Parsing instance_group '%s' env: %#v", job.Name, rawEnv)
+	}
+	resourcePool.Env = env
+
+	return resourcePool, nil
+}
+
+func (p *parser) parseCloudProperties(kind string, rawType cloudConfigType) (biproperty.Map, error) {
+	cloudProperties, err := biproperty.BuildMap(rawType.CloudProperties)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing %s '%s' cloud_properties: %#v", kind, rawType.Name, rawType.CloudProperties)
+	}
+
+	return cloudProperties, nil
+}
+
+// mergeCloudProperties returns a copy of base with overrides deep merged into it
+func mergeCloudProperties(base, overrides biproperty.Map) biproperty.Map {
+	result := biproperty.Map{}
+
+	for k, v := range base {
+		result[k] = v
+	}
+
+	for k, v := range overrides {
+		baseValue, baseIsMap := result[k].(biproperty.Map)
+		overrideValue, overrideIsMap := v.(biproperty.Map)
+
+		if baseIsMap && overrideIsMap {
+			result[k] = mergeCloudProperties(baseValue, overrideValue)
+		} else {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+func (p *parser) parseConsumedLinks(rawJobRef releaseJobRef) (map[string]ConsumedLink, error) {
+	if rawJobRef.Consumes == nil {
+		return nil, nil
+	}
+
+	links := map[string]ConsumedLink{}
+
+	for name, rawLink := range rawJobRef.Consumes {
+		if rawLink == nil || rawLink.blocked {
+			links[name] = ConsumedLink{Blocked: true}
+			continue
+		}
+
+		link := ConsumedLink{From: rawLink.From}
+
+		if len(rawLink.Address) > 0 || rawLink.Instances != nil || rawLink.Properties != nil {
+			manual := &ManualLink{Address: rawLink.Address, Properties: biproperty.Map{}}
+
+			if rawLink.Properties != nil {
+				properties, err := biproperty.BuildMap(*rawLink.Properties)
+				if err != nil {
+					return nil, bosherr.WrapErrorf(err, "Parsing job '%s' consumed link '%s' properties", rawJobRef.Name, name)
+				}
+
+				manual.Properties = properties
+			}
+
+			for _, instance := range rawLink.Instances {
+				manual.Instances = append(manual.Instances, ManualLinkInstance(instance))
+			}
+
+			link.Manual = manual
+		}
+
+		links[name] = link
+	}
+
+	return links, nil
+}
+
+func (p *parser) parseProvidedLinks(rawJobRef releaseJobRef) map[string]ProvidedLink {
+	if rawJobRef.Provides == nil {
+		return nil
+	}
+
+	links := map[string]ProvidedLink{}
+
+	for name, rawLink := range rawJobRef.Provides {
+		if rawLink == nil || rawLink.blocked {
+			links[name] = ProvidedLink{Blocked: true}
+		} else {
+			links[name] = ProvidedLink{As: rawLink.As}
+		}
+	}
+
+	return links
+}
+
 func (p *parser) parseNetworkManifests(rawNetworks []network) ([]Network, error) {
 	networks := make([]Network, len(rawNetworks))
 	for i, rawNetwork := range rawNetworks {
@@ -301,6 +626,7 @@ func (p *parser) parseNetworkManifests(rawNetworks []network) ([]Network, error)
 				Gateway:         subnet.Gateway,
 				DNS:             subnet.DNS,
 				CloudProperties: cloudProperties,
+				AZs:             p.subnetAZs(subnet),
 			})
 		}
 
@@ -310,6 +636,13 @@ func (p *parser) parseNetworkManifests(rawNetworks []network) ([]Network, error)
 	return networks, nil
 }
 
+func (p *parser) subnetAZs(rawSubnet subnet) []string {
+	if rawSubnet.AZ != "" {
+		return append([]string{rawSubnet.AZ}, rawSubnet.AZs...)
+	}
+	return rawSubnet.AZs
+}
+
 func (p *parser) parseResourcePoolManifests(rawResourcePools []resourcePool, path string) ([]ResourcePool, error) {
 	resourcePools := make([]ResourcePool, len(rawResourcePools))
 	for i, rawResourcePool := range rawResourcePools {
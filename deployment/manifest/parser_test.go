@@ -550,6 +550,78 @@ instance_groups:
 			})
 		})
 
+		Context("when job specifies consumed and provided links", func() {
+			BeforeEach(func() {
+				contents := `
+---
+instance_groups:
+- name: jobby
+  jobs:
+  - name: job1
+    consumes:
+      db: {from: primary-db}
+      cache: nil
+      queue: ~
+      backup:
+        address: backup.example.com
+        instances:
+        - {name: backup, address: 10.0.0.10, bootstrap: true}
+        properties:
+          bucket: b1
+    provides:
+      db: {as: primary-db}
+      metrics: nil
+`
+				interpolatedTemplate = bidepltpl.NewInterpolatedTemplate([]byte(contents), "fake-sha")
+			})
+
+			It("parses the links", func() {
+				deploymentManifest, err := parser.Parse(interpolatedTemplate, manifestPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				jobRef := deploymentManifest.Jobs[0].Templates[0]
+
+				Expect(jobRef.Consumes).To(Equal(map[string]ConsumedLink{
+					"db":    {From: "primary-db"},
+					"cache": {Blocked: true},
+					"queue": {Blocked: true},
+					"backup": {
+						Manual: &ManualLink{
+							Address:    "backup.example.com",
+							Instances:  []ManualLinkInstance{{Name: "backup", Address: "10.0.0.10", Bootstrap: true}},
+							Properties: biproperty.Map{"bucket": "b1"},
+						},
+					},
+				}))
+
+				Expect(jobRef.Provides).To(Equal(map[string]ProvidedLink{
+					"db":      {As: "primary-db"},
+					"metrics": {Blocked: true},
+				}))
+			})
+		})
+
+		Context("when job blocks link with unexpected value", func() {
+			BeforeEach(func() {
+				contents := `
+---
+instance_groups:
+- name: jobby
+  jobs:
+  - name: job1
+    consumes:
+      db: none
+`
+				interpolatedTemplate = bidepltpl.NewInterpolatedTemplate([]byte(contents), "fake-sha")
+			})
+
+			It("returns an error", func() {
+				_, err := parser.Parse(interpolatedTemplate, manifestPath)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Expected link to be a hash or 'nil' but was 'none'"))
+			})
+		})
+
 		Context("when both instance_groups and jobs are present at root level in deployment manifest", func() {
 			BeforeEach(func() {
 				contents := `
@@ -593,5 +665,159 @@ jobs:
 			})
 		})
 
+		Context("when instance groups use vm_types, disk_types and stemcells", func() {
+			BeforeEach(func() {
+				contents := `
+---
+name: fake-deployment-name
+azs:
+- name: z1
+  cloud_properties:
+    zone: fake-zone
+vm_types:
+- name: default
+  cloud_properties:
+    instance_type: m1.small
+    ephemeral_disk: {size: 10000}
+vm_extensions:
+- name: bigger-disk
+  cloud_properties:
+    ephemeral_disk: {type: gp2}
+disk_types:
+- name: fast
+  disk_size: 2048
+  cloud_properties:
+    type: ssd
+stemcells:
+- alias: default
+  url: http://fake-stemcell-url
+  sha1: fake-sha1
+networks:
+- name: private
+  type: manual
+  subnets:
+  - range: 10.0.0.0/24
+    gateway: 10.0.0.1
+    az: z1
+  - range: 10.0.1.0/24
+    gateway: 10.0.1.1
+    azs: [z2]
+instance_groups:
+- name: bosh
+  azs: [z1]
+  vm_type: default
+  vm_extensions: [bigger-disk]
+  stemcell: default
+  persistent_disk_type: fast
+  env:
+    bosh:
+      password: secret
+  networks:
+  - name: private
+    static_ips: [10.0.0.6]
+`
+				interpolatedTemplate = bidepltpl.NewInterpolatedTemplate([]byte(contents), "fake-sha")
+			})
+
+			It("translates them into a resource pool and disk pool", func() {
+				deploymentManifest, err := parser.Parse(interpolatedTemplate, manifestPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(deploymentManifest.ResourcePools).To(Equal([]ResourcePool{
+					{
+						Name:    "bosh",
+						Network: "private",
+						CloudProperties: biproperty.Map{
+							"zone":          "fake-zone",
+							"instance_type": "m1.small",
+							"ephemeral_disk": biproperty.Map{
+								"size": 10000,
+								"type": "gp2",
+							},
+						},
+						Env: biproperty.Map{
+							"bosh": biproperty.Map{
+								"password": "secret",
+							},
+						},
+						Stemcell: StemcellRef{
+							URL:  "http://fake-stemcell-url",
+							SHA1: "fake-sha1",
+						},
+					},
+				}))
+
+				Expect(deploymentManifest.DiskPools).To(Equal([]DiskPool{
+					{
+						Name:            "fast",
+						DiskSize:        2048,
+						CloudProperties: biproperty.Map{"type": "ssd"},
+					},
+				}))
+
+				job := deploymentManifest.Jobs[0]
+				Expect(job.ResourcePool).To(Equal("bosh"))
+				Expect(job.PersistentDiskPool).To(Equal("fast"))
+				Expect(job.AZs).To(Equal([]string{"z1"}))
+				Expect(job.VMType).To(Equal("default"))
+				Expect(job.VMExtensions).To(Equal([]string{"bigger-disk"}))
+				Expect(job.Stemcell).To(Equal("default"))
+
+				Expect(deploymentManifest.Stemcells).To(Equal([]Stemcell{
+					{Alias: "default", URL: "http://fake-stemcell-url", SHA1: "fake-sha1"},
+				}))
+				Expect(deploymentManifest.Networks[0].Subnets[0].AZs).To(Equal([]string{"z1"}))
+				Expect(deploymentManifest.Networks[0].Subnets[1].AZs).To(Equal([]string{"z2"}))
+			})
+
+			It("uses the subnet in the az of the instance group", func() {
+				deploymentManifest, err := parser.Parse(interpolatedTemplate, manifestPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				interfaces, err := deploymentManifest.NetworkInterfaces("bosh")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(interfaces["private"]["ip"]).To(Equal("10.0.0.6"))
+				Expect(interfaces["private"]["gateway"]).To(Equal("10.0.0.1"))
+			})
+		})
+
+		Context("when both resource_pools and vm_types are present in deployment manifest", func() {
+			BeforeEach(func() {
+				contents := `
+---
+resource_pools:
+- name: fake-resource-pool-name
+vm_types:
+- name: default
+`
+				interpolatedTemplate = bidepltpl.NewInterpolatedTemplate([]byte(contents), "fake-sha")
+			})
+
+			It("throws an error", func() {
+				_, err := parser.Parse(interpolatedTemplate, manifestPath)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Deployment specifies both resource_pools/disk_pools and vm_types/disk_types/stemcells keys, only one is allowed"))
+			})
+		})
+
+		Context("when an instance group specifies resource_pool together with stemcell", func() {
+			BeforeEach(func() {
+				contents := `
+---
+instance_groups:
+- name: bosh
+  resource_pool: fake-resource-pool-name
+  stemcell: default
+`
+				interpolatedTemplate = bidepltpl.NewInterpolatedTemplate([]byte(contents), "fake-sha")
+			})
+
+			It("throws an error", func() {
+				_, err := parser.Parse(interpolatedTemplate, manifestPath)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Deployment specifies both resource_pools/disk_pools and vm_types/disk_types/stemcells keys, only one is allowed"))
+			})
+		})
+
 	})
 })
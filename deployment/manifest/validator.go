@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"fmt"
 	"net"
 	"regexp"
 	"strings"
@@ -34,11 +35,21 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 		errs = append(errs, bosherr.Error("name must be provided"))
 	}
 
-	networksErrors := v.validateNetworks(deploymentManifest.Networks)
+	usesVMTypes := deploymentManifest.UsesVMTypes()
+
+	networksErrors := v.validateNetworks(deploymentManifest.Networks, usesVMTypes)
 	errs = append(errs, networksErrors...)
 
-	protocolRegex := regexp.MustCompile("^(file|http|https)://")
+	if usesVMTypes {
+		errs = append(errs, v.validateCloudConfig(deploymentManifest)...)
+	}
+
 	for idx, resourcePool := range deploymentManifest.ResourcePools {
+		if usesVMTypes {
+			// resource pools are built from instance groups by Parser
+			break
+		}
+
 		if v.isBlank(resourcePool.Name) {
 			errs = append(errs, bosherr.Errorf("resource_pools[%d].name must be provided", idx))
 		}
@@ -48,26 +59,20 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 			errs = append(errs, bosherr.Errorf("resource_pools[%d].network must be the name of a network", idx))
 		}
 
-		if v.isBlank(resourcePool.Stemcell.URL) {
-			errs = append(errs, bosherr.Errorf("resource_pools[%d].stemcell.url must be provided", idx))
-		}
-
-		matched := protocolRegex.MatchString(resourcePool.Stemcell.URL)
-		if !matched {
-			errs = append(errs, bosherr.Errorf("resource_pools[%d].stemcell.url must be a valid URL (file:// or http(s)://)", idx))
-		}
+		errs = append(errs, v.validateStemcellRef(fmt.Sprintf("resource_pools[%d].stemcell", idx), resourcePool.Stemcell)...)
+	}
 
-		if strings.HasPrefix(resourcePool.Stemcell.URL, "http") && v.isBlank(resourcePool.Stemcell.SHA1) {
-			errs = append(errs, bosherr.Errorf("resource_pools[%d].stemcell.sha1 must be provided for http URL", idx))
-		}
+	diskPoolsKey := "disk_pools"
+	if usesVMTypes {
+		diskPoolsKey = "disk_types"
 	}
 
 	for idx, diskPool := range deploymentManifest.DiskPools {
 		if v.isBlank(diskPool.Name) {
-			errs = append(errs, bosherr.Errorf("disk_pools[%d].name must be provided", idx))
+			errs = append(errs, bosherr.Errorf("%s[%d].name must be provided", diskPoolsKey, idx))
 		}
 		if diskPool.DiskSize <= 0 {
-			errs = append(errs, bosherr.Errorf("disk_pools[%d].disk_size must be > 0", idx))
+			errs = append(errs, bosherr.Errorf("%s[%d].disk_size must be > 0", diskPoolsKey, idx))
 		}
 	}
 
@@ -84,7 +89,11 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 		}
 		if job.PersistentDiskPool != "" {
 			if _, ok := v.diskPoolNames(deploymentManifest)[job.PersistentDiskPool]; !ok {
-				errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk_pool must be the name of a disk pool", idx))
+				if usesVMTypes {
+					errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk_type must be the name of a disk type", idx))
+				} else {
+					errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk_pool must be the name of a disk pool", idx))
+				}
 			}
 		}
 		if job.Instances < 0 {
@@ -93,7 +102,9 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 		if len(job.Networks) == 0 {
 			errs = append(errs, bosherr.Errorf("jobs[%d].networks must be a non-empty array", idx))
 		}
-		if v.isBlank(job.ResourcePool) {
+		if usesVMTypes {
+			errs = append(errs, v.validateJobCloudConfig(job, deploymentManifest, idx)...)
+		} else if v.isBlank(job.ResourcePool) {
 			errs = append(errs, bosherr.Errorf("jobs[%d].resource_pool must be provided", idx))
 		} else {
 			if _, ok := v.resourcePoolNames(deploymentManifest)[job.ResourcePool]; !ok {
@@ -101,7 +112,16 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 			}
 		}
 
-		errs = append(errs, v.validateJobNetworks(job.Networks, deploymentManifest.Networks, idx)...)
+		networks := deploymentManifest.Networks
+		if usesVMTypes {
+			// Static IPs must be within subnets of the job az
+			networks = make([]Network, len(deploymentManifest.Networks))
+			for networkIdx, network := range deploymentManifest.Networks {
+				networks[networkIdx] = network.inAZ(job.AZ())
+			}
+		}
+
+		errs = append(errs, v.validateJobNetworks(job.Networks, networks, idx)...)
 
 		if job.Lifecycle != "" && job.Lifecycle != JobLifecycleService {
 			errs = append(errs, bosherr.Errorf("jobs[%d].lifecycle must be 'service' ('%s' not supported)", idx, job.Lifecycle))
@@ -135,6 +155,110 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 	return nil
 }
 
+func (v *validator) validateCloudConfig(deploymentManifest Manifest) []error {
+	errs := []error{}
+
+	for idx, az := range deploymentManifest.AZs {
+		if v.isBlank(az.Name) {
+			errs = append(errs, bosherr.Errorf("azs[%d].name must be provided", idx))
+		}
+	}
+
+	for idx, vmType := range deploymentManifest.VMTypes {
+		if v.isBlank(vmType.Name) {
+			errs = append(errs, bosherr.Errorf("vm_types[%d].name must be provided", idx))
+		}
+	}
+
+	for idx, vmExtension := range deploymentManifest.VMExtensions {
+		if v.isBlank(vmExtension.Name) {
+			errs = append(errs, bosherr.Errorf("vm_extensions[%d].name must be provided", idx))
+		}
+	}
+
+	for idx, stemcell := range deploymentManifest.Stemcells {
+		if v.isBlank(stemcell.Alias) {
+			errs = append(errs, bosherr.Errorf("stemcells[%d].alias must be provided", idx))
+		}
+
+		errs = append(errs, v.validateStemcellRef(fmt.Sprintf("stemcells[%d]", idx), stemcell.Ref())...)
+	}
+
+	return errs
+}
+
+func (v *validator) validateJobCloudConfig(job Job, deploymentManifest Manifest, idx int) []error {
+	errs := []error{}
+
+	if v.isBlank(job.VMType) {
+		errs = append(errs, bosherr.Errorf("jobs[%d].vm_type must be provided", idx))
+	} else if !v.hasVMType(deploymentManifest, job.VMType) {
+		errs = append(errs, bosherr.Errorf("jobs[%d].vm_type must be the name of a vm type", idx))
+	}
+
+	for extensionIdx, name := range job.VMExtensions {
+		if !v.hasVMExtension(deploymentManifest, name) {
+			errs = append(errs, bosherr.Errorf("jobs[%d].vm_extensions[%d] must be the name of a vm extension", idx, extensionIdx))
+		}
+	}
+
+	if v.isBlank(job.Stemcell) {
+		errs = append(errs, bosherr.Errorf("jobs[%d].stemcell must be provided", idx))
+	} else if !v.hasStemcell(deploymentManifest, job.Stemcell) {
+		errs = append(errs, bosherr.Errorf("jobs[%d].stemcell must be the alias of a stemcell", idx))
+	}
+
+	if len(job.AZs) > 1 {
+		errs = append(errs, bosherr.Errorf("jobs[%d].azs must be of size 1", idx))
+	}
+
+	for azIdx, name := range job.AZs {
+		if !v.hasAZ(deploymentManifest, name) {
+			errs = append(errs, bosherr.Errorf("jobs[%d].azs[%d] must be the name of an az", idx, azIdx))
+		}
+	}
+
+	for networkIdx, jobNetwork := range job.Networks {
+		for _, network := range deploymentManifest.Networks {
+			if network.Name != jobNetwork.Name || network.Type != Manual || len(network.Subnets) == 0 {
+				continue
+			}
+
+			subnets := 0
+			for _, subnet := range network.Subnets {
+				if subnet.InAZ(job.AZ()) {
+					subnets++
+				}
+			}
+
+			if subnets != 1 {
+				errs = append(errs, bosherr.Errorf("jobs[%d].networks[%d] must have exactly one subnet in az '%s'", idx, networkIdx, job.AZ()))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateStemcellRef(key string, stemcell StemcellRef) []error {
+	errs := []error{}
+
+	if v.isBlank(stemcell.URL) {
+		errs = append(errs, bosherr.Errorf("%s.url must be provided", key))
+	}
+
+	protocolRegex := regexp.MustCompile("^(file|http|https)://")
+	if !protocolRegex.MatchString(stemcell.URL) {
+		errs = append(errs, bosherr.Errorf("%s.url must be a valid URL (file:// or http(s)://)", key))
+	}
+
+	if strings.HasPrefix(stemcell.URL, "http") && v.isBlank(stemcell.SHA1) {
+		errs = append(errs, bosherr.Errorf("%s.sha1 must be provided for http URL", key))
+	}
+
+	return errs
+}
+
 func (v *validator) ValidateReleaseJobs(deploymentManifest Manifest, releaseManager boshinst.ReleaseManager) error {
 	errs := []error{}
 
@@ -187,6 +311,42 @@ func (v *validator) resourcePoolNames(deploymentManifest Manifest) map[string]st
 	return names
 }
 
+func (v *validator) hasAZ(deploymentManifest Manifest, name string) bool {
+	for _, az := range deploymentManifest.AZs {
+		if az.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) hasVMType(deploymentManifest Manifest, name string) bool {
+	for _, vmType := range deploymentManifest.VMTypes {
+		if vmType.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) hasVMExtension(deploymentManifest Manifest, name string) bool {
+	for _, vmExtension := range deploymentManifest.VMExtensions {
+		if vmExtension.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) hasStemcell(deploymentManifest Manifest, alias string) bool {
+	for _, stemcell := range deploymentManifest.Stemcells {
+		if stemcell.Alias == alias {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *validator) isValidIP(ip string) bool {
 	parsedIP := net.ParseIP(ip)
 	return parsedIP != nil
@@ -210,31 +370,31 @@ func (in *somethingIpNet) Try(fn func(*net.IPNet) error) error {
 	return fn(in.ipNet)
 }
 
-func (v *validator) validateRange(idx int, ipRange string) ([]error, maybeIPNet) {
+func (v *validator) validateRange(idx, subnetIdx int, ipRange string) ([]error, maybeIPNet) {
 	if v.isBlank(ipRange) {
-		return []error{bosherr.Errorf("networks[%d].subnets[0].range must be provided", idx)}, &nothingIpNet{}
+		return []error{bosherr.Errorf("networks[%d].subnets[%d].range must be provided", idx, subnetIdx)}, &nothingIpNet{}
 	}
 
 	_, ipNet, err := net.ParseCIDR(ipRange)
 	if err != nil {
-		return []error{bosherr.Errorf("networks[%d].subnets[0].range must be an ip range", idx)}, &nothingIpNet{}
+		return []error{bosherr.Errorf("networks[%d].subnets[%d].range must be an ip range", idx, subnetIdx)}, &nothingIpNet{}
 	}
 
 	return []error{}, &somethingIpNet{ipNet: ipNet}
 }
 
-func (v *validator) validateNetworks(networks []Network) []error {
+func (v *validator) validateNetworks(networks []Network, usesVMTypes bool) []error {
 	errs := []error{}
 
 	for idx, network := range networks {
-		networkErrors := v.validateNetwork(network, idx)
+		networkErrors := v.validateNetwork(network, idx, usesVMTypes)
 		errs = append(errs, networkErrors...)
 	}
 
 	return errs
 }
 
-func (v *validator) validateNetwork(network Network, networkIdx int) []error {
+func (v *validator) validateNetwork(network Network, networkIdx int, usesVMTypes bool) []error {
 	errs := []error{}
 
 	if v.isBlank(network.Name) {
@@ -246,22 +406,39 @@ func (v *validator) validateNetwork(network Network, networkIdx int) []error {
 	}
 
 	if network.Type == Manual {
-		if len(network.Subnets) != 1 {
+		// Only instance groups with vm types are placed into azs
+		if len(network.Subnets) != 1 && !(usesVMTypes && v.subnetsHaveAZs(network.Subnets)) {
 			errs = append(errs, bosherr.Errorf("networks[%d].subnets must be of size 1", networkIdx))
 		} else {
-			ipRange := network.Subnets[0].Range
-			rangeErrors, maybeIpNet := v.validateRange(networkIdx, ipRange)
-			errs = append(errs, rangeErrors...)
+			for subnetIdx, subnet := range network.Subnets {
+				rangeErrors, maybeIpNet := v.validateRange(networkIdx, subnetIdx, subnet.Range)
+				errs = append(errs, rangeErrors...)
 
-			gateway := network.Subnets[0].Gateway
-			gatewayErrors := v.validateGateway(networkIdx, gateway, maybeIpNet)
-			errs = append(errs, gatewayErrors...)
+				gatewayErrors := v.validateGateway(networkIdx, subnetIdx, subnet.Gateway, maybeIpNet)
+				errs = append(errs, gatewayErrors...)
+			}
 		}
 	}
 
 	return errs
 }
 
+// subnetsHaveAZs reports whether subnets are placed into azs,
+// create-env then uses the subnet in the az of the instance group
+func (v *validator) subnetsHaveAZs(subnets []Subnet) bool {
+	if len(subnets) == 0 {
+		return false
+	}
+
+	for _, subnet := range subnets {
+		if len(subnet.AZs) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (v *validator) validateJobNetworks(jobNetworks []JobNetwork, networks []Network, jobIdx int) []error {
 	errs := []error{}
 	defaultCounts := make(map[NetworkDefault]int)
@@ -343,9 +520,9 @@ func (v *validator) validateStaticIP(ip string, jobNetwork JobNetwork, network N
 	return []error{bosherr.Errorf("jobs[%d].networks[%d] static ip '%s' must be within subnet range", jobIdx, networkIdx, ip)}
 }
 
-func (v *validator) validateGateway(idx int, gateway string, ipNet maybeIPNet) []error {
+func (v *validator) validateGateway(idx, subnetIdx int, gateway string, ipNet maybeIPNet) []error {
 	if v.isBlank(gateway) {
-		return []error{bosherr.Errorf("networks[%d].subnets[0].gateway must be provided", idx)}
+		return []error{bosherr.Errorf("networks[%d].subnets[%d].gateway must be provided", idx, subnetIdx)}
 	}
 
 	errors := []error{}
@@ -353,7 +530,7 @@ func (v *validator) validateGateway(idx int, gateway string, ipNet maybeIPNet) [
 	_ = ipNet.Try(func(ipNet *net.IPNet) error { //nolint:errcheck
 		gatewayIp := net.ParseIP(gateway)
 		if gatewayIp == nil {
-			errors = append(errors, bosherr.Errorf("networks[%d].subnets[0].gateway must be an ip", idx))
+			errors = append(errors, bosherr.Errorf("networks[%d].subnets[%d].gateway must be an ip", idx, subnetIdx))
 		}
 
 		if !ipNet.Contains(gatewayIp) {
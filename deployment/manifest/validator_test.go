@@ -376,6 +376,24 @@ var _ = Describe("Validator", func() {
 					Expect(err.Error()).To(ContainSubstring("networks[0].subnets must be of size 1"))
 				})
 
+				It("validates that there is exactly 1 subnet even if subnets are in azs", func() {
+					deploymentManifest := Manifest{
+						Networks: []Network{
+							{
+								Type: "manual",
+								Subnets: []Subnet{
+									{Range: "10.0.0.0/24", Gateway: "10.0.0.1", AZs: []string{"z1"}},
+									{Range: "10.0.1.0/24", Gateway: "10.0.1.1", AZs: []string{"z2"}},
+								},
+							},
+						},
+					}
+
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets must be of size 1"))
+				})
+
 				It("validates that range is present", func() {
 					deploymentManifest := Manifest{
 						Networks: []Network{
@@ -1012,6 +1030,157 @@ var _ = Describe("Validator", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("jobs[0].templates[0].release 'fake-other-release-name' must refer to release in releases"))
 		})
+
+		Describe("vm types", func() {
+			var deploymentManifest Manifest
+
+			BeforeEach(func() {
+				deploymentManifest = validManifest
+				deploymentManifest.ResourcePools = []ResourcePool{
+					{
+						Name:     "fake-job-name",
+						Network:  "fake-network-name",
+						Stemcell: StemcellRef{URL: "file://fake-stemcell-url"},
+					},
+				}
+				deploymentManifest.AZs = []AZ{{Name: "z1"}}
+				deploymentManifest.VMTypes = []VMType{{Name: "default"}}
+				deploymentManifest.VMExtensions = []VMExtension{{Name: "bigger-disk"}}
+				deploymentManifest.Stemcells = []Stemcell{{Alias: "default", URL: "file://fake-stemcell-url"}}
+
+				job := validManifest.Jobs[0]
+				job.AZs = []string{"z1"}
+				job.VMType = "default"
+				job.VMExtensions = []string{"bigger-disk"}
+				job.Stemcell = "default"
+				job.ResourcePool = "fake-job-name"
+				deploymentManifest.Jobs = []Job{job}
+			})
+
+			It("does not error if deployment is valid", func() {
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("validates stemcells", func() {
+				deploymentManifest.Stemcells = []Stemcell{{URL: "http://fake-stemcell-url"}}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("stemcells[0].alias must be provided"))
+				Expect(err.Error()).To(ContainSubstring("stemcells[0].sha1 must be provided for http URL"))
+				Expect(err.Error()).ToNot(ContainSubstring("resource_pools"))
+			})
+
+			It("validates names of azs, vm types and vm extensions", func() {
+				deploymentManifest.AZs = []AZ{{}}
+				deploymentManifest.VMTypes = []VMType{{}}
+				deploymentManifest.VMExtensions = []VMExtension{{}}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("azs[0].name must be provided"))
+				Expect(err.Error()).To(ContainSubstring("vm_types[0].name must be provided"))
+				Expect(err.Error()).To(ContainSubstring("vm_extensions[0].name must be provided"))
+			})
+
+			It("validates job references", func() {
+				deploymentManifest.Jobs[0].AZs = []string{"z2"}
+				deploymentManifest.Jobs[0].VMType = "non-existent-vm-type"
+				deploymentManifest.Jobs[0].VMExtensions = []string{"non-existent-vm-extension"}
+				deploymentManifest.Jobs[0].Stemcell = "non-existent-stemcell"
+				deploymentManifest.Jobs[0].PersistentDiskPool = "non-existent-disk-type"
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobs[0].azs[0] must be the name of an az"))
+				Expect(err.Error()).To(ContainSubstring("jobs[0].vm_type must be the name of a vm type"))
+				Expect(err.Error()).To(ContainSubstring("jobs[0].vm_extensions[0] must be the name of a vm extension"))
+				Expect(err.Error()).To(ContainSubstring("jobs[0].stemcell must be the alias of a stemcell"))
+				Expect(err.Error()).To(ContainSubstring("jobs[0].persistent_disk_type must be the name of a disk type"))
+			})
+
+			It("validates job vm type and stemcell are provided", func() {
+				deploymentManifest.Jobs[0].VMType = ""
+				deploymentManifest.Jobs[0].Stemcell = ""
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobs[0].vm_type must be provided"))
+				Expect(err.Error()).To(ContainSubstring("jobs[0].stemcell must be provided"))
+			})
+
+			It("validates there is only one job az", func() {
+				deploymentManifest.AZs = []AZ{{Name: "z1"}, {Name: "z2"}}
+				deploymentManifest.Jobs[0].AZs = []string{"z1", "z2"}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobs[0].azs must be of size 1"))
+			})
+
+			It("validates disk types", func() {
+				deploymentManifest.DiskPools = []DiskPool{{}}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("disk_types[0].name must be provided"))
+				Expect(err.Error()).To(ContainSubstring("disk_types[0].disk_size must be > 0"))
+			})
+
+			Context("with manual network subnets in azs", func() {
+				BeforeEach(func() {
+					deploymentManifest.Networks = []Network{
+						{
+							Name: "fake-network-name",
+							Type: Manual,
+							Subnets: []Subnet{
+								{Range: "10.0.0.0/24", Gateway: "10.0.0.1", AZs: []string{"z1"}},
+								{Range: "10.0.1.0/24", Gateway: "10.0.1.1", AZs: []string{"z2"}},
+							},
+						},
+					}
+				})
+
+				It("permits multiple subnets", func() {
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("validates every subnet", func() {
+					deploymentManifest.Networks[0].Subnets[1].Gateway = ""
+
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets[1].gateway must be provided"))
+				})
+
+				It("validates static ips are within the subnet of the job az", func() {
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.0.1.5"}
+
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] static ip '10.0.1.5' must be within subnet range"))
+				})
+
+				It("permits static ips in the second subnet when the job is in its az", func() {
+					deploymentManifest.AZs = []AZ{{Name: "z1"}, {Name: "z2"}}
+					deploymentManifest.Jobs[0].AZs = []string{"z2"}
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.0.1.5"}
+
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("validates job network has a subnet in the job az", func() {
+					deploymentManifest.Networks[0].Subnets[0].AZs = []string{"z2"}
+
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] must have exactly one subnet in az 'z1'"))
+				})
+			})
+		})
 	})
 
 	Describe("ValidateReleaseJobs", func() {
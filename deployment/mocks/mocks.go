@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/cloudfoundry/bosh-cli/v7/deployment (interfaces: Deployment,Factory,Deployer,Manager,ManagerFactory)
+// Source: github.com/cloudfoundry/bosh-cli/v7/deployment (interfaces: Deployment,Factory,Deployer,Manager,ManagerFactory,Planner)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -10,11 +10,13 @@ import (
 	agentclient "github.com/cloudfoundry/bosh-agent/v2/agentclient"
 	blobstore "github.com/cloudfoundry/bosh-cli/v7/blobstore"
 	cloud "github.com/cloudfoundry/bosh-cli/v7/cloud"
+	config "github.com/cloudfoundry/bosh-cli/v7/config"
 	deployment "github.com/cloudfoundry/bosh-cli/v7/deployment"
 	disk "github.com/cloudfoundry/bosh-cli/v7/deployment/disk"
 	instance "github.com/cloudfoundry/bosh-cli/v7/deployment/instance"
 	manifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
 	vm "github.com/cloudfoundry/bosh-cli/v7/deployment/vm"
+	release "github.com/cloudfoundry/bosh-cli/v7/release"
 	stemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
 	ui "github.com/cloudfoundry/bosh-cli/v7/ui"
 	gomock "github.com/golang/mock/gomock"
@@ -249,3 +251,41 @@ func (mr *MockManagerFactoryMockRecorder) NewManager(arg0, arg1, arg2 interface{
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewManager", reflect.TypeOf((*MockManagerFactory)(nil).NewManager), arg0, arg1, arg2)
 }
+
+// MockPlanner is a mock of Planner interface.
+type MockPlanner struct {
+	ctrl     *gomock.Controller
+	recorder *MockPlannerMockRecorder
+}
+
+// MockPlannerMockRecorder is the mock recorder for MockPlanner.
+type MockPlannerMockRecorder struct {
+	mock *MockPlanner
+}
+
+// NewMockPlanner creates a new mock instance.
+func NewMockPlanner(ctrl *gomock.Controller) *MockPlanner {
+	mock := &MockPlanner{ctrl: ctrl}
+	mock.recorder = &MockPlannerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPlanner) EXPECT() *MockPlannerMockRecorder {
+	return m.recorder
+}
+
+// Plan mocks base method.
+func (m *MockPlanner) Plan(arg0 manifest.Manifest, arg1 stemcell.ExtractedStemcell, arg2 string, arg3 []release.Release, arg4 config.DeploymentState, arg5, arg6 bool) (deployment.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Plan", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(deployment.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Plan indicates an expected call of Plan.
+func (mr *MockPlannerMockRecorder) Plan(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Plan", reflect.TypeOf((*MockPlanner)(nil).Plan), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
@@ -0,0 +1,327 @@
+package deployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	bidisk "github.com/cloudfoundry/bosh-cli/v7/deployment/disk"
+	bideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
+	birel "github.com/cloudfoundry/bosh-cli/v7/release"
+	bistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
+)
+
+const (
+	PlanActionUpload   = "upload"
+	PlanActionReuse    = "reuse"
+	PlanActionCreate   = "create"
+	PlanActionRecreate = "recreate"
+	PlanActionMigrate  = "migrate"
+	PlanActionKeep     = "keep"
+	PlanActionDelete   = "delete"
+)
+
+type PlannedChange struct {
+	Resource string
+	Name     string
+	Action   string
+	Reason   string
+}
+
+type Plan struct {
+	Changes []PlannedChange
+}
+
+func (p *Plan) add(resource, name, action, reason string) {
+	p.Changes = append(p.Changes, PlannedChange{Resource: resource, Name: name, Action: action, Reason: reason})
+}
+
+// Planner describes what Deployer.Deploy would do to the current deployment
+// without calling the CPI.
+type Planner interface {
+	Plan(
+		deploymentManifest bideplmanifest.Manifest,
+		extractedStemcell bistemcell.ExtractedStemcell,
+		manifestSHA string,
+		releases []birel.Release,
+		deploymentState biconfig.DeploymentState,
+		recreate bool,
+		recreatePersistentDisks bool,
+	) (Plan, error)
+}
+
+type planner struct {
+	deploymentManagerFactory ManagerFactory
+	stemcellManagerFactory   bistemcell.ManagerFactory
+	diskManagerFactory       bidisk.ManagerFactory
+}
+
+func NewPlanner(
+	deploymentManagerFactory ManagerFactory,
+	stemcellManagerFactory bistemcell.ManagerFactory,
+	diskManagerFactory bidisk.ManagerFactory,
+) Planner {
+	return &planner{
+		deploymentManagerFactory: deploymentManagerFactory,
+		stemcellManagerFactory:   stemcellManagerFactory,
+		diskManagerFactory:       diskManagerFactory,
+	}
+}
+
+func (p *planner) Plan(
+	deploymentManifest bideplmanifest.Manifest,
+	extractedStemcell bistemcell.ExtractedStemcell,
+	manifestSHA string,
+	releases []birel.Release,
+	deploymentState biconfig.DeploymentState,
+	recreate bool,
+	recreatePersistentDisks bool,
+) (Plan, error) {
+	plan := Plan{}
+
+	if len(deploymentManifest.Jobs) != 1 {
+		return plan, bosherr.Errorf("There must only be one job, found %d", len(deploymentManifest.Jobs))
+	}
+
+	diskPool, err := deploymentManifest.DiskPool(deploymentManifest.Jobs[0].Name)
+	if err != nil {
+		return plan, err
+	}
+
+	stemcellManifest := extractedStemcell.Manifest()
+	stemcellName := fmt.Sprintf("%s/%s", stemcellManifest.Name, stemcellManifest.Version)
+
+	// A state without a director ID has never been loaded, so nothing is deployed.
+	// The managers are skipped because looking anything up would save the state.
+	if deploymentState.DirectorID == "" {
+		plan.add("stemcell", stemcellName, PlanActionUpload, "stemcell has not been uploaded")
+		plan.add("vm", "", PlanActionCreate, "no VM is deployed")
+		if diskPool.DiskSize > 0 {
+			plan.add("disk", "", PlanActionCreate, fmt.Sprintf("no persistent disk exists, creating %d MiB", diskPool.DiskSize))
+		}
+		return plan, nil
+	}
+
+	// Managers are only used for lookups, so they are never given a CPI client
+	deploymentManager := p.deploymentManagerFactory.NewManager(nil, nil, nil)
+	stemcellManager := p.stemcellManagerFactory.NewManager(nil)
+	diskManager := p.diskManagerFactory.NewManager(nil)
+
+	_, deployed, err := deploymentManager.FindCurrent()
+	if err != nil {
+		return plan, err
+	}
+
+	err = p.planStemcell(&plan, stemcellManager, stemcellName, stemcellManifest)
+	if err != nil {
+		return plan, err
+	}
+
+	if deployed && deploymentState.CurrentVMCID != "" {
+		reasons := p.vmRecreateReasons(deploymentState, stemcellName, manifestSHA, releases, recreate, recreatePersistentDisks)
+		plan.add("vm", deploymentState.CurrentVMCID, PlanActionRecreate, strings.Join(reasons, "; "))
+	} else {
+		plan.add("vm", "", PlanActionCreate, "no VM is deployed")
+	}
+
+	err = p.planDisks(&plan, diskManager, diskPool, deploymentState, recreatePersistentDisks)
+	if err != nil {
+		return plan, err
+	}
+
+	return plan, nil
+}
+
+func (p *planner) planStemcell(plan *Plan, stemcellManager bistemcell.Manager, stemcellName string, stemcellManifest bistemcell.Manifest) error {
+	currentStemcells, err := stemcellManager.FindCurrent()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding current stemcells")
+	}
+
+	unusedStemcells, err := stemcellManager.FindUnused()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding unused stemcells")
+	}
+
+	var matching bistemcell.CloudStemcell
+	var others []bistemcell.CloudStemcell
+
+	for _, stemcell := range append(currentStemcells, unusedStemcells...) {
+		if matching == nil && stemcell.Name() == stemcellManifest.Name && stemcell.Version() == stemcellManifest.Version {
+			matching = stemcell
+		} else {
+			others = append(others, stemcell)
+		}
+	}
+
+	if matching != nil {
+		plan.add("stemcell", stemcellName, PlanActionReuse, fmt.Sprintf("already uploaded as '%s'", matching.CID()))
+	} else {
+		plan.add("stemcell", stemcellName, PlanActionUpload, "stemcell has not been uploaded")
+	}
+
+	for _, stemcell := range others {
+		plan.add("stemcell", fmt.Sprintf("%s/%s", stemcell.Name(), stemcell.Version()), PlanActionDelete,
+			fmt.Sprintf("'%s' is unused after the deploy", stemcell.CID()))
+	}
+
+	return nil
+}
+
+func (p *planner) vmRecreateReasons(
+	deploymentState biconfig.DeploymentState,
+	stemcellName string,
+	manifestSHA string,
+	releases []birel.Release,
+	recreate bool,
+	recreatePersistentDisks bool,
+) []string {
+	var reasons []string
+
+	if recreate {
+		reasons = append(reasons, "--recreate was given")
+	}
+
+	if recreatePersistentDisks {
+		reasons = append(reasons, "--recreate-persistent-disks was given")
+	}
+
+	if deploymentState.CurrentManifestSHA != manifestSHA {
+		reasons = append(reasons, "deployment manifest changed")
+	}
+
+	for _, stemcell := range deploymentState.Stemcells {
+		if stemcell.ID == deploymentState.CurrentStemcellID {
+			currentStemcellName := fmt.Sprintf("%s/%s", stemcell.Name, stemcell.Version)
+			if currentStemcellName != stemcellName {
+				reasons = append(reasons, fmt.Sprintf("stemcell changes from '%s' to '%s'", currentStemcellName, stemcellName))
+			}
+		}
+	}
+
+	var currentReleases, newReleases []string
+
+	for _, release := range deploymentState.Releases {
+		for _, id := range deploymentState.CurrentReleaseIDs {
+			if release.ID == id {
+				currentReleases = append(currentReleases, fmt.Sprintf("%s/%s", release.Name, release.Version))
+			}
+		}
+	}
+
+	for _, release := range releases {
+		newReleases = append(newReleases, fmt.Sprintf("%s/%s", release.Name(), release.Version()))
+	}
+
+	sort.Strings(currentReleases)
+	sort.Strings(newReleases)
+
+	if strings.Join(currentReleases, ",") != strings.Join(newReleases, ",") {
+		reasons = append(reasons, "releases changed")
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "the previous deploy did not complete")
+	}
+
+	return reasons
+}
+
+func (p *planner) planDisks(
+	plan *Plan,
+	diskManager bidisk.Manager,
+	diskPool bideplmanifest.DiskPool,
+	deploymentState biconfig.DeploymentState,
+	recreatePersistentDisks bool,
+) error {
+	currentDisks, err := diskManager.FindCurrent()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding current disks")
+	}
+
+	if diskPool.DiskSize == 0 {
+		for _, disk := range currentDisks {
+			plan.add("disk", disk.CID(), PlanActionKeep, "manifest has no persistent disk, the disk is not attached")
+		}
+		return nil
+	}
+
+	if len(currentDisks) > 1 {
+		return bosherr.Error("Multiple current disks not supported")
+	}
+
+	if len(currentDisks) == 0 {
+		plan.add("disk", "", PlanActionCreate, fmt.Sprintf("no persistent disk exists, creating %d MiB", diskPool.DiskSize))
+	} else {
+		disk := currentDisks[0]
+
+		needsMigration, err := disk.NeedsMigration(diskPool.DiskSize, diskPool.CloudProperties)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking if disk '%s' needs migration", disk.CID())
+		}
+
+		if needsMigration || recreatePersistentDisks {
+			reasons, err := p.diskMigrationReasons(disk.CID(), diskPool, deploymentState)
+			if err != nil {
+				return err
+			}
+
+			if recreatePersistentDisks {
+				reasons = append([]string{"--recreate-persistent-disks was given"}, reasons...)
+			}
+
+			plan.add("disk", disk.CID(), PlanActionMigrate, strings.Join(reasons, "; "))
+		} else {
+			plan.add("disk", disk.CID(), PlanActionKeep, "size and cloud properties are unchanged")
+		}
+	}
+
+	unusedDisks, err := diskManager.FindUnused()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding unused disks")
+	}
+
+	for _, disk := range unusedDisks {
+		plan.add("disk", disk.CID(), PlanActionDelete, "disk is not used by the deployment")
+	}
+
+	return nil
+}
+
+func (p *planner) diskMigrationReasons(diskCID string, diskPool bideplmanifest.DiskPool, deploymentState biconfig.DeploymentState) ([]string, error) {
+	var reasons []string
+
+	for _, record := range deploymentState.Disks {
+		if record.CID != diskCID {
+			continue
+		}
+
+		if record.Size != diskPool.DiskSize {
+			reasons = append(reasons, fmt.Sprintf("size changes from %d MiB to %d MiB", record.Size, diskPool.DiskSize))
+		}
+
+		currentCloudProperties, err := json.Marshal(record.CloudProperties)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Marshalling disk cloud properties")
+		}
+
+		newCloudProperties, err := json.Marshal(diskPool.CloudProperties)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Marshalling disk cloud properties")
+		}
+
+		if string(currentCloudProperties) != string(newCloudProperties) {
+			reasons = append(reasons, "cloud properties changed")
+		}
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "disk needs migration")
+	}
+
+	return reasons, nil
+}
@@ -0,0 +1,182 @@
+package deployment_test
+
+import (
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	biconfig "github.com/cloudfoundry/bosh-cli/v7/config"
+	. "github.com/cloudfoundry/bosh-cli/v7/deployment"
+	bidisk "github.com/cloudfoundry/bosh-cli/v7/deployment/disk"
+	fakebidisk "github.com/cloudfoundry/bosh-cli/v7/deployment/disk/fakes"
+	bideplmanifest "github.com/cloudfoundry/bosh-cli/v7/deployment/manifest"
+	mock_deployment "github.com/cloudfoundry/bosh-cli/v7/deployment/mocks"
+	birel "github.com/cloudfoundry/bosh-cli/v7/release"
+	fakebirel "github.com/cloudfoundry/bosh-cli/v7/release/releasefakes"
+	bistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell"
+	mock_stemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell/mocks"
+	fakebistemcell "github.com/cloudfoundry/bosh-cli/v7/stemcell/stemcellfakes"
+)
+
+var _ = Describe("Planner", func() {
+	var (
+		mockCtrl *gomock.Controller
+
+		mockDeploymentManagerFactory *mock_deployment.MockManagerFactory
+		mockDeploymentManager        *mock_deployment.MockManager
+		mockDeployment               *mock_deployment.MockDeployment
+		mockStemcellManager          *mock_stemcell.MockManager
+		fakeStemcellManagerFactory   *fakebistemcell.FakeManagerFactory
+		fakeDiskManager              *fakebidisk.FakeManager
+		fakeDiskManagerFactory       *fakebidisk.FakeManagerFactory
+
+		deploymentManifest bideplmanifest.Manifest
+		extractedStemcell  bistemcell.ExtractedStemcell
+		releases           []birel.Release
+		deploymentState    biconfig.DeploymentState
+
+		planner Planner
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+
+		mockDeploymentManagerFactory = mock_deployment.NewMockManagerFactory(mockCtrl)
+		mockDeploymentManager = mock_deployment.NewMockManager(mockCtrl)
+		mockDeployment = mock_deployment.NewMockDeployment(mockCtrl)
+		mockStemcellManager = mock_stemcell.NewMockManager(mockCtrl)
+
+		fakeStemcellManagerFactory = fakebistemcell.NewFakeManagerFactory()
+		fakeStemcellManagerFactory.SetNewManagerBehavior(nil, mockStemcellManager)
+
+		fakeDiskManager = fakebidisk.NewFakeManager()
+		fakeDiskManagerFactory = fakebidisk.NewFakeManagerFactory()
+		fakeDiskManagerFactory.NewManagerManager = fakeDiskManager
+
+		deploymentManifest = bideplmanifest.Manifest{
+			Name: "fake-deployment-name",
+			Jobs: []bideplmanifest.Job{
+				{Name: "fake-job-name", Instances: 1, PersistentDisk: 2048},
+			},
+		}
+
+		extractedStemcell = bistemcell.NewExtractedStemcell(
+			bistemcell.Manifest{Name: "fake-stemcell-name", Version: "2"},
+			"fake-extracted-path",
+			nil,
+			fakesys.NewFakeFileSystem(),
+		)
+
+		release := &fakebirel.FakeRelease{}
+		release.NameReturns("fake-release-name")
+		release.VersionReturns("1")
+		releases = []birel.Release{release}
+
+		deploymentState = biconfig.DeploymentState{
+			DirectorID:         "fake-director-id",
+			CurrentVMCID:       "fake-vm-cid",
+			CurrentManifestSHA: "fake-manifest-sha",
+			CurrentStemcellID:  "fake-stemcell-id",
+			Stemcells: []biconfig.StemcellRecord{
+				{ID: "fake-stemcell-id", Name: "fake-stemcell-name", Version: "1", CID: "fake-stemcell-cid-1"},
+			},
+			CurrentReleaseIDs: []string{"fake-release-id"},
+			Releases: []biconfig.ReleaseRecord{
+				{ID: "fake-release-id", Name: "fake-release-name", Version: "1"},
+			},
+			CurrentDiskID: "fake-disk-id",
+			Disks: []biconfig.DiskRecord{
+				{ID: "fake-disk-id", CID: "fake-disk-cid", Size: 1024, CloudProperties: biproperty.Map{}},
+			},
+		}
+
+		planner = NewPlanner(mockDeploymentManagerFactory, fakeStemcellManagerFactory, fakeDiskManagerFactory)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("when nothing has been deployed", func() {
+		It("plans to upload the stemcell and create the VM and disk without looking anything up", func() {
+			plan, err := planner.Plan(deploymentManifest, extractedStemcell, "fake-new-manifest-sha", releases, biconfig.DeploymentState{}, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(plan.Changes).To(Equal([]PlannedChange{
+				{Resource: "stemcell", Name: "fake-stemcell-name/2", Action: PlanActionUpload, Reason: "stemcell has not been uploaded"},
+				{Resource: "vm", Action: PlanActionCreate, Reason: "no VM is deployed"},
+				{Resource: "disk", Action: PlanActionCreate, Reason: "no persistent disk exists, creating 2048 MiB"},
+			}))
+			Expect(fakeDiskManagerFactory.NewManagerInputs).To(BeEmpty())
+		})
+	})
+
+	Context("when a deployment exists", func() {
+		var currentStemcell *fakebistemcell.FakeCloudStemcell
+
+		BeforeEach(func() {
+			mockDeploymentManagerFactory.EXPECT().NewManager(nil, nil, nil).Return(mockDeploymentManager)
+			mockDeploymentManager.EXPECT().FindCurrent().Return(mockDeployment, true, nil)
+
+			currentStemcell = fakebistemcell.NewFakeCloudStemcell("fake-stemcell-cid-1", "fake-stemcell-name", "1", 2)
+			mockStemcellManager.EXPECT().FindCurrent().Return([]bistemcell.CloudStemcell{currentStemcell}, nil)
+			mockStemcellManager.EXPECT().FindUnused().Return([]bistemcell.CloudStemcell{}, nil)
+
+			fakeDiskManager.SetFindUnusedBehavior([]bidisk.Disk{}, nil)
+		})
+
+		It("explains why the stemcell, VM and disk change", func() {
+			disk := fakebidisk.NewFakeDisk("fake-disk-cid")
+			disk.SetNeedsMigrationBehavior(true)
+			fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{disk}, nil)
+
+			plan, err := planner.Plan(deploymentManifest, extractedStemcell, "fake-new-manifest-sha", releases, deploymentState, false, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(plan.Changes).To(Equal([]PlannedChange{
+				{Resource: "stemcell", Name: "fake-stemcell-name/2", Action: PlanActionUpload, Reason: "stemcell has not been uploaded"},
+				{Resource: "stemcell", Name: "fake-stemcell-name/1", Action: PlanActionDelete, Reason: "'fake-stemcell-cid-1' is unused after the deploy"},
+				{Resource: "vm", Name: "fake-vm-cid", Action: PlanActionRecreate, Reason: "deployment manifest changed; stemcell changes from 'fake-stemcell-name/1' to 'fake-stemcell-name/2'"},
+				{Resource: "disk", Name: "fake-disk-cid", Action: PlanActionMigrate, Reason: "size changes from 1024 MiB to 2048 MiB"},
+			}))
+		})
+
+		It("keeps the disk and reuses the stemcell when they are unchanged", func() {
+			extractedStemcell = bistemcell.NewExtractedStemcell(
+				bistemcell.Manifest{Name: "fake-stemcell-name", Version: "1"},
+				"fake-extracted-path",
+				nil,
+				fakesys.NewFakeFileSystem(),
+			)
+
+			disk := fakebidisk.NewFakeDisk("fake-disk-cid")
+			fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{disk}, nil)
+
+			plan, err := planner.Plan(deploymentManifest, extractedStemcell, "fake-manifest-sha", releases, deploymentState, true, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(plan.Changes).To(Equal([]PlannedChange{
+				{Resource: "stemcell", Name: "fake-stemcell-name/1", Action: PlanActionReuse, Reason: "already uploaded as 'fake-stemcell-cid-1'"},
+				{Resource: "vm", Name: "fake-vm-cid", Action: PlanActionRecreate, Reason: "--recreate was given"},
+				{Resource: "disk", Name: "fake-disk-cid", Action: PlanActionKeep, Reason: "size and cloud properties are unchanged"},
+			}))
+		})
+
+		It("migrates the disk when --recreate-persistent-disks is given", func() {
+			disk := fakebidisk.NewFakeDisk("fake-disk-cid")
+			fakeDiskManager.SetFindCurrentBehavior([]bidisk.Disk{disk}, nil)
+
+			plan, err := planner.Plan(deploymentManifest, extractedStemcell, "fake-manifest-sha", releases, deploymentState, false, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(plan.Changes).To(ContainElement(PlannedChange{
+				Resource: "disk",
+				Name:     "fake-disk-cid",
+				Action:   PlanActionMigrate,
+				Reason:   "--recreate-persistent-disks was given; size changes from 1024 MiB to 2048 MiB",
+			}))
+		})
+	})
+})
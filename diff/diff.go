@@ -0,0 +1,260 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// Change describes a single difference between two documents.
+// Path uses name based array indices (e.g. /instance_groups/name=web)
+// whenever array items can be identified by their name.
+type Change struct {
+	Type ChangeType
+	Path patch.Pointer
+
+	From interface{}
+	To   interface{}
+}
+
+// CompareBytes parses two YAML documents and compares them keeping the order of keys.
+func CompareBytes(left, right []byte) ([]Change, error) {
+	leftDoc, err := parse(left)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing first document")
+	}
+
+	rightDoc, err := parse(right)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing second document")
+	}
+
+	return Compare(leftDoc, rightDoc), nil
+}
+
+// Compare returns changes needed to turn left into right.
+func Compare(left, right interface{}) []Change {
+	return compare([]patch.Token{patch.RootToken{}}, left, right)
+}
+
+func parse(bytes []byte) (interface{}, error) {
+	var doc yaml.MapSlice
+
+	err := yaml.Unmarshal(bytes, &doc)
+	if err == nil {
+		return doc, nil
+	}
+
+	// Documents that are not hashes are compared as they are
+	var val interface{}
+
+	err = yaml.Unmarshal(bytes, &val)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+func compare(path []patch.Token, left, right interface{}) []Change {
+	leftHash, leftIsHash := toMapSlice(left)
+	rightHash, rightIsHash := toMapSlice(right)
+
+	if leftIsHash && rightIsHash {
+		return compareHashes(path, leftHash, rightHash)
+	}
+
+	leftArray, leftIsArray := left.([]interface{})
+	rightArray, rightIsArray := right.([]interface{})
+
+	if leftIsArray && rightIsArray {
+		if namedItems(leftArray) && namedItems(rightArray) {
+			return compareNamedArrays(path, leftArray, rightArray)
+		}
+		return compareArrays(path, leftArray, rightArray)
+	}
+
+	if reflect.DeepEqual(left, right) {
+		return nil
+	}
+
+	return []Change{{Type: ChangeChanged, Path: pointer(path), From: left, To: right}}
+}
+
+func compareHashes(path []patch.Token, left, right yaml.MapSlice) []Change {
+	var changes []Change
+
+	rightItems := map[interface{}]interface{}{}
+	for _, item := range right {
+		rightItems[item.Key] = item.Value
+	}
+
+	leftItems := map[interface{}]interface{}{}
+
+	for _, item := range left {
+		leftItems[item.Key] = item.Value
+
+		keyPath := appendToken(path, patch.KeyToken{Key: fmt.Sprintf("%v", item.Key)})
+
+		rightVal, found := rightItems[item.Key]
+		if !found {
+			changes = append(changes, Change{Type: ChangeRemoved, Path: pointer(keyPath), From: item.Value})
+			continue
+		}
+
+		changes = append(changes, compare(keyPath, item.Value, rightVal)...)
+	}
+
+	for _, item := range right {
+		if _, found := leftItems[item.Key]; !found {
+			keyPath := appendToken(path, patch.KeyToken{Key: fmt.Sprintf("%v", item.Key)})
+			changes = append(changes, Change{Type: ChangeAdded, Path: pointer(keyPath), To: item.Value})
+		}
+	}
+
+	return changes
+}
+
+func compareNamedArrays(path []patch.Token, left, right []interface{}) []Change {
+	var changes []Change
+
+	rightItems := map[string]interface{}{}
+	for _, item := range right {
+		rightItems[itemName(item)] = item
+	}
+
+	leftItems := map[string]interface{}{}
+
+	for _, item := range left {
+		name := itemName(item)
+		leftItems[name] = item
+
+		itemPath := appendToken(path, patch.MatchingIndexToken{Key: "name", Value: name})
+
+		rightItem, found := rightItems[name]
+		if !found {
+			changes = append(changes, Change{Type: ChangeRemoved, Path: pointer(itemPath), From: item})
+			continue
+		}
+
+		changes = append(changes, compare(itemPath, item, rightItem)...)
+	}
+
+	for _, item := range right {
+		name := itemName(item)
+
+		if _, found := leftItems[name]; !found {
+			itemPath := appendToken(path, patch.MatchingIndexToken{Key: "name", Value: name})
+			changes = append(changes, Change{Type: ChangeAdded, Path: pointer(itemPath), To: item})
+		}
+	}
+
+	return changes
+}
+
+func compareArrays(path []patch.Token, left, right []interface{}) []Change {
+	var changes []Change
+
+	for i := 0; i < len(left) && i < len(right); i++ {
+		changes = append(changes, compare(appendToken(path, patch.IndexToken{Index: i}), left[i], right[i])...)
+	}
+
+	for i := len(left); i < len(right); i++ {
+		itemPath := appendToken(path, patch.IndexToken{Index: i})
+		changes = append(changes, Change{Type: ChangeAdded, Path: pointer(itemPath), To: right[i]})
+	}
+
+	// Trailing items are removed last to first so that indices stay valid when applied in order
+	for i := len(left) - 1; i >= len(right); i-- {
+		itemPath := appendToken(path, patch.IndexToken{Index: i})
+		changes = append(changes, Change{Type: ChangeRemoved, Path: pointer(itemPath), From: left[i]})
+	}
+
+	return changes
+}
+
+// namedItems returns true if all items are hashes with unique names.
+func namedItems(items []interface{}) bool {
+	if len(items) == 0 {
+		return false
+	}
+
+	names := map[string]struct{}{}
+
+	for _, item := range items {
+		name := itemName(item)
+		if len(name) == 0 {
+			return false
+		}
+
+		if _, found := names[name]; found {
+			return false
+		}
+
+		names[name] = struct{}{}
+	}
+
+	return true
+}
+
+func itemName(item interface{}) string {
+	hash, ok := toMapSlice(item)
+	if !ok {
+		return ""
+	}
+
+	for _, kv := range hash {
+		if kv.Key == "name" {
+			if name, ok := kv.Value.(string); ok {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// toMapSlice converts hashes to yaml.MapSlice; hashes that are not
+// already ordered are sorted by key to keep results stable.
+func toMapSlice(val interface{}) (yaml.MapSlice, bool) {
+	switch typedVal := val.(type) {
+	case yaml.MapSlice:
+		return typedVal, true
+	case map[interface{}]interface{}:
+		var hash yaml.MapSlice
+		for k, v := range typedVal {
+			hash = append(hash, yaml.MapItem{Key: k, Value: v})
+		}
+		sort.Slice(hash, func(i, j int) bool { return fmt.Sprintf("%v", hash[i].Key) < fmt.Sprintf("%v", hash[j].Key) })
+		return hash, true
+	case map[string]interface{}:
+		var hash yaml.MapSlice
+		for k, v := range typedVal {
+			hash = append(hash, yaml.MapItem{Key: k, Value: v})
+		}
+		sort.Slice(hash, func(i, j int) bool { return hash[i].Key.(string) < hash[j].Key.(string) })
+		return hash, true
+	default:
+		return nil, false
+	}
+}
+
+func appendToken(path []patch.Token, token patch.Token) []patch.Token {
+	return append(append([]patch.Token{}, path...), token)
+}
+
+func pointer(tokens []patch.Token) patch.Pointer {
+	return patch.NewPointer(tokens)
+}
@@ -0,0 +1,136 @@
+package diff_test
+
+import (
+	"github.com/cppforlife/go-patch/patch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/diff"
+)
+
+var _ = Describe("CompareBytes", func() {
+	paths := func(changes []Change) []string {
+		var result []string
+		for _, change := range changes {
+			result = append(result, string(change.Type)+" "+change.Path.String())
+		}
+		return result
+	}
+
+	It("returns no changes for equal documents regardless of key order", func() {
+		changes, err := CompareBytes([]byte("a: 1\nb: {c: 2, d: 3}"), []byte("b: {d: 3, c: 2}\na: 1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("reports added, removed and changed keys in document order", func() {
+		changes, err := CompareBytes([]byte(`
+name: dep
+update: {canaries: 1, max_in_flight: 2}
+stemcells: []
+`), []byte(`
+name: dep
+update: {canaries: 2, serial: false}
+features: {use_dns_addresses: true}
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(Equal([]Change{
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/update/canaries"), From: 1, To: 2},
+			{Type: ChangeRemoved, Path: patch.MustNewPointerFromString("/update/max_in_flight"), From: 2},
+			{Type: ChangeAdded, Path: patch.MustNewPointerFromString("/update/serial"), To: false},
+			{Type: ChangeRemoved, Path: patch.MustNewPointerFromString("/stemcells"), From: []interface{}{}},
+			{
+				Type: ChangeAdded,
+				Path: patch.MustNewPointerFromString("/features"),
+				To:   yaml.MapSlice{{Key: "use_dns_addresses", Value: true}},
+			},
+		}))
+	})
+
+	It("matches array items by name", func() {
+		changes, err := CompareBytes([]byte(`
+instance_groups:
+- name: web
+  instances: 1
+  jobs:
+  - name: nginx
+    properties: {port: 80}
+- name: db
+  instances: 1
+`), []byte(`
+instance_groups:
+- name: worker
+  instances: 1
+- name: web
+  instances: 2
+  jobs:
+  - name: nginx
+    properties: {port: 8080}
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(changes)).To(Equal([]string{
+			"changed /instance_groups/name=web/instances",
+			"changed /instance_groups/name=web/jobs/name=nginx/properties/port",
+			"removed /instance_groups/name=db",
+			"added /instance_groups/name=worker",
+		}))
+	})
+
+	It("compares array items without unique names by index", func() {
+		changes, err := CompareBytes([]byte(`
+azs: [z1, z2, z3]
+networks: [{static_ips: [1]}]
+`), []byte(`
+azs: [z1, z4]
+networks: [{static_ips: [1, 2]}]
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(changes)).To(Equal([]string{
+			"changed /azs/1",
+			"removed /azs/2",
+			"added /networks/0/static_ips/1",
+		}))
+	})
+
+	It("removes trailing array items last to first", func() {
+		changes, err := CompareBytes([]byte("azs: [z1, z2, z3]"), []byte("azs: [z1]"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(changes)).To(Equal([]string{"removed /azs/2", "removed /azs/1"}))
+	})
+
+	It("reports values that changed type", func() {
+		changes, err := CompareBytes([]byte("a: {b: 1}"), []byte("a: [1]"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(Equal([]Change{{
+			Type: ChangeChanged,
+			Path: patch.MustNewPointerFromString("/a"),
+			From: yaml.MapSlice{{Key: "b", Value: 1}},
+			To:   []interface{}{1},
+		}}))
+	})
+
+	It("compares documents that are not hashes", func() {
+		changes, err := CompareBytes([]byte("[1, 2]"), []byte("[1, 3]"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(changes)).To(Equal([]string{"changed /1"}))
+	})
+
+	It("returns error if documents cannot be parsed", func() {
+		_, err := CompareBytes([]byte("a: 1"), []byte("a: [1"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing second document"))
+	})
+})
+
+var _ = Describe("Compare", func() {
+	It("compares unordered hashes", func() {
+		changes := Compare(
+			map[interface{}]interface{}{"a": 1, "b": 2},
+			map[interface{}]interface{}{"b": 3, "a": 1},
+		)
+		Expect(changes).To(Equal([]Change{
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/b"), From: 2, To: 3},
+		}))
+	})
+})
@@ -0,0 +1,316 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
+)
+
+const RedactedValue = "<redacted>"
+
+type LineType string
+
+const (
+	LineHeader  LineType = "header"
+	LineAdded   LineType = "added"
+	LineRemoved LineType = "removed"
+)
+
+type Line struct {
+	Type LineType
+	Text string
+}
+
+// Redact hides values found under 'properties' keys similarly to
+// how the director redacts deployment diffs.
+func Redact(changes []Change) []Change {
+	var redacted []Change
+
+	for _, change := range changes {
+		underProps := false
+
+		for _, token := range change.Path.Tokens() {
+			if keyToken, ok := token.(patch.KeyToken); ok && keyToken.Key == "properties" {
+				underProps = true
+			}
+		}
+
+		change.From = redactValue(change.From, underProps)
+		change.To = redactValue(change.To, underProps)
+
+		redacted = append(redacted, change)
+	}
+
+	return redacted
+}
+
+func redactValue(val interface{}, underProps bool) interface{} {
+	if hash, ok := toMapSlice(val); ok {
+		redacted := yaml.MapSlice{}
+		for _, item := range hash {
+			isProps := fmt.Sprintf("%v", item.Key) == "properties"
+			redacted = append(redacted, yaml.MapItem{Key: item.Key, Value: redactValue(item.Value, underProps || isProps)})
+		}
+		return redacted
+	}
+
+	if array, ok := val.([]interface{}); ok {
+		redacted := []interface{}{}
+		for _, item := range array {
+			redacted = append(redacted, redactValue(item, underProps))
+		}
+		return redacted
+	}
+
+	if underProps && val != nil {
+		return RedactedValue
+	}
+
+	return val
+}
+
+// UnifiedLines renders each change as a header with the changed path
+// followed by removed and added values in YAML.
+func UnifiedLines(changes []Change) []Line {
+	var lines []Line
+
+	for _, change := range changes {
+		lines = append(lines, Line{Type: LineHeader, Text: fmt.Sprintf("@@ %s @@", change.Path.String())})
+
+		if change.Type != ChangeAdded {
+			for _, valLine := range strings.Split(FormatValue(change.From), "\n") {
+				lines = append(lines, Line{Type: LineRemoved, Text: "- " + valLine})
+			}
+		}
+
+		if change.Type != ChangeRemoved {
+			for _, valLine := range strings.Split(FormatValue(change.To), "\n") {
+				lines = append(lines, Line{Type: LineAdded, Text: "+ " + valLine})
+			}
+		}
+	}
+
+	return lines
+}
+
+// FormatValue renders scalar values as they are and other values as YAML.
+func FormatValue(val interface{}) string {
+	switch typedVal := val.(type) {
+	case nil:
+		return "~"
+	case string:
+		return typedVal
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprintf("%v", typedVal)
+	}
+
+	bytes, err := yaml.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+
+	return strings.TrimSuffix(string(bytes), "\n")
+}
+
+type opDefinition struct {
+	Type  string       `json:"type"`
+	Path  string       `json:"path"`
+	Value *interface{} `json:"value,omitempty"`
+}
+
+// OpsJSON renders changes as operations that can be used as an ops file
+// to turn the first document into the second one. Paths use go-patch syntax
+// (e.g. /instance_groups/name=web); see JSONPatch for RFC 6902 JSON Patch.
+func OpsJSON(changes []Change) ([]byte, error) {
+	opDefs := []opDefinition{}
+
+	for _, change := range changes {
+		switch change.Type {
+		case ChangeRemoved:
+			opDefs = append(opDefs, opDefinition{Type: "remove", Path: change.Path.String()})
+		case ChangeChanged:
+			opDefs = append(opDefs, opDefinition{Type: "replace", Path: change.Path.String(), Value: jsonValue(change.To)})
+		case ChangeAdded:
+			opDefs = append(opDefs, opDefinition{Type: "replace", Path: addPath(change.Path).String(), Value: jsonValue(change.To)})
+		}
+	}
+
+	bytes, err := json.MarshalIndent(opDefs, "", "  ")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshaling operations")
+	}
+
+	return bytes, nil
+}
+
+type jsonPatchOperation struct {
+	Op    string       `json:"op"`
+	Path  string       `json:"path"`
+	Value *interface{} `json:"value,omitempty"`
+}
+
+// JSONPatch renders changes as RFC 6902 JSON Patch that turns left, the first
+// compared document, into the second one. Array items matched by name are
+// addressed by their index in left as it is being patched.
+func JSONPatch(left []byte, changes []Change) ([]byte, error) {
+	var doc interface{}
+
+	err := yaml.Unmarshal(left, &doc)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing first document")
+	}
+
+	patchOps := []jsonPatchOperation{}
+
+	for _, change := range changes {
+		var patchOp jsonPatchOperation
+		var op patch.Op
+
+		switch change.Type {
+		case ChangeRemoved:
+			patchOp = jsonPatchOperation{Op: "remove"}
+			op = patch.RemoveOp{Path: change.Path}
+		case ChangeChanged:
+			patchOp = jsonPatchOperation{Op: "replace", Value: jsonValue(change.To)}
+			op = patch.ReplaceOp{Path: change.Path, Value: change.To}
+		case ChangeAdded:
+			patchOp = jsonPatchOperation{Op: "add", Value: jsonValue(change.To)}
+			op = patch.ReplaceOp{Path: addPath(change.Path), Value: change.To}
+		}
+
+		patchOp.Path, err = jsonPointer(doc, change.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		patchOps = append(patchOps, patchOp)
+
+		// Later changes are resolved against the document with earlier changes applied
+		doc, err = op.Apply(doc)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Applying change to '%s'", change.Path.String())
+		}
+	}
+
+	bytes, err := json.MarshalIndent(patchOps, "", "  ")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshaling JSON patch")
+	}
+
+	return bytes, nil
+}
+
+// jsonPointer converts path into RFC 6901 JSON Pointer by looking up
+// indices of array items matched by name in doc.
+func jsonPointer(doc interface{}, path patch.Pointer) (string, error) {
+	var pointer strings.Builder
+
+	for _, token := range path.Tokens() {
+		switch typedToken := token.(type) {
+		case patch.RootToken:
+			continue
+
+		case patch.KeyToken:
+			pointer.WriteString("/" + escapeJSONPointerToken(typedToken.Key))
+
+			var found bool
+			if hash, ok := toMapSlice(doc); ok {
+				for _, item := range hash {
+					if fmt.Sprintf("%v", item.Key) == typedToken.Key {
+						doc, found = item.Value, true
+					}
+				}
+			}
+
+			if !found {
+				// Only the last token of added paths is missing
+				doc = nil
+			}
+
+		case patch.IndexToken:
+			pointer.WriteString("/" + strconv.Itoa(typedToken.Index))
+
+			if array, ok := doc.([]interface{}); ok && typedToken.Index < len(array) {
+				doc = array[typedToken.Index]
+			} else {
+				doc = nil
+			}
+
+		case patch.MatchingIndexToken:
+			array, _ := doc.([]interface{})
+			index := -1
+
+			for i, item := range array {
+				if itemName(item) == typedToken.Value {
+					index = i
+					break
+				}
+			}
+
+			// Added items are appended to the end of arrays
+			if index < 0 {
+				pointer.WriteString("/-")
+				doc = nil
+				continue
+			}
+
+			pointer.WriteString("/" + strconv.Itoa(index))
+			doc = array[index]
+
+		default:
+			return "", bosherr.Errorf("Expected path '%s' to only use keys and indices", path.String())
+		}
+	}
+
+	return pointer.String(), nil
+}
+
+func escapeJSONPointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// addPath points at a new array item or an optional hash key.
+func addPath(path patch.Pointer) patch.Pointer {
+	tokens := append([]patch.Token{}, path.Tokens()...)
+	last := len(tokens) - 1
+
+	switch typedToken := tokens[last].(type) {
+	case patch.KeyToken:
+		typedToken.Optional = true
+		tokens[last] = typedToken
+	case patch.MatchingIndexToken, patch.IndexToken:
+		tokens[last] = patch.AfterLastIndexToken{}
+	}
+
+	return patch.NewPointer(tokens)
+}
+
+func jsonValue(val interface{}) *interface{} {
+	converted := jsonCompatibleValue(val)
+	return &converted
+}
+
+func jsonCompatibleValue(val interface{}) interface{} {
+	if hash, ok := toMapSlice(val); ok {
+		converted := map[string]interface{}{}
+		for _, item := range hash {
+			converted[fmt.Sprintf("%v", item.Key)] = jsonCompatibleValue(item.Value)
+		}
+		return converted
+	}
+
+	if array, ok := val.([]interface{}); ok {
+		converted := make([]interface{}, len(array))
+		for i, item := range array {
+			converted[i] = jsonCompatibleValue(item)
+		}
+		return converted
+	}
+
+	return val
+}
@@ -0,0 +1,183 @@
+package diff_test
+
+import (
+	"github.com/cppforlife/go-patch/patch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/diff"
+)
+
+var _ = Describe("Redact", func() {
+	It("redacts values under properties", func() {
+		changes := Redact([]Change{
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/instance_groups/name=web/jobs/name=nginx/properties/port"), From: 80, To: 8080},
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/instance_groups/name=web/instances"), From: 1, To: 2},
+			{
+				Type: ChangeAdded,
+				Path: patch.MustNewPointerFromString("/instance_groups/name=web/jobs/name=nginx"),
+				To: yaml.MapSlice{
+					{Key: "name", Value: "nginx"},
+					{Key: "properties", Value: yaml.MapSlice{{Key: "password", Value: "secret"}, {Key: "hosts", Value: []interface{}{"a"}}}},
+				},
+			},
+		})
+
+		Expect(changes).To(Equal([]Change{
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/instance_groups/name=web/jobs/name=nginx/properties/port"), From: "<redacted>", To: "<redacted>"},
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/instance_groups/name=web/instances"), From: 1, To: 2},
+			{
+				Type: ChangeAdded,
+				Path: patch.MustNewPointerFromString("/instance_groups/name=web/jobs/name=nginx"),
+				To: yaml.MapSlice{
+					{Key: "name", Value: "nginx"},
+					{Key: "properties", Value: yaml.MapSlice{{Key: "password", Value: "<redacted>"}, {Key: "hosts", Value: []interface{}{"<redacted>"}}}},
+				},
+			},
+		}))
+	})
+})
+
+var _ = Describe("UnifiedLines", func() {
+	It("renders removed and added values under changed paths", func() {
+		lines := UnifiedLines([]Change{
+			{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/update/canaries"), From: 1, To: 2},
+			{Type: ChangeRemoved, Path: patch.MustNewPointerFromString("/instance_groups/name=db"), From: yaml.MapSlice{{Key: "name", Value: "db"}, {Key: "instances", Value: 1}}},
+			{Type: ChangeAdded, Path: patch.MustNewPointerFromString("/features"), To: nil},
+		})
+
+		Expect(lines).To(Equal([]Line{
+			{Type: LineHeader, Text: "@@ /update/canaries @@"},
+			{Type: LineRemoved, Text: "- 1"},
+			{Type: LineAdded, Text: "+ 2"},
+			{Type: LineHeader, Text: "@@ /instance_groups/name=db @@"},
+			{Type: LineRemoved, Text: "- name: db"},
+			{Type: LineRemoved, Text: "- instances: 1"},
+			{Type: LineHeader, Text: "@@ /features @@"},
+			{Type: LineAdded, Text: "+ ~"},
+		}))
+	})
+})
+
+var _ = Describe("OpsJSON", func() {
+	It("renders operations that turn the first document into the second one", func() {
+		left := []byte(`
+name: dep
+azs: [z1, z2]
+instance_groups:
+- name: web
+  instances: 1
+- name: db
+`)
+		right := []byte(`
+name: dep
+azs: [z1]
+instance_groups:
+- name: web
+  instances: 2
+  vm_type: large
+- name: worker
+features: {use_dns_addresses: true}
+`)
+
+		changes, err := CompareBytes(left, right)
+		Expect(err).ToNot(HaveOccurred())
+
+		opsBytes, err := OpsJSON(changes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opsBytes).To(MatchJSON(`[
+			{"type": "remove", "path": "/azs/1"},
+			{"type": "replace", "path": "/instance_groups/name=web/instances", "value": 2},
+			{"type": "replace", "path": "/instance_groups/name=web/vm_type?", "value": "large"},
+			{"type": "remove", "path": "/instance_groups/name=db"},
+			{"type": "replace", "path": "/instance_groups/-", "value": {"name": "worker"}},
+			{"type": "replace", "path": "/features?", "value": {"use_dns_addresses": true}}
+		]`))
+
+		var opDefs []patch.OpDefinition
+		Expect(yaml.Unmarshal(opsBytes, &opDefs)).To(Succeed())
+
+		ops, err := patch.NewOpsFromDefinitions(opDefs)
+		Expect(err).ToNot(HaveOccurred())
+
+		var leftDoc, rightDoc interface{}
+		Expect(yaml.Unmarshal(left, &leftDoc)).To(Succeed())
+		Expect(yaml.Unmarshal(right, &rightDoc)).To(Succeed())
+
+		result, err := ops.Apply(leftDoc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Compare(result, rightDoc)).To(BeEmpty())
+	})
+
+	It("keeps null values of replaced paths", func() {
+		opsBytes, err := OpsJSON([]Change{{Type: ChangeChanged, Path: patch.MustNewPointerFromString("/a"), From: 1, To: nil}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opsBytes).To(MatchJSON(`[{"type": "replace", "path": "/a", "value": null}]`))
+	})
+
+	It("renders empty list if there are no changes", func() {
+		opsBytes, err := OpsJSON(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opsBytes).To(MatchJSON(`[]`))
+	})
+})
+
+var _ = Describe("JSONPatch", func() {
+	It("renders RFC 6902 operations with indices of array items matched by name", func() {
+		left := []byte(`
+name: dep
+azs: [z1, z2]
+instance_groups:
+- name: db
+- name: web
+  instances: 1
+`)
+		right := []byte(`
+name: dep
+azs: [z1]
+instance_groups:
+- name: web
+  instances: 2
+  vm_type: large
+- name: worker
+features: {use_dns_addresses: true}
+`)
+
+		changes, err := CompareBytes(left, right)
+		Expect(err).ToNot(HaveOccurred())
+
+		patchBytes, err := JSONPatch(left, changes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patchBytes).To(MatchJSON(`[
+			{"op": "remove", "path": "/azs/1"},
+			{"op": "remove", "path": "/instance_groups/0"},
+			{"op": "replace", "path": "/instance_groups/0/instances", "value": 2},
+			{"op": "add", "path": "/instance_groups/0/vm_type", "value": "large"},
+			{"op": "add", "path": "/instance_groups/-", "value": {"name": "worker"}},
+			{"op": "add", "path": "/features", "value": {"use_dns_addresses": true}}
+		]`))
+	})
+
+	It("escapes keys according to RFC 6901", func() {
+		patchBytes, err := JSONPatch([]byte("a/b: {c~d: 1}"), []Change{
+			{Type: ChangeChanged, Path: patch.NewPointer([]patch.Token{
+				patch.RootToken{}, patch.KeyToken{Key: "a/b"}, patch.KeyToken{Key: "c~d"},
+			}), From: 1, To: 2},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patchBytes).To(MatchJSON(`[{"op": "replace", "path": "/a~1b/c~0d", "value": 2}]`))
+	})
+
+	It("renders empty list if there are no changes", func() {
+		patchBytes, err := JSONPatch([]byte("a: 1"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patchBytes).To(MatchJSON(`[]`))
+	})
+
+	It("returns error if first document cannot be parsed", func() {
+		_, err := JSONPatch([]byte("a: ["), nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing first document"))
+	})
+})
@@ -5,11 +5,17 @@ import (
 
 	"github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
 type Client struct {
 	clientRequest     ClientRequest
 	taskClientRequest TaskClientRequest
+
+	uploadChunkSize int64
+
+	fs                  boshsys.FileSystem
+	resumableUploadsDir string
 }
 
 func NewClient(
@@ -21,7 +27,11 @@ func NewClient(
 ) Client {
 	clientRequest := NewClientRequest(endpoint, httpClient, fileReporter, logger)
 	taskClientRequest := NewTaskClientRequest(clientRequest, taskReporter, 500*time.Millisecond)
-	return Client{clientRequest, taskClientRequest}
+	return Client{
+		clientRequest:     clientRequest,
+		taskClientRequest: taskClientRequest,
+		uploadChunkSize:   DefaultUploadChunkSize,
+	}
 }
 
 func (c Client) WithContext(contextId string) Client {
@@ -30,5 +40,30 @@ func (c Client) WithContext(contextId string) Client {
 	taskClientRequest := c.taskClientRequest
 	taskClientRequest.clientRequest = clientRequest
 
-	return Client{clientRequest, taskClientRequest}
+	c.clientRequest = clientRequest
+	c.taskClientRequest = taskClientRequest
+
+	return c
+}
+
+// WithUploadChunkSize returns a copy of the client that splits
+// resumable uploads into chunks of given size.
+func (c Client) WithUploadChunkSize(size int64) Client {
+	if size > 0 {
+		c.uploadChunkSize = size
+	}
+
+	return c
+}
+
+// WithResumableUploadsDir returns a copy of the client that keeps
+// unfinished resumable uploads in dir so that they can be continued
+// by later commands uploading the same file.
+func (c Client) WithResumableUploadsDir(fs boshsys.FileSystem, dir string) Client {
+	if len(dir) > 0 {
+		c.fs = fs
+		c.resumableUploadsDir = dir
+	}
+
+	return c
 }
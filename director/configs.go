@@ -25,6 +25,16 @@ type ConfigsFilter struct {
 	Name string
 }
 
+// ConfigsNotSupportedError is returned when listing configs
+// from Directors that do not have configs API.
+type ConfigsNotSupportedError struct {
+	Err error
+}
+
+func (e ConfigsNotSupportedError) Error() string {
+	return fmt.Sprintf("Listing configs: %s", e.Err.Error())
+}
+
 type UpdateConfigBody struct {
 	Type             string `json:"type"`
 	Name             string `json:"name"`
@@ -129,11 +139,19 @@ func (c Client) listConfigs(limit int, filter ConfigsFilter) ([]Config, error) {
 	query.Add("latest", strconv.FormatBool(limit == 1))
 	path := fmt.Sprintf("/configs?%s", query.Encode())
 
-	err := c.clientRequest.Get(path, &resps)
+	respBody, response, err := c.clientRequest.RawGet(path, nil, nil)
 	if err != nil {
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return resps, ConfigsNotSupportedError{Err: err}
+		}
 		return resps, bosherr.WrapErrorf(err, "Listing configs")
 	}
 
+	err = json.Unmarshal(respBody, &resps)
+	if err != nil {
+		return resps, bosherr.WrapErrorf(err, "Listing configs: Unmarshaling Director response")
+	}
+
 	return resps, nil
 }
 
@@ -168,6 +168,23 @@ var _ = Describe("Director", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring(
 					"Listing configs: Director responded with non-successful status code '400'"))
+				Expect(err).ToNot(BeAssignableToTypeOf(ConfigsNotSupportedError{}))
+			})
+		})
+
+		Context("when director does not have configs API", func() {
+			It("returns configs not supported error", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/configs"),
+						ghttp.RespondWith(http.StatusNotFound, ""),
+					),
+				)
+
+				_, err := director.ListConfigs(1, ConfigsFilter{})
+				Expect(err).To(BeAssignableToTypeOf(ConfigsNotSupportedError{}))
+				Expect(err.Error()).To(ContainSubstring(
+					"Listing configs: Director responded with non-successful status code '404'"))
 			})
 		})
 	})
@@ -549,13 +549,17 @@ func (c Client) UpdateDeployment(manifest []byte, opts UpdateOpts) error {
 		query.Add("force_latest_variables", "true")
 	}
 
-	if len(opts.Diff.context) != 0 {
+	if len(opts.Diff.context) != 0 || len(opts.Context) != 0 {
 		context := map[string]interface{}{}
 
 		for key, value := range opts.Diff.context {
 			context[key] = value
 		}
 
+		for key, value := range opts.Context {
+			context[key] = value
+		}
+
 		contextJson, err := json.Marshal(context)
 		if err != nil {
 			return bosherr.WrapErrorf(err, "Marshaling context")
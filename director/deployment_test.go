@@ -995,6 +995,31 @@ var _ = Describe("Deployment", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("succeeds updating deployment with additional context values", func() {
+			context := map[string]interface{}{
+				"cloud_config_id": "2",
+			}
+
+			requestParams := "context=%7B%22cloud_config_id%22%3A%222%22%2C%22policy_override%22%3A%7B%22reason%22%3A%22hotfix%22%7D%7D"
+			ConfigureTaskResult(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/deployments", requestParams),
+					ghttp.VerifyBasicAuth("username", "password"),
+					ghttp.VerifyBody([]byte("manifest")),
+				),
+				``,
+				server,
+			)
+
+			updateOpts := UpdateOpts{
+				Diff:    NewDeploymentDiff(nil, context),
+				Context: map[string]interface{}{"policy_override": map[string]interface{}{"reason": "hotfix"}},
+			}
+
+			err := deployment.Update([]byte("manifest"), updateOpts)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
 		It("returns error if task response is non-200", func() {
 			AppendBadRequest(ghttp.VerifyRequest("POST", "/deployments"), server)
 
@@ -5,12 +5,12 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/cloudfoundry/bosh-utils/httpclient"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
 type Factory struct {
@@ -53,6 +53,11 @@ func (f Factory) httpClient(factoryConfig FactoryConfig, taskReporter TaskReport
 	}
 
 	rawClient := httpclient.CreateDefaultClient(certPool)
+
+	err = factoryConfig.HTTP.Configure(rawClient)
+	if err != nil {
+		return Client{}, err
+	}
 	authAdjustment := NewAuthRequestAdjustment(
 		factoryConfig.TokenFunc,
 		factoryConfig.Client,
@@ -78,7 +83,7 @@ func (f Factory) httpClient(factoryConfig FactoryConfig, taskReporter TaskReport
 		return nil
 	}
 
-	retryClient := httpclient.NewNetworkSafeRetryClient(rawClient, 5, 500*time.Millisecond, f.logger)
+	retryClient := factoryConfig.HTTP.RetryClient(rawClient, f.logger)
 
 	authedClient := NewAdjustableClient(retryClient, authAdjustment)
 
@@ -90,7 +95,11 @@ func (f Factory) httpClient(factoryConfig FactoryConfig, taskReporter TaskReport
 		Host:   net.JoinHostPort(factoryConfig.Host, fmt.Sprintf("%d", factoryConfig.Port)),
 	}
 
-	return NewClient(endpoint.String(), httpClient, taskReporter, fileReporter, f.logger), nil
+	client := NewClient(endpoint.String(), httpClient, taskReporter, fileReporter, f.logger)
+
+	client = client.WithUploadChunkSize(factoryConfig.HTTP.UploadChunkSize)
+
+	return client.WithResumableUploadsDir(boshsys.NewOsFileSystem(f.logger), factoryConfig.ResumableUploadsDir), nil
 }
 
 func clearBody(req *http.Request) {
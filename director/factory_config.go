@@ -9,6 +9,8 @@ import (
 
 	"github.com/cloudfoundry/bosh-utils/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 )
 
 type FactoryConfig struct {
@@ -22,6 +24,12 @@ type FactoryConfig struct {
 	ClientSecret string
 
 	TokenFunc func(bool) (string, error)
+
+	// HTTP client settings are optional
+	HTTP bihttpclient.Opts
+
+	// Unfinished resumable uploads are only kept within a command when empty
+	ResumableUploadsDir string
 }
 
 func NewConfigFromURL(url string) (FactoryConfig, error) {
@@ -77,6 +85,10 @@ func (c FactoryConfig) Validate() error {
 		return err
 	}
 
+	if err := c.HTTP.Validate(); err != nil {
+		return err
+	}
+
 	// Don't validate credentials since Info call does not require authentication.
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	. "github.com/cloudfoundry/bosh-cli/v7/director"
 )
 
@@ -104,6 +105,17 @@ var _ = Describe("FactoryConfig", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Parsing certificate 1: Missing PEM block"))
 		})
+
+		It("returns error if HTTP options are invalid", func() {
+			err := FactoryConfig{
+				Host: "host",
+				Port: 1,
+				HTTP: bihttpclient.Opts{Proxy: "ftp://proxy"},
+			}.Validate()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected proxy URL scheme"))
+		})
 	})
 
 	Describe("CACertPool", func() {
@@ -3,6 +3,7 @@ package director_test
 import (
 	"crypto/tls"
 	"net/http"
+	"time"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
@@ -11,6 +12,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
 
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 	. "github.com/cloudfoundry/bosh-cli/v7/director"
 )
 
@@ -112,6 +114,29 @@ var _ = Describe("Factory", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			It("retries requests according to HTTP options", func() {
+				factoryConfig, err := NewConfigFromURL(server.URL())
+				Expect(err).ToNot(HaveOccurred())
+
+				factoryConfig.CACert = validCACert
+				factoryConfig.HTTP = bihttpclient.Opts{MaxAttempts: 2, RetryDelay: time.Millisecond}
+
+				logger := boshlog.NewLogger(boshlog.LevelNone)
+
+				director, err := NewFactory(logger).New(factoryConfig, nil, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				server.AppendHandlers(
+					ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+					ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+					ghttp.RespondWith(http.StatusOK, `{}`),
+				)
+
+				_, err = director.Info()
+				Expect(err).To(HaveOccurred())
+				Expect(server.ReceivedRequests()).To(HaveLen(2))
+			})
+
 			It("succeeds making initial post request and clears out headers when redirecting to a get resource", func() {
 				fs := fakesys.NewFakeFileSystem()
 
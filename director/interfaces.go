@@ -227,6 +227,9 @@ type UpdateOpts struct {
 	DryRun                  bool
 	Diff                    DeploymentDiff
 	ForceLatestVariables    bool
+
+	// Context is recorded in the task context next to the diff context
+	Context map[string]interface{}
 }
 
 //counterfeiter:generate . ReleaseSeries
@@ -359,6 +359,22 @@ func (c Client) UploadReleaseFile(file UploadFile, rebase, fix bool) error {
 
 	path := "/releases?" + query.Encode()
 
+	upload, err := c.uploadResumably(file, fileInfo.Size())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Uploading release file")
+	}
+
+	if len(upload.URL) > 0 {
+		err = c.UploadReleaseURL(upload.URL, upload.SHA1, rebase, fix)
+		if err != nil {
+			return err
+		}
+
+		c.forgetResumableUpload(upload)
+
+		return nil
+	}
+
 	setHeadersAndBody := func(req *http.Request) {
 		req.Header.Add("Content-Type", "application/x-compressed")
 		req.ContentLength = fileInfo.Size()
@@ -0,0 +1,361 @@
+package director
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	gourl "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+/*
+Tarballs larger than a single chunk are uploaded with tus resumable upload
+protocol 1.0.0 (https://tus.io/protocols/resumable-upload) when Director
+(or a proxy in front of it) advertises it on /uploads together with creation
+and checksum extensions:
+
+  OPTIONS /uploads => Tus-Version, Tus-Extension, Tus-Checksum-Algorithm
+  POST    /uploads (Upload-Length) => 201 Location: /uploads/abc
+  PATCH   /uploads/abc (Upload-Offset, Upload-Checksum) => 204 Upload-Offset
+  HEAD    /uploads/abc => Upload-Offset
+
+Completed upload is then imported via the same API as remote tarballs,
+i.e. by specifying its location and SHA1.
+
+When client is configured with a resumable uploads directory, location of
+an unfinished upload is kept there under the SHA1 of the file so that
+a later command uploading the same file continues from the offset
+reported by HEAD instead of starting over.
+*/
+
+const (
+	DefaultUploadChunkSize = 64 * 1024 * 1024
+
+	tusResumableVersion = "1.0.0"
+
+	// Number of consecutive failed chunk uploads
+	// without any progress before upload is given up
+	resumableUploadMaxAttempts = 5
+
+	resumableUploadsLogTag = "director.resumableUploads"
+)
+
+type resumableUpload struct {
+	URL  string `json:"url"`
+	SHA1 string `json:"sha1"`
+	Size int64  `json:"size"`
+}
+
+// uploadResumably uploads file in chunks when file is larger than a single chunk
+// and Director supports resumable uploads. Returns upload with empty URL otherwise
+// so that file can be uploaded in a single request.
+func (c Client) uploadResumably(file UploadFile, size int64) (resumableUpload, error) {
+	var upload resumableUpload
+
+	if size <= c.uploadChunkSize {
+		return upload, nil
+	}
+
+	seeker, ok := file.(io.Seeker)
+	if !ok {
+		return upload, nil
+	}
+
+	if !c.supportsResumableUploads() {
+		return upload, nil
+	}
+
+	digest, err := c.uploadFileSHA1(file, seeker)
+	if err != nil {
+		return upload, err
+	}
+
+	uploadURL, offset := c.unfinishedResumableUpload(digest, size)
+
+	if uploadURL == nil {
+		uploadURL, err = c.createResumableUpload(size)
+		if err != nil {
+			return upload, err
+		}
+
+		c.saveResumableUpload(resumableUpload{URL: uploadURL.String(), SHA1: digest, Size: size})
+	}
+
+	tracked := c.clientRequest.fileReporter.TrackUpload(size, file)
+
+	defer tracked.Close() //nolint:errcheck
+
+	offset, err = tracked.Seek(offset, io.SeekStart)
+	if err != nil {
+		return upload, bosherr.WrapErrorf(err, "Seeking to offset '%d'", offset)
+	}
+
+	chunk := make([]byte, c.uploadChunkSize)
+
+	for offset < size {
+		n, err := io.ReadFull(tracked, chunk[:min(c.uploadChunkSize, size-offset)])
+		if err != nil {
+			return upload, bosherr.WrapErrorf(err, "Reading chunk at offset '%d'", offset)
+		}
+
+		offset, err = c.uploadChunk(uploadURL, offset, chunk[:n])
+		if err != nil {
+			return upload, err
+		}
+	}
+
+	return resumableUpload{URL: uploadURL.String(), SHA1: digest, Size: size}, nil
+}
+
+// unfinishedResumableUpload returns location and offset of an upload of the same
+// file left by an earlier command. Returns nil location if there is none to continue.
+func (c Client) unfinishedResumableUpload(digest string, size int64) (*gourl.URL, int64) {
+	if len(c.resumableUploadsDir) == 0 {
+		return nil, 0
+	}
+
+	path := c.resumableUploadPath(digest)
+
+	if !c.fs.FileExists(path) {
+		return nil, 0
+	}
+
+	var upload resumableUpload
+
+	bytes, err := c.fs.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(bytes, &upload)
+	}
+
+	if err != nil {
+		c.clientRequest.logger.Warn(resumableUploadsLogTag, "Reading resumable upload '%s': %s", path, err)
+		return nil, 0
+	}
+
+	uploadURL, err := gourl.Parse(upload.URL)
+	if err != nil || upload.Size != size || !strings.HasPrefix(upload.URL, c.clientRequest.endpoint+"/") {
+		return nil, 0
+	}
+
+	// Director may have already discarded the upload
+	resp, err := c.tusRequest("HEAD", uploadURL.RequestURI(), nil, nil)
+	if err != nil {
+		c.clientRequest.logger.Debug(resumableUploadsLogTag, "Checking resumable upload '%s': %s", upload.URL, err)
+		return nil, 0
+	}
+
+	offset, err := uploadOffset(resp)
+	if err != nil || offset < 0 || offset > size {
+		return nil, 0
+	}
+
+	c.clientRequest.logger.Debug(resumableUploadsLogTag, "Continuing resumable upload '%s' from offset '%d'", upload.URL, offset)
+
+	return uploadURL, offset
+}
+
+func (c Client) saveResumableUpload(upload resumableUpload) {
+	if len(c.resumableUploadsDir) == 0 {
+		return
+	}
+
+	bytes, err := json.Marshal(upload)
+	if err != nil {
+		return
+	}
+
+	err = c.fs.MkdirAll(c.resumableUploadsDir, os.ModePerm)
+	if err == nil {
+		err = c.fs.WriteFile(c.resumableUploadPath(upload.SHA1), bytes)
+	}
+
+	if err != nil {
+		c.clientRequest.logger.Warn(resumableUploadsLogTag, "Saving resumable upload '%s': %s", upload.URL, err)
+	}
+}
+
+// forgetResumableUpload is called once upload was imported
+// since Director does not need to keep it afterwards.
+func (c Client) forgetResumableUpload(upload resumableUpload) {
+	if len(c.resumableUploadsDir) == 0 {
+		return
+	}
+
+	err := c.fs.RemoveAll(c.resumableUploadPath(upload.SHA1))
+	if err != nil {
+		c.clientRequest.logger.Warn(resumableUploadsLogTag, "Removing resumable upload '%s': %s", upload.URL, err)
+	}
+}
+
+func (c Client) resumableUploadPath(digest string) string {
+	return filepath.Join(c.resumableUploadsDir, digest+".json")
+}
+
+// supportsResumableUploads treats any failure as lack of support
+// since older Directors do not respond to OPTIONS requests.
+func (c Client) supportsResumableUploads() bool {
+	resp, err := c.tusRequest("OPTIONS", "/uploads", nil, nil)
+	if err != nil {
+		return false
+	}
+
+	return headerListContains(resp.Header, "Tus-Version", tusResumableVersion) &&
+		headerListContains(resp.Header, "Tus-Extension", "creation") &&
+		headerListContains(resp.Header, "Tus-Extension", "checksum") &&
+		headerListContains(resp.Header, "Tus-Checksum-Algorithm", "sha1")
+}
+
+func (c Client) uploadFileSHA1(file UploadFile, seeker io.Seeker) (string, error) {
+	hash := sha1.New() //nolint:gosec
+
+	_, err := io.Copy(hash, file)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Calculating file SHA1")
+	}
+
+	_, err = seeker.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Seeking to file start")
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (c Client) createResumableUpload(size int64) (*gourl.URL, error) {
+	setHeaders := func(req *http.Request) {
+		req.Header.Add("Upload-Length", strconv.FormatInt(size, 10))
+	}
+
+	resp, err := c.tusRequest("POST", "/uploads", nil, setHeaders)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Creating resumable upload")
+	}
+
+	uploadURL, err := resp.Location()
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Getting Location header of resumable upload")
+	}
+
+	return uploadURL, nil
+}
+
+// uploadChunk sends chunk starting at given offset and returns offset
+// of the next chunk. Failed attempts are continued from the offset reported by the Director.
+func (c Client) uploadChunk(uploadURL *gourl.URL, offset int64, chunk []byte) (int64, error) {
+	end := offset + int64(len(chunk))
+	path := uploadURL.RequestURI()
+
+	var lastErr error
+
+	for attempt := 0; attempt < resumableUploadMaxAttempts; {
+		resp, err := c.tusRequest("PATCH", path, chunk, chunkHeaders(offset, chunk))
+		if err == nil {
+			var newOffset int64
+
+			newOffset, err = uploadOffset(resp)
+			if err == nil && newOffset == end {
+				return end, nil
+			}
+
+			if err == nil {
+				err = bosherr.Errorf("Expected Director to report offset '%d' but was '%d'", end, newOffset)
+			}
+		}
+
+		lastErr = err
+
+		resp, err = c.tusRequest("HEAD", path, nil, nil)
+		if err != nil {
+			return 0, bosherr.WrapErrorf(lastErr, "Uploading chunk at offset '%d'", offset)
+		}
+
+		newOffset, err := uploadOffset(resp)
+		if err != nil {
+			return 0, bosherr.WrapErrorf(err, "Uploading chunk at offset '%d'", offset)
+		}
+
+		if newOffset < offset || newOffset > end {
+			return 0, bosherr.Errorf("Expected Director to report offset between '%d' and '%d' but was '%d'", offset, end, newOffset)
+		}
+
+		if newOffset == end {
+			return end, nil
+		}
+
+		if newOffset > offset {
+			// Only remaining part of the chunk needs to be sent
+			chunk = chunk[newOffset-offset:]
+			offset = newOffset
+			attempt = 0
+		} else {
+			attempt++
+		}
+	}
+
+	return 0, bosherr.WrapErrorf(lastErr, "Uploading chunk at offset '%d'", offset)
+}
+
+func chunkHeaders(offset int64, chunk []byte) func(*http.Request) {
+	checksum := sha1.Sum(chunk) //nolint:gosec
+
+	return func(req *http.Request) {
+		req.Header.Add("Content-Type", "application/offset+octet-stream")
+		req.Header.Add("Upload-Offset", strconv.FormatInt(offset, 10))
+		req.Header.Add("Upload-Checksum", "sha1 "+base64.StdEncoding.EncodeToString(checksum[:]))
+	}
+}
+
+// tusRequest sends request with given method since generic HTTP client
+// only provides GET, POST, PUT and DELETE requests.
+func (c Client) tusRequest(method, path string, payload []byte, f func(*http.Request)) (*http.Response, error) {
+	r := c.clientRequest
+	url := r.endpoint + path
+
+	wrapperFunc := r.setContextIDHeader(func(req *http.Request) {
+		req.Method = method
+		req.Header.Add("Tus-Resumable", tusResumableVersion)
+
+		if f != nil {
+			f(req)
+		}
+	})
+
+	resp, err := r.httpClient.PutCustomized(url, payload, wrapperFunc)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Performing request %s '%s'", method, url)
+	}
+
+	_, resp, err = r.readResponse(resp, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func uploadOffset(resp *http.Response) (int64, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Parsing Upload-Offset header")
+	}
+
+	return offset, nil
+}
+
+func headerListContains(header http.Header, name, value string) bool {
+	for _, item := range strings.Split(header.Get(name), ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+
+	return false
+}
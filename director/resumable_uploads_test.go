@@ -0,0 +1,370 @@
+package director_test
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
+	. "github.com/cloudfoundry/bosh-cli/v7/director"
+)
+
+// resumableUploadsStandIn implements tus 1.0.0 resumable upload protocol
+// with creation and checksum extensions in memory
+type resumableUploadsStandIn struct {
+	mutex sync.Mutex
+
+	supported bool
+	uploads   map[string]*standInUpload
+
+	patches int
+
+	// Stores only part of the next chunk and drops connection
+	dropNextChunkAfter int
+	// Number of upcoming chunks to reject because of checksum mismatch
+	rejectChunks int
+}
+
+type standInUpload struct {
+	size int64
+	data []byte
+}
+
+func newResumableUploadsStandIn(server *ghttp.Server) *resumableUploadsStandIn {
+	s := &resumableUploadsStandIn{supported: true, uploads: map[string]*standInUpload{}}
+
+	server.RouteToHandler("OPTIONS", "/uploads", s.options)
+	server.RouteToHandler("POST", "/uploads", s.create)
+	server.RouteToHandler("PATCH", regexp.MustCompile(`^/uploads/[^/]+$`), s.patch)
+	server.RouteToHandler("HEAD", regexp.MustCompile(`^/uploads/[^/]+$`), s.head)
+
+	return s
+}
+
+func (s *resumableUploadsStandIn) options(w http.ResponseWriter, req *http.Request) {
+	if !s.supported {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", "1.0.0")
+	w.Header().Set("Tus-Version", "1.0.0")
+	w.Header().Set("Tus-Extension", "creation,checksum")
+	w.Header().Set("Tus-Checksum-Algorithm", "md5,sha1")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *resumableUploadsStandIn) create(w http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	Expect(req.Header.Get("Tus-Resumable")).To(Equal("1.0.0"))
+
+	size, err := strconv.ParseInt(req.Header.Get("Upload-Length"), 10, 64)
+	Expect(err).ToNot(HaveOccurred())
+
+	id := fmt.Sprintf("upload-%d", len(s.uploads)+1)
+	s.uploads[id] = &standInUpload{size: size}
+
+	w.Header().Set("Location", "/uploads/"+id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *resumableUploadsStandIn) head(w http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	upload, found := s.uploads[strings.TrimPrefix(req.URL.Path, "/uploads/")]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.Itoa(len(upload.data)))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *resumableUploadsStandIn) patch(w http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.patches++
+
+	upload := s.uploads[strings.TrimPrefix(req.URL.Path, "/uploads/")]
+
+	Expect(req.Header.Get("Tus-Resumable")).To(Equal("1.0.0"))
+	Expect(req.Header.Get("Content-Type")).To(Equal("application/offset+octet-stream"))
+
+	chunk, err := io.ReadAll(req.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	offset, err := strconv.Atoi(req.Header.Get("Upload-Offset"))
+	Expect(err).ToNot(HaveOccurred())
+
+	if offset != len(upload.data) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	checksum := sha1.Sum(chunk) //nolint:gosec
+
+	if s.rejectChunks > 0 || req.Header.Get("Upload-Checksum") != "sha1 "+base64.StdEncoding.EncodeToString(checksum[:]) {
+		s.rejectChunks--
+		w.WriteHeader(460)
+		return
+	}
+
+	if s.dropNextChunkAfter > 0 {
+		upload.data = append(upload.data, chunk[:s.dropNextChunkAfter]...)
+		s.dropNextChunkAfter = 0
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Close()).To(Succeed())
+		return
+	}
+
+	upload.data = append(upload.data, chunk...)
+
+	w.Header().Set("Upload-Offset", strconv.Itoa(len(upload.data)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *resumableUploadsStandIn) data(id string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return string(s.uploads[id].data)
+}
+
+var _ = Describe("Resumable uploads", func() {
+	var (
+		director   Director
+		server     *ghttp.Server
+		standIn    *resumableUploadsStandIn
+		file       *os.File
+		uploadsDir string
+	)
+
+	const content = "0123456789"
+
+	BeforeEach(func() {
+		server = ghttp.NewUnstartedServer()
+		server.HTTPTestServer.TLS = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+		server.HTTPTestServer.StartTLS()
+
+		factoryConfig, err := NewConfigFromURL(server.URL())
+		Expect(err).ToNot(HaveOccurred())
+
+		factoryConfig.Client = "username"
+		factoryConfig.ClientSecret = "password"
+		factoryConfig.CACert = validCACert
+		factoryConfig.HTTP = bihttpclient.Opts{MaxAttempts: 1, UploadChunkSize: 4}
+
+		uploadsDir, err = os.MkdirTemp("", "bosh-resumable-uploads")
+		Expect(err).ToNot(HaveOccurred())
+
+		factoryConfig.ResumableUploadsDir = filepath.Join(uploadsDir, "uploads")
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		director, err = NewFactory(logger).New(factoryConfig, NewNoopTaskReporter(), NewNoopFileReporter())
+		Expect(err).ToNot(HaveOccurred())
+
+		standIn = newResumableUploadsStandIn(server)
+
+		file, err = os.CreateTemp("", "bosh-resumable-upload")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = file.WriteString(content)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = file.Seek(0, io.SeekStart)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.Remove(file.Name())   //nolint:errcheck
+		os.RemoveAll(uploadsDir) //nolint:errcheck
+	})
+
+	contentSHA1 := func() string {
+		sum := sha1.Sum([]byte(content)) //nolint:gosec
+		return hex.EncodeToString(sum[:])
+	}
+
+	It("uploads release in chunks and creates release from the upload", func() {
+		ConfigureTaskResult(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/releases", "rebase=true&fix=true"),
+				ghttp.VerifyBasicAuth("username", "password"),
+				ghttp.VerifyHeader(http.Header{
+					"Content-Type": []string{"application/json"},
+				}),
+				ghttp.VerifyJSON(fmt.Sprintf(`{"location": "%s/uploads/upload-1", "sha1": "%s"}`, server.URL(), contentSHA1())),
+			),
+			"",
+			server,
+		)
+
+		Expect(director.UploadReleaseFile(file, true, true)).To(Succeed())
+
+		Expect(standIn.data("upload-1")).To(Equal(content))
+		Expect(standIn.patches).To(Equal(3))
+	})
+
+	It("uploads stemcell in chunks and creates stemcell from the upload", func() {
+		ConfigureTaskResult(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/stemcells", ""),
+				ghttp.VerifyJSON(fmt.Sprintf(`{"location": "%s/uploads/upload-1", "sha1": "%s"}`, server.URL(), contentSHA1())),
+			),
+			"",
+			server,
+		)
+
+		Expect(director.UploadStemcellFile(file, false)).To(Succeed())
+		Expect(standIn.data("upload-1")).To(Equal(content))
+	})
+
+	It("resumes from offset reported by the director after connection is dropped", func() {
+		standIn.dropNextChunkAfter = 2
+
+		ConfigureTaskResult(ghttp.VerifyRequest("POST", "/releases"), "", server)
+
+		Expect(director.UploadReleaseFile(file, false, false)).To(Succeed())
+		Expect(standIn.data("upload-1")).To(Equal(content))
+	})
+
+	It("resends chunks rejected because of checksum mismatch", func() {
+		standIn.rejectChunks = 2
+
+		ConfigureTaskResult(ghttp.VerifyRequest("POST", "/releases"), "", server)
+
+		Expect(director.UploadReleaseFile(file, false, false)).To(Succeed())
+		Expect(standIn.data("upload-1")).To(Equal(content))
+		Expect(standIn.patches).To(Equal(5))
+	})
+
+	It("returns error if chunk keeps failing without progress", func() {
+		standIn.rejectChunks = 100
+
+		err := director.UploadReleaseFile(file, false, false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Uploading release file: Uploading chunk at offset '0'"))
+		Expect(standIn.patches).To(Equal(5))
+	})
+
+	Describe("continuing uploads of earlier commands", func() {
+		savedUploadPath := func() string {
+			return filepath.Join(uploadsDir, "uploads", contentSHA1()+".json")
+		}
+
+		saveUpload := func(id string) {
+			Expect(os.MkdirAll(filepath.Join(uploadsDir, "uploads"), 0700)).To(Succeed())
+
+			upload := fmt.Sprintf(`{"url": "%s/uploads/%s", "sha1": "%s", "size": %d}`, server.URL(), id, contentSHA1(), len(content))
+			Expect(os.WriteFile(savedUploadPath(), []byte(upload), 0600)).To(Succeed())
+		}
+
+		It("keeps unfinished upload and continues it in a later upload of the same file", func() {
+			standIn.rejectChunks = 100
+
+			err := director.UploadReleaseFile(file, false, false)
+			Expect(err).To(HaveOccurred())
+
+			saved, err := os.ReadFile(savedUploadPath())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(saved).To(MatchJSON(fmt.Sprintf(`{"url": "%s/uploads/upload-1", "sha1": "%s", "size": 10}`, server.URL(), contentSHA1())))
+
+			standIn.rejectChunks = 0
+
+			ConfigureTaskResult(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/releases"),
+					ghttp.VerifyJSON(fmt.Sprintf(`{"location": "%s/uploads/upload-1", "sha1": "%s"}`, server.URL(), contentSHA1())),
+				),
+				"",
+				server,
+			)
+
+			file, err = os.Open(file.Name())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(director.UploadReleaseFile(file, false, false)).To(Succeed())
+			Expect(standIn.data("upload-1")).To(Equal(content))
+			Expect(standIn.uploads).To(HaveLen(1))
+
+			Expect(savedUploadPath()).ToNot(BeAnExistingFile())
+		})
+
+		It("sends only the part that the director has not received yet", func() {
+			standIn.uploads["upload-1"] = &standInUpload{size: 10, data: []byte("0123")}
+			saveUpload("upload-1")
+
+			ConfigureTaskResult(ghttp.VerifyRequest("POST", "/stemcells"), "", server)
+
+			Expect(director.UploadStemcellFile(file, false)).To(Succeed())
+			Expect(standIn.data("upload-1")).To(Equal(content))
+			Expect(standIn.patches).To(Equal(2))
+
+			Expect(savedUploadPath()).ToNot(BeAnExistingFile())
+		})
+
+		It("starts a new upload if the director no longer knows the saved one", func() {
+			saveUpload("upload-9")
+
+			ConfigureTaskResult(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/releases"),
+					ghttp.VerifyJSON(fmt.Sprintf(`{"location": "%s/uploads/upload-1", "sha1": "%s"}`, server.URL(), contentSHA1())),
+				),
+				"",
+				server,
+			)
+
+			Expect(director.UploadReleaseFile(file, false, false)).To(Succeed())
+			Expect(standIn.data("upload-1")).To(Equal(content))
+		})
+	})
+
+	It("uploads file in a single request if director does not support resumable uploads", func() {
+		standIn.supported = false
+
+		ConfigureTaskResult(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/releases"),
+				ghttp.VerifyHeader(http.Header{
+					"Content-Type":   []string{"application/x-compressed"},
+					"Content-Length": []string{"10"},
+				}),
+				ghttp.VerifyBody([]byte(content)),
+			),
+			"",
+			server,
+		)
+
+		Expect(director.UploadReleaseFile(file, false, false)).To(Succeed())
+		Expect(standIn.patches).To(Equal(0))
+	})
+})
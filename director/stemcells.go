@@ -231,6 +231,22 @@ func (c Client) UploadStemcellFile(file UploadFile, fix bool) error {
 
 	path := "/stemcells?" + query.Encode()
 
+	upload, err := c.uploadResumably(file, fileInfo.Size())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Uploading stemcell file")
+	}
+
+	if len(upload.URL) > 0 {
+		err = c.UploadStemcellURL(upload.URL, upload.SHA1, fix)
+		if err != nil {
+			return err
+		}
+
+		c.forgetResumableUpload(upload)
+
+		return nil
+	}
+
 	setHeadersAndBody := func(req *http.Request) {
 		req.Header.Add("Content-Type", "application/x-compressed")
 		req.ContentLength = fileInfo.Size()
@@ -0,0 +1,102 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+// CredHubVariables reads the current value of credentials from a
+// CredHub-compatible data API. Relative variable names are looked up
+// under Prefix; names starting with '/' are used as is.
+type CredHubVariables struct {
+	onDemandVariables
+
+	Client *http.Client
+
+	URL    string // e.g. https://credhub.example.com:8844
+	Prefix string
+	Token  string
+}
+
+var _ Variables = CredHubVariables{}
+
+func (v CredHubVariables) Get(varDef VariableDefinition) (interface{}, bool, error) {
+	name := v.credentialName(varDef.Name)
+
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("current", "true")
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(v.URL, "/")+"/api/v1/data?"+query.Encode(), nil)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Building CredHub request for variable '%s'", varDef.Name)
+	}
+
+	if len(v.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+v.Token)
+	}
+
+	resp, err := v.client().Do(req)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Reading variable '%s' from CredHub", varDef.Name)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, bosherr.Errorf("Reading variable '%s' from CredHub: unexpected status '%s'", varDef.Name, resp.Status)
+	}
+
+	var body struct {
+		Data []struct {
+			Value interface{} `json:"value"`
+		} `json:"data"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Unmarshalling CredHub credential '%s'", name)
+	}
+
+	if len(body.Data) == 0 {
+		return nil, false, nil
+	}
+
+	val, err := yamlValue(body.Data[0].Value)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Converting CredHub credential '%s'", name)
+	}
+
+	return val, true, nil
+}
+
+func (v CredHubVariables) credentialName(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+
+	prefix := strings.Trim(v.Prefix, "/")
+	if len(prefix) == 0 {
+		return "/" + name
+	}
+
+	return fmt.Sprintf("/%s/%s", prefix, name)
+}
+
+func (v CredHubVariables) client() *http.Client {
+	if v.Client != nil {
+		return v.Client
+	}
+
+	return boshhttp.CreateExternalDefaultClient(nil)
+}
@@ -0,0 +1,81 @@
+package template_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+var _ = Describe("CredHubVariables", func() {
+	var (
+		server *ghttp.Server
+		vars   CredHubVariables
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		vars = CredHubVariables{
+			URL:    server.URL(),
+			Prefix: "bosh/my-env",
+			Token:  "fake-token",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Get", func() {
+		It("returns current value of credential under prefix", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/api/v1/data", "current=true&name=%2Fbosh%2Fmy-env%2Fcert"),
+					ghttp.VerifyHeader(http.Header{"Authorization": []string{"Bearer fake-token"}}),
+					ghttp.RespondWith(http.StatusOK, `{"data":[{"type":"certificate","value":{"ca":"fake-ca","certificate":"fake-cert"}}]}`),
+				),
+			)
+
+			val, found, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal(map[interface{}]interface{}{
+				"ca":          "fake-ca",
+				"certificate": "fake-cert",
+			}))
+		})
+
+		It("uses absolute names as is", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/api/v1/data", "current=true&name=%2Fshared%2Fpassword"),
+					ghttp.RespondWith(http.StatusOK, `{"data":[{"value":"fake-password"}]}`),
+				),
+			)
+
+			val, found, err := vars.Get(VariableDefinition{Name: "/shared/password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("returns not found if credential does not exist", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, `{"error":"not found"}`))
+
+			_, found, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns an error for unexpected statuses", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, `{}`))
+
+			_, _, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading variable 'cert' from CredHub: unexpected status '401 Unauthorized'"))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// ExecVariables asks an executable plugin for variables. For every variable
+// the plugin is run with an ExecVariablesRequest as JSON on stdin and is
+// expected to print an ExecVariablesResponse as JSON on stdout.
+type ExecVariables struct {
+	onDemandVariables
+
+	CmdRunner boshsys.CmdRunner
+
+	Path string
+	Args []string
+}
+
+type ExecVariablesRequest struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type,omitempty"`
+	Options interface{} `json:"options,omitempty"`
+}
+
+type ExecVariablesResponse struct {
+	Found bool        `json:"found"`
+	Value interface{} `json:"value"`
+	Error string      `json:"error,omitempty"`
+}
+
+var _ Variables = ExecVariables{}
+
+func (v ExecVariables) Get(varDef VariableDefinition) (interface{}, bool, error) {
+	reqBytes, err := json.Marshal(ExecVariablesRequest{
+		Name:    varDef.Name,
+		Type:    varDef.Type,
+		Options: jsonCompatibleValue(varDef.Options),
+	})
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Marshalling plugin request for variable '%s'", varDef.Name)
+	}
+
+	stdout, _, _, err := v.CmdRunner.RunComplexCommand(boshsys.Command{
+		Name:  v.Path,
+		Args:  v.Args,
+		Stdin: bytes.NewReader(reqBytes),
+		Quiet: true,
+	})
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Running variables plugin '%s' for variable '%s'", v.Path, varDef.Name)
+	}
+
+	var resp ExecVariablesResponse
+
+	err = json.Unmarshal([]byte(stdout), &resp)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Unmarshalling response of variables plugin '%s'", v.Path)
+	}
+
+	if len(resp.Error) > 0 {
+		return nil, false, bosherr.Errorf("Variables plugin '%s' failed to get variable '%s': %s", v.Path, varDef.Name, resp.Error)
+	}
+
+	if !resp.Found {
+		return nil, false, nil
+	}
+
+	val, err := yamlValue(resp.Value)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Converting variable '%s' from variables plugin '%s'", varDef.Name, v.Path)
+	}
+
+	return val, true, nil
+}
+
+// jsonCompatibleValue converts YAML maps with interface{} keys so they can be marshalled as JSON.
+func jsonCompatibleValue(val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for k, v := range typedVal {
+			converted[fmt.Sprintf("%v", k)] = jsonCompatibleValue(v)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := map[string]interface{}{}
+		for k, v := range typedVal {
+			converted[k] = jsonCompatibleValue(v)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(typedVal))
+		for i, v := range typedVal {
+			converted[i] = jsonCompatibleValue(v)
+		}
+		return converted
+	default:
+		return val
+	}
+}
@@ -0,0 +1,71 @@
+package template_test
+
+import (
+	"errors"
+	"io"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+var _ = Describe("ExecVariables", func() {
+	var (
+		cmdRunner *fakesys.FakeCmdRunner
+		vars      ExecVariables
+	)
+
+	BeforeEach(func() {
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		vars = ExecVariables{CmdRunner: cmdRunner, Path: "/plugin"}
+	})
+
+	Describe("Get", func() {
+		It("sends variable definition to the plugin and returns its value", func() {
+			cmdRunner.AddCmdResult("/plugin", fakesys.FakeCmdResult{
+				Stdout: `{"found":true,"value":{"certificate":"fake-cert"}}`,
+			})
+
+			val, found, err := vars.Get(VariableDefinition{
+				Name:    "cert",
+				Type:    "certificate",
+				Options: map[interface{}]interface{}{"ca": "ca"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal(map[interface{}]interface{}{"certificate": "fake-cert"}))
+
+			Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+
+			stdin, err := io.ReadAll(cmdRunner.RunComplexCommands[0].Stdin)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(stdin)).To(Equal(`{"name":"cert","type":"certificate","options":{"ca":"ca"}}`))
+		})
+
+		It("returns not found if plugin does not know the variable", func() {
+			cmdRunner.AddCmdResult("/plugin", fakesys.FakeCmdResult{Stdout: `{"found":false}`})
+
+			_, found, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns an error if plugin reports an error", func() {
+			cmdRunner.AddCmdResult("/plugin", fakesys.FakeCmdResult{Stdout: `{"error":"fake-err"}`})
+
+			_, _, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Variables plugin '/plugin' failed to get variable 'cert': fake-err"))
+		})
+
+		It("returns an error if plugin fails to run", func() {
+			cmdRunner.AddCmdResult("/plugin", fakesys.FakeCmdResult{Error: errors.New("fake-run-err")})
+
+			_, _, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-run-err"))
+		})
+	})
+})
@@ -11,14 +11,29 @@ func NewMultiVars(varss []Variables) MultiVars {
 var _ Variables = MultiVars{}
 
 func (m MultiVars) Get(varDef VariableDefinition) (interface{}, bool, error) {
+	val, found, _, err := m.GetWithSource(varDef)
+	return val, found, err
+}
+
+// GetWithSource additionally describes which of the variables found the value.
+func (m MultiVars) GetWithSource(varDef VariableDefinition) (interface{}, bool, string, error) {
 	for _, vars := range m.varss {
+		if multiVars, ok := vars.(MultiVars); ok {
+			val, found, source, err := multiVars.GetWithSource(varDef)
+			if found || err != nil {
+				return val, found, source, err
+			}
+
+			continue
+		}
+
 		val, found, err := vars.Get(varDef)
 		if found || err != nil {
-			return val, found, err
+			return val, found, VariableSource(vars, varDef.Name), err
 		}
 	}
 
-	return nil, false, nil
+	return nil, false, "", nil
 }
 
 func (m MultiVars) List() ([]VariableDefinition, error) {
@@ -70,6 +70,25 @@ var _ = Describe("MultiVariables", func() {
 		})
 	})
 
+	Describe("GetWithSource", func() {
+		It("returns the source of the variables that found the value", func() {
+			vars1 := StaticVariables{"key1": "val1"}
+			vars2 := SourcedVariables{Variables: StaticVariables{"key2": "val2"}, Source: "fake-source"}
+			vars := NewMultiVars([]Variables{vars1, NewMultiVars([]Variables{vars2})})
+
+			val, found, source, err := vars.GetWithSource(VariableDefinition{Name: "key2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("val2"))
+			Expect(source).To(Equal("fake-source"))
+
+			_, found, source, err = vars.GetWithSource(VariableDefinition{Name: "key1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(source).To(BeEmpty())
+		})
+	})
+
 	Describe("List", func() {
 		It("returns list of names from multiple vars with duplicates", func() {
 			defs, err := NewMultiVars(nil).List()
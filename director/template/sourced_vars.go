@@ -0,0 +1,55 @@
+package template
+
+import (
+	"sort"
+	"strings"
+)
+
+// VariableSourcer is implemented by variables that can describe
+// where a variable comes from (e.g. '--vars-file=/path').
+type VariableSourcer interface {
+	VariableSource(name string) string
+}
+
+// VariableSource describes where vars keep the named variable, or returns an empty string.
+func VariableSource(vars Variables, name string) string {
+	if sourcer, ok := vars.(VariableSourcer); ok {
+		return sourcer.VariableSource(name)
+	}
+
+	return ""
+}
+
+// SourcedVariables labels variables with the place they were loaded from.
+type SourcedVariables struct {
+	Variables
+
+	// Sources by variable name; dotted names (e.g. 'cert.ca') apply to their first segment
+	Sources map[string]string
+
+	// Source is used for variables without an entry in Sources
+	Source string
+}
+
+var _ VariableSourcer = SourcedVariables{}
+
+func (v SourcedVariables) VariableSource(name string) string {
+	if source, found := v.Sources[name]; found {
+		return source
+	}
+
+	var sourcedNames []string
+
+	for sourcedName := range v.Sources {
+		if strings.HasPrefix(sourcedName, name+".") {
+			sourcedNames = append(sourcedNames, sourcedName)
+		}
+	}
+
+	if len(sourcedNames) > 0 {
+		sort.Strings(sourcedNames)
+		return v.Sources[sourcedNames[0]]
+	}
+
+	return v.Source
+}
@@ -0,0 +1,38 @@
+package template_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+var _ = Describe("SourcedVariables", func() {
+	var vars SourcedVariables
+
+	BeforeEach(func() {
+		vars = SourcedVariables{
+			Variables: StaticVariables{"key": "val", "cert.ca": "ca"},
+			Sources:   map[string]string{"key": "key-source", "cert.ca": "cert-source"},
+			Source:    "default-source",
+		}
+	})
+
+	It("gets values from underlying variables", func() {
+		val, found, err := vars.Get(VariableDefinition{Name: "key"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(val).To(Equal("val"))
+	})
+
+	It("returns source of variables by name including dotted names", func() {
+		Expect(vars.VariableSource("key")).To(Equal("key-source"))
+		Expect(vars.VariableSource("cert")).To(Equal("cert-source"))
+		Expect(vars.VariableSource("other")).To(Equal("default-source"))
+	})
+
+	It("is used by VariableSource", func() {
+		Expect(VariableSource(vars, "key")).To(Equal("key-source"))
+		Expect(VariableSource(StaticVariables{}, "key")).To(BeEmpty())
+	})
+})
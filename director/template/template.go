@@ -21,6 +21,9 @@ type EvaluateOpts struct {
 	ExpectAllVarsUsed     bool
 	PostVarSubstitutionOp patch.Op
 	UnescapedMultiline    bool
+
+	// Trace records where interpolated variables came from when set
+	Trace *EvaluateTrace
 }
 
 func NewTemplate(bytes []byte) Template {
@@ -42,7 +45,7 @@ func (t Template) Evaluate(vars Variables, op patch.Op, opts EvaluateOpts) ([]by
 		}
 	}
 
-	obj, err = t.interpolateRoot(obj, newVarsTracker(vars, opts.ExpectAllKeys, opts.ExpectAllVarsUsed))
+	obj, err = t.interpolateRoot(obj, newVarsTracker(vars, opts.ExpectAllKeys, opts.ExpectAllVarsUsed), opts.Trace)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -66,13 +69,13 @@ func (t Template) Evaluate(vars Variables, op patch.Op, opts EvaluateOpts) ([]by
 	return bytes, nil
 }
 
-func (t Template) interpolateRoot(obj interface{}, tracker varsTracker) (interface{}, error) {
+func (t Template) interpolateRoot(obj interface{}, tracker varsTracker, trace *EvaluateTrace) (interface{}, error) {
 	err := tracker.ExtractDefinitions(obj)
 	if err != nil {
 		return nil, err
 	}
 
-	obj, err = interpolator{}.Interpolate(obj, varsLookup{tracker})
+	obj, err = interpolator{trace: trace}.Interpolate(obj, varsLookup{tracker})
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +88,9 @@ func (t Template) interpolateRoot(obj interface{}, tracker varsTracker) (interfa
 	return obj, tracker.Error()
 }
 
-type interpolator struct{}
+type interpolator struct {
+	trace *EvaluateTrace
+}
 
 var (
 	interpolationRegex         = regexp.MustCompile(`\(\( ?(!?[-/\.\w\pL]+) ?\)\)`)
@@ -93,15 +98,19 @@ var (
 )
 
 func (i interpolator) Interpolate(node interface{}, varsLookup varsLookup) (interface{}, error) {
+	return i.interpolate(node, []patch.Token{patch.RootToken{}}, varsLookup)
+}
+
+func (i interpolator) interpolate(node interface{}, tokens []patch.Token, varsLookup varsLookup) (interface{}, error) {
 	switch typedNode := node.(type) {
 	case map[interface{}]interface{}:
 		for k, v := range typedNode {
-			evaluatedValue, err := i.Interpolate(v, varsLookup)
+			evaluatedValue, err := i.interpolate(v, i.childTokens(tokens, patch.KeyToken{Key: fmt.Sprintf("%v", k)}), varsLookup)
 			if err != nil {
 				return nil, err
 			}
 
-			evaluatedKey, err := i.Interpolate(k, varsLookup)
+			evaluatedKey, err := i.interpolate(k, tokens, varsLookup)
 			if err != nil {
 				return nil, err
 			}
@@ -113,7 +122,7 @@ func (i interpolator) Interpolate(node interface{}, varsLookup varsLookup) (inte
 	case []interface{}:
 		for idx, x := range typedNode {
 			var err error
-			typedNode[idx], err = i.Interpolate(x, varsLookup)
+			typedNode[idx], err = i.interpolate(x, i.childTokens(tokens, patch.IndexToken{Index: idx}), varsLookup)
 			if err != nil {
 				return nil, err
 			}
@@ -126,6 +135,8 @@ func (i interpolator) Interpolate(node interface{}, varsLookup varsLookup) (inte
 				return nil, bosherr.WrapErrorf(err, "Finding variable '%s'", name)
 			}
 
+			i.recordVariable(name, tokens, found, varsLookup)
+
 			if found {
 				// ensure that value type is preserved when replacing the entire field
 				if interpolationAnchoredRegex.MatchString(typedNode) {
@@ -150,6 +161,27 @@ func (i interpolator) Interpolate(node interface{}, varsLookup varsLookup) (inte
 	return node, nil
 }
 
+func (i interpolator) childTokens(tokens []patch.Token, token patch.Token) []patch.Token {
+	if i.trace == nil {
+		return tokens
+	}
+
+	return append(append([]patch.Token{}, tokens...), token)
+}
+
+func (i interpolator) recordVariable(name string, tokens []patch.Token, found bool, varsLookup varsLookup) {
+	if i.trace == nil {
+		return
+	}
+
+	i.trace.Variables = append(i.trace.Variables, VariableTrace{
+		Name:   name,
+		Path:   patch.NewPointer(tokens).String(),
+		Source: varsLookup.sources[strings.Split(name, ".")[0]],
+		Found:  found,
+	})
+}
+
 func (i interpolator) extractVarNames(value string) []string {
 	var names []string
 
@@ -220,6 +252,7 @@ type varsTracker struct {
 	missing    map[string]struct{} // track missing var names
 	visited    map[string]struct{}
 	visitedAll map[string]struct{} // track all var names that were accessed
+	sources    map[string]string   // track where found vars came from
 }
 
 func newVarsTracker(vars Variables, expectAllFound, expectAllUsed bool) varsTracker {
@@ -230,6 +263,7 @@ func newVarsTracker(vars Variables, expectAllFound, expectAllUsed bool) varsTrac
 		missing:        map[string]struct{}{},
 		visited:        map[string]struct{}{},
 		visitedAll:     map[string]struct{}{},
+		sources:        map[string]string{},
 	}
 }
 
@@ -256,7 +290,19 @@ func (t varsTracker) Get(name string) (interface{}, bool, error) {
 		t.missing[name] = struct{}{}
 	}
 
-	val, found, err := t.vars.Get(def)
+	var val interface{}
+	var found bool
+
+	if multiVars, ok := t.vars.(MultiVars); ok {
+		var source string
+
+		val, found, source, err = multiVars.GetWithSource(def)
+		t.sources[name] = source
+	} else {
+		val, found, err = t.vars.Get(def)
+		t.sources[name] = VariableSource(t.vars, name)
+	}
+
 	if !found {
 		t.missing[name] = struct{}{}
 	}
@@ -862,4 +862,40 @@ variables:
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("fake-err"))
 	})
+
+	Context("when trace is given", func() {
+		It("records path and source of each variable", func() {
+			template := NewTemplate([]byte(`
+name: ((name))
+groups:
+- props: {url: "https://((host)):((port))", ca: ((cert.ca))}
+missing: ((missing))
+`))
+
+			vars := NewMultiVars([]Variables{
+				SourcedVariables{
+					Variables: StaticVariables{"name": "dep", "host": "example.com"},
+					Sources:   map[string]string{"name": "--var=name", "host": "--vars-file=vars.yml"},
+				},
+				SourcedVariables{
+					Variables: StaticVariables{"port": 443, "cert": map[interface{}]interface{}{"ca": "fake-ca"}},
+					Source:    "--vars-store=store.yml",
+				},
+			})
+
+			trace := &EvaluateTrace{}
+
+			result, err := template.Evaluate(vars, nil, EvaluateOpts{Trace: trace})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(result)).To(ContainSubstring("url: https://example.com:443"))
+
+			Expect(trace.Variables).To(ConsistOf(
+				VariableTrace{Name: "name", Path: "/name", Source: "--var=name", Found: true},
+				VariableTrace{Name: "host", Path: "/groups/0/props/url", Source: "--vars-file=vars.yml", Found: true},
+				VariableTrace{Name: "port", Path: "/groups/0/props/url", Source: "--vars-store=store.yml", Found: true},
+				VariableTrace{Name: "cert.ca", Path: "/groups/0/props/ca", Source: "--vars-store=store.yml", Found: true},
+				VariableTrace{Name: "missing", Path: "/missing", Found: false},
+			))
+		})
+	})
 })
@@ -0,0 +1,99 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-patch/patch"
+	"gopkg.in/yaml.v2"
+)
+
+// EvaluateTrace records where the pieces of an evaluated template came from.
+type EvaluateTrace struct {
+	Ops       []OpTrace
+	Variables []VariableTrace
+}
+
+type OpTrace struct {
+	Source string
+	Type   string
+	Path   string
+
+	// ChangedPaths point at values that were added, replaced or removed by the operation
+	ChangedPaths []string
+}
+
+type VariableTrace struct {
+	Name   string
+	Path   string
+	Source string
+	Found  bool
+}
+
+type tracedOp struct {
+	op     patch.Op
+	source string
+	trace  *EvaluateTrace
+}
+
+// NewTracedOp records every application of op to trace.
+// Documents are copied before each application hence it's only meant for explaining.
+func NewTracedOp(op patch.Op, source string, trace *EvaluateTrace) patch.Op {
+	return tracedOp{op: op, source: source, trace: trace}
+}
+
+func (op tracedOp) Apply(doc interface{}) (interface{}, error) {
+	before, err := copyDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := op.op.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	opTrace := OpTrace{Source: op.source}
+	opTrace.Type, opTrace.Path = describeOp(op.op)
+
+	for _, diffOp := range (patch.Diff{Left: before, Right: after, Unchecked: true}).Calculate() {
+		_, path := describeOp(diffOp)
+		opTrace.ChangedPaths = append(opTrace.ChangedPaths, path)
+	}
+
+	op.trace.Ops = append(op.trace.Ops, opTrace)
+
+	return after, nil
+}
+
+func describeOp(op patch.Op) (string, string) {
+	switch typedOp := op.(type) {
+	case patch.DescriptiveOp:
+		return describeOp(typedOp.Op)
+	case patch.ReplaceOp:
+		return "replace", typedOp.Path.String()
+	case patch.RemoveOp:
+		return "remove", typedOp.Path.String()
+	case patch.TestOp:
+		return "test", typedOp.Path.String()
+	case patch.FindOp:
+		return "find", typedOp.Path.String()
+	default:
+		return fmt.Sprintf("%T", op), ""
+	}
+}
+
+func copyDoc(doc interface{}) (interface{}, error) {
+	bytes, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var docCopy interface{}
+
+	err = yaml.Unmarshal(bytes, &docCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	return docCopy, nil
+}
@@ -0,0 +1,52 @@
+package template_test
+
+import (
+	"errors"
+
+	"github.com/cppforlife/go-patch/patch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+var _ = Describe("NewTracedOp", func() {
+	var trace *EvaluateTrace
+
+	BeforeEach(func() {
+		trace = &EvaluateTrace{}
+	})
+
+	It("records applied operations with the paths they changed", func() {
+		op := patch.Ops{
+			NewTracedOp(patch.DescriptiveOp{
+				Op: patch.ReplaceOp{Path: patch.MustNewPointerFromString("/groups/name=web/size"), Value: 2},
+			}, "ops.yml [0]", trace),
+			NewTracedOp(patch.RemoveOp{Path: patch.MustNewPointerFromString("/other")}, "ops.yml [1]", trace),
+		}
+
+		doc := map[interface{}]interface{}{
+			"groups": []interface{}{map[interface{}]interface{}{"name": "web", "size": 1}},
+			"other":  "val",
+		}
+
+		result, err := op.Apply(doc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(map[interface{}]interface{}{
+			"groups": []interface{}{map[interface{}]interface{}{"name": "web", "size": 2}},
+		}))
+
+		Expect(trace.Ops).To(Equal([]OpTrace{
+			{Source: "ops.yml [0]", Type: "replace", Path: "/groups/name=web/size", ChangedPaths: []string{"/groups/0/size"}},
+			{Source: "ops.yml [1]", Type: "remove", Path: "/other", ChangedPaths: []string{"/other"}},
+		}))
+	})
+
+	It("does not record failed operations", func() {
+		op := NewTracedOp(patch.ErrOp{Err: errors.New("fake-err")}, "ops.yml [0]", trace)
+
+		_, err := op.Apply(map[interface{}]interface{}{})
+		Expect(err).To(Equal(errors.New("fake-err")))
+		Expect(trace.Ops).To(BeEmpty())
+	})
+})
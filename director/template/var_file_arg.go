@@ -12,6 +12,7 @@ type VarFileArg struct {
 	FS boshsys.FileSystem
 
 	Vars StaticVariables
+	Path string
 }
 
 func (a *VarFileArg) UnmarshalFlag(data string) error {
@@ -43,6 +44,7 @@ func (a *VarFileArg) UnmarshalFlag(data string) error {
 	}
 
 	(*a).Vars = StaticVariables{pieces[0]: string(bytes)}
+	(*a).Path = absPath
 
 	return nil
 }
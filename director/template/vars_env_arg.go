@@ -9,7 +9,8 @@ import (
 )
 
 type VarsEnvArg struct {
-	Vars StaticVariables
+	Vars   StaticVariables
+	Prefix string
 
 	EnvironFunc func() []string
 }
@@ -47,6 +48,7 @@ func (a *VarsEnvArg) UnmarshalFlag(prefix string) error {
 	}
 
 	(*a).Vars = vars
+	(*a).Prefix = prefix
 
 	return nil
 }
@@ -11,6 +11,7 @@ type VarsFileArg struct {
 	FS boshsys.FileSystem
 
 	Vars StaticVariables
+	Path string
 }
 
 func (a *VarsFileArg) UnmarshalFlag(filePath string) error {
@@ -35,6 +36,7 @@ func (a *VarsFileArg) UnmarshalFlag(filePath string) error {
 	}
 
 	a.Vars = vars
+	a.Path = filePath
 
 	return nil
 }
@@ -0,0 +1,81 @@
+package template
+
+import (
+	"encoding/json"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CachingVariables remembers what each variable resolved to so that
+// external sources are asked at most once per variable and command.
+type CachingVariables struct {
+	vars Variables
+
+	cache map[string]cachedVariable
+	lock  *sync.Mutex
+}
+
+type cachedVariable struct {
+	val   interface{}
+	found bool
+}
+
+var _ Variables = CachingVariables{}
+
+func NewCachingVariables(vars Variables) CachingVariables {
+	return CachingVariables{
+		vars:  vars,
+		cache: map[string]cachedVariable{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+func (v CachingVariables) Get(varDef VariableDefinition) (interface{}, bool, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if cached, found := v.cache[varDef.Name]; found {
+		return cached.val, cached.found, nil
+	}
+
+	val, found, err := v.vars.Get(varDef)
+	if err != nil {
+		return nil, false, err
+	}
+
+	v.cache[varDef.Name] = cachedVariable{val: val, found: found}
+
+	return val, found, nil
+}
+
+func (v CachingVariables) List() ([]VariableDefinition, error) {
+	return v.vars.List()
+}
+
+// onDemandVariables is embedded by external variables sources. Their
+// variables are looked up on demand, so there is nothing to list upfront.
+type onDemandVariables struct{}
+
+func (onDemandVariables) List() ([]VariableDefinition, error) {
+	return nil, nil
+}
+
+// yamlValue converts decoded JSON into the shapes YAML decoding produces
+// (e.g. map[interface{}]interface{}) so that values from external sources
+// can be traversed like values from vars files.
+func yamlValue(val interface{}) (interface{}, error) {
+	bytes, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var converted interface{}
+
+	err = yaml.Unmarshal(bytes, &converted)
+	if err != nil {
+		return nil, err
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,212 @@
+package template
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
+)
+
+const (
+	DefaultVaultTokenEnv   = "VAULT_TOKEN"
+	DefaultCredHubTokenEnv = "CREDHUB_TOKEN"
+)
+
+// VarsSourceArg configures an external variables source from a URI:
+//
+//	vault+https://vault.example.com:8200/MOUNT/PATH[?token_env=VAULT_TOKEN&kv_version=2]
+//	credhub+https://credhub.example.com:8844/PREFIX[?token_env=CREDHUB_TOKEN]
+//	exec:///path/to/plugin
+//
+// Vault and CredHub sources also accept ca_cert (path to a PEM file),
+// proxy and request_timeout query params for their HTTP client.
+// Values are cached for the lifetime of the command.
+type VarsSourceArg struct {
+	Vars Variables
+
+	FS            boshsys.FileSystem
+	CmdRunner     boshsys.CmdRunner
+	LookupEnvFunc func(string) (string, bool)
+}
+
+func (a *VarsSourceArg) UnmarshalFlag(data string) error {
+	if len(data) == 0 {
+		return bosherr.Errorf("Expected variables source to be non-empty")
+	}
+
+	if a.LookupEnvFunc == nil {
+		a.LookupEnvFunc = os.LookupEnv
+	}
+
+	if a.FS == nil {
+		a.FS = boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+	}
+
+	if a.CmdRunner == nil {
+		a.CmdRunner = boshsys.NewExecCmdRunner(boshlog.NewLogger(boshlog.LevelNone))
+	}
+
+	uri, err := url.Parse(data)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing variables source '%s'", data)
+	}
+
+	var vars Variables
+
+	switch uri.Scheme {
+	case "vault+http", "vault+https":
+		vars, err = a.vaultVariables(uri)
+	case "credhub+http", "credhub+https":
+		vars, err = a.credHubVariables(uri)
+	case "exec":
+		vars, err = a.execVariables(uri)
+	default:
+		return bosherr.Errorf("Expected variables source '%s' to start with 'vault+http(s)://', 'credhub+http(s)://' or 'exec:'", data)
+	}
+
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Configuring variables source '%s'", data)
+	}
+
+	(*a).Vars = SourcedVariables{Variables: NewCachingVariables(vars), Source: "--vars-source=" + data}
+
+	return nil
+}
+
+func (a VarsSourceArg) vaultVariables(uri *url.URL) (Variables, error) {
+	pieces := strings.SplitN(strings.Trim(uri.Path, "/"), "/", 2)
+	if len(pieces[0]) == 0 {
+		return nil, bosherr.Error("Expected Vault secrets engine mount in path")
+	}
+
+	client, err := a.httpClient(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := VaultVariables{
+		Client:    client,
+		Address:   a.baseURL(uri, "vault+"),
+		Mount:     pieces[0],
+		KVVersion: 2,
+	}
+
+	if len(pieces) == 2 {
+		vars.Path = pieces[1]
+	}
+
+	if kvVersion := uri.Query().Get("kv_version"); len(kvVersion) > 0 {
+		version, err := strconv.Atoi(kvVersion)
+		if err != nil || (version != 1 && version != 2) {
+			return nil, bosherr.Errorf("Expected kv_version to be 1 or 2 but was '%s'", kvVersion)
+		}
+
+		vars.KVVersion = version
+	}
+
+	token, err := a.token(uri, DefaultVaultTokenEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	vars.Token = token
+
+	return vars, nil
+}
+
+func (a VarsSourceArg) credHubVariables(uri *url.URL) (Variables, error) {
+	client, err := a.httpClient(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := a.token(uri, DefaultCredHubTokenEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	return CredHubVariables{
+		Client: client,
+		URL:    a.baseURL(uri, "credhub+"),
+		Prefix: strings.Trim(uri.Path, "/"),
+		Token:  token,
+	}, nil
+}
+
+func (a VarsSourceArg) execVariables(uri *url.URL) (Variables, error) {
+	path := uri.Path
+	if len(uri.Opaque) > 0 {
+		path = uri.Opaque
+	}
+
+	if len(path) == 0 {
+		return nil, bosherr.Error("Expected plugin path to be non-empty")
+	}
+
+	return ExecVariables{CmdRunner: a.CmdRunner, Path: path}, nil
+}
+
+// httpClient builds a client the same way as for the Director so that
+// proxies and timeouts behave alike.
+func (a VarsSourceArg) httpClient(uri *url.URL) (*http.Client, error) {
+	query := uri.Query()
+
+	opts := bihttpclient.Opts{Proxy: query.Get("proxy")}
+
+	if requestTimeout := query.Get("request_timeout"); len(requestTimeout) > 0 {
+		timeout, err := time.ParseDuration(requestTimeout)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing request_timeout '%s'", requestTimeout)
+		}
+
+		opts.RequestTimeout = timeout
+	}
+
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var caCert string
+
+	if caCertPath := query.Get("ca_cert"); len(caCertPath) > 0 {
+		caCert, err = a.FS.ReadFileString(caCertPath)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading CA certificate '%s'", caCertPath)
+		}
+	}
+
+	return opts.ExternalClient(caCert)
+}
+
+// token reads the token from the environment; only an explicitly
+// configured token_env has to be set.
+func (a VarsSourceArg) token(uri *url.URL, defaultEnv string) (string, error) {
+	tokenEnv := uri.Query().Get("token_env")
+	if len(tokenEnv) == 0 {
+		token, _ := a.LookupEnvFunc(defaultEnv)
+		return token, nil
+	}
+
+	token, found := a.LookupEnvFunc(tokenEnv)
+	if !found {
+		return "", bosherr.Errorf("Expected environment variable '%s' to be set", tokenEnv)
+	}
+
+	return token, nil
+}
+
+func (a VarsSourceArg) baseURL(uri *url.URL, schemePrefix string) string {
+	return (&url.URL{
+		Scheme: strings.TrimPrefix(uri.Scheme, schemePrefix),
+		Host:   uri.Host,
+	}).String()
+}
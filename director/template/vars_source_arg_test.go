@@ -0,0 +1,175 @@
+package template_test
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+var _ = Describe("VarsSourceArg", func() {
+	Describe("UnmarshalFlag", func() {
+		var (
+			server *ghttp.Server
+			fs     *fakesys.FakeFileSystem
+			arg    VarsSourceArg
+			env    map[string]string
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			fs = fakesys.NewFakeFileSystem()
+			env = map[string]string{}
+			arg = VarsSourceArg{
+				FS:        fs,
+				CmdRunner: fakesys.NewFakeCmdRunner(),
+				LookupEnvFunc: func(name string) (string, bool) {
+					val, found := env[name]
+					return val, found
+				},
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("configures Vault source with token from VAULT_TOKEN by default", func() {
+			env["VAULT_TOKEN"] = "fake-token"
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/secret/data/bosh/my-env/password"),
+					ghttp.VerifyHeader(http.Header{"X-Vault-Token": []string{"fake-token"}}),
+					ghttp.RespondWith(http.StatusOK, `{"data":{"data":{"value":"fake-password"}}}`),
+				),
+			)
+
+			err := (&arg).UnmarshalFlag("vault+" + server.URL() + "/secret/bosh/my-env")
+			Expect(err).ToNot(HaveOccurred())
+
+			val, found, err := arg.Vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("fake-password"))
+
+			// Cached values do not hit the server again
+			_, _, err = arg.Vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("configures Vault KV v1 source with token from given env variable", func() {
+			env["MY_TOKEN"] = "fake-token"
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/kv/password"),
+					ghttp.VerifyHeader(http.Header{"X-Vault-Token": []string{"fake-token"}}),
+					ghttp.RespondWith(http.StatusOK, `{"data":{"value":"fake-password"}}`),
+				),
+			)
+
+			err := (&arg).UnmarshalFlag("vault+" + server.URL() + "/kv?kv_version=1&token_env=MY_TOKEN")
+			Expect(err).ToNot(HaveOccurred())
+
+			val, _, err := arg.Vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("configures CredHub source", func() {
+			env["CREDHUB_TOKEN"] = "fake-token"
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/api/v1/data", "current=true&name=%2Fbosh%2Fmy-env%2Fpassword"),
+					ghttp.VerifyHeader(http.Header{"Authorization": []string{"Bearer fake-token"}}),
+					ghttp.RespondWith(http.StatusOK, `{"data":[{"value":"fake-password"}]}`),
+				),
+			)
+
+			err := (&arg).UnmarshalFlag("credhub+" + server.URL() + "/bosh/my-env")
+			Expect(err).ToNot(HaveOccurred())
+
+			val, _, err := arg.Vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("trusts the given CA certificate", func() {
+			tlsServer := ghttp.NewTLSServer()
+			defer tlsServer.Close()
+
+			caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.HTTPTestServer.Certificate().Raw})
+			Expect(fs.WriteFile("/ca.pem", caCert)).To(Succeed())
+
+			tlsServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"data":[{"value":"fake-password"}]}`))
+
+			err := (&arg).UnmarshalFlag("credhub+" + tlsServer.URL() + "/bosh?ca_cert=/ca.pem")
+			Expect(err).ToNot(HaveOccurred())
+
+			val, _, err := arg.Vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("returns an error if CA certificate cannot be read", func() {
+			err := (&arg).UnmarshalFlag("vault+https://vault/secret?ca_cert=/missing.pem")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading CA certificate '/missing.pem'"))
+		})
+
+		It("returns an error if HTTP client options are invalid", func() {
+			err := (&arg).UnmarshalFlag("vault+https://vault/secret?request_timeout=soon")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing request_timeout 'soon'"))
+
+			err = (&arg).UnmarshalFlag("credhub+https://credhub/bosh?proxy=ftp://proxy")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected proxy URL scheme to be one of"))
+		})
+
+		It("configures executable plugin source", func() {
+			cmdRunner := fakesys.NewFakeCmdRunner()
+			cmdRunner.AddCmdResult("/path/to/plugin", fakesys.FakeCmdResult{Stdout: `{"found":true,"value":"fake-password"}`})
+			arg.CmdRunner = cmdRunner
+
+			err := (&arg).UnmarshalFlag("exec:///path/to/plugin")
+			Expect(err).ToNot(HaveOccurred())
+
+			val, _, err := arg.Vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("returns an error if given token env variable is not set", func() {
+			err := (&arg).UnmarshalFlag("vault+https://vault/secret?token_env=MY_TOKEN")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected environment variable 'MY_TOKEN' to be set"))
+		})
+
+		It("returns an error if Vault mount is missing", func() {
+			err := (&arg).UnmarshalFlag("vault+https://vault")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected Vault secrets engine mount in path"))
+		})
+
+		It("returns an error for unknown schemes", func() {
+			err := (&arg).UnmarshalFlag("ftp://vault")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected variables source 'ftp://vault' to start with"))
+		})
+
+		It("returns an error if source is empty", func() {
+			err := (&arg).UnmarshalFlag("")
+			Expect(err).To(Equal(errors.New("Expected variables source to be non-empty")))
+		})
+	})
+})
@@ -0,0 +1,65 @@
+package template_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+type countingVariables struct {
+	vars  StaticVariables
+	err   error
+	calls map[string]int
+}
+
+func (v countingVariables) Get(varDef VariableDefinition) (interface{}, bool, error) {
+	v.calls[varDef.Name]++
+	if v.err != nil {
+		return nil, false, v.err
+	}
+	return v.vars.Get(varDef)
+}
+
+func (v countingVariables) List() ([]VariableDefinition, error) { return v.vars.List() }
+
+var _ = Describe("CachingVariables", func() {
+	var (
+		source countingVariables
+		vars   CachingVariables
+	)
+
+	BeforeEach(func() {
+		source = countingVariables{vars: StaticVariables{"key": "val"}, calls: map[string]int{}}
+		vars = NewCachingVariables(source)
+	})
+
+	It("asks underlying variables once for found and missing variables", func() {
+		for i := 0; i < 2; i++ {
+			val, found, err := vars.Get(VariableDefinition{Name: "key"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("val"))
+
+			_, found, err = vars.Get(VariableDefinition{Name: "missing"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		}
+
+		Expect(source.calls).To(Equal(map[string]int{"key": 1, "missing": 1}))
+	})
+
+	It("does not cache errors", func() {
+		source.err = errors.New("fake-err")
+		vars = NewCachingVariables(source)
+
+		for i := 0; i < 2; i++ {
+			_, _, err := vars.Get(VariableDefinition{Name: "key"})
+			Expect(err).To(Equal(errors.New("fake-err")))
+		}
+
+		Expect(source.calls["key"]).To(Equal(2))
+	})
+})
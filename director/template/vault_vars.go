@@ -0,0 +1,128 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+// VaultVariables reads variables from a Vault KV secrets engine.
+// Each variable is a secret under Path; a secret with a single 'value'
+// key resolves to that value, any other secret resolves to all of its keys.
+type VaultVariables struct {
+	onDemandVariables
+
+	Client *http.Client
+
+	Address   string // e.g. https://vault.example.com:8200
+	Mount     string
+	Path      string
+	Token     string
+	KVVersion int
+}
+
+var _ Variables = VaultVariables{}
+
+func (v VaultVariables) Get(varDef VariableDefinition) (interface{}, bool, error) {
+	req, err := http.NewRequest("GET", v.secretURL(varDef.Name), nil)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Building Vault request for variable '%s'", varDef.Name)
+	}
+
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.client().Do(req)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Reading variable '%s' from Vault", varDef.Name)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, bosherr.Errorf("Reading variable '%s' from Vault: unexpected status '%s'", varDef.Name, resp.Status)
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Unmarshalling Vault secret for variable '%s'", varDef.Name)
+	}
+
+	secretData := body.Data
+
+	if v.KVVersion != 1 {
+		var versioned struct {
+			Data json.RawMessage `json:"data"`
+		}
+
+		err = json.Unmarshal(body.Data, &versioned)
+		if err != nil {
+			return nil, false, bosherr.WrapErrorf(err, "Unmarshalling Vault secret for variable '%s'", varDef.Name)
+		}
+
+		secretData = versioned.Data
+	}
+
+	var secret map[string]interface{}
+
+	err = json.Unmarshal(secretData, &secret)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Unmarshalling Vault secret for variable '%s'", varDef.Name)
+	}
+
+	// Deleted KV v2 versions come back with null data
+	if secret == nil {
+		return nil, false, nil
+	}
+
+	var val interface{} = secret
+
+	if inner, found := secret["value"]; found && len(secret) == 1 {
+		val = inner
+	}
+
+	val, err = yamlValue(val)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Converting Vault secret for variable '%s'", varDef.Name)
+	}
+
+	return val, true, nil
+}
+
+func (v VaultVariables) secretURL(name string) string {
+	segments := []string{strings.Trim(v.Mount, "/")}
+
+	if v.KVVersion != 1 {
+		segments = append(segments, "data")
+	}
+
+	if path := strings.Trim(v.Path, "/"); len(path) > 0 {
+		segments = append(segments, path)
+	}
+
+	for _, piece := range strings.Split(strings.Trim(name, "/"), "/") {
+		segments = append(segments, url.PathEscape(piece))
+	}
+
+	return fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(v.Address, "/"), strings.Join(segments, "/"))
+}
+
+func (v VaultVariables) client() *http.Client {
+	if v.Client != nil {
+		return v.Client
+	}
+
+	return boshhttp.CreateExternalDefaultClient(nil)
+}
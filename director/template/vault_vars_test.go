@@ -0,0 +1,99 @@
+package template_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/director/template"
+)
+
+var _ = Describe("VaultVariables", func() {
+	var (
+		server *ghttp.Server
+		vars   VaultVariables
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		vars = VaultVariables{
+			Address:   server.URL(),
+			Mount:     "secret",
+			Path:      "bosh/my-env",
+			Token:     "fake-token",
+			KVVersion: 2,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Get", func() {
+		It("returns the 'value' key of a KV v2 secret", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/secret/data/bosh/my-env/password"),
+					ghttp.VerifyHeader(http.Header{"X-Vault-Token": []string{"fake-token"}}),
+					ghttp.RespondWith(http.StatusOK, `{"data":{"data":{"value":"fake-password"},"metadata":{"version":1}}}`),
+				),
+			)
+
+			val, found, err := vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("returns all keys of secrets with multiple keys", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/secret/data/bosh/my-env/cert"),
+					ghttp.RespondWith(http.StatusOK, `{"data":{"data":{"certificate":"fake-cert","private_key":"fake-key"}}}`),
+				),
+			)
+
+			val, found, err := vars.Get(VariableDefinition{Name: "cert"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal(map[interface{}]interface{}{
+				"certificate": "fake-cert",
+				"private_key": "fake-key",
+			}))
+		})
+
+		It("reads KV v1 secrets", func() {
+			vars.KVVersion = 1
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/secret/bosh/my-env/password"),
+					ghttp.RespondWith(http.StatusOK, `{"data":{"value":"fake-password"}}`),
+				),
+			)
+
+			val, found, err := vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(val).To(Equal("fake-password"))
+		})
+
+		It("returns not found if secret does not exist", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, `{"errors":[]}`))
+
+			_, found, err := vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns an error for unexpected statuses", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusForbidden, `{"errors":["permission denied"]}`))
+
+			_, _, err := vars.Get(VariableDefinition{Name: "password"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading variable 'password' from Vault: unexpected status '403 Forbidden'"))
+		})
+	})
+})
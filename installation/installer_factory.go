@@ -33,6 +33,7 @@ type installerFactory struct {
 	logTag                 string
 	fs                     boshsys.FileSystem
 	digestCreateAlgorithms []boshcrypto.Algorithm
+	rubyERBRenderer        bool
 }
 
 func NewInstallerFactory(
@@ -44,6 +45,7 @@ func NewInstallerFactory(
 	logger boshlog.Logger,
 	fs boshsys.FileSystem,
 	digestCreateAlgorithms []boshcrypto.Algorithm,
+	rubyERBRenderer bool,
 ) InstallerFactory {
 	return &installerFactory{
 		ui:                     ui,
@@ -55,12 +57,14 @@ func NewInstallerFactory(
 		logTag:                 "installer",
 		fs:                     fs,
 		digestCreateAlgorithms: digestCreateAlgorithms,
+		rubyERBRenderer:        rubyERBRenderer,
 	}
 }
 
 func (f *installerFactory) NewInstaller(target Target) Installer {
 	context := &installerFactoryContext{
 		target:                 target,
+		ui:                     f.ui,
 		runner:                 f.runner,
 		logger:                 f.logger,
 		extractor:              f.extractor,
@@ -68,6 +72,7 @@ func (f *installerFactory) NewInstaller(target Target) Installer {
 		releaseJobResolver:     f.releaseJobResolver,
 		fs:                     f.fs,
 		digestCreateAlgorithms: f.digestCreateAlgorithms,
+		rubyERBRenderer:        f.rubyERBRenderer,
 	}
 
 	return NewInstaller(
@@ -82,6 +87,7 @@ func (f *installerFactory) NewInstaller(target Target) Installer {
 
 type installerFactoryContext struct {
 	target             Target
+	ui                 biui.UI
 	fs                 boshsys.FileSystem
 	runner             boshsys.CmdRunner
 	logger             boshlog.Logger
@@ -95,11 +101,16 @@ type installerFactoryContext struct {
 	blobExtractor          blobextract.Extractor
 	compiledPackageRepo    bistatepkg.CompiledPackageRepo
 	digestCreateAlgorithms []boshcrypto.Algorithm
+	rubyERBRenderer        bool
 }
 
 func (c *installerFactoryContext) JobRenderer() JobRenderer {
 
 	erbRenderer := bierbrenderer.NewERBRenderer(c.fs, c.runner, c.logger)
+	if !c.rubyERBRenderer {
+		erbRenderer = bierbrenderer.NewNativeERBRenderer(c.fs, erbRenderer, c.ui, c.logger)
+	}
+
 	jobRenderer := bitemplate.NewJobRenderer(erbRenderer, c.fs, c.uuidGenerator, c.logger)
 	jobListRenderer := bitemplate.NewJobListRenderer(jobRenderer, c.logger)
 
@@ -4,8 +4,13 @@ import (
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 )
 
+// LocalFakeCPI selects the built-in local fake CPI instead of a CPI release.
+const LocalFakeCPI = "fake"
+
 type Manifest struct {
 	Name string
+	// CPI optionally selects a built-in CPI, in which case no templates are needed
+	CPI string
 	// Deprecated: use Templates instead
 	Template   ReleaseJobRef
 	Templates  []ReleaseJobRef
@@ -14,6 +19,10 @@ type Manifest struct {
 	Cert       Certificate
 }
 
+func (m Manifest) UsesLocalFakeCPI() bool {
+	return m.CPI == LocalFakeCPI
+}
+
 type Certificate struct {
 	CA string
 }
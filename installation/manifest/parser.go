@@ -35,6 +35,7 @@ type manifest struct {
 }
 
 type installation struct {
+	CPI        string `yaml:"cpi"`
 	Template   template
 	Templates  []template
 	Properties map[interface{}]interface{}
@@ -114,6 +115,7 @@ func (p *parser) Parse(path string, vars boshtpl.Variables, op patch.Op, release
 
 	installationManifest := Manifest{
 		Name: comboManifest.Name,
+		CPI:  comboManifest.CloudProvider.CPI,
 		Mbus: comboManifest.CloudProvider.Mbus,
 		Cert: comboManifest.CloudProvider.Cert,
 	}
@@ -129,7 +131,7 @@ func (p *parser) Parse(path string, vars boshtpl.Variables, op patch.Op, release
 		}
 
 		installationManifest.Templates = templateList
-	} else {
+	} else if comboManifest.CloudProvider.Template != (template{}) || comboManifest.CloudProvider.CPI == "" {
 		templateList = append(templateList, ReleaseJobRef{
 			Name:    comboManifest.CloudProvider.Template.Name,
 			Release: comboManifest.CloudProvider.Template.Release,
@@ -27,8 +27,13 @@ func NewValidator(logger boshlog.Logger) Validator {
 func (v *validator) Validate(manifest Manifest, releaseSetManifest birelsetmanifest.Manifest) error {
 	var errs []error
 
+	if manifest.CPI != "" && !manifest.UsesLocalFakeCPI() {
+		return fmt.Errorf("cloud_provider.cpi must be '%s' when provided, got '%s'", LocalFakeCPI, manifest.CPI)
+	}
+
 	// When there is nothing in templates, return an error. It should have a CPI release.
-	if len(manifest.Templates) == 0 {
+	// The built-in CPI does not need one.
+	if len(manifest.Templates) == 0 && !manifest.UsesLocalFakeCPI() {
 		return fmt.Errorf("either cloud_provider.templates or cloud_provider.template must be provided and must contain at least one release")
 	}
 
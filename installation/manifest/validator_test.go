@@ -56,6 +56,22 @@ var _ = Describe("Validator", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("does not require templates for the local fake cpi", func() {
+			manifest := Manifest{Name: "fake-installation-name", CPI: LocalFakeCPI}
+
+			err := validator.Validate(manifest, releaseSetManifest)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("errors when the cpi is not a built-in cpi", func() {
+			manifest := validManifest
+			manifest.CPI = "other"
+
+			err := validator.Validate(manifest, releaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("cloud_provider.cpi must be 'fake' when provided, got 'other'"))
+		})
+
 		It("errors when validating an empty manifest", func() {
 			manifest := Manifest{}
 
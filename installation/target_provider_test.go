@@ -35,6 +35,7 @@ var _ = Describe("TargetProvider", func() {
 			fakeUUIDGenerator,
 			logger,
 			configPath,
+			nil,
 		)
 		targetProvider = NewTargetProvider(deploymentStateService, fakeUUIDGenerator, installationsRootPath, "")
 	})
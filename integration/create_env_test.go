@@ -399,7 +399,7 @@ cloud_provider:
 			ui := biui.NewWriterUI(stdOut, stdErr, logger)
 			doGet := func(deploymentManifestPath string, statePath string, deploymentVars boshtpl.Variables, deploymentOp patch.Op) cmd.DeploymentPreparer {
 				// todo: figure this out?
-				deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, statePath))
+				deploymentStateService = biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, statePath), nil)
 				vmRepo = biconfig.NewVMRepo(deploymentStateService)
 				diskRepo = biconfig.NewDiskRepo(deploymentStateService, fakeRepoUUIDGenerator)
 				stemcellRepo = biconfig.NewStemcellRepo(deploymentStateService, fakeRepoUUIDGenerator)
@@ -458,6 +458,7 @@ cloud_provider:
 					logger,
 					"deployCmd",
 					deploymentStateService,
+					false,
 					legacyDeploymentStateMigrator,
 					releaseManager,
 					deploymentRecord,
@@ -477,6 +478,11 @@ cloud_provider:
 					deploymentManifestParser,
 					tempRootConfigurator,
 					targetProvider,
+					bidepl.NewPlanner(
+						bidepl.NewManagerFactory(vmManagerFactory, instanceManagerFactory, diskManagerFactory, stemcellManagerFactory, deploymentFactory),
+						stemcellManagerFactory,
+						diskManagerFactory,
+					),
 				)
 			}
 
@@ -770,7 +776,7 @@ cloud_provider:
 
 			logger = boshlog.NewLogger(boshlog.LevelNone)
 			fakeUUIDGenerator = fakeuuid.NewFakeGenerator()
-			setupDeploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""))
+			setupDeploymentStateService := biconfig.NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath, ""), nil)
 			deploymentState, err := setupDeploymentStateService.Load()
 			Expect(err).ToNot(HaveOccurred())
 			directorID = deploymentState.DirectorID
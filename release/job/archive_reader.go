@@ -62,14 +62,27 @@ func (r ArchiveReaderImpl) Read(ref boshman.JobRef, path string) (*Job, error) {
 
 	job.Templates = manifest.Templates
 	job.PackageNames = manifest.Packages
+	job.Provides = manifest.Provides
+	job.Consumes = manifest.Consumes
 
+	properties, err := buildPropertyDefinitions(job.Name(), manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Properties = properties
+
+	return job, nil
+}
+
+func buildPropertyDefinitions(jobName string, manifest boshjobman.Manifest) (map[string]PropertyDefinition, error) {
 	properties := make(map[string]PropertyDefinition, len(manifest.Properties))
 
 	for propertyName, rawPropertyDef := range manifest.Properties {
 		defaultValue, err := biproperty.Build(rawPropertyDef.Default)
 		if err != nil {
 			errMsg := "Parsing job '%s' property '%s' default: %#v"
-			return nil, bosherr.WrapErrorf(err, errMsg, job.Name(), propertyName, rawPropertyDef.Default)
+			return nil, bosherr.WrapErrorf(err, errMsg, jobName, propertyName, rawPropertyDef.Default)
 		}
 
 		properties[propertyName] = PropertyDefinition{
@@ -78,7 +91,5 @@ func (r ArchiveReaderImpl) Read(ref boshman.JobRef, path string) (*Job, error) {
 		}
 	}
 
-	job.Properties = properties
-
-	return job, nil
+	return properties, nil
 }
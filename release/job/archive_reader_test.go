@@ -12,6 +12,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-cli/v7/release/job"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
 	boshman "github.com/cloudfoundry/bosh-cli/v7/release/manifest"
 	. "github.com/cloudfoundry/bosh-cli/v7/release/resource"
 )
@@ -50,6 +51,10 @@ properties:
   prop:
     description: prop-desc
     default: prop-default
+provides:
+- {name: db, type: postgres, properties: [prop]}
+consumes:
+- {name: backup, type: s3}
 `)
 			Expect(err).ToNot(HaveOccurred())
 
@@ -70,6 +75,9 @@ properties:
 				},
 			}))
 
+			Expect(job.Provides).To(Equal([]boshjobman.LinkDefinition{{Name: "db", Type: "postgres", Properties: []string{"prop"}}}))
+			Expect(job.Consumes).To(Equal([]boshjobman.LinkDefinition{{Name: "backup", Type: "s3"}}))
+
 			Expect(job.ExtractedPath()).To(Equal("/extracted/job"))
 
 			Expect(compressor.DecompressFileToDirTarballPaths).To(Equal([]string{"archive-path"}))
@@ -42,6 +42,30 @@ func (r DirReaderImpl) Read(path string) (*Job, error) {
 	return job, nil
 }
 
+// ReadSource reads job directory without building its archive.
+// Returned job refers to the directory as its extracted path
+// so that its templates can be rendered in place; cleaning up keeps the directory.
+func (r DirReaderImpl) ReadSource(path string) (*Job, error) {
+	manifest, _, err := r.collectFiles(path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Collecting job files")
+	}
+
+	job := NewJob(resource.NewResource(manifest.Name, "", nil))
+	job.extractedPath = path
+	job.Templates = manifest.Templates
+	job.PackageNames = manifest.Packages
+	job.Provides = manifest.Provides
+	job.Consumes = manifest.Consumes
+
+	job.Properties, err = buildPropertyDefinitions(manifest.Name, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
 func (r DirReaderImpl) collectFiles(path string) (boshjobman.Manifest, []resource.File, error) {
 	var files []resource.File
 
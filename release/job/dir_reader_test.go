@@ -139,4 +139,45 @@ properties:
 			Expect(err.Error()).To(ContainSubstring("Job directory 'my-job-name' does not match job name 'other-job-name' in spec"))
 		})
 	})
+
+	Describe("ReadSource", func() {
+		It("returns a job referring to job directory as its extracted path", func() {
+			err := fs.WriteFileString(filepath.Join("/", "my-job", "spec"), `---
+name: my-job
+templates: {src: dst}
+packages: [pkg]
+properties:
+  prop:
+    description: prop-desc
+    default: prop-default
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString(filepath.Join("/", "my-job", "templates", "src"), "tpl-content")
+			Expect(err).ToNot(HaveOccurred())
+
+			job, err := reader.ReadSource(filepath.Join("/", "my-job"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(job.Name()).To(Equal("my-job"))
+			Expect(job.ExtractedPath()).To(Equal(filepath.Join("/", "my-job")))
+			Expect(job.Templates).To(Equal(map[string]string{"src": "dst"}))
+			Expect(job.PackageNames).To(Equal([]string{"pkg"}))
+			Expect(job.Properties).To(Equal(map[string]PropertyDefinition{
+				"prop": {Description: "prop-desc", Default: "prop-default"},
+			}))
+
+			Expect(job.CleanUp()).To(Succeed())
+			Expect(fs.FileExists(filepath.Join("/", "my-job", "spec"))).To(BeTrue())
+		})
+
+		It("returns error if spec file is not valid", func() {
+			err := fs.WriteFileString(filepath.Join("/", "my-job", "spec"), `-`)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = reader.ReadSource(filepath.Join("/", "my-job"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Collecting job files"))
+		})
+	})
 })
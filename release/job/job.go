@@ -7,6 +7,7 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 
 	"github.com/cloudfoundry/bosh-cli/v7/crypto"
+	boshjobman "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
 	boshpkg "github.com/cloudfoundry/bosh-cli/v7/release/pkg"
 	"github.com/cloudfoundry/bosh-cli/v7/release/resource"
 )
@@ -24,6 +25,8 @@ type Job struct {
 	PackageNames []string
 	Packages     []boshpkg.Compilable
 	Properties   map[string]PropertyDefinition
+	Provides     []boshjobman.LinkDefinition
+	Consumes     []boshjobman.LinkDefinition
 
 	extractedPath string
 	fs            boshsys.FileSystem
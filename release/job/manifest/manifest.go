@@ -11,6 +11,9 @@ type Manifest struct {
 	Templates  map[string]string             `yaml:"templates"`
 	Packages   []string                      `yaml:"packages"`
 	Properties map[string]PropertyDefinition `yaml:"properties"`
+
+	Provides []LinkDefinition `yaml:"provides"`
+	Consumes []LinkDefinition `yaml:"consumes"`
 }
 
 type PropertyDefinition struct {
@@ -18,6 +21,15 @@ type PropertyDefinition struct {
 	Default     interface{} `yaml:"default"`
 }
 
+// LinkDefinition describes a link provided or consumed by a job.
+// Properties are only specified for provided links.
+type LinkDefinition struct {
+	Name       string   `yaml:"name"`
+	Type       string   `yaml:"type"`
+	Optional   bool     `yaml:"optional"`
+	Properties []string `yaml:"properties"`
+}
+
 func NewManifestFromPath(path string, fs boshsys.FileSystem) (Manifest, error) {
 	var manifest Manifest
 
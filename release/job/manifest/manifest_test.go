@@ -64,6 +64,34 @@ properties:
 		}))
 	})
 
+	It("parses provided and consumed links", func() {
+		contents := `---
+name: name
+
+provides:
+- name: db
+  type: postgres
+  properties: [port, db.name]
+
+consumes:
+- name: backup
+  type: s3
+  optional: true
+`
+
+		err := fs.WriteFileString("/path", contents)
+		Expect(err).ToNot(HaveOccurred())
+
+		manifest, err := NewManifestFromPath("/path", fs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manifest.Provides).To(Equal([]LinkDefinition{
+			{Name: "db", Type: "postgres", Properties: []string{"port", "db.name"}},
+		}))
+		Expect(manifest.Consumes).To(Equal([]LinkDefinition{
+			{Name: "backup", Type: "s3", Optional: true},
+		}))
+	})
+
 	It("returns error if manifest is not valid yaml", func() {
 		err := fs.WriteFileString("/path", "-")
 		Expect(err).ToNot(HaveOccurred())
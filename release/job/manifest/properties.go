@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type PropertyProblem struct {
+	// Name is the dotted property name, e.g. 'nats.user'
+	Name    string
+	Message string
+
+	// Warning is set for problems that do not necessarily break rendering
+	Warning bool
+}
+
+// CheckProperties compares configured job properties against declared property definitions.
+// Unknown properties are only reported if checkUnknown is set since properties
+// shared by instance groups or deployments may be meant for other jobs.
+// Missing properties without defaults are only warnings since templates
+// may guard optional properties with if_p.
+func (m Manifest) CheckProperties(props map[interface{}]interface{}, checkUnknown bool) []PropertyProblem {
+	var problems []PropertyProblem
+
+	if checkUnknown {
+		problems = append(problems, m.unknownProperties("", props)...)
+	}
+
+	for name, def := range m.Properties {
+		val, found := lookupProperty(props, name)
+
+		if !found || val == nil {
+			if def.Default == nil {
+				problems = append(problems, PropertyProblem{Name: name, Message: "Missing property without default", Warning: true})
+			}
+			continue
+		}
+
+		if def.Default == nil || isVariablePlaceholder(val) {
+			continue
+		}
+
+		expectedKind, actualKind := propertyKind(def.Default), propertyKind(val)
+
+		if !compatibleKinds(expectedKind, actualKind) {
+			problems = append(problems, PropertyProblem{
+				Name:    name,
+				Message: fmt.Sprintf("Expected %s like the default but was %s", expectedKind, actualKind),
+			})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Name < problems[j].Name })
+
+	return problems
+}
+
+func (m Manifest) unknownProperties(prefix string, props map[interface{}]interface{}) []PropertyProblem {
+	var problems []PropertyProblem
+
+	for key, val := range props {
+		name := fmt.Sprintf("%s%v", prefix, key)
+
+		if _, declared := m.Properties[name]; declared {
+			continue
+		}
+
+		if !m.declaresPropertiesUnder(name) {
+			problems = append(problems, PropertyProblem{Name: name, Message: "Unknown property"})
+			continue
+		}
+
+		nestedProps, ok := val.(map[interface{}]interface{})
+		if !ok {
+			if val != nil {
+				problems = append(problems, PropertyProblem{
+					Name:    name,
+					Message: fmt.Sprintf("Expected hash since properties are declared under it but was %s", propertyKind(val)),
+				})
+			}
+			continue
+		}
+
+		problems = append(problems, m.unknownProperties(name+".", nestedProps)...)
+	}
+
+	return problems
+}
+
+func (m Manifest) declaresPropertiesUnder(name string) bool {
+	for declaredName := range m.Properties {
+		if strings.HasPrefix(declaredName, name+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookupProperty(props map[interface{}]interface{}, name string) (interface{}, bool) {
+	var val interface{} = props
+
+	for _, key := range strings.Split(name, ".") {
+		nestedProps, ok := val.(map[interface{}]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		val, ok = nestedProps[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+func isVariablePlaceholder(val interface{}) bool {
+	str, ok := val.(string)
+	return ok && strings.HasPrefix(str, "((") && strings.HasSuffix(str, "))")
+}
+
+func propertyKind(val interface{}) string {
+	switch val.(type) {
+	case map[interface{}]interface{}, map[string]interface{}:
+		return "hash"
+	case []interface{}:
+		return "array"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
+
+// compatibleKinds treats numbers and strings as interchangeable since
+// templates commonly render both the same way (e.g. ports).
+func compatibleKinds(expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+
+	scalars := map[string]bool{"number": true, "string": true}
+
+	return scalars[expected] && scalars[actual]
+}
@@ -0,0 +1,111 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/release/job/manifest"
+)
+
+var _ = Describe("Manifest", func() {
+	Describe("CheckProperties", func() {
+		var (
+			manifest Manifest
+		)
+
+		BeforeEach(func() {
+			manifest = Manifest{
+				Name: "name",
+				Properties: map[string]PropertyDefinition{
+					"port":          {Default: 8080},
+					"nats.user":     {},
+					"nats.password": {},
+					"tls.enabled":   {Default: false},
+					"hosts":         {Default: []interface{}{}},
+					"env":           {Default: map[interface{}]interface{}{}},
+				},
+			}
+		})
+
+		props := func(str string) map[interface{}]interface{} {
+			var props map[interface{}]interface{}
+			Expect(yaml.Unmarshal([]byte(str), &props)).To(Succeed())
+			return props
+		}
+
+		It("returns no problems if properties match definitions", func() {
+			problems := manifest.CheckProperties(props(`
+port: 80
+nats: {user: user, password: pass}
+tls: {enabled: true}
+hosts: [a, b]
+env: {key: val}
+`), true)
+			Expect(problems).To(BeEmpty())
+		})
+
+		It("reports unknown properties including nested ones", func() {
+			problems := manifest.CheckProperties(props(`
+prot: 80
+nats: {user: user, password: pass, usr: typo}
+env: {anything: goes}
+`), true)
+			Expect(problems).To(Equal([]PropertyProblem{
+				{Name: "nats.usr", Message: "Unknown property"},
+				{Name: "prot", Message: "Unknown property"},
+			}))
+		})
+
+		It("does not report unknown properties if not asked to", func() {
+			problems := manifest.CheckProperties(props(`
+nats: {user: user, password: pass}
+other_job: {prop: val}
+`), false)
+			Expect(problems).To(BeEmpty())
+		})
+
+		It("reports missing properties without defaults", func() {
+			problems := manifest.CheckProperties(props(`
+nats: {user: user, password: ~}
+`), true)
+			Expect(problems).To(Equal([]PropertyProblem{
+				{Name: "nats.password", Message: "Missing property without default", Warning: true},
+			}))
+		})
+
+		It("reports properties that do not match the type of their default", func() {
+			problems := manifest.CheckProperties(props(`
+port: "80"
+nats: {user: user, password: pass}
+tls: {enabled: "yes"}
+hosts: a
+env: [a]
+`), true)
+			Expect(problems).To(Equal([]PropertyProblem{
+				{Name: "env", Message: "Expected hash like the default but was array"},
+				{Name: "hosts", Message: "Expected array like the default but was string"},
+				{Name: "tls.enabled", Message: "Expected boolean like the default but was string"},
+			}))
+		})
+
+		It("reports values in place of hashes that hold declared properties", func() {
+			problems := manifest.CheckProperties(props(`
+nats: user
+`), true)
+			Expect(problems).To(Equal([]PropertyProblem{
+				{Name: "nats", Message: "Expected hash since properties are declared under it but was string"},
+				{Name: "nats.password", Message: "Missing property without default", Warning: true},
+				{Name: "nats.user", Message: "Missing property without default", Warning: true},
+			}))
+		})
+
+		It("skips type checks for unresolved variables", func() {
+			problems := manifest.CheckProperties(props(`
+nats: {user: user, password: pass}
+tls: {enabled: ((tls_enabled))}
+`), true)
+			Expect(problems).To(BeEmpty())
+		})
+	})
+})
@@ -4,11 +4,18 @@ import (
 	gobytes "bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
 	"os"
 
+	"cloud.google.com/go/storage"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 
 	gcsclient "github.com/cloudfoundry/bosh-gcscli/client"
 	gcsconfig "github.com/cloudfoundry/bosh-gcscli/config"
@@ -79,8 +86,92 @@ func (b GCSBlobstore) CleanUp(path string) error {
 	return b.fs.RemoveAll(path)
 }
 
+func (b GCSBlobstore) Put(path string, blobID string) error {
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	file, err := b.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening source file")
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	return client.Put(file, blobID)
+}
+
+// PutIfAbsent uploads the file only if there is no object with blobID yet.
+// The upload carries a DoesNotExist precondition (ifGenerationMatch=0) so
+// that concurrent writers cannot both succeed.
+func (b GCSBlobstore) PutIfAbsent(path string, blobID string) (bool, error) {
+	conf, err := b.config()
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gcs, err := b.authenticatedStorageClient(ctx, conf)
+	if err != nil {
+		return false, err
+	}
+
+	defer gcs.Close() //nolint:errcheck
+
+	file, err := b.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Opening source file")
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	handle := gcs.Bucket(conf.BucketName).Object(blobID)
+	if conf.EncryptionKey != nil {
+		handle = handle.Key(conf.EncryptionKey)
+	}
+
+	writer := handle.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	writer.StorageClass = conf.StorageClass
+
+	_, err = io.Copy(writer, file)
+	if err != nil {
+		// cancelling the context aborts the upload
+		cancel()
+		return false, bosherr.WrapErrorf(err, "Uploading '%s'", blobID)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return false, nil
+		}
+
+		return false, bosherr.WrapErrorf(err, "Uploading '%s'", blobID)
+	}
+
+	return true, nil
+}
+
+func (b GCSBlobstore) Exists(blobID string) (bool, error) {
+	client, err := b.client()
+	if err != nil {
+		return false, err
+	}
+
+	return client.Exists(blobID)
+}
+
 func (b GCSBlobstore) Delete(blobID string) error {
-	panic("Not implemented")
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	return client.Delete(blobID)
 }
 
 func (b GCSBlobstore) Validate() error {
@@ -89,14 +180,9 @@ func (b GCSBlobstore) Validate() error {
 }
 
 func (b GCSBlobstore) client() (*gcsclient.GCSBlobstore, error) {
-	bytes, err := json.Marshal(b.options)
-	if err != nil {
-		return nil, bosherr.WrapError(err, "Marshaling config")
-	}
-
-	conf, err := gcsconfig.NewFromReader(gobytes.NewBuffer(bytes))
+	conf, err := b.config()
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Reading config")
+		return nil, err
 	}
 
 	client, err := gcsclient.New(context.Background(), &conf)
@@ -106,3 +192,42 @@ func (b GCSBlobstore) client() (*gcsclient.GCSBlobstore, error) {
 
 	return client, nil
 }
+
+func (b GCSBlobstore) config() (gcsconfig.GCSCli, error) {
+	bytes, err := json.Marshal(b.options)
+	if err != nil {
+		return gcsconfig.GCSCli{}, bosherr.WrapError(err, "Marshaling config")
+	}
+
+	conf, err := gcsconfig.NewFromReader(gobytes.NewBuffer(bytes))
+	if err != nil {
+		return gcsconfig.GCSCli{}, bosherr.WrapError(err, "Reading config")
+	}
+
+	return conf, nil
+}
+
+// authenticatedStorageClient builds a storage client with the same
+// credentials bosh-gcscli uses for writes
+func (b GCSBlobstore) authenticatedStorageClient(ctx context.Context, conf gcsconfig.GCSCli) (*storage.Client, error) {
+	switch conf.CredentialsSource {
+	case gcsconfig.DefaultCredentialsSource:
+		tokenSource, err := google.DefaultTokenSource(ctx, storage.ScopeFullControl)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Finding default credentials")
+		}
+
+		return storage.NewClient(ctx, option.WithTokenSource(tokenSource))
+
+	case gcsconfig.ServiceAccountFileCredentialsSource:
+		token, err := google.JWTConfigFromJSON([]byte(conf.ServiceAccountFile), storage.ScopeFullControl)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading service account key")
+		}
+
+		return storage.NewClient(ctx, option.WithTokenSource(token.TokenSource(ctx)))
+
+	default:
+		return nil, bosherr.Error("Cannot write to GCS blobstore without credentials")
+	}
+}
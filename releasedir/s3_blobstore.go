@@ -3,8 +3,14 @@ package releasedir
 import (
 	gobytes "bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
 	s3client "github.com/cloudfoundry/bosh-s3cli/client"
 	s3config "github.com/cloudfoundry/bosh-s3cli/config"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -81,8 +87,100 @@ func (b S3Blobstore) CleanUp(path string) error {
 	return b.fs.RemoveAll(path)
 }
 
+func (b S3Blobstore) Put(path string, blobID string) error {
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	file, err := b.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening source file")
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	return client.Put(file, blobID)
+}
+
+// PutIfAbsent uploads the file only if there is no object with blobID yet.
+// It sends a conditional PutObject (If-None-Match: *) so that concurrent
+// writers cannot both succeed.
+func (b S3Blobstore) PutIfAbsent(path string, blobID string) (bool, error) {
+	conf, err := b.config()
+	if err != nil {
+		return false, err
+	}
+
+	if conf.CredentialsSource == s3config.NoneCredentialsSource {
+		return false, bosherr.Error("Cannot write to S3 blobstore without credentials")
+	}
+
+	s3ClientSDK, err := s3client.NewAwsS3Client(&conf)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Building client SDK")
+	}
+
+	file, err := b.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Opening source file")
+	}
+
+	defer file.Close() //nolint:errcheck
+
+	key := blobID
+	if len(conf.FolderName) != 0 {
+		key = fmt.Sprintf("%s/%s", conf.FolderName, blobID)
+	}
+
+	input := &s3.PutObjectInput{
+		Body:   file,
+		Bucket: aws.String(conf.BucketName),
+		Key:    aws.String(key),
+	}
+	if conf.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(conf.ServerSideEncryption)
+	}
+	if conf.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(conf.SSEKMSKeyID)
+	}
+
+	req, _ := s3ClientSDK.PutObjectRequest(input)
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+
+	err = req.Send()
+	if err != nil {
+		var reqErr awserr.RequestFailure
+		if errors.As(err, &reqErr) {
+			switch reqErr.StatusCode() {
+			case http.StatusPreconditionFailed, http.StatusConflict:
+				// 409 is returned when a concurrent conditional write wins the race
+				return false, nil
+			}
+		}
+
+		return false, bosherr.WrapErrorf(err, "Uploading '%s'", blobID)
+	}
+
+	return true, nil
+}
+
+func (b S3Blobstore) Exists(blobID string) (bool, error) {
+	client, err := b.client()
+	if err != nil {
+		return false, err
+	}
+
+	return client.Exists(blobID)
+}
+
 func (b S3Blobstore) Delete(blobID string) error {
-	panic("Not implemented")
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+
+	return client.Delete(blobID)
 }
 
 func (b S3Blobstore) Validate() error {
@@ -91,14 +189,9 @@ func (b S3Blobstore) Validate() error {
 }
 
 func (b S3Blobstore) client() (s3client.S3CompatibleClient, error) {
-	bytes, err := json.Marshal(b.options)
-	if err != nil {
-		return nil, bosherr.WrapErrorf(err, "Marshaling config")
-	}
-
-	conf, err := s3config.NewFromReader(gobytes.NewBuffer(bytes))
+	conf, err := b.config()
 	if err != nil {
-		return nil, bosherr.WrapErrorf(err, "Reading config")
+		return nil, err
 	}
 
 	s3ClientSDK, err := s3client.NewAwsS3Client(&conf)
@@ -110,3 +203,17 @@ func (b S3Blobstore) client() (s3client.S3CompatibleClient, error) {
 
 	return client, nil
 }
+
+func (b S3Blobstore) config() (s3config.S3Cli, error) {
+	bytes, err := json.Marshal(b.options)
+	if err != nil {
+		return s3config.S3Cli{}, bosherr.WrapErrorf(err, "Marshaling config")
+	}
+
+	conf, err := s3config.NewFromReader(gobytes.NewBuffer(bytes))
+	if err != nil {
+		return s3config.S3Cli{}, bosherr.WrapErrorf(err, "Reading config")
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,96 @@
+package releasedir_test
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/releasedir"
+)
+
+var _ = Describe("S3Blobstore", func() {
+	var (
+		server    *ghttp.Server
+		path      string
+		blobstore S3Blobstore
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		serverURL, err := url.Parse(server.URL())
+		Expect(err).ToNot(HaveOccurred())
+
+		port, err := strconv.Atoi(serverURL.Port())
+		Expect(err).ToNot(HaveOccurred())
+
+		fs := boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+
+		file, err := fs.TempFile("s3-blobstore-test")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+
+		path = file.Name()
+		Expect(fs.WriteFileString(path, "fake-lock")).To(Succeed())
+		DeferCleanup(func() { fs.RemoveAll(path) }) //nolint:errcheck
+
+		blobstore = NewS3Blobstore(fs, &fakeuuid.FakeGenerator{}, map[string]interface{}{
+			"bucket_name":       "fake-bucket",
+			"folder_name":       "fake-folder",
+			"access_key_id":     "fake-key",
+			"secret_access_key": "fake-secret",
+			"host":              serverURL.Hostname(),
+			"port":              port,
+			"region":            "fake-region",
+			"use_ssl":           false,
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("PutIfAbsent", func() {
+		It("uploads the object with a conditional write", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/fake-bucket/fake-folder/fake-blob-id"),
+				ghttp.VerifyHeaderKV("If-None-Match", "*"),
+				ghttp.VerifyBody([]byte("fake-lock")),
+				ghttp.RespondWith(http.StatusOK, ""),
+			))
+
+			created, err := blobstore.PutIfAbsent(path, "fake-blob-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(BeTrue())
+		})
+
+		It("reports that the object was not created when it already exists", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/fake-bucket/fake-folder/fake-blob-id"),
+				ghttp.RespondWith(http.StatusPreconditionFailed, "<Error><Code>PreconditionFailed</Code></Error>"),
+			))
+
+			created, err := blobstore.PutIfAbsent(path, "fake-blob-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created).To(BeFalse())
+		})
+
+		It("returns other errors", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("PUT", "/fake-bucket/fake-folder/fake-blob-id"),
+				ghttp.RespondWith(http.StatusForbidden, "<Error><Code>AccessDenied</Code></Error>"),
+			))
+
+			_, err := blobstore.PutIfAbsent(path, "fake-blob-id")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Uploading 'fake-blob-id'"))
+		})
+	})
+})
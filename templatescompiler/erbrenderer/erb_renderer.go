@@ -1,8 +1,13 @@
 package erbrenderer
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"path/filepath"
+	"strings"
+	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -11,6 +16,66 @@ import (
 
 type ERBRenderer interface {
 	Render(srcPath, dstPath string, context TemplateEvaluationContext) error
+
+	// RenderBatch renders templates of all given jobs in a single ruby process
+	RenderBatch(jobs []BatchJob) error
+}
+
+// BatchJob groups templates of a job that share an evaluation context
+type BatchJob struct {
+	Name      string
+	Context   TemplateEvaluationContext
+	Templates []BatchTemplate
+}
+
+type BatchTemplate struct {
+	Name    string
+	SrcPath string
+	DstPath string
+}
+
+type TemplateError struct {
+	Job      string
+	Template string
+	Line     string
+	Message  string
+}
+
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("Error filling in template '%s' for job '%s' (line %s: %s)", e.Template, e.Job, e.Line, e.Message)
+}
+
+type BatchError struct {
+	Errors []TemplateError
+}
+
+func (e BatchError) Error() string {
+	var msgs []string
+
+	for _, err := range e.Errors {
+		msgs = append(msgs, "  - "+err.Error())
+	}
+
+	return fmt.Sprintf("Rendering %d template(s) failed:\n%s", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+type batchRequest struct {
+	Job       string                    `json:"job"`
+	Context   TemplateEvaluationContext `json:"context"`
+	Templates []batchRequestTemplate    `json:"templates"`
+}
+
+type batchRequestTemplate struct {
+	Name string `json:"name"`
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+}
+
+type batchResult struct {
+	Job      string `json:"job"`
+	Template string `json:"template"`
+	Line     string `json:"line"`
+	Error    string `json:"error"`
 }
 
 type erbRenderer struct {
@@ -75,6 +140,103 @@ func (r erbRenderer) Render(srcPath, dstPath string, context TemplateEvaluationC
 	return nil
 }
 
+func (r erbRenderer) RenderBatch(jobs []BatchJob) error {
+	started := time.Now()
+
+	tmpDir, err := r.fs.TempDir("erb-renderer")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating temporary directory")
+	}
+	defer func() {
+		if err = r.fs.RemoveAll(tmpDir); err != nil {
+			r.logger.Warn(r.logTag, "Failed to remove temp dir: %s", err.Error())
+		}
+	}()
+
+	rendererScriptPath := filepath.Join(tmpDir, "erb-render.rb")
+	err = r.writeRendererScript(rendererScriptPath)
+	if err != nil {
+		return err
+	}
+
+	var stdin bytes.Buffer
+	var expectedResults int
+
+	encoder := json.NewEncoder(&stdin)
+
+	for _, job := range jobs {
+		request := batchRequest{Job: job.Name, Context: job.Context}
+
+		for _, template := range job.Templates {
+			request.Templates = append(request.Templates, batchRequestTemplate{
+				Name: template.Name,
+				Src:  template.SrcPath,
+				Dst:  template.DstPath,
+			})
+		}
+
+		err = encoder.Encode(request)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Marshalling context for job '%s'", job.Name)
+		}
+
+		expectedResults += len(job.Templates)
+	}
+
+	command := boshsys.Command{
+		Name:  "ruby",
+		Args:  []string{rendererScriptPath, "--batch"},
+		Stdin: &stdin,
+	}
+
+	stdout, _, _, err := r.runner.RunComplexCommand(command)
+	if err != nil {
+		return bosherr.WrapError(err, "Running ruby to render templates")
+	}
+
+	var results []batchResult
+
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+
+	for {
+		var result batchResult
+
+		err = decoder.Decode(&result)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return bosherr.WrapError(err, "Unmarshalling rendering results")
+		}
+
+		results = append(results, result)
+	}
+
+	if len(results) != expectedResults {
+		return bosherr.Errorf("Expected results for %d template(s) but renderer reported %d", expectedResults, len(results))
+	}
+
+	var batchErr BatchError
+
+	for _, result := range results {
+		if len(result.Error) > 0 {
+			batchErr.Errors = append(batchErr.Errors, TemplateError{
+				Job:      result.Job,
+				Template: result.Template,
+				Line:     result.Line,
+				Message:  result.Error,
+			})
+		}
+	}
+
+	r.logger.Debug(r.logTag, "Rendered %d template(s) of %d job(s) in %s", expectedResults, len(jobs), time.Since(started))
+
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+
+	return nil
+}
+
 func (r erbRenderer) writeRendererScript(scriptPath string) error {
 	err := r.fs.WriteFileString(scriptPath, r.rendererScript)
 	if err != nil {
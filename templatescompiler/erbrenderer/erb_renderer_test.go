@@ -1,6 +1,7 @@
 package erbrenderer_test
 
 import (
+	"encoding/json"
 	"errors"
 	"path/filepath"
 
@@ -87,4 +88,123 @@ var _ = Describe("ErbRenderer", func() {
 			Expect(err.Error()).To(ContainSubstring("fake-cmd-error"))
 		})
 	})
+
+	Describe("RenderBatch", func() {
+		var (
+			jobs      []BatchJob
+			batchCmd  string
+			stdinJSON []map[string]interface{}
+		)
+
+		BeforeEach(func() {
+			jobs = []BatchJob{
+				{
+					Name:    "web",
+					Context: context,
+					Templates: []BatchTemplate{
+						{Name: "config.yml.erb", SrcPath: "web/templates/config.yml.erb", DstPath: "out/web/config.yml"},
+						{Name: "monit", SrcPath: "web/monit", DstPath: "out/web/monit"},
+					},
+				},
+				{
+					Name:    "db",
+					Context: context,
+					Templates: []BatchTemplate{
+						{Name: "monit", SrcPath: "db/monit", DstPath: "out/db/monit"},
+					},
+				},
+			}
+
+			batchCmd = "ruby " + filepath.Join("fake-temp-dir", "erb-render.rb") + " --batch"
+			stdinJSON = nil
+
+			runner.SetCmdCallback(batchCmd, func() {
+				decoder := json.NewDecoder(runner.RunComplexCommands[0].Stdin)
+
+				for decoder.More() {
+					var request map[string]interface{}
+					Expect(decoder.Decode(&request)).To(Succeed())
+					stdinJSON = append(stdinJSON, request)
+				}
+			})
+		})
+
+		It("renders templates of all jobs in a single ruby process", func() {
+			runner.AddCmdResult(batchCmd, fakesys.FakeCmdResult{Stdout: `{"job":"web","template":"config.yml.erb"}
+{"job":"web","template":"monit"}
+{"job":"db","template":"monit"}
+`})
+
+			err := erbRenderer.RenderBatch(jobs)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunComplexCommands).To(HaveLen(1))
+			Expect(runner.RunComplexCommands[0].Args).To(Equal([]string{filepath.Join("fake-temp-dir", "erb-render.rb"), "--batch"}))
+
+			Expect(stdinJSON).To(Equal([]map[string]interface{}{
+				{
+					"job":     "web",
+					"context": map[string]interface{}{},
+					"templates": []interface{}{
+						map[string]interface{}{"name": "config.yml.erb", "src": "web/templates/config.yml.erb", "dst": "out/web/config.yml"},
+						map[string]interface{}{"name": "monit", "src": "web/monit", "dst": "out/web/monit"},
+					},
+				},
+				{
+					"job":     "db",
+					"context": map[string]interface{}{},
+					"templates": []interface{}{
+						map[string]interface{}{"name": "monit", "src": "db/monit", "dst": "out/db/monit"},
+					},
+				},
+			}))
+
+			Expect(fs.FileExists("fake-temp-dir")).To(BeFalse())
+		})
+
+		It("returns error naming job, template and line for every failed template", func() {
+			runner.AddCmdResult(batchCmd, fakesys.FakeCmdResult{Stdout: `{"job":"web","template":"config.yml.erb","line":"3","error":"#<UnknownProperty: Can't find property '[\"port\"]'>"}
+{"job":"web","template":"monit"}
+{"job":"db","template":"monit","line":"1","error":"#<NoMethodError: undefined method>"}
+`})
+
+			err := erbRenderer.RenderBatch(jobs)
+			Expect(err).To(HaveOccurred())
+
+			batchErr, ok := err.(BatchError)
+			Expect(ok).To(BeTrue())
+			Expect(batchErr.Errors).To(Equal([]TemplateError{
+				{Job: "web", Template: "config.yml.erb", Line: "3", Message: `#<UnknownProperty: Can't find property '["port"]'>`},
+				{Job: "db", Template: "monit", Line: "1", Message: "#<NoMethodError: undefined method>"},
+			}))
+
+			Expect(err.Error()).To(Equal(`Rendering 2 template(s) failed:
+  - Error filling in template 'config.yml.erb' for job 'web' (line 3: #<UnknownProperty: Can't find property '["port"]'>)
+  - Error filling in template 'monit' for job 'db' (line 1: #<NoMethodError: undefined method>)`))
+		})
+
+		It("returns error if renderer does not report results for all templates", func() {
+			runner.AddCmdResult(batchCmd, fakesys.FakeCmdResult{Stdout: `{"job":"web","template":"config.yml.erb"}`})
+
+			err := erbRenderer.RenderBatch(jobs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected results for 3 template(s) but renderer reported 1"))
+		})
+
+		It("returns error if renderer output cannot be parsed", func() {
+			runner.AddCmdResult(batchCmd, fakesys.FakeCmdResult{Stdout: "not-json"})
+
+			err := erbRenderer.RenderBatch(jobs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling rendering results"))
+		})
+
+		It("returns error if ruby command fails", func() {
+			runner.AddCmdResult(batchCmd, fakesys.FakeCmdResult{Error: errors.New("fake-cmd-error")})
+
+			err := erbRenderer.RenderBatch(jobs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Running ruby to render templates: fake-cmd-error"))
+		})
+	})
 })
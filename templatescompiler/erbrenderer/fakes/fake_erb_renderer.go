@@ -10,8 +10,9 @@ import (
 )
 
 type FakeERBRenderer struct {
-	RenderInputs   []RenderInput
-	renderBehavior map[string]renderOutput
+	RenderInputs      []RenderInput
+	RenderBatchInputs [][]bierbrenderer.BatchJob
+	renderBehavior    map[string]renderOutput
 }
 
 type RenderInput struct {
@@ -52,6 +53,33 @@ func (f *FakeERBRenderer) Render(srcPath, dstPath string, context bierbrenderer.
 	return fmt.Errorf("Unsupported Input: Render('%s', '%s', '%s')", srcPath, dstPath, context) //nolint:staticcheck
 }
 
+// RenderBatch renders each template via Render so that behavior set with SetRenderBehavior applies
+func (f *FakeERBRenderer) RenderBatch(jobs []bierbrenderer.BatchJob) error {
+	f.RenderBatchInputs = append(f.RenderBatchInputs, jobs)
+
+	var batchErr bierbrenderer.BatchError
+
+	for _, job := range jobs {
+		for _, template := range job.Templates {
+			err := f.Render(template.SrcPath, template.DstPath, job.Context)
+			if err != nil {
+				batchErr.Errors = append(batchErr.Errors, bierbrenderer.TemplateError{
+					Job:      job.Name,
+					Template: template.Name,
+					Line:     "unknown",
+					Message:  err.Error(),
+				})
+			}
+		}
+	}
+
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+
+	return nil
+}
+
 func (f *FakeERBRenderer) SetRenderBehavior(srcPath, dstPath string, context bierbrenderer.TemplateEvaluationContext, err error) error {
 	input := RenderInput{
 		SrcPath: srcPath,
@@ -0,0 +1,217 @@
+package erbrenderer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+
+	"github.com/cloudfoundry/bosh-cli/v7/templatescompiler/erbrenderer/nativeerb"
+	biui "github.com/cloudfoundry/bosh-cli/v7/ui"
+)
+
+type nativeERBRenderer struct {
+	fs       boshsys.FileSystem
+	fallback ERBRenderer
+	ui       biui.UI
+	logger   boshlog.Logger
+	logTag   string
+}
+
+// NewNativeERBRenderer renders templates without ruby. Templates that use
+// constructs the native renderer does not support are rendered with fallback,
+// which is shown as a warning since fallback usually requires ruby.
+func NewNativeERBRenderer(
+	fs boshsys.FileSystem,
+	fallback ERBRenderer,
+	ui biui.UI,
+	logger boshlog.Logger,
+) ERBRenderer {
+	return nativeERBRenderer{
+		fs:       fs,
+		fallback: fallback,
+		ui:       ui,
+		logger:   logger,
+		logTag:   "nativeERBRenderer",
+	}
+}
+
+func (r nativeERBRenderer) Render(srcPath, dstPath string, context TemplateEvaluationContext) error {
+	r.logger.Debug(r.logTag, "Rendering template %s", dstPath)
+
+	nativeContext, err := r.newContext(context)
+	if err == nil {
+		err = r.renderTemplate(srcPath, dstPath, nativeContext)
+	}
+
+	var unsupportedErr nativeerb.UnsupportedError
+	if errors.As(err, &unsupportedErr) {
+		r.warnFallback("Rendering template '%s' with ruby: %s", srcPath, unsupportedErr.Error())
+
+		err = r.fallback.Render(srcPath, dstPath, context)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Rendering template '%s' with ruby (%s)", srcPath, unsupportedErr.Error())
+		}
+
+		return nil
+	}
+
+	var rubyErr nativeerb.RubyError
+	if errors.As(err, &rubyErr) {
+		return bosherr.Errorf("Error filling in template '%s' for %s (line %d: %s)",
+			srcPath, nativeContext.InstanceName(), rubyErr.Line, rubyErr.Inspect())
+	}
+
+	return err
+}
+
+func (r nativeERBRenderer) RenderBatch(jobs []BatchJob) error {
+	started := time.Now()
+
+	var fallbackJobs []BatchJob
+	var batchErr BatchError
+	var rendered, fallbackTemplates int
+
+	// Reasons for rendering with ruby by job, or by job and template
+	fallbackReasons := map[string]string{}
+
+	for _, job := range jobs {
+		fallbackJob := BatchJob{Name: job.Name, Context: job.Context}
+
+		nativeContext, err := r.newContext(job.Context)
+		if err != nil {
+			var unsupportedErr nativeerb.UnsupportedError
+			if !errors.As(err, &unsupportedErr) {
+				return err
+			}
+
+			r.warnFallback("Rendering templates of job '%s' with ruby: %s", job.Name, err.Error())
+			fallbackReasons[job.Name] = err.Error()
+
+			fallbackJobs = append(fallbackJobs, job)
+			fallbackTemplates += len(job.Templates)
+
+			continue
+		}
+
+		for _, template := range job.Templates {
+			err := r.renderTemplate(template.SrcPath, template.DstPath, nativeContext)
+
+			var unsupportedErr nativeerb.UnsupportedError
+			var rubyErr nativeerb.RubyError
+
+			switch {
+			case err == nil:
+				rendered++
+
+			case errors.As(err, &unsupportedErr):
+				r.warnFallback("Rendering template '%s' of job '%s' with ruby: %s", template.Name, job.Name, err.Error())
+				fallbackReasons[job.Name+"/"+template.Name] = err.Error()
+
+				fallbackJob.Templates = append(fallbackJob.Templates, template)
+
+			case errors.As(err, &rubyErr):
+				batchErr.Errors = append(batchErr.Errors, TemplateError{
+					Job:      job.Name,
+					Template: template.Name,
+					Line:     strconv.Itoa(rubyErr.Line),
+					Message:  rubyErr.Inspect(),
+				})
+
+			default:
+				return err
+			}
+		}
+
+		if len(fallbackJob.Templates) > 0 {
+			fallbackJobs = append(fallbackJobs, fallbackJob)
+			fallbackTemplates += len(fallbackJob.Templates)
+		}
+	}
+
+	if len(fallbackJobs) > 0 {
+		err := r.fallback.RenderBatch(fallbackJobs)
+		if err != nil {
+			var fallbackBatchErr BatchError
+			if !errors.As(err, &fallbackBatchErr) {
+				var reasons []string
+				for _, job := range fallbackJobs {
+					for _, template := range job.Templates {
+						reasons = append(reasons, fmt.Sprintf("template '%s' of job '%s': %s",
+							template.Name, job.Name, fallbackReason(fallbackReasons, job.Name, template.Name)))
+					}
+				}
+
+				return bosherr.WrapErrorf(err, "Rendering templates with ruby (%s)", strings.Join(reasons, ", "))
+			}
+
+			for _, templateErr := range fallbackBatchErr.Errors {
+				templateErr.Message = fmt.Sprintf("%s (rendered with ruby: %s)",
+					templateErr.Message, fallbackReason(fallbackReasons, templateErr.Job, templateErr.Template))
+
+				batchErr.Errors = append(batchErr.Errors, templateErr)
+			}
+		}
+	}
+
+	r.logger.Debug(r.logTag, "Rendered %d template(s) natively and %d template(s) with ruby in %s",
+		rendered, fallbackTemplates, time.Since(started))
+
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+
+	return nil
+}
+
+func (r nativeERBRenderer) warnFallback(msg string, args ...interface{}) {
+	r.logger.Warn(r.logTag, msg, args...)
+	r.ui.ErrorLinef("Warning: "+msg, args...)
+}
+
+func fallbackReason(reasons map[string]string, jobName, templateName string) string {
+	if reason, found := reasons[jobName+"/"+templateName]; found {
+		return reason
+	}
+
+	return reasons[jobName]
+}
+
+func (r nativeERBRenderer) newContext(context TemplateEvaluationContext) (*nativeerb.Context, error) {
+	contextBytes, err := json.Marshal(context)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshalling context")
+	}
+
+	return nativeerb.NewContext(contextBytes)
+}
+
+func (r nativeERBRenderer) renderTemplate(srcPath, dstPath string, context *nativeerb.Context) error {
+	source, err := r.fs.ReadFileString(srcPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading template '%s'", srcPath)
+	}
+
+	template, err := nativeerb.Parse(srcPath, source)
+	if err != nil {
+		return err
+	}
+
+	content, err := template.Execute(context)
+	if err != nil {
+		return err
+	}
+
+	err = r.fs.WriteFileString(dstPath, content)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing rendered template '%s'", dstPath)
+	}
+
+	return nil
+}
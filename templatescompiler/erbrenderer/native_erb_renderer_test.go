@@ -0,0 +1,185 @@
+package erbrenderer_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/templatescompiler/erbrenderer"
+	fakebierbrenderer "github.com/cloudfoundry/bosh-cli/v7/templatescompiler/erbrenderer/fakes"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+)
+
+type jsonContext string
+
+func (c jsonContext) MarshalJSON() ([]byte, error) {
+	return []byte(c), nil
+}
+
+var _ = Describe("NativeERBRenderer", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		fallback    *fakebierbrenderer.FakeERBRenderer
+		ui          *fakeui.FakeUI
+		erbRenderer ERBRenderer
+		context     jsonContext
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs = fakesys.NewFakeFileSystem()
+		fallback = fakebierbrenderer.NewFakeERBRender()
+		ui = &fakeui.FakeUI{}
+		context = jsonContext(`{
+			"job": {"name": "fake-job"},
+			"index": 0,
+			"default_properties": {"port": 8080},
+			"job_properties": {}
+		}`)
+
+		erbRenderer = NewNativeERBRenderer(fs, fallback, ui, logger)
+	})
+
+	Describe("Render", func() {
+		It("renders supported templates without ruby", func() {
+			err := fs.WriteFileString("/src/config.erb", "port: <%= p('port') %>\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = erbRenderer.Render("/src/config.erb", "/dst/config", context)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/dst/config")).To(Equal("port: 8080\n"))
+			Expect(fallback.RenderInputs).To(BeEmpty())
+		})
+
+		It("renders templates using unsupported constructs with fallback renderer", func() {
+			err := fs.WriteFileString("/src/config.erb", "host: <%= `hostname` %>\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fallback.SetRenderBehavior("/src/config.erb", "/dst/config", context, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = erbRenderer.Render("/src/config.erb", "/dst/config", context)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fallback.RenderInputs).To(Equal([]fakebierbrenderer.RenderInput{
+				{SrcPath: "/src/config.erb", DstPath: "/dst/config", Context: context},
+			}))
+			Expect(fs.FileExists("/dst/config")).To(BeFalse())
+
+			Expect(ui.Errors).To(Equal([]string{
+				"Warning: Rendering template '/src/config.erb' with ruby: Unsupported construct 'backtick command' at line 1",
+			}))
+		})
+
+		It("returns fallback renderer errors with the reason for falling back", func() {
+			err := fs.WriteFileString("/src/config.erb", "host: <%= `hostname` %>\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fallback.SetRenderBehavior("/src/config.erb", "/dst/config", context, errors.New("fake-ruby-error"))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = erbRenderer.Render("/src/config.erb", "/dst/config", context)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Rendering template '/src/config.erb' with ruby " +
+				"(Unsupported construct 'backtick command' at line 1): fake-ruby-error"))
+		})
+
+		It("returns errors raised by templates like ruby does", func() {
+			err := fs.WriteFileString("/src/config.erb", "\n<%= p('missing') %>\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = erbRenderer.Render("/src/config.erb", "/dst/config", context)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Error filling in template '/src/config.erb' for fake-job/0 " +
+				"(line 2: #<TemplateEvaluationContext::UnknownProperty: Can't find property 'missing'>)"))
+			Expect(fallback.RenderInputs).To(BeEmpty())
+		})
+
+		It("returns an error when reading template fails", func() {
+			err := fs.WriteFileString("/src/config.erb", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			fs.RegisterReadFileError("/src/config.erb", errors.New("fake-read-error"))
+
+			err = erbRenderer.Render("/src/config.erb", "/dst/config", context)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-read-error"))
+		})
+	})
+
+	Describe("RenderBatch", func() {
+		var jobs []BatchJob
+
+		BeforeEach(func() {
+			err := fs.WriteFileString("/src/native.erb", "<%= p('port') %>")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/src/ruby.erb", "<%= p('port').to_s(16).rjust(8, '0') %>")
+			Expect(err).ToNot(HaveOccurred())
+
+			jobs = []BatchJob{
+				{
+					Name:    "fake-job",
+					Context: context,
+					Templates: []BatchTemplate{
+						{Name: "native", SrcPath: "/src/native.erb", DstPath: "/dst/native"},
+						{Name: "ruby", SrcPath: "/src/ruby.erb", DstPath: "/dst/ruby"},
+					},
+				},
+			}
+		})
+
+		It("renders supported templates natively and the rest with a single fallback batch", func() {
+			err := fallback.SetRenderBehavior("/src/ruby.erb", "/dst/ruby", context, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = erbRenderer.RenderBatch(jobs)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/dst/native")).To(Equal("8080"))
+			Expect(ui.Errors).To(Equal([]string{
+				"Warning: Rendering template 'ruby' of job 'fake-job' with ruby: " +
+					"Unsupported construct 'String#rjust with (Integer, String)' at line 1",
+			}))
+			Expect(fallback.RenderBatchInputs).To(Equal([][]BatchJob{
+				{
+					{
+						Name:      "fake-job",
+						Context:   context,
+						Templates: []BatchTemplate{{Name: "ruby", SrcPath: "/src/ruby.erb", DstPath: "/dst/ruby"}},
+					},
+				},
+			}))
+		})
+
+		It("does not run fallback renderer when all templates are supported", func() {
+			jobs[0].Templates = jobs[0].Templates[:1]
+
+			err := erbRenderer.RenderBatch(jobs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallback.RenderBatchInputs).To(BeEmpty())
+			Expect(ui.Errors).To(BeEmpty())
+		})
+
+		It("returns errors of natively rendered templates together with fallback errors", func() {
+			err := fs.WriteFileString("/src/native.erb", "<% raise 'fake-error' %>")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fallback.SetRenderBehavior("/src/ruby.erb", "/dst/ruby", context, errors.New("fake-ruby-error"))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = erbRenderer.RenderBatch(jobs)
+			Expect(err).To(Equal(BatchError{
+				Errors: []TemplateError{
+					{Job: "fake-job", Template: "native", Line: "1", Message: "#<RuntimeError: fake-error>"},
+					{Job: "fake-job", Template: "ruby", Line: "unknown", Message: "fake-ruby-error " +
+						"(rendered with ruby: Unsupported construct 'String#rjust with (Integer, String)' at line 1)"},
+				},
+			}))
+		})
+	})
+})
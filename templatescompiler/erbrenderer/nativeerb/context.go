@@ -0,0 +1,489 @@
+package nativeerb
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Context mirrors TemplateEvaluationContext of the Ruby renderer
+type Context struct {
+	name          value
+	index         value
+	rawProperties *hash
+	properties    value
+	links         *hash
+	spec          value
+}
+
+type evaluationLink struct {
+	instances  *array
+	properties value
+	address    value
+}
+
+type linkInstance struct {
+	fields *hash
+}
+
+// elseBlock is returned by if_p and if_link so that
+// '.else' and '.else_if_p' blocks can be chained
+type elseBlock struct {
+	active bool
+
+	// receiver of 'else_if_p', either *Context or *evaluationLink
+	receiver value
+}
+
+// NewContext builds context from the same JSON that is passed to the Ruby renderer
+func NewContext(contextJSON []byte) (*Context, error) {
+	root, err := decodeJSON(contextJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := root.(*hash)
+	if !ok {
+		return nil, unsupported(0, "'context of type %s'", typeName(root))
+	}
+
+	c := &Context{}
+
+	if job, ok := spec.getString("job").(*hash); ok {
+		c.name = job.getString("name")
+	}
+
+	c.index = spec.getString("index")
+
+	var properties1 value
+
+	if jobProperties := spec.getString("job_properties"); jobProperties != nil {
+		properties1 = jobProperties
+	} else {
+		globalProperties, ok := spec.getString("global_properties").(*hash)
+		if !ok {
+			return nil, unsupported(0, "'context without global properties'")
+		}
+
+		clusterProperties, ok := spec.getString("cluster_properties").(*hash)
+		if !ok {
+			return nil, unsupported(0, "'context without cluster properties'")
+		}
+
+		recursiveMerge(globalProperties, clusterProperties)
+		properties1 = globalProperties
+	}
+
+	defaultProperties, ok := spec.getString("default_properties").(*hash)
+	if !ok {
+		return nil, unsupported(0, "'context without default properties'")
+	}
+
+	properties := newHash()
+
+	for i, name := range defaultProperties.keys {
+		nameStr, ok := name.(string)
+		if !ok {
+			return nil, unsupported(0, "'property name of type %s'", typeName(name))
+		}
+
+		err = copyProperty(properties, properties1, nameStr, defaultProperties.vals[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.rawProperties = properties
+	c.properties = toOpenStruct(properties)
+
+	switch links := spec.getString("links").(type) {
+	case nil:
+		c.links = newHash()
+	case *hash:
+		c.links = links
+	default:
+		return nil, unsupported(0, "'links of type %s'", typeName(links))
+	}
+
+	c.spec = toOpenStruct(spec)
+
+	return c, nil
+}
+
+// InstanceName returns 'name/index' as used in Ruby renderer errors
+func (c *Context) InstanceName() string {
+	name, _ := toS(c.name)   //nolint:errcheck
+	index, _ := toS(c.index) //nolint:errcheck
+
+	return name + "/" + index
+}
+
+func recursiveMerge(dst, src *hash) {
+	for i, k := range src.keys {
+		newValue := src.vals[i]
+		oldValue, _, _ := dst.get(k) //nolint:errcheck
+
+		oldHash, oldIsHash := oldValue.(*hash)
+		newHash, newIsHash := newValue.(*hash)
+
+		if oldIsHash && newIsHash {
+			recursiveMerge(oldHash, newHash)
+			continue
+		}
+
+		dst.set(k, newValue) //nolint:errcheck
+	}
+}
+
+// copyProperty mirrors TemplateEvaluationContext#copy_property
+func copyProperty(dst *hash, src value, name string, defaultValue value) error {
+	keys := strings.Split(name, ".")
+	srcRef := src
+
+	for _, key := range keys {
+		var err error
+
+		srcRef, err = index(srcRef, key)
+		if err != nil {
+			return err
+		}
+
+		if srcRef == nil {
+			break
+		}
+	}
+
+	dstRef := dst
+
+	for _, key := range keys[:len(keys)-1] {
+		next := dstRef.getString(key)
+
+		if next == nil {
+			next = newHash()
+			dstRef.set(key, next) //nolint:errcheck
+		}
+
+		nextHash, ok := next.(*hash)
+		if !ok {
+			return unsupported(0, "'property %s nested under %s value'", name, typeName(next))
+		}
+
+		dstRef = nextHash
+	}
+
+	last := keys[len(keys)-1]
+
+	if dstRef.getString(last) == nil {
+		dstRef.set(last, newHash()) //nolint:errcheck
+	}
+
+	if srcRef == nil {
+		srcRef = defaultValue
+	}
+
+	dstRef.set(last, srcRef) //nolint:errcheck
+
+	return nil
+}
+
+// index mirrors 'collection[key]' with a string key as used in property lookups
+func index(collection value, key string) (value, error) {
+	switch typed := collection.(type) {
+	case *hash:
+		return typed.getString(key), nil
+	case string:
+		// String#[] returns the key if it is a substring
+		if strings.Contains(typed, key) {
+			return key, nil
+		}
+		return nil, nil
+	}
+
+	return nil, unsupported(0, "'property lookup in %s'", typeName(collection))
+}
+
+func lookupProperty(collection value, name string) (value, error) {
+	ref := collection
+
+	for _, key := range strings.Split(name, ".") {
+		var err error
+
+		ref, err = index(ref, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if ref == nil {
+			return nil, nil
+		}
+	}
+
+	return ref, nil
+}
+
+func toOpenStruct(v value) value {
+	switch typed := v.(type) {
+	case *hash:
+		fields := newHash()
+
+		for i, k := range typed.keys {
+			fields.set(k, toOpenStruct(typed.vals[i])) //nolint:errcheck
+		}
+
+		return &openStruct{fields: fields}
+
+	case *array:
+		items := make([]value, len(typed.items))
+
+		for i, item := range typed.items {
+			items[i] = toOpenStruct(item)
+		}
+
+		return newArray(items...)
+	}
+
+	return v
+}
+
+// propertyNames mirrors Array(args[0]) in p and if_p
+func propertyNames(v value) ([]string, error) {
+	var items []value
+
+	switch typed := v.(type) {
+	case nil:
+		return nil, nil
+	case *array:
+		items = typed.items
+	default:
+		items = []value{v}
+	}
+
+	names := make([]string, len(items))
+
+	for i, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, unsupported(0, "'property name of type %s'", typeName(item))
+		}
+
+		names[i] = name
+	}
+
+	return names, nil
+}
+
+func unknownProperty(names []string) error {
+	return RubyError{
+		Class:   "TemplateEvaluationContext::UnknownProperty",
+		Message: "Can't find property '" + strings.Join(names, "', or '") + "'",
+	}
+}
+
+// p mirrors TemplateEvaluationContext#p and EvaluationLink#p
+func p(properties value, args []value) (value, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, unsupported(0, "'p with %d arguments'", len(args))
+	}
+
+	names, err := propertyNames(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		result, err := lookupProperty(properties, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if result != nil {
+			return result, nil
+		}
+	}
+
+	if len(args) == 2 {
+		return args[1], nil
+	}
+
+	return nil, unknownProperty(names)
+}
+
+// ifP mirrors TemplateEvaluationContext#if_p and EvaluationLink#if_p
+func (e *evaluator) ifP(receiver value, properties value, args []value, blk *block) (value, error) {
+	values := make([]value, len(args))
+
+	for i, arg := range args {
+		name, ok := arg.(string)
+		if !ok {
+			return nil, unsupported(0, "'if_p with %s argument'", typeName(arg))
+		}
+
+		v, err := lookupProperty(properties, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if v == nil {
+			return &elseBlock{active: true, receiver: receiver}, nil
+		}
+
+		values[i] = v
+	}
+
+	if blk == nil {
+		return nil, unsupported(0, "'if_p without block'")
+	}
+
+	_, err := e.yield(blk, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &elseBlock{active: false}, nil
+}
+
+func (c *Context) link(name value) (value, error) {
+	spec, err := c.linkSpec(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec == nil {
+		nameStr, _ := toS(name) //nolint:errcheck
+
+		return nil, RubyError{
+			Class:   "TemplateEvaluationContext::UnknownLink",
+			Message: "Can't find link '" + nameStr + "'",
+		}
+	}
+
+	return newEvaluationLink(spec)
+}
+
+func (c *Context) linkSpec(name value) (value, error) {
+	if _, ok := name.(string); !ok {
+		return nil, unsupported(0, "'link name of type %s'", typeName(name))
+	}
+
+	spec, _, err := c.links.get(name)
+
+	return spec, err
+}
+
+func newEvaluationLink(spec value) (*evaluationLink, error) {
+	specHash, ok := spec.(*hash)
+	if !ok {
+		return nil, unsupported(0, "'link of type %s'", typeName(spec))
+	}
+
+	instances := newArray()
+
+	switch specInstances := specHash.getString("instances").(type) {
+	case nil:
+	case *array:
+		for _, instance := range specInstances.items {
+			instanceHash, ok := instance.(*hash)
+			if !ok {
+				return nil, unsupported(0, "'link instance of type %s'", typeName(instance))
+			}
+
+			fields := newHash()
+
+			for _, field := range []string{"name", "index", "id", "az", "address", "bootstrap"} {
+				fields.set(field, instanceHash.getString(field)) //nolint:errcheck
+			}
+
+			instances.items = append(instances.items, &linkInstance{fields: fields})
+		}
+	default:
+		return nil, unsupported(0, "'link instances of type %s'", typeName(specInstances))
+	}
+
+	properties := specHash.getString("properties")
+	if properties == nil {
+		properties = newHash()
+	}
+
+	return &evaluationLink{
+		instances:  instances,
+		properties: properties,
+		address:    specHash.getString("address"),
+	}, nil
+}
+
+// decodeJSON decodes JSON preserving order of object keys like Ruby's JSON.parse
+func decodeJSON(data []byte) (value, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	v, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, unsupported(0, "'context JSON (%s)'", err.Error())
+	}
+
+	return v, nil
+}
+
+func decodeJSONValue(decoder *json.Decoder) (value, error) {
+	t, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := t.(type) {
+	case json.Delim:
+		switch typed {
+		case '{':
+			result := newHash()
+
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				val, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				result.set(keyToken.(string), val) //nolint:errcheck
+			}
+
+			_, err = decoder.Token()
+
+			return result, err
+
+		case '[':
+			result := newArray()
+
+			for decoder.More() {
+				val, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				result.items = append(result.items, val)
+			}
+
+			_, err = decoder.Token()
+
+			return result, err
+		}
+
+	case json.Number:
+		literal := typed.String()
+
+		if !strings.ContainsAny(literal, ".eE") {
+			return strconv.ParseInt(literal, 10, 64)
+		}
+
+		return strconv.ParseFloat(literal, 64)
+
+	case string, bool, nil:
+		return typed, nil
+	}
+
+	return nil, unsupported(0, "'JSON token %v'", t)
+}
@@ -0,0 +1,55 @@
+package nativeerb
+
+import (
+	"fmt"
+)
+
+// UnsupportedError is returned when a template uses a construct
+// that cannot be evaluated exactly as Ruby would evaluate it.
+// Such templates should be rendered with Ruby instead.
+type UnsupportedError struct {
+	Construct string
+	Line      int
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("Unsupported construct %s at line %d", e.Construct, e.Line)
+}
+
+// RubyError is an error raised by the template itself,
+// e.g. when a required property is not set.
+type RubyError struct {
+	Class   string
+	Message string
+	Line    int
+}
+
+func (e RubyError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Inspect())
+}
+
+// Inspect formats the error as Ruby's Exception#inspect does
+func (e RubyError) Inspect() string {
+	return fmt.Sprintf("#<%s: %s>", e.Class, e.Message)
+}
+
+func unsupported(line int, format string, args ...interface{}) error {
+	return UnsupportedError{Construct: fmt.Sprintf(format, args...), Line: line}
+}
+
+func withLine(err error, line int) error {
+	switch typedErr := err.(type) {
+	case UnsupportedError:
+		if typedErr.Line == 0 {
+			typedErr.Line = line
+		}
+		return typedErr
+	case RubyError:
+		if typedErr.Line == 0 {
+			typedErr.Line = line
+		}
+		return typedErr
+	default:
+		return err
+	}
+}
@@ -0,0 +1,644 @@
+package nativeerb
+
+import (
+	"math"
+	"strings"
+)
+
+type evaluator struct {
+	context *Context
+	out     strings.Builder
+}
+
+type scope struct {
+	vars   map[string]value
+	parent *scope
+}
+
+type block struct {
+	node  *blockNode
+	scope *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: map[string]value{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) (value, bool) {
+	for current := s; current != nil; current = current.parent {
+		if v, found := current.vars[name]; found {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *scope) assign(name string, v value) {
+	for current := s; current != nil; current = current.parent {
+		if _, found := current.vars[name]; found {
+			current.vars[name] = v
+			return
+		}
+	}
+
+	s.vars[name] = v
+}
+
+func (e *evaluator) evalStmts(stmts []node, s *scope) (value, error) {
+	var result value
+
+	for _, stmt := range stmts {
+		var err error
+
+		result, err = e.eval(stmt, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (e *evaluator) eval(n node, s *scope) (value, error) {
+	v, err := e.evalNode(n, s)
+	if err != nil {
+		return nil, withLine(err, n.pos())
+	}
+
+	return v, nil
+}
+
+func (e *evaluator) evalNode(n node, s *scope) (value, error) {
+	switch typed := n.(type) {
+	case *textNode:
+		e.out.WriteString(typed.text)
+		return nil, nil
+
+	case *outputNode:
+		v, err := e.eval(typed.expr, s)
+		if err != nil {
+			return nil, err
+		}
+
+		str, err := toS(v)
+		if err != nil {
+			return nil, err
+		}
+
+		e.out.WriteString(str)
+
+		return nil, nil
+
+	case *literalNode:
+		return typed.val, nil
+
+	case *stringNode:
+		var sb strings.Builder
+
+		for _, part := range typed.parts {
+			v, err := e.eval(part, s)
+			if err != nil {
+				return nil, err
+			}
+
+			str, err := toS(v)
+			if err != nil {
+				return nil, err
+			}
+
+			sb.WriteString(str)
+		}
+
+		return sb.String(), nil
+
+	case *arrayNode:
+		result := newArray()
+
+		for _, item := range typed.items {
+			v, err := e.eval(item, s)
+			if err != nil {
+				return nil, err
+			}
+
+			result.items = append(result.items, v)
+		}
+
+		return result, nil
+
+	case *hashNode:
+		result := newHash()
+
+		for i := range typed.keys {
+			k, err := e.eval(typed.keys[i], s)
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := e.eval(typed.vals[i], s)
+			if err != nil {
+				return nil, err
+			}
+
+			err = result.set(k, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return result, nil
+
+	case *varNode:
+		v, _ := s.lookup(typed.name)
+		return v, nil
+
+	case *constNode:
+		switch typed.name {
+		case "JSON", "YAML", "Psych", "Hash", "Array", "String", "Integer", "Float", "Numeric",
+			"NilClass", "TrueClass", "FalseClass", "Symbol", "OpenStruct":
+			return constant(typed.name), nil
+		}
+
+		return nil, unsupported(typed.line, "'constant %s'", typed.name)
+
+	case *assignNode:
+		return e.evalAssign(typed, s)
+
+	case *indexAssignNode:
+		return e.evalIndexAssign(typed, s)
+
+	case *callNode:
+		return e.evalCall(typed, s)
+
+	case *indexNode:
+		recv, err := e.eval(typed.recv, s)
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := e.evalArgs(typed.args, s)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.index(recv, args)
+
+	case *binaryNode:
+		l, err := e.eval(typed.l, s)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := e.eval(typed.r, s)
+		if err != nil {
+			return nil, err
+		}
+
+		return binaryOp(typed.op, l, r)
+
+	case *andNode:
+		l, err := e.eval(typed.l, s)
+		if err != nil || !truthy(l) {
+			return l, err
+		}
+
+		return e.eval(typed.r, s)
+
+	case *orNode:
+		l, err := e.eval(typed.l, s)
+		if err != nil || truthy(l) {
+			return l, err
+		}
+
+		return e.eval(typed.r, s)
+
+	case *notNode:
+		v, err := e.eval(typed.x, s)
+		if err != nil {
+			return nil, err
+		}
+
+		return !truthy(v), nil
+
+	case *ifNode:
+		cond, err := e.eval(typed.cond, s)
+		if err != nil {
+			return nil, err
+		}
+
+		if truthy(cond) {
+			return e.evalStmts(typed.then, s)
+		}
+
+		return e.evalStmts(typed.els, s)
+	}
+
+	return nil, unsupported(n.pos(), "'%T'", n)
+}
+
+func (e *evaluator) evalArgs(nodes []node, s *scope) ([]value, error) {
+	args := make([]value, len(nodes))
+
+	for i, n := range nodes {
+		v, err := e.eval(n, s)
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = v
+	}
+
+	return args, nil
+}
+
+func (e *evaluator) evalAssign(n *assignNode, s *scope) (value, error) {
+	current, _ := s.lookup(n.name)
+
+	switch n.op {
+	case "||=":
+		if truthy(current) {
+			return current, nil
+		}
+	case "&&=":
+		if !truthy(current) {
+			return current, nil
+		}
+	}
+
+	v, err := e.eval(n.val, s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "+=", "-=", "*=":
+		v, err = binaryOp(n.op[:1], current, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.assign(n.name, v)
+
+	return v, nil
+}
+
+func (e *evaluator) evalIndexAssign(n *indexAssignNode, s *scope) (value, error) {
+	recv, err := e.eval(n.recv, s)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := e.evalArgs(n.index, s)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := e.eval(n.val, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) != 1 {
+		return nil, unsupported(n.line, "'[]= with %d arguments'", len(args))
+	}
+
+	switch typed := recv.(type) {
+	case *hash:
+		return v, typed.set(args[0], v)
+
+	case *array:
+		i, ok := args[0].(int64)
+		if !ok {
+			return nil, unsupported(n.line, "'Array#[]= with %s'", typeName(args[0]))
+		}
+
+		if i < 0 {
+			i += int64(len(typed.items))
+		}
+
+		if i < 0 || i >= int64(len(typed.items)) {
+			return nil, unsupported(n.line, "'Array#[]= out of bounds'")
+		}
+
+		typed.items[i] = v
+
+		return v, nil
+	}
+
+	return nil, unsupported(n.line, "'[]= on %s'", typeName(recv))
+}
+
+func (e *evaluator) evalCall(n *callNode, s *scope) (value, error) {
+	var recv value
+
+	if n.recv != nil {
+		var err error
+
+		recv, err = e.eval(n.recv, s)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.safe && recv == nil {
+			return nil, nil
+		}
+	}
+
+	args, err := e.evalArgs(n.args, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var blk *block
+	if n.block != nil {
+		blk = &block{node: n.block, scope: s}
+	}
+
+	if n.recv == nil {
+		return e.callFunction(n.name, args, blk)
+	}
+
+	return e.callMethod(recv, n.name, args, blk)
+}
+
+// yield calls a block with Ruby's proc argument semantics
+func (e *evaluator) yield(blk *block, args []value) (value, error) {
+	if len(blk.node.symbol) > 0 {
+		if len(args) == 0 {
+			return nil, unsupported(blk.node.line, "'&:%s without arguments'", blk.node.symbol)
+		}
+
+		return e.callMethod(args[0], blk.node.symbol, nil, nil)
+	}
+
+	params := blk.node.params
+
+	if len(params) > 1 && len(args) == 1 {
+		if arr, ok := args[0].(*array); ok {
+			args = arr.items
+		}
+	}
+
+	s := newScope(blk.scope)
+
+	for i, param := range params {
+		if i < len(args) {
+			s.vars[param] = args[i]
+		} else {
+			s.vars[param] = nil
+		}
+	}
+
+	return e.evalStmts(blk.node.body, s)
+}
+
+func (e *evaluator) index(recv value, args []value) (value, error) {
+	if len(args) != 1 {
+		return nil, unsupported(0, "'[] with %d arguments'", len(args))
+	}
+
+	switch typed := recv.(type) {
+	case *hash:
+		v, _, err := typed.get(args[0])
+		return v, err
+
+	case *array:
+		i, ok := args[0].(int64)
+		if !ok {
+			return nil, unsupported(0, "'Array#[] with %s'", typeName(args[0]))
+		}
+
+		if i < 0 {
+			i += int64(len(typed.items))
+		}
+
+		if i < 0 || i >= int64(len(typed.items)) {
+			return nil, nil
+		}
+
+		return typed.items[i], nil
+
+	case *openStruct:
+		return typed.field(args[0])
+	}
+
+	return nil, unsupported(0, "'[] on %s'", typeName(recv))
+}
+
+func binaryOp(op string, l, r value) (value, error) {
+	switch op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+
+	switch typedL := l.(type) {
+	case int64:
+		switch typedR := r.(type) {
+		case int64:
+			return intOp(op, typedL, typedR)
+		case float64:
+			return floatOp(op, float64(typedL), typedR)
+		}
+
+	case float64:
+		switch typedR := r.(type) {
+		case int64:
+			return floatOp(op, typedL, float64(typedR))
+		case float64:
+			return floatOp(op, typedL, typedR)
+		}
+
+	case string:
+		switch {
+		case op == "+":
+			if typedR, ok := r.(string); ok {
+				return typedL + typedR, nil
+			}
+		case op == "*":
+			if typedR, ok := r.(int64); ok && typedR >= 0 && typedR < 1<<20 {
+				return strings.Repeat(typedL, int(typedR)), nil
+			}
+		}
+
+	case *array:
+		switch op {
+		case "+":
+			if typedR, ok := r.(*array); ok {
+				return newArray(append(append([]value{}, typedL.items...), typedR.items...)...), nil
+			}
+		case "-":
+			if typedR, ok := r.(*array); ok {
+				return arrayDifference(typedL, typedR)
+			}
+		case "<<":
+			typedL.items = append(typedL.items, r)
+			return typedL, nil
+		}
+	}
+
+	return nil, unsupported(0, "'%s %s %s'", typeName(l), op, typeName(r))
+}
+
+func intOp(op string, l, r int64) (value, error) {
+	switch op {
+	case "+":
+		result := l + r
+		if (result > l) != (r > 0) {
+			return nil, unsupported(0, "'integer overflow'")
+		}
+		return result, nil
+
+	case "-":
+		result := l - r
+		if (result < l) != (r > 0) {
+			return nil, unsupported(0, "'integer overflow'")
+		}
+		return result, nil
+
+	case "*":
+		if l != 0 && r != 0 {
+			result := l * r
+			if result/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+				return nil, unsupported(0, "'integer overflow'")
+			}
+			return result, nil
+		}
+		return int64(0), nil
+
+	case "/", "%":
+		if r == 0 {
+			return nil, unsupported(0, "'division by zero'")
+		}
+
+		// Ruby rounds integer division towards negative infinity
+		q, m := l/r, l%r
+		if m != 0 && (m < 0) != (r < 0) {
+			q--
+			m += r
+		}
+
+		if op == "/" {
+			return q, nil
+		}
+
+		return m, nil
+	}
+
+	return nil, unsupported(0, "'Integer %s Integer'", op)
+}
+
+func floatOp(op string, l, r float64) (value, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	}
+
+	return nil, unsupported(0, "'Float %s Float'", op)
+}
+
+func compare(l, r value) (int, error) {
+	switch typedL := l.(type) {
+	case int64:
+		switch typedR := r.(type) {
+		case int64:
+			return compareOrdered(typedL, typedR), nil
+		case float64:
+			return compareOrdered(float64(typedL), typedR), nil
+		}
+
+	case float64:
+		switch typedR := r.(type) {
+		case int64:
+			return compareOrdered(typedL, float64(typedR)), nil
+		case float64:
+			if math.IsNaN(typedL) || math.IsNaN(typedR) {
+				return 0, unsupported(0, "'comparison with NaN'")
+			}
+			return compareOrdered(typedL, typedR), nil
+		}
+
+	case string:
+		if typedR, ok := r.(string); ok {
+			return strings.Compare(typedL, typedR), nil
+		}
+
+	case *array:
+		if typedR, ok := r.(*array); ok {
+			for i := 0; i < len(typedL.items) && i < len(typedR.items); i++ {
+				c, err := compare(typedL.items[i], typedR.items[i])
+				if err != nil || c != 0 {
+					return c, err
+				}
+			}
+
+			return compareOrdered(len(typedL.items), len(typedR.items)), nil
+		}
+	}
+
+	return 0, unsupported(0, "'comparison of %s with %s'", typeName(l), typeName(r))
+}
+
+func compareOrdered[T int | int64 | float64](l, r T) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+
+	return 0
+}
+
+// arrayDifference mirrors Array#- which compares elements with eql?
+func arrayDifference(l, r *array) (value, error) {
+	excluded := map[hashKey]bool{}
+
+	for _, item := range r.items {
+		key, ok := keyOf(item)
+		if !ok {
+			return nil, unsupported(0, "'Array#- with %s elements'", typeName(item))
+		}
+
+		excluded[key] = true
+	}
+
+	result := newArray()
+
+	for _, item := range l.items {
+		key, ok := keyOf(item)
+		if !ok {
+			return nil, unsupported(0, "'Array#- with %s elements'", typeName(item))
+		}
+
+		if !excluded[key] {
+			result.items = append(result.items, item)
+		}
+	}
+
+	return result, nil
+}
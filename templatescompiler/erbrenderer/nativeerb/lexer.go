@@ -0,0 +1,501 @@
+package nativeerb
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tNewline
+	tText
+	tOutput
+	tOutputEnd
+	tIdent
+	tConst
+	tLabel
+	tInt
+	tFloat
+	tString
+	tSymbol
+	tWords
+	tOp
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	line int
+
+	// spaceBefore is set when the token is preceded by whitespace,
+	// which Ruby uses to tell command arguments from binary operators
+	spaceBefore bool
+
+	intVal   int64
+	floatVal float64
+	parts    []stringPart
+	words    []string
+}
+
+// stringPart is either a literal piece of a string or interpolated code
+type stringPart struct {
+	lit    string
+	code   []token
+	isCode bool
+}
+
+var operators = []string{
+	"**=", "||=", "&&=", "<=>", "===", "...",
+	"**", "==", "!=", ">=", "<=", "&&", "||", "<<", ">>", "=~", "!~", "+=", "-=", "*=", "/=", "%=", "::", "..", "=>", "->", "&.",
+	"+", "-", "*", "/", "%", "=", "<", ">", "!", "&", "|", "^", "~", "?", ":", ",", ".", "(", ")", "[", "]", "{", "}",
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+
+	tokens []token
+	space  bool
+}
+
+func lex(src string, line int) ([]token, error) {
+	l := &lexer{src: src, line: line}
+
+	err := l.run()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.tokens, nil
+}
+
+func (l *lexer) emit(t token) {
+	t.line = l.line
+	t.spaceBefore = l.space
+	l.tokens = append(l.tokens, t)
+	l.space = false
+}
+
+// valueEnded reports whether the previous token ends an expression,
+// in which case '/', '%', ':' and '<<' are treated as operators.
+func (l *lexer) valueEnded() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+
+	prev := l.tokens[len(l.tokens)-1]
+
+	switch prev.kind {
+	case tInt, tFloat, tString, tSymbol, tWords, tConst:
+		return true
+	case tIdent:
+		return !isKeyword(prev.val) || prev.val == "end" || prev.val == "self" || prev.val == "nil" || prev.val == "true" || prev.val == "false"
+	case tOp:
+		return prev.val == ")" || prev.val == "]" || prev.val == "}"
+	}
+
+	return false
+}
+
+func (l *lexer) run() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+			l.space = true
+
+		case c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '\n':
+			l.pos += 2
+			l.line++
+			l.space = true
+
+		case c == '\n':
+			l.emit(token{kind: tNewline, val: "\n"})
+			l.pos++
+			l.line++
+
+		case c == ';':
+			l.emit(token{kind: tNewline, val: ";"})
+			l.pos++
+
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+
+		case isDigit(c):
+			err := l.lexNumber()
+			if err != nil {
+				return err
+			}
+
+		case c == '"' || c == '`':
+			if c == '`' {
+				return unsupported(l.line, "'backtick command'")
+			}
+
+			parts, err := l.lexDoubleQuoted('"')
+			if err != nil {
+				return err
+			}
+
+			l.emit(token{kind: tString, parts: parts})
+
+		case c == '\'':
+			str, err := l.lexSingleQuoted()
+			if err != nil {
+				return err
+			}
+
+			l.emit(token{kind: tString, parts: []stringPart{{lit: str}}})
+
+		case c == '@' || c == '$':
+			return unsupported(l.line, "'%s variable'", map[byte]string{'@': "instance", '$': "global"}[c])
+
+		case c == ':' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '"':
+			l.pos++
+
+			parts, err := l.lexDoubleQuoted('"')
+			if err != nil {
+				return err
+			}
+
+			if len(parts) > 1 || (len(parts) == 1 && parts[0].isCode) {
+				return unsupported(l.line, "'interpolated symbol'")
+			}
+
+			name := ""
+			if len(parts) == 1 {
+				name = parts[0].lit
+			}
+
+			l.emit(token{kind: tSymbol, val: name})
+
+		case c == ':' && l.pos+1 < len(l.src) && isIdentStart(l.src[l.pos+1]) && (!l.valueEnded() || (l.space && !l.spaceAfter(1))):
+			l.pos++
+			name := l.readIdent()
+			l.emit(token{kind: tSymbol, val: name})
+
+		case c == '%' && !l.valueEnded() && l.pos+1 < len(l.src) && !isSpace(l.src[l.pos+1]) && l.src[l.pos+1] != '=':
+			err := l.lexPercentLiteral()
+			if err != nil {
+				return err
+			}
+
+		case c == '/' && !l.valueEnded():
+			return unsupported(l.line, "'regular expression'")
+
+		case c == '<' && strings.HasPrefix(l.src[l.pos:], "<<") && !l.valueEnded() && l.pos+2 < len(l.src) && (l.src[l.pos+2] == '~' || l.src[l.pos+2] == '-' || isIdentStart(l.src[l.pos+2])):
+			return unsupported(l.line, "'heredoc'")
+
+		case isIdentStart(c):
+			name := l.readIdent()
+
+			if l.pos < len(l.src) && l.src[l.pos] == ':' && !strings.HasPrefix(l.src[l.pos:], "::") && !isUpper(name[0]) &&
+				(len(l.tokens) == 0 || l.tokens[len(l.tokens)-1].val != "?") {
+				l.pos++
+				l.emit(token{kind: tLabel, val: name})
+			} else if isUpper(name[0]) {
+				l.emit(token{kind: tConst, val: name})
+			} else {
+				l.emit(token{kind: tIdent, val: name})
+			}
+
+		default:
+			op := ""
+
+			for _, candidate := range operators {
+				if strings.HasPrefix(l.src[l.pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+
+			if len(op) == 0 {
+				return unsupported(l.line, "'%c'", c)
+			}
+
+			l.pos += len(op)
+			l.emit(token{kind: tOp, val: op})
+		}
+	}
+
+	return nil
+}
+
+func (l *lexer) spaceAfter(offset int) bool {
+	return l.pos+offset >= len(l.src) || isSpace(l.src[l.pos+offset])
+}
+
+func (l *lexer) readIdent() string {
+	start := l.pos
+
+	for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+		l.pos++
+	}
+
+	// Method names may end with ? or ! but not when followed by =,
+	// e.g. 'a!=b' or 'a ? b : c' with no spaces
+	if l.pos < len(l.src) && (l.src[l.pos] == '?' || l.src[l.pos] == '!') &&
+		(l.pos+1 >= len(l.src) || l.src[l.pos+1] != '=') &&
+		(l.src[l.pos] == '!' || l.pos+1 >= len(l.src) || !isIdentStart(l.src[l.pos+1])) {
+		l.pos++
+	}
+
+	return l.src[start:l.pos]
+}
+
+func (l *lexer) lexNumber() error {
+	start := l.pos
+	isFloat := false
+
+	for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '_') {
+		l.pos++
+	}
+
+	if l.pos+1 < len(l.src) && l.src[l.pos] == '.' && isDigit(l.src[l.pos+1]) {
+		isFloat = true
+		l.pos++
+
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '_') {
+			l.pos++
+		}
+	}
+
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E' || l.src[l.pos] == 'x' || l.src[l.pos] == 'b' || l.src[l.pos] == 'o' || l.src[l.pos] == 'r' || l.src[l.pos] == 'i') {
+		return unsupported(l.line, "'number literal %s'", l.src[start:l.pos+1])
+	}
+
+	literal := strings.ReplaceAll(l.src[start:l.pos], "_", "")
+
+	if isFloat {
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return unsupported(l.line, "'number literal %s'", literal)
+		}
+
+		l.emit(token{kind: tFloat, val: literal, floatVal: f})
+		return nil
+	}
+
+	if len(literal) > 1 && literal[0] == '0' {
+		return unsupported(l.line, "'octal literal %s'", literal)
+	}
+
+	i, err := strconv.ParseInt(literal, 10, 64)
+	if err != nil {
+		return unsupported(l.line, "'number literal %s'", literal)
+	}
+
+	l.emit(token{kind: tInt, val: literal, intVal: i})
+
+	return nil
+}
+
+func (l *lexer) lexSingleQuoted() (string, error) {
+	var sb strings.Builder
+
+	l.pos++
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == '\\' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '\\' || l.src[l.pos+1] == '\''):
+			sb.WriteByte(l.src[l.pos+1])
+			l.pos += 2
+		case c == '\'':
+			l.pos++
+			return sb.String(), nil
+		default:
+			if c == '\n' {
+				l.line++
+			}
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return "", unsupported(l.line, "'unterminated string'")
+}
+
+func (l *lexer) lexDoubleQuoted(closing byte) ([]stringPart, error) {
+	var parts []stringPart
+	var sb strings.Builder
+
+	l.pos++
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return nil, unsupported(l.line, "'unterminated string'")
+			}
+
+			escaped := l.src[l.pos+1]
+			l.pos += 2
+
+			switch escaped {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 's':
+				sb.WriteByte(' ')
+			case 'e':
+				sb.WriteByte(0x1b)
+			case '0':
+				sb.WriteByte(0)
+			case '\n':
+				l.line++
+			case '\\', '"', '#', '\'', '{', '}', '[', ']', '(', ')', '/', '|', '!':
+				sb.WriteByte(escaped)
+			default:
+				return nil, unsupported(l.line, "'string escape \\%c'", escaped)
+			}
+
+		case c == '#' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '{':
+			if sb.Len() > 0 {
+				parts = append(parts, stringPart{lit: sb.String()})
+				sb.Reset()
+			}
+
+			code, err := l.interpolation()
+			if err != nil {
+				return nil, err
+			}
+
+			parts = append(parts, stringPart{code: code, isCode: true})
+
+		case c == '#' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '@' || l.src[l.pos+1] == '$'):
+			return nil, unsupported(l.line, "'variable interpolation'")
+
+		case c == closing:
+			l.pos++
+
+			if sb.Len() > 0 || len(parts) == 0 {
+				parts = append(parts, stringPart{lit: sb.String()})
+			}
+
+			return parts, nil
+
+		default:
+			if c == '\n' {
+				l.line++
+			}
+
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return nil, unsupported(l.line, "'unterminated string'")
+}
+
+// interpolation lexes code inside #{...} allowing nested braces and strings
+func (l *lexer) interpolation() ([]token, error) {
+	l.pos += 2
+
+	start := l.pos
+	startLine := l.line
+	depth := 0
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				code := l.src[start:l.pos]
+				l.pos++
+				return lex(code, startLine)
+			}
+			depth--
+		case '"', '\'':
+			sub := &lexer{src: l.src, pos: l.pos, line: l.line}
+
+			var err error
+			if c == '"' {
+				_, err = sub.lexDoubleQuoted('"')
+			} else {
+				_, err = sub.lexSingleQuoted()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			l.pos = sub.pos
+			l.line = sub.line
+
+			continue
+		case '\n':
+			l.line++
+		}
+
+		l.pos++
+	}
+
+	return nil, unsupported(l.line, "'unterminated string interpolation'")
+}
+
+func (l *lexer) lexPercentLiteral() error {
+	kind := l.src[l.pos+1]
+
+	if kind != 'w' {
+		return unsupported(l.line, "'%%%c literal'", kind)
+	}
+
+	if l.pos+2 >= len(l.src) {
+		return unsupported(l.line, "'%%w literal'")
+	}
+
+	closing, found := map[byte]byte{'(': ')', '[': ']', '{': '}', '<': '>', '|': '|'}[l.src[l.pos+2]]
+	if !found {
+		return unsupported(l.line, "'%%w literal'")
+	}
+
+	end := strings.IndexByte(l.src[l.pos+3:], closing)
+	if end < 0 {
+		return unsupported(l.line, "'unterminated %%w literal'")
+	}
+
+	body := l.src[l.pos+3 : l.pos+3+end]
+	if strings.ContainsAny(body, "\\") {
+		return unsupported(l.line, "'%%w literal with escapes'")
+	}
+
+	l.emit(token{kind: tWords, words: strings.Fields(body)})
+	l.line += strings.Count(body, "\n")
+	l.pos += 3 + end + 1
+
+	return nil
+}
+
+var keywords = map[string]bool{
+	"alias": true, "and": true, "begin": true, "break": true, "case": true, "class": true, "def": true,
+	"defined?": true, "do": true, "else": true, "elsif": true, "end": true, "ensure": true, "false": true,
+	"for": true, "if": true, "in": true, "module": true, "next": true, "nil": true, "not": true, "or": true,
+	"redo": true, "rescue": true, "retry": true, "return": true, "self": true, "super": true, "then": true,
+	"true": true, "undef": true, "unless": true, "until": true, "when": true, "while": true, "yield": true,
+	"__FILE__": true, "__LINE__": true, "BEGIN": true, "END": true,
+}
+
+func isKeyword(s string) bool { return keywords[s] }
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isUpper(c byte) bool      { return c >= 'A' && c <= 'Z' }
+func isSpace(c byte) bool      { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+func isIdentStart(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || isUpper(c) }
+func isIdentChar(c byte) bool  { return isIdentStart(c) || isDigit(c) }
@@ -0,0 +1,1289 @@
+package nativeerb
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// objectMethods are methods that OpenStruct fields may or may not
+// shadow depending on Ruby version
+var objectMethods = map[string]bool{
+	"class": true, "clone": true, "define_singleton_method": true, "delete_field": true,
+	"dig": true, "display": true, "dup": true, "each_pair": true, "enum_for": true,
+	"eql?": true, "equal?": true, "extend": true, "freeze": true, "frozen?": true,
+	"hash": true, "inspect": true, "instance_eval": true, "instance_exec": true,
+	"instance_of?": true, "instance_variable_get": true, "instance_variable_set": true,
+	"instance_variables": true, "is_a?": true, "itself": true, "kind_of?": true,
+	"method": true, "methods": true, "nil?": true, "object_id": true,
+	"public_method": true, "public_send": true, "respond_to?": true, "send": true,
+	"singleton_class": true, "singleton_methods": true, "tap": true, "then": true,
+	"to_enum": true, "to_h": true, "to_json": true, "to_s": true, "to_yaml": true,
+	"yield_self": true,
+}
+
+// callFunction calls a method without receiver, i.e. on TemplateEvaluationContext
+func (e *evaluator) callFunction(name string, args []value, blk *block) (value, error) {
+	c := e.context
+
+	switch name {
+	case "p":
+		return p(c.rawProperties, args)
+
+	case "if_p":
+		return e.ifP(c, c.rawProperties, args, blk)
+
+	case "link":
+		if len(args) != 1 {
+			return nil, unsupported(0, "'link with %d arguments'", len(args))
+		}
+
+		return c.link(args[0])
+
+	case "if_link":
+		if len(args) != 1 {
+			return nil, unsupported(0, "'if_link with %d arguments'", len(args))
+		}
+
+		spec, err := c.linkSpec(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if spec == nil {
+			return &elseBlock{active: true, receiver: c}, nil
+		}
+
+		link, err := newEvaluationLink(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		if blk == nil {
+			return nil, unsupported(0, "'if_link without block'")
+		}
+
+		_, err = e.yield(blk, []value{link})
+		if err != nil {
+			return nil, err
+		}
+
+		return &elseBlock{active: false}, nil
+
+	case "raise":
+		switch {
+		case len(args) == 0:
+			return nil, RubyError{Class: "RuntimeError", Message: "unhandled exception"}
+		case len(args) == 1:
+			if message, ok := args[0].(string); ok {
+				return nil, RubyError{Class: "RuntimeError", Message: message}
+			}
+		}
+
+		return nil, unsupported(0, "'raise with %s'", describeArgs(args))
+
+	case "require":
+		if len(args) == 1 {
+			switch args[0] {
+			case "json", "yaml", "ostruct":
+				return false, nil
+			}
+		}
+
+		return nil, unsupported(0, "'require with %s'", describeArgs(args))
+	}
+
+	if len(args) == 0 {
+		switch name {
+		case "name":
+			return c.name, nil
+		case "index":
+			return c.index, nil
+		case "spec":
+			return c.spec, nil
+		case "properties":
+			return c.properties, nil
+		case "raw_properties":
+			return c.rawProperties, nil
+		}
+	}
+
+	return nil, unsupported(0, "'method %s'", name)
+}
+
+func (e *evaluator) callMethod(recv value, name string, args []value, blk *block) (value, error) {
+	if os, ok := recv.(*openStruct); ok {
+		v, found, err := os.fields.get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			if objectMethods[name] {
+				return nil, unsupported(0, "'OpenStruct field %s'", name)
+			}
+
+			if len(args) == 0 && blk == nil {
+				return v, nil
+			}
+		}
+	}
+
+	switch name {
+	case "nil?":
+		if len(args) == 0 {
+			return recv == nil, nil
+		}
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		if len(args) == 1 {
+			return binaryOp(name, recv, args[0])
+		}
+
+	case "is_a?", "kind_of?", "instance_of?":
+		if len(args) == 1 {
+			return isA(recv, args[0], name == "instance_of?")
+		}
+
+	case "to_s":
+		if len(args) == 0 {
+			return toS(recv)
+		}
+
+	case "inspect":
+		if len(args) == 0 {
+			return inspect(recv)
+		}
+
+	case "to_json":
+		if len(args) == 0 {
+			return generateJSON(recv, false)
+		}
+
+	case "to_yaml":
+		if len(args) == 0 {
+			return toYAML(recv)
+		}
+
+	case "freeze", "itself":
+		if len(args) == 0 {
+			return recv, nil
+		}
+	}
+
+	switch typed := recv.(type) {
+	case nil:
+		return nilMethod(name, args)
+	case int64:
+		return e.intMethod(typed, name, args, blk)
+	case float64:
+		return floatMethod(typed, name, args)
+	case string:
+		return stringMethod(typed, name, args, blk)
+	case symbol:
+		if name == "to_sym" && len(args) == 0 {
+			return typed, nil
+		}
+	case *array:
+		return e.arrayMethod(typed, name, args, blk)
+	case *hash:
+		return e.hashMethod(typed, name, args, blk)
+	case *openStruct:
+		return e.openStructMethod(typed, name, args, blk)
+	case *evaluationLink:
+		return e.linkMethod(typed, name, args, blk)
+	case *linkInstance:
+		if len(args) == 0 {
+			if v, found, _ := typed.fields.get(name); found { //nolint:errcheck
+				return v, nil
+			}
+		}
+	case *elseBlock:
+		return e.elseBlockMethod(typed, name, args, blk)
+	case constant:
+		return constMethod(typed, name, args)
+	}
+
+	return nil, unsupported(0, "'%s#%s'", typeName(recv), name)
+}
+
+func describeArgs(args []value) string {
+	names := make([]string, len(args))
+
+	for i, arg := range args {
+		names[i] = typeName(arg)
+	}
+
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func isA(v value, class value, exact bool) (value, error) {
+	c, ok := class.(constant)
+	if !ok {
+		return nil, unsupported(0, "'is_a? with %s'", typeName(class))
+	}
+
+	if c == "Numeric" {
+		switch v.(type) {
+		case int64, float64:
+			return !exact, nil
+		}
+
+		return false, nil
+	}
+
+	switch c {
+	case "Hash", "Array", "String", "Integer", "Float", "NilClass",
+		"TrueClass", "FalseClass", "Symbol", "OpenStruct":
+		return typeName(v) == string(c), nil
+	}
+
+	return nil, unsupported(0, "'is_a? with %s'", string(c))
+}
+
+func nilMethod(name string, args []value) (value, error) {
+	if len(args) == 0 {
+		switch name {
+		case "to_a":
+			return newArray(), nil
+		case "to_i":
+			return int64(0), nil
+		case "to_f":
+			return 0.0, nil
+		}
+	}
+
+	return nil, unsupported(0, "'NilClass#%s'", name)
+}
+
+func (e *evaluator) intMethod(i int64, name string, args []value, blk *block) (value, error) {
+	if len(args) == 0 {
+		switch name {
+		case "to_i", "to_int", "floor", "ceil", "round", "truncate":
+			return i, nil
+		case "to_f":
+			return float64(i), nil
+		case "zero?":
+			return i == 0, nil
+		case "positive?":
+			return i > 0, nil
+		case "negative?":
+			return i < 0, nil
+		case "even?":
+			return i%2 == 0, nil
+		case "odd?":
+			return i%2 != 0, nil
+		case "abs":
+			if i == math.MinInt64 {
+				return nil, unsupported(0, "'integer overflow'")
+			}
+			if i < 0 {
+				return -i, nil
+			}
+			return i, nil
+		case "succ", "next":
+			return intOp("+", i, 1)
+		case "pred":
+			return intOp("-", i, 1)
+		case "times":
+			if blk == nil {
+				break
+			}
+
+			for n := int64(0); n < i; n++ {
+				_, err := e.yield(blk, []value{n})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return i, nil
+		}
+	}
+
+	if len(args) == 1 {
+		switch name {
+		case "upto":
+			limit, ok := args[0].(int64)
+			if !ok || blk == nil {
+				break
+			}
+
+			for n := i; n <= limit; n++ {
+				_, err := e.yield(blk, []value{n})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return i, nil
+		case "to_s":
+			base, ok := args[0].(int64)
+			if !ok || base < 2 || base > 36 {
+				break
+			}
+
+			return strconv.FormatInt(i, int(base)), nil
+		}
+	}
+
+	return nil, unsupported(0, "'Integer#%s'", name)
+}
+
+func floatMethod(f float64, name string, args []value) (value, error) {
+	if len(args) == 0 {
+		switch name {
+		case "to_f":
+			return f, nil
+		case "to_i", "to_int", "truncate":
+			return floatToInt(math.Trunc(f))
+		case "floor":
+			return floatToInt(math.Floor(f))
+		case "ceil":
+			return floatToInt(math.Ceil(f))
+		case "round":
+			// Float#round rounds half away from zero
+			return floatToInt(math.Round(f))
+		case "abs":
+			return math.Abs(f), nil
+		case "zero?":
+			return f == 0, nil
+		case "nan?":
+			return math.IsNaN(f), nil
+		case "finite?":
+			return !math.IsNaN(f) && !math.IsInf(f, 0), nil
+		}
+	}
+
+	return nil, unsupported(0, "'Float#%s'", name)
+}
+
+func floatToInt(f float64) (value, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return nil, unsupported(0, "'conversion of %s to Integer'", formatFloat(f))
+	}
+
+	return int64(f), nil
+}
+
+func stringMethod(s string, name string, args []value, blk *block) (value, error) {
+	if !utf8.ValidString(s) {
+		return nil, unsupported(0, "'String#%s of invalid UTF-8 string'", name)
+	}
+
+	if blk != nil {
+		return nil, unsupported(0, "'String#%s with block'", name)
+	}
+
+	if len(args) == 0 {
+		switch name {
+		case "length", "size":
+			return int64(utf8.RuneCountInString(s)), nil
+		case "bytesize":
+			return int64(len(s)), nil
+		case "empty?":
+			return len(s) == 0, nil
+		case "to_str", "dup", "clone":
+			return s, nil
+		case "to_sym":
+			return symbol(s), nil
+		case "to_i":
+			return stringToInt(s)
+		case "upcase", "downcase", "capitalize":
+			if !isASCII(s) {
+				return nil, unsupported(0, "'String#%s of non-ASCII string'", name)
+			}
+
+			switch name {
+			case "upcase":
+				return strings.ToUpper(s), nil
+			case "downcase":
+				return strings.ToLower(s), nil
+			}
+
+			if len(s) == 0 {
+				return s, nil
+			}
+
+			return strings.ToUpper(s[:1]) + strings.ToLower(s[1:]), nil
+		case "strip", "lstrip", "rstrip":
+			if strings.Contains(s, "\x00") {
+				return nil, unsupported(0, "'String#%s of string with null characters'", name)
+			}
+
+			switch name {
+			case "strip":
+				return strings.Trim(s, rubySpace), nil
+			case "lstrip":
+				return strings.TrimLeft(s, rubySpace), nil
+			}
+
+			return strings.TrimRight(s, rubySpace), nil
+		case "chomp":
+			switch {
+			case strings.HasSuffix(s, "\r\n"):
+				return s[:len(s)-2], nil
+			case strings.HasSuffix(s, "\n"), strings.HasSuffix(s, "\r"):
+				return s[:len(s)-1], nil
+			}
+
+			return s, nil
+		case "split":
+			return splitWhitespace(s), nil
+		}
+	}
+
+	strArgs := make([]string, len(args))
+
+	for i, arg := range args {
+		str, ok := arg.(string)
+		if !ok {
+			return nil, unsupported(0, "'String#%s with %s'", name, describeArgs(args))
+		}
+
+		strArgs[i] = str
+	}
+
+	switch {
+	case name == "start_with?" && len(args) > 0:
+		for _, prefix := range strArgs {
+			if strings.HasPrefix(s, prefix) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+
+	case name == "end_with?" && len(args) > 0:
+		for _, suffix := range strArgs {
+			if strings.HasSuffix(s, suffix) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+
+	case name == "include?" && len(args) == 1:
+		return strings.Contains(s, strArgs[0]), nil
+
+	case name == "chomp" && len(args) == 1 && len(strArgs[0]) > 0:
+		return strings.TrimSuffix(s, strArgs[0]), nil
+
+	case name == "split" && len(args) == 1:
+		if strArgs[0] == " " {
+			return splitWhitespace(s), nil
+		}
+
+		items := []value{}
+		for _, item := range splitString(s, strArgs[0]) {
+			items = append(items, item)
+		}
+
+		return newArray(items...), nil
+
+	case (name == "gsub" || name == "sub") && len(args) == 2:
+		if strings.Contains(strArgs[1], "\\") {
+			return nil, unsupported(0, "'String#%s with back references'", name)
+		}
+
+		if name == "sub" {
+			return strings.Replace(s, strArgs[0], strArgs[1], 1), nil
+		}
+
+		return strings.ReplaceAll(s, strArgs[0], strArgs[1]), nil
+	}
+
+	return nil, unsupported(0, "'String#%s'", name)
+}
+
+const rubySpace = " \t\n\v\f\r"
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitString mirrors String#split with a string separator
+// which drops trailing empty fields
+func splitString(s, sep string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	var fields []string
+
+	if len(sep) == 0 {
+		for _, r := range s {
+			fields = append(fields, string(r))
+		}
+	} else {
+		fields = strings.Split(s, sep)
+	}
+
+	for len(fields) > 0 && len(fields[len(fields)-1]) == 0 {
+		fields = fields[:len(fields)-1]
+	}
+
+	return fields
+}
+
+// splitWhitespace mirrors String#split with awk-style splitting
+func splitWhitespace(s string) value {
+	result := newArray()
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r < utf8.RuneSelf && strings.ContainsRune(rubySpace, r)
+	})
+
+	for _, field := range fields {
+		result.items = append(result.items, field)
+	}
+
+	return result
+}
+
+// stringToInt mirrors String#to_i
+func stringToInt(s string) (value, error) {
+	s = strings.TrimLeft(s, rubySpace)
+
+	negative := false
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	var digits strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case isDigit(s[i]):
+			digits.WriteByte(s[i])
+		case s[i] == '_' && digits.Len() > 0 && i+1 < len(s) && isDigit(s[i+1]):
+			continue
+		default:
+			i = len(s)
+		}
+	}
+
+	if digits.Len() == 0 {
+		return int64(0), nil
+	}
+
+	literal := digits.String()
+	if negative {
+		literal = "-" + literal
+	}
+
+	i, err := strconv.ParseInt(literal, 10, 64)
+	if err != nil {
+		return nil, unsupported(0, "'integer overflow'")
+	}
+
+	return i, nil
+}
+
+func (e *evaluator) arrayMethod(a *array, name string, args []value, blk *block) (value, error) {
+	if blk == nil {
+		return arrayMethodWithoutBlock(a, name, args)
+	}
+
+	if len(args) > 0 {
+		return nil, unsupported(0, "'Array#%s with arguments and block'", name)
+	}
+
+	switch name {
+	case "each":
+		for _, item := range a.items {
+			_, err := e.yield(blk, []value{item})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return a, nil
+
+	case "each_with_index":
+		for i, item := range a.items {
+			_, err := e.yield(blk, []value{item, int64(i)})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return a, nil
+
+	case "map", "collect", "flat_map", "collect_concat":
+		result := newArray()
+
+		for _, item := range a.items {
+			v, err := e.yield(blk, []value{item})
+			if err != nil {
+				return nil, err
+			}
+
+			if nested, ok := v.(*array); ok && (name == "flat_map" || name == "collect_concat") {
+				result.items = append(result.items, nested.items...)
+			} else {
+				result.items = append(result.items, v)
+			}
+		}
+
+		return result, nil
+
+	case "select", "filter", "reject":
+		result := newArray()
+
+		for _, item := range a.items {
+			v, err := e.yield(blk, []value{item})
+			if err != nil {
+				return nil, err
+			}
+
+			if truthy(v) == (name != "reject") {
+				result.items = append(result.items, item)
+			}
+		}
+
+		return result, nil
+
+	case "find", "detect":
+		for _, item := range a.items {
+			v, err := e.yield(blk, []value{item})
+			if err != nil {
+				return nil, err
+			}
+
+			if truthy(v) {
+				return item, nil
+			}
+		}
+
+		return nil, nil
+
+	case "any?", "all?", "none?", "count":
+		var matched int64
+
+		for _, item := range a.items {
+			v, err := e.yield(blk, []value{item})
+			if err != nil {
+				return nil, err
+			}
+
+			if truthy(v) {
+				matched++
+			}
+		}
+
+		return countResult(name, matched, len(a.items)), nil
+
+	case "sort_by":
+		keys := make([]value, len(a.items))
+
+		for i, item := range a.items {
+			v, err := e.yield(blk, []value{item})
+			if err != nil {
+				return nil, err
+			}
+
+			keys[i] = v
+		}
+
+		return sortByKeys(a.items, keys)
+	}
+
+	return nil, unsupported(0, "'Array#%s with block'", name)
+}
+
+func countResult(name string, matched int64, total int) value {
+	switch name {
+	case "any?":
+		return matched > 0
+	case "all?":
+		return matched == int64(total)
+	case "none?":
+		return matched == 0
+	}
+
+	return matched
+}
+
+// sortByKeys sorts items by keys, Ruby's sort is not stable
+// so items with equal keys could end up in any order
+func sortByKeys(items []value, keys []value) (value, error) {
+	indices := make([]int, len(items))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	var sortErr error
+
+	sort.SliceStable(indices, func(i, j int) bool {
+		c, err := compare(keys[indices[i]], keys[indices[j]])
+		if err != nil {
+			sortErr = err
+		}
+
+		return c < 0
+	})
+
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	result := newArray()
+
+	for i, index := range indices {
+		if i > 0 {
+			c, err := compare(keys[indices[i-1]], keys[index])
+			if err != nil {
+				return nil, err
+			}
+
+			previous := items[indices[i-1]]
+			if c == 0 && (typeName(previous) != typeName(items[index]) || !equal(previous, items[index])) {
+				return nil, unsupported(0, "'sort with equal keys'")
+			}
+		}
+
+		result.items = append(result.items, items[index])
+	}
+
+	return result, nil
+}
+
+func arrayMethodWithoutBlock(a *array, name string, args []value) (value, error) {
+	if len(args) == 0 {
+		switch name {
+		case "size", "length", "count":
+			return int64(len(a.items)), nil
+		case "empty?":
+			return len(a.items) == 0, nil
+		case "any?", "all?", "none?":
+			var matched int64
+
+			for _, item := range a.items {
+				if truthy(item) {
+					matched++
+				}
+			}
+
+			return countResult(name, matched, len(a.items)), nil
+		case "first":
+			if len(a.items) == 0 {
+				return nil, nil
+			}
+			return a.items[0], nil
+		case "last":
+			if len(a.items) == 0 {
+				return nil, nil
+			}
+			return a.items[len(a.items)-1], nil
+		case "to_a", "entries":
+			return a, nil
+		case "dup", "clone":
+			return newArray(append([]value{}, a.items...)...), nil
+		case "reverse":
+			result := newArray()
+			for i := len(a.items) - 1; i >= 0; i-- {
+				result.items = append(result.items, a.items[i])
+			}
+			return result, nil
+		case "compact":
+			result := newArray()
+			for _, item := range a.items {
+				if item != nil {
+					result.items = append(result.items, item)
+				}
+			}
+			return result, nil
+		case "flatten":
+			result := newArray()
+			flatten(result, a)
+			return result, nil
+		case "uniq":
+			return uniq(a)
+		case "sort":
+			return sortByKeys(a.items, a.items)
+		case "min", "max":
+			var result value
+
+			for i, item := range a.items {
+				if i == 0 {
+					result = item
+					continue
+				}
+
+				c, err := compare(item, result)
+				if err != nil {
+					return nil, err
+				}
+
+				if (name == "min" && c < 0) || (name == "max" && c > 0) {
+					result = item
+				}
+			}
+
+			return result, nil
+		case "sum":
+			var sum value = int64(0)
+
+			for _, item := range a.items {
+				if _, ok := item.(int64); !ok {
+					return nil, unsupported(0, "'Array#sum of %s'", typeName(item))
+				}
+
+				var err error
+
+				sum, err = binaryOp("+", sum, item)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return sum, nil
+		case "join":
+			return join(a, "")
+		}
+	}
+
+	if len(args) == 1 {
+		switch name {
+		case "include?", "member?":
+			for _, item := range a.items {
+				if equal(item, args[0]) {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		case "count":
+			var matched int64
+
+			for _, item := range a.items {
+				if equal(item, args[0]) {
+					matched++
+				}
+			}
+
+			return matched, nil
+		case "join":
+			sep, ok := args[0].(string)
+			if !ok {
+				break
+			}
+
+			return join(a, sep)
+		case "first", "last", "take", "drop":
+			n, ok := args[0].(int64)
+			if !ok || n < 0 {
+				break
+			}
+
+			if n > int64(len(a.items)) {
+				n = int64(len(a.items))
+			}
+
+			var items []value
+
+			switch name {
+			case "first", "take":
+				items = a.items[:n]
+			case "last":
+				items = a.items[int64(len(a.items))-n:]
+			default:
+				items = a.items[n:]
+			}
+
+			return newArray(append([]value{}, items...)...), nil
+		}
+	}
+
+	if len(args) > 0 {
+		switch name {
+		case "push", "append":
+			a.items = append(a.items, args...)
+			return a, nil
+		}
+	}
+
+	return nil, unsupported(0, "'Array#%s'", name)
+}
+
+func flatten(dst *array, src *array) {
+	for _, item := range src.items {
+		if nested, ok := item.(*array); ok {
+			flatten(dst, nested)
+		} else {
+			dst.items = append(dst.items, item)
+		}
+	}
+}
+
+func uniq(a *array) (value, error) {
+	seen := map[hashKey]bool{}
+	result := newArray()
+
+	for _, item := range a.items {
+		key, ok := keyOf(item)
+		if !ok {
+			return nil, unsupported(0, "'Array#uniq with %s elements'", typeName(item))
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			result.items = append(result.items, item)
+		}
+	}
+
+	return result, nil
+}
+
+// join mirrors Array#join which joins nested arrays recursively
+func join(a *array, sep string) (value, error) {
+	parts := make([]string, len(a.items))
+
+	for i, item := range a.items {
+		var err error
+
+		if nested, ok := item.(*array); ok {
+			var joined value
+
+			joined, err = join(nested, sep)
+			if err == nil {
+				parts[i] = joined.(string)
+			}
+		} else {
+			parts[i], err = toS(item)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return strings.Join(parts, sep), nil
+}
+
+func (e *evaluator) hashMethod(h *hash, name string, args []value, blk *block) (value, error) {
+	if blk != nil && len(args) == 0 {
+		return e.hashMethodWithBlock(h, name, blk)
+	}
+
+	if blk != nil && name != "fetch" {
+		return nil, unsupported(0, "'Hash#%s with arguments and block'", name)
+	}
+
+	if len(args) == 0 {
+		switch name {
+		case "size", "length", "count":
+			return int64(h.size()), nil
+		case "empty?":
+			return h.size() == 0, nil
+		case "any?":
+			return h.size() > 0, nil
+		case "keys":
+			return newArray(append([]value{}, h.keys...)...), nil
+		case "values":
+			return newArray(append([]value{}, h.vals...)...), nil
+		case "to_h", "dup", "clone":
+			return h.dup(), nil
+		case "to_a":
+			return h.pairs(), nil
+		case "sort":
+			pairs := h.pairs()
+			return sortByKeys(pairs.items, pairs.items)
+		}
+	}
+
+	switch name {
+	case "key?", "has_key?", "include?", "member?":
+		if len(args) == 1 {
+			_, found, err := h.get(args[0])
+			return found, err
+		}
+
+	case "fetch":
+		if len(args) < 1 || len(args) > 2 {
+			break
+		}
+
+		v, found, err := h.get(args[0])
+		if err != nil || found {
+			return v, err
+		}
+
+		switch {
+		case len(args) == 2:
+			return args[1], nil
+		case blk != nil:
+			return e.yield(blk, []value{args[0]})
+		}
+
+		key, err := inspect(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, RubyError{Class: "KeyError", Message: "key not found: " + key}
+
+	case "dig":
+		if len(args) > 0 {
+			return dig(h, args)
+		}
+
+	case "merge":
+		result := h.dup()
+
+		for _, arg := range args {
+			other, ok := arg.(*hash)
+			if !ok {
+				return nil, unsupported(0, "'Hash#merge with %s'", typeName(arg))
+			}
+
+			for i, k := range other.keys {
+				result.set(k, other.vals[i]) //nolint:errcheck
+			}
+		}
+
+		return result, nil
+	}
+
+	return nil, unsupported(0, "'Hash#%s'", name)
+}
+
+func (e *evaluator) hashMethodWithBlock(h *hash, name string, blk *block) (value, error) {
+	switch name {
+	case "each", "each_pair":
+		for i, k := range h.keys {
+			_, err := e.yield(blk, []value{newArray(k, h.vals[i])})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return h, nil
+
+	case "map", "collect", "flat_map", "collect_concat", "find", "detect", "any?", "all?", "none?", "count", "sort_by":
+		return e.arrayMethod(h.pairs(), name, nil, blk)
+
+	case "select", "filter", "reject":
+		result := newHash()
+
+		for i, k := range h.keys {
+			v, err := e.yield(blk, []value{k, h.vals[i]})
+			if err != nil {
+				return nil, err
+			}
+
+			if truthy(v) == (name != "reject") {
+				result.set(k, h.vals[i]) //nolint:errcheck
+			}
+		}
+
+		return result, nil
+
+	case "transform_values":
+		result := newHash()
+
+		for i, k := range h.keys {
+			v, err := e.yield(blk, []value{h.vals[i]})
+			if err != nil {
+				return nil, err
+			}
+
+			result.set(k, v) //nolint:errcheck
+		}
+
+		return result, nil
+	}
+
+	return nil, unsupported(0, "'Hash#%s with block'", name)
+}
+
+func (h *hash) pairs() *array {
+	result := newArray()
+
+	for i, k := range h.keys {
+		result.items = append(result.items, newArray(k, h.vals[i]))
+	}
+
+	return result
+}
+
+// dig mirrors Hash#dig, Array#dig and OpenStruct#dig
+func dig(v value, keys []value) (value, error) {
+	for _, key := range keys {
+		var err error
+
+		switch typed := v.(type) {
+		case nil:
+			return nil, nil
+		case *hash:
+			v, _, err = typed.get(key)
+		case *array:
+			i, ok := key.(int64)
+			if !ok {
+				return nil, unsupported(0, "'Array#dig with %s'", typeName(key))
+			}
+
+			if i < 0 {
+				i += int64(len(typed.items))
+			}
+
+			v = nil
+			if i >= 0 && i < int64(len(typed.items)) {
+				v = typed.items[i]
+			}
+		case *openStruct:
+			v, err = typed.field(key)
+		default:
+			return nil, unsupported(0, "'dig on %s'", typeName(v))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (os *openStruct) field(name value) (value, error) {
+	switch typed := name.(type) {
+	case string:
+		return os.fields.getString(typed), nil
+	case symbol:
+		return os.fields.getString(string(typed)), nil
+	}
+
+	return nil, unsupported(0, "'OpenStruct#[] with %s'", typeName(name))
+}
+
+func (e *evaluator) openStructMethod(os *openStruct, name string, args []value, blk *block) (value, error) {
+	switch {
+	case name == "to_h" && len(args) == 0 && blk == nil:
+		result := newHash()
+
+		for i, k := range os.fields.keys {
+			result.set(symbol(k.(string)), os.fields.vals[i]) //nolint:errcheck
+		}
+
+		return result, nil
+
+	case name == "each_pair" && len(args) == 0 && blk != nil:
+		for i, k := range os.fields.keys {
+			_, err := e.yield(blk, []value{newArray(symbol(k.(string)), os.fields.vals[i])})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return os, nil
+
+	case name == "dig" && len(args) > 0 && blk == nil:
+		return dig(os, args)
+
+	case objectMethods[name]:
+		return nil, unsupported(0, "'OpenStruct#%s'", name)
+
+	case len(args) == 0 && blk == nil && !strings.HasSuffix(name, "?") && !strings.HasSuffix(name, "!"):
+		// missing fields read as nil
+		return nil, nil
+	}
+
+	return nil, unsupported(0, "'OpenStruct#%s'", name)
+}
+
+func (e *evaluator) linkMethod(link *evaluationLink, name string, args []value, blk *block) (value, error) {
+	switch name {
+	case "instances":
+		if len(args) == 0 {
+			return link.instances, nil
+		}
+	case "properties":
+		if len(args) == 0 {
+			return link.properties, nil
+		}
+	case "address":
+		if len(args) <= 1 {
+			return link.address, nil
+		}
+	case "p":
+		return p(link.properties, args)
+	case "if_p":
+		return e.ifP(link, link.properties, args, blk)
+	}
+
+	return nil, unsupported(0, "'EvaluationLink#%s'", name)
+}
+
+func (e *evaluator) elseBlockMethod(b *elseBlock, name string, args []value, blk *block) (value, error) {
+	switch name {
+	case "else":
+		if len(args) > 0 {
+			break
+		}
+
+		if !b.active {
+			return nil, nil
+		}
+
+		if blk == nil {
+			return nil, unsupported(0, "'else without block'")
+		}
+
+		return e.yield(blk, nil)
+
+	case "else_if_p":
+		if !b.active {
+			return &elseBlock{active: false}, nil
+		}
+
+		switch receiver := b.receiver.(type) {
+		case *Context:
+			return e.ifP(receiver, receiver.rawProperties, args, blk)
+		case *evaluationLink:
+			return e.ifP(receiver, receiver.properties, args, blk)
+		}
+	}
+
+	return nil, unsupported(0, "'ElseBlock#%s'", name)
+}
+
+func constMethod(c constant, name string, args []value) (value, error) {
+	if len(args) != 1 {
+		return nil, unsupported(0, "'%s.%s with %d arguments'", string(c), name, len(args))
+	}
+
+	switch c {
+	case "JSON":
+		switch name {
+		case "dump":
+			// JSON.dump is overridden to inspect strings and numbers
+			switch args[0].(type) {
+			case string, int64, float64:
+				return inspect(args[0])
+			}
+
+			return generateJSON(args[0], false)
+		case "generate":
+			return generateJSON(args[0], false)
+		case "pretty_generate":
+			return generateJSON(args[0], true)
+		}
+
+	case "YAML", "Psych":
+		if name == "dump" {
+			return toYAML(args[0])
+		}
+	}
+
+	return nil, unsupported(0, "'%s.%s'", string(c), name)
+}
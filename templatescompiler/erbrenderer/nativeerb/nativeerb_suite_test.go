@@ -0,0 +1,13 @@
+package nativeerb_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNativeerb(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Nativeerb Suite")
+}
@@ -0,0 +1,1316 @@
+package nativeerb
+
+type node interface {
+	pos() int
+}
+
+type position struct{ line int }
+
+func (p position) pos() int { return p.line }
+
+type (
+	textNode struct {
+		position
+		text string
+	}
+
+	outputNode struct {
+		position
+		expr node
+	}
+
+	literalNode struct {
+		position
+		val value
+	}
+
+	stringNode struct {
+		position
+		parts []node
+	}
+
+	arrayNode struct {
+		position
+		items []node
+	}
+
+	hashNode struct {
+		position
+		keys []node
+		vals []node
+	}
+
+	varNode struct {
+		position
+		name string
+	}
+
+	constNode struct {
+		position
+		name string
+	}
+
+	assignNode struct {
+		position
+		name string
+		op   string
+		val  node
+	}
+
+	indexAssignNode struct {
+		position
+		recv  node
+		index []node
+		val   node
+	}
+
+	callNode struct {
+		position
+		recv  node
+		name  string
+		args  []node
+		block *blockNode
+		safe  bool
+	}
+
+	indexNode struct {
+		position
+		recv node
+		args []node
+	}
+
+	binaryNode struct {
+		position
+		op   string
+		l, r node
+	}
+
+	andNode struct {
+		position
+		l, r node
+	}
+
+	orNode struct {
+		position
+		l, r node
+	}
+
+	notNode struct {
+		position
+		x node
+	}
+
+	ifNode struct {
+		position
+		cond node
+		then []node
+		els  []node
+	}
+
+	blockNode struct {
+		position
+		params []string
+		body   []node
+
+		// symbol is set for '&:name' block arguments
+		symbol string
+	}
+)
+
+type parseScope struct {
+	vars   map[string]bool
+	parent *parseScope
+}
+
+func newParseScope(parent *parseScope) *parseScope {
+	return &parseScope{vars: map[string]bool{}, parent: parent}
+}
+
+func (s *parseScope) defined(name string) bool {
+	for scope := s; scope != nil; scope = scope.parent {
+		if scope.vars[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	scope  *parseScope
+
+	// noDo is set while parsing command arguments
+	// since 'do' blocks bind to the outermost command
+	noDo bool
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.kind != tEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) isOp(val string) bool {
+	t := p.peek()
+	return t.kind == tOp && t.val == val
+}
+
+func (p *parser) isKeyword(val string) bool {
+	t := p.peek()
+	return t.kind == tIdent && t.val == val
+}
+
+func (p *parser) acceptOp(val string) bool {
+	if p.isOp(val) {
+		p.next()
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectOp(val string) error {
+	if !p.acceptOp(val) {
+		return p.unexpected()
+	}
+
+	return nil
+}
+
+func (p *parser) expectKeyword(val string) error {
+	if !p.isKeyword(val) {
+		return p.unexpected()
+	}
+
+	p.next()
+
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+
+	switch t.kind {
+	case tEOF:
+		return unsupported(t.line, "'unexpected end of template'")
+	case tNewline:
+		return unsupported(t.line, "'unexpected end of line'")
+	case tText:
+		return unsupported(t.line, "'unexpected template text'")
+	case tOutput, tOutputEnd:
+		return unsupported(t.line, "'code spanning output tag'")
+	case tString:
+		return unsupported(t.line, "'unexpected string'")
+	default:
+		return unsupported(t.line, "'%s'", t.val)
+	}
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tNewline {
+		p.next()
+	}
+}
+
+func (p *parser) parseProgram() ([]node, error) {
+	stmts, err := p.parseStmts()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tEOF {
+		return nil, p.unexpected()
+	}
+
+	return stmts, nil
+}
+
+// parseStmts parses statements until a token that ends a statement list
+func (p *parser) parseStmts() ([]node, error) {
+	var stmts []node
+
+	for {
+		p.skipNewlines()
+
+		t := p.peek()
+
+		if t.kind == tEOF || (t.kind == tOp && t.val == "}") || (t.kind == tOp && t.val == ")") {
+			return stmts, nil
+		}
+
+		if t.kind == tIdent && (t.val == "end" || t.val == "else" || t.val == "elsif" || t.val == "when" || t.val == "rescue" || t.val == "ensure") {
+			return stmts, nil
+		}
+
+		stmt, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+
+		stmts = append(stmts, stmt)
+
+		// template text and output tags delimit statements themselves
+		switch stmt.(type) {
+		case *textNode, *outputNode:
+			continue
+		}
+
+		t = p.peek()
+
+		if t.kind != tNewline && t.kind != tEOF && t.kind != tText && t.kind != tOutput &&
+			!(t.kind == tOp && (t.val == "}" || t.val == ")")) &&
+			!(t.kind == tIdent && (t.val == "end" || t.val == "else" || t.val == "elsif")) {
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *parser) parseStmt() (node, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tText:
+		p.next()
+		return &textNode{position{t.line}, t.val}, nil
+
+	case tOutput:
+		p.next()
+		p.skipNewlines()
+
+		expr, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipNewlines()
+
+		if p.peek().kind != tOutputEnd {
+			return nil, p.unexpected()
+		}
+
+		p.next()
+
+		return &outputNode{position{t.line}, expr}, nil
+	}
+
+	stmt, err := p.parseNotExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("if") || p.isKeyword("unless") || p.isKeyword("while") || p.isKeyword("until") || p.isKeyword("rescue") {
+		modifier := p.next()
+
+		if modifier.val != "if" && modifier.val != "unless" {
+			return nil, unsupported(modifier.line, "'%s modifier'", modifier.val)
+		}
+
+		cond, err := p.parseNotExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if modifier.val == "unless" {
+			cond = &notNode{position{modifier.line}, cond}
+		}
+
+		stmt = &ifNode{position: position{modifier.line}, cond: cond, then: []node{stmt}}
+	}
+
+	return stmt, nil
+}
+
+// parseNotExpr handles low precedence 'and', 'or' and 'not'
+func (p *parser) parseNotExpr() (node, error) {
+	left, err := p.parseLowNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") || p.isKeyword("or") {
+		op := p.next()
+		p.skipNewlines()
+
+		right, err := p.parseLowNot()
+		if err != nil {
+			return nil, err
+		}
+
+		if op.val == "and" {
+			left = &andNode{position{op.line}, left, right}
+		} else {
+			left = &orNode{position{op.line}, left, right}
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseLowNot() (node, error) {
+	if p.isKeyword("not") {
+		t := p.next()
+
+		x, err := p.parseLowNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{position{t.line}, x}, nil
+	}
+
+	return p.parseExpr()
+}
+
+// parseExpr parses assignments and everything of higher precedence
+func (p *parser) parseExpr() (node, error) {
+	t := p.peek()
+
+	if t.kind == tIdent && !isKeyword(t.val) && p.peekAt(1).kind == tOp {
+		switch op := p.peekAt(1).val; op {
+		case "=", "||=", "&&=", "+=", "-=", "*=", "<<=":
+			if op == "<<=" {
+				return nil, unsupported(t.line, "'%s'", op)
+			}
+
+			p.next()
+			p.next()
+			p.skipNewlines()
+
+			if op != "=" && !p.scope.defined(t.val) {
+				if op != "||=" {
+					return nil, unsupported(t.line, "'%s on undefined variable'", op)
+				}
+			}
+
+			// Ruby defines the variable before evaluating the right hand side
+			p.scope.vars[t.val] = true
+
+			val, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			return &assignNode{position{t.line}, t.val, op, val}, nil
+		}
+	}
+
+	if t.kind == tIdent && !isKeyword(t.val) && p.peekAt(1).kind == tOp && p.peekAt(1).val == "," && !p.scope.defined(t.val) {
+		// 'a, b = ...' style multiple assignment
+		for i := 2; p.peekAt(i).kind != tNewline && p.peekAt(i).kind != tEOF && p.peekAt(i).kind != tOutputEnd; i++ {
+			if p.peekAt(i).kind == tOp && p.peekAt(i).val == "=" {
+				return nil, unsupported(t.line, "'multiple assignment'")
+			}
+		}
+	}
+
+	expr, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tOp {
+		switch op := p.peek(); op.val {
+		case "=":
+			index, ok := expr.(*indexNode)
+			if !ok {
+				return nil, unsupported(op.line, "'assignment to %s'", describeNode(expr))
+			}
+
+			p.next()
+			p.skipNewlines()
+
+			val, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			return &indexAssignNode{position{op.line}, index.recv, index.args, val}, nil
+
+		case "||=", "&&=", "+=", "-=", "*=", "/=", "%=", "**=", "<<=":
+			return nil, unsupported(op.line, "'%s on %s'", op.val, describeNode(expr))
+		}
+	}
+
+	return expr, nil
+}
+
+func describeNode(n node) string {
+	switch typed := n.(type) {
+	case *callNode:
+		return "method '" + typed.name + "'"
+	case *indexNode:
+		return "index"
+	default:
+		return "expression"
+	}
+}
+
+func (p *parser) parseTernary() (node, error) {
+	cond, err := p.parseRange()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOp("?") {
+		return cond, nil
+	}
+
+	t := p.next()
+	p.skipNewlines()
+
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+
+	if p.peek().kind == tLabel {
+		return nil, unsupported(t.line, "'ternary without spaces'")
+	}
+
+	err = p.expectOp(":")
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+
+	els, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ifNode{position{t.line}, cond, []node{then}, []node{els}}, nil
+}
+
+func (p *parser) parseRange() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isOp("..") || p.isOp("...") {
+		return nil, unsupported(p.peek().line, "'range'")
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("||") {
+		t := p.next()
+		p.skipNewlines()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &orNode{position{t.line}, left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("&&") {
+		t := p.next()
+		p.skipNewlines()
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &andNode{position{t.line}, left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isKeyword("defined?") {
+		return nil, unsupported(p.peek().line, "'defined?'")
+	}
+
+	return p.parseBinary(0)
+}
+
+var binaryPrecedence = [][]string{
+	{"<=>", "==", "===", "!=", "=~", "!~"},
+	{"<", "<=", ">", ">="},
+	{"|", "^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+var supportedBinary = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"<<": true, "+": true, "-": true, "*": true, "/": true, "%": true,
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnaryMinus()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+
+		if t.kind != tOp || !contains(binaryPrecedence[level], t.val) {
+			return left, nil
+		}
+
+		if !supportedBinary[t.val] {
+			return nil, unsupported(t.line, "'%s' operator", t.val)
+		}
+
+		p.next()
+		p.skipNewlines()
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{position{t.line}, t.val, left, right}
+
+		// comparison operators are not associative in Ruby
+		if level <= 1 && p.peek().kind == tOp && contains(binaryPrecedence[level], p.peek().val) {
+			return nil, unsupported(t.line, "'chained comparison'")
+		}
+	}
+}
+
+func (p *parser) parseUnaryMinus() (node, error) {
+	if p.isOp("-") {
+		t := p.next()
+
+		// negative numeric literals
+		if next := p.peek(); (next.kind == tInt || next.kind == tFloat) && !next.spaceBefore {
+			p.next()
+
+			if next.kind == tInt {
+				return p.parsePostfix(&literalNode{position{t.line}, -next.intVal})
+			}
+
+			return p.parsePostfix(&literalNode{position{t.line}, -next.floatVal})
+		}
+
+		x, err := p.parseUnaryMinus()
+		if err != nil {
+			return nil, err
+		}
+
+		return &binaryNode{position{t.line}, "-", &literalNode{position{t.line}, int64(0)}, x}, nil
+	}
+
+	return p.parsePow()
+}
+
+func (p *parser) parsePow() (node, error) {
+	base, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isOp("**") {
+		return nil, unsupported(p.peek().line, "'**' operator")
+	}
+
+	return base, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+
+	if t.kind == tOp {
+		switch t.val {
+		case "!":
+			p.next()
+
+			x, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+
+			return &notNode{position{t.line}, x}, nil
+
+		case "~", "+", "&", "*", "->", "::":
+			return nil, unsupported(t.line, "'unary %s'", t.val)
+		}
+	}
+
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parsePostfix(primary)
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tInt:
+		p.next()
+		return &literalNode{position{t.line}, t.intVal}, nil
+
+	case tFloat:
+		p.next()
+		return &literalNode{position{t.line}, t.floatVal}, nil
+
+	case tString:
+		p.next()
+		return p.stringNode(t)
+
+	case tSymbol:
+		p.next()
+		return &literalNode{position{t.line}, symbol(t.val)}, nil
+
+	case tWords:
+		p.next()
+
+		arr := &arrayNode{position: position{t.line}}
+		for _, word := range t.words {
+			arr.items = append(arr.items, &literalNode{position{t.line}, word})
+		}
+
+		return arr, nil
+
+	case tConst:
+		p.next()
+
+		if p.isOp("::") {
+			return nil, unsupported(t.line, "'%s::'", t.val)
+		}
+
+		return &constNode{position{t.line}, t.val}, nil
+
+	case tLabel:
+		return nil, unsupported(t.line, "'keyword arguments'")
+
+	case tIdent:
+		return p.parseIdentifier()
+
+	case tOp:
+		switch t.val {
+		case "(":
+			p.next()
+
+			stmts, err := p.parseStmts()
+			if err != nil {
+				return nil, err
+			}
+
+			err = p.expectOp(")")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(stmts) != 1 {
+				return nil, unsupported(t.line, "'multiple statements in parentheses'")
+			}
+
+			return stmts[0], nil
+
+		case "[":
+			p.next()
+
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+
+			return &arrayNode{position{t.line}, items}, nil
+
+		case "{":
+			p.next()
+			return p.parseHash(t)
+		}
+	}
+
+	return nil, p.unexpected()
+}
+
+func (p *parser) stringNode(t token) (node, error) {
+	str := &stringNode{position: position{t.line}}
+
+	for _, part := range t.parts {
+		if !part.isCode {
+			str.parts = append(str.parts, &literalNode{position{t.line}, part.lit})
+			continue
+		}
+
+		sub := &parser{tokens: append(append([]token{}, part.code...), token{kind: tEOF, line: t.line}), scope: p.scope}
+
+		stmts, err := sub.parseProgram()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(stmts) != 1 {
+			return nil, unsupported(t.line, "'multiple statements in string interpolation'")
+		}
+
+		str.parts = append(str.parts, stmts[0])
+	}
+
+	return str, nil
+}
+
+func (p *parser) parseHash(t token) (node, error) {
+	hash := &hashNode{position: position{t.line}}
+
+	for {
+		p.skipNewlines()
+
+		if p.acceptOp("}") {
+			return hash, nil
+		}
+
+		err := p.parseHashPair(hash, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipNewlines()
+
+		if !p.acceptOp(",") {
+			p.skipNewlines()
+
+			err = p.expectOp("}")
+			if err != nil {
+				return nil, err
+			}
+
+			return hash, nil
+		}
+	}
+}
+
+// parseHashPair parses 'key => value' or 'label: value' adding it to hash,
+// key is passed when it has already been parsed
+func (p *parser) parseHashPair(hash *hashNode, key node) error {
+	if key != nil {
+		err := p.expectOp("=>")
+		if err != nil {
+			return err
+		}
+	} else if p.peek().kind == tLabel {
+		label := p.next()
+		key = &literalNode{position{label.line}, symbol(label.val)}
+	} else {
+		var err error
+
+		key, err = p.parseTernary()
+		if err != nil {
+			return err
+		}
+
+		p.skipNewlines()
+
+		if p.isOp(":") {
+			return unsupported(p.peek().line, "'string label in hash'")
+		}
+
+		err = p.expectOp("=>")
+		if err != nil {
+			return err
+		}
+	}
+
+	p.skipNewlines()
+
+	val, err := p.parseTernary()
+	if err != nil {
+		return err
+	}
+
+	hash.keys = append(hash.keys, key)
+	hash.vals = append(hash.vals, val)
+
+	return nil
+}
+
+// parseTrailingHash parses pairs of a hash without braces
+// that ends an argument list, e.g. 'merge("a" => 1)'
+func (p *parser) parseTrailingHash(firstKey node, closing string) (node, error) {
+	hash := &hashNode{position: position{p.peek().line}}
+	key := firstKey
+
+	for {
+		err := p.parseHashPair(hash, key)
+		if err != nil {
+			return nil, err
+		}
+
+		key = nil
+
+		p.skipNewlines()
+
+		if !p.acceptOp(",") {
+			p.skipNewlines()
+
+			return hash, p.expectOp(closing)
+		}
+
+		p.skipNewlines()
+
+		if p.acceptOp(closing) {
+			return hash, nil
+		}
+	}
+}
+
+// parseList parses comma separated expressions up to the closing token
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+
+	noDo := p.noDo
+	p.noDo = false
+	defer func() { p.noDo = noDo }()
+
+	for {
+		p.skipNewlines()
+
+		if p.acceptOp(closing) {
+			return items, nil
+		}
+
+		if p.isOp("*") || p.isOp("&") {
+			return nil, unsupported(p.peek().line, "'%s argument'", p.peek().val)
+		}
+
+		if p.peek().kind == tLabel {
+			hash, err := p.parseTrailingHash(nil, closing)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(items, hash), nil
+		}
+
+		item, err := p.parseNotExprNoAssign()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.isOp("=>") {
+			hash, err := p.parseTrailingHash(item, closing)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(items, hash), nil
+		}
+
+		items = append(items, item)
+
+		p.skipNewlines()
+
+		if !p.acceptOp(",") {
+			p.skipNewlines()
+
+			err = p.expectOp(closing)
+			if err != nil {
+				return nil, err
+			}
+
+			return items, nil
+		}
+	}
+}
+
+func (p *parser) parseNotExprNoAssign() (node, error) {
+	if p.isKeyword("not") {
+		return nil, unsupported(p.peek().line, "'not' in argument")
+	}
+
+	return p.parseExpr()
+}
+
+func (p *parser) parseIdentifier() (node, error) {
+	t := p.next()
+
+	switch t.val {
+	case "nil":
+		return &literalNode{position{t.line}, nil}, nil
+	case "true":
+		return &literalNode{position{t.line}, true}, nil
+	case "false":
+		return &literalNode{position{t.line}, false}, nil
+	case "if", "unless":
+		return p.parseIf(t)
+	}
+
+	if isKeyword(t.val) {
+		return nil, unsupported(t.line, "'%s'", t.val)
+	}
+
+	if p.scope.defined(t.val) && !(p.isOp("(") && !p.peek().spaceBefore) {
+		return &varNode{position{t.line}, t.val}, nil
+	}
+
+	call := &callNode{position: position{t.line}, name: t.val}
+
+	err := p.parseCallArgs(call)
+	if err != nil {
+		return nil, err
+	}
+
+	return call, nil
+}
+
+// parseCallArgs parses arguments and a block of a method call
+func (p *parser) parseCallArgs(call *callNode) error {
+	next := p.peek()
+
+	switch {
+	case next.kind == tOp && next.val == "(" && !next.spaceBefore:
+		p.next()
+
+		args, err := p.parseList(")")
+		if err != nil {
+			return err
+		}
+
+		call.args = args
+
+	case next.spaceBefore && p.startsCommandArg(next):
+		args, err := p.parseCommandArgs()
+		if err != nil {
+			return err
+		}
+
+		call.args = args
+	}
+
+	if p.isOp("&") {
+		return unsupported(p.peek().line, "'block argument'")
+	}
+
+	return p.parseBlock(call)
+}
+
+func (p *parser) startsCommandArg(t token) bool {
+	switch t.kind {
+	case tInt, tFloat, tString, tSymbol, tWords, tConst:
+		return true
+	case tIdent:
+		return !isKeyword(t.val) || t.val == "nil" || t.val == "true" || t.val == "false" || t.val == "not"
+	case tLabel:
+		return true
+	case tOp:
+		if t.val == "[" || t.val == "!" || t.val == "->" {
+			return true
+		}
+
+		// 'foo -1' and 'foo *args' are ambiguous in Ruby
+		if (t.val == "-" || t.val == "*" || t.val == "&" || t.val == "::") && !p.peekAt(1).spaceBefore {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) parseCommandArgs() ([]node, error) {
+	var args []node
+
+	noDo := p.noDo
+	p.noDo = true
+	defer func() { p.noDo = noDo }()
+
+	for {
+		if p.isOp("*") || p.isOp("&") || p.isOp("::") {
+			return nil, unsupported(p.peek().line, "'%s argument'", p.peek().val)
+		}
+
+		if p.peek().kind == tLabel {
+			return nil, unsupported(p.peek().line, "'keyword arguments'")
+		}
+
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.isOp("=>") {
+			return nil, unsupported(p.peek().line, "'hash without braces'")
+		}
+
+		args = append(args, arg)
+
+		if !p.acceptOp(",") {
+			return args, nil
+		}
+
+		p.skipNewlines()
+	}
+}
+
+func (p *parser) parseBlock(call *callNode) error {
+	t := p.peek()
+
+	var closing string
+
+	switch {
+	case t.kind == tIdent && t.val == "do":
+		if p.noDo {
+			return nil
+		}
+		closing = "end"
+	case t.kind == tOp && t.val == "{":
+		closing = "}"
+	case t.kind == tOp && t.val == "&" && p.peekAt(1).kind == tSymbol:
+		return unsupported(t.line, "'block argument'")
+	default:
+		return nil
+	}
+
+	p.next()
+
+	block := &blockNode{position: position{t.line}}
+
+	p.scope = newParseScope(p.scope)
+	defer func() { p.scope = p.scope.parent }()
+
+	if p.acceptOp("|") {
+		for !p.isOp("|") {
+			param := p.next()
+
+			if param.kind != tIdent || isKeyword(param.val) {
+				return unsupported(param.line, "'block parameters'")
+			}
+
+			block.params = append(block.params, param.val)
+			p.scope.vars[param.val] = true
+
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+
+		err := p.expectOp("|")
+		if err != nil {
+			return unsupported(t.line, "'block parameters'")
+		}
+	} else if p.isOp("||") {
+		p.next()
+	}
+
+	body, err := p.parseStmts()
+	if err != nil {
+		return err
+	}
+
+	if closing == "end" {
+		err = p.expectKeyword("end")
+	} else {
+		err = p.expectOp("}")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	block.body = body
+	call.block = block
+
+	return nil
+}
+
+func (p *parser) parsePostfix(recv node) (node, error) {
+	for {
+		t := p.peek()
+
+		// method chains continuing on the next line
+		if t.kind == tNewline && t.val == "\n" {
+			i := 0
+			for p.peekAt(i).kind == tNewline && p.peekAt(i).val == "\n" {
+				i++
+			}
+
+			if next := p.peekAt(i); next.kind == tOp && (next.val == "." || next.val == "&.") {
+				p.pos += i
+				continue
+			}
+
+			return recv, nil
+		}
+
+		if t.kind != tOp {
+			return recv, nil
+		}
+
+		switch t.val {
+		case ".", "&.":
+			p.next()
+			p.skipNewlines()
+
+			name := p.next()
+			if name.kind != tIdent && name.kind != tConst {
+				if name.kind == tLabel {
+					return nil, unsupported(name.line, "'keyword arguments'")
+				}
+
+				if name.kind == tOp && name.val == "(" {
+					return nil, unsupported(name.line, "'.()' call")
+				}
+
+				return nil, unsupported(name.line, "'.%s'", name.val)
+			}
+
+			call := &callNode{position: position{name.line}, recv: recv, name: name.val, safe: t.val == "&."}
+
+			if p.isOp("=") && !p.isOp("==") {
+				return nil, unsupported(name.line, "'attribute assignment'")
+			}
+
+			if p.isOp("(") && !p.peek().spaceBefore && p.peekAt(1).kind == tOp && p.peekAt(1).val == "&" && p.peekAt(2).kind == tSymbol {
+				p.next()
+				p.next()
+				sym := p.next()
+
+				err := p.expectOp(")")
+				if err != nil {
+					return nil, err
+				}
+
+				call.block = &blockNode{position: position{sym.line}, symbol: sym.val}
+				recv = call
+
+				continue
+			}
+
+			err := p.parseCallArgs(call)
+			if err != nil {
+				return nil, err
+			}
+
+			recv = call
+
+		case "[":
+			if t.spaceBefore {
+				return recv, nil
+			}
+
+			p.next()
+
+			args, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+
+			recv = &indexNode{position{t.line}, recv, args}
+
+		case "::":
+			return nil, unsupported(t.line, "'::'")
+
+		default:
+			return recv, nil
+		}
+	}
+}
+
+func (p *parser) parseIf(t token) (node, error) {
+	cond, err := p.parseNotExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.val == "unless" {
+		cond = &notNode{position{t.line}, cond}
+	}
+
+	if p.isKeyword("then") {
+		p.next()
+	}
+
+	then, err := p.parseStmts()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &ifNode{position: position{t.line}, cond: cond, then: then}
+
+	switch {
+	case p.isKeyword("elsif"):
+		if t.val == "unless" {
+			return nil, unsupported(p.peek().line, "'elsif' in unless")
+		}
+
+		elsif := p.next()
+
+		nested, err := p.parseIf(elsif)
+		if err != nil {
+			return nil, err
+		}
+
+		n.els = []node{nested}
+
+		// the nested if consumed the closing 'end'
+		return n, nil
+
+	case p.isKeyword("else"):
+		p.next()
+
+		n.els, err = p.parseStmts()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.expectKeyword("end")
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
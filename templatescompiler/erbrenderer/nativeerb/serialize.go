@@ -0,0 +1,381 @@
+package nativeerb
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// generateJSON mirrors JSON.generate and JSON.pretty_generate
+func generateJSON(v value, pretty bool) (string, error) {
+	var sb strings.Builder
+
+	err := writeJSON(&sb, v, pretty, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func writeJSON(sb *strings.Builder, v value, pretty bool, depth int) error {
+	switch typed := v.(type) {
+	case nil:
+		sb.WriteString("null")
+
+	case bool:
+		sb.WriteString(strconv.FormatBool(typed))
+
+	case int64:
+		sb.WriteString(strconv.FormatInt(typed, 10))
+
+	case float64:
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			return unsupported(0, "'JSON generation of %s'", formatFloat(typed))
+		}
+
+		sb.WriteString(formatFloat(typed))
+
+	case string:
+		return writeJSONString(sb, typed)
+
+	case symbol:
+		return writeJSONString(sb, string(typed))
+
+	case *array:
+		if len(typed.items) == 0 {
+			if pretty {
+				// pretty empty arrays differ between json gem versions
+				return unsupported(0, "'pretty JSON generation of empty array'")
+			}
+
+			sb.WriteString("[]")
+
+			return nil
+		}
+
+		sb.WriteByte('[')
+
+		for i, item := range typed.items {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+
+			writeJSONIndent(sb, pretty, depth+1)
+
+			err := writeJSON(sb, item, pretty, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		writeJSONIndent(sb, pretty, depth)
+		sb.WriteByte(']')
+
+	case *hash:
+		if typed.size() == 0 {
+			if pretty {
+				return unsupported(0, "'pretty JSON generation of empty hash'")
+			}
+
+			sb.WriteString("{}")
+
+			return nil
+		}
+
+		sb.WriteByte('{')
+
+		for i, k := range typed.keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+
+			writeJSONIndent(sb, pretty, depth+1)
+
+			var key string
+
+			switch typedKey := k.(type) {
+			case string:
+				key = typedKey
+			case symbol:
+				key = string(typedKey)
+			case int64:
+				key = strconv.FormatInt(typedKey, 10)
+			default:
+				return unsupported(0, "'JSON generation of %s key'", typeName(k))
+			}
+
+			err := writeJSONString(sb, key)
+			if err != nil {
+				return err
+			}
+
+			sb.WriteByte(':')
+
+			if pretty {
+				sb.WriteByte(' ')
+			}
+
+			err = writeJSON(sb, typed.vals[i], pretty, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		writeJSONIndent(sb, pretty, depth)
+		sb.WriteByte('}')
+
+	default:
+		return unsupported(0, "'JSON generation of %s'", typeName(v))
+	}
+
+	return nil
+}
+
+func writeJSONIndent(sb *strings.Builder, pretty bool, depth int) {
+	if pretty {
+		sb.WriteByte('\n')
+		sb.WriteString(strings.Repeat("  ", depth))
+	}
+}
+
+func writeJSONString(sb *strings.Builder, s string) error {
+	if !utf8.ValidString(s) {
+		return unsupported(0, "'JSON generation of invalid UTF-8 string'")
+	}
+
+	sb.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '\n':
+			sb.WriteString("\\n")
+		case c == '\r':
+			sb.WriteString("\\r")
+		case c == '\t':
+			sb.WriteString("\\t")
+		case c == '\f':
+			sb.WriteString("\\f")
+		case c == '\b':
+			sb.WriteString("\\b")
+		case c < 0x20:
+			return unsupported(0, "'JSON generation of string with control characters'")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	sb.WriteByte('"')
+
+	return nil
+}
+
+// toYAML mirrors Psych's output for documents made of mappings and sequences
+// of plain scalars, anything that Psych would quote or tag is not supported
+func toYAML(v value) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString("---")
+
+	switch typed := v.(type) {
+	case *hash:
+		if typed.size() == 0 {
+			sb.WriteString(" {}\n")
+			return sb.String(), nil
+		}
+
+		sb.WriteByte('\n')
+
+		err := writeYAMLMapping(&sb, typed, 0, "")
+		if err != nil {
+			return "", err
+		}
+
+	case *array:
+		if len(typed.items) == 0 {
+			sb.WriteString(" []\n")
+			return sb.String(), nil
+		}
+
+		sb.WriteByte('\n')
+
+		err := writeYAMLSequence(&sb, typed, 0, "")
+		if err != nil {
+			return "", err
+		}
+
+	default:
+		return "", unsupported(0, "'YAML generation of %s document'", typeName(v))
+	}
+
+	return sb.String(), nil
+}
+
+// writeYAMLMapping writes mapping entries at indent,
+// first entry is prefixed with firstPrefix instead of indentation
+func writeYAMLMapping(sb *strings.Builder, h *hash, indent int, firstPrefix string) error {
+	for i, k := range h.keys {
+		if i == 0 && len(firstPrefix) > 0 {
+			sb.WriteString(firstPrefix)
+		} else {
+			sb.WriteString(strings.Repeat(" ", indent))
+		}
+
+		key, err := yamlScalar(k)
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString(key)
+		sb.WriteByte(':')
+
+		err = writeYAMLValue(sb, h.vals[i], indent+2, indent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeYAMLSequence(sb *strings.Builder, a *array, indent int, firstPrefix string) error {
+	for i, item := range a.items {
+		prefix := strings.Repeat(" ", indent) + "-"
+		if i == 0 && len(firstPrefix) > 0 {
+			prefix = firstPrefix + "-"
+		}
+
+		switch typed := item.(type) {
+		case *hash:
+			if typed.size() > 0 {
+				err := writeYAMLMapping(sb, typed, indent+2, prefix+" ")
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+		case *array:
+			if len(typed.items) > 0 {
+				err := writeYAMLSequence(sb, typed, indent+2, prefix+" ")
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		sb.WriteString(prefix)
+
+		err := writeYAMLValue(sb, item, indent+2, indent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeYAMLValue writes value following a mapping key or a sequence dash,
+// nested mappings are indented while nested sequences are not
+func writeYAMLValue(sb *strings.Builder, v value, mappingIndent, sequenceIndent int) error {
+	switch typed := v.(type) {
+	case *hash:
+		if typed.size() == 0 {
+			sb.WriteString(" {}\n")
+			return nil
+		}
+
+		sb.WriteByte('\n')
+
+		return writeYAMLMapping(sb, typed, mappingIndent, "")
+
+	case *array:
+		if len(typed.items) == 0 {
+			sb.WriteString(" []\n")
+			return nil
+		}
+
+		sb.WriteByte('\n')
+
+		return writeYAMLSequence(sb, typed, sequenceIndent, "")
+	}
+
+	scalar, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+
+	sb.WriteByte(' ')
+	sb.WriteString(scalar)
+	sb.WriteByte('\n')
+
+	return nil
+}
+
+func yamlScalar(v value) (string, error) {
+	switch typed := v.(type) {
+	case bool:
+		return strconv.FormatBool(typed), nil
+
+	case int64:
+		return strconv.FormatInt(typed, 10), nil
+
+	case float64:
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			return "", unsupported(0, "'YAML generation of %s'", formatFloat(typed))
+		}
+
+		return formatFloat(typed), nil
+
+	case string:
+		if !isPlainYAMLString(typed) {
+			return "", unsupported(0, "'YAML generation of quoted string'")
+		}
+
+		return typed, nil
+
+	case symbol:
+		if !isSimpleSymbol(string(typed)) || !isPlainYAMLString(string(typed)) {
+			return "", unsupported(0, "'YAML generation of symbol :%s'", string(typed))
+		}
+
+		return ":" + string(typed), nil
+	}
+
+	return "", unsupported(0, "'YAML generation of %s'", typeName(v))
+}
+
+// isPlainYAMLString reports whether Psych emits the string unquoted
+// and it cannot be read back as another type
+func isPlainYAMLString(s string) bool {
+	if len(s) == 0 || len(s) > 80 {
+		return false
+	}
+
+	if !(s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z') {
+		return false
+	}
+
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !isIdentChar(c) && c != '-' && c != '.' && c != '/' {
+			return false
+		}
+	}
+
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "true", "false", "on", "off", "null":
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,197 @@
+// Package nativeerb renders ERB templates without Ruby. It supports the
+// subset of Ruby used by BOSH job templates and returns UnsupportedError
+// for anything it cannot evaluate exactly as Ruby would.
+package nativeerb
+
+import (
+	"strings"
+)
+
+type Template struct {
+	name  string
+	stmts []node
+}
+
+// Parse parses ERB source with the same trim mode ('-') as the Ruby renderer
+func Parse(name, source string) (*Template, error) {
+	tokens, err := scan(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, scope: newParseScope(nil)}
+
+	stmts, err := p.parseProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{name: name, stmts: stmts}, nil
+}
+
+// Execute evaluates the template returning rendered content
+func (t *Template) Execute(context *Context) (string, error) {
+	e := &evaluator{context: context}
+
+	_, err := e.evalStmts(t.stmts, newScope(nil))
+	if err != nil {
+		return "", err
+	}
+
+	return e.out.String(), nil
+}
+
+// scan splits ERB source into text and code, lexing code into Ruby tokens
+func scan(source string) ([]token, error) {
+	var tokens []token
+
+	line := 1
+	pos := 0
+
+	var text strings.Builder
+	textLine := 1
+
+	flushText := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, token{kind: tText, val: text.String(), line: textLine})
+			text.Reset()
+		}
+	}
+
+	for pos < len(source) {
+		start := strings.Index(source[pos:], "<%")
+		if start < 0 {
+			if text.Len() == 0 {
+				textLine = line
+			}
+
+			rest := source[pos:]
+			if strings.Contains(rest, "%%>") {
+				return nil, unsupported(line, "'%%%%>' in template text")
+			}
+
+			text.WriteString(rest)
+			break
+		}
+
+		start += pos
+		chunk := source[pos:start]
+
+		if strings.Contains(chunk, "%%>") {
+			return nil, unsupported(line, "'%%%%>' in template text")
+		}
+
+		if text.Len() == 0 {
+			textLine = line
+		}
+
+		line += strings.Count(chunk, "\n")
+
+		if strings.HasPrefix(source[start:], "<%%") {
+			text.WriteString(chunk)
+			text.WriteString("<%")
+			pos = start + 3
+			continue
+		}
+
+		tagStart := start + 2
+		kind := byte(0)
+
+		if tagStart < len(source) && (source[tagStart] == '=' || source[tagStart] == '#' || source[tagStart] == '-') {
+			kind = source[tagStart]
+			tagStart++
+		}
+
+		if kind == '-' {
+			chunk = trimIndentation(source, pos, chunk)
+
+			if tagStart < len(source) && (source[tagStart] == '=' || source[tagStart] == '#') {
+				return nil, unsupported(line, "'<%%-%c' tag", source[tagStart])
+			}
+		}
+
+		text.WriteString(chunk)
+
+		end := strings.Index(source[tagStart:], "%>")
+		for end > 0 && source[tagStart+end-1] == '%' {
+			next := strings.Index(source[tagStart+end+2:], "%>")
+			if next < 0 {
+				end = -1
+				break
+			}
+			end += 2 + next
+		}
+
+		if end < 0 {
+			return nil, unsupported(line, "'unterminated tag'")
+		}
+
+		end += tagStart
+		content := source[tagStart:end]
+		pos = end + 2
+
+		content = strings.ReplaceAll(content, "%%>", "%>")
+		contentLine := line
+
+		line += strings.Count(content, "\n")
+
+		if strings.HasSuffix(content, "-") {
+			content = content[:len(content)-1]
+
+			if pos < len(source) && source[pos] == '\n' {
+				pos++
+				line++
+			}
+		}
+
+		switch kind {
+		case '#':
+			continue
+
+		case '=':
+			flushText()
+
+			code, err := lex(content, contentLine)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tOutput, line: contentLine})
+			tokens = append(tokens, code...)
+			tokens = append(tokens, token{kind: tOutputEnd, line: line})
+
+		default:
+			flushText()
+
+			code, err := lex(content, contentLine)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, code...)
+			tokens = append(tokens, token{kind: tNewline, val: ";", line: line})
+		}
+	}
+
+	flushText()
+
+	tokens = append(tokens, token{kind: tEOF, line: line})
+
+	return tokens, nil
+}
+
+// trimIndentation removes spaces and tabs preceding '<%-'
+// when the tag starts a line, as Ruby's ERB does
+func trimIndentation(source string, chunkStart int, chunk string) string {
+	lineStart := strings.LastIndex(chunk, "\n") + 1
+
+	if lineStart == 0 && chunkStart > 0 && source[chunkStart-1] != '\n' {
+		return chunk
+	}
+
+	if strings.Trim(chunk[lineStart:], " \t") != "" {
+		return chunk
+	}
+
+	return chunk[:lineStart]
+}
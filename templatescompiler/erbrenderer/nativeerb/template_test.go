@@ -0,0 +1,144 @@
+package nativeerb_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/v7/templatescompiler/erbrenderer/nativeerb"
+)
+
+var _ = Describe("Template", func() {
+	contextJSON := `{
+		"job": {"name": "fake-job"},
+		"index": 0,
+		"id": "fake-id",
+		"az": "z1",
+		"bootstrap": true,
+		"address": "fake-address",
+		"networks": {"default": {"ip": "10.0.0.5"}},
+		"default_properties": {
+			"port": 8080,
+			"host": "localhost",
+			"ratio": 0.5,
+			"users": null,
+			"tls.enabled": false,
+			"tls.cert": null,
+			"tags": ["a", "b"],
+			"limits": {"cpu": 2, "memory": "1G"}
+		},
+		"job_properties": {
+			"host": "example.com",
+			"users": [{"name": "admin", "role": "rw"}, {"name": "guest", "role": "ro"}],
+			"tls": {"cert": "fake-cert"}
+		},
+		"links": {
+			"db": {
+				"address": "db.internal",
+				"instances": [
+					{"name": "db", "index": 0, "id": "db-id-0", "az": "z1", "address": "10.0.1.1", "bootstrap": true},
+					{"name": "db", "index": 1, "id": "db-id-1", "az": "z2", "address": "10.0.1.2", "bootstrap": false}
+				],
+				"properties": {"port": 5432, "credentials": {"user": "admin"}}
+			}
+		}
+	}`
+
+	render := func(source string) (string, error) {
+		context, err := nativeerb.NewContext([]byte(contextJSON))
+		Expect(err).ToNot(HaveOccurred())
+
+		template, err := nativeerb.Parse("fake-template", source)
+		if err != nil {
+			return "", err
+		}
+
+		return template.Execute(context)
+	}
+
+	DescribeTable("renders templates",
+		func(source, expected string) {
+			result, err := render(source)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		},
+		Entry("plain text", "plain text\n", "plain text\n"),
+		Entry("escaped tags", "<%% not code %>", "<% not code %>"),
+		Entry("comments", "a<%# comment %>b", "ab"),
+		Entry("properties with defaults", "<%= p('port') %> <%= p('host') %>", "8080 example.com"),
+		Entry("nested properties", "<%= p('tls.cert') %> <%= p('tls.enabled') %>", "fake-cert false"),
+		Entry("property fallbacks", "<%= p(['missing', 'host']) %> <%= p('limits.x', 'default') %>", "example.com default"),
+		Entry("floats", "<%= p('ratio') * 3 %> <%= 100000000000000000000.0 %> <%= 10.0 / 4 %>", "1.5 1.0e+20 2.5"),
+		Entry("integer division", "<%= 7 / 2 %> <%= -7 / 2 %> <%= -7 % 3 %>", "3 -4 2"),
+		Entry("if_p with a set property", "<% if_p('host') do |host| %>host=<%= host %><% end %>", "host=example.com"),
+		Entry("if_p with multiple properties", "<% if_p('host', 'port') do |host, port| %><%= host %>:<%= port %><% end %>", "example.com:8080"),
+		Entry("if_p with an unset property", "<% if_p('tls.missing') do |v| %>set<% end.else do %>unset<% end %>", "unset"),
+		Entry("else_if_p", "<% if_p('tls.missing') do %>a<% end.else_if_p('host') do |h| %><%= h %><% end %>", "example.com"),
+		Entry("spec", "<%= spec.id %> <%= spec.az %> <%= spec.bootstrap %> <%= spec.networks.default.ip %> <%= spec.missing.inspect %>", "fake-id z1 true 10.0.0.5 nil"),
+		Entry("name and index", "<%= name %>/<%= index %>", "fake-job/0"),
+		Entry("properties as open structs", "<%= properties.limits.memory %>", "1G"),
+		Entry("links", "<%= link('db').address %> <%= link('db').p('port') %> <%= link('db').p('credentials.user') %>", "db.internal 5432 admin"),
+		Entry("link instances", "<% link('db').instances.each do |i| %><%= i.address %>,<%= i.bootstrap %>;<% end %>", "10.0.1.1,true;10.0.1.2,false;"),
+		Entry("if_link", "<% if_link('db') do |db| %><%= db.instances.size %><% end %><% if_link('cache') do |c| %>x<% end.else do %>none<% end %>", "2none"),
+		Entry("link if_p", "<% link('db').if_p('port') do |port| %><%= port %><% end %>", "5432"),
+		Entry("loops with index", "<% p('users').each_with_index do |user, i| %><%= i %>:<%= user['name'] %> <% end %>", "0:admin 1:guest "),
+		Entry("hash iteration", "<% p('limits').each do |k, v| %><%= k %>=<%= v %>;<% end %>", "cpu=2;memory=1G;"),
+		Entry("map and join", "<%= p('users').map { |u| u['name'].upcase }.join(',') %>", "ADMIN,GUEST"),
+		Entry("symbol blocks", "<%= p('tags').map(&:upcase) %>", `["A", "B"]`),
+		Entry("select and reject", "<%= p('users').select { |u| u['role'] == 'rw' }.map { |u| u['name'] } %>", `["admin"]`),
+		Entry("conditionals", "<% if p('port') > 1024 %>high<% elsif p('port') > 0 %>low<% else %>none<% end %>", "high"),
+		Entry("unless and modifiers", "<% unless p('tls.enabled') %>off<% end %><%= 'on' if p('tls.enabled') %>", "off"),
+		Entry("ternaries", "<%= p('tls.enabled') ? 'https' : 'http' %>", "http"),
+		Entry("local variables", "<% port = p('port') + 1 %><% port += 1 %><%= port %>", "8082"),
+		Entry("string interpolation", `<%= "#{p('host')}:#{p('port')}" %>`, "example.com:8080"),
+		Entry("string methods", `<%= " a,b,,c,, ".strip.split(",").inspect %> <%= "abc".start_with?("a") %> <%= "a-b".gsub("-", "_") %>`, `["a", "b", "", "c"] true a_b`),
+		Entry("trim mode", "a\n  <%- if true -%>\nb\n  <%- end -%>\nc\n", "a\nb\nc\n"),
+		Entry("hash literals and merge", "<%= {'a' => 1}.merge('b' => 2).to_json %>", `{"a":1,"b":2}`),
+		Entry("JSON helpers", "<%= JSON.dump(p('host')) %> <%= JSON.dump(p('limits')) %> <%= p('tags').to_json %>", `"example.com" {"cpu":2,"memory":"1G"} ["a","b"]`),
+		Entry("JSON pretty generation", "<%= JSON.pretty_generate(p('limits')) %>", "{\n  \"cpu\": 2,\n  \"memory\": \"1G\"\n}"),
+		Entry("YAML helpers", "<%= p('users').to_yaml %>", "---\n- name: admin\n  role: rw\n- name: guest\n  role: ro\n"),
+		Entry("nested YAML", "<%= {'a' => {'b' => ['c', 'd']}}.to_yaml %>", "---\na:\n  b:\n  - c\n  - d\n"),
+		Entry("sorting", "<%= [3, 1, 2].sort.reverse %> <%= p('users').sort_by { |u| u['role'] }.first['name'] %>", "[3, 2, 1] guest"),
+		Entry("dig and fetch", "<%= p('limits').dig('cpu') %> <%= p('limits').fetch('disk', 'none') %>", "2 none"),
+		Entry("safe navigation", "<%= p('users').first&.fetch('name') %> <%= nil&.upcase.inspect %>", "admin nil"),
+	)
+
+	Describe("errors raised by templates", func() {
+		It("reports unknown properties like ruby", func() {
+			_, err := render("a\n<%= p('missing') %>")
+			Expect(err).To(Equal(nativeerb.RubyError{
+				Class:   "TemplateEvaluationContext::UnknownProperty",
+				Message: "Can't find property 'missing'",
+				Line:    2,
+			}))
+			Expect(err.(nativeerb.RubyError).Inspect()).To(Equal("#<TemplateEvaluationContext::UnknownProperty: Can't find property 'missing'>"))
+		})
+
+		It("reports unknown links", func() {
+			_, err := render("<%= link('missing').address %>")
+			Expect(err).To(Equal(nativeerb.RubyError{
+				Class:   "TemplateEvaluationContext::UnknownLink",
+				Message: "Can't find link 'missing'",
+				Line:    1,
+			}))
+		})
+
+		It("reports raised errors", func() {
+			_, err := render("\n\n<% raise 'fake-error' unless p('tls.enabled') %>")
+			Expect(err).To(Equal(nativeerb.RubyError{Class: "RuntimeError", Message: "fake-error", Line: 3}))
+		})
+	})
+
+	DescribeTable("reports constructs that need ruby",
+		func(source string, construct string, line int) {
+			_, err := render(source)
+			Expect(err).To(Equal(nativeerb.UnsupportedError{Construct: construct, Line: line}))
+		},
+		Entry("regular expressions", "<%= p('host') =~ /example/ %>", "'regular expression'", 1),
+		Entry("unknown methods", "\n<%= p('host').unpack('C*') %>", "'String#unpack'", 2),
+		Entry("unknown functions", "<%= format('%d', 1) %>", "'method format'", 1),
+		Entry("unknown constants", "<%= File.read('x') %>", "'constant File'", 1),
+		Entry("hash to_s", "<%= p('limits') %>", "'to_s of Hash'", 1),
+		Entry("quoted YAML strings", "<%= {'a' => '1 2'}.to_yaml %>", "'YAML generation of quoted string'", 1),
+		Entry("integer overflow", "<%= 9223372036854775807 + 1 %>", "'integer overflow'", 1),
+	)
+})
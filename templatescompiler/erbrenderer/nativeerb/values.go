@@ -0,0 +1,387 @@
+package nativeerb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// value is one of nil, bool, int64, float64, string, symbol,
+// *array, *hash, *openStruct, *evaluationLink, *linkInstance,
+// *elseBlock or constant
+type value interface{}
+
+type symbol string
+
+type constant string
+
+type array struct {
+	items []value
+}
+
+// hash preserves insertion order as Ruby's Hash does
+type hash struct {
+	keys  []value
+	vals  []value
+	index map[hashKey]int
+}
+
+type hashKey struct {
+	kind byte
+	s    string
+	i    int64
+}
+
+type openStruct struct {
+	fields *hash
+}
+
+func newArray(items ...value) *array {
+	return &array{items: items}
+}
+
+func newHash() *hash {
+	return &hash{index: map[hashKey]int{}}
+}
+
+func keyOf(v value) (hashKey, bool) {
+	switch typed := v.(type) {
+	case nil:
+		return hashKey{kind: 'n'}, true
+	case bool:
+		if typed {
+			return hashKey{kind: 't'}, true
+		}
+		return hashKey{kind: 'f'}, true
+	case int64:
+		return hashKey{kind: 'i', i: typed}, true
+	case string:
+		return hashKey{kind: 's', s: typed}, true
+	case symbol:
+		return hashKey{kind: 'y', s: string(typed)}, true
+	}
+
+	return hashKey{}, false
+}
+
+func (h *hash) get(k value) (value, bool, error) {
+	key, ok := keyOf(k)
+	if !ok {
+		return nil, false, unsupported(0, "'hash key of type %s'", typeName(k))
+	}
+
+	i, found := h.index[key]
+	if !found {
+		return nil, false, nil
+	}
+
+	return h.vals[i], true, nil
+}
+
+func (h *hash) set(k, v value) error {
+	key, ok := keyOf(k)
+	if !ok {
+		return unsupported(0, "'hash key of type %s'", typeName(k))
+	}
+
+	if i, found := h.index[key]; found {
+		h.vals[i] = v
+		return nil
+	}
+
+	h.index[key] = len(h.keys)
+	h.keys = append(h.keys, k)
+	h.vals = append(h.vals, v)
+
+	return nil
+}
+
+func (h *hash) getString(k string) value {
+	v, _, _ := h.get(k) //nolint:errcheck
+	return v
+}
+
+func (h *hash) size() int { return len(h.keys) }
+
+func (h *hash) dup() *hash {
+	result := newHash()
+
+	for i, k := range h.keys {
+		result.set(k, h.vals[i]) //nolint:errcheck
+	}
+
+	return result
+}
+
+func typeName(v value) string {
+	switch v.(type) {
+	case nil:
+		return "NilClass"
+	case bool:
+		if v.(bool) {
+			return "TrueClass"
+		}
+		return "FalseClass"
+	case int64:
+		return "Integer"
+	case float64:
+		return "Float"
+	case string:
+		return "String"
+	case symbol:
+		return "Symbol"
+	case *array:
+		return "Array"
+	case *hash:
+		return "Hash"
+	case *openStruct:
+		return "OpenStruct"
+	case *evaluationLink:
+		return "EvaluationLink"
+	case *linkInstance:
+		return "EvaluationLinkInstance"
+	case *elseBlock:
+		return "ElseBlock"
+	case constant:
+		return "Class"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+func truthy(v value) bool {
+	switch typed := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	}
+
+	return true
+}
+
+// toS mirrors Ruby's to_s for values that format the same across Ruby versions
+func toS(v value) (string, error) {
+	switch typed := v.(type) {
+	case nil:
+		return "", nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	case int64:
+		return strconv.FormatInt(typed, 10), nil
+	case float64:
+		return formatFloat(typed), nil
+	case string:
+		return typed, nil
+	case symbol:
+		return string(typed), nil
+	case *array:
+		return inspect(typed)
+	case constant:
+		return string(typed), nil
+	}
+
+	return "", unsupported(0, "'to_s of %s'", typeName(v))
+}
+
+// inspect mirrors Ruby's inspect for values that format the same across Ruby versions
+func inspect(v value) (string, error) {
+	switch typed := v.(type) {
+	case nil:
+		return "nil", nil
+	case string:
+		return inspectString(typed)
+	case symbol:
+		if !isSimpleSymbol(string(typed)) {
+			return "", unsupported(0, "'inspect of symbol :%s'", string(typed))
+		}
+		return ":" + string(typed), nil
+	case *array:
+		var items []string
+
+		for _, item := range typed.items {
+			s, err := inspect(item)
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, s)
+		}
+
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case bool, int64, float64:
+		return toS(v)
+	}
+
+	return "", unsupported(0, "'inspect of %s'", typeName(v))
+}
+
+func isSimpleSymbol(s string) bool {
+	if len(s) == 0 || !isIdentStart(s[0]) {
+		return false
+	}
+
+	for i := 1; i < len(s); i++ {
+		if !isIdentChar(s[i]) && !(i == len(s)-1 && (s[i] == '?' || s[i] == '!')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inspectString mirrors String#inspect for ASCII strings,
+// non-ASCII output depends on the encoding Ruby runs with
+func inspectString(s string) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c >= utf8.RuneSelf:
+			return "", unsupported(0, "'inspect of non-ASCII string'")
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '#' && i+1 < len(s) && (s[i+1] == '{' || s[i+1] == '$' || s[i+1] == '@'):
+			sb.WriteString("\\#")
+		case c == '\n':
+			sb.WriteString("\\n")
+		case c == '\t':
+			sb.WriteString("\\t")
+		case c == '\r':
+			sb.WriteString("\\r")
+		case c == '\f':
+			sb.WriteString("\\f")
+		case c == '\v':
+			sb.WriteString("\\v")
+		case c == '\a':
+			sb.WriteString("\\a")
+		case c == '\b':
+			sb.WriteString("\\b")
+		case c == 0x1b:
+			sb.WriteString("\\e")
+		case c < 0x20 || c == 0x7f:
+			return "", unsupported(0, "'inspect of string with control characters'")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	sb.WriteByte('"')
+
+	return sb.String(), nil
+}
+
+// formatFloat mirrors Ruby's Float#to_s
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		if math.Signbit(f) {
+			return "-0.0"
+		}
+		return "0.0"
+	}
+
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+
+	// shortest representation that round trips, e.g. '1.2345e+02'
+	exp := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(exp, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+
+	e, _ := strconv.Atoi(exponent) //nolint:errcheck
+	decpt := e + 1
+
+	switch {
+	case decpt > 0 && decpt <= 16:
+		if decpt >= len(digits) {
+			return sign + digits + strings.Repeat("0", decpt-len(digits)) + ".0"
+		}
+		return sign + digits[:decpt] + "." + digits[decpt:]
+
+	case decpt <= 0 && decpt > -4:
+		return sign + "0." + strings.Repeat("0", -decpt) + digits
+
+	default:
+		fraction := digits[1:]
+		if len(fraction) == 0 {
+			fraction = "0"
+		}
+		return fmt.Sprintf("%s%s.%se%+03d", sign, digits[:1], fraction, decpt-1)
+	}
+}
+
+// equal mirrors Ruby's == for supported values
+func equal(a, b value) bool {
+	switch typedA := a.(type) {
+	case int64:
+		switch typedB := b.(type) {
+		case int64:
+			return typedA == typedB
+		case float64:
+			return float64(typedA) == typedB
+		}
+		return false
+	case float64:
+		switch typedB := b.(type) {
+		case int64:
+			return typedA == float64(typedB)
+		case float64:
+			return typedA == typedB
+		}
+		return false
+	case *array:
+		typedB, ok := b.(*array)
+		if !ok || len(typedA.items) != len(typedB.items) {
+			return false
+		}
+
+		for i := range typedA.items {
+			if !equal(typedA.items[i], typedB.items[i]) {
+				return false
+			}
+		}
+
+		return true
+	case *hash:
+		typedB, ok := b.(*hash)
+		if !ok || typedA.size() != typedB.size() {
+			return false
+		}
+
+		for i, k := range typedA.keys {
+			v, found, _ := typedB.get(k) //nolint:errcheck
+			if !found || !equal(typedA.vals[i], v) {
+				return false
+			}
+		}
+
+		return true
+	case *openStruct:
+		typedB, ok := b.(*openStruct)
+		return ok && equal(typedA.fields, typedB.fields)
+	}
+
+	switch b.(type) {
+	case int64, float64, *array, *hash, *openStruct:
+		return false
+	}
+
+	return a == b
+}
@@ -43,6 +43,7 @@ class TemplateEvaluationContext
 
     @properties = openstruct(properties)
     @raw_properties = properties
+    @links = spec['links'] || {}
     @spec = openstruct(spec)
   end
 
@@ -73,8 +74,22 @@ class TemplateEvaluationContext
     InactiveElseBlock.new
   end
 
+  def link(name)
+    link_spec = @links[name]
+    raise UnknownLink.new(name) if link_spec.nil?
+
+    create_evaluation_link(link_spec)
+  end
+
   def if_link(name)
-    false
+    link_spec = @links[name]
+
+    if link_spec.nil?
+      ActiveElseBlock.new(self)
+    else
+      yield create_evaluation_link(link_spec)
+      InactiveElseBlock.new
+    end
   end
 
   private
@@ -98,6 +113,21 @@ class TemplateEvaluationContext
     dst_ref[keys[-1]] = src_ref.nil? ? default : src_ref
   end
 
+  def create_evaluation_link(link_spec)
+    instances = (link_spec['instances'] || []).map do |instance|
+      EvaluationLinkInstance.new(
+        instance['name'],
+        instance['index'],
+        instance['id'],
+        instance['az'],
+        instance['address'],
+        instance['bootstrap']
+      )
+    end
+
+    EvaluationLink.new(instances, link_spec['properties'] || {}, link_spec['address'])
+  end
+
   def openstruct(object)
     case object
       when Hash
@@ -131,6 +161,76 @@ class TemplateEvaluationContext
     end
   end
 
+  class UnknownLink < StandardError
+    def initialize(name)
+      super("Can't find link '#{name}'")
+    end
+  end
+
+  class EvaluationLinkInstance
+    attr_reader :name, :index, :id, :az, :address, :bootstrap
+
+    def initialize(name, index, id, az, address, bootstrap)
+      @name = name
+      @index = index
+      @id = id
+      @az = az
+      @address = address
+      @bootstrap = bootstrap
+    end
+  end
+
+  class EvaluationLink
+    attr_reader :instances, :properties
+
+    def initialize(instances, properties, address)
+      @instances = instances
+      @properties = properties
+      @address = address
+    end
+
+    def address(criteria = {})
+      @address
+    end
+
+    def p(*args)
+      names = Array(args[0])
+
+      names.each do |name|
+        result = lookup_property(@properties, name)
+        return result unless result.nil?
+      end
+
+      return args[1] if args.length == 2
+      raise UnknownProperty.new(names)
+    end
+
+    def if_p(*names)
+      values = names.map do |name|
+        value = lookup_property(@properties, name)
+        return ActiveElseBlock.new(self) if value.nil?
+        value
+      end
+
+      yield *values
+      InactiveElseBlock.new
+    end
+
+    private
+
+    def lookup_property(collection, name)
+      keys = name.split(".")
+      ref = collection
+
+      keys.each do |key|
+        ref = ref[key]
+        return nil if ref.nil?
+      end
+
+      ref
+    end
+  end
+
   class ActiveElseBlock
     def initialize(template)
       @context = template
@@ -174,31 +274,95 @@ class ERBRenderer
   end
 
   def render(src_path, dst_path)
+    render_template(src_path, dst_path)
+  rescue Exception => e
+    name = "#{@context.name}/#{@context.index}"
+    location = "(line #{ERBRenderer.line_number(e, src_path)}: #{e.inspect})"
+
+    raise("Error filling in template '#{src_path}' for #{name} #{location}")
+  end
+
+  def render_template(src_path, dst_path)
     erb = ERB.new(File.read(src_path), safe_level = nil, trim_mode = "-")
     erb.filename = src_path
 
     File.open(dst_path, "w") do |f|
       f.write(erb.result(@context.get_binding))
     end
+  end
 
-  rescue Exception => e
-    name = "#{@context.name}/#{@context.index}"
+  def self.line_number(e, src_path)
+    line_i = e.backtrace.index { |l| l.include?(src_path) }
+    line_i ? e.backtrace[line_i].split(':')[1] : "unknown"
+  end
+end
 
-    line_i = e.backtrace.index { |l| l.include?(erb.filename) }
-    line_num = line_i ? e.backtrace[line_i].split(':')[1] : "unknown"
-    location = "(line #{line_num}: #{e.inspect})"
+# Renders templates of multiple jobs in a single process.
+# Reads one JSON request per job from input and
+# writes one JSON result per template to output.
+class BatchERBRenderer
+  def initialize(input, output)
+    @input = input
+    @output = output
+  end
 
-    raise("Error filling in template '#{src_path}' for #{name} #{location}")
+  def run
+    @input.each_line do |line|
+      next if line.strip.empty?
+
+      request = JSON.parse(line)
+      render_job(request["job"], request["context"], request["templates"])
+    end
+  end
+
+  private
+
+  # Each template gets its own context, as it would in a separate process,
+  # so that state set by one template (e.g. instance variables) does not
+  # leak into the next one. Contexts modify their spec hence the copy.
+  def render_job(job, context_hash, templates)
+    templates.each do |t|
+      begin
+        renderer = ERBRenderer.new(TemplateEvaluationContext.new(Marshal.load(Marshal.dump(context_hash))))
+      rescue Exception => e
+        write_result(job, t["name"], "unknown", e.inspect)
+        next
+      end
+
+      begin
+        renderer.render_template(t["src"], t["dst"])
+        write_result(job, t["name"])
+      rescue Exception => e
+        write_result(job, t["name"], ERBRenderer.line_number(e, t["src"]), e.inspect)
+      end
+    end
+  end
+
+  def write_result(job, template, line = nil, error = nil)
+    result = { "job" => job, "template" => template }
+    result["line"] = line.to_s if error
+    result["error"] = error if error
+
+    @output.puts(JSON.generate(result))
+    @output.flush
   end
 end
 
 if $0 == __FILE__
-  context_path, src_path, dst_path = *ARGV
+  if ARGV[0] == "--batch"
+    # Keep templates writing to stdout from corrupting results
+    output = $stdout.dup
+    $stdout.reopen($stderr)
 
-  context_hash = JSON.load(File.read(context_path))
-  context = TemplateEvaluationContext.new(context_hash)
+    BatchERBRenderer.new($stdin, output).run
+  else
+    context_path, src_path, dst_path = *ARGV
 
-  renderer = ERBRenderer.new(context)
-  renderer.render(src_path, dst_path)
+    context_hash = JSON.load(File.read(context_path))
+    context = TemplateEvaluationContext.new(context_hash)
+
+    renderer = ERBRenderer.new(context)
+    renderer.render(src_path, dst_path)
+  end
 end
 `
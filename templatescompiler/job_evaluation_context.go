@@ -18,7 +18,7 @@ type jobEvaluationContext struct {
 	jobProperties        biproperty.Map
 	globalProperties     biproperty.Map
 	deploymentName       string
-	address              string
+	instance             InstanceSpec
 	uuidGen              boshuuid.Generator
 	logger               boshlog.Logger
 	logTag               string
@@ -38,6 +38,9 @@ type RootContext struct {
 	// Usually is accessed with <%= spec.networks.default.ip %>
 	NetworkContexts map[string]networkContext `json:"networks"`
 
+	// Accessed via link("name") and if_link("name") helpers
+	Links map[string]LinkSpec `json:"links,omitempty"`
+
 	//TODO: this should be a map[string]interface{}
 	GlobalProperties  biproperty.Map  `json:"global_properties"`  // values from manifest's top-level properties
 	ClusterProperties biproperty.Map  `json:"cluster_properties"` // values from instance group (deployment job) properties
@@ -55,6 +58,45 @@ type networkContext struct {
 	Gateway string `json:"gateway"`
 }
 
+// InstanceSpec overrides instance specific values exposed to templates via spec.
+// Zero values keep defaults used when rendering jobs for a single VM environment.
+type InstanceSpec struct {
+	ID        string
+	Index     int
+	AZ        string
+	Bootstrap *bool
+	Address   string
+
+	Networks map[string]NetworkSpec
+	Links    map[string]LinkSpec
+}
+
+type NetworkSpec struct {
+	IP      string
+	Netmask string
+	Gateway string
+}
+
+// LinkSpec matches link information provided by the Director to templates.
+type LinkSpec struct {
+	Address        string             `json:"address,omitempty"`
+	InstanceGroup  string             `json:"instance_group,omitempty"`
+	DefaultNetwork string             `json:"default_network,omitempty"`
+	DeploymentName string             `json:"deployment_name,omitempty"`
+	Domain         string             `json:"domain,omitempty"`
+	Instances      []LinkInstanceSpec `json:"instances"`
+	Properties     biproperty.Map     `json:"properties"`
+}
+
+type LinkInstanceSpec struct {
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	Index     int    `json:"index"`
+	AZ        string `json:"az,omitempty"`
+	Address   string `json:"address"`
+	Bootstrap bool   `json:"bootstrap"`
+}
+
 func NewJobEvaluationContext(
 	releaseJob bireljob.Job,
 	releaseJobProperties *biproperty.Map,
@@ -64,6 +106,28 @@ func NewJobEvaluationContext(
 	address string,
 	uuidGen boshuuid.Generator,
 	logger boshlog.Logger,
+) bierbrenderer.TemplateEvaluationContext {
+	return NewInstanceJobEvaluationContext(
+		releaseJob,
+		releaseJobProperties,
+		jobProperties,
+		globalProperties,
+		deploymentName,
+		InstanceSpec{Address: address},
+		uuidGen,
+		logger,
+	)
+}
+
+func NewInstanceJobEvaluationContext(
+	releaseJob bireljob.Job,
+	releaseJobProperties *biproperty.Map,
+	jobProperties biproperty.Map,
+	globalProperties biproperty.Map,
+	deploymentName string,
+	instance InstanceSpec,
+	uuidGen boshuuid.Generator,
+	logger boshlog.Logger,
 ) bierbrenderer.TemplateEvaluationContext {
 	return jobEvaluationContext{
 		releaseJob:           releaseJob,
@@ -71,7 +135,7 @@ func NewJobEvaluationContext(
 		jobProperties:        jobProperties,
 		globalProperties:     globalProperties,
 		deploymentName:       deploymentName,
-		address:              address,
+		instance:             instance,
 		uuidGen:              uuidGen,
 		logTag:               "jobEvaluationContext",
 		logger:               logger,
@@ -83,25 +147,34 @@ func (ec jobEvaluationContext) MarshalJSON() ([]byte, error) {
 	var err error
 
 	context := RootContext{
-		Index:             0,
+		ID:                ec.instance.ID,
+		Index:             ec.instance.Index,
 		AZ:                "unknown",
 		Bootstrap:         true,
 		JobContext:        jobContext{Name: ec.releaseJob.Name()},
 		Deployment:        ec.deploymentName,
+		Address:           ec.instance.Address,
 		NetworkContexts:   ec.buildNetworkContexts(),
+		Links:             ec.instance.Links,
 		GlobalProperties:  ec.globalProperties,
 		ClusterProperties: ec.jobProperties,
 		JobProperties:     ec.releaseJobProperties,
 		DefaultProperties: defaultProperties,
 	}
 
-	if len(ec.address) > 0 {
-		context.Address = ec.address
+	if len(ec.instance.AZ) > 0 {
+		context.AZ = ec.instance.AZ
 	}
 
-	context.ID, err = ec.uuidGen.Generate()
-	if err != nil {
-		return []byte{}, bosherr.WrapErrorf(err, "Setting job eval context's ID to UUID: %#v", context)
+	if ec.instance.Bootstrap != nil {
+		context.Bootstrap = *ec.instance.Bootstrap
+	}
+
+	if len(context.ID) == 0 {
+		context.ID, err = ec.uuidGen.Generate()
+		if err != nil {
+			return []byte{}, bosherr.WrapErrorf(err, "Setting job eval context's ID to UUID: %#v", context)
+		}
 	}
 
 	ec.logger.Debug(ec.logTag, "Marshalling context %#v", context)
@@ -123,6 +196,16 @@ func (ec jobEvaluationContext) propertyDefaults(properties map[string]bireljob.P
 }
 
 func (ec jobEvaluationContext) buildNetworkContexts() map[string]networkContext {
+	if len(ec.instance.Networks) > 0 {
+		contexts := make(map[string]networkContext, len(ec.instance.Networks))
+
+		for name, network := range ec.instance.Networks {
+			contexts[name] = networkContext(network)
+		}
+
+		return contexts
+	}
+
 	// IP is being returned by agent
 	return map[string]networkContext{
 		"default": networkContext{
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -101,6 +102,88 @@ var _ = Describe("JobEvaluationContext", func() {
 		generatedContext := act()
 		Expect(generatedContext.Bootstrap).To(Equal(true))
 	})
+
+	Context("when instance spec is provided", func() {
+		var (
+			instance InstanceSpec
+		)
+
+		BeforeEach(func() {
+			bootstrap := false
+
+			instance = InstanceSpec{
+				ID:        "fake-id",
+				Index:     2,
+				AZ:        "z2",
+				Bootstrap: &bootstrap,
+				Address:   "fake-address",
+				Networks: map[string]NetworkSpec{
+					"private": {IP: "10.0.0.5", Netmask: "255.255.255.0", Gateway: "10.0.0.1"},
+				},
+				Links: map[string]LinkSpec{
+					"db": {
+						Address:   "db.bosh",
+						Instances: []LinkInstanceSpec{{Name: "db", ID: "db-id", Address: "10.0.0.6", Bootstrap: true}},
+						Properties: biproperty.Map{
+							"port": 5432,
+						},
+					},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			jobEvaluationContext = NewInstanceJobEvaluationContext(
+				*releaseJob,
+				jobProperties,
+				instanceGroupProperties,
+				deploymentProperties,
+				"fake-deployment-name",
+				instance,
+				uuidGen,
+				boshlog.NewLogger(boshlog.LevelNone),
+			)
+		})
+
+		It("uses instance values instead of defaults", func() {
+			uuidGen.GeneratedUUID = "fake-uuid"
+
+			generatedContext := act()
+			Expect(generatedContext.ID).To(Equal("fake-id"))
+			Expect(generatedContext.Index).To(Equal(2))
+			Expect(generatedContext.AZ).To(Equal("z2"))
+			Expect(generatedContext.Bootstrap).To(BeFalse())
+			Expect(generatedContext.Address).To(Equal("fake-address"))
+			Expect(generatedContext.NetworkContexts).To(HaveLen(1))
+			Expect(generatedContext.NetworkContexts["private"].IP).To(Equal("10.0.0.5"))
+			Expect(generatedContext.NetworkContexts["private"].Gateway).To(Equal("10.0.0.1"))
+		})
+
+		It("exposes links", func() {
+			generatedContext := act()
+			Expect(generatedContext.Links["db"].Address).To(Equal("db.bosh"))
+			Expect(generatedContext.Links["db"].Instances).To(Equal(instance.Links["db"].Instances))
+			Expect(generatedContext.Links["db"].Properties).To(Equal(biproperty.Map{"port": float64(5432)}))
+		})
+
+		Context("when id is not provided", func() {
+			BeforeEach(func() {
+				instance.ID = ""
+			})
+
+			It("generates id", func() {
+				uuidGen.GeneratedUUID = "fake-uuid"
+				Expect(act().ID).To(Equal("fake-uuid"))
+			})
+		})
+	})
+
+	It("does not include links by default", func() {
+		generatedJSON, err := jobEvaluationContext.MarshalJSON()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(generatedJSON)).ToNot(ContainSubstring(`"links"`))
+	})
+
 	Context("when the UUID generator raise an error", func() {
 		It("it raises an error", func() {
 			uuidGen.GenerateError = errors.Error("boom")
@@ -261,4 +344,30 @@ var _ = Describe("JobEvaluationContext", func() {
 			})
 		})
 	})
+
+	It("renders each template of a batch with a separate context", func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := boshsys.NewOsFileSystem(logger)
+		erbRenderer = erbrenderer.NewERBRenderer(fs, boshsys.NewExecCmdRunner(logger), logger)
+
+		dir, err := os.MkdirTemp("", "batch")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir) //nolint:errcheck
+
+		Expect(os.WriteFile(filepath.Join(dir, "first.erb"), []byte("<% @leaked = 'first' %>first"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "second.erb"), []byte("<%= @leaked.inspect %>"), 0600)).To(Succeed())
+
+		err = erbRenderer.RenderBatch([]erbrenderer.BatchJob{{
+			Name:    "fake-job",
+			Context: jobEvaluationContext,
+			Templates: []erbrenderer.BatchTemplate{
+				{Name: "first", SrcPath: filepath.Join(dir, "first.erb"), DstPath: filepath.Join(dir, "first")},
+				{Name: "second", SrcPath: filepath.Join(dir, "second.erb"), DstPath: filepath.Join(dir, "second")},
+			},
+		}})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.ReadFile(filepath.Join(dir, "first"))).To(Equal([]byte("first")))
+		Expect(os.ReadFile(filepath.Join(dir, "second"))).To(Equal([]byte("nil")))
+	})
 })
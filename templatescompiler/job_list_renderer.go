@@ -16,6 +16,7 @@ type JobListRenderer interface {
 		globalProperties biproperty.Map,
 		deploymentName string,
 		address string,
+		links map[string]map[string]LinkSpec,
 	) (RenderedJobList, error)
 }
 
@@ -43,17 +44,31 @@ func (r *jobListRenderer) Render(
 	globalProperties biproperty.Map,
 	deploymentName string,
 	address string,
+	links map[string]map[string]LinkSpec,
 ) (RenderedJobList, error) {
 	r.logger.Debug(r.logTag, "Rendering job list: deploymentName='%s' jobProperties=%#v globalProperties=%#v", deploymentName, jobProperties, globalProperties)
 	renderedJobList := NewRenderedJobList()
 
-	// render all the jobs' templates
+	// render all the jobs' templates at once
+	requests := make([]JobRenderRequest, 0, len(releaseJobs))
+
 	for _, releaseJob := range releaseJobs {
-		renderedJob, err := r.jobRenderer.Render(releaseJob, releaseJobProperties[releaseJob.Name()], jobProperties, globalProperties, deploymentName, address)
-		if err != nil {
-			defer renderedJobList.DeleteSilently()
-			return renderedJobList, bosherr.WrapErrorf(err, "Rendering templates for job '%s/%s'", releaseJob.Name(), releaseJob.Fingerprint())
-		}
+		requests = append(requests, JobRenderRequest{
+			ReleaseJob:           releaseJob,
+			ReleaseJobProperties: releaseJobProperties[releaseJob.Name()],
+			JobProperties:        jobProperties,
+			GlobalProperties:     globalProperties,
+			DeploymentName:       deploymentName,
+			Instance:             InstanceSpec{Address: address, Links: links[releaseJob.Name()]},
+		})
+	}
+
+	renderedJobs, err := r.jobRenderer.RenderBatch(requests)
+	if err != nil {
+		return renderedJobList, bosherr.WrapError(err, "Rendering templates for jobs")
+	}
+
+	for _, renderedJob := range renderedJobs {
 		renderedJobList.Add(renderedJob)
 	}
 
@@ -36,12 +36,11 @@ var _ = Describe("JobListRenderer", func() {
 		globalProperties     biproperty.Map
 		deploymentName       string
 		address              string
+		links                map[string]map[string]LinkSpec
 
 		renderedJobs []*mock_template.MockRenderedJob
 
 		jobListRenderer JobListRenderer
-
-		expectRender1 *gomock.Call
 	)
 
 	BeforeEach(func() {
@@ -72,6 +71,12 @@ var _ = Describe("JobListRenderer", func() {
 		deploymentName = "fake-deployment-name"
 		address = "1.2.3.4"
 
+		links = map[string]map[string]LinkSpec{
+			"fake-release-job-name-1": {
+				"fake-link": {Address: "fake-link-address"},
+			},
+		}
+
 		renderedJobs = []*mock_template.MockRenderedJob{
 			mock_template.NewMockRenderedJob(mockCtrl),
 			mock_template.NewMockRenderedJob(mockCtrl),
@@ -80,14 +85,28 @@ var _ = Describe("JobListRenderer", func() {
 		jobListRenderer = NewJobListRenderer(mockJobRenderer, logger)
 	})
 
-	JustBeforeEach(func() {
-		mockJobRenderer.EXPECT().Render(releaseJobs[0], releaseJobProperties[releaseJobs[0].Name()], jobProperties, globalProperties, deploymentName, address).Return(renderedJobs[0], nil)
-		expectRender1 = mockJobRenderer.EXPECT().Render(releaseJobs[1], releaseJobProperties[releaseJobs[1].Name()], jobProperties, globalProperties, deploymentName, address).Return(renderedJobs[1], nil)
-	})
-
 	Describe("Render", func() {
-		It("returns a new RenderedJobList with all the RenderedJobs", func() {
-			renderedJobList, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+		It("renders all jobs in a single batch and returns a new RenderedJobList with all the RenderedJobs", func() {
+			mockJobRenderer.EXPECT().RenderBatch([]JobRenderRequest{
+				{
+					ReleaseJob:           releaseJobs[0],
+					ReleaseJobProperties: releaseJobProperties[releaseJobs[0].Name()],
+					JobProperties:        jobProperties,
+					GlobalProperties:     globalProperties,
+					DeploymentName:       deploymentName,
+					Instance:             InstanceSpec{Address: address},
+				},
+				{
+					ReleaseJob:           releaseJobs[1],
+					ReleaseJobProperties: releaseJobProperties[releaseJobs[1].Name()],
+					JobProperties:        jobProperties,
+					GlobalProperties:     globalProperties,
+					DeploymentName:       deploymentName,
+					Instance:             InstanceSpec{Address: address, Links: links["fake-release-job-name-1"]},
+				},
+			}).Return([]RenderedJob{renderedJobs[0], renderedJobs[1]}, nil)
+
+			renderedJobList, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, links)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(renderedJobList.All()).To(Equal([]RenderedJob{
 				renderedJobs[0],
@@ -95,17 +114,14 @@ var _ = Describe("JobListRenderer", func() {
 			}))
 		})
 
-		Context("when rendering a job fails", func() {
-			JustBeforeEach(func() {
-				expectRender1.Return(nil, bosherr.Error("fake-render-error"))
-			})
-
-			It("returns an error and cleans up any sucessfully rendered jobs", func() {
-				renderedJobs[0].EXPECT().DeleteSilently()
+		Context("when rendering jobs fails", func() {
+			It("returns an error", func() {
+				mockJobRenderer.EXPECT().RenderBatch(gomock.Any()).Return(nil, bosherr.Error("fake-render-error"))
 
-				_, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+				renderedJobList, err := jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, links)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-render-error"))
+				Expect(renderedJobList.All()).To(BeEmpty())
 			})
 		})
 	})
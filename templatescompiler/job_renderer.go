@@ -3,6 +3,7 @@ package templatescompiler
 import (
 	"os"
 	"path/filepath"
+	"sort"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -16,6 +17,20 @@ import (
 
 type JobRenderer interface {
 	Render(releaseJob bireljob.Job, releaseJobProperties *biproperty.Map, jobProperties biproperty.Map, globalProperties biproperty.Map, deploymentName string, address string) (RenderedJob, error)
+	RenderInstance(releaseJob bireljob.Job, releaseJobProperties *biproperty.Map, jobProperties biproperty.Map, globalProperties biproperty.Map, deploymentName string, instance InstanceSpec) (RenderedJob, error)
+
+	// RenderBatch renders templates of all requested jobs at once
+	// which is considerably faster than rendering jobs one by one
+	RenderBatch(requests []JobRenderRequest) ([]RenderedJob, error)
+}
+
+type JobRenderRequest struct {
+	ReleaseJob           bireljob.Job
+	ReleaseJobProperties *biproperty.Map
+	JobProperties        biproperty.Map
+	GlobalProperties     biproperty.Map
+	DeploymentName       string
+	Instance             InstanceSpec
 }
 
 type jobRenderer struct {
@@ -42,51 +57,110 @@ func NewJobRenderer(
 }
 
 func (r *jobRenderer) Render(releaseJob bireljob.Job, releaseJobProperties *biproperty.Map, jobProperties biproperty.Map, globalProperties biproperty.Map, deploymentName string, address string) (RenderedJob, error) {
-	context := NewJobEvaluationContext(releaseJob, releaseJobProperties, jobProperties, globalProperties, deploymentName, address, r.uuidGen, r.logger)
-
-	sourcePath := releaseJob.ExtractedPath()
+	return r.RenderInstance(releaseJob, releaseJobProperties, jobProperties, globalProperties, deploymentName, InstanceSpec{Address: address})
+}
 
-	destinationPath, err := r.fs.TempDir("rendered-jobs")
+func (r *jobRenderer) RenderInstance(releaseJob bireljob.Job, releaseJobProperties *biproperty.Map, jobProperties biproperty.Map, globalProperties biproperty.Map, deploymentName string, instance InstanceSpec) (RenderedJob, error) {
+	renderedJobs, err := r.RenderBatch([]JobRenderRequest{{
+		ReleaseJob:           releaseJob,
+		ReleaseJobProperties: releaseJobProperties,
+		JobProperties:        jobProperties,
+		GlobalProperties:     globalProperties,
+		DeploymentName:       deploymentName,
+		Instance:             instance,
+	}})
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Creating rendered job directory")
+		return nil, err
+	}
+
+	return renderedJobs[0], nil
+}
+
+func (r *jobRenderer) RenderBatch(requests []JobRenderRequest) ([]RenderedJob, error) {
+	var renderedJobs []RenderedJob
+	var batchJobs []bierbrenderer.BatchJob
+
+	deleteRenderedJobs := func() {
+		for _, renderedJob := range renderedJobs {
+			renderedJob.DeleteSilently()
+		}
 	}
 
-	renderedJob := NewRenderedJob(releaseJob, destinationPath, r.fs, r.logger)
+	for _, request := range requests {
+		releaseJob := request.ReleaseJob
+
+		destinationPath, err := r.fs.TempDir("rendered-jobs")
+		if err != nil {
+			deleteRenderedJobs()
+			return nil, bosherr.WrapError(err, "Creating rendered job directory")
+		}
+
+		renderedJobs = append(renderedJobs, NewRenderedJob(releaseJob, destinationPath, r.fs, r.logger))
 
-	for src, dst := range releaseJob.Templates {
-		err := r.renderFile(
-			filepath.Join(sourcePath, "templates", src),
-			filepath.Join(destinationPath, dst),
-			context,
-		)
+		batchJob, err := r.batchJob(request, destinationPath)
 		if err != nil {
-			defer renderedJob.DeleteSilently()
-			return nil, bosherr.WrapErrorf(err, "Rendering template src: %s, dst: %s", src, dst)
+			deleteRenderedJobs()
+			return nil, err
 		}
+
+		batchJobs = append(batchJobs, batchJob)
 	}
 
-	err = r.renderFile(
-		filepath.Join(sourcePath, "monit"),
-		filepath.Join(destinationPath, "monit"),
-		context,
-	)
+	err := r.erbRenderer.RenderBatch(batchJobs)
 	if err != nil {
-		defer renderedJob.DeleteSilently()
-		return nil, bosherr.WrapError(err, "Rendering monit file")
+		deleteRenderedJobs()
+		return nil, bosherr.WrapError(err, "Rendering job templates")
 	}
 
-	return renderedJob, nil
+	return renderedJobs, nil
 }
 
-func (r *jobRenderer) renderFile(sourcePath, destinationPath string, context bierbrenderer.TemplateEvaluationContext) error {
-	err := r.fs.MkdirAll(filepath.Dir(destinationPath), os.ModePerm)
-	if err != nil {
-		return bosherr.WrapErrorf(err, "Creating tempdir '%s'", filepath.Dir(destinationPath))
+func (r *jobRenderer) batchJob(request JobRenderRequest, destinationPath string) (bierbrenderer.BatchJob, error) {
+	releaseJob := request.ReleaseJob
+	sourcePath := releaseJob.ExtractedPath()
+
+	batchJob := bierbrenderer.BatchJob{
+		Name: releaseJob.Name(),
+		Context: NewInstanceJobEvaluationContext(
+			releaseJob,
+			request.ReleaseJobProperties,
+			request.JobProperties,
+			request.GlobalProperties,
+			request.DeploymentName,
+			request.Instance,
+			r.uuidGen,
+			r.logger,
+		),
 	}
 
-	err = r.erbRenderer.Render(sourcePath, destinationPath, context)
-	if err != nil {
-		return bosherr.WrapErrorf(err, "Rendering template src: %s, dst: %s", sourcePath, destinationPath)
+	var srcs []string
+
+	for src := range releaseJob.Templates {
+		srcs = append(srcs, src)
 	}
-	return nil
+
+	sort.Strings(srcs)
+
+	for _, src := range srcs {
+		batchJob.Templates = append(batchJob.Templates, bierbrenderer.BatchTemplate{
+			Name:    src,
+			SrcPath: filepath.Join(sourcePath, "templates", src),
+			DstPath: filepath.Join(destinationPath, releaseJob.Templates[src]),
+		})
+	}
+
+	batchJob.Templates = append(batchJob.Templates, bierbrenderer.BatchTemplate{
+		Name:    "monit",
+		SrcPath: filepath.Join(sourcePath, "monit"),
+		DstPath: filepath.Join(destinationPath, "monit"),
+	})
+
+	for _, template := range batchJob.Templates {
+		err := r.fs.MkdirAll(filepath.Dir(template.DstPath), os.ModePerm)
+		if err != nil {
+			return batchJob, bosherr.WrapErrorf(err, "Creating tempdir '%s'", filepath.Dir(template.DstPath))
+		}
+	}
+
+	return batchJob, nil
 }
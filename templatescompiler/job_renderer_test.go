@@ -121,4 +121,104 @@ var _ = Describe("JobRenderer", func() {
 			})
 		})
 	})
+
+	Describe("RenderBatch", func() {
+		var (
+			otherJob     *boshreljob.Job
+			otherContext bierbrenderer.TemplateEvaluationContext
+		)
+
+		BeforeEach(func() {
+			otherJob = boshreljob.NewExtractedJob(NewResourceWithBuiltArchive("registry", "job-fp", "path", "sha1"), "other-src-path", fs)
+			otherJob.Templates = map[string]string{}
+
+			logger := boshlog.NewLogger(boshlog.LevelNone)
+			otherContext = NewJobEvaluationContext(*otherJob, nil, jobProperties, globalProperties, "fake-deployment-name", "1.2.3.4", nil, logger)
+
+			_ = fakeERBRenderer.SetRenderBehavior( //nolint:errcheck
+				filepath.Join("other-src-path", "monit"),
+				filepath.Join(dstPath, "monit"),
+				otherContext,
+				nil,
+			)
+		})
+
+		requests := func() []JobRenderRequest {
+			return []JobRenderRequest{
+				{
+					ReleaseJob:           *job,
+					ReleaseJobProperties: &releaseJobProperties,
+					JobProperties:        jobProperties,
+					GlobalProperties:     globalProperties,
+					DeploymentName:       "fake-deployment-name",
+					Instance:             InstanceSpec{Address: "1.2.3.4"},
+				},
+				{
+					ReleaseJob:       *otherJob,
+					JobProperties:    jobProperties,
+					GlobalProperties: globalProperties,
+					DeploymentName:   "fake-deployment-name",
+					Instance:         InstanceSpec{Address: "1.2.3.4"},
+				},
+			}
+		}
+
+		It("renders templates of all jobs in a single batch", func() {
+			renderedJobs, err := jobRenderer.RenderBatch(requests())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renderedJobs).To(HaveLen(2))
+			Expect(renderedJobs[0].Job().Name()).To(Equal("cpi"))
+			Expect(renderedJobs[1].Job().Name()).To(Equal("registry"))
+
+			Expect(fakeERBRenderer.RenderBatchInputs).To(HaveLen(1))
+
+			batchJobs := fakeERBRenderer.RenderBatchInputs[0]
+			Expect(batchJobs).To(HaveLen(2))
+
+			Expect(batchJobs[0].Name).To(Equal("cpi"))
+			Expect(batchJobs[0].Templates).To(Equal([]bierbrenderer.BatchTemplate{
+				{
+					Name:    "director.yml.erb",
+					SrcPath: filepath.Join(srcPath, "templates/director.yml.erb"),
+					DstPath: filepath.Join(dstPath, "config/director.yml"),
+				},
+				{
+					Name:    "monit",
+					SrcPath: filepath.Join(srcPath, "monit"),
+					DstPath: filepath.Join(dstPath, "monit"),
+				},
+			}))
+
+			Expect(batchJobs[1].Name).To(Equal("registry"))
+			Expect(batchJobs[1].Templates).To(Equal([]bierbrenderer.BatchTemplate{
+				{
+					Name:    "monit",
+					SrcPath: filepath.Join("other-src-path", "monit"),
+					DstPath: filepath.Join(dstPath, "monit"),
+				},
+			}))
+		})
+
+		Context("when rendering any template fails", func() {
+			BeforeEach(func() {
+				_ = fakeERBRenderer.SetRenderBehavior( //nolint:errcheck
+					filepath.Join("other-src-path", "monit"),
+					filepath.Join(dstPath, "monit"),
+					otherContext,
+					bosherr.Error("fake-template-render-error"),
+				)
+			})
+
+			It("returns an error naming the job and template and cleans up all rendered jobs", func() {
+				Expect(fs.MkdirAll(dstPath, 0755)).To(Succeed())
+
+				_, err := jobRenderer.RenderBatch(requests())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Error filling in template 'monit' for job 'registry'"))
+				Expect(err.Error()).To(ContainSubstring("fake-template-render-error"))
+
+				Expect(fs.FileExists(dstPath)).To(BeFalse())
+			})
+		})
+	})
 })
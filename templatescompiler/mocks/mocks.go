@@ -51,6 +51,36 @@ func (mr *MockJobRendererMockRecorder) Render(arg0, arg1, arg2, arg3, arg4, arg5
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockJobRenderer)(nil).Render), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// RenderBatch mocks base method.
+func (m *MockJobRenderer) RenderBatch(arg0 []templatescompiler.JobRenderRequest) ([]templatescompiler.RenderedJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderBatch", arg0)
+	ret0, _ := ret[0].([]templatescompiler.RenderedJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenderBatch indicates an expected call of RenderBatch.
+func (mr *MockJobRendererMockRecorder) RenderBatch(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderBatch", reflect.TypeOf((*MockJobRenderer)(nil).RenderBatch), arg0)
+}
+
+// RenderInstance mocks base method.
+func (m *MockJobRenderer) RenderInstance(arg0 job.Job, arg1 *property.Map, arg2, arg3 property.Map, arg4 string, arg5 templatescompiler.InstanceSpec) (templatescompiler.RenderedJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderInstance", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(templatescompiler.RenderedJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenderInstance indicates an expected call of RenderInstance.
+func (mr *MockJobRendererMockRecorder) RenderInstance(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderInstance", reflect.TypeOf((*MockJobRenderer)(nil).RenderInstance), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
 // MockJobListRenderer is a mock of JobListRenderer interface.
 type MockJobListRenderer struct {
 	ctrl     *gomock.Controller
@@ -75,18 +105,18 @@ func (m *MockJobListRenderer) EXPECT() *MockJobListRendererMockRecorder {
 }
 
 // Render mocks base method.
-func (m *MockJobListRenderer) Render(arg0 []job.Job, arg1 map[string]*property.Map, arg2, arg3 property.Map, arg4, arg5 string) (templatescompiler.RenderedJobList, error) {
+func (m *MockJobListRenderer) Render(arg0 []job.Job, arg1 map[string]*property.Map, arg2, arg3 property.Map, arg4, arg5 string, arg6 map[string]map[string]templatescompiler.LinkSpec) (templatescompiler.RenderedJobList, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Render", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret := m.ctrl.Call(m, "Render", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 	ret0, _ := ret[0].(templatescompiler.RenderedJobList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Render indicates an expected call of Render.
-func (mr *MockJobListRendererMockRecorder) Render(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+func (mr *MockJobListRendererMockRecorder) Render(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockJobListRenderer)(nil).Render), arg0, arg1, arg2, arg3, arg4, arg5)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockJobListRenderer)(nil).Render), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 }
 
 // MockRenderedJob is a mock of RenderedJob interface.
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net"
 	"net/url"
-	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	"github.com/cloudfoundry/bosh-utils/httpclient"
@@ -51,7 +50,12 @@ func (f Factory) httpClient(config Config) (Client, error) {
 	}
 
 	rawClient := httpclient.CreateDefaultClient(certPool)
-	retryClient := httpclient.NewNetworkSafeRetryClient(rawClient, 5, 500*time.Millisecond, f.logger)
+
+	err = config.HTTP.Configure(rawClient)
+	if err != nil {
+		return Client{}, err
+	}
+	retryClient := config.HTTP.RetryClient(rawClient, f.logger)
 
 	httpClient := httpclient.NewHTTPClient(retryClient, f.logger)
 
@@ -9,6 +9,8 @@ import (
 
 	"github.com/cloudfoundry/bosh-utils/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+
+	bihttpclient "github.com/cloudfoundry/bosh-cli/v7/common/httpclient"
 )
 
 type Config struct {
@@ -20,6 +22,9 @@ type Config struct {
 	ClientSecret string
 
 	CACert string
+
+	// HTTP client settings are optional
+	HTTP bihttpclient.Opts
 }
 
 func NewConfigFromURL(url string) (Config, error) {
@@ -81,6 +86,10 @@ func (c Config) Validate() error {
 		return err
 	}
 
+	if err := c.HTTP.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -50,7 +50,12 @@ type ReadCloserProxy struct {
 func (p ReadCloserProxy) Seek(offset int64, whence int) (int64, error) {
 	seeker, ok := p.reader.(io.Seeker)
 	if ok {
-		return seeker.Seek(offset, whence)
+		pos, err := seeker.Seek(offset, whence)
+		if err == nil {
+			// Resumed or retried uploads continue progress from new position
+			p.bar.SetCurrent(pos)
+		}
+		return pos, err
 	}
 
 	return 0, nil
@@ -1,6 +1,8 @@
 package ui_test
 
 import (
+	"io"
+
 	. "github.com/cloudfoundry/bosh-cli/v7/ui"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -19,6 +21,11 @@ type FakeSeekableReader struct {
 }
 type FakeReaderCloser struct{}
 
+// FakePositionedSeekableReader reports requested offset as new position
+type FakePositionedSeekableReader struct {
+	FakeSeekableReader
+}
+
 func (FakeSeekableReader) Read(_ []byte) (n int, err error) {
 	panic("should not call")
 }
@@ -37,6 +44,12 @@ func (r FakeSeekableReader) Seek(offset int64, whence int) (int64, error) {
 	return 0, nil
 }
 
+func (r FakePositionedSeekableReader) Seek(offset int64, whence int) (int64, error) {
+	_, err := r.FakeSeekableReader.Seek(offset, whence)
+
+	return offset, err
+}
+
 func (r FakeSeekableReader) Close() error {
 	r.callTracker.Closes++
 
@@ -59,6 +72,23 @@ var _ = Describe("ReadCloserProxy", func() {
 			})
 		})
 
+		Context("when reader is seekable", func() {
+			It("continues progress from new position", func() {
+				fakeUI := &fakes.FakeUI{}
+				seekerReader := FakePositionedSeekableReader{
+					FakeSeekableReader{callTracker: &CallTracker{}},
+				}
+				fileReporter := NewFileReporter(fakeUI)
+				readCloserProxy := fileReporter.TrackUpload(100, seekerReader)
+
+				_, err := readCloserProxy.Seek(50, io.SeekStart)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(readCloserProxy.Close()).To(Succeed())
+				Expect(fakeUI.Said[len(fakeUI.Said)-1]).To(ContainSubstring("50.00%"))
+			})
+		})
+
 		Context("when reader is NOT seekable", func() {
 			It("does not complain and returns 0, nil", func() {
 				reader := FakeReaderCloser{}
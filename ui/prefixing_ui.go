@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+// prefixingUI prints whole lines with a prefix so that output of
+// operations running at the same time (e.g. tasks) can be told apart.
+// Partial lines are kept until they are ended or the UI is flushed.
+type prefixingUI struct {
+	parent UI
+	prefix string
+
+	partial     string
+	partialLock sync.Mutex
+}
+
+func NewPrefixingUI(parent UI, prefix string) UI {
+	return &prefixingUI{parent: parent, prefix: prefix}
+}
+
+func (ui *prefixingUI) ErrorLinef(pattern string, args ...interface{}) {
+	ui.flushPartial()
+	ui.printLines(ui.parent.ErrorLinef, fmt.Sprintf(pattern, args...))
+}
+
+func (ui *prefixingUI) PrintLinef(pattern string, args ...interface{}) {
+	ui.flushPartial()
+	ui.printLines(ui.parent.PrintLinef, fmt.Sprintf(pattern, args...))
+}
+
+func (ui *prefixingUI) BeginLinef(pattern string, args ...interface{}) {
+	ui.write(fmt.Sprintf(pattern, args...))
+}
+
+func (ui *prefixingUI) EndLinef(pattern string, args ...interface{}) {
+	ui.write(fmt.Sprintf(pattern, args...) + "\n")
+}
+
+func (ui *prefixingUI) PrintBlock(block []byte) {
+	ui.write(string(block))
+}
+
+func (ui *prefixingUI) PrintErrorBlock(block string) {
+	ui.flushPartial()
+	ui.printLines(ui.parent.ErrorLinef, block)
+}
+
+// PrintTable renders table first so that each of its lines gets the prefix
+func (ui *prefixingUI) PrintTable(table table.Table) {
+	ui.flushPartial()
+
+	var buf bytes.Buffer
+
+	err := table.Print(&buf)
+	if err != nil {
+		ui.parent.ErrorLinef("%sPrinting table: %s", ui.prefix, err)
+		return
+	}
+
+	ui.printLines(ui.parent.PrintLinef, buf.String())
+}
+
+func (ui *prefixingUI) PrintTableFiltered(table table.Table, filterHeader []table.Header) {
+	ui.PrintTable(table)
+}
+
+func (ui *prefixingUI) AskForText(label string) (string, error) {
+	return ui.parent.AskForText(label)
+}
+
+func (ui *prefixingUI) AskForTextWithDefaultValue(label, defaultValue string) (string, error) {
+	return ui.parent.AskForTextWithDefaultValue(label, defaultValue)
+}
+
+func (ui *prefixingUI) AskForChoice(label string, options []string) (int, error) {
+	return ui.parent.AskForChoice(label, options)
+}
+
+func (ui *prefixingUI) AskForPassword(label string) (string, error) {
+	return ui.parent.AskForPassword(label)
+}
+
+func (ui *prefixingUI) AskForConfirmation() error {
+	return ui.parent.AskForConfirmation()
+}
+
+func (ui *prefixingUI) AskForConfirmationWithLabel(label string) error {
+	return ui.parent.AskForConfirmationWithLabel(label)
+}
+
+func (ui *prefixingUI) IsInteractive() bool {
+	return ui.parent.IsInteractive()
+}
+
+func (ui *prefixingUI) Flush() {
+	ui.flushPartial()
+	ui.parent.Flush()
+}
+
+func (ui *prefixingUI) write(str string) {
+	ui.partialLock.Lock()
+	defer ui.partialLock.Unlock()
+
+	lines := strings.Split(ui.partial+str, "\n")
+
+	// Last piece is either empty or a partial line
+	ui.partial = lines[len(lines)-1]
+
+	ui.printLines(ui.parent.PrintLinef, strings.Join(lines[:len(lines)-1], "\n"))
+}
+
+func (ui *prefixingUI) flushPartial() {
+	ui.partialLock.Lock()
+	defer ui.partialLock.Unlock()
+
+	ui.printLines(ui.parent.PrintLinef, ui.partial)
+	ui.partial = ""
+}
+
+// printLines skips empty lines since they are only meaningful without a prefix
+func (ui *prefixingUI) printLines(printFunc func(string, ...interface{}), str string) {
+	for _, line := range strings.Split(str, "\n") {
+		if len(strings.TrimSpace(line)) > 0 {
+			printFunc("%s%s", ui.prefix, line)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package ui_test
+
+import (
+	"bytes"
+	"strings"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-cli/v7/ui"
+	fakeui "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	. "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+)
+
+var _ = Describe("PrefixingUI", func() {
+	var (
+		uiOut, uiErr *bytes.Buffer
+		parentUI     UI
+		ui           UI
+	)
+
+	BeforeEach(func() {
+		uiOut = bytes.NewBufferString("")
+		uiErr = bytes.NewBufferString("")
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		parentUI = NewWriterUI(uiOut, uiErr, logger)
+	})
+
+	JustBeforeEach(func() {
+		ui = NewPrefixingUI(parentUI, "dep | ")
+	})
+
+	Describe("ErrorLinef", func() {
+		It("delegates to the parent UI with a prefix on each line", func() {
+			ui.ErrorLinef("fake-error-line1\nfake-error-line2")
+			Expect(uiErr.String()).To(Equal("dep | fake-error-line1\ndep | fake-error-line2\n"))
+			Expect(uiOut.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("PrintLinef", func() {
+		It("delegates to the parent UI with a prefix", func() {
+			ui.PrintLinef("fake-line")
+			Expect(uiOut.String()).To(Equal("dep | fake-line\n"))
+			Expect(uiErr.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("BeginLinef/EndLinef/PrintBlock", func() {
+		It("prints whole lines with a prefix once they are ended", func() {
+			ui.BeginLinef("Task %d", 1)
+			ui.PrintBlock([]byte(" | 10:00:00 | Updating\n\n"))
+			ui.BeginLinef("Task %d", 1)
+			Expect(uiOut.String()).To(Equal("dep | Task 1 | 10:00:00 | Updating\n"))
+
+			ui.EndLinef(". Done")
+			Expect(uiOut.String()).To(Equal("dep | Task 1 | 10:00:00 | Updating\ndep | Task 1. Done\n"))
+		})
+
+		It("prints partial lines when flushed", func() {
+			ui.BeginLinef("Task 1")
+			ui.Flush()
+			Expect(uiOut.String()).To(Equal("dep | Task 1\n"))
+		})
+	})
+
+	Describe("PrintErrorBlock", func() {
+		It("prints pending output before the error block with a prefix", func() {
+			ui.BeginLinef("Task 1")
+			ui.PrintErrorBlock("Warning: fake-warning")
+			Expect(uiOut.String()).To(Equal("dep | Task 1\n"))
+			Expect(uiErr.String()).To(Equal("dep | Warning: fake-warning\n"))
+		})
+	})
+
+	Describe("PrintTable", func() {
+		It("prints each line of the table with a prefix", func() {
+			table := Table{
+				Content: "things",
+				Header:  []Header{NewHeader("header1")},
+				Rows:    [][]Value{{ValueString{S: "r1c1"}}},
+			}
+
+			var unprefixed bytes.Buffer
+			Expect(table.Print(&unprefixed)).To(Succeed())
+
+			ui.BeginLinef("Task 1")
+			ui.PrintTable(table)
+
+			var expected string
+			for _, line := range strings.Split(unprefixed.String(), "\n") {
+				if len(strings.TrimSpace(line)) > 0 {
+					expected += "dep | " + line + "\n"
+				}
+			}
+
+			Expect(uiOut.String()).To(Equal("dep | Task 1\n" + expected))
+			Expect(uiOut.String()).To(ContainSubstring("dep | r1c1"))
+		})
+	})
+
+	Describe("PrintTableFiltered", func() {
+		It("prints each line of the table with a prefix", func() {
+			table := Table{
+				Header: []Header{NewHeader("header1")},
+				Rows:   [][]Value{{ValueString{S: "r1c1"}}},
+			}
+
+			ui.PrintTableFiltered(table, nil)
+			Expect(uiOut.String()).To(ContainSubstring("dep | r1c1"))
+
+			for _, line := range strings.Split(strings.TrimSuffix(uiOut.String(), "\n"), "\n") {
+				Expect(line).To(HavePrefix("dep | "))
+			}
+		})
+	})
+
+	Describe("AskForConfirmation", func() {
+		It("delegates to the parent UI", func() {
+			parentFakeUI := &fakeui.FakeUI{}
+			ui = NewPrefixingUI(parentFakeUI, "dep | ")
+
+			Expect(ui.AskForConfirmation()).To(Succeed())
+			Expect(parentFakeUI.AskedConfirmationCalled).To(BeTrue())
+		})
+	})
+})